@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"io"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/rubyist/tracerx"
+)
+
+// defaultBootstrapMinObjects is the fewest objects a fetch must need before
+// the server is asked for a bootstrap bundle, unless lfs.bootstrap.minobjects
+// says otherwise.
+const defaultBootstrapMinObjects = 100
+
+// bootstrapObjects downloads the bundle of objects which the server offers to
+// new clones, if it offers one, when none of the given objects are in local
+// storage yet and there are at least lfs.bootstrap.minobjects of them. Each
+// object in the bundle is verified as it is imported, so that the fetch which
+// follows only downloads those which the bundle lacked. Since that fetch gets
+// any objects the bundle didn't provide anyway, failures are only warnings.
+func bootstrapObjects(pointers []*lfs.WrappedPointer) {
+	if !cfg.Git.Bool("lfs.bootstrap", true) {
+		return
+	}
+
+	seen := make(map[string]bool, len(pointers))
+	for _, p := range pointers {
+		if cfg.LFSObjectExists(p.Oid, p.Size) {
+			return
+		}
+		seen[p.Oid] = true
+	}
+	if len(seen) == 0 || len(seen) < cfg.Git.Int("lfs.bootstrap.minobjects", defaultBootstrapMinObjects) {
+		return
+	}
+
+	client := getAPIClient()
+	b, err := client.Bootstrap(cfg.Remote(), currentRemoteRef())
+	if err != nil {
+		if errors.IsNotImplementedError(err) {
+			tracerx.Printf("bootstrap: server does not offer a bundle")
+		} else {
+			Error("warning: could not get bootstrap bundle: %v", err)
+		}
+		return
+	}
+
+	if b.Size > 0 {
+		Print("bootstrap: Downloading bundle (%s)", humanize.FormatBytes(uint64(b.Size)))
+	} else {
+		Print("bootstrap: Downloading bundle")
+	}
+
+	r, err := client.OpenBootstrap(b)
+	if err != nil {
+		Error("warning: %v", err)
+		return
+	}
+	defer r.Close()
+
+	// Keep any objects imported before a failure, since they have been
+	// verified, and let the fetch get the rest.
+	ar, err := newObjectArchiveReader(r)
+	defer ar.Close()
+	for err == nil {
+		_, err = ar.Next()
+	}
+	if err != io.EOF {
+		Error("warning: could not read bootstrap bundle: %v", err)
+	}
+	if ar != nil {
+		Print("bootstrap: %d object(s) imported from bundle", ar.Imported)
+	}
+}
@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/filepathfilter"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveFormat string
+	archiveOutput string
+	archivePrefix string
+)
+
+// archiveWriter writes the entries of an archive in a particular format.
+type archiveWriter interface {
+	// WriteHeader begins a new entry, described by the given tar header,
+	// whose contents are written with Write.
+	WriteHeader(hdr *tar.Header) error
+	Write(b []byte) (int, error)
+	Close() error
+}
+
+func archiveCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) == 0 {
+		Print("Usage: git lfs archive [--format=<fmt>] [-o <file>] [--prefix=<prefix>/] <tree-ish> [<path>...]")
+		os.Exit(1)
+	}
+
+	format, err := archiveFormatFor(archiveFormat, archiveOutput)
+	if err != nil {
+		Exit("fatal: %v", err)
+	}
+
+	treeish := args[0]
+	ref, err := git.ResolveRef(treeish)
+	if err != nil {
+		Exit("fatal: not a valid tree-ish: %q", treeish)
+	}
+
+	paths := rootedPaths(args[1:])
+	manifest := getTransferManifestOperationRemote("download", cfg.Remote())
+
+	pointers, err := archivePointers(ref.Sha, filepathfilter.New(paths, nil))
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+	if !fetchForArchive(pointers, manifest) {
+		Exit("error: failed to fetch some objects for %q", treeish)
+	}
+
+	var out io.Writer = os.Stdout
+	if len(archiveOutput) > 0 && archiveOutput != "-" {
+		f, err := os.Create(archiveOutput)
+		if err != nil {
+			Exit("fatal: could not create %q: %v", archiveOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeArchive(out, format, treeish, paths, pointers, manifest); err != nil {
+		if out != os.Stdout {
+			os.Remove(archiveOutput)
+		}
+		Exit("fatal: could not write archive: %v", err)
+	}
+}
+
+// archiveFormatFor returns the archive format given by --format, or implied by
+// the extension of the output file, defaulting to "tar".
+func archiveFormatFor(format, output string) (string, error) {
+	if len(format) == 0 {
+		if strings.EqualFold(filepath.Ext(output), ".zip") {
+			return "zip", nil
+		}
+		return "tar", nil
+	}
+
+	switch format {
+	case "tar", "zip":
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown archive format %q", format)
+}
+
+// archivePointers returns the pointers of the files in the given tree which
+// are tracked by Git LFS according to the .gitattributes files in that tree,
+// and which the given filter allows, by path.
+func archivePointers(ref string, filter *filepathfilter.Filter) (map[string]*lfs.WrappedPointer, error) {
+	pointers := make(map[string]*lfs.WrappedPointer)
+	var multiErr error
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			if multiErr != nil {
+				multiErr = fmt.Errorf("%v\n%v", multiErr, err)
+			} else {
+				multiErr = err
+			}
+			return
+		}
+
+		pointers[p.Name] = p
+	})
+	defer gitscanner.Close()
+
+	gitscanner.Filter = filter
+	if err := gitscanner.ScanTrackedTree(ref); err != nil {
+		return nil, err
+	}
+	return pointers, multiErr
+}
+
+// fetchForArchive downloads the objects for the given pointers which are not
+// present locally. Progress is written to stderr, since the archive itself may
+// be written to stdout.
+func fetchForArchive(pointers map[string]*lfs.WrappedPointer, manifest *tq.Manifest) bool {
+	seen := make(map[string]bool, len(pointers))
+	var missing []*lfs.WrappedPointer
+	for _, p := range pointers {
+		if seen[p.Oid] {
+			continue
+		}
+		seen[p.Oid] = true
+
+		lfs.LinkOrCopyFromReference(cfg, p.Oid, p.Size)
+		if !cfg.LFSObjectExists(p.Oid, p.Size) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	logger := tasklog.NewLogger(os.Stderr,
+		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
+	)
+	meter := buildProgressMeter(false, tq.Download)
+	logger.Enqueue(meter)
+
+	q := newDownloadQueue(manifest, cfg.Remote(), tq.WithProgress(meter))
+	for _, p := range missing {
+		meter.Add(p.Size)
+		q.Add(downloadTransfer(p))
+	}
+
+	meter.Start()
+	q.Wait()
+	meter.Finish()
+	logger.Close()
+	exitIfInterrupted(q, tq.Download)
+
+	ok := true
+	for _, err := range q.Errors() {
+		ok = false
+		FullError(err)
+	}
+	return ok
+}
+
+// writeArchive writes an archive of the given tree-ish to "out", in which the
+// content of each Git LFS object replaces its pointer file, given by path in
+// "pointers".
+func writeArchive(out io.Writer, format, treeish string, paths []string, pointers map[string]*lfs.WrappedPointer, manifest *tq.Manifest) error {
+	cmd := git.Archive(treeish, archivePrefix, paths)
+	cmd.Dir = cfg.LocalWorkingDir()
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var aw archiveWriter
+	if format == "zip" {
+		aw = newZipArchiveWriter(out)
+	} else {
+		aw = tar.NewWriter(out)
+	}
+
+	gf := lfs.NewGitFilter(cfg)
+	tr := tar.NewReader(stdout)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			cmd.Wait()
+			return err
+		}
+
+		if err := copyArchiveEntry(aw, gf, manifest, pointers, hdr, tr); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return errors.Wrapf(err, "%s", hdr.Name)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrap(err, "git archive")
+	}
+	return aw.Close()
+}
+
+// copyArchiveEntry writes the entry described by "hdr" to the archive, along
+// with its contents from "r", replacing the contents of any Git LFS file in
+// "pointers" with those of the object it refers to.
+func copyArchiveEntry(aw archiveWriter, gf *lfs.GitFilter, manifest *tq.Manifest, pointers map[string]*lfs.WrappedPointer, hdr *tar.Header, r io.Reader) error {
+	name := strings.TrimPrefix(hdr.Name, archivePrefix)
+	p, ok := pointers[name]
+	if hdr.Typeflag != tar.TypeReg || !ok || p.Size == 0 {
+		if err := aw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := io.Copy(aw, r)
+		return err
+	}
+
+	ptr := p.Pointer
+	hdr.Format = tar.FormatUnknown
+
+	if len(ptr.Extensions) == 0 {
+		hdr.Size = ptr.Size
+		if err := aw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := gf.Smudge(aw, ptr, name, true, manifest, nil)
+		return err
+	}
+
+	// Smudge extensions may change the size of the content, which must
+	// be known before the header is written, so spool it first.
+	tmp, err := ioutil.TempFile(cfg.TempDir(), "archive")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := gf.Smudge(tmp, ptr, name, true, manifest, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	hdr.Size = n
+	if err := aw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(aw, tmp)
+	return err
+}
+
+// zipArchiveWriter converts the entries of a tar archive, as written by "git
+// archive", into a zip archive.
+type zipArchiveWriter struct {
+	zw *zip.Writer
+	w  io.Writer
+}
+
+func newZipArchiveWriter(out io.Writer) *zipArchiveWriter {
+	return &zipArchiveWriter{zw: zip.NewWriter(out), w: ioutil.Discard}
+}
+
+func (z *zipArchiveWriter) WriteHeader(hdr *tar.Header) error {
+	z.w = ioutil.Discard
+
+	zh := &zip.FileHeader{
+		Name:     hdr.Name,
+		Modified: hdr.ModTime,
+		Method:   zip.Deflate,
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeXGlobalHeader:
+		// "git archive" records the commit ID as a comment, as it
+		// does in the zip archives it creates.
+		if comment, ok := hdr.PAXRecords["comment"]; ok {
+			return z.zw.SetComment(comment)
+		}
+		return nil
+	case tar.TypeDir:
+		zh.Method = zip.Store
+		zh.SetMode(os.ModeDir | os.FileMode(hdr.Mode).Perm())
+	case tar.TypeSymlink:
+		zh.Method = zip.Store
+		zh.SetMode(os.ModeSymlink | 0777)
+	case tar.TypeReg:
+		zh.SetMode(os.FileMode(hdr.Mode).Perm())
+	default:
+		return nil
+	}
+
+	w, err := z.zw.CreateHeader(zh)
+	if err != nil {
+		return err
+	}
+	if hdr.Typeflag == tar.TypeSymlink {
+		_, err = io.WriteString(w, hdr.Linkname)
+		return err
+	}
+	z.w = w
+	return nil
+}
+
+func (z *zipArchiveWriter) Write(b []byte) (int, error) {
+	return z.w.Write(b)
+}
+
+func (z *zipArchiveWriter) Close() error {
+	return z.zw.Close()
+}
+
+func init() {
+	RegisterCommand("archive", archiveCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVar(&archiveFormat, "format", "", "Format of the archive: tar or zip")
+		cmd.Flags().StringVarP(&archiveOutput, "output", "o", "", "Write the archive to this file instead of stdout")
+		cmd.Flags().StringVar(&archivePrefix, "prefix", "", "Prepend this prefix to each path in the archive")
+	})
+}
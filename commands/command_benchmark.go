@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchmarkSize    string
+	benchmarkNetwork bool
+	benchmarkYes     bool
+
+	// benchmarkChunkSize is the size of the buffer of random data used to
+	// perform each benchmark.
+	benchmarkChunkSize uint64 = humanize.Mebibyte
+)
+
+// benchmarkResult is the outcome of a single benchmark, along with the
+// throughput which we would typically expect to see for it on a reasonably
+// modern machine and connection.
+type benchmarkResult struct {
+	name     string
+	size     uint64
+	duration time.Duration
+	typical  uint64
+	err      error
+}
+
+func (r *benchmarkResult) rate() uint64 {
+	if r.duration <= 0 {
+		return 0
+	}
+	return uint64(float64(r.size) / r.duration.Seconds())
+}
+
+func benchmarkCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	size, err := humanize.ParseBytes(benchmarkSize)
+	if err != nil {
+		Exit("Invalid size %q: %s", benchmarkSize, err)
+	}
+	// Round up to a whole number of chunks.
+	size = (size + benchmarkChunkSize - 1) / benchmarkChunkSize * benchmarkChunkSize
+	if size == 0 {
+		size = benchmarkChunkSize
+	}
+
+	remote := cfg.Remote()
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	// Objects uploaded by the network benchmark are never removed, so
+	// require the user to confirm that they may be left on the server.
+	if benchmarkNetwork && !benchmarkYes {
+		Exit("--network permanently uploads a new %s object to the Git LFS server of the %q remote each time it is run.\nUse a remote set aside for testing, and pass --yes to confirm.",
+			humanize.FormatBytes(size), remote)
+	}
+
+	chunk := make([]byte, benchmarkChunkSize)
+	if _, err := rand.Read(chunk); err != nil {
+		ExitWithError(err)
+	}
+
+	Print("Benchmarking with %s of data", humanize.FormatBytes(size))
+	Print("")
+
+	results := []*benchmarkResult{
+		benchmarkHash(chunk, size),
+		benchmarkDiskWrite(chunk, size),
+	}
+	if benchmarkNetwork {
+		results = append(results, benchmarkTransfers(chunk, size, remote)...)
+	}
+
+	slow := false
+	for _, r := range results {
+		if r.err != nil {
+			Print("  %-10s  failed: %s", r.name, r.err)
+			continue
+		}
+
+		note := ""
+		if r.rate() < r.typical {
+			note = " (slower than typical)"
+			slow = true
+		}
+		Print("  %-10s  %12s  typical: >= %s%s", r.name,
+			humanize.FormatByteRate(r.size, r.duration),
+			humanize.FormatByteRate(r.typical, time.Second), note)
+	}
+
+	if !benchmarkNetwork {
+		Print("")
+		Print("Use --network --yes to also measure transfers to and from the %q remote,", remote)
+		Print("which leaves a test object on its Git LFS server.")
+	}
+	if slow {
+		Print("")
+		Print("Some results are slower than typical values for this environment.")
+	}
+}
+
+// benchmarkHash measures the speed at which objects can be hashed, which
+// limits the speed of the clean filter and of object verification.
+func benchmarkHash(chunk []byte, size uint64) *benchmarkResult {
+	h := sha256.New()
+
+	start := time.Now()
+	for n := uint64(0); n < size; n += uint64(len(chunk)) {
+		h.Write(chunk)
+	}
+	h.Sum(nil)
+
+	return &benchmarkResult{
+		name:     "hash",
+		size:     size,
+		duration: time.Since(start),
+		typical:  200 * humanize.Megabyte,
+	}
+}
+
+// benchmarkDiskWrite measures the speed at which data can be written to, and
+// flushed to disk in, the temporary directory of the local storage path, which
+// is where objects are written before being moved into place.
+func benchmarkDiskWrite(chunk []byte, size uint64) *benchmarkResult {
+	result := &benchmarkResult{
+		name:    "disk write",
+		size:    size,
+		typical: 100 * humanize.Megabyte,
+	}
+
+	if err := tools.MkdirAll(cfg.TempDir(), cfg); err != nil {
+		result.err = err
+		return result
+	}
+
+	f, err := ioutil.TempFile(cfg.TempDir(), "benchmark")
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer os.Remove(f.Name())
+
+	start := time.Now()
+	for n := uint64(0); n < size && err == nil; n += uint64(len(chunk)) {
+		_, err = f.Write(chunk)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	result.duration = time.Since(start)
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	result.err = err
+	return result
+}
+
+// benchmarkTransfers uploads a synthetic object to the given remote, and then
+// downloads it again, measuring the speed of each transfer.
+func benchmarkTransfers(chunk []byte, size uint64, remote string) []*benchmarkResult {
+	upload := &benchmarkResult{name: "upload", size: size, typical: 10 * humanize.Megabyte}
+	download := &benchmarkResult{name: "download", size: size, typical: 10 * humanize.Megabyte}
+
+	oid, path, err := writeBenchmarkObject(chunk, size)
+	if err != nil {
+		upload.err, download.err = err, err
+		return []*benchmarkResult{upload, download}
+	}
+	defer os.Remove(path)
+
+	name := "benchmark-" + oid[:8]
+
+	uq := tq.NewTransferQueue(tq.Upload,
+		getTransferManifestOperationRemote("upload", remote), remote,
+		tq.RemoteRef(currentRemoteRef()))
+	start := time.Now()
+	uq.Add(name, path, oid, int64(size), false, nil)
+	uq.Wait()
+	upload.duration = time.Since(start)
+	if errs := uq.Errors(); len(errs) > 0 {
+		upload.err = errs[0]
+		download.err = errors.New("skipped because upload failed")
+		return []*benchmarkResult{upload, download}
+	}
+
+	if err := os.Remove(path); err != nil {
+		download.err = err
+		return []*benchmarkResult{upload, download}
+	}
+
+	dq := newDownloadQueue(getTransferManifestOperationRemote("download", remote), remote)
+	start = time.Now()
+	dq.Add(name, path, oid, int64(size), false, nil)
+	dq.Wait()
+	download.duration = time.Since(start)
+	if errs := dq.Errors(); len(errs) > 0 {
+		download.err = errs[0]
+	}
+
+	return []*benchmarkResult{upload, download}
+}
+
+// writeBenchmarkObject writes a new, unique object of the given size into the
+// local storage directory and returns its OID and path.
+func writeBenchmarkObject(chunk []byte, size uint64) (string, string, error) {
+	// Make each object unique, so that the server does not already have
+	// it from an earlier run.
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", err
+	}
+
+	f, err := ioutil.TempFile(cfg.TempDir(), "benchmark")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+
+	_, err = w.Write(salt)
+	for n := uint64(len(salt)); n < size && err == nil; n += uint64(len(chunk)) {
+		b := chunk
+		if rem := size - n; rem < uint64(len(b)) {
+			b = b[:rem]
+		}
+		_, err = w.Write(b)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	oid := hex.EncodeToString(h.Sum(nil))
+	path, err := cfg.Filesystem().ObjectPath(oid)
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", "", err
+	}
+	return oid, path, nil
+}
+
+func init() {
+	RegisterCommand("benchmark", benchmarkCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&benchmarkSize, "size", "s", "64MB", "Amount of data to use for each benchmark.")
+		cmd.Flags().BoolVarP(&benchmarkNetwork, "network", "", false, "Also measure upload and download speed.")
+		cmd.Flags().BoolVarP(&benchmarkYes, "yes", "y", false, "Confirm that --network may leave objects on the remote.")
+	})
+}
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleCreateAll   bool
+	bundleCreateStdin bool
+)
+
+// bundleIndexName is the name of the index at the start of a bundle, which
+// lists the objects it contains.
+const bundleIndexName = "lfs-bundle.json"
+
+const bundleVersion = 1
+
+// bundleIndex describes the objects in a bundle, so that an incomplete bundle
+// is detected when it is unbundled.
+type bundleIndex struct {
+	Version int             `json:"version"`
+	Objects []*bundleObject `json:"objects"`
+}
+
+type bundleObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// bundleCreateCommand writes the Git LFS objects referenced by the given
+// revisions, or listed on standard input, to a bundle which "git lfs bundle
+// unbundle" can import into another repository.
+func bundleCreateCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) < 1 || (len(args) == 1 && !bundleCreateAll && !bundleCreateStdin) {
+		Print("Usage: git lfs bundle create <file> { --all | --stdin | <rev>... }")
+		os.Exit(1)
+	}
+
+	filename := args[0]
+
+	var objects []*bundleObject
+	var err error
+	if bundleCreateStdin {
+		objects, err = bundleObjectsFromStdin()
+	} else {
+		objects, err = bundleObjectsForRevs(args[1:])
+	}
+	if err != nil {
+		Exit("fatal: %v", err)
+	}
+
+	var missing []string
+	for _, o := range objects {
+		if !cfg.LFSObjectExists(o.Oid, o.Size) {
+			missing = append(missing, o.Oid)
+		}
+	}
+	if len(missing) > 0 {
+		for _, oid := range missing {
+			Error("  %s", oid)
+		}
+		Exit("fatal: %d object(s) not present locally; run `git lfs fetch` first", len(missing))
+	}
+
+	out, compression, err := createObjectArchiveOutput(filename)
+	if err != nil {
+		Exit("fatal: could not create %q: %v", filename, err)
+	}
+
+	aw, err := writeBundle(out, compression, objects)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		if out != os.Stdout {
+			os.Remove(filename)
+		}
+		Exit("fatal: could not write bundle: %v", err)
+	}
+
+	Error("Bundled %d object(s), %s", aw.Count, humanize.FormatBytes(uint64(aw.Size)))
+	adviseIgnoreArtifact(filename)
+}
+
+// bundleObjectsForRevs returns the objects referenced by any commit reachable
+// from the given revisions, which are interpreted as by git-rev-list(1): a
+// revision prefixed with "^" is excluded, and "A..B" includes B but excludes
+// A. With --all, every object referenced in the repository is included.
+func bundleObjectsForRevs(revs []string) ([]*bundleObject, error) {
+	var include, exclude []string
+	for _, rev := range revs {
+		if strings.HasPrefix(rev, "^") {
+			exclude = append(exclude, rev[1:])
+		} else if i := strings.Index(rev, ".."); i >= 0 {
+			exclude = append(exclude, rev[:i])
+			include = append(include, rev[i+2:])
+		} else {
+			include = append(include, rev)
+		}
+	}
+
+	var objects []*bundleObject
+	var multiErr error
+	seen := make(map[string]bool)
+
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			if multiErr != nil {
+				multiErr = fmt.Errorf("%v\n%v", multiErr, err)
+			} else {
+				multiErr = err
+			}
+			return
+		}
+
+		if seen[p.Oid] {
+			return
+		}
+		seen[p.Oid] = true
+		objects = append(objects, &bundleObject{Oid: p.Oid, Size: p.Size})
+	})
+	defer gitscanner.Close()
+
+	var err error
+	if bundleCreateAll {
+		err = gitscanner.ScanAll(nil)
+	} else {
+		err = gitscanner.ScanRefs(include, exclude, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return objects, multiErr
+}
+
+// bundleObjectsFromStdin returns the objects whose OIDs are given on standard
+// input, one per line.
+func bundleObjectsFromStdin() ([]*bundleObject, error) {
+	var objects []*bundleObject
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		oid := strings.TrimSpace(scanner.Text())
+		if len(oid) == 0 || seen[oid] {
+			continue
+		}
+		if !objectOidRE.MatchString(oid) {
+			return nil, fmt.Errorf("invalid OID: %q", oid)
+		}
+		seen[oid] = true
+
+		stat, err := os.Stat(cfg.Filesystem().ObjectPathname(oid))
+		if err != nil {
+			return nil, fmt.Errorf("object %s not present locally", oid)
+		}
+		objects = append(objects, &bundleObject{Oid: oid, Size: stat.Size()})
+	}
+	return objects, scanner.Err()
+}
+
+// writeBundle writes the index for the given objects, followed by the objects
+// themselves.
+func writeBundle(out io.Writer, compression objectArchiveCompression, objects []*bundleObject) (*objectArchiveWriter, error) {
+	index, err := json.Marshal(&bundleIndex{
+		Version: bundleVersion,
+		Objects: objects,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aw, err := newObjectArchiveWriter(out, compression)
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.AddFile(bundleIndexName, index); err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		if err := aw.AddObject(o.Oid, o.Size); err != nil {
+			return nil, err
+		}
+	}
+	return aw, aw.Close()
+}
+
+// bundleUnbundleCommand imports the objects in a bundle into the local store,
+// verifying each one, and checks that all of the objects in its index were
+// present.
+func bundleUnbundleCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) != 1 {
+		Print("Usage: git lfs bundle unbundle <file>")
+		os.Exit(1)
+	}
+
+	in, err := openObjectArchiveInput(args[0])
+	if err != nil {
+		Exit("fatal: could not open %q: %v", args[0], err)
+	}
+	defer in.Close()
+
+	ar, err := newObjectArchiveReader(in)
+	if err != nil {
+		Exit("fatal: could not read bundle: %v", err)
+	}
+	defer ar.Close()
+
+	var index *bundleIndex
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Exit("fatal: could not unbundle %q: %v", args[0], err)
+		}
+
+		if hdr.Name != bundleIndexName || index != nil {
+			Error("warning: ignoring unexpected file in bundle: %q", hdr.Name)
+			continue
+		}
+
+		index, err = readBundleIndex(ar)
+		if err != nil {
+			Exit("fatal: could not read bundle index: %v", err)
+		}
+	}
+
+	if index == nil {
+		Exit("fatal: %q is not a Git LFS bundle", args[0])
+	}
+
+	var missing []string
+	for _, o := range index.Objects {
+		if !cfg.LFSObjectExists(o.Oid, o.Size) {
+			missing = append(missing, o.Oid)
+		}
+	}
+	if len(missing) > 0 {
+		for _, oid := range missing {
+			Error("  %s", oid)
+		}
+		Exit("fatal: bundle is incomplete: %d object(s) missing", len(missing))
+	}
+
+	Print("Unbundled %d object(s), %d already present", ar.Imported, ar.Present)
+}
+
+func readBundleIndex(r io.Reader) (*bundleIndex, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &bundleIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	if index.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", index.Version)
+	}
+	return index, nil
+}
+
+func init() {
+	RegisterCommand("bundle", nil, func(cmd *cobra.Command) {
+		createCmd := NewCommand("create", bundleCreateCommand)
+		createCmd.Flags().BoolVar(&bundleCreateAll, "all", false, "Bundle all objects referenced by the repository")
+		createCmd.Flags().BoolVar(&bundleCreateStdin, "stdin", false, "Read the OIDs of objects to bundle from stdin")
+
+		cmd.AddCommand(
+			createCmd,
+			NewCommand("unbundle", bundleUnbundleCommand),
+		)
+	})
+}
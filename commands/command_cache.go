@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cachePackOutput   string
+	cachePackRefs     []string
+	cachePackManifest string
+	cachePackSince    string
+)
+
+// cachePackCommand writes the Git LFS objects present locally for the given
+// refs, or the current ref, to an archive which "git lfs cache unpack" can
+// restore, such as in a later run of a CI job.
+func cachePackCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(cachePackOutput) == 0 {
+		Print("Usage: git lfs cache pack --output <file> [--manifest <file>] [--since <manifest>] [<ref>...]")
+		os.Exit(1)
+	}
+
+	args = append(cachePackRefs, args...)
+
+	var refs []*git.Ref
+	if len(args) > 0 {
+		resolved, err := git.ResolveRefs(args)
+		if err != nil {
+			Exit("fatal: invalid ref argument: %v", args)
+		}
+		refs = resolved
+	} else {
+		ref, err := git.CurrentRef()
+		if err != nil {
+			Exit("fatal: could not determine the current ref: %v", err)
+		}
+		refs = []*git.Ref{ref}
+	}
+
+	var since map[string]int64
+	if len(cachePackSince) > 0 {
+		m, err := readCacheManifest(cachePackSince)
+		if err != nil {
+			Exit("fatal: could not read manifest %q: %v", cachePackSince, err)
+		}
+		since = m
+	}
+
+	out, compression, err := createObjectArchiveOutput(cachePackOutput)
+	if err != nil {
+		Exit("fatal: could not create %q: %v", cachePackOutput, err)
+	}
+
+	cached, missing, err := writeCachePack(out, compression, refs, since)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		if out != os.Stdout {
+			os.Remove(cachePackOutput)
+		}
+		Exit("fatal: could not write cache: %v", err)
+	}
+
+	adviseIgnoreArtifact(cachePackOutput)
+
+	if len(cachePackManifest) > 0 {
+		if err := writeCacheManifest(cachePackManifest, cached); err != nil {
+			Exit("fatal: could not write manifest %q: %v", cachePackManifest, err)
+		}
+		adviseIgnoreArtifact(cachePackManifest)
+	}
+
+	if missing > 0 {
+		Error("warning: %d object(s) not present locally were left out; run `git lfs fetch` first to include them", missing)
+	}
+}
+
+// writeCachePack writes the objects for the given refs which are present
+// locally, honoring lfs.fetchinclude and lfs.fetchexclude, and leaving out
+// those in the "since" manifest of a previous cache. It returns the objects
+// for the refs which are in either cache, and the number which were not
+// present locally.
+func writeCachePack(out io.Writer, compression objectArchiveCompression, refs []*git.Ref, since map[string]int64) (map[string]int64, int, error) {
+	filter := buildFilepathFilter(cfg, nil, nil, true)
+	aw, err := newObjectArchiveWriter(out, compression)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cached := make(map[string]int64)
+	seen := make(map[string]bool)
+	missing := 0
+	for _, ref := range refs {
+		pointers, err := pointersToFetchForRef(ref.Sha, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, p := range pointers {
+			if seen[p.Oid] {
+				continue
+			}
+			seen[p.Oid] = true
+
+			if size, ok := since[p.Oid]; ok && size == p.Size {
+				cached[p.Oid] = p.Size
+				continue
+			}
+			if !cfg.LFSObjectExists(p.Oid, p.Size) {
+				missing++
+				continue
+			}
+			if err := aw.AddObject(p.Oid, p.Size); err != nil {
+				return nil, 0, err
+			}
+			cached[p.Oid] = p.Size
+		}
+	}
+
+	if err := aw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	Error("Packed %d object(s), %s", aw.Count, humanize.FormatBytes(uint64(aw.Size)))
+	return cached, missing, nil
+}
+
+// readCacheManifest reads a manifest written by "git lfs cache pack
+// --manifest", which lists the OID and size of each cached object on a line.
+func readCacheManifest(filename string) (map[string]int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objects := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !objectOidRE.MatchString(fields[0]) {
+			return nil, fmt.Errorf("invalid line: %q", line)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line: %q", line)
+		}
+		objects[fields[0]] = size
+	}
+	return objects, scanner.Err()
+}
+
+// writeCacheManifest writes the given objects to a manifest, sorted by OID.
+func writeCacheManifest(filename string, objects map[string]int64) error {
+	oids := make([]string, 0, len(objects))
+	for oid := range objects {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, oid := range oids {
+		fmt.Fprintf(w, "%s %d\n", oid, objects[oid])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cacheUnpackCommand restores the objects in an archive written by "git lfs
+// cache pack" into the local store, verifying each one.
+func cacheUnpackCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) > 1 {
+		Print("Usage: git lfs cache unpack [<file>|-]")
+		os.Exit(1)
+	}
+
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	in, err := openObjectArchiveInput(name)
+	if err != nil {
+		Exit("fatal: could not open %q: %v", name, err)
+	}
+	defer in.Close()
+
+	ar, err := newObjectArchiveReader(in)
+	if err != nil {
+		Exit("fatal: could not read cache: %v", err)
+	}
+	defer ar.Close()
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Exit("fatal: could not unpack cache: %v", err)
+		}
+		Error("warning: ignoring unexpected file in cache: %q", hdr.Name)
+	}
+
+	Print("Unpacked %d object(s), %d already present", ar.Imported, ar.Present)
+}
+
+func init() {
+	RegisterCommand("cache", nil, func(cmd *cobra.Command) {
+		packCmd := NewCommand("pack", cachePackCommand)
+		packCmd.Aliases = []string{"export"}
+		packCmd.Flags().StringVarP(&cachePackOutput, "output", "o", "", "Write the cache to this file, or \"-\" for stdout")
+		packCmd.Flags().StringSliceVar(&cachePackRefs, "ref", nil, "Cache the objects for this ref")
+		packCmd.Flags().StringVar(&cachePackManifest, "manifest", "", "Write a manifest of the cached objects to this file")
+		packCmd.Flags().StringVar(&cachePackSince, "since", "", "Leave out the objects in this manifest from a previous cache")
+
+		unpackCmd := NewCommand("unpack", cacheUnpackCommand)
+		unpackCmd.Aliases = []string{"import"}
+
+		cmd.AddCommand(packCmd, unpackCmd)
+	})
+}
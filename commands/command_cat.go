@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/spf13/cobra"
+)
+
+func catCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) != 1 {
+		Print("Usage: git lfs cat <rev>:<path>")
+		os.Exit(1)
+	}
+
+	object := args[0]
+	ref, err := git.ResolveRef(object)
+	if err != nil {
+		Exit("fatal: could not resolve %q: %v", object, err)
+	}
+
+	scanner, err := git.NewObjectScanner(cfg.GitEnv(), cfg.OSEnv())
+	if err != nil {
+		Exit("Could not create object scanner: %v", err)
+	}
+	defer scanner.Close()
+
+	if !scanner.Scan(ref.Sha) {
+		Exit("fatal: could not read object %q: %v", ref.Sha, scanner.Err())
+	}
+	if typ := scanner.Type(); typ != "blob" {
+		Exit("fatal: %q is a %s, not a blob", object, typ)
+	}
+
+	// Use the path within the revision, if any, to name the file in
+	// progress output and error messages.
+	filename := object
+	if i := strings.Index(object, ":"); i >= 0 {
+		filename = object[i+1:]
+	}
+
+	ptr, contents, err := lfs.DecodeFrom(scanner.Contents())
+	if err != nil {
+		// Not a Git LFS pointer, so write the blob as it is.
+		if _, err := io.Copy(os.Stdout, contents); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	gitfilter := lfs.NewGitFilter(cfg)
+	manifest := getTransferManifestOperationRemote("download", cfg.Remote())
+	if _, err := gitfilter.Smudge(os.Stdout, ptr, filename, true, manifest, nil); err != nil {
+		Exit("Error reading Git LFS object %s for %q: %v", ptr.Oid, filename, err)
+	}
+}
+
+func init() {
+	RegisterCommand("cat", catCommand, nil)
+}
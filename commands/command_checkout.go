@@ -1,14 +1,20 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tools"
 	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +23,8 @@ var (
 	checkoutBase   bool
 	checkoutOurs   bool
 	checkoutTheirs bool
+	checkoutStdin  bool
+	checkoutJobs   int
 )
 
 func checkoutCommand(cmd *cobra.Command, args []string) {
@@ -27,7 +35,25 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 		Exit("Error parsing args: %v", err)
 	}
 
-	if checkoutTo != "" && stage != git.IndexStageDefault {
+	if checkoutJobs < 1 {
+		Exit("--jobs must be at least 1")
+	}
+
+	if recurseSubmodulesArg && checkoutTo != "" {
+		Exit("Cannot combine --to with --recurse-submodules")
+	}
+
+	if checkoutStdin {
+		if checkoutTo == "" {
+			Exit("--stdin requires --to")
+		}
+		args = append(args, readCheckoutPaths()...)
+	}
+
+	if checkoutTo != "" && checkoutToDirectory(args) {
+		checkoutToDir(rootedPaths(args), stage)
+		return
+	} else if checkoutTo != "" && stage != git.IndexStageDefault {
 		checkoutConflict(rootedPaths(args)[0], stage)
 		return
 	} else if checkoutTo != "" || stage != git.IndexStageDefault {
@@ -47,13 +73,6 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 
 	var totalBytes int64
 	var pointers []*lfs.WrappedPointer
-	logger := tasklog.NewLogger(os.Stdout,
-		tasklog.ForceProgress(cfg.ForceProgress()),
-	)
-	meter := tq.NewMeter(cfg)
-	meter.Direction = tq.Checkout
-	meter.Logger = meter.LoggerFromEnv(cfg.Os)
-	logger.Enqueue(meter)
 	chgitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
 		if err != nil {
 			LoggedError(err, "Scanner error: %s", err)
@@ -61,8 +80,6 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 		}
 
 		totalBytes += p.Size
-		meter.Add(p.Size)
-		meter.StartTransfer(p.Name)
 		pointers = append(pointers, p)
 	})
 
@@ -73,18 +90,115 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 	}
 	chgitscanner.Close()
 
-	meter.Start()
+	if cfg.Git.Bool("core.ignorecase", false) {
+		warnCaseCollisions(pointers)
+	}
+
+	fetchForCheckout(pointers)
+
+	// Only the files whose content is present locally are checked out.
+	local := make([]*lfs.WrappedPointer, 0, len(pointers))
 	for _, p := range pointers {
-		singleCheckout.Run(p)
+		if cfg.LFSObjectExists(p.Oid, p.Size) {
+			local = append(local, p)
+		}
+	}
+	checkDiskSpace(0, checkoutSize(local))
 
-		// not strictly correct (parallel) but we don't have a callback & it's just local
-		// plus only 1 slot in channel so it'll block & be close
+	logger := tasklog.NewLogger(os.Stdout,
+		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
+	)
+	meter := tq.NewMeter(cfg)
+	meter.Direction = tq.Checkout
+	meter.Logger = meter.LoggerFromEnv(cfg.Os)
+	logger.Enqueue(meter)
+	for _, p := range pointers {
+		meter.Add(p.Size)
+		meter.StartTransfer(p.Name)
+	}
+
+	checkout := newParallelCheckout(singleCheckout, checkoutJobs, func(p *lfs.WrappedPointer) {
 		meter.TransferBytes("checkout", p.Name, p.Size, totalBytes, int(p.Size))
 		meter.FinishTransfer(p.Name)
+	})
+
+	meter.Start()
+	for _, p := range pointers {
+		checkout.Run(p)
 	}
 
+	checkout.Close()
 	meter.Finish()
-	singleCheckout.Close()
+	logger.Close()
+
+	if n := singleCheckout.Skipped(); n > 0 {
+		Error("Skipped checkout for %d file(s) with content not available locally.", n)
+	}
+
+	if n := singleCheckout.Failed(); n > 0 {
+		Exit("Could not check out %d file(s); see lfs.symlinkpolicy", n)
+	}
+
+	runInSubmodules(cmd)
+}
+
+// fetchForCheckout downloads the objects of the given files which are not in
+// the local store, together in batches through the transfer queue, so that the
+// files can be checked out rather than left as pointers. Files excluded by
+// lfs.fetchinclude and lfs.fetchexclude are left alone, as are all files in a
+// repository without a remote to download from. Objects which cannot be
+// downloaded are reported, and their files skipped.
+func fetchForCheckout(pointers []*lfs.WrappedPointer) {
+	filter := buildFilepathFilter(cfg, nil, nil, true)
+	seen := make(map[string]bool)
+	var missing []*lfs.WrappedPointer
+	for _, p := range pointers {
+		if seen[p.Oid] || !filter.Allows(p.Name) {
+			continue
+		}
+		seen[p.Oid] = true
+
+		lfs.LinkOrCopyFromReference(cfg, p.Oid, p.Size)
+		if !cfg.LFSObjectExists(p.Oid, p.Size) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	remote := cfg.Remote()
+	if len(getAPIClient().Endpoints.Endpoint("download", remote).Url) == 0 {
+		return
+	}
+	checkDiskSpace(downloadSize(missing), 0)
+
+	logger := tasklog.NewLogger(os.Stdout,
+		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
+	)
+	meter := buildProgressMeter(false, tq.Download)
+	logger.Enqueue(meter)
+
+	q := trackQueue(newDownloadQueue(getTransferManifestOperationRemote("download", remote), remote,
+		tq.WithProgress(meter),
+	))
+	for _, p := range missing {
+		tracerx.Printf("fetch %v [%v]", p.Name, p.Oid)
+		meter.Add(p.Size)
+		q.Add(downloadTransfer(p))
+	}
+
+	meter.Start()
+	q.Wait()
+	meter.Finish()
+	logger.Close()
+	exitIfInterrupted(q, tq.Download)
+
+	for _, err := range q.Errors() {
+		FullError(err)
+	}
 }
 
 func checkoutConflict(file string, stage git.IndexStage) {
@@ -94,14 +208,26 @@ func checkoutConflict(file string, stage git.IndexStage) {
 		return
 	}
 
-	ref, err := git.ResolveRef(fmt.Sprintf(":%d:%s", stage, file))
+	scanner, err := git.NewObjectScanner(cfg.GitEnv(), cfg.OSEnv())
 	if err != nil {
-		Exit("Could not checkout (are you not in the middle of a merge?): %v", err)
+		Exit("Could not create object scanner: %v", err)
 	}
 
-	scanner, err := git.NewObjectScanner(cfg.GitEnv(), cfg.OSEnv())
+	p := conflictPointer(scanner, file, stage)
+	scanner.Close()
+
+	if err := singleCheckout.RunToPath(p, checkoutTo); err != nil {
+		Exit("Error checking out %v to %q: %v", p.Oid, checkoutTo, err)
+	}
+	singleCheckout.Close()
+}
+
+// conflictPointer returns the pointer for the given stage of a conflicted
+// file, exiting if it cannot be found.
+func conflictPointer(scanner *git.ObjectScanner, file string, stage git.IndexStage) *lfs.WrappedPointer {
+	ref, err := git.ResolveRef(fmt.Sprintf(":%d:%s", stage, file))
 	if err != nil {
-		Exit("Could not create object scanner: %v", err)
+		Exit("Could not checkout (are you not in the middle of a merge?): %v", err)
 	}
 
 	if !scanner.Scan(ref.Sha) {
@@ -113,12 +239,116 @@ func checkoutConflict(file string, stage git.IndexStage) {
 		Exit("Could not find decoder pointer for object %q: %v", ref.Sha, err)
 	}
 
-	p := &lfs.WrappedPointer{Name: file, Pointer: ptr}
+	return &lfs.WrappedPointer{Name: file, Pointer: ptr}
+}
 
-	if err := singleCheckout.RunToPath(p, checkoutTo); err != nil {
-		Exit("Error checking out %v to %q: %v", ref.Sha, checkoutTo, err)
+// checkoutToDirectory returns whether the "--to" argument names a directory
+// into which each of the given paths should be checked out, rather than the
+// destination of a single file.
+func checkoutToDirectory(args []string) bool {
+	if checkoutStdin || len(args) > 1 {
+		return true
+	}
+	if strings.HasSuffix(checkoutTo, "/") || strings.HasSuffix(checkoutTo, string(filepath.Separator)) {
+		return true
+	}
+	stat, err := os.Stat(checkoutTo)
+	return err == nil && stat.IsDir()
+}
+
+// readCheckoutPaths reads a list of NUL-delimited paths from standard input.
+func readCheckoutPaths() []string {
+	requireStdin("The --stdin flag expects a NUL-delimited list of paths.")
+
+	var paths []string
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Split(splitOnNulOrEOF)
+	for scanner.Scan() {
+		if path := scanner.Text(); len(path) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		ExitWithError(err)
+	}
+	return paths
+}
+
+// splitOnNulOrEOF behaves like tools.SplitOnNul, but also returns a final,
+// unterminated path.
+func splitOnNulOrEOF(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = tools.SplitOnNul(data, atEOF)
+	if advance == 0 && atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return advance, token, err
+}
+
+// checkoutToDir checks out the Git LFS objects for each of the given paths
+// into the "--to" directory, preserving their paths relative to the root of
+// the repository. Without a stage, paths may also be directories or patterns,
+// and objects are taken from the tree of the current ref.
+func checkoutToDir(paths []string, stage git.IndexStage) {
+	if len(paths) == 0 {
+		Exit("--to with a directory requires at least one path")
+	}
+
+	singleCheckout := newSingleCheckout(cfg.Git, "")
+	if singleCheckout.Skip() {
+		fmt.Println("Cannot checkout LFS objects, Git LFS is not installed.")
+		return
+	}
+
+	var pointers []*lfs.WrappedPointer
+	if stage == git.IndexStageDefault {
+		ref, err := git.CurrentRef()
+		if err != nil {
+			Panic(err, "Could not checkout")
+		}
+
+		gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+			if err != nil {
+				LoggedError(err, "Scanner error: %s", err)
+				return
+			}
+			pointers = append(pointers, p)
+		})
+		gitscanner.Filter = filepathfilter.New(paths, nil)
+
+		if err := gitscanner.ScanTree(ref.Sha); err != nil {
+			ExitWithError(err)
+		}
+		gitscanner.Close()
+	} else {
+		scanner, err := git.NewObjectScanner(cfg.GitEnv(), cfg.OSEnv())
+		if err != nil {
+			Exit("Could not create object scanner: %v", err)
+		}
+		for _, file := range paths {
+			pointers = append(pointers, conflictPointer(scanner, file, stage))
+		}
+		scanner.Close()
+	}
+
+	failed := 0
+	for _, p := range pointers {
+		to := filepath.Join(checkoutTo, filepath.FromSlash(p.Name))
+		if err := singleCheckout.RunToPath(p, to); err != nil {
+			if errors.IsDownloadDeclinedError(err) {
+				// Don't leave a pointer behind in place of the
+				// content outside of the working tree.
+				os.Remove(to)
+				err = fmt.Errorf("content not available locally, run `git lfs fetch` first")
+			}
+			Error("Error checking out %s to %q: %v", p.Name, to, err)
+			failed++
+		}
 	}
 	singleCheckout.Close()
+
+	if failed > 0 {
+		Exit("Could not check out %d file(s) to %q", failed, checkoutTo)
+	}
 }
 
 func whichCheckout() (stage git.IndexStage, err error) {
@@ -166,5 +396,28 @@ func init() {
 		cmd.Flags().BoolVar(&checkoutOurs, "ours", false, "Checkout our version of a conflicted file")
 		cmd.Flags().BoolVar(&checkoutTheirs, "theirs", false, "Checkout their version of a conflicted file")
 		cmd.Flags().BoolVar(&checkoutBase, "base", false, "Checkout the base version of a conflicted file")
+		cmd.Flags().BoolVar(&checkoutStdin, "stdin", false, "Read NUL-delimited paths to check out from standard input")
+		cmd.Flags().IntVarP(&checkoutJobs, "jobs", "j", 1, "Check out this many files at once")
+		addDiskSpaceForceFlag(cmd)
+		addRecurseSubmodulesFlag(cmd)
 	})
 }
+
+// warnCaseCollisions warns about each of the given files which will be
+// overwritten by another whose path differs only in case, since they refer to
+// the same file on a case-insensitive filesystem.
+func warnCaseCollisions(pointers []*lfs.WrappedPointer) {
+	byName := make(map[string]*lfs.WrappedPointer, len(pointers))
+	names := make([]string, 0, len(pointers))
+	for _, p := range pointers {
+		byName[p.Name] = p
+		names = append(names, p.Name)
+	}
+
+	for _, group := range tools.CaseCollisions(names) {
+		last := byName[group[len(group)-1]]
+		for _, name := range group[:len(group)-1] {
+			Error("warning: %q (%s) will be overwritten by %q (%s), since their paths differ only in case", name, byName[name].Oid, last.Name, last.Oid)
+		}
+	}
+}
@@ -3,13 +3,24 @@ package commands
 import (
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
 
+var (
+	// cleanBatch is a command-line flag belonging to the "git-lfs clean"
+	// command specifying whether to clean many files read from STDIN.
+	cleanBatch = false
+)
+
 // clean cleans an object read from the given `io.Reader`, "from", and writes
 // out a corresponding pointer to the `io.Writer`, "to". If there were any
 // errors encountered along the way, they will be returned immediately if the
@@ -65,6 +76,23 @@ func clean(gf *lfs.GitFilter, to io.Writer, from io.Reader, fileName string, fil
 		ExitWithError(errors.Wrap(err, "Error cleaning LFS object"))
 	}
 
+	if ptr := placeholderPointer(fileName, cleaned.Filename, cleaned.Pointer); ptr != nil {
+		_, err = lfs.EncodePointer(to, ptr)
+		return ptr, err
+	}
+
+	if !meetsTrackingConditions(gf, fileName, cleaned.Filename, cleaned.Pointer) {
+		// Store the content as a plain Git blob instead.
+		f, err := os.Open(cleaned.Filename)
+		if err != nil {
+			ExitWithError(errors.Wrap(err, "Error cleaning LFS object"))
+		}
+		defer f.Close()
+
+		_, err = io.Copy(to, f)
+		return nil, err
+	}
+
 	tmpfile := cleaned.Filename
 	mediafile, err := gf.ObjectPath(cleaned.Oid)
 	if err != nil {
@@ -76,7 +104,16 @@ func clean(gf *lfs.GitFilter, to io.Writer, from io.Reader, fileName string, fil
 			Exit("Files don't match:\n%s\n%s", mediafile, tmpfile)
 		}
 		Debug("%s exists", mediafile)
+	} else if cfg.LFSObjectExists(cleaned.Oid, cleaned.Size) {
+		// The object is held by another backend, such as in a pack.
+		Debug("%s is stored", cleaned.Oid)
 	} else {
+		if err := inspectObject(config.InspectStageClean, fileName, cleaned.Oid, cleaned.Size, func() (io.ReadCloser, error) {
+			return os.Open(tmpfile)
+		}); err != nil {
+			ExitWithError(errors.Wrap(err, "Error cleaning LFS object"))
+		}
+
 		if err := os.Rename(tmpfile, mediafile); err != nil {
 			Panic(err, "Unable to move %s to %s\n", tmpfile, mediafile)
 		}
@@ -88,17 +125,100 @@ func clean(gf *lfs.GitFilter, to io.Writer, from io.Reader, fileName string, fil
 	return cleaned.Pointer, err
 }
 
+// inspectObject runs the inspectors configured for the given stage on the
+// content of the object with the given OID and size, for the file with the
+// given path, and prints the annotations they write. It returns an error if
+// one of them rejected the object.
+func inspectObject(stage, path, oid string, size int64, open func() (io.ReadCloser, error)) error {
+	inspectors := cfg.Inspectors()
+	if len(inspectors) == 0 {
+		return nil
+	}
+
+	annotations, err := lfs.InspectObject(inspectors, stage, path, oid, size, open)
+	for _, annotation := range annotations {
+		Error("Git LFS: %s: %s", path, annotation)
+	}
+	return err
+}
+
+// placeholderPointer returns the pointer staged for the file with the given
+// name if its cleaned content, in tmpfile, is a placeholder written because
+// the pointer's object could not be downloaded, as lfs.missingcontent asks,
+// so that the placeholder is not taken for new content. Otherwise it returns
+// nil.
+func placeholderPointer(fileName, tmpfile string, cleaned *lfs.Pointer) *lfs.Pointer {
+	if len(fileName) == 0 || len(cleaned.Extensions) > 0 || !lfs.IsPlaceholderFile(tmpfile, cleaned) {
+		return nil
+	}
+
+	blob, err := git.StagedBlob(filepath.ToSlash(fileName))
+	if err != nil {
+		return nil
+	}
+	staged, err := lfs.DecodePointer(strings.NewReader(blob))
+	if err != nil || staged.Size != cleaned.Size || staged.Oid == cleaned.Oid {
+		return nil
+	}
+	if cfg.LFSObjectExists(staged.Oid, staged.Size) {
+		return nil
+	}
+
+	tracerx.Printf("clean: %s is a placeholder for %s, keeping its pointer", fileName, staged.Oid)
+	return staged
+}
+
+// meetsTrackingConditions returns whether the cleaned content of the file
+// with the given name, in tmpfile, meets the conditions set by the
+// lfs-min-size and lfs-content-type attributes for it to be stored as a Git
+// LFS object. Content which does not is stored as a plain Git blob. Content
+// changed by extensions is always stored as an object.
+func meetsTrackingConditions(gf *lfs.GitFilter, fileName, tmpfile string, cleaned *lfs.Pointer) bool {
+	if len(fileName) == 0 || len(cleaned.Extensions) > 0 {
+		return true
+	}
+
+	conditions, err := gf.TrackingConditions(fileName)
+	if err != nil {
+		Error("warning: unable to check tracking conditions for %s: %v", fileName, err)
+		return true
+	}
+	if conditions == nil {
+		return true
+	}
+
+	if ok, err := conditions.AllowsFile(tmpfile, cleaned.Size); err != nil || ok {
+		return true
+	}
+	tracerx.Printf("clean: %s does not meet its tracking conditions, storing it in Git", fileName)
+	return false
+}
+
 func cleanCommand(cmd *cobra.Command, args []string) {
 	requireStdin("This command should be run by the Git 'clean' filter")
 	setupRepository()
 	installHooks(false)
 
+	gitfilter := lfs.NewGitFilter(cfg)
+	defer gitfilter.Close()
+
+	if cleanBatch {
+		if len(args) > 0 {
+			ExitWithError(errors.New("fatal: --batch cannot be combined with a path"))
+		}
+
+		filterBatch(func(to io.Writer, from io.Reader, path string, size int64) error {
+			_, err := clean(gitfilter, to, from, path, size)
+			return err
+		})
+		return
+	}
+
 	var fileName string
 	if len(args) > 0 {
 		fileName = args[0]
 	}
 
-	gitfilter := lfs.NewGitFilter(cfg)
 	ptr, err := clean(gitfilter, os.Stdout, os.Stdin, fileName, -1)
 	if err != nil {
 		Error(err.Error())
@@ -110,5 +230,7 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 }
 
 func init() {
-	RegisterCommand("clean", cleanCommand, nil)
+	RegisterCommand("clean", cleanCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&cleanBatch, "batch", "", false, "")
+	})
 }
@@ -119,6 +119,7 @@ func postCloneSubmodules(args []string) error {
 	// inside this instance, since that way we get a clean env in that subrepo
 	cmd := subprocess.ExecCommand("git", "submodule", "foreach", "--recursive",
 		"git lfs pull")
+	cmd.Env = append(cmd.Env, getAPIClient().SSHAuthSessionEnv()...)
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configValidate bool
+)
+
+// configCommand lists the settings of Git LFS's keys which are in effect, and
+// where each was set, or, with --validate, lists the problems with them.
+func configCommand(cmd *cobra.Command, args []string) {
+	settings, err := cfg.Settings()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	if !configValidate {
+		for _, s := range config.EffectiveSettings(settings) {
+			if config.IsLFSKey(s.Key) {
+				Print("%s\t%s", s.Origin, s)
+			}
+		}
+		return
+	}
+
+	ok := true
+	for _, p := range config.Validate(settings) {
+		Print("%s", p)
+		ok = false
+	}
+	for _, msg := range unreachableEndpoints() {
+		Print("%s", msg)
+		ok = false
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+	Print("Git LFS configuration OK")
+}
+
+// unreachableEndpoints returns a description of each of the endpoints that
+// objects are downloaded from and uploaded to to which a request could not be
+// sent. Any response, even an error, means the server is reachable. SSH
+// endpoints are not checked, since that would mean authenticating.
+func unreachableEndpoints() []string {
+	var unreachable []string
+	checked := make(map[string]bool)
+	for _, operation := range []string{"download", "upload"} {
+		remote := cfg.Remote()
+		if operation == "upload" {
+			remote = cfg.PushRemote()
+		}
+
+		e := getAPIClient().Endpoints.Endpoint(operation, remote)
+		if len(e.Url) == 0 || checked[e.Url] || len(e.SSHMetadata.UserAndHost) > 0 {
+			continue
+		}
+		checked[e.Url] = true
+		if !strings.HasPrefix(e.Url, "http://") && !strings.HasPrefix(e.Url, "https://") {
+			continue
+		}
+
+		req, err := http.NewRequest("GET", e.Url, nil)
+		if err == nil {
+			var res *http.Response
+			if res, err = getAPIClient().Do(req); res != nil {
+				res.Body.Close()
+				continue
+			}
+		}
+		unreachable = append(unreachable, fmt.Sprintf("endpoint %s (%s): unreachable: %v", e.Url, operation, err))
+	}
+	return unreachable
+}
+
+func init() {
+	RegisterCommand("config", configCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVar(&configValidate, "validate", false, "Check the settings for problems")
+	})
+}
@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/rubyist/tracerx"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonSocketArg string
+)
+
+// daemonShutdownTimeout is how long "git lfs daemon" waits for clients to
+// disconnect once it has been asked to shut down.
+const daemonShutdownTimeout = 5 * time.Second
+
+// DaemonObject identifies an object in requests to "git lfs daemon".
+type DaemonObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// DaemonStatusArgs are the arguments of the Daemon.Status method, which has
+// none.
+type DaemonStatusArgs struct{}
+
+// DaemonStatusReply is the result of the Daemon.Status method.
+type DaemonStatusReply struct {
+	Pid       int       `json:"pid"`
+	Root      string    `json:"root"`
+	Remote    string    `json:"remote"`
+	StartedAt time.Time `json:"started_at"`
+	// Queued is the number of fetches and prunes waiting to be run, and
+	// Running is whether a fetch or prune is in progress.
+	Queued  int  `json:"queued"`
+	Running bool `json:"running"`
+	// Fetched and Failed are the numbers of objects fetched, or which
+	// could not be, since the daemon started.
+	Fetched int `json:"fetched"`
+	Failed  int `json:"failed"`
+}
+
+// DaemonFetchArgs are the arguments of the Daemon.Fetch method.
+type DaemonFetchArgs struct {
+	// Objects are objects to fetch, and Refs are refs whose objects are
+	// fetched.
+	Objects []DaemonObject `json:"objects"`
+	Refs    []string       `json:"refs"`
+	// Wait is whether to reply once the objects have been fetched, rather
+	// than once they are queued.
+	Wait bool `json:"wait"`
+}
+
+// DaemonFetchReply is the result of the Daemon.Fetch method.
+type DaemonFetchReply struct {
+	// Errors holds an error for each object which could not be fetched,
+	// if Wait was set.
+	Errors []string `json:"errors"`
+}
+
+// DaemonHasArgs are the arguments of the Daemon.Has method.
+type DaemonHasArgs struct {
+	Oids []string `json:"oids"`
+}
+
+// DaemonHasReply is the result of the Daemon.Has method.
+type DaemonHasReply struct {
+	// Present is whether each of the requested objects is in local
+	// storage.
+	Present map[string]bool `json:"present"`
+}
+
+// DaemonPruneArgs are the arguments of the Daemon.Prune method.
+type DaemonPruneArgs struct {
+	DryRun       bool `json:"dry_run"`
+	VerifyRemote bool `json:"verify_remote"`
+}
+
+// DaemonPruneReply is the result of the Daemon.Prune method.
+type DaemonPruneReply struct {
+	// Output is the output of "git lfs prune".
+	Output string `json:"output"`
+}
+
+// Daemon is the receiver of the methods "git lfs daemon" serves. Fetches and
+// prunes are run one at a time, in the order they are requested, so that
+// clients sharing a daemon do not transfer the same objects twice or prune
+// objects as they are fetched.
+type Daemon struct {
+	listener  net.Listener
+	remote    string
+	startedAt time.Time
+	work      chan *daemonJob
+
+	mu      sync.Mutex
+	queued  int
+	running bool
+	fetched int
+	failed  int
+}
+
+// daemonJob is a fetch or prune waiting to be run by the daemon. Its result is
+// sent on done, which is buffered so that nobody need wait for it.
+type daemonJob struct {
+	run  func() error
+	done chan error
+}
+
+func daemonCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	path := daemonSocketArg
+	if len(path) == 0 {
+		path = filepath.Join(cfg.LFSStorageDir(), "daemon.sock")
+	}
+
+	listener, err := listenDaemonSocket(path)
+	if err != nil {
+		ExitWithError(err)
+	}
+	defer os.Remove(path)
+
+	d := &Daemon{
+		listener:  listener,
+		remote:    cfg.Remote(),
+		startedAt: time.Now(),
+		work:      make(chan *daemonJob, 64),
+	}
+	server := rpc.NewServer()
+	if err := server.Register(d); err != nil {
+		ExitWithError(err)
+	}
+	go d.runJobs()
+
+	Print("git lfs daemon: listening on %s", path)
+
+	var conns sync.WaitGroup
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// The listener is closed by Daemon.Shutdown.
+			tracerx.Printf("daemon: stopped accepting connections: %v", err)
+			break
+		}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}()
+	}
+
+	// Give clients a chance to read their replies, including that to the
+	// shutdown request, before exiting.
+	closed := make(chan struct{})
+	go func() {
+		conns.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(daemonShutdownTimeout):
+	}
+}
+
+// listenDaemonSocket listens on the Unix socket at the given path, which is
+// only accessible by the current user. It returns an error if another daemon
+// is already listening there, and replaces the socket if one is not.
+func listenDaemonSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.Errorf("git lfs daemon is already running on %s", path)
+	}
+	os.Remove(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to listen on %s", path)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// runJobs runs the queued fetches and prunes, one at a time.
+func (d *Daemon) runJobs() {
+	for job := range d.work {
+		d.mu.Lock()
+		d.queued--
+		d.running = true
+		d.mu.Unlock()
+
+		job.done <- job.run()
+
+		d.mu.Lock()
+		d.running = false
+		d.mu.Unlock()
+	}
+}
+
+// enqueue queues the given function to be run after those already queued, and
+// returns a channel on which its result is sent.
+func (d *Daemon) enqueue(run func() error) <-chan error {
+	job := &daemonJob{run: run, done: make(chan error, 1)}
+
+	d.mu.Lock()
+	d.queued++
+	d.mu.Unlock()
+
+	d.work <- job
+	return job.done
+}
+
+// Status reports what the daemon is doing.
+func (d *Daemon) Status(args *DaemonStatusArgs, reply *DaemonStatusReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	*reply = DaemonStatusReply{
+		Pid:       os.Getpid(),
+		Root:      cfg.LocalWorkingDir(),
+		Remote:    d.remote,
+		StartedAt: d.startedAt,
+		Queued:    d.queued,
+		Running:   d.running,
+		Fetched:   d.fetched,
+		Failed:    d.failed,
+	}
+	return nil
+}
+
+// Fetch queues the given objects, and the objects of the given refs, to be
+// downloaded into local storage.
+func (d *Daemon) Fetch(args *DaemonFetchArgs, reply *DaemonFetchReply) error {
+	for _, o := range args.Objects {
+		if err := checkDaemonOid(o.Oid); err != nil {
+			return err
+		}
+	}
+
+	var errs []string
+	done := d.enqueue(func() error {
+		errs = d.fetch(args.Objects, args.Refs)
+		return nil
+	})
+
+	if args.Wait {
+		<-done
+		reply.Errors = errs
+	}
+	return nil
+}
+
+// fetch downloads the given objects, and the objects of the given refs, which
+// are not already in local storage, and returns an error message for each
+// which could not be.
+func (d *Daemon) fetch(objects []DaemonObject, refs []string) []string {
+	var errs []string
+
+	pointers := make([]*lfs.WrappedPointer, 0, len(objects))
+	for _, o := range objects {
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Name:    o.Oid,
+			Pointer: lfs.NewPointer(o.Oid, o.Size, nil),
+		})
+	}
+	for _, ref := range refs {
+		refPointers, err := pointersToFetchForRef(ref, nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		pointers = append(pointers, refPointers...)
+	}
+
+	q := newDownloadQueue(getTransferManifestOperationRemote("download", d.remote), d.remote)
+	var fetched int
+	seen := make(map[string]bool, len(pointers))
+	for _, p := range pointers {
+		if seen[p.Oid] || cfg.LFSObjectExists(p.Oid, p.Size) {
+			continue
+		}
+		seen[p.Oid] = true
+		fetched++
+
+		tracerx.Printf("daemon: fetch %v [%v]", p.Name, p.Oid)
+		q.Add(downloadTransfer(p))
+	}
+	q.Wait()
+
+	qerrs := q.Errors()
+	for _, err := range qerrs {
+		errs = append(errs, err.Error())
+	}
+
+	d.mu.Lock()
+	d.fetched += fetched - len(qerrs)
+	d.failed += len(qerrs)
+	d.mu.Unlock()
+	return errs
+}
+
+// Has reports whether each of the given objects is in local storage.
+func (d *Daemon) Has(args *DaemonHasArgs, reply *DaemonHasReply) error {
+	for _, oid := range args.Oids {
+		if err := checkDaemonOid(oid); err != nil {
+			return err
+		}
+	}
+
+	reply.Present = make(map[string]bool, len(args.Oids))
+	for _, oid := range args.Oids {
+		present := false
+		if r, err := cfg.Filesystem().OpenObject(oid); err == nil {
+			r.Close()
+			present = true
+		}
+		reply.Present[oid] = present
+	}
+	return nil
+}
+
+// checkDaemonOid returns an error if the given OID, sent by a client, is not
+// the OID of a Git LFS object, so that it is never used in a path.
+func checkDaemonOid(oid string) error {
+	if !objectOidRE.MatchString(oid) {
+		return errors.Errorf("invalid OID: %q", oid)
+	}
+	return nil
+}
+
+// Prune runs "git lfs prune" once the queued fetches have finished.
+func (d *Daemon) Prune(args *DaemonPruneArgs, reply *DaemonPruneReply) error {
+	pruneArgs := []string{"prune"}
+	if args.DryRun {
+		pruneArgs = append(pruneArgs, "--dry-run")
+	}
+	if args.VerifyRemote {
+		pruneArgs = append(pruneArgs, "--verify-remote")
+	}
+
+	return <-d.enqueue(func() error {
+		// Pruning runs in a separate process, since "git lfs prune"
+		// exits if it cannot determine which objects to retain.
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := subprocess.ExecCommand(exe, pruneArgs...)
+		cmd.Dir = cfg.LocalWorkingDir()
+		out, err := cmd.CombinedOutput()
+		reply.Output = string(out)
+		if err != nil {
+			return errors.Wrap(err, "git lfs prune")
+		}
+		return nil
+	})
+}
+
+// Shutdown stops the daemon once the fetches and prunes already queued have
+// finished.
+func (d *Daemon) Shutdown(args *struct{}, reply *struct{}) error {
+	return <-d.enqueue(func() error {
+		return d.listener.Close()
+	})
+}
+
+func init() {
+	RegisterCommand("daemon", daemonCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&daemonSocketArg, "socket", "s", "", "Listen on the given Unix socket")
+	})
+}
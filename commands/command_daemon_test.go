@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonRejectsInvalidOids(t *testing.T) {
+	d := &Daemon{}
+	for _, oid := range []string{"ab", "../..", "../../../../etc/passwd"} {
+		var hasReply DaemonHasReply
+		err := d.Has(&DaemonHasArgs{Oids: []string{oid}}, &hasReply)
+		if assert.NotNil(t, err, "Has(%q)", oid) {
+			assert.Contains(t, err.Error(), "invalid OID")
+		}
+
+		var fetchReply DaemonFetchReply
+		err = d.Fetch(&DaemonFetchArgs{Objects: []DaemonObject{{Oid: oid, Size: 1}}}, &fetchReply)
+		if assert.NotNil(t, err, "Fetch(%q)", oid) {
+			assert.Contains(t, err.Error(), "invalid OID")
+		}
+	}
+}
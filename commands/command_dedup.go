@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 
 	"github.com/git-lfs/git-lfs/v2/config"
@@ -10,12 +12,16 @@ import (
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
 	"github.com/spf13/cobra"
 )
 
 var (
 	dedupFlags = struct {
-		test bool
+		test    bool
+		analyze bool
+		dryRun  bool
+		yes     bool
 	}{}
 	dedupStats = &struct {
 		totalProcessedCount int64
@@ -45,6 +51,10 @@ func dedupCommand(cmd *cobra.Command, args []string) {
 		dedupTestCommand(cmd, args)
 		return
 	}
+	if dedupFlags.analyze {
+		dedupAnalyzeCommand(cmd, args)
+		return
+	}
 
 	setupRepository()
 	if gitDir, err := git.GitDir(); err != nil {
@@ -64,12 +74,38 @@ func dedupCommand(cmd *cobra.Command, args []string) {
 	}
 
 	// We assume working tree is clean.
+	var pointers []*lfs.WrappedPointer
+	var totalSize int64
 	gitScanner := lfs.NewGitScanner(config.New(), func(p *lfs.WrappedPointer, err error) {
 		if err != nil {
 			Exit("Could not scan for Git LFS tree: %s", err)
 			return
 		}
+		pointers = append(pointers, p)
+		totalSize += p.Size
+	})
+	defer gitScanner.Close()
+
+	if err := gitScanner.ScanTree("HEAD"); err != nil {
+		ExitWithError(err)
+	}
+
+	affected := &preview{
+		summary:  fmt.Sprintf("dedup: %d file(s) would be de-duplicated (%s)", len(pointers), humanize.FormatBytes(uint64(totalSize))),
+		question: "dedup: replace these files with clones of their objects?",
+		columns:  []string{"PATH", "SIZE"},
+	}
+	for _, p := range pointers {
+		affected.add(p.Name, humanize.FormatBytes(uint64(p.Size)))
+	}
+	if !affected.confirm(os.Stdin, OutputWriter, dedupFlags.dryRun, dedupFlags.yes, false) {
+		if !dedupFlags.dryRun {
+			Exit("dedup: aborted, no files were changed")
+		}
+		return
+	}
 
+	for _, p := range pointers {
 		if success, err := dedup(p); err != nil {
 			Error("Skipped: %s (Size: %d)\n          %s", p.Name, p.Size, err)
 		} else if !success {
@@ -80,11 +116,6 @@ func dedupCommand(cmd *cobra.Command, args []string) {
 			atomic.AddInt64(&dedupStats.totalProcessedCount, 1)
 			atomic.AddInt64(&dedupStats.totalProcessedSize, p.Size)
 		}
-	})
-	defer gitScanner.Close()
-
-	if err := gitScanner.ScanTree("HEAD"); err != nil {
-		ExitWithError(err)
 	}
 
 	Print("\n\nSuccessfully finished.\n"+
@@ -94,6 +125,122 @@ func dedupCommand(cmd *cobra.Command, args []string) {
 		dedupStats.totalProcessedCount)
 }
 
+// dedupAnalyzeCommand reports, without changing anything, the objects which
+// are checked out at more than one path, the objects which are identical
+// across local branches and tags, and how much space would be saved by
+// replacing the checked out files with clones of their objects.
+func dedupAnalyzeCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	// Objects checked out at HEAD, by OID, with each path at which they
+	// are checked out.
+	var oids []string
+	checkedOut := make(map[string][]*lfs.WrappedPointer)
+	scanDedupTree("HEAD", func(p *lfs.WrappedPointer) {
+		if _, ok := checkedOut[p.Oid]; !ok {
+			oids = append(oids, p.Oid)
+		}
+		checkedOut[p.Oid] = append(checkedOut[p.Oid], p)
+	})
+
+	copies := &preview{columns: []string{"OID", "SIZE", "PATHS"}}
+	var copiesCount int
+	var copiesSize int64
+	for _, oid := range oids {
+		ps := checkedOut[oid]
+		if len(ps) < 2 {
+			continue
+		}
+		names := make([]string, 0, len(ps))
+		for _, p := range ps {
+			names = append(names, p.Name)
+		}
+		copies.add(oid[:10], humanize.FormatBytes(uint64(ps[0].Size)), strings.Join(names, ", "))
+		copiesCount += len(ps) - 1
+		copiesSize += int64(len(ps)-1) * ps[0].Size
+	}
+	copies.summary = fmt.Sprintf("dedup: %d object(s) are checked out at more than one path, with %d extra copies (%s)",
+		len(copies.rows), copiesCount, humanize.FormatBytes(uint64(copiesSize)))
+	copies.render(OutputWriter, false)
+
+	refs, err := git.LocalRefs()
+	if err != nil {
+		ExitWithError(err)
+	}
+	var refOids []string
+	sizes := make(map[string]int64)
+	refNames := make(map[string][]string)
+	for _, ref := range refs {
+		seen := make(map[string]bool)
+		scanDedupTree(ref.Sha, func(p *lfs.WrappedPointer) {
+			if seen[p.Oid] {
+				return
+			}
+			seen[p.Oid] = true
+			if _, ok := refNames[p.Oid]; !ok {
+				refOids = append(refOids, p.Oid)
+			}
+			sizes[p.Oid] = p.Size
+			refNames[p.Oid] = append(refNames[p.Oid], ref.Name)
+		})
+	}
+
+	shared := &preview{columns: []string{"OID", "SIZE", "REFS"}}
+	var sharedSize int64
+	for _, oid := range refOids {
+		if len(refNames[oid]) < 2 {
+			continue
+		}
+		shared.add(oid[:10], humanize.FormatBytes(uint64(sizes[oid])), strings.Join(refNames[oid], ", "))
+		sharedSize += sizes[oid]
+	}
+	shared.summary = fmt.Sprintf("dedup: %d object(s) are identical across %d local branch(es) and tag(s), and stored once (%s)",
+		len(shared.rows), len(refs), humanize.FormatBytes(uint64(sharedSize)))
+	shared.render(OutputWriter, false)
+
+	// Each checked out file whose object is in the local storage directory
+	// is a full copy of it, which a clone would share with the object.
+	savings := &preview{columns: []string{"PATH", "SIZE"}}
+	var savingsSize int64
+	for _, oid := range oids {
+		for _, p := range checkedOut[oid] {
+			if !cfg.LFSObjectExists(p.Oid, p.Size) {
+				continue
+			}
+			if fi, err := os.Stat(filepath.Join(cfg.LocalWorkingDir(), p.Name)); err != nil || fi.Size() != p.Size {
+				continue
+			}
+			savings.add(p.Name, humanize.FormatBytes(uint64(p.Size)))
+			savingsSize += p.Size
+		}
+	}
+	savings.summary = fmt.Sprintf("dedup: %d file(s) could be replaced with clones of their objects, saving up to %s",
+		len(savings.rows), humanize.FormatBytes(uint64(savingsSize)))
+	savings.render(OutputWriter, false)
+
+	if len(cfg.Extensions()) > 0 {
+		Print("dedup: Git LFS extensions are configured, so files can not be replaced with clones")
+	} else if supported, err := tools.CheckCloneFileSupported(cfg.TempDir()); err != nil || !supported {
+		Print("dedup: this file system does not support clones, so no space would be saved")
+	}
+}
+
+// scanDedupTree calls fn with each pointer in the tree of the given ref.
+func scanDedupTree(ref string, fn func(p *lfs.WrappedPointer)) {
+	gitScanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			Exit("Could not scan for Git LFS tree: %s", err)
+			return
+		}
+		fn(p)
+	})
+	defer gitScanner.Close()
+
+	if err := gitScanner.ScanTree(ref); err != nil {
+		ExitWithError(err)
+	}
+}
+
 // dedup executes
 // Precondition: working tree MUST clean. We can replace working tree files from mediafile safely.
 func dedup(p *lfs.WrappedPointer) (success bool, err error) {
@@ -132,5 +279,8 @@ func dedup(p *lfs.WrappedPointer) (success bool, err error) {
 func init() {
 	RegisterCommand("dedup", dedupCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&dedupFlags.test, "test", "t", false, "test")
+		cmd.Flags().BoolVarP(&dedupFlags.analyze, "analyze", "a", false, "Report duplicate objects and potential savings without changing anything")
+		cmd.Flags().BoolVarP(&dedupFlags.dryRun, "dry-run", "d", false, "Don't change anything, just report")
+		cmd.Flags().BoolVarP(&dedupFlags.yes, "yes", "y", false, "Don't ask before changing anything")
 	})
 }
@@ -0,0 +1,303 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duJSONArg   bool
+	duRemoteArg bool
+)
+
+// duUsage is a number of objects and their total size in bytes.
+type duUsage struct {
+	Objects int   `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+func (u *duUsage) add(size int64) {
+	u.Objects++
+	u.Size += size
+}
+
+func (u duUsage) String() string {
+	return fmt.Sprintf("%s, %d object(s)", humanize.FormatBytes(uint64(u.Size)), u.Objects)
+}
+
+type duRefUsage struct {
+	Ref string `json:"ref"`
+	duUsage
+}
+
+type duPathUsage struct {
+	Path string `json:"path"`
+	duUsage
+}
+
+type duAgeUsage struct {
+	Age string `json:"age"`
+	duUsage
+}
+
+type duRemoteUsage struct {
+	Remote string `json:"remote"`
+	*lfsapi.StorageUsage
+}
+
+// duReport is the output of "git lfs du", in the form written by --json.
+type duReport struct {
+	Local       duUsage        `json:"local"`
+	Reclaimable duUsage        `json:"reclaimable"`
+	Refs        []*duRefUsage  `json:"refs"`
+	Paths       []*duPathUsage `json:"paths"`
+	Ages        []*duAgeUsage  `json:"ages"`
+	Remote      *duRemoteUsage `json:"remote,omitempty"`
+	RemoteError string         `json:"remote_error,omitempty"`
+}
+
+// duAges are the buckets into which local objects are grouped by how long ago
+// they were last written.
+var duAges = []struct {
+	label  string
+	maxAge time.Duration
+}{
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"year", 365 * 24 * time.Hour},
+	{"older", 0},
+}
+
+func duCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	objects := make(map[string]int64)
+	report := &duReport{}
+	err := cfg.EachLFSObject(func(obj fs.Object) error {
+		objects[obj.Oid] = obj.Size
+		report.Local.add(obj.Size)
+		return nil
+	})
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "could not read local objects"))
+	}
+
+	report.Refs = []*duRefUsage{}
+	report.Paths = []*duPathUsage{}
+	if head, err := git.CurrentRef(); err == nil {
+		report.Reclaimable = duReclaimable()
+		report.Refs = duRefs(args, objects)
+		report.Paths = duPaths(head, objects)
+	} else if len(args) > 0 {
+		Exit("Invalid ref argument: %v: there are no commits yet", args)
+	}
+	report.Ages = duAgesOf(objects)
+
+	if duRemoteArg {
+		remote := cfg.Remote()
+		usage, err := getAPIClient().StorageUsage(remote)
+		if errors.IsNotImplementedError(err) {
+			report.RemoteError = "the server does not report its storage usage"
+		} else if err != nil {
+			report.RemoteError = err.Error()
+		} else {
+			report.Remote = &duRemoteUsage{Remote: remote, StorageUsage: usage}
+		}
+	}
+
+	if duJSONArg {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+	printDuReport(report)
+}
+
+// duReclaimable returns the usage of the local objects which "git lfs prune"
+// would remove with the current configuration.
+func duReclaimable() duUsage {
+	progressChan := make(PruneProgressChan, 100)
+	go func() {
+		for range progressChan {
+		}
+	}()
+	localObjects, retained, _ := pruneScan(lfs.NewFetchPruneConfig(cfg.Git), false, progressChan)
+	close(progressChan)
+
+	var usage duUsage
+	for _, obj := range localObjects {
+		if !retained.Contains(obj.Oid) {
+			usage.add(obj.Size)
+		}
+	}
+	return usage
+}
+
+// duRefs returns the usage of the local objects referenced by the trees of the
+// given refs, or of the local branches and tags if none are given.
+func duRefs(args []string, objects map[string]int64) []*duRefUsage {
+	var refs []*git.Ref
+	if len(args) > 0 {
+		resolved, err := git.ResolveRefs(args)
+		if err != nil {
+			Panic(err, "Invalid ref argument: %v", args)
+		}
+		refs = resolved
+	} else {
+		all, err := git.LocalRefs()
+		if err != nil {
+			Panic(err, "Could not list refs")
+		}
+		for _, ref := range all {
+			if ref.Type == git.RefTypeLocalBranch || ref.Type == git.RefTypeLocalTag {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	usages := make([]*duRefUsage, 0, len(refs))
+	for _, ref := range refs {
+		pointers, err := pointersToFetchForRef(ref.Sha, nil)
+		if err != nil {
+			Panic(err, "Could not scan for Git LFS files")
+		}
+
+		usage := &duRefUsage{Ref: ref.Refspec()}
+		seen := tools.NewStringSet()
+		for _, p := range pointers {
+			if size, ok := objects[p.Oid]; ok && seen.Add(p.Oid) {
+				usage.add(size)
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// duPaths returns the usage of the local objects referenced by the tree of
+// the given HEAD ref, grouped by the top-level directory of the files referencing them, or
+// by file for files in the root of the repository.
+func duPaths(head *git.Ref, objects map[string]int64) []*duPathUsage {
+	pointers, err := pointersToFetchForRef(head.Sha, nil)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	byPath := make(map[string]*duPathUsage)
+	seen := make(map[string]tools.StringSet)
+	for _, p := range pointers {
+		size, ok := objects[p.Oid]
+		if !ok {
+			continue
+		}
+
+		path := p.Name
+		if i := strings.Index(path, "/"); i >= 0 {
+			path = path[:i+1]
+		}
+		if _, ok := byPath[path]; !ok {
+			byPath[path] = &duPathUsage{Path: path}
+			seen[path] = tools.NewStringSet()
+		}
+		if seen[path].Add(p.Oid) {
+			byPath[path].add(size)
+		}
+	}
+
+	usages := make([]*duPathUsage, 0, len(byPath))
+	for _, usage := range byPath {
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Size != usages[j].Size {
+			return usages[i].Size > usages[j].Size
+		}
+		return usages[i].Path < usages[j].Path
+	})
+	return usages
+}
+
+// duAgesOf returns the usage of the local objects grouped by the duAges bucket
+// of the time since each was last written.
+func duAgesOf(objects map[string]int64) []*duAgeUsage {
+	usages := make([]*duAgeUsage, 0, len(duAges))
+	for _, age := range duAges {
+		usages = append(usages, &duAgeUsage{Age: age.label})
+	}
+
+	for oid, size := range objects {
+		fi, err := os.Stat(tools.LongPath(cfg.Filesystem().ObjectPathname(oid)))
+		if err != nil {
+			continue
+		}
+		age := time.Since(fi.ModTime())
+		for i, bucket := range duAges {
+			if bucket.maxAge == 0 || age < bucket.maxAge {
+				usages[i].add(size)
+				break
+			}
+		}
+	}
+	return usages
+}
+
+func printDuReport(report *duReport) {
+	Print("Local storage: %s", report.Local)
+	Print("  reclaimable by prune: %s", report.Reclaimable)
+
+	if report.Remote != nil {
+		remote := report.Remote
+		line := humanize.FormatBytes(uint64(remote.Size))
+		if remote.Quota > 0 {
+			line += fmt.Sprintf(" of %s quota", humanize.FormatBytes(uint64(remote.Quota)))
+		}
+		line += fmt.Sprintf(", %d object(s)", remote.Objects)
+		Print("Remote storage (%s): %s", remote.Remote, line)
+	} else if len(report.RemoteError) > 0 {
+		Print("Remote storage: not available: %s", report.RemoteError)
+	}
+
+	if len(report.Refs) > 0 {
+		Print("\nBy ref:")
+		for _, usage := range report.Refs {
+			Print("  %s\t%s", usage.Ref, usage.duUsage)
+		}
+	}
+
+	if len(report.Paths) > 0 {
+		Print("\nBy path in HEAD:")
+		for _, usage := range report.Paths {
+			Print("  %s\t%s", usage.Path, usage.duUsage)
+		}
+	}
+
+	Print("\nBy age:")
+	for _, usage := range report.Ages {
+		label := "within a " + usage.Age
+		if usage.Age == "older" {
+			label = "older"
+		}
+		Print("  %s\t%s", label, usage.duUsage)
+	}
+}
+
+func init() {
+	RegisterCommand("du", duCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVar(&duJSONArg, "json", false, "Print the usage as JSON")
+		cmd.Flags().BoolVar(&duRemoteArg, "remote", false, "Also ask the server how much storage it uses")
+	})
+}
@@ -3,12 +3,15 @@ package commands
 import (
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tools"
 	"github.com/git-lfs/git-lfs/v2/tq"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
@@ -18,6 +21,13 @@ var (
 	fetchRecentArg bool
 	fetchAllArg    bool
 	fetchPruneArg  bool
+
+	fetchManifestArg      string
+	fetchVerifyArg        bool
+	fetchWriteManifestArg string
+
+	fetchIncludeFromArg []string
+	fetchExcludeFromArg []string
 )
 
 func getIncludeExcludeArgs(cmd *cobra.Command) (include, exclude *string) {
@@ -33,6 +43,45 @@ func getIncludeExcludeArgs(cmd *cobra.Command) (include, exclude *string) {
 	return
 }
 
+// fetchIncludeExcludePaths returns the patterns of the paths to fetch and not
+// to fetch. Those given with --include and read from the files given with
+// --include-from, in that order, take the place of lfs.fetchinclude, and
+// likewise for excludes. It also returns whether any were given.
+func fetchIncludeExcludePaths(cmd *cobra.Command) (include, exclude []string, given bool) {
+	checkSubmodulePathArgs(cmd)
+
+	includeArg, excludeArg := getIncludeExcludeArgs(cmd)
+	include, exclude = determineIncludeExcludePaths(cfg, includeArg, excludeArg, true)
+
+	if len(fetchIncludeFromArg) > 0 && includeArg == nil {
+		include = nil
+	}
+	include = append(include, readFetchPatternFiles(fetchIncludeFromArg)...)
+
+	if len(fetchExcludeFromArg) > 0 && excludeArg == nil {
+		exclude = nil
+	}
+	exclude = append(exclude, readFetchPatternFiles(fetchExcludeFromArg)...)
+
+	given = includeArg != nil || excludeArg != nil ||
+		len(fetchIncludeFromArg) > 0 || len(fetchExcludeFromArg) > 0
+	return include, exclude, given
+}
+
+// readFetchPatternFiles returns the patterns in the files with the given
+// names.
+func readFetchPatternFiles(names []string) []string {
+	var patterns []string
+	for _, name := range names {
+		p, err := readPatternFile(name)
+		if err != nil {
+			ExitWithError(err)
+		}
+		patterns = append(patterns, p...)
+	}
+	return patterns
+}
+
 func fetchCommand(cmd *cobra.Command, args []string) {
 	setupRepository()
 
@@ -45,6 +94,28 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	include, exclude, filterGiven := fetchIncludeExcludePaths(cmd)
+
+	if len(fetchManifestArg) > 0 {
+		if len(args) > 1 || fetchAllArg || fetchRecentArg || filterGiven {
+			Exit("Cannot combine --manifest with refs, --all, --recent, --include or --exclude")
+		}
+		if len(fetchWriteManifestArg) > 0 {
+			Exit("Cannot combine --manifest with --write-manifest")
+		}
+		if recurseSubmodulesArg {
+			Exit("Cannot combine --manifest with --recurse-submodules")
+		}
+		if !fetchFromManifest(fetchManifestArg, fetchVerifyArg) {
+			c := getAPIClient()
+			e := c.Endpoints.Endpoint("download", cfg.Remote())
+			Exit("error: failed to fetch some objects from '%s'", e.Url)
+		}
+		return
+	} else if fetchVerifyArg {
+		Exit("--verify requires --manifest")
+	}
+
 	if len(args) > 1 {
 		resolvedrefs, err := git.ResolveRefs(args[1:])
 		if err != nil {
@@ -59,18 +130,26 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		refs = []*git.Ref{ref}
 	}
 
+	if len(fetchWriteManifestArg) > 0 {
+		if len(refs) != 1 || fetchAllArg || fetchRecentArg || filterGiven {
+			Exit("--write-manifest requires a single ref, and cannot be combined with --all, --recent, --include or --exclude")
+		}
+		if recurseSubmodulesArg {
+			Exit("Cannot combine --write-manifest with --recurse-submodules")
+		}
+	}
+
 	success := true
 	gitscanner := lfs.NewGitScanner(cfg, nil)
 	defer gitscanner.Close()
 
-	include, exclude := getIncludeExcludeArgs(cmd)
 	fetchPruneCfg := lfs.NewFetchPruneConfig(cfg.Git)
 
 	if fetchAllArg {
 		if fetchRecentArg {
 			Exit("Cannot combine --all with --recent")
 		}
-		if include != nil || exclude != nil {
+		if filterGiven {
 			Exit("Cannot combine --all with --include or --exclude")
 		}
 		if len(cfg.FetchIncludePaths()) > 0 || len(cfg.FetchExcludePaths()) > 0 {
@@ -88,7 +167,7 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		}
 
 	} else { // !all
-		filter := buildFilepathFilter(cfg, include, exclude, true)
+		filter := filepathfilter.New(include, exclude)
 
 		// Fetch refs sequentially per arg order; duplicates in later refs will be ignored
 		for _, ref := range refs {
@@ -106,14 +185,27 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 	if fetchPruneArg {
 		verify := fetchPruneCfg.PruneVerifyRemoteAlways
 		// no dry-run or verbose options in fetch, assume false
-		prune(fetchPruneCfg, verify, false, false)
+		unlock := acquireOperationLock("migrate")
+		prune(fetchPruneCfg, verify, false, false, true)
+		unlock()
 	}
+	limitStorage()
 
 	if !success {
 		c := getAPIClient()
 		e := c.Endpoints.Endpoint("download", cfg.Remote())
 		Exit("error: failed to fetch some objects from '%s'", e.Url)
 	}
+
+	if len(fetchWriteManifestArg) > 0 {
+		if err := writeFetchManifest(fetchWriteManifestArg, refs[0]); err != nil {
+			Exit("fatal: could not write manifest %q: %v", fetchWriteManifestArg, err)
+		}
+		Print("fetch: Wrote manifest for %s to %s", refs[0].Refspec(), fetchWriteManifestArg)
+		adviseIgnoreArtifact(fetchWriteManifestArg)
+	}
+
+	runInSubmodules(cmd)
 }
 
 func pointersToFetchForRef(ref string, filter *filepathfilter.Filter) ([]*lfs.WrappedPointer, error) {
@@ -158,6 +250,7 @@ func pointersToFetchForRefs(refs []string) ([]*lfs.WrappedPointer, error) {
 
 	logger := tasklog.NewLogger(OutputWriter,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	logger.Enqueue(task)
 	var numObjs int64
@@ -286,6 +379,7 @@ func scanAll() []*lfs.WrappedPointer {
 
 	logger := tasklog.NewLogger(OutputWriter,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	logger.Enqueue(task)
 	var numObjs int64
@@ -324,12 +418,40 @@ func scanAll() []*lfs.WrappedPointer {
 // Fetch and report completion of each OID to a channel (optional, pass nil to skip)
 // Returns true if all completed with no errors, false if errors were written to stderr/log
 func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter, out chan<- *lfs.WrappedPointer) bool {
-	ready, pointers, meter := readyAndMissingPointers(allpointers, filter)
-	q := newDownloadQueue(
+	bootstrapObjects(allpointers)
+	cache := newMissingCache(getAPIClient().Endpoints.Endpoint("download", cfg.Remote()).Url)
+
+	ready, pointers, knownMissing, meter := readyAndMissingPointers(allpointers, filter, cache)
+	checkDiskSpace(downloadSize(pointers), 0)
+
+	var missingMu sync.Mutex
+	missing := make([]string, 0, len(knownMissing))
+	for _, p := range knownMissing {
+		missing = append(missing, p.Oid)
+	}
+
+	q := trackQueue(newDownloadQueue(
 		getTransferManifestOperationRemote("download", cfg.Remote()),
 		cfg.Remote(), tq.WithProgress(meter),
-	)
+		tq.WithMissingCallback(func(oid string) {
+			cache.Add(oid)
+
+			missingMu.Lock()
+			missing = append(missing, oid)
+			missingMu.Unlock()
+		}),
+	))
+
+	// fetch only reports single OID, but OID *might* be referenced by multiple
+	// WrappedPointers if same content is at multiple paths, so map oid->slice
+	oidToPointers := make(map[string][]*lfs.WrappedPointer, len(pointers))
+	report := func(oid string) {
+		for _, p := range oidToPointers[oid] {
+			out <- p
+		}
+	}
 
+	var reported sync.WaitGroup
 	if out != nil {
 		// If we already have it, or it won't be fetched
 		// report it to chan immediately to support pull/checkout
@@ -337,27 +459,23 @@ func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfil
 			out <- p
 		}
 
-		dlwatch := q.Watch()
+		for _, pointer := range pointers {
+			plist := oidToPointers[pointer.Oid]
+			oidToPointers[pointer.Oid] = append(plist, pointer)
+		}
+		// Objects recently missing from the server may be fetched
+		// from its fallback remotes.
+		for _, pointer := range knownMissing {
+			oidToPointers[pointer.Oid] = append(oidToPointers[pointer.Oid], pointer)
+		}
 
+		dlwatch := q.Watch()
+		reported.Add(1)
 		go func() {
-			// fetch only reports single OID, but OID *might* be referenced by multiple
-			// WrappedPointers if same content is at multiple paths, so map oid->slice
-			oidToPointers := make(map[string][]*lfs.WrappedPointer, len(pointers))
-			for _, pointer := range pointers {
-				plist := oidToPointers[pointer.Oid]
-				oidToPointers[pointer.Oid] = append(plist, pointer)
-			}
-
+			defer reported.Done()
 			for t := range dlwatch {
-				plist, ok := oidToPointers[t.Oid]
-				if !ok {
-					continue
-				}
-				for _, p := range plist {
-					out <- p
-				}
+				report(t.Oid)
 			}
-			close(out)
 		}()
 	}
 
@@ -369,19 +487,95 @@ func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfil
 
 	processQueue := time.Now()
 	q.Wait()
+	reported.Wait()
 	tracerx.PerformanceSince("process queue", processQueue)
+	cache.Save()
 
 	ok := true
+	var denied []error
 	for _, err := range q.Errors() {
+		// Objects the server doesn't have are summarized below, once
+		// any fallback remotes have been asked for them, and so are
+		// those the user may not download.
+		if isMissingObjectError(err) {
+			continue
+		}
+		if isDeniedObjectError(err) {
+			denied = append(denied, err)
+			continue
+		}
 		ok = false
 		FullError(err)
 	}
-	return ok
+
+	fetched := func(string) {}
+	if out != nil {
+		fetched = report
+	}
+	missing, errs := fetchFromFallbackRemotes(cfg.Remote(), allpointers, missing, fetched)
+	for _, err := range errs {
+		ok = false
+		FullError(err)
+	}
+	if out != nil {
+		close(out)
+	}
+
+	numKnown := 0
+	stillMissing := tools.NewStringSetFromSlice(missing)
+	for _, p := range knownMissing {
+		if stillMissing.Contains(p.Oid) {
+			numKnown++
+		}
+	}
+	reportPermanentlyMissing(allpointers, missing, numKnown)
+	reportUnavailableObjects("fetch", "denied by the server", unavailableObjects(denied))
+	exitIfInterrupted(q, tq.Download)
+
+	if len(missing) == 0 && len(denied) == 0 {
+		return ok
+	}
+	if cfg.AllowMissingObjects() {
+		Error("fetch: continuing without these objects")
+		return ok
+	}
+	return false
 }
 
-func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) ([]*lfs.WrappedPointer, []*lfs.WrappedPointer, *tq.Meter) {
+// reportPermanentlyMissing prints a summary of the objects which the server
+// does not have, whether it just reported that or did so recently enough that
+// it was not asked again.
+func reportPermanentlyMissing(allpointers []*lfs.WrappedPointer, missing []string, numKnown int) {
+	if len(missing) == 0 {
+		return
+	}
+
+	oids := tools.NewStringSetFromSlice(missing)
+	names := make([]string, 0, len(missing))
+	for _, p := range allpointers {
+		if oids.Contains(p.Oid) {
+			names = append(names, fmt.Sprintf("  %s (%s)", p.Name, p.Oid))
+			oids.Remove(p.Oid)
+		}
+	}
+	sort.Strings(names)
+
+	Error("fetch: %d object(s) permanently missing from the server:", len(missing))
+	for _, name := range names {
+		Error(name)
+	}
+	if numKnown > 0 {
+		Error("fetch: %d of these were not requested again, as the server recently reported not having them (see lfs.fetchmissinghours)", numKnown)
+	}
+}
+
+// readyAndMissingPointers partitions the given pointers into those whose
+// objects exist locally, those to download, and those which the server
+// recently reported not having, according to the given cache.
+func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter, cache *missingCache) ([]*lfs.WrappedPointer, []*lfs.WrappedPointer, []*lfs.WrappedPointer, *tq.Meter) {
 	logger := tasklog.NewLogger(os.Stdout,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	meter := buildProgressMeter(false, tq.Download)
 	logger.Enqueue(meter)
@@ -389,6 +583,7 @@ func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepath
 	seen := make(map[string]bool, len(allpointers))
 	missing := make([]*lfs.WrappedPointer, 0, len(allpointers))
 	ready := make([]*lfs.WrappedPointer, 0, len(allpointers))
+	var knownMissing []*lfs.WrappedPointer
 
 	for _, p := range allpointers {
 		// no need to download the same object multiple times
@@ -401,23 +596,38 @@ func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepath
 		// no need to download objects that exist locally already
 		lfs.LinkOrCopyFromReference(cfg, p.Oid, p.Size)
 		if cfg.LFSObjectExists(p.Oid, p.Size) {
+			cache.Remove(p.Oid)
 			ready = append(ready, p)
 			continue
 		}
 
+		// no need to ask for objects the server recently said it doesn't have
+		if cache.Contains(p.Oid) {
+			tracerx.Printf("fetch: %s is recorded as missing from the server, skipping", p.Oid)
+			knownMissing = append(knownMissing, p)
+			continue
+		}
+
 		missing = append(missing, p)
 		meter.Add(p.Size)
 	}
 
-	return ready, missing, meter
+	return ready, missing, knownMissing, meter
 }
 
 func init() {
 	RegisterCommand("fetch", fetchCommand, func(cmd *cobra.Command) {
 		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
 		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().StringArrayVar(&fetchIncludeFromArg, "include-from", nil, "Include the paths matching the patterns in a file")
+		cmd.Flags().StringArrayVar(&fetchExcludeFromArg, "exclude-from", nil, "Exclude the paths matching the patterns in a file")
 		cmd.Flags().BoolVarP(&fetchRecentArg, "recent", "r", false, "Fetch recent refs & commits")
 		cmd.Flags().BoolVarP(&fetchAllArg, "all", "a", false, "Fetch all LFS files ever referenced")
 		cmd.Flags().BoolVarP(&fetchPruneArg, "prune", "p", false, "After fetching, prune old data")
+		cmd.Flags().StringVar(&fetchManifestArg, "manifest", "", "Fetch exactly the objects listed in this manifest")
+		cmd.Flags().BoolVar(&fetchVerifyArg, "verify", false, "Verify the fetched objects and the current tree against the manifest")
+		cmd.Flags().StringVar(&fetchWriteManifestArg, "write-manifest", "", "After fetching, write a manifest of the Git LFS files in the ref to this file")
+		addDiskSpaceForceFlag(cmd)
+		addRecurseSubmodulesFlag(cmd)
 	})
 }
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
@@ -64,18 +66,29 @@ func filterCommand(cmd *cobra.Command, args []string) {
 
 	ptrs := make(map[string]*lfs.Pointer)
 
+	started := time.Now().Truncate(time.Second)
+
+	// written holds the pointers with metadata whose contents have been
+	// sent to Git, by path. Git only writes each file once it has read its
+	// contents, so the metadata is applied once Git is done with us.
+	written := make(map[string]*lfs.Pointer)
+
 	var q *tq.TransferQueue
 	var malformed []string
 	var malformedOnWindows []string
 	var closeOnce *sync.Once
 	var available chan *tq.Transfer
 	gitfilter := lfs.NewGitFilter(cfg)
+	defer gitfilter.Close()
+
 	for s.Scan() {
 		var n int64
 		var err error
 		var delayed bool
 		var w *pktline.PktlineWriter
 
+		var smudged *lfs.Pointer
+
 		req := s.Request()
 
 		switch req.Header["command"] {
@@ -111,6 +124,8 @@ func filterCommand(cmd *cobra.Command, args []string) {
 
 				if delayed {
 					ptrs[req.Header["pathname"]] = ptr
+				} else {
+					smudged = ptr
 				}
 			} else {
 				s.WriteStatus(statusFromErr(nil))
@@ -119,7 +134,7 @@ func filterCommand(cmd *cobra.Command, args []string) {
 					break
 				}
 
-				n, err = smudge(gitfilter, w, from, req.Header["pathname"], skip, filter)
+				n, smudged, err = smudge(gitfilter, w, from, req.Header["pathname"], skip, filter)
 				if err == nil {
 					delete(ptrs, req.Header["pathname"])
 				}
@@ -202,8 +217,14 @@ func filterCommand(cmd *cobra.Command, args []string) {
 		}
 
 		s.WriteStatus(status)
+
+		if err == nil && smudged != nil && len(smudged.Metadata) > 0 {
+			written[req.Header["pathname"]] = smudged
+		}
 	}
 
+	applyWrittenMetadata(gitfilter, written, started)
+
 	if len(malformed) > 0 {
 		fmt.Fprintf(os.Stderr, "Encountered %d file(s) that should have been pointers, but weren't:\n", len(malformed))
 		for _, m := range malformed {
@@ -226,6 +247,25 @@ func filterCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// applyWrittenMetadata applies the metadata recorded in the given pointers to
+// the files at the corresponding paths in the working tree. Only files which
+// Git wrote with the smudged contents since the given time are updated, since
+// Git also smudges contents for other purposes, such as "git cat-file
+// --filters", without writing them to the working tree.
+func applyWrittenMetadata(gf *lfs.GitFilter, written map[string]*lfs.Pointer, since time.Time) {
+	for path, ptr := range written {
+		filename := filepath.Join(cfg.LocalWorkingDir(), path)
+		stat, err := os.Stat(filename)
+		if err != nil || !stat.Mode().IsRegular() || stat.Size() != ptr.Size || stat.ModTime().Before(since) {
+			continue
+		}
+
+		if err := gf.ApplyPointerMetadata(filename, ptr); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not apply metadata to %s: %s\n", path, err)
+		}
+	}
+}
+
 // infiniteTransferBuffer streams the results of q.Watch() into "available" as
 // if available had an infinite channel buffer.
 func infiniteTransferBuffer(q *tq.TransferQueue, available chan<- *tq.Transfer) {
@@ -6,21 +6,27 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
+	"github.com/git-lfs/git-lfs/v2/fs"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fsckDryRun   bool
-	fsckObjects  bool
-	fsckPointers bool
+	fsckDryRun       bool
+	fsckObjects      bool
+	fsckPointers     bool
+	fsckConnectivity bool
+	fsckParanoid     bool
 )
 
 type corruptPointer struct {
@@ -72,7 +78,7 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	if !fsckPointers && !fsckObjects {
+	if !fsckPointers && !fsckObjects && !fsckConnectivity {
 		fsckPointers = true
 		fsckObjects = true
 	}
@@ -81,6 +87,16 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 	var corruptOids []string
 	var corruptPointers []corruptPointer
 	if fsckObjects {
+		// Move misnamed objects to their canonical paths first, so
+		// that they are found when the objects are checked.
+		anomalies := doFsckObjectNames()
+		if len(anomalies) > 0 {
+			ok = false
+			if !fsckDryRun {
+				repairObjectNames(anomalies)
+			}
+		}
+
 		corruptOids = doFsckObjects(start, end, useIndex)
 		ok = ok && len(corruptOids) == 0
 	}
@@ -88,6 +104,14 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 		corruptPointers = doFsckPointers(start, end)
 		ok = ok && len(corruptPointers) == 0
 	}
+	if fsckParanoid || (fsckPointers && cfg.Git.Bool("core.ignorecase", false)) {
+		collisions := doFsckCaseCollisions(end, fsckParanoid)
+		corruptPointers = append(corruptPointers, collisions...)
+		ok = ok && len(collisions) == 0
+	}
+	if fsckConnectivity {
+		ok = doFsckConnectivity(start, end, useIndex) && ok
+	}
 
 	if ok {
 		Print("Git LFS fsck OK")
@@ -98,20 +122,106 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	badDir := filepath.Join(cfg.LFSStorageDir(), "bad")
+	badDir := fsckBadDir()
 	Print("objects: repair: moving corrupt objects to %s", badDir)
 
+	for _, oid := range corruptOids {
+		if cfg.Filesystem().IsReadOnlyObject(oid) {
+			Print("objects: repair: %s is in the read-only object store and was not moved", oid)
+			continue
+		}
+		badFile := filepath.Join(badDir, oid)
+		if err := tools.RobustRename(cfg.Filesystem().ObjectPathname(oid), badFile); err != nil {
+			ExitWithError(err)
+		}
+	}
+	os.Exit(1)
+}
+
+// fsckBadDir returns the directory to which corrupt objects are moved,
+// creating it if necessary.
+func fsckBadDir() string {
+	badDir := filepath.Join(cfg.LFSStorageDir(), "bad")
 	if err := tools.MkdirAll(badDir, cfg); err != nil {
 		ExitWithError(err)
 	}
+	return badDir
+}
 
-	for _, oid := range corruptOids {
-		badFile := filepath.Join(badDir, oid)
-		if err := os.Rename(cfg.Filesystem().ObjectPathname(oid), badFile); err != nil {
+// doFsckObjectNames checks for files in the object directory which are not at
+// the canonical path for an object, such as those with mixed-case names left
+// by case-insensitive filesystems.
+func doFsckObjectNames() []fs.ObjectAnomaly {
+	var anomalies []fs.ObjectAnomaly
+	err := cfg.Filesystem().EachObjectAnomaly(func(a fs.ObjectAnomaly) error {
+		Print("objects: invalidName: %s is not a valid object path", a.Name)
+		anomalies = append(anomalies, a)
+		return nil
+	})
+	if err != nil {
+		ExitWithError(err)
+	}
+	return anomalies
+}
+
+// repairObjectNames moves each misnamed object whose content matches the OID
+// its name refers to to its canonical path, and any other file to the
+// directory for corrupt objects.
+func repairObjectNames(anomalies []fs.ObjectAnomaly) {
+	for _, a := range anomalies {
+		if len(a.Oid) > 0 {
+			oid, err := hashObjectFile(a.Path)
+			if err != nil {
+				ExitWithError(err)
+			}
+
+			if oid == a.Oid {
+				canonical := path.Join(oid[0:2], oid[2:4], oid)
+
+				err := cfg.Filesystem().RepairObjectAnomaly(a)
+				if err == nil {
+					Print("objects: repair: renamed %s to %s", a.Name, canonical)
+					continue
+				}
+
+				// The object may have been written again
+				// since, such as by the clean filter, in
+				// which case this is a duplicate.
+				if err == fs.ErrObjectExists {
+					existing, herr := hashObjectFile(cfg.Filesystem().ObjectPathname(oid))
+					if herr == nil && existing == oid {
+						Print("objects: repair: removing %s, a duplicate of %s", a.Name, canonical)
+						if err := os.Remove(tools.LongPath(a.Path)); err != nil {
+							ExitWithError(err)
+						}
+						continue
+					}
+				}
+				Print("objects: repair: could not rename %s: %s", a.Name, err)
+			}
+		}
+
+		badFile := filepath.Join(fsckBadDir(), filepath.Base(a.Path))
+		Print("objects: repair: moving %s to %s", a.Name, badFile)
+		if err := tools.RobustRename(a.Path, badFile); err != nil {
 			ExitWithError(err)
 		}
 	}
-	os.Exit(1)
+}
+
+// hashObjectFile returns the OID of the content of the file at the given path.
+func hashObjectFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	oidHash := sha256.New()
+	if _, err := io.Copy(oidHash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(oidHash.Sum(nil)), nil
 }
 
 // doFsckObjects checks that the objects in the given ref are correct and exist.
@@ -174,14 +284,33 @@ func doFsckPointers(start, end string) []corruptPointer {
 				Print("pointer: %s", cp.String())
 				corruptPointers = append(corruptPointers, cp)
 			}
+			if err := lfs.ValidatePointerMetadata(p.Pointer); err != nil {
+				cp := corruptPointer{
+					blobOid: p.Sha1,
+					lfsOid:  p.Oid,
+					message: fmt.Sprintf("Pointer for %s (blob %s) has invalid metadata: %s", p.Oid, p.Sha1, err),
+					kind:    "invalidPointerMetadata",
+				}
+				Print("pointer: %s", cp.String())
+				corruptPointers = append(corruptPointers, cp)
+			}
 		} else if errors.IsPointerScanError(err) {
 			psErr, ok := err.(errors.PointerScanError)
 			if ok {
 				cp := corruptPointer{
 					treeOid: psErr.OID(),
 					path:    psErr.Path(),
-					message: fmt.Sprintf("%q (treeish %s) should have been a pointer but was not", psErr.Path(), psErr.OID()),
-					kind:    "unexpectedGitObject",
+				}
+				switch {
+				case errors.IsSymlinkPointerError(err):
+					cp.message = fmt.Sprintf("%q (treeish %s) is a symbolic link whose target is a pointer", psErr.Path(), psErr.OID())
+					cp.kind = "symlinkPointer"
+				case errors.IsPointerSizeError(err):
+					cp.message = fmt.Sprintf("%q (treeish %s) should have been a pointer but was not", psErr.Path(), psErr.OID())
+					cp.kind = "unexpectedGitObject"
+				default:
+					cp.message = fmt.Sprintf("%q (treeish %s) is small enough to be a pointer but was not a valid one", psErr.Path(), psErr.OID())
+					cp.kind = "suspiciousGitObject"
 				}
 				Print("pointer: %s", cp.String())
 				corruptPointers = append(corruptPointers, cp)
@@ -205,12 +334,163 @@ func doFsckPointers(start, end string) []corruptPointer {
 	return corruptPointers
 }
 
+// doFsckCaseCollisions reports the Git LFS files in the tree at ref which
+// would be overwritten by another file whose path differs only in case, when
+// checked out on a case-insensitive filesystem. If all is set, every path in
+// the tree is considered, rather than just those of Git LFS files.
+func doFsckCaseCollisions(ref string, all bool) []corruptPointer {
+	pointers := make(map[string]*lfs.WrappedPointer)
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			Panic(err, "Error checking Git LFS files")
+		}
+		pointers[p.Name] = p
+	})
+	if err := gitscanner.ScanTree(ref); err != nil {
+		ExitWithError(err)
+	}
+	gitscanner.Close()
+
+	blobs := make(map[string]string)
+	if all {
+		cmd, err := git.LsTree(ref)
+		if err != nil {
+			ExitWithError(err)
+		}
+		cmd.Stdin.Close()
+
+		scanner := git.NewLsTreeScanner(cmd.Stdout)
+		for scanner.Scan() {
+			if t := scanner.TreeBlob(); t != nil {
+				blobs[t.Filename] = t.Oid
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			ExitWithError(errors.Wrap(err, "git ls-tree"))
+		}
+	} else {
+		for name, p := range pointers {
+			blobs[name] = p.Sha1
+		}
+	}
+
+	describe := func(name string) string {
+		if p, ok := pointers[name]; ok {
+			return fmt.Sprintf("object %s", p.Oid)
+		}
+		return fmt.Sprintf("blob %s", blobs[name])
+	}
+
+	names := make([]string, 0, len(blobs))
+	for name := range blobs {
+		names = append(names, name)
+	}
+
+	var corruptPointers []corruptPointer
+	for _, group := range tools.CaseCollisions(names) {
+		last := group[len(group)-1]
+		for _, name := range group[:len(group)-1] {
+			cp := corruptPointer{
+				blobOid: blobs[name],
+				path:    name,
+				message: fmt.Sprintf("%q (%s) would be overwritten by %q (%s) on a case-insensitive filesystem", name, describe(name), last, describe(last)),
+				kind:    "caseCollision",
+			}
+			if p, ok := pointers[name]; ok {
+				cp.lfsOid = p.Oid
+			}
+			Print("pointer: %s", cp.String())
+			corruptPointers = append(corruptPointers, cp)
+		}
+	}
+	return corruptPointers
+}
+
+// doFsckConnectivity cross-checks the local object store against history: it
+// reports objects which are not referenced by any pointer in the given range,
+// or any ref (and the index) if useIndex is set, and pointers whose objects
+// are missing, unless excluded by lfs.fetchexclude. It returns whether there
+// were no such problems.
+func doFsckConnectivity(start, end string, useIndex bool) bool {
+	referenced := make(map[string]*lfs.WrappedPointer)
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			Panic(err, "Error checking Git LFS files")
+		}
+		if _, ok := referenced[p.Oid]; !ok {
+			referenced[p.Oid] = p
+		}
+	})
+
+	var err error
+	if useIndex {
+		err = gitscanner.ScanAll(nil)
+		if err == nil {
+			err = gitscanner.ScanIndex("HEAD", nil)
+		}
+	} else if start == "" {
+		err = gitscanner.ScanRef(end, nil)
+	} else {
+		err = gitscanner.ScanRefRange(start, end, nil)
+	}
+	if err != nil {
+		ExitWithError(err)
+	}
+	gitscanner.Close()
+
+	var unreachable []fs.Object
+	err = cfg.EachLFSObject(func(o fs.Object) error {
+		if _, ok := referenced[o.Oid]; !ok {
+			unreachable = append(unreachable, o)
+		}
+		return nil
+	})
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	exclude := filepathfilter.New(nil, cfg.FetchExcludePaths())
+	var missing []*lfs.WrappedPointer
+	excluded := 0
+	for _, p := range referenced {
+		if p.Size == 0 || cfg.LFSObjectExists(p.Oid, p.Size) {
+			continue
+		}
+		if !exclude.Allows(p.Name) {
+			excluded++
+			continue
+		}
+		missing = append(missing, p)
+	}
+
+	sort.Slice(unreachable, func(i, j int) bool { return unreachable[i].Oid < unreachable[j].Oid })
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+
+	for _, o := range unreachable {
+		Print("connectivity: unreachableObject: %s (%s) is not referenced by any scanned ref", o.Oid, humanize.FormatBytes(uint64(o.Size)))
+	}
+	for _, p := range missing {
+		Print("connectivity: missingObject: %s (%s) is referenced but not present locally", p.Name, p.Oid)
+	}
+	if excluded > 0 {
+		Debug("connectivity: %d missing object(s) excluded by lfs.fetchexclude", excluded)
+	}
+
+	if len(unreachable) > 0 {
+		Print("connectivity: %d unreachable object(s); run `git lfs prune` to remove them", len(unreachable))
+	}
+	if len(missing) > 0 {
+		Print("connectivity: %d missing object(s); run `git lfs fetch --all` to download them", len(missing))
+	}
+	return len(unreachable) == 0 && len(missing) == 0
+}
+
 func fsckPointer(name, oid string, size int64) (bool, error) {
 	path := cfg.Filesystem().ObjectPathname(oid)
 
 	Debug("Examining %v (%v)", name, path)
 
-	f, err := os.Open(path)
+	f, err := cfg.Filesystem().OpenObject(oid)
 	if pErr, pOk := err.(*os.PathError); pOk {
 		// This is an empty file.  No problem here.
 		if size == 0 {
@@ -245,5 +525,7 @@ func init() {
 		cmd.Flags().BoolVarP(&fsckDryRun, "dry-run", "d", false, "List corrupt objects without deleting them.")
 		cmd.Flags().BoolVarP(&fsckObjects, "objects", "", false, "Fsck objects.")
 		cmd.Flags().BoolVarP(&fsckPointers, "pointers", "", false, "Fsck pointers.")
+		cmd.Flags().BoolVarP(&fsckConnectivity, "connectivity", "", false, "Check objects against the pointers in history.")
+		cmd.Flags().BoolVarP(&fsckParanoid, "paranoid", "", false, "Check every path in the tree for case collisions.")
 	})
 }
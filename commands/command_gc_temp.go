@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/rubyist/tracerx"
+	"github.com/spf13/cobra"
+)
+
+// autoGCTempInterval is how often stale temporary files are cleaned up when a
+// command starts.
+const autoGCTempInterval = 24 * time.Hour
+
+var (
+	gcTempDryRunArg  bool
+	gcTempVerboseArg bool
+)
+
+// gcTempCommand removes the stale files left in the temporary and incomplete
+// directories by transfers which failed or were interrupted, and saves any
+// abandoned downloads so that they can be resumed.
+func gcTempCommand(cmd *cobra.Command, args []string) {
+	// Don't use setupRepository(), which would clean up first.
+	requireInRepo()
+
+	var removed, resumed int
+	var removedSize int64
+	err := cfg.Filesystem().EachStaleTempFile(gcTempPolicy(), func(s fs.StaleTempFile) error {
+		if gcTempVerboseArg {
+			Print(" * %s (%s, %s)", s.Path, s.Reason, humanize.FormatBytes(uint64(s.Size)))
+		}
+		if s.Resume {
+			resumed++
+		} else {
+			removed++
+			removedSize += s.Size
+		}
+		if gcTempDryRunArg {
+			return nil
+		}
+		return cfg.Filesystem().CleanStaleTempFile(s)
+	})
+	if err != nil {
+		Exit("fatal: could not clean up temporary files: %v", err)
+	}
+
+	if gcTempDryRunArg {
+		Print("gc-temp: %d file(s) would be removed (%s), %d abandoned download(s) would be saved for resuming", removed, humanize.FormatBytes(uint64(removedSize)), resumed)
+	} else {
+		Print("gc-temp: removed %d file(s) (%s), saved %d abandoned download(s) for resuming", removed, humanize.FormatBytes(uint64(removedSize)), resumed)
+		touchGCTempStamp()
+	}
+}
+
+// gcTempPolicy returns the policy for which temporary files are stale, given
+// by lfs.gctemp.tmphours and lfs.gctemp.incompletedays.
+func gcTempPolicy() fs.TempPolicy {
+	policy := fs.DefaultTempPolicy
+	if v := cfg.Git.Int("lfs.gctemp.tmphours", 0); v > 0 {
+		policy.TmpAge = time.Duration(v) * time.Hour
+	}
+	if v := cfg.Git.Int("lfs.gctemp.incompletedays", 0); v > 0 {
+		policy.IncompleteAge = time.Duration(v) * 24 * time.Hour
+	}
+	return policy
+}
+
+// autoGCTemp cleans up stale temporary files, as "git lfs gc-temp" does, if
+// that has not been done within autoGCTempInterval, so that the files left by
+// crashed transfers do not build up. Failures are only traced.
+func autoGCTemp() {
+	if !cfg.Git.Bool("lfs.gctemp.auto", true) {
+		return
+	}
+
+	stamp := gcTempStampPath()
+	fi, err := os.Stat(stamp)
+	if err == nil && time.Since(fi.ModTime()) < autoGCTempInterval {
+		return
+	}
+	if _, err := os.Stat(cfg.LFSStorageDir()); err != nil {
+		// Nothing has been stored yet.
+		return
+	}
+
+	err = cfg.Filesystem().EachStaleTempFile(gcTempPolicy(), func(s fs.StaleTempFile) error {
+		tracerx.Printf("gc-temp: cleaning up %s temporary file: %s", s.Reason, s.Path)
+		if err := cfg.Filesystem().CleanStaleTempFile(s); err != nil {
+			tracerx.Printf("gc-temp: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		tracerx.Printf("gc-temp: %v", err)
+		return
+	}
+	touchGCTempStamp()
+}
+
+func gcTempStampPath() string {
+	return filepath.Join(cfg.LFSStorageDir(), "gc-temp")
+}
+
+// touchGCTempStamp records that stale temporary files have just been cleaned
+// up.
+func touchGCTempStamp() {
+	stamp := gcTempStampPath()
+	now := time.Now()
+	if err := os.Chtimes(stamp, now, now); err == nil {
+		return
+	}
+
+	f, err := os.OpenFile(stamp, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		tracerx.Printf("gc-temp: %v", err)
+		return
+	}
+	f.Close()
+}
+
+func init() {
+	RegisterCommand("gc-temp", gcTempCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&gcTempDryRunArg, "dry-run", "d", false, "Don't remove anything, just report")
+		cmd.Flags().BoolVarP(&gcTempVerboseArg, "verbose", "v", false, "Print each file which is/would be removed or saved")
+	})
+}
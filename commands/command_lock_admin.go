@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"os"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockAdminBreakArg bool
+)
+
+// lockAdminCommand lists the operation locks held by Git LFS processes in the
+// current repository, or breaks them, such as when a process has hung while
+// holding one.
+func lockAdminCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	f := cfg.Filesystem()
+	if !lockAdminBreakArg {
+		if len(args) > 0 {
+			Exit("Lock names may only be given with --break")
+		}
+
+		err := f.EachLock(func(info *fs.LockInfo) error {
+			var stale string
+			if info.Stale() {
+				stale = " (stale)"
+			}
+			Print("%s\tprocess %d on %s\tgit lfs %s\tsince %s%s", info.Name, info.Pid,
+				info.Hostname, info.Command, info.CreatedAt.Format(time.RFC3339), stale)
+			return nil
+		})
+		if err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	// Without names, only break the stale locks, which is always safe.
+	names := args
+	if len(names) == 0 {
+		err := f.EachLock(func(info *fs.LockInfo) error {
+			if info.Stale() {
+				names = append(names, info.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			ExitWithError(err)
+		}
+	}
+
+	for _, name := range names {
+		if err := f.BreakLock(name); err != nil {
+			if os.IsNotExist(err) {
+				Exit("No lock named %q", name)
+			}
+			ExitWithError(err)
+		}
+		Print("Broke lock %q", name)
+	}
+}
+
+// acquireOperationLock acquires the operation lock with the given name, waiting
+// for up to lfs.operationlocktimeout seconds for another process holding it to
+// finish, and exits if it cannot. The lock is released when the returned
+// function is called, or if the command exits first, broken by the next
+// command to need it.
+func acquireOperationLock(name string) func() {
+	timeout := time.Duration(cfg.Git.Int("lfs.operationlocktimeout", 300)) * time.Second
+	lock, err := cfg.Filesystem().Lock(name, timeout)
+	if err != nil {
+		if fs.IsLockedError(err) {
+			Exit("%v\nIf that process is no longer running, remove the lock with:\n\n  git lfs lock-admin --break %s", err, name)
+		}
+		ExitWithError(err)
+	}
+	return func() {
+		lock.Unlock()
+	}
+}
+
+func init() {
+	RegisterCommand("lock-admin", lockAdminCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&lockAdminBreakArg, "break", "", false, "Break the named locks, or all stale locks")
+	})
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -16,9 +17,26 @@ var (
 	lsFilesScanDeleted  = false
 	lsFilesShowSize     = false
 	lsFilesShowNameOnly = false
+	lsFilesWorktree     = false
+	lsFilesStates       []string
 	debug               = false
 )
 
+const (
+	// lsFilesStatePointer is the state of a file whose working tree copy
+	// is the pointer itself, such as after a clone with smudging skipped.
+	lsFilesStatePointer = "pointer"
+	// lsFilesStateHydrated is the state of a file whose working tree copy
+	// is the content of its object.
+	lsFilesStateHydrated = "hydrated"
+	// lsFilesStateModified is the state of a file whose working tree copy
+	// is neither its pointer nor the content of its object.
+	lsFilesStateModified = "modified"
+	// lsFilesStateMissing is the state of a file which is not in the
+	// working tree.
+	lsFilesStateMissing = "missing"
+)
+
 func lsFilesCommand(cmd *cobra.Command, args []string) {
 	setupRepository()
 
@@ -55,6 +73,23 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	states := make(map[string]bool)
+	for _, state := range lsFilesStates {
+		switch state {
+		case lsFilesStatePointer, lsFilesStateHydrated, lsFilesStateModified, lsFilesStateMissing:
+			states[state] = true
+		default:
+			Exit("fatal: unknown --worktree-state %q, expected %s, %s, %s, or %s", state,
+				lsFilesStatePointer, lsFilesStateHydrated, lsFilesStateModified, lsFilesStateMissing)
+		}
+	}
+	if len(states) > 0 {
+		lsFilesWorktree = true
+	}
+	if lsFilesWorktree && cfg.LocalWorkingDir() == "" {
+		Exit("fatal: --worktree requires a working tree")
+	}
+
 	showOidLen := 10
 	if longOIDs {
 		showOidLen = 64
@@ -74,8 +109,17 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 			}
 		}
 
+		var state string
+		if lsFilesWorktree {
+			state = lsFilesWorktreeState(p)
+			if len(states) > 0 && !states[state] {
+				seen[p.Name] = struct{}{}
+				return
+			}
+		}
+
 		if debug {
-			Print(
+			msg := fmt.Sprintf(
 				"filepath: %s\n"+
 					"    size: %d\n"+
 					"checkout: %v\n"+
@@ -89,6 +133,10 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 				p.OidType,
 				p.Oid,
 				p.Version)
+			if lsFilesWorktree {
+				msg += fmt.Sprintf("worktree: %s\n", state)
+			}
+			Print(msg)
 		} else {
 			msg := []string{p.Oid[:showOidLen], lsFilesMarker(p), p.Name}
 			if lsFilesShowNameOnly {
@@ -98,6 +146,9 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 				size := humanize.FormatBytes(uint64(p.Size))
 				msg = append(msg, "("+size+")")
 			}
+			if lsFilesWorktree {
+				msg = append(msg, "["+state+"]")
+			}
 
 			Print(strings.Join(msg, " "))
 		}
@@ -146,6 +197,33 @@ func fileExistsOfSize(p *lfs.WrappedPointer) bool {
 	return err == nil && info.Size() == p.Size
 }
 
+// lsFilesWorktreeState returns the state of the working tree copy of the file
+// of the given pointer: whether it is the pointer, the content of its object,
+// modified, or missing.
+func lsFilesWorktreeState(p *lfs.WrappedPointer) string {
+	path := cfg.Filesystem().DecodePathname(p.Name)
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return lsFilesStateMissing
+	}
+
+	if wp, err := lfs.DecodePointerFromFile(path); err == nil {
+		if wp.Oid == p.Oid {
+			return lsFilesStatePointer
+		}
+		return lsFilesStateModified
+	}
+
+	if info.Size() != p.Size {
+		return lsFilesStateModified
+	}
+	oid, err := hashObjectFile(path)
+	if err != nil || oid != p.Oid {
+		return lsFilesStateModified
+	}
+	return lsFilesStateHydrated
+}
+
 func lsFilesMarker(p *lfs.WrappedPointer) string {
 	if fileExistsOfSize(p) {
 		return "*"
@@ -161,6 +239,8 @@ func init() {
 		cmd.Flags().BoolVarP(&debug, "debug", "d", false, "")
 		cmd.Flags().BoolVarP(&lsFilesScanAll, "all", "a", false, "")
 		cmd.Flags().BoolVar(&lsFilesScanDeleted, "deleted", false, "")
+		cmd.Flags().BoolVarP(&lsFilesWorktree, "worktree", "w", false, "Show the state of each file in the working tree")
+		cmd.Flags().StringSliceVar(&lsFilesStates, "worktree-state", nil, "Show only files in the working tree in these states")
 		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
 		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
 	})
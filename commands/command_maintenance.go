@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maintenancePackMaxSize   string
+	maintenanceRunTasks      []string
+	maintenanceRunSchedule   string
+	maintenanceRunRegistered bool
+)
+
+// maintenanceScheduleLevels orders the schedules at which maintenance tasks
+// may run, from the most to the least frequent.
+var maintenanceScheduleLevels = map[string]int{"hourly": 1, "daily": 2, "weekly": 3}
+
+// defaultMaintenancePackMaxSize is the size of the largest objects packed by
+// "git lfs maintenance pack" unless lfs.pack.maxobjectsize is set.
+const defaultMaintenancePackMaxSize = "1MiB"
+
+// maintenanceLockName is the name of the operation lock held by "git lfs
+// maintenance run", so that a scheduled run is skipped while the previous one
+// is still going.
+const maintenanceLockName = "maintenance"
+
+// maintenanceRepoKey is the global configuration key listing the repositories
+// registered with "git lfs maintenance start".
+const maintenanceRepoKey = "lfs.maintenance.repo"
+
+// maintenanceTask is a task run by "git lfs maintenance run".
+type maintenanceTask struct {
+	name string
+	// schedule is how often the task is run by default, or empty if it
+	// is only run once enabled.
+	schedule string
+	run      func() error
+}
+
+// maintenanceTasks are the tasks "git lfs maintenance run" knows, in the order
+// in which they are run.
+var maintenanceTasks = []*maintenanceTask{
+	{name: "prefetch", schedule: "hourly", run: lfsSubcommandTask("fetch", "--recent")},
+	{name: "gc-temp", schedule: "daily", run: lfsSubcommandTask("gc-temp")},
+	{name: "pushed-journal", schedule: "weekly", run: compactPushJournalsTask},
+	{name: "prune", run: lfsSubcommandTask("prune", "--yes")},
+	{name: "verify", schedule: "weekly", run: lfsSubcommandTask("fsck", "--objects")},
+	{name: "pack", run: lfsSubcommandTask("maintenance", "pack")},
+}
+
+// enabled returns whether the task is run when no tasks are named, as set by
+// lfs.maintenance.<task>.enabled.
+func (t *maintenanceTask) enabled() bool {
+	return cfg.Git.Bool("lfs.maintenance."+t.name+".enabled", len(t.schedule) > 0)
+}
+
+// scheduled returns how often the task is run, as set by
+// lfs.maintenance.<task>.schedule.
+func (t *maintenanceTask) scheduled() string {
+	if schedule, ok := cfg.Git.Get("lfs.maintenance." + t.name + ".schedule"); ok {
+		return schedule
+	}
+	if len(t.schedule) == 0 {
+		return "daily"
+	}
+	return t.schedule
+}
+
+// lfsSubcommandTask returns a task which runs Git LFS with the given
+// arguments, in a separate process, since commands exit on failure.
+func lfsSubcommandTask(args ...string) func() error {
+	return func() error {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := subprocess.ExecCommand(exe, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "git lfs %s", strings.Join(args, " "))
+		}
+		return nil
+	}
+}
+
+func compactPushJournalsTask() error {
+	dropped, err := compactPushJournals()
+	if err != nil {
+		return err
+	}
+	Print("pushed-journal: dropped %d redundant line(s)", dropped)
+	return nil
+}
+
+// maintenanceRunCommand runs the named maintenance tasks, or those enabled,
+// and with --schedule, due at the given frequency.
+func maintenanceRunCommand(cmd *cobra.Command, args []string) {
+	var level int
+	if len(maintenanceRunSchedule) > 0 {
+		var ok bool
+		if level, ok = maintenanceScheduleLevels[maintenanceRunSchedule]; !ok {
+			Exit("Invalid schedule %q: must be hourly, daily, or weekly", maintenanceRunSchedule)
+		}
+	}
+
+	if maintenanceRunRegistered {
+		maintenanceRunRegisteredRepos()
+		return
+	}
+
+	setupRepository()
+
+	tasks, err := maintenanceTasksToRun(level)
+	if err != nil {
+		Exit("%v", err)
+	}
+
+	lock, err := cfg.Filesystem().TryLock(maintenanceLockName)
+	if fs.IsLockedError(err) {
+		Print("maintenance: skipped, since %v", err)
+		return
+	}
+	if err != nil {
+		ExitWithError(err)
+	}
+	defer lock.Unlock()
+
+	failed := false
+	for _, task := range tasks {
+		Print("maintenance: running %s", task.name)
+		if err := task.run(); err != nil {
+			Error("maintenance: %s failed: %v", task.name, err)
+			failed = true
+		}
+	}
+	if failed {
+		lock.Unlock()
+		os.Exit(2)
+	}
+}
+
+// maintenanceTasksToRun returns the tasks named with --task or, if none are,
+// those enabled, and if level is not zero, due at the schedule with that
+// level. Tasks due more often are due too, so the daily run includes the
+// hourly tasks.
+func maintenanceTasksToRun(level int) ([]*maintenanceTask, error) {
+	if len(maintenanceRunTasks) > 0 {
+		tasks := make([]*maintenanceTask, 0, len(maintenanceRunTasks))
+		for _, name := range maintenanceRunTasks {
+			task := findMaintenanceTask(name)
+			if task == nil {
+				return nil, errors.Errorf("Unknown maintenance task %q", name)
+			}
+			tasks = append(tasks, task)
+		}
+		return tasks, nil
+	}
+
+	var tasks []*maintenanceTask
+	for _, task := range maintenanceTasks {
+		if !task.enabled() {
+			continue
+		}
+		if level > 0 {
+			taskLevel, ok := maintenanceScheduleLevels[task.scheduled()]
+			if !ok {
+				return nil, errors.Errorf("Invalid schedule %q for maintenance task %q", task.scheduled(), task.name)
+			}
+			if taskLevel > level {
+				continue
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func findMaintenanceTask(name string) *maintenanceTask {
+	for _, task := range maintenanceTasks {
+		if task.name == name {
+			return task
+		}
+	}
+	return nil
+}
+
+// maintenanceRunRegisteredRepos runs "git lfs maintenance run" in each of the
+// repositories registered with "git lfs maintenance start", as the scheduler
+// does.
+func maintenanceRunRegisteredRepos() {
+	exe, err := os.Executable()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	args := []string{"maintenance", "run"}
+	if len(maintenanceRunSchedule) > 0 {
+		args = append(args, "--schedule="+maintenanceRunSchedule)
+	}
+	for _, task := range maintenanceRunTasks {
+		args = append(args, "--task="+task)
+	}
+
+	failed := false
+	for _, repo := range cfg.GitConfig().FindGlobalAll(maintenanceRepoKey) {
+		Print("maintenance: %s", repo)
+		cmd := subprocess.ExecCommand(exe, args...)
+		cmd.Dir = repo
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			Error("maintenance: %s failed: %v", repo, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+}
+
+// maintenanceStartCommand registers the current repository to have its
+// maintenance tasks run by the scheduler, and schedules them.
+func maintenanceStartCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	repo := maintenanceRepoPath()
+	registered := false
+	for _, r := range cfg.GitConfig().FindGlobalAll(maintenanceRepoKey) {
+		registered = registered || r == repo
+	}
+	if !registered {
+		if _, err := cfg.GitConfig().AddGlobal(maintenanceRepoKey, repo); err != nil {
+			ExitWithError(err)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		ExitWithError(err)
+	}
+	if err := scheduleMaintenance(exe); err != nil {
+		Exit("Could not schedule maintenance: %v", err)
+	}
+	Print("Scheduled maintenance for %s", repo)
+}
+
+// maintenanceStopCommand unregisters the current repository, and once no
+// repositories are registered, removes the schedule.
+func maintenanceStopCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	repo := maintenanceRepoPath()
+	for _, r := range cfg.GitConfig().FindGlobalAll(maintenanceRepoKey) {
+		if r == repo {
+			if _, err := cfg.GitConfig().UnsetGlobalValue(maintenanceRepoKey, repo); err != nil {
+				ExitWithError(err)
+			}
+			break
+		}
+	}
+	Print("Stopped maintenance for %s", repo)
+
+	if len(cfg.GitConfig().FindGlobalAll(maintenanceRepoKey)) > 0 {
+		return
+	}
+	if err := unscheduleMaintenance(); err != nil {
+		Exit("Could not remove maintenance schedule: %v", err)
+	}
+}
+
+// maintenanceRepoPath returns the path by which the current repository is
+// registered for maintenance: its working tree or, if it is bare, its Git
+// directory.
+func maintenanceRepoPath() string {
+	if dir := cfg.LocalWorkingDir(); len(dir) > 0 {
+		return dir
+	}
+	return cfg.LocalGitDir()
+}
+
+// maintenancePackCommand moves the small objects in the object directory into
+// pack files, whatever the configured storage backend, so that repositories
+// with very many of them need fewer files.
+func maintenancePackCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	maxSizeFmt := maintenancePackMaxSize
+	if len(maxSizeFmt) == 0 {
+		maxSizeFmt, _ = cfg.Git.Get("lfs.pack.maxobjectsize")
+	}
+	if len(maxSizeFmt) == 0 {
+		maxSizeFmt = defaultMaintenancePackMaxSize
+	}
+	maxSize, err := humanize.ParseBytes(maxSizeFmt)
+	if err != nil {
+		Exit("Invalid maximum object size %q: %v", maxSizeFmt, err)
+	}
+
+	f := cfg.Filesystem()
+	packed, size := moveLooseObjects(f.PackObject, func(obj fs.Object) bool {
+		return uint64(obj.Size) <= maxSize
+	})
+
+	freed, err := f.CompactObjectStore()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	Print("maintenance pack: %d object(s) packed (%s)", packed, humanize.FormatBytes(uint64(size)))
+	if freed > 0 {
+		Print("maintenance pack: %s reclaimed from pruned objects", humanize.FormatBytes(uint64(freed)))
+	}
+}
+
+func init() {
+	RegisterCommand("maintenance", nil, func(cmd *cobra.Command) {
+		runCmd := NewCommand("run", maintenanceRunCommand)
+		runCmd.Flags().StringSliceVar(&maintenanceRunTasks, "task", nil, "Run only the named task")
+		runCmd.Flags().StringVar(&maintenanceRunSchedule, "schedule", "", "Run the tasks due hourly, daily, or weekly")
+		runCmd.Flags().BoolVar(&maintenanceRunRegistered, "registered", false, "Run in each registered repository")
+
+		startCmd := NewCommand("start", maintenanceStartCommand)
+		stopCmd := NewCommand("stop", maintenanceStopCommand)
+
+		packCmd := NewCommand("pack", maintenancePackCommand)
+		packCmd.Flags().StringVar(&maintenancePackMaxSize, "max-size", "", "Pack objects up to this size")
+
+		cmd.AddCommand(runCmd, startCmd, stopCmd, packCmd)
+	})
+}
@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/filepathfilter"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestFormat    string
+	manifestOutput    string
+	manifestSignature string
+	manifestVerify    bool
+)
+
+const (
+	// manifestFormatSHA256Sums is the format of sha256sum(1), as in a
+	// SHA256SUMS file.
+	manifestFormatSHA256Sums = "sha256sums"
+	// manifestFormatInToto is an in-toto attestation statement, whose
+	// subjects are the Git LFS files.
+	manifestFormatInToto = "in-toto"
+)
+
+// manifestPredicateType identifies the predicate of the in-toto statements
+// which "git lfs manifest" writes.
+const manifestPredicateType = "https://git-lfs.github.com/spec/manifest/v1"
+
+// inTotoStatement is an in-toto attestation statement, version 1.
+type inTotoStatement struct {
+	Type          string             `json:"_type"`
+	Subject       []*inTotoSubject   `json:"subject"`
+	PredicateType string             `json:"predicateType"`
+	Predicate     *manifestPredicate `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// manifestPredicate describes the tree whose Git LFS files are the subjects of
+// an in-toto statement.
+type manifestPredicate struct {
+	Ref    string `json:"ref"`
+	Commit string `json:"commit"`
+}
+
+func manifestCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) == 0 {
+		Print("Usage: git lfs manifest [--format=<fmt>] [-o <file>] [--signature=<file>] [--verify] <tree-ish> [<path>...]")
+		os.Exit(1)
+	}
+
+	switch manifestFormat {
+	case manifestFormatSHA256Sums, manifestFormatInToto:
+	default:
+		Exit("fatal: unknown manifest format %q, expected %s or %s",
+			manifestFormat, manifestFormatSHA256Sums, manifestFormatInToto)
+	}
+
+	treeish := args[0]
+	ref, err := git.ResolveRef(treeish)
+	if err != nil {
+		Exit("fatal: not a valid tree-ish: %q", treeish)
+	}
+
+	pointers := manifestPointers(ref.Sha, filepathfilter.New(rootedPaths(args[1:]), nil))
+	if manifestVerify && !checkManifestObjects(pointers) {
+		os.Exit(2)
+	}
+
+	var manifest []byte
+	if manifestFormat == manifestFormatInToto {
+		manifest, err = inTotoManifest(treeish, ref.Sha, pointers)
+	} else {
+		manifest = sha256SumsManifest(pointers)
+	}
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	if len(manifestOutput) > 0 && manifestOutput != "-" {
+		if err := ioutil.WriteFile(manifestOutput, manifest, 0644); err != nil {
+			Exit("fatal: could not write %q: %v", manifestOutput, err)
+		}
+	} else {
+		os.Stdout.Write(manifest)
+	}
+
+	if len(manifestSignature) > 0 {
+		name, email := cfg.CurrentCommitter()
+		signer, err := git.NewSigner(cfg.GitEnv(), fmt.Sprintf("%s <%s>", name, email))
+		if err != nil {
+			Exit("fatal: cannot sign manifest: %v", err)
+		}
+		sig, err := signer.Sign(manifest)
+		if err != nil {
+			Exit("fatal: cannot sign manifest: %v", err)
+		}
+		if err := ioutil.WriteFile(manifestSignature, sig, 0644); err != nil {
+			Exit("fatal: could not write %q: %v", manifestSignature, err)
+		}
+	}
+}
+
+// manifestPointers returns the pointers of the Git LFS files in the tree of the
+// given commit which the filter allows, sorted by path.
+func manifestPointers(ref string, filter *filepathfilter.Filter) []*lfs.WrappedPointer {
+	var pointers []*lfs.WrappedPointer
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			Panic(err, "Could not scan for Git LFS files")
+		}
+		pointers = append(pointers, p)
+	})
+	gitscanner.Filter = filter
+
+	if err := gitscanner.ScanTree(ref); err != nil {
+		ExitWithError(err)
+	}
+	gitscanner.Close()
+
+	sort.Slice(pointers, func(i, j int) bool { return pointers[i].Name < pointers[j].Name })
+	return pointers
+}
+
+// checkManifestObjects checks that the object of each of the given pointers
+// is present locally and matches its OID, and so the content which the
+// manifest attests to is what would be checked out. It returns whether they
+// all do.
+func checkManifestObjects(pointers []*lfs.WrappedPointer) bool {
+	ok := true
+	for _, p := range pointers {
+		if p.Size == 0 {
+			continue
+		}
+		if !cfg.LFSObjectExists(p.Oid, p.Size) {
+			Error("manifest: missingObject: %s (%s) is not present locally; run `git lfs fetch` to download it", p.Name, p.Oid)
+			ok = false
+			continue
+		}
+
+		oid, err := hashObject(p.Oid)
+		if err != nil {
+			Error("manifest: openError: %s (%s) could not be checked: %v", p.Name, p.Oid, err)
+			ok = false
+		} else if oid != p.Oid {
+			Error("manifest: corruptObject: %s (%s) is corrupt", p.Name, p.Oid)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// hashObject returns the OID of the content of the given local object,
+// whichever storage backend holds it.
+func hashObject(oid string) (string, error) {
+	f, err := cfg.Filesystem().OpenObject(oid)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	oidHash := sha256.New()
+	if _, err := io.Copy(oidHash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(oidHash.Sum(nil)), nil
+}
+
+// sha256SumsManifest returns the given pointers in the format of sha256sum(1),
+// which may be checked with "sha256sum -c" against the files once checked
+// out. As in sha256sum(1), lines for paths containing a backslash or newline
+// begin with a backslash, and those characters are escaped.
+func sha256SumsManifest(pointers []*lfs.WrappedPointer) []byte {
+	var buf bytes.Buffer
+	for _, p := range pointers {
+		name := p.Name
+		if strings.ContainsAny(name, "\\\n") {
+			name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+			buf.WriteByte('\\')
+		}
+		fmt.Fprintf(&buf, "%s  %s\n", p.Oid, name)
+	}
+	return buf.Bytes()
+}
+
+// inTotoManifest returns an in-toto statement whose subjects are the files of
+// the given pointers, in the tree of the given commit.
+func inTotoManifest(treeish, commit string, pointers []*lfs.WrappedPointer) ([]byte, error) {
+	statement := &inTotoStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       make([]*inTotoSubject, 0, len(pointers)),
+		PredicateType: manifestPredicateType,
+		Predicate:     &manifestPredicate{Ref: treeish, Commit: commit},
+	}
+	for _, p := range pointers {
+		statement.Subject = append(statement.Subject, &inTotoSubject{
+			Name:   p.Name,
+			Digest: map[string]string{"sha256": p.Oid},
+		})
+	}
+
+	manifest, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(manifest, '\n'), nil
+}
+
+func init() {
+	RegisterCommand("manifest", manifestCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVar(&manifestFormat, "format", manifestFormatSHA256Sums, "Write the manifest in this format")
+		cmd.Flags().StringVarP(&manifestOutput, "output", "o", "", "Write the manifest to this file")
+		cmd.Flags().StringVar(&manifestSignature, "signature", "", "Write a detached signature of the manifest to this file")
+		cmd.Flags().BoolVar(&manifestVerify, "verify", false, "Check that each object is present locally and not corrupt")
+	})
+}
@@ -1,15 +1,19 @@
 package commands
 
 import (
-	"bufio"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/git/githistory"
 	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tools"
 	"github.com/git-lfs/gitobj/v2"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +30,10 @@ var (
 	// whenever 'git lfs migrate' asks for user input.
 	migrateYes bool
 
+	// migrateDryRun indicates that 'git lfs migrate' should show the refs
+	// it would update, and not update them.
+	migrateDryRun bool
+
 	// migrateSkipFetch assumes that the client has the latest copy of
 	// remote references, and thus should not contact the remote for a set
 	// of updated references.
@@ -53,6 +61,17 @@ var (
 	// migrateCommitMessage is the message to use with the commit generated
 	// by the migrate command
 	migrateCommitMessage string
+	// migrateStdin indicates that 'git lfs migrate import --no-rewrite'
+	// should read the files to import from standard input, and
+	// migrateNulDelimited that they are NUL-delimited rather than on
+	// separate lines.
+	migrateStdin        bool
+	migrateNulDelimited bool
+	// migrateGPGSign is the key with which to sign the commit generated by
+	// 'git lfs migrate import --no-rewrite', if the --gpg-sign flag is
+	// given, or migrateGPGSignDefaultKey to use the committer's default
+	// key.
+	migrateGPGSign string
 
 	// exportRemote is the remote from which to download objects when
 	// performing an export
@@ -61,6 +80,18 @@ var (
 	// migrateFixup is the flag indicating whether or not to infer the
 	// included and excluded filepath patterns.
 	migrateFixup bool
+
+	// migrateReplace indicates that 'git lfs migrate import' should create
+	// replace refs for the rewritten commits rather than move any refs,
+	// and migrateObjectDir is the object directory, if any, to which it
+	// should write the rewritten objects.
+	migrateReplace   bool
+	migrateObjectDir string
+
+	// migrateSignedCommits is what to do with the signatures of rewritten
+	// commits: keep them, although they are no longer valid, strip them,
+	// or re-sign the commits.
+	migrateSignedCommits string
 )
 
 // migrate takes the given command and arguments, *gitobj.ObjectDatabase, as well
@@ -90,6 +121,60 @@ func getObjectDatabase() (*gitobj.ObjectDatabase, error) {
 	return git.ObjectDatabase(cfg.OSEnv(), cfg.GitEnv(), dir, cfg.TempDir())
 }
 
+// getOverlayObjectDatabase creates a *git.ObjectDatabase which writes objects
+// to the given object directory, and reads them from there or from the
+// repository. Unless this is a dry run, the directory is added to the
+// repository's alternates, so that Git can read the objects written to it.
+func getOverlayObjectDatabase(objectDir string) (*gitobj.ObjectDatabase, error) {
+	dir, err := git.GitCommonDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open root")
+	}
+
+	objectDir, err = filepath.Abs(objectDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := tools.MkdirAll(objectDir, cfg); err != nil {
+		return nil, errors.Wrapf(err, "cannot create object directory %s", objectDir)
+	}
+	if !migrateDryRun {
+		if err := addAlternate(filepath.Join(dir, "objects"), objectDir); err != nil {
+			return nil, errors.Wrapf(err, "cannot add %s to the alternates", objectDir)
+		}
+	}
+
+	return git.OverlayObjectDatabase(cfg.OSEnv(), cfg.GitEnv(), dir, objectDir, cfg.TempDir())
+}
+
+// addAlternate adds the object directory "alternate" to the alternates of the
+// object directory "objects", unless it is already one of them.
+func addAlternate(objects, alternate string) error {
+	path := filepath.Join(objects, "info", "alternates")
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.TrimSpace(line) == alternate {
+			return nil
+		}
+	}
+
+	if err := tools.MkdirAll(filepath.Dir(path), cfg); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, alternate); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // rewriteOptions returns *githistory.RewriteOptions able to be passed to a
 // *githistory.Rewriter that reflect the current arguments and flags passed to
 // an invocation of git-lfs-migrate(1).
@@ -109,17 +194,33 @@ func rewriteOptions(args []string, opts *githistory.RewriteOptions, l *tasklog.L
 		return nil, err
 	}
 
+	signatures, err := newSignedCommits(migrateSignedCommits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report what became of the signatures of rewritten commits before
+	// the refs are moved onto them.
+	confirm := opts.ConfirmFn
+	confirmFn := func(rewritten int, changes []*githistory.RefChange) bool {
+		signatures.report(l)
+		return confirm == nil || confirm(rewritten, changes)
+	}
+
 	return &githistory.RewriteOptions{
 		Include: include,
 		Exclude: exclude,
 
 		UpdateRefs:        opts.UpdateRefs,
+		ReplaceRefs:       opts.ReplaceRefs,
+		ConfirmFn:         confirmFn,
 		Verbose:           opts.Verbose,
 		ObjectMapFilePath: opts.ObjectMapFilePath,
 
 		BlobFn:            opts.BlobFn,
 		TreePreCallbackFn: opts.TreePreCallbackFn,
 		TreeCallbackFn:    opts.TreeCallbackFn,
+		CommitCallbackFn:  signatures.rewrite,
 	}, nil
 }
 
@@ -309,11 +410,75 @@ func getHistoryRewriter(cmd *cobra.Command, db *gitobj.ObjectDatabase, l *tasklo
 	include, exclude := getIncludeExcludeArgs(cmd)
 	filter := buildFilepathFilter(cfg, include, exclude, false)
 
+	// With --object-dir, the object database is rooted there, so run Git
+	// in the repository's own object directory instead.
+	var root string
+	if len(migrateObjectDir) > 0 {
+		dir, err := git.GitCommonDir()
+		if err != nil {
+			ExitWithError(errors.Wrap(err, "cannot open root"))
+		}
+		root = filepath.Join(dir, "objects")
+	}
+
 	return githistory.NewRewriter(db,
-		githistory.WithFilter(filter), githistory.WithLogger(l))
+		githistory.WithFilter(filter), githistory.WithLogger(l),
+		githistory.WithRoot(root))
+}
+
+// confirmRefUpdates returns a githistory.ConfirmFn which shows the refs that a
+// migration would update, and asks whether to update them, unless --dry-run or
+// --yes is given, or the answer is not read from a terminal. It sets
+// "confirmed" to whether the refs are to be updated.
+func confirmRefUpdates(l *tasklog.Logger, confirmed *bool) githistory.ConfirmFn {
+	return func(rewritten int, changes []*githistory.RefChange) bool {
+		if len(changes) == 0 && !migrateDryRun {
+			*confirmed = true
+			return true
+		}
+
+		// Let the logger finish writing the progress of the rewrite
+		// before showing the preview.
+		l.Simple().Complete()
+
+		p := &preview{
+			summary:  fmt.Sprintf("migrate: %d commit(s) rewritten, %d ref(s) would be updated", rewritten, len(changes)),
+			question: "migrate: update these refs?",
+			columns:  []string{"REF", "OLD", "NEW"},
+		}
+		if migrateReplace {
+			p.summary = fmt.Sprintf("migrate: %d commit(s) rewritten, %d replace ref(s) would be created", rewritten, len(changes))
+			p.question = "migrate: create these replace refs?"
+			p.columns = []string{"REF", "NEW"}
+		}
+		for _, c := range changes {
+			if migrateReplace {
+				p.add(c.Ref.Refspec(), hex.EncodeToString(c.To))
+			} else {
+				p.add(c.Ref.Refspec(), hex.EncodeToString(c.From), hex.EncodeToString(c.To))
+			}
+		}
+
+		*confirmed = p.confirm(os.Stdin, os.Stderr, migrateDryRun, migrateYes, migrateVerbose)
+		return *confirmed
+	}
+}
+
+// exitUnconfirmed exits after the refs a migration would update were shown,
+// and were not to be updated, successfully if that was because of --dry-run.
+func exitUnconfirmed() {
+	if migrateDryRun {
+		os.Exit(0)
+	}
+	Exit("migrate: aborted, no refs were updated")
 }
 
 func ensureWorkingCopyClean(in io.Reader, out io.Writer) {
+	if migrateDryRun {
+		// Nothing in the working copy is changed.
+		return
+	}
+
 	dirty, err := git.IsWorkingCopyDirty()
 	if err != nil {
 		ExitWithError(errors.Wrap(err,
@@ -324,37 +489,8 @@ func ensureWorkingCopyClean(in io.Reader, out io.Writer) {
 		return
 	}
 
-	var proceed bool
-	if migrateYes {
-		proceed = true
-	} else {
-		answer := bufio.NewReader(in)
-	L:
-		for {
-			fmt.Fprintf(out, "migrate: override changes in your working copy?  All uncommitted changes will be lost! [y/N] ")
-			s, err := answer.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					break L
-				}
-				ExitWithError(errors.Wrap(err,
-					"fatal: could not read answer"))
-			}
-
-			switch strings.TrimSpace(s) {
-			case "n", "N", "":
-				proceed = false
-				break L
-			case "y", "Y":
-				proceed = true
-				break L
-			}
-
-			if !strings.HasSuffix(s, "\n") {
-				fmt.Fprintf(out, "\n")
-			}
-		}
-	}
+	proceed := migrateYes || askYesNo(in, out,
+		"migrate: override changes in your working copy?  All uncommitted changes will be lost!")
 
 	if proceed {
 		fmt.Fprintf(out, "migrate: changes in your working copy will be overridden ...\n")
@@ -377,7 +513,15 @@ func init() {
 	importCmd.Flags().StringVar(&objectMapFilePath, "object-map", "", "Object map file")
 	importCmd.Flags().BoolVar(&migrateNoRewrite, "no-rewrite", false, "Add new history without rewriting previous")
 	importCmd.Flags().StringVarP(&migrateCommitMessage, "message", "m", "", "With --no-rewrite, an optional commit message")
+	importCmd.Flags().BoolVar(&migrateStdin, "stdin", false, "With --no-rewrite, read the files to import from standard input")
+	importCmd.Flags().BoolVarP(&migrateNulDelimited, "null", "z", false, "With --stdin, read NUL-delimited files")
+	importCmd.Flags().StringVarP(&migrateGPGSign, "gpg-sign", "S", "", "With --no-rewrite, sign the commit")
+	importCmd.Flags().Lookup("gpg-sign").NoOptDefVal = migrateGPGSignDefaultKey
 	importCmd.Flags().BoolVar(&migrateFixup, "fixup", false, "Infer filepaths based on .gitattributes")
+	importCmd.Flags().BoolVar(&migrateReplace, "replace", false, "Create replace refs for the rewritten commits instead of updating refs")
+	importCmd.Flags().StringVar(&migrateObjectDir, "object-dir", "", "With --replace, write the rewritten objects to this alternate object directory")
+
+	rollbackCmd := NewCommand("rollback", migrateRollbackCommand)
 
 	exportCmd := NewCommand("export", migrateExportCommand)
 	exportCmd.Flags().BoolVar(&migrateVerbose, "verbose", false, "Verbose logging")
@@ -394,7 +538,9 @@ func init() {
 		cmd.PersistentFlags().BoolVar(&migrateSkipFetch, "skip-fetch", false, "Assume up-to-date remote references.")
 
 		cmd.PersistentFlags().BoolVarP(&migrateYes, "yes", "y", false, "Don't prompt for answers.")
+		cmd.PersistentFlags().BoolVar(&migrateDryRun, "dry-run", false, "Show the refs which would be updated, and don't update them.")
+		cmd.PersistentFlags().StringVar(&migrateSignedCommits, "signed-commits", signedCommitsKeep, "What to do with the signatures of rewritten commits: keep, strip, or resign")
 
-		cmd.AddCommand(exportCmd, importCmd, info)
+		cmd.AddCommand(exportCmd, importCmd, info, rollbackCmd)
 	})
 }
@@ -18,9 +18,11 @@ import (
 
 func migrateExportCommand(cmd *cobra.Command, args []string) {
 	ensureWorkingCopyClean(os.Stdin, os.Stderr)
+	defer acquireOperationLock("migrate")()
 
 	l := tasklog.NewLogger(os.Stderr,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	defer l.Close()
 
@@ -40,6 +42,7 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 	tracked := trackedFromExportFilter(filter)
 	gitfilter := lfs.NewGitFilter(cfg)
 
+	var confirmed bool
 	opts := &githistory.RewriteOptions{
 		Verbose:           migrateVerbose,
 		ObjectMapFilePath: objectMapFilePath,
@@ -56,7 +59,7 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 				return nil, err
 			}
 
-			downloadPath, err := gitfilter.ObjectPath(ptr.Oid)
+			downloadPath, err := cfg.Filesystem().UnpackedObjectPath(ptr.Oid)
 			if err != nil {
 				return nil, err
 			}
@@ -100,6 +103,7 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 		},
 
 		UpdateRefs: true,
+		ConfirmFn:  confirmRefUpdates(l, &confirmed),
 	}
 
 	setupRepository()
@@ -135,7 +139,7 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 				return
 			}
 
-			if _, err := os.Stat(downloadPath); os.IsNotExist(err) {
+			if !cfg.LFSObjectExists(p.Oid, p.Size) {
 				q.Add(p.Name, downloadPath, p.Oid, p.Size, false, nil)
 			}
 		})
@@ -154,6 +158,9 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 	if _, err := rewriter.Rewrite(opts); err != nil {
 		ExitWithError(err)
 	}
+	if !confirmed {
+		exitUnconfirmed()
+	}
 
 	// Only perform `git-checkout(1) -f` if the repository is non-bare.
 	if bare, _ := git.IsBare(); !bare {
@@ -175,7 +182,7 @@ func migrateExportCommand(cmd *cobra.Command, args []string) {
 	fetchPruneCfg.FetchRecentRefsDays = 0
 
 	// Prune our cache
-	prune(fetchPruneCfg, false, false, true)
+	prune(fetchPruneCfg, false, false, true, true)
 }
 
 // trackedFromExportFilter returns an ordered set of strings where each entry
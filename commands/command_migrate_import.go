@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
@@ -22,15 +23,43 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// migrateGPGSignDefaultKey is the value of --gpg-sign when it is given without
+// a key, which signs with the committer's default key.
+const migrateGPGSignDefaultKey = "default"
+
 func migrateImportCommand(cmd *cobra.Command, args []string) {
+	if migrateStdin {
+		if !migrateNoRewrite {
+			ExitWithError(errors.Errorf("fatal: --stdin requires --no-rewrite"))
+		}
+		// Read the files before ensureWorkingCopyClean can read an
+		// answer from standard input.
+		args = append(args, readMigratePaths()...)
+	}
+
+	if migrateReplace && migrateNoRewrite {
+		ExitWithError(errors.Errorf("fatal: --replace and --no-rewrite cannot be combined"))
+	}
+	if len(migrateObjectDir) > 0 && !migrateReplace {
+		ExitWithError(errors.Errorf("fatal: --object-dir requires --replace"))
+	}
+
 	ensureWorkingCopyClean(os.Stdin, os.Stderr)
+	defer acquireOperationLock("migrate")()
 
 	l := tasklog.NewLogger(os.Stderr,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	defer l.Close()
 
-	db, err := getObjectDatabase()
+	var db *gitobj.ObjectDatabase
+	var err error
+	if len(migrateObjectDir) > 0 {
+		db, err = getOverlayObjectDatabase(migrateObjectDir)
+	} else {
+		db, err = getObjectDatabase()
+	}
 	if err != nil {
 		ExitWithError(err)
 	}
@@ -39,7 +68,9 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 	// To avoid confusion later, let's make sure that we've installed the
 	// necessary hooks so that a newly migrated repository is `git
 	// push`-able immediately following a `git lfs migrate import`.
-	installHooks(false)
+	if !migrateDryRun {
+		installHooks(false)
+	}
 
 	if migrateNoRewrite {
 		if migrateFixup {
@@ -63,15 +94,15 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 
 		root := commit.TreeID
 
-		filter := git.GetAttributeFilter(cfg.LocalWorkingDir(), cfg.LocalGitDir())
-		if len(filter.Include()) == 0 {
-			ExitWithError(errors.Errorf("fatal: no Git LFS filters found in .gitattributes"))
+		tracked, err := noRewriteTrackedFn(db, root)
+		if err != nil {
+			ExitWithError(err)
 		}
 
 		gf := lfs.NewGitFilter(cfg)
 
 		for _, file := range args {
-			if !filter.Allows(file) {
+			if !tracked(file) {
 				ExitWithError(errors.Errorf("fatal: file %s did not match any Git LFS filters in .gitattributes", file))
 			}
 		}
@@ -83,36 +114,68 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		name, email := cfg.CurrentAuthor()
-		author := &gitobj.Signature{
-			Name:  name,
-			Email: email,
-			When:  cfg.CurrentAuthorTimestamp(),
-		}
+		message := generateMigrateCommitMessage(cmd, args)
 
-		name, email = cfg.CurrentCommitter()
-		committer := &gitobj.Signature{
-			Name:  name,
-			Email: email,
-			When:  cfg.CurrentCommitterTimestamp(),
-		}
+		var oid []byte
+		if cmd.Flag("gpg-sign").Changed {
+			// gitobj cannot sign commits, so leave it to Git.
+			key := migrateGPGSign
+			if key == migrateGPGSignDefaultKey {
+				key = ""
+			}
+			oid, err = git.CommitTree(root, [][]byte{sha}, message, true, key)
+		} else {
+			name, email := cfg.CurrentAuthor()
+			author := &gitobj.Signature{
+				Name:  name,
+				Email: email,
+				When:  cfg.CurrentAuthorTimestamp(),
+			}
 
-		oid, err := db.WriteCommit(&gitobj.Commit{
-			Author:    author.String(),
-			Committer: committer.String(),
-			ParentIDs: [][]byte{sha},
-			Message:   generateMigrateCommitMessage(cmd, strings.Join(args, ",")),
-			TreeID:    root,
-		})
+			name, email = cfg.CurrentCommitter()
+			committer := &gitobj.Signature{
+				Name:  name,
+				Email: email,
+				When:  cfg.CurrentCommitterTimestamp(),
+			}
+
+			oid, err = db.WriteCommit(&gitobj.Commit{
+				Author:    author.String(),
+				Committer: committer.String(),
+				ParentIDs: [][]byte{sha},
+				Message:   message,
+				TreeID:    root,
+			})
+		}
 
 		if err != nil {
 			ExitWithError(errors.Wrap(err, "fatal: unable to write commit"))
 		}
 
+		p := &preview{
+			summary:  "migrate: 1 commit(s) added, 1 ref(s) would be updated",
+			question: "migrate: update this ref?",
+			columns:  []string{"REF", "OLD", "NEW"},
+		}
+		p.add(ref.Refspec(), ref.Sha, hex.EncodeToString(oid))
+		if !p.confirm(os.Stdin, os.Stderr, migrateDryRun, migrateYes, false) {
+			exitUnconfirmed()
+		}
+
 		if err := git.UpdateRef(ref, oid, "git lfs migrate import --no-rewrite"); err != nil {
 			ExitWithError(errors.Wrap(err, "fatal: unable to update ref"))
 		}
 
+		record, err := newMigrateRecord()
+		if err == nil {
+			if err = record.add(ref.Refspec(), ref.Sha); err == nil {
+				err = record.save()
+			}
+		}
+		if err != nil {
+			Error("migrate: could not record the migration, so it cannot be rolled back: %v", err)
+		}
+
 		if err := checkoutNonBare(l); err != nil {
 			ExitWithError(errors.Wrap(err, "fatal: could not checkout"))
 		}
@@ -139,9 +202,27 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 		ExitWithError(errors.Wrap(err, "fatal: cannot parse --above=<n>"))
 	}
 
+	// Keep a record of the migration for 'git lfs migrate rollback',
+	// including the map of the commits it rewrote. With --replace, no
+	// refs are moved, and the migration is undone by deleting the replace
+	// refs instead.
+	var record *migrateRecord
+	mapPath := objectMapFilePath
+	if !migrateDryRun && !migrateReplace {
+		record, err = newMigrateRecord()
+		if err != nil {
+			ExitWithError(err)
+		}
+		if len(mapPath) == 0 {
+			mapPath = record.commitMapPath()
+		}
+	}
+
+	var confirmed bool
+	var changes []*githistory.RefChange
 	migrate(args, rewriter, l, &githistory.RewriteOptions{
 		Verbose:           migrateVerbose,
-		ObjectMapFilePath: objectMapFilePath,
+		ObjectMapFilePath: mapPath,
 		BlobFn: func(path string, b *gitobj.Blob) (*gitobj.Blob, error) {
 			if filepath.Base(path) == ".gitattributes" {
 				return b, nil
@@ -250,8 +331,21 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 			}), nil
 		},
 
-		UpdateRefs: true,
+		UpdateRefs:  true,
+		ReplaceRefs: migrateReplace,
+		ConfirmFn:   recordRefUpdates(confirmRefUpdates(l, &confirmed), &changes),
 	})
+	if !confirmed {
+		if record != nil {
+			record.remove()
+		}
+		exitUnconfirmed()
+	}
+	if record != nil {
+		if err := record.saveChanges(changes, mapPath); err != nil {
+			Error("migrate: could not record the migration, so it cannot be rolled back: %v", err)
+		}
+	}
 
 	if err := checkoutNonBare(l); err != nil {
 		ExitWithError(errors.Wrap(err, "fatal: could not checkout"))
@@ -259,12 +353,102 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 }
 
 // generateMigrateCommitMessage generates a commit message used with
-// --no-rewrite, using --message (if given) or generating one if it isn't.
-func generateMigrateCommitMessage(cmd *cobra.Command, patterns string) string {
+// --no-rewrite for the given files, using --message (if given) or generating
+// one if it isn't.
+func generateMigrateCommitMessage(cmd *cobra.Command, files []string) string {
 	if cmd.Flag("message").Changed {
-		return migrateCommitMessage
+		return expandMigrateCommitMessage(migrateCommitMessage, files)
+	}
+	return fmt.Sprintf("%s: convert to Git LFS", strings.Join(files, ","))
+}
+
+// expandMigrateCommitMessage replaces the placeholders in the given --message
+// template: "%f" with the comma-separated list of files, "%n" with the number
+// of them, and "%%" with a single "%".
+func expandMigrateCommitMessage(template string, files []string) string {
+	var msg strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			msg.WriteByte(template[i])
+			continue
+		}
+
+		switch template[i+1] {
+		case 'f':
+			msg.WriteString(strings.Join(files, ","))
+		case 'n':
+			msg.WriteString(strconv.Itoa(len(files)))
+		case '%':
+			msg.WriteByte('%')
+		default:
+			msg.WriteString(template[i : i+2])
+		}
+		i++
+	}
+	return msg.String()
+}
+
+// readMigratePaths reads the list of files given to --stdin, one per line, or
+// NUL-delimited with -z.
+func readMigratePaths() []string {
+	requireStdin("The --stdin flag expects a list of files.")
+
+	var paths []string
+	scanner := bufio.NewScanner(os.Stdin)
+	if migrateNulDelimited {
+		scanner.Split(splitOnNulOrEOF)
+	}
+	for scanner.Scan() {
+		if path := scanner.Text(); len(path) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		ExitWithError(errors.Wrap(err, "fatal: could not read files from standard input"))
+	}
+	return paths
+}
+
+// noRewriteTrackedFn returns a function which reports whether a file is
+// tracked by a Git LFS filter, and so may be imported with --no-rewrite. A bare
+// repository has no working copy from which to read the .gitattributes files,
+// so they are read from the given tree of the current commit instead.
+func noRewriteTrackedFn(db *gitobj.ObjectDatabase, root []byte) (func(string) bool, error) {
+	filter := git.GetAttributeFilter(cfg.LocalWorkingDir(), cfg.LocalGitDir())
+
+	bare, err := git.IsBare()
+	if err != nil {
+		return nil, errors.Wrap(err, "fatal: unable to determine bareness")
 	}
-	return fmt.Sprintf("%s: convert to Git LFS", patterns)
+	if !bare {
+		if len(filter.Include()) == 0 {
+			return nil, errors.Errorf("fatal: no Git LFS filters found in .gitattributes")
+		}
+		return filter.Allows, nil
+	}
+
+	t, err := db.Tree(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "fatal: unable to load tree")
+	}
+	attrs, err := gitattr.New(db, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "fatal: unable to read .gitattributes")
+	}
+
+	return func(path string) bool {
+		if len(filter.Include()) > 0 && filter.Allows(path) {
+			return true
+		}
+
+		var ok bool
+		for _, attr := range attrs.Applied(path) {
+			if attr.K == "filter" {
+				ok = attr.V == "lfs"
+			}
+		}
+		return ok
+	}, nil
 }
 
 // checkoutNonBare forces a checkout of the current reference, so long as the
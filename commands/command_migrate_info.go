@@ -59,6 +59,7 @@ var (
 func migrateInfoCommand(cmd *cobra.Command, args []string) {
 	l := tasklog.NewLogger(os.Stderr,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 
 	db, err := getObjectDatabase()
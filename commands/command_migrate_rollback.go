@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/git/githistory"
+	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// migrateRecordPendingSuffix ends the name of the directory of a
+	// record which is still being written, and which 'git lfs migrate
+	// rollback' ignores.
+	migrateRecordPendingSuffix = ".pending"
+)
+
+// migrateRecord is the record which 'git lfs migrate import' keeps of the refs
+// it updated, and of the commits it rewrote, so that 'git lfs migrate rollback'
+// can undo it.
+type migrateRecord struct {
+	// dir is the directory which holds the record.
+	dir string
+	// refs are the refs which the migration updated.
+	refs []*migrateRecordRef
+}
+
+// migrateRecordRef is a ref which a migration updated, with its values before
+// and after the migration.
+type migrateRecordRef struct {
+	name     string
+	original string
+	migrated string
+}
+
+// migrateRecordsDir returns the directory in which the records of migrations
+// are kept.
+func migrateRecordsDir() string {
+	return filepath.Join(cfg.LocalGitStorageDir(), "lfs", "migrate")
+}
+
+// newMigrateRecord begins a record of a migration, which is only used by
+// 'git lfs migrate rollback' once it is saved.
+func newMigrateRecord() (*migrateRecord, error) {
+	name := fmt.Sprintf("%020d", time.Now().UnixNano())
+	dir := filepath.Join(migrateRecordsDir(), name+migrateRecordPendingSuffix)
+	if err := tools.MkdirAll(dir, cfg); err != nil {
+		return nil, errors.Wrap(err, "could not create migration record")
+	}
+	return &migrateRecord{dir: dir}, nil
+}
+
+// lastMigrateRecord returns the record of the most recent migration which has
+// not been rolled back, or nil if there is none.
+func lastMigrateRecord() (*migrateRecord, error) {
+	entries, err := ioutil.ReadDir(migrateRecordsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasSuffix(entry.Name(), migrateRecordPendingSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	r := &migrateRecord{dir: filepath.Join(migrateRecordsDir(), names[len(names)-1])}
+	f, err := os.Open(r.refsPath())
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read migration record %s", r.dir)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			return nil, errors.Errorf("invalid line in migration record %s: %q", r.dir, scanner.Text())
+		}
+		r.refs = append(r.refs, &migrateRecordRef{
+			name:     fields[0],
+			original: fields[1],
+			migrated: fields[2],
+		})
+	}
+	return r, scanner.Err()
+}
+
+func (r *migrateRecord) refsPath() string {
+	return filepath.Join(r.dir, "refs")
+}
+
+// commitMapPath returns the path of the file which maps the original commits
+// to the rewritten ones, in the format of the --object-map option.
+func (r *migrateRecord) commitMapPath() string {
+	return filepath.Join(r.dir, "commit-map")
+}
+
+// add records that the migration updated the given ref, which was originally
+// at the given object, to wherever it is now.
+func (r *migrateRecord) add(refname, original string) error {
+	ref, err := git.ResolveRef(refname)
+	if err != nil {
+		return err
+	}
+	r.refs = append(r.refs, &migrateRecordRef{
+		name:     refname,
+		original: original,
+		migrated: ref.Sha,
+	})
+	return nil
+}
+
+// copyCommitMap copies the file given to --object-map into the record.
+func (r *migrateRecord) copyCommitMap(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(r.commitMapPath())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// save writes the list of refs into the record, and makes it available to
+// 'git lfs migrate rollback'.
+func (r *migrateRecord) save() error {
+	f, err := os.Create(r.refsPath())
+	if err != nil {
+		return err
+	}
+	for _, ref := range r.refs {
+		fmt.Fprintf(f, "%s %s %s\n", ref.name, ref.original, ref.migrated)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	dir := strings.TrimSuffix(r.dir, migrateRecordPendingSuffix)
+	if err := os.Rename(r.dir, dir); err != nil {
+		return err
+	}
+	r.dir = dir
+	return nil
+}
+
+// remove removes the record, whether or not it was saved.
+func (r *migrateRecord) remove() error {
+	return os.RemoveAll(r.dir)
+}
+
+// commitMap returns the map from the hex-encoded rewritten commits to the
+// original ones.
+func (r *migrateRecord) commitMap() (map[string]string, error) {
+	f, err := os.Open(r.commitMapPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	m := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) == 2 {
+			m[fields[1]] = fields[0]
+		}
+	}
+	return m, scanner.Err()
+}
+
+// migrateRollbackCommand undoes the most recent 'git lfs migrate import' by
+// returning the refs it updated, and any others which point to the commits it
+// rewrote, to the original commits, and checking them out again.
+func migrateRollbackCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+	ensureWorkingCopyClean(os.Stdin, os.Stderr)
+	defer acquireOperationLock("migrate")()
+
+	l := tasklog.NewLogger(os.Stderr,
+		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
+	)
+	defer l.Close()
+
+	record, err := lastMigrateRecord()
+	if err != nil {
+		ExitWithError(err)
+	}
+	if record == nil {
+		Exit("migrate: there is no migration to roll back")
+	}
+
+	restores, err := migrateRestores(record)
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	db, err := getObjectDatabase()
+	if err != nil {
+		ExitWithError(err)
+	}
+	defer db.Close()
+
+	// The original objects are only kept while the reflog, or some
+	// other ref, keeps them, so check that none are missing before
+	// moving any refs.
+	for _, restore := range restores {
+		sha, err := hex.DecodeString(restore.original)
+		if err == nil {
+			_, err = db.Object(sha)
+		}
+		if err != nil {
+			ExitWithError(errors.Wrapf(err, "fatal: original object %s of %s is missing", restore.original, restore.name))
+		}
+	}
+
+	p := &preview{
+		summary:  fmt.Sprintf("migrate: %d ref(s) would be rolled back", len(restores)),
+		question: "migrate: roll back these refs?",
+		columns:  []string{"REF", "CURRENT", "ORIGINAL"},
+	}
+	for _, restore := range restores {
+		p.add(restore.name, restore.migrated, restore.original)
+	}
+	if !p.confirm(os.Stdin, os.Stderr, migrateDryRun, migrateYes, false) {
+		exitUnconfirmed()
+	}
+
+	list := l.List("migrate: Rolling back refs")
+	for _, restore := range restores {
+		sha, _ := hex.DecodeString(restore.original)
+		ref := git.ParseRef(restore.name, restore.migrated)
+		if err := git.UpdateRef(ref, sha, "git lfs migrate rollback"); err != nil {
+			ExitWithError(errors.Wrapf(err, "fatal: could not roll back %s", restore.name))
+		}
+		list.Entry(fmt.Sprintf("  %s\t%s -> %s", restore.name, restore.migrated, restore.original))
+	}
+	list.Complete()
+
+	if err := record.remove(); err != nil {
+		ExitWithError(errors.Wrap(err, "fatal: could not remove migration record"))
+	}
+
+	if err := checkoutNonBare(l); err != nil {
+		ExitWithError(errors.Wrap(err, "fatal: could not checkout"))
+	}
+}
+
+// migrateRestores returns the refs which rolling back the given migration
+// would restore: those which it updated, which must not have moved since, and
+// any other local refs which point to the commits it rewrote.
+func migrateRestores(record *migrateRecord) ([]*migrateRecordRef, error) {
+	var restores []*migrateRecordRef
+	seen := make(map[string]bool)
+	for _, recorded := range record.refs {
+		seen[recorded.name] = true
+
+		current, err := git.ResolveRef(recorded.name)
+		if err != nil || current.Sha != recorded.migrated {
+			return nil, errors.Errorf("fatal: %s has changed since the migration, so it cannot be rolled back", recorded.name)
+		}
+		restores = append(restores, recorded)
+	}
+
+	commitMap, err := record.commitMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read migration record")
+	}
+	if len(commitMap) == 0 {
+		return restores, nil
+	}
+
+	refs, err := git.AllRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if ref.Type == git.RefTypeRemoteBranch || seen[ref.Refspec()] {
+			continue
+		}
+		if original, ok := commitMap[ref.Sha]; ok {
+			restores = append(restores, &migrateRecordRef{
+				name:     ref.Refspec(),
+				original: original,
+				migrated: ref.Sha,
+			})
+		}
+	}
+	return restores, nil
+}
+
+// saveChanges saves the record of a rewrite which updated the given refs, and
+// wrote its map of commits to the given path, or removes the record if the
+// rewrite updated no refs.
+func (r *migrateRecord) saveChanges(changes []*githistory.RefChange, objectMapPath string) error {
+	if len(changes) == 0 {
+		return r.remove()
+	}
+
+	for _, c := range changes {
+		if err := r.add(c.Ref.Refspec(), c.Ref.Sha); err != nil {
+			return err
+		}
+	}
+	if objectMapPath != r.commitMapPath() {
+		if err := r.copyCommitMap(objectMapPath); err != nil {
+			return err
+		}
+	}
+	return r.save()
+}
+
+// recordRefUpdates returns a githistory.ConfirmFn which calls the given one,
+// and keeps the refs which it is given to be updated in "changes".
+func recordRefUpdates(fn githistory.ConfirmFn, changes *[]*githistory.RefChange) githistory.ConfirmFn {
+	return func(rewritten int, c []*githistory.RefChange) bool {
+		*changes = c
+		return fn(rewritten, c)
+	}
+}
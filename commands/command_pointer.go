@@ -22,6 +22,7 @@ var (
 	pointerCheck    bool
 	pointerStrict   bool
 	pointerNoStrict bool
+	pointerBatch    bool
 )
 
 func pointerCommand(cmd *cobra.Command, args []string) {
@@ -30,6 +31,11 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 	buildOid := ""
 	compareOid := ""
 
+	if pointerBatch {
+		pointerBatchCommand()
+		return
+	}
+
 	if pointerCheck {
 		var r io.ReadCloser
 		var err error
@@ -173,5 +179,6 @@ func init() {
 		cmd.Flags().BoolVarP(&pointerCheck, "check", "", false, "Check whether the given file is a Git LFS pointer.")
 		cmd.Flags().BoolVarP(&pointerStrict, "strict", "", false, "Check whether the given Git LFS pointer is canonical.")
 		cmd.Flags().BoolVarP(&pointerNoStrict, "no-strict", "", false, "Don't check whether the given Git LFS pointer is canonical.")
+		cmd.Flags().BoolVarP(&pointerBatch, "batch", "", false, "Read a list of files from STDIN and write the results as JSON.")
 	})
 }
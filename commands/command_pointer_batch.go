@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+)
+
+// pointerBatchEntry is the result of processing a single line of input to
+// "git lfs pointer --batch", written as a line of JSON.
+type pointerBatchEntry struct {
+	File string `json:"file"`
+
+	// Pointer is the pointer built from the file, or, when checking, the
+	// canonical form of the pointer read from the file.
+	Pointer string `json:"pointer,omitempty"`
+	Oid     string `json:"oid,omitempty"`
+	Size    *int64 `json:"size,omitempty"`
+
+	// Valid and Canonical are only set when checking, and Matches is only
+	// set when comparing against a pointer built elsewhere.
+	Valid     *bool `json:"valid,omitempty"`
+	Canonical *bool `json:"canonical,omitempty"`
+	Matches   *bool `json:"matches,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// ok returns whether the entry represents a successful result, which is used
+// to determine the exit status of the command.
+func (e *pointerBatchEntry) ok() bool {
+	if len(e.Error) > 0 {
+		return false
+	}
+	if e.Valid != nil && !*e.Valid {
+		return false
+	}
+	if pointerStrict && e.Canonical != nil && !*e.Canonical {
+		return false
+	}
+	return e.Matches == nil || *e.Matches
+}
+
+// pointerBatchCommand reads one entry per line from STDIN and writes one JSON
+// object per line to STDOUT, so that callers can process many files without
+// running a separate process for each.
+//
+// Each line is the path to a file from which to build a pointer, optionally
+// followed by a tab and the path to a pointer built by another
+// implementation to compare against. With --check, each line is instead the
+// path to a file to check for a valid pointer.
+func pointerBatchCommand() {
+	if len(pointerFile) > 0 || len(pointerCompare) > 0 || pointerStdin {
+		ExitWithError(fmt.Errorf("fatal: --batch cannot be combined with --file, --pointer, or --stdin"))
+	}
+	if pointerStrict && pointerNoStrict {
+		ExitWithError(fmt.Errorf("fatal: cannot combine --strict with --no-strict"))
+	}
+	if pointerStrict && !pointerCheck {
+		ExitWithError(fmt.Errorf("fatal: --strict requires --check"))
+	}
+
+	requireStdin("The --batch flag expects a list of files from STDIN.")
+
+	enc := json.NewEncoder(os.Stdout)
+	failed := false
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry *pointerBatchEntry
+		if pointerCheck {
+			entry = checkPointerBatchEntry(line)
+		} else {
+			fields := strings.SplitN(line, "\t", 2)
+			entry = buildPointerBatchEntry(fields[0])
+			if len(fields) > 1 && len(entry.Error) == 0 {
+				comparePointerBatchEntry(entry, fields[1])
+			}
+		}
+
+		if !entry.ok() {
+			failed = true
+		}
+		if err := enc.Encode(entry); err != nil {
+			ExitWithError(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		ExitWithError(errors.Wrap(err, "could not read from STDIN"))
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// buildPointerBatchEntry builds a pointer from the contents of the given file.
+func buildPointerBatchEntry(file string) *pointerBatchEntry {
+	entry := &pointerBatchEntry{File: file}
+
+	f, err := os.Open(file)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	defer f.Close()
+
+	oidHash := sha256.New()
+	size, err := io.Copy(oidHash, f)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	ptr := lfs.NewPointer(hex.EncodeToString(oidHash.Sum(nil)), size, nil)
+	entry.Pointer = ptr.Encoded()
+	entry.Oid = ptr.Oid
+	entry.Size = &ptr.Size
+	return entry
+}
+
+// comparePointerBatchEntry compares the pointer built for the given entry with
+// the pointer in the given file. As with --pointer, the two only match if they
+// are byte-for-byte identical, and so would have the same Git blob OID.
+func comparePointerBatchEntry(entry *pointerBatchEntry, file string) {
+	_, err := lfs.DecodePointerFromFile(file)
+	if err != nil {
+		entry.Error = fmt.Sprintf("%s: %s", file, err)
+		return
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+
+	matches := bytes.Equal(data, []byte(entry.Pointer))
+	entry.Matches = &matches
+}
+
+// checkPointerBatchEntry checks whether the given file contains a valid
+// pointer.
+func checkPointerBatchEntry(file string) *pointerBatchEntry {
+	entry := &pointerBatchEntry{File: file}
+
+	if _, err := os.Stat(file); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	p, err := lfs.DecodePointerFromFile(file)
+	valid := err == nil
+	entry.Valid = &valid
+	if !valid {
+		return entry
+	}
+
+	entry.Canonical = &p.Canonical
+	entry.Pointer = p.Encoded()
+	entry.Oid = p.Oid
+	entry.Size = &p.Size
+	return entry
+}
@@ -56,6 +56,10 @@ func prePushCommand(cmd *cobra.Command, args []string) {
 	}
 
 	ctx := newUploadContext(prePushDryRun)
+	ctx.verifyOnlyRefs = cfg.PushVerifyOnlyRefs()
+	if !prePushDryRun && cfg.Git.Bool("lfs.pushupstreamdedup", false) {
+		ctx.enableUpstreamDedup()
+	}
 	updates := prePushRefs(os.Stdin)
 	if err := uploadForRefUpdates(ctx, updates, false); err != nil {
 		ExitWithError(err)
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/rubyist/tracerx"
+	"github.com/spf13/cobra"
+)
+
+var (
+	preReceiveCheckMaxSize  string
+	preReceiveCheckLarge    bool
+	preReceiveCheckMissing  bool
+	preReceiveCheckPointers bool
+)
+
+// defaultPreReceiveCheckMaxSize is the size of the largest files which may be
+// pushed without being stored with Git LFS, unless configured otherwise.
+const defaultPreReceiveCheckMaxSize = "10MiB"
+
+// preReceiveCheckCommand is run through a server's pre-receive hook, which
+// receives a line on stdin for each ref being updated:
+//
+//	<old sha1> <new sha1> <ref name>
+//
+// For each ref, it checks the files which the push adds to the repository,
+// i.e., those in commits which are reachable from the new value of the ref but
+// not from any existing branch or tag, and rejects the push if any of them are
+// too large not to be stored with Git LFS, are pointers to objects which have
+// not been uploaded, or are pointers which are not canonical.
+func preReceiveCheckCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	maxSizeFmt := preReceiveCheckMaxSize
+	if len(maxSizeFmt) == 0 {
+		maxSizeFmt, _ = cfg.Git.Get("lfs.prereceive.maxobjectsize")
+	}
+	if len(maxSizeFmt) == 0 {
+		maxSizeFmt = defaultPreReceiveCheckMaxSize
+	}
+	maxSize, err := humanize.ParseBytes(maxSizeFmt)
+	if err != nil {
+		Exit("Invalid maximum object size %q: %v", maxSizeFmt, err)
+	}
+
+	if !preReceiveCheckLarge && !preReceiveCheckMissing && !preReceiveCheckPointers {
+		preReceiveCheckLarge = true
+		preReceiveCheckMissing = true
+		preReceiveCheckPointers = true
+	}
+
+	existing, err := git.LocalRefs()
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "could not list refs"))
+	}
+	exclude := make([]string, 0, len(existing))
+	for _, ref := range existing {
+		exclude = append(exclude, ref.Sha)
+	}
+
+	scanner, err := git.NewObjectScanner(cfg.GitEnv(), cfg.OSEnv())
+	if err != nil {
+		ExitWithError(err)
+	}
+	defer scanner.Close()
+
+	problems := 0
+	for _, ref := range preReceiveRefs(os.Stdin) {
+		n, err := preReceiveCheckRef(scanner, ref, exclude, maxSize)
+		if err != nil {
+			ExitWithError(err)
+		}
+		problems += n
+	}
+
+	if problems > 0 {
+		Print("Git LFS: push rejected: %d problem(s) found", problems)
+		os.Exit(1)
+	}
+}
+
+// preReceiveRefs parses the ref updates which the pre-receive hook receives:
+//
+//	<old sha1> <new sha1> <ref name>
+//
+// It returns each ref at its new value, except for those being deleted, which
+// add no files to check.
+func preReceiveRefs(r io.Reader) []*git.Ref {
+	scanner := bufio.NewScanner(r)
+	var refs []*git.Ref
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		tracerx.Printf("pre-receive: %s", line)
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 || git.IsZeroObjectID(fields[1]) {
+			continue
+		}
+		refs = append(refs, git.ParseRef(fields[2], fields[1]))
+	}
+
+	return refs
+}
+
+// preReceiveCheckRef checks each file which the update of the given ref adds
+// which is not reachable from the excluded commits, printing a message for
+// each problem found. It returns the number of them.
+func preReceiveCheckRef(scanner *git.ObjectScanner, ref *git.Ref, exclude []string, maxSize uint64) (int, error) {
+	revs, err := git.NewRevListScanner([]string{ref.Sha}, exclude, &git.ScanRefsOptions{
+		Mode: git.ScanRefsMode,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	problems := 0
+	for revs.Scan() {
+		// Commits and root trees have no name, and nor do they hold
+		// any files.
+		name := revs.Name()
+		if len(name) == 0 {
+			continue
+		}
+
+		oid := hex.EncodeToString(revs.OID())
+		if !scanner.Scan(oid) {
+			revs.Close()
+			return problems, errors.Wrapf(scanner.Err(), "could not read %s", oid)
+		}
+		if scanner.Type() != "blob" {
+			continue
+		}
+
+		p, err := lfs.DecodePointer(scanner.Contents())
+		if err != nil {
+			size := uint64(scanner.Size())
+			if preReceiveCheckLarge && size > maxSize {
+				Print("%s: largeObject: %q (blob %s) is %s, larger than %s; store it with Git LFS",
+					ref.Refspec(), name, oid, humanize.FormatBytes(size), humanize.FormatBytes(maxSize))
+				problems++
+			}
+			continue
+		}
+
+		if preReceiveCheckMissing && p.Size > 0 && !cfg.LFSObjectExists(p.Oid, p.Size) {
+			Print("%s: missingObject: %q (%s) has not been uploaded to Git LFS", ref.Refspec(), name, p.Oid)
+			problems++
+		}
+		if preReceiveCheckPointers {
+			if !p.Canonical {
+				Print("%s: nonCanonicalPointer: %q (blob %s) is not a canonical pointer", ref.Refspec(), name, oid)
+				problems++
+			}
+			if err := lfs.ValidatePointerMetadata(p); err != nil {
+				Print("%s: invalidPointerMetadata: %q (blob %s) has invalid metadata: %s", ref.Refspec(), name, oid, err)
+				problems++
+			}
+		}
+	}
+
+	if err := revs.Err(); err != nil {
+		revs.Close()
+		return problems, err
+	}
+	return problems, revs.Close()
+}
+
+func init() {
+	RegisterCommand("pre-receive-check", preReceiveCheckCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVar(&preReceiveCheckMaxSize, "max-size", "", "Reject files larger than this which are not stored with Git LFS")
+		cmd.Flags().BoolVarP(&preReceiveCheckLarge, "large", "", false, "Check for large files not stored with Git LFS.")
+		cmd.Flags().BoolVarP(&preReceiveCheckMissing, "missing", "", false, "Check for pointers to objects which have not been uploaded.")
+		cmd.Flags().BoolVarP(&preReceiveCheckPointers, "pointers", "", false, "Check for pointers which are not canonical.")
+	})
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/spf13/cobra"
+)
+
+// profileCommand lists the fetch profiles, marking the one in use.
+func profileCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	current := cfg.FetchProfile()
+	for _, name := range cfg.FetchProfiles() {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+
+		Print("%s %s", marker, name)
+		for _, setting := range config.FetchProfileSettings {
+			if val, ok := cfg.Git.Get(profileKey(name, setting)); ok {
+				Print("    %s = %s", setting, val)
+			}
+		}
+	}
+}
+
+// profileUseCommand makes the named fetch profile the one used by later
+// fetches, by setting lfs.profile in the repository's configuration.
+func profileUseCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) != 1 {
+		Exit("Usage: git lfs profile use <name>")
+	}
+
+	name := args[0]
+	profiles := cfg.FetchProfiles()
+	if !profileExists(profiles, name) {
+		if len(profiles) == 0 {
+			Exit("Unknown profile %q: no profiles are defined", name)
+		}
+		Exit("Unknown profile %q: must be one of %s", name, strings.Join(profiles, ", "))
+	}
+
+	if _, err := cfg.SetGitLocalKey("lfs.profile", name); err != nil {
+		ExitWithError(err)
+	}
+
+	Print("Using profile %q", name)
+	Print("Run 'git lfs pull' to download the objects it includes.")
+}
+
+func profileExists(profiles []string, name string) bool {
+	for _, p := range profiles {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func profileKey(name, setting string) string {
+	return fmt.Sprintf("lfs.profile.%s.%s", name, setting)
+}
+
+func init() {
+	RegisterCommand("profile", profileCommand, func(cmd *cobra.Command) {
+		cmd.AddCommand(
+			NewCommand("list", profileCommand),
+			NewCommand("use", profileUseCommand),
+		)
+	})
+}
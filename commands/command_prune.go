@@ -24,6 +24,7 @@ import (
 
 var (
 	pruneDryRunArg      bool
+	pruneYesArg         bool
 	pruneVerboseArg     bool
 	pruneVerifyArg      bool
 	pruneRecentArg      bool
@@ -42,7 +43,11 @@ func pruneCommand(cmd *cobra.Command, args []string) {
 		(fetchPruneConfig.PruneVerifyRemoteAlways || pruneVerifyArg)
 	fetchPruneConfig.PruneRecent = pruneRecentArg || pruneForceArg
 	fetchPruneConfig.PruneForce = pruneForceArg
-	prune(fetchPruneConfig, verify, pruneDryRunArg, pruneVerboseArg)
+	unlock := acquireOperationLock("migrate")
+	prune(fetchPruneConfig, verify, pruneDryRunArg, pruneVerboseArg, pruneYesArg)
+	unlock()
+
+	runInSubmodules(cmd)
 }
 
 type PruneProgressType int
@@ -60,73 +65,37 @@ type PruneProgress struct {
 }
 type PruneProgressChan chan PruneProgress
 
-func prune(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote, dryRun, verbose bool) {
-	localObjects := make([]fs.Object, 0, 100)
-	retainedObjects := tools.NewStringSetWithCapacity(100)
+// prune deletes the local objects which the given configuration doesn't retain.
+// Objects written by a migration are only retained once it has updated the
+// refs, so callers must hold the "migrate" operation lock. With dryRun, it only
+// lists the objects, and unless yes is given, it asks before deleting them, as
+// preview.confirm does.
+func prune(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote, dryRun, verbose, yes bool) {
+	defer acquireOperationLock("prune")()
 
 	logger := tasklog.NewLogger(OutputWriter,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	defer logger.Close()
 
-	var reachableObjects tools.StringSet
-	var taskwait sync.WaitGroup
-
-	// Add all the base funcs to the waitgroup before starting them, in case
-	// one completes really fast & hits 0 unexpectedly
-	// each main process can Add() to the wg itself if it subdivides the task
-	taskwait.Add(5) // 1..5: localObjects, current & recent refs, unpushed, worktree, stashes
-	if verifyRemote {
-		taskwait.Add(1) // 6
-	}
-
 	progressChan := make(PruneProgressChan, 100)
 
-	// Collect errors
-	errorChan := make(chan error, 10)
-	var errorwait sync.WaitGroup
-	errorwait.Add(1)
-	var taskErrors []error
-	go pruneTaskCollectErrors(&taskErrors, errorChan, &errorwait)
-
-	// Populate the single list of local objects
-	go pruneTaskGetLocalObjects(&localObjects, progressChan, &taskwait)
-
-	// Now find files to be retained from many sources
-	retainChan := make(chan string, 100)
-
-	gitscanner := lfs.NewGitScanner(cfg, nil)
-	gitscanner.Filter = filepathfilter.New(nil, cfg.FetchExcludePaths())
-
-	sem := semaphore.NewWeighted(int64(runtime.NumCPU() * 2))
-
-	go pruneTaskGetRetainedCurrentAndRecentRefs(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
-	go pruneTaskGetRetainedUnpushed(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
-	go pruneTaskGetRetainedWorktree(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
-	go pruneTaskGetRetainedStashed(gitscanner, retainChan, errorChan, &taskwait, sem)
-	if verifyRemote {
-		reachableObjects = tools.NewStringSetWithCapacity(100)
-		go pruneTaskGetReachableObjects(gitscanner, &reachableObjects, errorChan, &taskwait, sem)
-	}
-
-	// Now collect all the retained objects, on separate wait
-	var retainwait sync.WaitGroup
-	retainwait.Add(1)
-	go pruneTaskCollectRetained(&retainedObjects, retainChan, progressChan, &retainwait)
-
 	// Report progress
 	var progresswait sync.WaitGroup
 	progresswait.Add(1)
 	go pruneTaskDisplayProgress(progressChan, &progresswait, logger)
 
-	taskwait.Wait() // wait for subtasks
-	gitscanner.Close()
-	close(retainChan) // triggers retain collector to end now all tasks have
-	retainwait.Wait() // make sure all retained objects added
+	localObjects, retainedObjects, reachableObjects := pruneScan(fetchPruneConfig, verifyRemote, progressChan)
 
-	close(errorChan) // triggers error collector to end now all tasks have
-	errorwait.Wait() // make sure all errors have been processed
-	pruneCheckErrors(taskErrors)
+	// Objects which are reachable cannot be verified with the remote while
+	// Git LFS is offline, so they are kept, and only unreachable objects,
+	// which need not be verified, are pruned.
+	keepReachable := verifyRemote && cfg.Offline()
+	if keepReachable {
+		verifyRemote = false
+	}
+	var keptReachable int
 
 	prunableObjects := make([]string, 0, len(localObjects)/2)
 
@@ -135,6 +104,10 @@ func prune(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote, dryRun, verbose
 	var verifiedObjects tools.StringSet
 	var totalSize int64
 	var verboseOutput []string
+	affected := &preview{
+		question: "prune: delete these objects?",
+		columns:  []string{"OID", "SIZE"},
+	}
 	var verifyc chan *tq.Transfer
 	var verifywait sync.WaitGroup
 
@@ -159,9 +132,14 @@ func prune(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote, dryRun, verbose
 	}
 
 	for _, file := range localObjects {
+		if keepReachable && !retainedObjects.Contains(file.Oid) && reachableObjects.Contains(file.Oid) {
+			keptReachable++
+			continue
+		}
 		if !retainedObjects.Contains(file.Oid) {
 			prunableObjects = append(prunableObjects, file.Oid)
 			totalSize += file.Size
+			affected.add(file.Oid, humanize.FormatBytes(uint64(file.Size)))
 			if verbose {
 				// Save up verbose output for the end.
 				verboseOutput = append(verboseOutput,
@@ -191,26 +169,113 @@ func prune(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote, dryRun, verbose
 		progresswait.Wait()
 	}
 
+	if keptReachable > 0 {
+		Print("prune: Git LFS is offline, so %d reachable object(s) could not be verified with the remote and were kept", keptReachable)
+	}
+
+	if !dryRun {
+		pruneTreeCache(fetchPruneConfig)
+	}
+
 	if len(prunableObjects) == 0 {
 		return
 	}
 
+	// Let the logger finish writing the progress of the scan before
+	// showing the preview.
+	logger.Simple().Complete()
+
+	affected.summary = fmt.Sprintf("prune: %d file(s) would be pruned (%s)", len(prunableObjects), humanize.FormatBytes(uint64(totalSize)))
+	if !affected.confirm(os.Stdin, OutputWriter, dryRun, yes, verbose) {
+		if !dryRun {
+			Exit("prune: aborted, no objects were deleted")
+		}
+		return
+	}
+
 	info := tasklog.NewSimpleTask()
 	logger.Enqueue(info)
-	if dryRun {
-		info.Logf("prune: %d file(s) would be pruned (%s)", len(prunableObjects), humanize.FormatBytes(uint64(totalSize)))
-		for _, item := range verboseOutput {
-			info.Logf("\n * %s", item)
-		}
-		info.Complete()
-	} else {
-		for _, item := range verboseOutput {
-			info.Logf("\n%s", item)
-		}
-		info.Complete()
+	for _, item := range verboseOutput {
+		info.Logf("\n%s", item)
+	}
+	info.Complete()
+
+	pruneDeleteFiles(prunableObjects, logger)
+}
+
+// pruneScan returns the local objects and the set of those which are retained
+// according to the given configuration, along with, if verifyRemote is true,
+// the set of all objects reachable from any ref. It reports its progress to
+// progressChan, which the caller must drain, and exits if there are errors.
+func pruneScan(fetchPruneConfig lfs.FetchPruneConfig, verifyRemote bool, progressChan PruneProgressChan) ([]fs.Object, tools.StringSet, tools.StringSet) {
+	localObjects := make([]fs.Object, 0, 100)
+	retainedObjects := tools.NewStringSetWithCapacity(100)
+
+	var reachableObjects tools.StringSet
+	var taskwait sync.WaitGroup
+
+	// Add all the base funcs to the waitgroup before starting them, in case
+	// one completes really fast & hits 0 unexpectedly
+	// each main process can Add() to the wg itself if it subdivides the task
+	taskwait.Add(5) // 1..5: localObjects, current & recent refs, unpushed, worktree, stashes
+	if verifyRemote {
+		taskwait.Add(1) // 6
+	}
+
+	// Collect errors
+	errorChan := make(chan error, 10)
+	var errorwait sync.WaitGroup
+	errorwait.Add(1)
+	var taskErrors []error
+	go pruneTaskCollectErrors(&taskErrors, errorChan, &errorwait)
+
+	// Populate the single list of local objects
+	go pruneTaskGetLocalObjects(&localObjects, progressChan, &taskwait)
+
+	// Now find files to be retained from many sources
+	retainChan := make(chan string, 100)
 
-		pruneDeleteFiles(prunableObjects, logger)
+	gitscanner := lfs.NewGitScanner(cfg, nil)
+	gitscanner.Filter = filepathfilter.New(nil, cfg.FetchExcludePaths())
+
+	sem := semaphore.NewWeighted(int64(runtime.NumCPU() * 2))
+
+	go pruneTaskGetRetainedCurrentAndRecentRefs(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
+	go pruneTaskGetRetainedUnpushed(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
+	go pruneTaskGetRetainedWorktree(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait, sem)
+	go pruneTaskGetRetainedStashed(gitscanner, retainChan, errorChan, &taskwait, sem)
+	if verifyRemote {
+		reachableObjects = tools.NewStringSetWithCapacity(100)
+		go pruneTaskGetReachableObjects(gitscanner, &reachableObjects, errorChan, &taskwait, sem)
 	}
+
+	// Now collect all the retained objects, on separate wait
+	var retainwait sync.WaitGroup
+	retainwait.Add(1)
+	go pruneTaskCollectRetained(&retainedObjects, retainChan, progressChan, &retainwait)
+
+	taskwait.Wait() // wait for subtasks
+	gitscanner.Close()
+	close(retainChan) // triggers retain collector to end now all tasks have
+	retainwait.Wait() // make sure all retained objects added
+
+	close(errorChan) // triggers error collector to end now all tasks have
+	errorwait.Wait() // make sure all errors have been processed
+	pruneCheckErrors(taskErrors)
+
+	return localObjects, retainedObjects, reachableObjects
+}
+
+// pruneTreeCache removes the cached scans of trees which have not been used
+// within the period for which recent refs are retained.
+func pruneTreeCache(fetchconf lfs.FetchPruneConfig) {
+	days := fetchconf.FetchRecentRefsDays + fetchconf.PruneOffsetDays
+	n, err := lfs.PruneTreeCache(cfg, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		Error("warning: could not prune tree cache: %v", err)
+		return
+	}
+	tracerx.Printf("PRUNE: removed %d cached tree scan(s) unused for %d days", n, days)
 }
 
 func pruneCheckVerified(prunableObjects []string, reachableObjects, verifiedObjects tools.StringSet) {
@@ -302,19 +367,25 @@ func pruneDeleteFiles(prunableObjects []string, logger *tasklog.Logger) {
 	// In case we fail to delete some
 	var deletedFiles int
 	for _, oid := range prunableObjects {
-		mediaFile, err := cfg.Filesystem().ObjectPath(oid)
+		// Another process writing the object wants it, so keep it.
+		lock, err := cfg.Filesystem().TryLock(fs.ObjectLockName(oid))
 		if err != nil {
-			problems.WriteString(fmt.Sprintf("Unable to find media path for %v: %v\n", oid, err))
+			tracerx.Printf("prune: keeping %v: %v", oid, err)
+			task.Count(1)
 			continue
 		}
-		err = os.Remove(mediaFile)
+		err = cfg.Filesystem().RemoveObject(oid)
+		lock.Unlock()
 		if err != nil {
-			problems.WriteString(fmt.Sprintf("Failed to remove file %v: %v\n", mediaFile, err))
+			problems.WriteString(fmt.Sprintf("Failed to remove object %v: %v\n", oid, err))
 			continue
 		}
 		deletedFiles++
 		task.Count(1)
 	}
+	if _, err := cfg.Filesystem().CompactObjectStore(); err != nil {
+		problems.WriteString(fmt.Sprintf("Failed to compact object storage: %v\n", err))
+	}
 	if problems.Len() > 0 {
 		LoggedError(fmt.Errorf("failed to delete some files"), problems.String())
 		Exit("Prune failed, see errors above")
@@ -529,10 +600,12 @@ func pruneTaskGetReachableObjects(gitscanner *lfs.GitScanner, outObjectSet *tool
 func init() {
 	RegisterCommand("prune", pruneCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&pruneDryRunArg, "dry-run", "d", false, "Don't delete anything, just report")
+		cmd.Flags().BoolVarP(&pruneYesArg, "yes", "y", false, "Don't ask before deleting anything")
 		cmd.Flags().BoolVarP(&pruneVerboseArg, "verbose", "v", false, "Print full details of what is/would be deleted")
 		cmd.Flags().BoolVarP(&pruneRecentArg, "recent", "", false, "Prune even recent objects")
 		cmd.Flags().BoolVarP(&pruneForceArg, "force", "f", false, "Prune everything that has been pushed")
 		cmd.Flags().BoolVarP(&pruneVerifyArg, "verify-remote", "c", false, "Verify that remote has LFS files before deleting")
 		cmd.Flags().BoolVar(&pruneDoNotVerifyArg, "no-verify-remote", false, "Override lfs.pruneverifyremotealways and don't verify")
+		addRecurseSubmodulesFlag(cmd)
 	})
 }
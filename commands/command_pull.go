@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
@@ -26,9 +27,16 @@ func pullCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if pullJobs < 1 {
+		Exit("--jobs must be at least 1")
+	}
+
 	includeArg, excludeArg := getIncludeExcludeArgs(cmd)
 	filter := buildFilepathFilter(cfg, includeArg, excludeArg, true)
 	pull(filter)
+	limitStorage()
+
+	runInSubmodules(cmd)
 }
 
 func pull(filter *filepathfilter.Filter) {
@@ -37,16 +45,34 @@ func pull(filter *filepathfilter.Filter) {
 		Panic(err, "Could not pull")
 	}
 
+	all, err := pointersToFetchForRef(ref.Sha, filter)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+	if !diskSpaceForceArg {
+		checkDiskSpace(downloadSize(all), checkoutSize(all))
+	}
+	bootstrapObjects(all)
+
 	pointers := newPointerMap()
 	logger := tasklog.NewLogger(os.Stdout,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	meter := tq.NewMeter(cfg)
 	meter.Logger = meter.LoggerFromEnv(cfg.Os)
 	logger.Enqueue(meter)
 	remote := cfg.Remote()
-	singleCheckout := newSingleCheckout(cfg.Git, remote)
-	q := newDownloadQueue(singleCheckout.Manifest(), remote, tq.WithProgress(meter))
+	singleCheckout := newParallelCheckout(newSingleCheckout(cfg.Git, remote), pullJobs, nil)
+	var missingMu sync.Mutex
+	var missing []string
+	q := trackQueue(newDownloadQueue(singleCheckout.Manifest(), remote, tq.WithProgress(meter),
+		tq.WithMissingCallback(func(oid string) {
+			missingMu.Lock()
+			missing = append(missing, oid)
+			missingMu.Unlock()
+		}),
+	))
 	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
 		if err != nil {
 			LoggedError(err, "Scanner error: %s", err)
@@ -97,25 +123,87 @@ func pull(filter *filepathfilter.Filter) {
 	wg.Wait()
 	tracerx.PerformanceSince("process queue", processQueue)
 
-	singleCheckout.Close()
-
 	success := true
+	var missingErrs, deniedErrs []error
 	for _, err := range q.Errors() {
+		// Objects the remote doesn't have may be on its fallback
+		// remotes.
+		if isMissingObjectError(err) {
+			missingErrs = append(missingErrs, err)
+			continue
+		}
+		if isDeniedObjectError(err) {
+			deniedErrs = append(deniedErrs, err)
+			continue
+		}
 		success = false
 		FullError(err)
 	}
 
+	var missingPointers []*lfs.WrappedPointer
+	missingByOid := make(map[string][]*lfs.WrappedPointer, len(missing))
+	for _, oid := range missing {
+		missingByOid[oid] = pointers.All(oid)
+		missingPointers = append(missingPointers, missingByOid[oid]...)
+	}
+	missing, errs := fetchFromFallbackRemotes(remote, missingPointers, missing, func(oid string) {
+		for _, p := range missingByOid[oid] {
+			singleCheckout.Run(p)
+		}
+	})
+	for _, err := range errs {
+		success = false
+		FullError(err)
+	}
+
+	// Files whose objects could not be downloaded are left as pointers,
+	// or replaced with placeholders, as lfs.missingcontent asks.
+	missingErrs = stillMissingErrors(missingErrs, missing)
+	unavailableMissing := unavailableObjects(missingErrs)
+	unavailableDenied := unavailableObjects(deniedErrs)
+	reportUnavailableObjects("pull", "permanently missing from the server", unavailableMissing)
+	reportUnavailableObjects("pull", "denied by the server", unavailableDenied)
+	if len(missingErrs) > 0 || len(deniedErrs) > 0 {
+		switch cfg.MissingContent() {
+		case config.MissingContentPlaceholder:
+			Error("pull: writing placeholders for their files, as lfs.missingcontent is \"placeholder\"")
+			for _, obj := range unavailableMissing {
+				for _, p := range missingByOid[obj.Oid] {
+					singleCheckout.RunPlaceholder(p)
+				}
+			}
+			for _, obj := range unavailableDenied {
+				for _, p := range pointers.All(obj.Oid) {
+					singleCheckout.RunPlaceholder(p)
+				}
+			}
+		case config.MissingContentPointer:
+			Error("pull: leaving their files as pointers")
+		default:
+			success = false
+		}
+	}
+
+	singleCheckout.Close()
+	exitIfInterrupted(q, tq.Download)
+
 	if !success {
 		c := getAPIClient()
 		e := c.Endpoints.Endpoint("download", remote)
 		Exit("error: failed to fetch some objects from '%s'", e.Url)
 	}
 
+	if n := singleCheckout.Failed(); n > 0 {
+		Exit("error: could not check out %d file(s); see lfs.symlinkpolicy", n)
+	}
+
 	if singleCheckout.Skip() {
 		fmt.Println("Skipping object checkout, Git LFS is not installed.")
 	}
 }
 
+var pullJobs int
+
 // tracks LFS objects being downloaded, according to their unique OIDs.
 type pointerMap struct {
 	pointers map[string][]*lfs.WrappedPointer
@@ -154,5 +242,8 @@ func init() {
 	RegisterCommand("pull", pullCommand, func(cmd *cobra.Command) {
 		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
 		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().IntVarP(&pullJobs, "jobs", "j", 1, "Check out this many files at once")
+		addDiskSpaceForceFlag(cmd)
+		addRecurseSubmodulesFlag(cmd)
 	})
 }
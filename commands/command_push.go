@@ -12,10 +12,12 @@ import (
 )
 
 var (
-	pushDryRun    = false
-	pushObjectIDs = false
-	pushAll       = false
-	useStdin      = false
+	pushDryRun     = false
+	pushObjectIDs  = false
+	pushAll        = false
+	pushForceCheck = false
+	pushUpstream   = false
+	useStdin       = false
 
 	// shares some global vars and functions with command_pre_push.go
 )
@@ -43,6 +45,10 @@ func pushCommand(cmd *cobra.Command, args []string) {
 	}
 
 	ctx := newUploadContext(pushDryRun)
+	ctx.skipPushed = pushAll && !pushForceCheck
+	if !pushDryRun && (pushUpstream || cfg.Git.Bool("lfs.pushupstreamdedup", false)) {
+		ctx.enableUpstreamDedup()
+	}
 	if pushObjectIDs {
 		if len(args) < 2 {
 			Print("Usage: git lfs push --object-id <remote> <lfs-object-id> [lfs-object-id] ...")
@@ -77,7 +83,7 @@ func uploadsBetweenRefAndRemote(ctx *uploadContext, refnames []string) {
 func uploadsWithObjectIDs(ctx *uploadContext, oids []string) {
 	pointers := make([]*lfs.WrappedPointer, len(oids))
 	for i, oid := range oids {
-		mp, err := ctx.gitfilter.ObjectPath(oid)
+		mp, err := cfg.Filesystem().UnpackedObjectPath(oid)
 		if err != nil {
 			ExitWithError(errors.Wrap(err, "Unable to find local media path:"))
 		}
@@ -142,5 +148,7 @@ func init() {
 		cmd.Flags().BoolVarP(&pushDryRun, "dry-run", "d", false, "Do everything except actually send the updates")
 		cmd.Flags().BoolVarP(&pushObjectIDs, "object-id", "o", false, "Push LFS object ID(s)")
 		cmd.Flags().BoolVarP(&pushAll, "all", "a", false, "Push all objects for the current ref to the remote.")
+		cmd.Flags().BoolVarP(&pushForceCheck, "force-check", "", false, "With --all, ask the server about objects recorded as pushed.")
+		cmd.Flags().BoolVarP(&pushUpstream, "upstream-dedup", "", false, "Skip objects the upstream remote's server already has.")
 	})
 }
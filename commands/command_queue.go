@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queueDownload bool
+	queueUpload   bool
+)
+
+// queueCommand lists the objects which were not transferred by earlier
+// fetches and pushes, with the errors their transfers failed with.
+func queueCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	for _, d := range queueDirections() {
+		for _, p := range readPendingQueue(d) {
+			reason := p.Error
+			if len(reason) == 0 {
+				reason = "not transferred"
+			}
+			Print("%s %s %s (%s, %s): %s", d, p.Remote, p.Name, p.Oid[:10],
+				humanize.FormatBytes(uint64(p.Size)), reason)
+		}
+	}
+}
+
+// queueRetryCommand transfers the objects which were not transferred by
+// earlier fetches and pushes again, without scanning for them, and leaves
+// only those which fail again in the queue.
+func queueRetryCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	failed := false
+	for _, d := range queueDirections() {
+		if !retryPendingQueue(d) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+}
+
+// queueClearCommand forgets the objects which were not transferred by earlier
+// fetches and pushes.
+func queueClearCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	for _, d := range queueDirections() {
+		if err := tq.WritePendingFile(pendingQueueFile(d), nil); err != nil {
+			ExitWithError(err)
+		}
+	}
+}
+
+// queueDirections returns the directions of the queues which the command
+// should act on: those given by --download and --upload, or both.
+func queueDirections() []tq.Direction {
+	if queueDownload == queueUpload {
+		return []tq.Direction{tq.Download, tq.Upload}
+	} else if queueDownload {
+		return []tq.Direction{tq.Download}
+	}
+	return []tq.Direction{tq.Upload}
+}
+
+func readPendingQueue(d tq.Direction) []*tq.PendingObject {
+	pending, err := tq.ReadPendingFile(pendingQueueFile(d))
+	if err != nil {
+		Exit("fatal: could not read the %s queue: %v", d, err)
+	}
+	return pending
+}
+
+// retryPendingQueue transfers the objects pending in the given direction, with
+// a transfer queue for each remote and ref they were pending for, and returns
+// whether they were all transferred.
+func retryPendingQueue(d tq.Direction) bool {
+	type queueKey struct{ remote, ref string }
+
+	groups := make(map[queueKey][]*tq.PendingObject)
+	var keys []queueKey
+	for _, p := range readPendingQueue(d) {
+		key := queueKey{p.Remote, p.Ref}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	ok := true
+	for _, key := range keys {
+		logger := tasklog.NewLogger(os.Stdout,
+			tasklog.ForceProgress(cfg.ForceProgress()),
+			tasklog.PlainProgress(cfg.PlainProgress()),
+		)
+		meter := buildProgressMeter(false, d)
+		logger.Enqueue(meter)
+
+		var ref *git.Ref
+		if len(key.ref) > 0 {
+			ref = git.ParseRef(key.ref, "")
+		}
+
+		q := trackQueue(tq.NewTransferQueue(d, getTransferManifestOperationRemote(d.String(), key.remote), key.remote,
+			tq.RemoteRef(ref),
+			tq.WithProgress(meter),
+			tq.WithPendingFile(pendingQueueFile(d)),
+		))
+		for _, p := range groups[key] {
+			path, err := cfg.Filesystem().ObjectPath(p.Oid)
+			meter.Add(p.Size)
+			q.Add(p.Name, path, p.Oid, p.Size, false, err)
+		}
+		q.Wait()
+		meter.Finish()
+		exitIfInterrupted(q, d)
+
+		for _, err := range q.Errors() {
+			ok = false
+			FullError(err)
+		}
+	}
+	return ok
+}
+
+func init() {
+	RegisterCommand("queue", queueCommand, func(cmd *cobra.Command) {
+		cmd.PersistentFlags().BoolVar(&queueDownload, "download", false, "Only the objects which were not downloaded")
+		cmd.PersistentFlags().BoolVar(&queueUpload, "upload", false, "Only the objects which were not uploaded")
+		cmd.AddCommand(
+			NewCommand("list", queueCommand),
+			NewCommand("retry", queueRetryCommand),
+			NewCommand("clear", queueClearCommand),
+		)
+	})
+}
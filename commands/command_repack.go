@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+// repackCommand moves the objects in the object directory into the backend
+// configured with lfs.storage.backend, and reclaims the space taken by
+// objects pruned from it.
+func repackCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	f := cfg.Filesystem()
+	store, err := f.ObjectStore()
+	if err != nil {
+		ExitWithError(err)
+	}
+	if store.Name() == fs.LooseBackend {
+		Exit("Objects are already stored loose; set lfs.storage.backend to pack them")
+	}
+
+	moved, size := moveLooseObjects(f.MoveToStore, func(fs.Object) bool { return true })
+
+	freed, err := f.CompactObjectStore()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	Print("repack: %d object(s) moved into %s storage (%s)", moved, store.Name(), humanize.FormatBytes(uint64(size)))
+	if freed > 0 {
+		Print("repack: %s reclaimed from pruned objects", humanize.FormatBytes(uint64(freed)))
+	}
+}
+
+// moveLooseObjects calls move for each object in the object directory which
+// include accepts, and returns the number and total size of the objects moved.
+// Objects being downloaded or pruned by other processes are skipped.
+func moveLooseObjects(move func(oid string) error, include func(obj fs.Object) bool) (int, int64) {
+	f := cfg.Filesystem()
+
+	var oids []string
+	err := f.EachObject(func(obj fs.Object) error {
+		if tools.FileExists(f.ObjectPathname(obj.Oid)) && include(obj) {
+			oids = append(oids, obj.Oid)
+		}
+		return nil
+	})
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	var moved int
+	var size int64
+	for _, oid := range oids {
+		lock, err := f.TryLock(fs.ObjectLockName(oid))
+		if fs.IsLockedError(err) {
+			continue
+		}
+		if err != nil {
+			ExitWithError(err)
+		}
+
+		stat, err := os.Stat(f.ObjectPathname(oid))
+		if err == nil {
+			err = move(oid)
+		}
+		lock.Unlock()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			ExitWithError(err)
+		}
+
+		moved++
+		size += stat.Size()
+	}
+	return moved, size
+}
+
+func init() {
+	RegisterCommand("repack", repackCommand, nil)
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/spf13/cobra"
+)
+
+// replayCommand sends a batch request recorded with GIT_LFS_RECORD to the
+// server again, and reports how the response differs from the recorded one, so
+// that problems with a server can be reproduced.
+func replayCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	if len(args) < 1 || len(args) > 2 {
+		Print("Usage: git lfs replay <recording> [<remote>]")
+		os.Exit(1)
+	}
+
+	rec, err := tq.ReadBatchRecord(args[0])
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	if len(args) > 1 {
+		if err := cfg.SetValidRemote(args[1]); err != nil {
+			Exit("Invalid remote name %q: %s", args[1], err)
+		}
+	} else if len(rec.Remote) > 0 {
+		if err := cfg.SetValidRemote(rec.Remote); err != nil {
+			Exit("Recorded remote %q is not valid here (%s); give a remote to replay to", rec.Remote, err)
+		}
+	}
+	remote := cfg.Remote()
+
+	endpoint := getAPIClient().Endpoints.Endpoint(rec.Request.Operation, remote)
+	Print("replay: %s batch of %d object(s) to %s", rec.Request.Operation, len(rec.Request.Objects), endpoint.Url)
+
+	manifest := getTransferManifestOperationRemote(rec.Request.Operation, remote)
+	res, err := tq.Replay(manifest, remote, rec)
+	if err != nil {
+		if len(rec.Error) > 0 {
+			Print("replay: recorded error: %s", rec.Error)
+		}
+		ExitWithError(err)
+	}
+	if len(rec.Error) > 0 {
+		Print("replay: the recorded request failed with %q, but succeeded now", rec.Error)
+	}
+
+	var recorded []*tq.Transfer
+	var recordedAdapter string
+	if rec.Response != nil {
+		recorded = rec.Response.Objects
+		recordedAdapter = rec.Response.TransferAdapterName
+	}
+	if recordedAdapter != res.TransferAdapterName {
+		Print("replay: transfer adapter %q, recorded %q", res.TransferAdapterName, recordedAdapter)
+	}
+
+	diffs := replayDiffs(recorded, res.Objects)
+	for _, diff := range diffs {
+		Print("  %s", diff)
+	}
+	Print("replay: %d object(s) in the response, %d differ from the recording", len(res.Objects), len(diffs))
+}
+
+// replayDiffs describes each object whose outcome in the given response
+// differs from that in the recorded one.
+func replayDiffs(recorded, replayed []*tq.Transfer) []string {
+	before := make(map[string]string, len(recorded))
+	for _, t := range recorded {
+		before[t.Oid] = replayStatus(t)
+	}
+	after := make(map[string]string, len(replayed))
+	for _, t := range replayed {
+		after[t.Oid] = replayStatus(t)
+	}
+
+	var diffs []string
+	for oid, status := range after {
+		if was, ok := before[oid]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: %s, not in recording", oid, status))
+		} else if was != status {
+			diffs = append(diffs, fmt.Sprintf("%s: %s, recorded %s", oid, status, was))
+		}
+	}
+	for oid, was := range before {
+		if _, ok := after[oid]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: missing from response, recorded %s", oid, was))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// replayStatus summarizes the outcome of a single object in a batch response.
+func replayStatus(t *tq.Transfer) string {
+	if t.Error != nil {
+		return fmt.Sprintf("error %d (%s)", t.Error.Code, t.Error.Message)
+	}
+
+	rels := make([]string, 0, len(t.Actions)+len(t.Links))
+	for rel := range t.Actions {
+		rels = append(rels, rel)
+	}
+	for rel := range t.Links {
+		rels = append(rels, rel)
+	}
+	if len(rels) == 0 {
+		return "no actions"
+	}
+	sort.Strings(rels)
+	return "actions " + strings.Join(rels, ",")
+}
+
+func init() {
+	RegisterCommand("replay", replayCommand, nil)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
 	"github.com/git-lfs/git-lfs/v2/git"
@@ -19,6 +20,9 @@ var (
 	// smudgeSkip is a command-line flag belonging to the "git-lfs smudge"
 	// command specifying whether to skip the smudge process.
 	smudgeSkip = false
+	// smudgeBatch is a command-line flag belonging to the "git-lfs smudge"
+	// command specifying whether to smudge many files read from STDIN.
+	smudgeBatch = false
 )
 
 // delayedSmudge performs a 'delayed' smudge, adding the LFS pointer to the
@@ -27,7 +31,8 @@ var (
 // exists, it streams the contents to be written into the working copy to "to".
 //
 // delayedSmudge returns the number of bytes written, whether the checkout was
-// delayed, the *lfs.Pointer that was smudged, and an error, if one occurred.
+// delayed, the *lfs.Pointer that was smudged, if its object's contents were
+// written or delayed, and an error, if one occurred.
 func delayedSmudge(gf *lfs.GitFilter, s *git.FilterProcessScanner, to io.Writer, from io.Reader, q *tq.TransferQueue, filename string, skip bool, filter *filepathfilter.Filter) (int64, bool, *lfs.Pointer, error) {
 	ptr, pbuf, perr := lfs.DecodeFrom(from)
 	if perr != nil {
@@ -42,7 +47,7 @@ func delayedSmudge(gf *lfs.GitFilter, s *git.FilterProcessScanner, to io.Writer,
 			return n, false, nil, errors.Wrap(err, perr.Error())
 		}
 
-		if n != 0 {
+		if n != 0 && !storedAsBlob(gf, filename, n) {
 			return 0, false, nil, errors.NewNotAPointerError(errors.Errorf(
 				"Unable to parse pointer at: %q", filename,
 			))
@@ -58,7 +63,7 @@ func delayedSmudge(gf *lfs.GitFilter, s *git.FilterProcessScanner, to io.Writer,
 	}
 
 	if !skip && filter.Allows(filename) {
-		if _, statErr := os.Stat(path); statErr != nil && ptr.Size != 0 {
+		if ptr.Size != 0 && !cfg.LFSObjectExists(ptr.Oid, ptr.Size) {
 			q.Add(filename, path, ptr.Oid, ptr.Size, false, err)
 			return 0, true, ptr, nil
 		}
@@ -79,7 +84,7 @@ func delayedSmudge(gf *lfs.GitFilter, s *git.FilterProcessScanner, to io.Writer,
 	}
 
 	n, err := ptr.Encode(to)
-	return int64(n), false, ptr, err
+	return int64(n), false, nil, err
 }
 
 // smudge smudges the given `*lfs.Pointer`, "ptr", and writes its objects
@@ -94,29 +99,31 @@ func delayedSmudge(gf *lfs.GitFilter, s *git.FilterProcessScanner, to io.Writer,
 // will not be downloaded, and the object will remain a pointer on disk, as if
 // the smudge filter had not been applied at all.
 //
-// Any errors encountered along the way will be returned immediately if they
-// were non-fatal, otherwise execution will halt and the process will be
+// smudge returns the number of bytes written, the *lfs.Pointer that was
+// smudged, if its object's contents were written, and an error, if one
+// occurred. Any errors encountered along the way will be returned immediately
+// if they were non-fatal, otherwise execution will halt and the process will be
 // terminated by using the `commands.Panic()` func.
-func smudge(gf *lfs.GitFilter, to io.Writer, from io.Reader, filename string, skip bool, filter *filepathfilter.Filter) (int64, error) {
+func smudge(gf *lfs.GitFilter, to io.Writer, from io.Reader, filename string, skip bool, filter *filepathfilter.Filter) (int64, *lfs.Pointer, error) {
 	ptr, pbuf, perr := lfs.DecodeFrom(from)
 	if perr != nil {
 		n, err := tools.Spool(to, pbuf, cfg.TempDir())
 		if err != nil {
-			return 0, errors.Wrap(err, perr.Error())
+			return 0, nil, errors.Wrap(err, perr.Error())
 		}
 
-		if n != 0 {
-			return 0, errors.NewNotAPointerError(errors.Errorf(
+		if n != 0 && !storedAsBlob(gf, filename, n) {
+			return 0, nil, errors.NewNotAPointerError(errors.Errorf(
 				"Unable to parse pointer at: %q", filename,
 			))
 		}
-		return 0, nil
+		return 0, nil, nil
 	}
 
 	lfs.LinkOrCopyFromReference(cfg, ptr.Oid, ptr.Size)
 	cb, file, err := gf.CopyCallbackFile("download", filename, 1, 1)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	download := !skip
@@ -130,22 +137,48 @@ func smudge(gf *lfs.GitFilter, to io.Writer, from io.Reader, filename string, sk
 	}
 
 	if err != nil {
+		var oid string = ptr.Oid
+		if len(oid) >= 7 {
+			oid = oid[:7]
+		}
+
+		// The object cannot be downloaded, so write what
+		// lfs.missingcontent asks for in its place.
+		if isUnavailableObjectError(err) && cfg.MissingContent() == config.MissingContentPlaceholder {
+			Error("warning: writing placeholder for %s (%s): %s", filename, oid, errors.Cause(err))
+			n, err := lfs.WritePlaceholder(to, ptr)
+			return n, nil, err
+		}
+
 		ptr.Encode(to)
 		// Download declined error is ok to skip if we weren't requesting download
 		if !(errors.IsDownloadDeclinedError(err) && !download) {
-			var oid string = ptr.Oid
-			if len(oid) >= 7 {
-				oid = oid[:7]
-			}
-
-			LoggedError(err, "Error downloading object: %s (%s): %s", filename, oid, err)
-			if !cfg.SkipDownloadErrors() {
-				os.Exit(2)
+			if isUnavailableObjectError(err) && cfg.AllowMissingObjects() {
+				Error("warning: leaving pointer for %s (%s): %s", filename, oid, errors.Cause(err))
+			} else {
+				LoggedError(err, "Error downloading object: %s (%s): %s", filename, oid, err)
+				if !cfg.SkipDownloadErrors() {
+					os.Exit(failureExitCode())
+				}
 			}
 		}
+		return n, nil, nil
 	}
 
-	return n, nil
+	return n, ptr, nil
+}
+
+// storedAsBlob returns whether the file with the given name and size may have
+// been stored as a plain Git blob rather than as a Git LFS object because it
+// did not meet the conditions set by the lfs-min-size and lfs-content-type
+// attributes.
+func storedAsBlob(gf *lfs.GitFilter, fileName string, size int64) bool {
+	if len(fileName) == 0 {
+		return false
+	}
+
+	conditions, err := gf.TrackingConditions(fileName)
+	return err == nil && conditions.MayBeBlob(size)
 }
 
 func smudgeCommand(cmd *cobra.Command, args []string) {
@@ -158,8 +191,25 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 	}
 	filter := filepathfilter.New(cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
 	gitfilter := lfs.NewGitFilter(cfg)
+	defer gitfilter.Close()
+
+	if smudgeBatch {
+		if len(args) > 0 {
+			ExitWithError(errors.New("fatal: --batch cannot be combined with a path"))
+		}
+
+		filterBatch(func(to io.Writer, from io.Reader, path string, size int64) error {
+			_, _, err := smudge(gitfilter, to, from, path, smudgeSkip, filter)
+			if errors.IsNotAPointerError(err) {
+				fmt.Fprintln(os.Stderr, err.Error())
+				return nil
+			}
+			return err
+		})
+		return
+	}
 
-	if n, err := smudge(gitfilter, os.Stdout, os.Stdin, smudgeFilename(args), smudgeSkip, filter); err != nil {
+	if n, _, err := smudge(gitfilter, os.Stdout, os.Stdin, smudgeFilename(args), smudgeSkip, filter); err != nil {
 		if errors.IsNotAPointerError(err) {
 			fmt.Fprintln(os.Stderr, err.Error())
 		} else {
@@ -184,5 +234,6 @@ func possiblyMalformedObjectSize(n int64) bool {
 func init() {
 	RegisterCommand("smudge", smudgeCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&smudgeSkip, "skip", "s", false, "")
+		cmd.Flags().BoolVarP(&smudgeBatch, "batch", "", false, "")
 	})
 }
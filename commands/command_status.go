@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -166,7 +167,14 @@ func blobInfo(s *lfs.PointerScanner, blobSha, name string) (sha, from string, er
 }
 
 func scanIndex(ref string) (staged, unstaged []*lfs.DiffIndexEntry, err error) {
-	uncached, err := lfs.NewDiffIndexScanner(ref, false, true)
+	monitor := newStatusMonitor()
+
+	var uncached *lfs.DiffIndexScanner
+	if paths, ok := monitor.Paths(); !ok {
+		uncached, err = lfs.NewDiffIndexScanner(ref, false, true)
+	} else if len(paths) > 0 {
+		uncached, err = lfs.NewDiffIndexScanner(ref, false, true, paths...)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -183,14 +191,126 @@ func scanIndex(ref string) (staged, unstaged []*lfs.DiffIndexEntry, err error) {
 		return nil, nil, err
 	}
 
-	unstaged, err = drainScanner(seenNames, uncached)
-	if err != nil {
-		return nil, nil, err
+	if uncached != nil {
+		unstaged, err = drainScanner(seenNames, uncached)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
+	monitor.Save(append(staged, unstaged...))
 	return
 }
 
+// fsmonitorMaxPaths is the largest number of paths which statusMonitor will
+// pass to git diff-index, beyond which the whole working tree is compared.
+const fsmonitorMaxPaths = 1000
+
+// statusMonitor uses the hook named by core.fsmonitor to narrow down the paths
+// whose working tree copies may differ from HEAD to those which the hook
+// reports as changed since the last status, along with those which differed
+// at the time, which are recorded in the Git directory with the hook's token.
+type statusMonitor struct {
+	path  string
+	head  string
+	state statusMonitorState
+
+	next    string
+	changed []string
+	ok      bool
+}
+
+type statusMonitorState struct {
+	Token string   `json:"token"`
+	Head  string   `json:"head"`
+	Paths []string `json:"paths"`
+}
+
+func newStatusMonitor() *statusMonitor {
+	m := &statusMonitor{
+		path: filepath.Join(cfg.LocalGitDir(), "lfs", "fsmonitor"),
+	}
+
+	hook := cfg.FSMonitorHook()
+	if len(hook) == 0 {
+		return m
+	}
+	if ref, err := git.CurrentRef(); err == nil {
+		m.head = ref.Sha
+	}
+	if data, err := ioutil.ReadFile(m.path); err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			Debug("fsmonitor: ignoring invalid state: %v", err)
+		}
+	}
+
+	next, changed, all, err := git.FSMonitorChanges(cfg.LocalWorkingDir(), hook, m.state.Token)
+	if err != nil {
+		Debug("fsmonitor: %v", err)
+		return m
+	}
+	m.next = next
+	m.ok = true
+
+	if !all && len(m.state.Token) > 0 && m.state.Head == m.head {
+		m.changed = changed
+	} else {
+		m.state.Paths = nil
+		m.changed = nil
+		m.state.Token = ""
+	}
+	return m
+}
+
+// Paths returns the paths which may have changed in the working tree, and
+// whether they are known; if not, the whole working tree must be compared.
+func (m *statusMonitor) Paths() ([]string, bool) {
+	if !m.ok || len(m.state.Token) == 0 {
+		return nil, false
+	}
+
+	seen := make(map[string]struct{})
+	var paths []string
+	for _, path := range append(m.state.Paths, m.changed...) {
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) > fsmonitorMaxPaths {
+		return nil, false
+	}
+	return paths, true
+}
+
+// Save records the hook's latest token, along with the paths of the given
+// entries, which include all of those whose working tree copies differ from
+// HEAD.
+func (m *statusMonitor) Save(entries []*lfs.DiffIndexEntry) {
+	if !m.ok {
+		return
+	}
+
+	state := statusMonitorState{Token: m.next, Head: m.head}
+	for _, entry := range entries {
+		state.Paths = append(state.Paths, entry.SrcName)
+		if len(entry.DstName) > 0 && entry.DstName != entry.SrcName {
+			state.Paths = append(state.Paths, entry.DstName)
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = tools.MkdirAll(filepath.Dir(m.path), cfg)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(m.path, data, 0644)
+	}
+	if err != nil {
+		Debug("fsmonitor: could not save state: %v", err)
+	}
+}
+
 func drainScanner(cache map[string]struct{}, scanner *lfs.DiffIndexScanner) ([]*lfs.DiffIndexEntry, error) {
 	var to []*lfs.DiffIndexEntry
 
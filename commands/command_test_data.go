@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	testDataObjects    int
+	testDataCommits    int
+	testDataBranches   int
+	testDataSizeDist   string
+	testDataExtensions string
+	testDataSeed       int64
+
+	// testDataEpoch is the date of the first generated commit. Each
+	// subsequent commit is made one hour later, so that the generated
+	// history (and its commit SHAs) is identical for a given set of
+	// options.
+	testDataEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// sizeBucket is a range of object sizes, inclusive, along with the relative
+// weight with which a size from the range is chosen.
+type sizeBucket struct {
+	min, max uint64
+	weight   int
+}
+
+// testDataRepo holds the state used while generating a synthetic repository.
+type testDataRepo struct {
+	dir     string
+	cfg     *config.Configuration
+	filter  *lfs.GitFilter
+	rng     *rand.Rand
+	buckets []*sizeBucket
+	exts    []string
+
+	// paths holds the files that exist on each branch, so that later
+	// commits may create new versions of them.
+	paths map[string][]string
+	// count is the number of files created so far, used to name new
+	// files.
+	count int
+	// size is the total size of all objects written so far.
+	size uint64
+}
+
+func testDataCommand(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		Print("Usage: git lfs test-data [options] <directory>")
+		os.Exit(1)
+	}
+
+	if testDataObjects < 1 {
+		Exit("Invalid number of objects: %d", testDataObjects)
+	}
+	if testDataCommits < 1 {
+		Exit("Invalid number of commits: %d", testDataCommits)
+	}
+	if testDataBranches < 1 {
+		Exit("Invalid number of branches: %d", testDataBranches)
+	}
+
+	buckets, err := parseSizeDistribution(testDataSizeDist)
+	if err != nil {
+		Exit("Invalid size distribution %q: %s", testDataSizeDist, err)
+	}
+
+	var exts []string
+	for _, ext := range strings.Split(testDataExtensions, ",") {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); len(ext) > 0 {
+			exts = append(exts, ext)
+		}
+	}
+	if len(exts) == 0 {
+		Exit("At least one extension must be given with --extensions")
+	}
+
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		ExitWithError(err)
+	}
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) > 0 {
+		Exit("Destination %q already exists and is not empty", args[0])
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ExitWithError(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		ExitWithError(err)
+	}
+	if err := runTestDataGit("init", "-q"); err != nil {
+		ExitWithError(errors.Wrap(err, "could not initialize repository"))
+	}
+	if err := runTestDataGit("symbolic-ref", "HEAD", "refs/heads/main"); err != nil {
+		ExitWithError(err)
+	}
+
+	repoCfg := config.NewIn(dir, filepath.Join(dir, ".git"))
+	repo := &testDataRepo{
+		dir:     dir,
+		cfg:     repoCfg,
+		filter:  lfs.NewGitFilter(repoCfg),
+		rng:     rand.New(rand.NewSource(testDataSeed)),
+		buckets: buckets,
+		exts:    exts,
+		paths:   make(map[string][]string),
+	}
+
+	if err := repo.generate(testDataObjects, testDataCommits, testDataBranches); err != nil {
+		ExitWithError(err)
+	}
+
+	Print("Generated %d object(s) (%s) in %d commit(s) on %d branch(es) in %s",
+		testDataObjects, humanize.FormatBytes(repo.size),
+		testDataCommits, testDataBranches, args[0])
+}
+
+// generate writes the given number of objects across the given number of
+// commits, spreading the commits across the given number of branches.
+func (r *testDataRepo) generate(objects, commits, branches int) error {
+	if err := r.writeAttributes(); err != nil {
+		return err
+	}
+
+	for i := 0; i < commits; i++ {
+		branch := "main"
+		if n := i % branches; n > 0 {
+			branch = fmt.Sprintf("branch-%d", n)
+		}
+
+		if i > 0 {
+			args := []string{"checkout", "-q", branch}
+			if _, ok := r.paths[branch]; !ok {
+				// Start each new branch from the current tip
+				// of main, and copy its set of files.
+				args = []string{"checkout", "-q", "-b", branch, "main"}
+				r.paths[branch] = append([]string(nil), r.paths["main"]...)
+			}
+			if err := runTestDataGit(args...); err != nil {
+				return err
+			}
+		}
+
+		n := objects / commits
+		if i < objects%commits {
+			n++
+		}
+		for j := 0; j < n; j++ {
+			if err := r.addObject(branch); err != nil {
+				return err
+			}
+		}
+
+		if err := r.commit(i, n); err != nil {
+			return err
+		}
+	}
+
+	if branches > 1 && commits > 1 {
+		return runTestDataGit("checkout", "-q", "main")
+	}
+	return nil
+}
+
+func (r *testDataRepo) writeAttributes() error {
+	var lines []string
+	for _, ext := range r.exts {
+		lines = append(lines, fmt.Sprintf("*.%s filter=lfs diff=lfs merge=lfs -text", escapeAttrPattern(ext)))
+	}
+
+	path := filepath.Join(r.dir, ".gitattributes")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return runTestDataGit("add", ".gitattributes")
+}
+
+// addObject writes a new object into the local object store, and stages a
+// pointer to it, either as a new file or as a new version of an existing
+// file on the given branch.
+func (r *testDataRepo) addObject(branch string) error {
+	var name string
+	if existing := r.paths[branch]; len(existing) > 0 && r.rng.Intn(4) == 0 {
+		name = existing[r.rng.Intn(len(existing))]
+	} else {
+		ext := r.exts[r.rng.Intn(len(r.exts))]
+		name = fmt.Sprintf("data/%03d/file%06d.%s", r.count/100, r.count, ext)
+		r.count++
+		r.paths[branch] = append(r.paths[branch], name)
+	}
+
+	size := r.nextSize()
+	data := io.LimitReader(rand.New(rand.NewSource(r.rng.Int63())), int64(size))
+
+	cleaned, err := r.filter.Clean(data, name, int64(size), nil)
+	if err != nil {
+		return errors.Wrap(err, "could not create object")
+	}
+
+	mediafile, err := r.cfg.Filesystem().ObjectPath(cleaned.Oid)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(mediafile); err == nil {
+		os.Remove(cleaned.Filename)
+	} else if err := os.Rename(cleaned.Filename, mediafile); err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(cleaned.Pointer.Encoded()), 0644); err != nil {
+		return err
+	}
+
+	r.size += size
+	return runTestDataGit("add", name)
+}
+
+// nextSize returns a size chosen from the configured size distribution.
+func (r *testDataRepo) nextSize() uint64 {
+	total := 0
+	for _, b := range r.buckets {
+		total += b.weight
+	}
+
+	n := r.rng.Intn(total)
+	for _, b := range r.buckets {
+		if n < b.weight {
+			return b.min + uint64(r.rng.Int63n(int64(b.max-b.min+1)))
+		}
+		n -= b.weight
+	}
+	return r.buckets[len(r.buckets)-1].max
+}
+
+func (r *testDataRepo) commit(i, objects int) error {
+	date := git.FormatGitDate(testDataEpoch.Add(time.Duration(i) * time.Hour))
+
+	cmd := subprocess.ExecCommand("git",
+		"-c", "user.name=Git LFS Test Data",
+		"-c", "user.email=git-lfs@example.com",
+		"-c", "commit.gpgsign=false",
+		"commit", "-q", "--allow-empty", "--no-verify",
+		"-m", fmt.Sprintf("Add %d object(s)", objects))
+	cmd.Env = append(cmd.Env,
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_DATE="+date)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("could not commit: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func runTestDataGit(args ...string) error {
+	cmd := subprocess.ExecCommand("git", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("error running git %s: %s: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// parseSizeDistribution parses a comma-separated list of size ranges, each
+// of the form "<min>-<max>[:<weight>]" or "<size>[:<weight>]", where sizes
+// are given in any form understood by humanize.ParseBytes.
+func parseSizeDistribution(str string) ([]*sizeBucket, error) {
+	var buckets []*sizeBucket
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		weight := 1
+		if idx := strings.LastIndex(part, ":"); idx >= 0 {
+			w, err := strconv.Atoi(part[idx+1:])
+			if err != nil || w < 1 {
+				return nil, errors.Errorf("invalid weight in %q", part)
+			}
+			weight = w
+			part = part[:idx]
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		min, err := humanize.ParseBytes(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, err
+		}
+		max := min
+		if len(bounds) > 1 {
+			if max, err = humanize.ParseBytes(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, err
+			}
+		}
+		if max < min {
+			return nil, errors.Errorf("invalid range %q", part)
+		}
+
+		buckets = append(buckets, &sizeBucket{min: min, max: max, weight: weight})
+	}
+
+	if len(buckets) == 0 {
+		return nil, errors.New("no sizes given")
+	}
+	return buckets, nil
+}
+
+func init() {
+	RegisterCommand("test-data", testDataCommand, func(cmd *cobra.Command) {
+		cmd.Flags().IntVarP(&testDataObjects, "objects", "n", 100, "Number of objects to generate.")
+		cmd.Flags().IntVarP(&testDataCommits, "commits", "c", 10, "Number of commits to spread the objects across.")
+		cmd.Flags().IntVarP(&testDataBranches, "branches", "b", 1, "Number of branches to spread the commits across.")
+		cmd.Flags().StringVarP(&testDataSizeDist, "size-dist", "s", "1KB-1MB", "Distribution of object sizes.")
+		cmd.Flags().StringVarP(&testDataExtensions, "extensions", "e", "bin", "Comma-separated list of file extensions to use.")
+		cmd.Flags().Int64VarP(&testDataSeed, "seed", "", 1, "Seed for the random number generator.")
+	})
+}
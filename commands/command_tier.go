@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"os"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+// defaultTierDays is the number of days after which an unused object is moved
+// to secondary storage, unless lfs.tier.days says otherwise.
+const defaultTierDays = 30
+
+var (
+	tierDaysArg    int
+	tierDryRunArg  bool
+	tierVerboseArg bool
+)
+
+// tierCommand moves the objects in the object directory which have not been
+// used for a while to the secondary storage configured with lfs.tier.path,
+// from which they are recalled when they are next needed.
+func tierCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	f := cfg.Filesystem()
+	if len(f.TierDir) == 0 {
+		Exit("No secondary storage is configured; set lfs.tier.path to use it")
+	}
+
+	days := tierDaysArg
+	if days < 0 {
+		days = cfg.Git.Int("lfs.tier.days", defaultTierDays)
+	}
+	cutoff := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+
+	move := f.TierObject
+	if tierDryRunArg {
+		move = func(string) error { return nil }
+	}
+
+	moved, size := moveLooseObjects(move, func(obj fs.Object) bool {
+		fi, err := os.Stat(f.ObjectPathname(obj.Oid))
+		if err != nil || tools.LastUsed(fi).After(cutoff) {
+			return false
+		}
+		if tierVerboseArg {
+			Print(" * %s (%s)", obj.Oid, humanize.FormatBytes(uint64(obj.Size)))
+		}
+		return true
+	})
+
+	if tierDryRunArg {
+		Print("tier: %d object(s) would be moved to %s (%s)", moved, f.TierDir, humanize.FormatBytes(uint64(size)))
+	} else {
+		Print("tier: %d object(s) moved to %s (%s)", moved, f.TierDir, humanize.FormatBytes(uint64(size)))
+	}
+}
+
+func init() {
+	RegisterCommand("tier", tierCommand, func(cmd *cobra.Command) {
+		cmd.Flags().IntVar(&tierDaysArg, "days", -1, "Move objects unused for this many days")
+		cmd.Flags().BoolVarP(&tierDryRunArg, "dry-run", "d", false, "Don't move anything, just report")
+		cmd.Flags().BoolVarP(&tierVerboseArg, "verbose", "v", false, "Print each object which is/would be moved")
+	})
+}
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/spf13/cobra"
+)
+
+// transferAgentBatchDelay is how long to wait for further requests after the
+// first of a batch, before transferring those which have arrived so far.
+const transferAgentBatchDelay = 100 * time.Millisecond
+
+// transferAgentRequest is a message sent to "git lfs transfer-agent", as
+// described in docs/custom-transfers.md. Not all fields are filled in on all
+// requests.
+type transferAgentRequest struct {
+	Event     string `json:"event"`
+	Operation string `json:"operation"`
+	Remote    string `json:"remote"`
+	Oid       string `json:"oid"`
+	Size      int64  `json:"size"`
+	Path      string `json:"path"`
+}
+
+type transferAgentError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// transferAgentResponse is a message sent by "git lfs transfer-agent" in
+// response to a request.
+type transferAgentResponse struct {
+	Event          string              `json:"event,omitempty"`
+	Oid            string              `json:"oid,omitempty"`
+	Path           string              `json:"path,omitempty"`
+	BytesSoFar     int64               `json:"bytesSoFar,omitempty"`
+	BytesSinceLast int64               `json:"bytesSinceLast,omitempty"`
+	Error          *transferAgentError `json:"error,omitempty"`
+}
+
+// transferAgent transfers the objects requested over the custom transfer
+// protocol with the transfer queue, as any other command does.
+type transferAgent struct {
+	direction tq.Direction
+	remote    string
+	manifest  *tq.Manifest
+	tempdir   string
+	output    *json.Encoder
+}
+
+// transferAgentCommand speaks the custom transfer protocol on stdin and
+// stdout, so that other programs can transfer objects through Git LFS as they
+// would through a custom transfer agent.
+func transferAgentCommand(cmd *cobra.Command, args []string) {
+	setupRepository()
+
+	requests := make(chan *transferAgentRequest)
+	go readTransferAgentRequests(os.Stdin, requests)
+
+	output := json.NewEncoder(os.Stdout)
+	init, ok := <-requests
+	if !ok {
+		return
+	}
+	agent, err := newTransferAgent(init, output)
+	if err != nil {
+		output.Encode(&transferAgentResponse{Error: &transferAgentError{Code: 32, Message: err.Error()}})
+		os.Exit(2)
+	}
+	defer os.RemoveAll(agent.tempdir)
+	output.Encode(&transferAgentResponse{})
+
+	for {
+		batch, more := agent.nextBatch(requests)
+		agent.transfer(batch)
+		if !more {
+			return
+		}
+	}
+}
+
+// readTransferAgentRequests decodes one request from each line of the given
+// input, and sends it on the given channel, which is closed at the end of the
+// input.
+func readTransferAgentRequests(input *os.File, requests chan<- *transferAgentRequest) {
+	defer close(requests)
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		req := &transferAgentRequest{}
+		if err := json.Unmarshal(scanner.Bytes(), req); err != nil {
+			ExitWithError(errors.Wrap(err, "error decoding json"))
+		}
+		requests <- req
+	}
+	if err := scanner.Err(); err != nil {
+		ExitWithError(errors.Wrap(err, "error reading input"))
+	}
+}
+
+func newTransferAgent(init *transferAgentRequest, output *json.Encoder) (*transferAgent, error) {
+	if init.Event != "init" {
+		return nil, errors.Errorf("expected init event, got %q", init.Event)
+	}
+
+	var direction tq.Direction
+	switch init.Operation {
+	case "upload":
+		direction = tq.Upload
+	case "download":
+		direction = tq.Download
+	default:
+		return nil, errors.Errorf("unknown operation %q", init.Operation)
+	}
+
+	if len(init.Remote) > 0 {
+		if err := cfg.SetValidRemote(init.Remote); err != nil {
+			return nil, errors.Wrapf(err, "invalid remote %q", init.Remote)
+		}
+	}
+	remote := cfg.Remote()
+
+	tempdir, err := ioutil.TempDir(cfg.TempDir(), "lfs-transfer-agent-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferAgent{
+		direction: direction,
+		remote:    remote,
+		manifest:  getTransferManifestOperationRemote(init.Operation, remote),
+		tempdir:   tempdir,
+		output:    output,
+	}, nil
+}
+
+// nextBatch returns the next request, along with any more which are sent
+// within transferAgentBatchDelay of it, up to lfs.transfer.batchsize, so that
+// clients which do not wait for each transfer to complete before requesting
+// the next have their objects transferred in batches. It returns false once
+// there are no more requests to read.
+func (a *transferAgent) nextBatch(requests <-chan *transferAgentRequest) ([]*transferAgentRequest, bool) {
+	var batch []*transferAgentRequest
+	var timer *time.Timer
+
+	req, ok := <-requests
+	for ok {
+		switch req.Event {
+		case "terminate":
+			return batch, false
+		case "upload", "download":
+			batch = append(batch, req)
+		default:
+			Exit("unknown event %q", req.Event)
+		}
+
+		if size := a.manifest.BatchSize(); size > 0 && len(batch) >= size {
+			return batch, true
+		}
+		if timer == nil {
+			timer = time.NewTimer(transferAgentBatchDelay)
+			defer timer.Stop()
+		}
+		select {
+		case req, ok = <-requests:
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, false
+}
+
+// transfer transfers the objects of the given requests, and responds to each
+// once they are all complete.
+func (a *transferAgent) transfer(batch []*transferAgentRequest) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var mu sync.Mutex
+	succeeded := make(map[string]bool, len(batch))
+	succeed := func(oid string) {
+		mu.Lock()
+		succeeded[oid] = true
+		mu.Unlock()
+	}
+
+	q := tq.NewTransferQueue(a.direction, a.manifest, a.remote,
+		tq.RemoteRef(currentRemoteRef()),
+		tq.WithPresentCallback(succeed))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	watch := q.Watch()
+	go func() {
+		defer wg.Done()
+		for t := range watch {
+			succeed(t.Oid)
+		}
+	}()
+
+	for _, req := range batch {
+		if req.Event != a.direction.String() {
+			continue
+		}
+		if a.direction == tq.Download {
+			if cfg.LFSObjectExists(req.Oid, req.Size) {
+				succeed(req.Oid)
+				continue
+			}
+			path, err := cfg.Filesystem().ObjectPath(req.Oid)
+			q.Add(req.Oid, path, req.Oid, req.Size, false, err)
+		} else {
+			q.Add(req.Oid, req.Path, req.Oid, req.Size, false, nil)
+		}
+	}
+	q.Wait()
+	wg.Wait()
+
+	errs := q.Errors()
+	for _, req := range batch {
+		res := &transferAgentResponse{Event: "complete", Oid: req.Oid}
+
+		var err error
+		if req.Event != a.direction.String() {
+			err = errors.Errorf("cannot %s in a %s transfer agent", req.Event, a.direction)
+		} else if !succeeded[req.Oid] {
+			err = transferAgentErrorFor(req.Oid, errs)
+		} else if a.direction == tq.Download {
+			res.Path, err = a.copyObject(req.Oid)
+		}
+
+		if err != nil {
+			res.Error = &transferAgentError{Code: 2, Message: err.Error()}
+		} else {
+			a.output.Encode(&transferAgentResponse{
+				Event:          "progress",
+				Oid:            req.Oid,
+				BytesSoFar:     req.Size,
+				BytesSinceLast: req.Size,
+			})
+		}
+		a.output.Encode(res)
+	}
+}
+
+// copyObject copies the local object with the given OID to a temporary file,
+// which is handed over to the client, and returns its path.
+func (a *transferAgent) copyObject(oid string) (string, error) {
+	tmp, err := ioutil.TempFile(a.tempdir, "download")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	return tmp.Name(), lfs.LinkOrCopyObject(cfg, cfg.Filesystem(), oid, tmp.Name())
+}
+
+// transferAgentErrorFor returns the error from the transfer queue which
+// mentions the object with the given OID, or a generic one if there is none.
+func transferAgentErrorFor(oid string, errs []error) error {
+	for _, err := range errs {
+		if strings.Contains(err.Error(), oid) {
+			return err
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("unable to transfer object %s", oid)
+}
+
+func init() {
+	RegisterCommand("transfer-agent", transferAgentCommand, nil)
+}
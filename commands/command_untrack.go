@@ -6,9 +6,16 @@ import (
 	"os"
 	"strings"
 
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/spf13/cobra"
 )
 
+var (
+	untrackRestoreFlag bool
+)
+
 // untrackCommand takes a list of paths as an argument, and removes each path from the
 // default attributes file (.gitattributes), if it exists.
 func untrackCommand(cmd *cobra.Command, args []string) {
@@ -21,6 +28,10 @@ func untrackCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if untrackRestoreFlag && !git.IsGitVersionAtLeast("2.16.0") {
+		Exit("git version >= 2.16.0 is required for --restore")
+	}
+
 	data, err := ioutil.ReadFile(".gitattributes")
 	if err != nil {
 		return
@@ -33,10 +44,11 @@ func untrackCommand(cmd *cobra.Command, args []string) {
 		Print("Error opening .gitattributes for writing")
 		return
 	}
-	defer attributesFile.Close()
 
 	scanner := bufio.NewScanner(attributes)
 
+	var untracked []string
+
 	// Iterate through each line of the attributes file and rewrite it,
 	// if the path was meant to be untracked, omit it, and print a message instead.
 	for scanner.Scan() {
@@ -49,10 +61,62 @@ func untrackCommand(cmd *cobra.Command, args []string) {
 		path := strings.Fields(line)[0]
 		if removePath(path, args) {
 			Print("Untracking %q", unescapeAttrPattern(path))
+			untracked = append(untracked, unescapeAttrPattern(path))
 		} else {
 			attributesFile.WriteString(line + "\n")
 		}
 	}
+	attributesFile.Close()
+
+	if untrackRestoreFlag && len(untracked) > 0 {
+		restoreUntrackedFiles(untracked)
+	}
+}
+
+// restoreUntrackedFiles replaces any pointers in the working tree which match
+// the given patterns with their contents, and stages the files (and the
+// updated .gitattributes) so that they are stored as regular Git blobs.
+func restoreUntrackedFiles(patterns []string) {
+	gitfilter := lfs.NewGitFilter(cfg)
+	manifest := getTransferManifestOperationRemote("download", cfg.Remote())
+
+	paths := []string{".gitattributes"}
+	failed := false
+	for _, pattern := range patterns {
+		files, err := git.GetTrackedFiles(pattern)
+		if err != nil {
+			ExitWithError(errors.Wrapf(err, "could not list files matching %q", pattern))
+		}
+
+		for _, file := range files {
+			ptr, err := lfs.DecodePointerFromFile(file)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				if !errors.IsNotAPointerError(err) && !errors.IsBadPointerKeyError(err) {
+					Error("Could not restore %q: %s", file, err)
+					failed = true
+					continue
+				}
+			} else if err := gitfilter.SmudgeToFile(file, ptr, true, manifest, nil); err != nil {
+				Error("Could not restore %q: %s", file, err)
+				failed = true
+				continue
+			}
+
+			Print("Restoring %q", file)
+			paths = append(paths, file)
+		}
+	}
+
+	if err := git.AddRenormalize(paths); err != nil {
+		ExitWithError(errors.Wrap(err, "could not stage restored files"))
+	}
+
+	if failed {
+		os.Exit(2)
+	}
 }
 
 func removePath(path string, args []string) bool {
@@ -67,5 +131,7 @@ func removePath(path string, args []string) bool {
 }
 
 func init() {
-	RegisterCommand("untrack", untrackCommand, nil)
+	RegisterCommand("untrack", untrackCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&untrackRestoreFlag, "restore", "", false, "restore the contents of untracked files and stage them as regular Git files")
+	})
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
@@ -18,6 +19,7 @@ import (
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
 	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
 	"github.com/git-lfs/git-lfs/v2/locking"
 	"github.com/git-lfs/git-lfs/v2/subprocess"
 	"github.com/git-lfs/git-lfs/v2/tools"
@@ -121,9 +123,16 @@ func newDownloadCheckQueue(manifest *tq.Manifest, remote string, options ...tq.O
 func newDownloadQueue(manifest *tq.Manifest, remote string, options ...tq.Option) *tq.TransferQueue {
 	return tq.NewTransferQueue(tq.Download, manifest, remote, append(options,
 		tq.RemoteRef(currentRemoteRef()),
+		tq.WithPendingFile(pendingQueueFile(tq.Download)),
 	)...)
 }
 
+// pendingQueueFile returns the file in which the objects which were not
+// transferred in the given direction are recorded, for "git lfs queue".
+func pendingQueueFile(d tq.Direction) string {
+	return filepath.Join(cfg.LFSStorageDir(), "queue", d.String()+".json")
+}
+
 func currentRemoteRef() *git.Ref {
 	return git.NewRefUpdate(cfg.Git, cfg.PushRemote(), cfg.CurrentRef(), nil).Right()
 }
@@ -210,10 +219,23 @@ func Print(format string, args ...interface{}) {
 	fmt.Fprintf(OutputWriter, format+"\n", args...)
 }
 
+// offlineExitCode is the exit status of commands which fail because they
+// needed to connect to a server while Git LFS is offline.
+const offlineExitCode = 3
+
+// failureExitCode returns the status to exit with after a failure, which is
+// offlineExitCode if any connection was refused because Git LFS is offline.
+func failureExitCode() int {
+	if lfshttp.RefusedOffline() {
+		return offlineExitCode
+	}
+	return 2
+}
+
 // Exit prints a formatted message and exits.
 func Exit(format string, args ...interface{}) {
 	Error(format, args...)
-	os.Exit(2)
+	os.Exit(failureExitCode())
 }
 
 // ExitWithError either panics with a full stack trace for fatal errors, or
@@ -267,7 +289,7 @@ func LoggedError(err error, format string, args ...interface{}) {
 // a log file before exiting.
 func Panic(err error, format string, args ...interface{}) {
 	LoggedError(err, format, args...)
-	os.Exit(2)
+	os.Exit(failureExitCode())
 }
 
 func Cleanup() {
@@ -329,6 +351,7 @@ func setupRepository() {
 			err, "fatal: could not determine bareness"))
 	}
 	verifyRepositoryVersion()
+	autoGCTemp()
 
 	if !bare {
 		changeToWorkingCopy()
@@ -350,6 +373,7 @@ func setupWorkingCopy() {
 	requireInRepo()
 	requireWorkingCopy()
 	verifyRepositoryVersion()
+	autoGCTemp()
 	changeToWorkingCopy()
 }
 
@@ -538,6 +562,43 @@ func determineIncludeExcludePaths(config *config.Configuration, includeArg, excl
 	return
 }
 
+// readPatternFile reads the include or exclude patterns in the file with the
+// given name, or from standard input if it is "-". If the file contains NUL
+// bytes, they separate the patterns; otherwise, each line is a pattern, and
+// blank lines and lines starting with "#" are ignored. Patterns are cleaned
+// as those given on the command line are.
+func readPatternFile(name string) ([]string, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read patterns from %q", name)
+	}
+
+	var patterns []string
+	if bytes.IndexByte(data, 0) >= 0 {
+		for _, pattern := range tools.CleanPaths(string(data), "\x00") {
+			if len(pattern) > 0 {
+				patterns = append(patterns, pattern)
+			}
+		}
+		return patterns, nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, tools.CleanPaths(line, "\x00")...)
+	}
+	return patterns, nil
+}
+
 func buildProgressMeter(dryRun bool, d tq.Direction) *tq.Meter {
 	m := tq.NewMeter(cfg)
 	m.Logger = m.LoggerFromEnv(cfg.Os)
@@ -546,6 +607,51 @@ func buildProgressMeter(dryRun bool, d tq.Direction) *tq.Meter {
 	return m
 }
 
+// adviseIgnoreArtifact prints a hint if a file written by a command, such as an
+// archive of objects, is in the working tree but not ignored by Git, so that it
+// isn't committed by accident, as Git LFS would then store it as an object
+// itself.
+func adviseIgnoreArtifact(filename string) {
+	root := cfg.LocalWorkingDir()
+	if len(root) == 0 || len(filename) == 0 || filename == "-" {
+		return
+	}
+
+	abs, err := filepath.Abs(filename)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		Debug("Could not determine whether %q is in the working tree: %v", filename, err)
+		return
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		return
+	}
+
+	ignored, err := git.IsIgnored(abs)
+	if err != nil {
+		Debug("Could not determine whether %q is ignored: %v", filename, err)
+		return
+	}
+	if ignored {
+		return
+	}
+
+	Error("hint: %q is in the working tree but is not ignored by Git.", filename)
+	Error("hint: To avoid committing it, add it to .gitignore:")
+	Error("hint:   echo '/%s' >> .gitignore", rel)
+}
+
 func requireGitVersion() {
 	minimumGit := "1.8.2"
 
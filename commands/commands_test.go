@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -47,3 +51,34 @@ func TestDetermineIncludeExcludePathsReturnsNothingWhenAbsent(t *testing.T) {
 	assert.Empty(t, i)
 	assert.Empty(t, e)
 }
+
+func TestReadPatternFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "patterns")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for desc, c := range map[string]struct {
+		content  string
+		expected []string
+	}{
+		"lines": {
+			"# Android developers\napp/**\n\n  assets/ \r\n",
+			[]string{"app/**", "assets"},
+		},
+		"NUL-separated": {
+			"app/**\x00# not a comment\x00dir with spaces/\x00",
+			[]string{"app/**", "# not a comment", "dir with spaces"},
+		},
+		"empty": {"", nil},
+	} {
+		name := filepath.Join(dir, "profile")
+		require.Nil(t, ioutil.WriteFile(name, []byte(c.content), 0644))
+
+		patterns, err := readPatternFile(name)
+		require.Nil(t, err, desc)
+		assert.Equal(t, c.expected, patterns, desc)
+	}
+
+	_, err = readPatternFile(filepath.Join(dir, "missing"))
+	assert.Contains(t, err.Error(), "could not read patterns from")
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+// diskSpaceForceArg skips the check for free disk space made before objects
+// are downloaded or checked out.
+var diskSpaceForceArg bool
+
+func addDiskSpaceForceFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&diskSpaceForceArg, "force", false, "Don't check for enough free disk space first")
+}
+
+// checkDiskSpace exits, unless --force was given, if there is not enough free
+// space to store the given number of bytes of downloaded objects and to write
+// the given number of bytes to the working tree. This is better than running
+// out of space part of the way through.
+func checkDiskSpace(storageBytes, worktreeBytes int64) {
+	if diskSpaceForceArg {
+		return
+	}
+
+	needs := map[string]uint64{
+		cfg.LFSObjectDir(): uint64(storageBytes),
+	}
+	if worktreeBytes > 0 {
+		needs[cfg.LocalWorkingDir()] += uint64(worktreeBytes)
+	}
+
+	err := tools.CheckFreeSpace(needs)
+	if serr, ok := err.(*tools.InsufficientSpaceError); ok {
+		Exit("Not enough free disk space: %s needed in %q, but only %s available.\nFree up some space, or use --force to try anyway.",
+			humanize.FormatBytes(serr.Needed), serr.Dir, humanize.FormatBytes(serr.Available))
+	} else if err != nil {
+		ExitWithError(errors.Wrap(err, "could not check free disk space"))
+	}
+}
+
+// downloadSize returns the total size of the objects of the given pointers
+// which do not exist locally, counting each object once.
+func downloadSize(pointers []*lfs.WrappedPointer) int64 {
+	var size int64
+	seen := make(map[string]bool, len(pointers))
+	for _, p := range pointers {
+		if seen[p.Oid] || cfg.LFSObjectExists(p.Oid, p.Size) {
+			continue
+		}
+		seen[p.Oid] = true
+		size += p.Size
+	}
+	return size
+}
+
+// checkoutSize returns how many more bytes the working tree files of the given
+// pointers take up once their content is checked out, from their current size,
+// which is usually that of a pointer file.
+func checkoutSize(pointers []*lfs.WrappedPointer) int64 {
+	var size int64
+	for _, p := range pointers {
+		var current int64
+		if fi, err := os.Lstat(filepath.Join(cfg.LocalWorkingDir(), p.Name)); err == nil {
+			current = fi.Size()
+		}
+		if p.Size > current {
+			size += p.Size - current
+		}
+	}
+	return size
+}
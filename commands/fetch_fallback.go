@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/rubyist/tracerx"
+)
+
+// fetchFallbackRemotes returns the remotes set with lfs.fetchfallbackremotes,
+// separated by commas or spaces, from which the objects missing from the given
+// remote are fetched, in the order in which they are tried.
+func fetchFallbackRemotes(remote string) []string {
+	setting, _ := cfg.Git.Get("lfs.fetchfallbackremotes")
+	fields := strings.FieldsFunc(setting, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	known := tools.NewStringSetFromSlice(cfg.Remotes())
+	remotes := make([]string, 0, len(fields))
+	for _, r := range fields {
+		if r == remote {
+			continue
+		}
+		if !known.Contains(r) {
+			Error("warning: ignoring unknown remote %q in lfs.fetchfallbackremotes", r)
+			continue
+		}
+		remotes = append(remotes, r)
+	}
+	return remotes
+}
+
+// isMissingObjectError returns whether the given transfer error means that
+// the server does not have the object.
+func isMissingObjectError(err error) bool {
+	oerr, ok := errors.Cause(err).(*tq.ObjectError)
+	return ok && oerr.Missing()
+}
+
+// stillMissingErrors returns those of the given missing object errors which
+// are about the objects with the given OIDs, which are still missing once the
+// fallback remotes have been asked for them.
+func stillMissingErrors(errs []error, missing []string) []error {
+	oids := tools.NewStringSetFromSlice(missing)
+	var still []error
+	for _, err := range errs {
+		// The errors are of the form "[<oid>] <message>".
+		msg := err.Error()
+		if end := strings.Index(msg, "]"); strings.HasPrefix(msg, "[") && end > 0 && !oids.Contains(msg[1:end]) {
+			continue
+		}
+		still = append(still, err)
+	}
+	return still
+}
+
+// fetchFromFallbackRemotes downloads the objects with the given OIDs, which
+// the given remote does not have, from each of its fallback remotes in turn,
+// calling fetched with the OID of each one downloaded. It returns the OIDs of
+// the objects none of the fallback remotes has, and the errors other than
+// objects being missing.
+func fetchFromFallbackRemotes(remote string, pointers []*lfs.WrappedPointer, missing []string, fetched func(oid string)) ([]string, []error) {
+	if len(missing) == 0 {
+		return missing, nil
+	}
+	remotes := fetchFallbackRemotes(remote)
+	if len(remotes) == 0 {
+		return missing, nil
+	}
+
+	byOid := make(map[string]*lfs.WrappedPointer, len(pointers))
+	for _, p := range pointers {
+		byOid[p.Oid] = p
+	}
+
+	endpoints := getAPIClient().Endpoints
+	tried := tools.NewStringSet()
+	tried.Add(endpoints.Endpoint("download", remote).Url)
+
+	var errs []error
+	for _, fallback := range remotes {
+		if interrupted() {
+			break
+		}
+
+		url := endpoints.Endpoint("download", fallback).Url
+		if !tried.Add(url) {
+			// The fallback remote uses a server already asked.
+			continue
+		}
+
+		cache := newMissingCache(url)
+		var stillMissing []string
+		var mu sync.Mutex
+		q := trackQueue(newDownloadQueue(
+			getTransferManifestOperationRemote("download", fallback),
+			fallback,
+			tq.WithMissingCallback(func(oid string) {
+				cache.Add(oid)
+
+				mu.Lock()
+				stillMissing = append(stillMissing, oid)
+				mu.Unlock()
+			}),
+		))
+
+		dlwatch := q.Watch()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range dlwatch {
+				cache.Remove(t.Oid)
+				fetched(t.Oid)
+			}
+		}()
+
+		for _, oid := range missing {
+			p, ok := byOid[oid]
+			if !ok {
+				continue
+			}
+			if cache.Contains(oid) {
+				tracerx.Printf("fetch: %s is recorded as missing from %s, skipping", oid, fallback)
+				stillMissing = append(stillMissing, oid)
+				continue
+			}
+			tracerx.Printf("fetch %v [%v] from %v", p.Name, p.Oid, fallback)
+			q.Add(downloadTransfer(p))
+		}
+		q.Wait()
+		wg.Wait()
+		cache.Save()
+
+		for _, err := range q.Errors() {
+			if !isMissingObjectError(err) {
+				errs = append(errs, err)
+			}
+		}
+
+		missing = stillMissing
+		if len(missing) == 0 {
+			break
+		}
+	}
+	return missing, errs
+}
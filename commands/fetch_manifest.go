@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// fetchManifest lists the Git LFS objects to download with "git lfs fetch
+// --manifest", along with the paths at which they are expected in the tree.
+type fetchManifest struct {
+	// Ref is the commit whose tree the manifest was written for, if
+	// any. It is informational only.
+	Ref     string                 `json:"ref,omitempty"`
+	Objects []*fetchManifestObject `json:"objects"`
+}
+
+type fetchManifestObject struct {
+	Path string `json:"path,omitempty"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+func readFetchManifest(filename string, requirePaths bool) (*fetchManifest, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	manifest := &fetchManifest{}
+	if err := json.NewDecoder(f).Decode(manifest); err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool, len(manifest.Objects))
+	for _, o := range manifest.Objects {
+		if !objectOidRE.MatchString(o.Oid) || o.Size < 0 {
+			return nil, fmt.Errorf("invalid object: oid %q, size %d", o.Oid, o.Size)
+		}
+		if len(o.Path) == 0 {
+			if requirePaths {
+				return nil, fmt.Errorf("object %s has no path, which --verify requires", o.Oid)
+			}
+			continue
+		}
+		if paths[o.Path] {
+			return nil, fmt.Errorf("duplicate path %q", o.Path)
+		}
+		paths[o.Path] = true
+	}
+	return manifest, nil
+}
+
+// writeFetchManifest writes a manifest listing every Git LFS file in the tree
+// of the given ref, sorted by path, which "git lfs fetch --manifest --verify"
+// can later check a tree against.
+func writeFetchManifest(filename string, ref *git.Ref) error {
+	manifest := &fetchManifest{
+		Ref:     ref.Sha,
+		Objects: make([]*fetchManifestObject, 0),
+	}
+
+	var multiErr error
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			if multiErr != nil {
+				multiErr = fmt.Errorf("%v\n%v", multiErr, err)
+			} else {
+				multiErr = err
+			}
+			return
+		}
+
+		manifest.Objects = append(manifest.Objects, &fetchManifestObject{
+			Path: p.Name,
+			Oid:  p.Oid,
+			Size: p.Size,
+		})
+	})
+	defer gitscanner.Close()
+
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		return err
+	}
+	if multiErr != nil {
+		return multiErr
+	}
+
+	sort.Slice(manifest.Objects, func(i, j int) bool {
+		return manifest.Objects[i].Path < manifest.Objects[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// fetchFromManifest downloads exactly the objects listed in the given
+// manifest. With verify, the content of each object in the local store is
+// checked against its OID, and the Git LFS files in the tree of the current
+// ref are checked against the paths in the manifest, so that a checkout would
+// produce exactly the files it describes.
+func fetchFromManifest(filename string, verify bool) bool {
+	manifest, err := readFetchManifest(filename, verify)
+	if err != nil {
+		Exit("fatal: could not read manifest %q: %v", filename, err)
+	}
+
+	pointers := make([]*lfs.WrappedPointer, 0, len(manifest.Objects))
+	for _, o := range manifest.Objects {
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Name:    o.Path,
+			Pointer: lfs.NewPointer(o.Oid, o.Size, nil),
+		})
+	}
+
+	Print("fetch: Fetching %d object(s) from manifest %s", len(pointers), filename)
+	if !fetchAndReportToChan(pointers, nil, nil) {
+		return false
+	}
+	if !verify {
+		return true
+	}
+
+	ok := true
+	for _, err := range verifyManifestObjects(manifest) {
+		ok = false
+		Error("  %v", err)
+	}
+	for _, err := range verifyManifestTree(manifest) {
+		ok = false
+		Error("  %v", err)
+	}
+	if !ok {
+		Exit("fatal: verification against manifest %q failed", filename)
+	}
+
+	Print("fetch: Verified %d object(s) against manifest %s", len(manifest.Objects), filename)
+	return true
+}
+
+// verifyManifestObjects hashes each object in the manifest in the local store
+// and returns an error for each whose content doesn't match its OID or size.
+func verifyManifestObjects(manifest *fetchManifest) []error {
+	var errs []error
+	seen := make(map[string]bool, len(manifest.Objects))
+	for _, o := range manifest.Objects {
+		if seen[o.Oid] {
+			continue
+		}
+		seen[o.Oid] = true
+
+		f, err := os.Open(cfg.Filesystem().ObjectPathname(o.Oid))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("object %s: %v", o.Oid, err))
+			continue
+		}
+
+		hr := tools.NewHashingReader(f)
+		n, err := io.Copy(ioutil.Discard, hr)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("object %s: %v", o.Oid, err))
+		} else if n != o.Size || hr.Hash() != o.Oid {
+			errs = append(errs, fmt.Errorf("object %s: content has %d bytes with OID %s", o.Oid, n, hr.Hash()))
+		}
+	}
+	return errs
+}
+
+// verifyManifestTree returns an error for each Git LFS file in the tree of
+// the current ref which is missing from the manifest or has a different
+// object, and each path in the manifest which isn't a Git LFS file in the
+// tree.
+func verifyManifestTree(manifest *fetchManifest) []error {
+	ref, err := git.CurrentRef()
+	if err != nil {
+		return []error{err}
+	}
+
+	tree := make(map[string]*lfs.WrappedPointer)
+	var errs []error
+	gitscanner := lfs.NewGitScanner(cfg, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		tree[p.Name] = p
+	})
+	defer gitscanner.Close()
+
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		return append(errs, err)
+	}
+
+	paths := make(map[string]bool, len(manifest.Objects))
+	for _, o := range manifest.Objects {
+		if len(o.Path) == 0 {
+			continue
+		}
+		paths[o.Path] = true
+
+		p, ok := tree[o.Path]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: not a Git LFS file in %s", o.Path, ref.Name))
+		} else if p.Oid != o.Oid || p.Size != o.Size {
+			errs = append(errs, fmt.Errorf("%s: %s has object %s, manifest has %s", o.Path, ref.Name, p.Oid, o.Oid))
+		}
+	}
+
+	var extra []string
+	for name := range tree {
+		if !paths[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		errs = append(errs, fmt.Errorf("%s: not in manifest", name))
+	}
+	return errs
+}
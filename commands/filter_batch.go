@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+// filterBatchFunc converts the content of the file with the given path and
+// size, read from "from", writing the result to "to".
+type filterBatchFunc func(to io.Writer, from io.Reader, path string, size int64) error
+
+// filterBatch runs "convert" for each file read from STDIN by "git lfs clean
+// --batch" and "git lfs smudge --batch", and writes the results to STDOUT, so
+// that callers can convert many files without running a separate process for
+// each, or speaking the filter process protocol.
+//
+// Each file is read as a header line, "<size> SP <path> LF", followed by
+// exactly <size> bytes of content. Its result is written as "<size> LF"
+// followed by exactly <size> bytes of converted content, or as "error SP
+// <message> LF" if it could not be converted. Results are written in the
+// order the files were read, and each is flushed before the next file is
+// read.
+func filterBatch(convert filterBatchFunc) {
+	tmp, err := ioutil.TempFile(cfg.TempDir(), "filter-batch")
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "could not create temporary file"))
+	}
+	failed := filterBatchLoop(bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout), tmp, convert)
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// filterBatchLoop reads each file from "in", converts it into "tmp", and
+// writes the result to "out", returning whether any file failed to convert.
+func filterBatchLoop(in *bufio.Reader, out *bufio.Writer, tmp *os.File, convert filterBatchFunc) bool {
+	failed := false
+	for {
+		path, size, err := readFilterBatchHeader(in)
+		if err == io.EOF {
+			return failed
+		} else if err != nil {
+			ExitWithError(err)
+		}
+
+		if err := resetFilterBatchFile(tmp); err != nil {
+			ExitWithError(err)
+		}
+
+		content := &io.LimitedReader{R: in, N: size}
+		cerr := convert(tmp, content, path, size)
+
+		// Skip whatever content was left unread, so that the next
+		// header is read from the right place.
+		if _, err := io.Copy(ioutil.Discard, content); err != nil {
+			ExitWithError(errors.Wrap(err, "could not read from STDIN"))
+		}
+		if content.N > 0 {
+			ExitWithError(errors.Errorf("fatal: unexpected end of input in content of %q", path))
+		}
+
+		if cerr != nil {
+			failed = true
+			fmt.Fprintf(out, "error %s\n", strings.Replace(cerr.Error(), "\n", " ", -1))
+		} else if err := writeFilterBatchResult(out, tmp); err != nil {
+			ExitWithError(err)
+		}
+
+		if err := out.Flush(); err != nil {
+			ExitWithError(errors.Wrap(err, "could not write to STDOUT"))
+		}
+	}
+}
+
+// readFilterBatchHeader reads the header line of the next file, returning
+// io.EOF if there are no more files.
+func readFilterBatchHeader(in *bufio.Reader) (string, int64, error) {
+	line, err := in.ReadString('\n')
+	if err == io.EOF && len(line) == 0 {
+		return "", 0, io.EOF
+	} else if err != nil && err != io.EOF {
+		return "", 0, errors.Wrap(err, "could not read from STDIN")
+	}
+
+	fields := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 2)
+	if len(fields) < 2 || len(fields[1]) == 0 {
+		return "", 0, errors.Errorf("fatal: invalid header: %q", line)
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || size < 0 {
+		return "", 0, errors.Errorf("fatal: invalid size in header: %q", line)
+	}
+	return fields[1], size, nil
+}
+
+// resetFilterBatchFile empties the given file, so that it may hold the result
+// of converting the next file.
+func resetFilterBatchFile(tmp *os.File) error {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "could not seek in temporary file")
+	}
+	if err := tmp.Truncate(0); err != nil {
+		return errors.Wrap(err, "could not truncate temporary file")
+	}
+	return nil
+}
+
+// writeFilterBatchResult writes the converted content held in the given file,
+// preceded by its size.
+func writeFilterBatchResult(out io.Writer, tmp *os.File) error {
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.Wrap(err, "could not seek in temporary file")
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "could not seek in temporary file")
+	}
+
+	if _, err := fmt.Fprintf(out, "%d\n", size); err != nil {
+		return errors.Wrap(err, "could not write to STDOUT")
+	}
+	if _, err := io.CopyN(out, tmp, size); err != nil {
+		return errors.Wrap(err, "could not write to STDOUT")
+	}
+	return nil
+}
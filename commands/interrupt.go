@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/git-lfs/git-lfs/v2/tq"
+)
+
+var (
+	// interruptSig is the signal which interrupted the command, if any,
+	// and queues holds the transfer queues which Interrupt() cancels.
+	interruptSig os.Signal
+	queues       []*tq.TransferQueue
+	interruptMu  sync.Mutex
+)
+
+// trackQueue records the given transfer queue, so that its transfers are
+// stopped gracefully if the command is interrupted, and returns it. Callers
+// must call exitIfInterrupted() once the queue has finished.
+func trackQueue(q *tq.TransferQueue) *tq.TransferQueue {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+
+	queues = append(queues, q)
+	return q
+}
+
+// Interrupt cancels the tracked transfer queues when the command receives the
+// given signal, and returns whether any of them were still running. If so,
+// the command exits on its own once their transfers have stopped; otherwise,
+// the caller should exit immediately.
+func Interrupt(sig os.Signal) bool {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+
+	interruptSig = sig
+
+	var running bool
+	for _, q := range queues {
+		if q.Cancel() {
+			running = true
+		}
+	}
+	return running
+}
+
+// exitIfInterrupted prints a summary of the transfers made by the given queue,
+// which has finished, and exits, if the command was interrupted.
+func exitIfInterrupted(q *tq.TransferQueue, dir tq.Direction) {
+	interruptMu.Lock()
+	sig := interruptSig
+	interruptMu.Unlock()
+
+	if sig == nil {
+		return
+	}
+
+	verb := "uploaded"
+	if dir == tq.Download {
+		verb = "downloaded"
+	}
+	Error("\nStopped because of %q signal: %d object(s) %s, %d not %s.", sig, q.Transferred(), verb, q.Canceled(), verb)
+	if dir == tq.Download && q.Canceled() > 0 {
+		Error("Partially downloaded objects will be resumed when fetching again.")
+	}
+
+	Cleanup()
+
+	exitCode := 1
+	if sysSig, ok := sig.(syscall.Signal); ok {
+		exitCode = int(sysSig)
+	}
+	os.Exit(exitCode + 128)
+}
+
+// interrupted returns whether the command has been interrupted, so that no
+// more transfers should be started.
+func interrupted() bool {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	return interruptSig != nil
+}
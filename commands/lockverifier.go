@@ -12,6 +12,7 @@ import (
 	"github.com/git-lfs/git-lfs/v2/lfshttp"
 	"github.com/git-lfs/git-lfs/v2/locking"
 	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/rubyist/tracerx"
 )
 
 type verifyState byte
@@ -142,6 +143,25 @@ func (lv *lockVerifier) Enabled() bool {
 	return lv.verifyState == verifyStateEnabled
 }
 
+// UseCapabilities records whether the server advertised supporting the File
+// Locking API, unless lock verification is already configured for it, so that
+// later pushes verify locks, or skip doing so, without having to ask.
+func (lv *lockVerifier) UseCapabilities(c *tq.Capabilities) {
+	if c == nil || c.Locking == nil || lv.verifyState != verifyStateUnknown {
+		return
+	}
+
+	var err error
+	if *c.Locking {
+		err = enableFor(lv.endpoint.Url)
+	} else {
+		err = disableFor(lv.endpoint.Url)
+	}
+	if err != nil {
+		tracerx.Printf("commands: could not record locking support: %v", err)
+	}
+}
+
 func (lv *lockVerifier) newRefLocks(ref *git.Ref, l locking.Lock) *refLock {
 	return &refLock{
 		allRefs: lv.verifiedRefs,
@@ -0,0 +1,110 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+)
+
+const (
+	maintenanceCrontabBegin = "# BEGIN GIT LFS MAINTENANCE SCHEDULE"
+	maintenanceCrontabEnd   = "# END GIT LFS MAINTENANCE SCHEDULE"
+)
+
+// scheduleMaintenance adds entries to the user's crontab running the given
+// Git LFS executable hourly, daily, and weekly in the registered repositories,
+// replacing any it added before. Each runs at a random minute, so that many
+// users' runs are spread out.
+func scheduleMaintenance(exe string) error {
+	minute := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(60)
+	quoted := "'" + strings.Replace(exe, "'", `'\''`, -1) + "'"
+	run := quoted + " maintenance run --registered --schedule="
+
+	return updateMaintenanceCrontab([]string{
+		maintenanceCrontabBegin,
+		fmt.Sprintf("%d 1-23 * * * %shourly", minute, run),
+		fmt.Sprintf("%d 0 * * 1-6 %sdaily", minute, run),
+		fmt.Sprintf("%d 0 * * 0 %sweekly", minute, run),
+		maintenanceCrontabEnd,
+	})
+}
+
+// unscheduleMaintenance removes the entries added to the user's crontab by
+// scheduleMaintenance.
+func unscheduleMaintenance() error {
+	return updateMaintenanceCrontab(nil)
+}
+
+// updateMaintenanceCrontab replaces the Git LFS maintenance section of the
+// user's crontab with the given lines, keeping the rest.
+func updateMaintenanceCrontab(section []string) error {
+	current, err := currentCrontab()
+	if err != nil {
+		return err
+	}
+
+	cmd := subprocess.ExecCommand("crontab", "-")
+	cmd.Stdin = bytes.NewBufferString(replaceMaintenanceSection(string(current), section))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("crontab: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// currentCrontab returns the user's crontab, or the empty string if they have
+// none yet. Any other failure to read it is an error, so that it is never
+// replaced by one which lacks its entries.
+func currentCrontab() (string, error) {
+	// The output is read as it is, since SimpleExec would trim it.
+	var stderr bytes.Buffer
+	cmd := subprocess.ExecCommand("crontab", "-l")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		return string(out), nil
+	}
+
+	msg := strings.TrimSpace(stderr.String())
+	if _, ok := err.(*exec.ExitError); ok && strings.HasPrefix(strings.ToLower(msg), "no crontab for") {
+		return "", nil
+	}
+	return "", fmt.Errorf("crontab -l: %v: %s", err, msg)
+}
+
+// replaceMaintenanceSection returns the given crontab with its Git LFS
+// maintenance section replaced by the given lines, which are appended if it
+// has none. The lines outside the section are kept exactly as they are.
+func replaceMaintenanceSection(crontab string, section []string) string {
+	current := strings.Split(crontab, "\n")
+	if len(current) > 0 && len(current[len(current)-1]) == 0 {
+		// The crontab's final newline leaves an empty last line.
+		current = current[:len(current)-1]
+	}
+
+	var lines []string
+	inSection := false
+	for _, line := range current {
+		switch {
+		case line == maintenanceCrontabBegin:
+			inSection = true
+		case line == maintenanceCrontabEnd:
+			inSection = false
+		case !inSection:
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, section...)
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,60 @@
+//go:build windows
+// +build windows
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+)
+
+// maintenanceTaskName returns the name of the scheduled task running Git LFS
+// maintenance at the given schedule.
+func maintenanceTaskName(schedule string) string {
+	return fmt.Sprintf("Git LFS Maintenance (%s)", schedule)
+}
+
+// scheduleMaintenance creates scheduled tasks running the given Git LFS
+// executable hourly, daily, and weekly in the registered repositories,
+// replacing any it created before.
+func scheduleMaintenance(exe string) error {
+	run := fmt.Sprintf("\"%s\" maintenance run --registered --schedule=", exe)
+	schedules := [][]string{
+		{"hourly", "/sc", "hourly"},
+		{"daily", "/sc", "daily", "/st", "00:00"},
+		{"weekly", "/sc", "weekly", "/d", "SUN", "/st", "00:00"},
+	}
+
+	for _, s := range schedules {
+		args := append([]string{"/create", "/f", "/tn", maintenanceTaskName(s[0]), "/tr", run + s[0]}, s[1:]...)
+		if err := runSchtasks(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unscheduleMaintenance deletes the scheduled tasks created by
+// scheduleMaintenance.
+func unscheduleMaintenance() error {
+	for _, schedule := range []string{"hourly", "daily", "weekly"} {
+		if _, err := subprocess.SimpleExec("schtasks", "/query", "/tn", maintenanceTaskName(schedule)); err != nil {
+			// The task does not exist.
+			continue
+		}
+		if err := runSchtasks("/delete", "/f", "/tn", maintenanceTaskName(schedule)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runSchtasks(args ...string) error {
+	out, err := subprocess.ExecCommand("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/tasklog"
+	"github.com/git-lfs/gitobj/v2"
+)
+
+const (
+	// signedCommitsKeep keeps the signatures of rewritten commits as they
+	// were, although they are no longer valid.
+	signedCommitsKeep = "keep"
+	// signedCommitsStrip removes the signatures of rewritten commits.
+	signedCommitsStrip = "strip"
+	// signedCommitsResign replaces the signatures of rewritten commits
+	// with ones made with the local signing key.
+	signedCommitsResign = "resign"
+)
+
+// signatureHeaders are the headers of a commit which hold its signatures, one
+// for each object format.
+var signatureHeaders = map[string]bool{
+	"gpgsig":        true,
+	"gpgsig-sha256": true,
+}
+
+// signedCommits handles the signatures of the commits which a migration
+// rewrites, according to the --signed-commits mode, and keeps track of the
+// commits it handled so that they can be reported.
+type signedCommits struct {
+	mode   string
+	signer *git.Signer
+
+	// rows are the original ID and the subject of each signed commit
+	// which was rewritten.
+	rows [][]string
+}
+
+// newSignedCommits returns a signedCommits for the given --signed-commits mode.
+func newSignedCommits(mode string) (*signedCommits, error) {
+	switch mode {
+	case signedCommitsKeep, signedCommitsStrip, signedCommitsResign:
+		return &signedCommits{mode: mode}, nil
+	}
+	return nil, errors.Errorf("fatal: unknown --signed-commits mode %q, expected %s, %s, or %s",
+		mode, signedCommitsKeep, signedCommitsStrip, signedCommitsResign)
+}
+
+// rewrite is a githistory.CommitCallbackFn which keeps, removes, or replaces
+// the signatures of the given rewritten commit.
+func (s *signedCommits) rewrite(oid []byte, original, rewritten *gitobj.Commit) (*gitobj.Commit, error) {
+	var unsigned []*gitobj.ExtraHeader
+	var keys []string
+	for _, hdr := range rewritten.ExtraHeaders {
+		if signatureHeaders[hdr.K] {
+			keys = append(keys, hdr.K)
+		} else {
+			unsigned = append(unsigned, hdr)
+		}
+	}
+	if len(keys) == 0 {
+		return rewritten, nil
+	}
+
+	subject := strings.SplitN(strings.TrimSpace(original.Message), "\n", 2)[0]
+	s.rows = append(s.rows, []string{hex.EncodeToString(oid), subject})
+
+	if s.mode == signedCommitsKeep {
+		return rewritten, nil
+	}
+
+	// Copy the commit, rather than changing it, since it shares its
+	// headers with the original.
+	commit := *rewritten
+	commit.ExtraHeaders = unsigned
+	if s.mode == signedCommitsStrip {
+		return &commit, nil
+	}
+
+	if s.signer == nil {
+		name, email := cfg.CurrentCommitter()
+		signer, err := git.NewSigner(cfg.GitEnv(), fmt.Sprintf("%s <%s>", name, email))
+		if err != nil {
+			return nil, errors.Wrap(err, "fatal: cannot re-sign commits")
+		}
+		s.signer = signer
+	}
+
+	var payload bytes.Buffer
+	if _, err := commit.Encode(&payload); err != nil {
+		return nil, err
+	}
+	sig, err := s.signer.Sign(payload.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "fatal: cannot re-sign commit %x", oid)
+	}
+
+	for _, key := range keys {
+		commit.ExtraHeaders = append(commit.ExtraHeaders, &gitobj.ExtraHeader{
+			K: key,
+			V: strings.TrimSuffix(string(sig), "\n"),
+		})
+	}
+	return &commit, nil
+}
+
+// report warns about the signed commits which were rewritten, and what became
+// of their signatures.
+func (s *signedCommits) report(l *tasklog.Logger) {
+	if len(s.rows) == 0 {
+		return
+	}
+
+	var summary string
+	switch s.mode {
+	case signedCommitsKeep:
+		summary = fmt.Sprintf("migrate: warning: the signatures of %d rewritten commit(s) are no longer valid; use --signed-commits=strip or --signed-commits=resign", len(s.rows))
+	case signedCommitsStrip:
+		summary = fmt.Sprintf("migrate: warning: removed the signatures of %d rewritten commit(s)", len(s.rows))
+	case signedCommitsResign:
+		summary = fmt.Sprintf("migrate: re-signed %d rewritten commit(s) with your signing key", len(s.rows))
+	}
+
+	// Let the logger finish writing the progress of the rewrite before
+	// showing the report.
+	l.Simple().Complete()
+
+	p := &preview{
+		summary: summary,
+		columns: []string{"COMMIT", "SUBJECT"},
+		rows:    s.rows,
+	}
+	p.render(os.Stderr, migrateVerbose)
+}
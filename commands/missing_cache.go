@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// missingCache records the OIDs of the objects which a Git LFS server reported
+// not having, and when, so that fetches within the configured time need not
+// ask it about them again. Each server has its own cache in ".git/lfs/missing",
+// named by the SHA-256 hash of its URL, with one OID and Unix time per line.
+type missingCache struct {
+	path    string
+	ttl     time.Duration
+	oids    map[string]time.Time
+	changed bool
+	mu      sync.Mutex
+}
+
+// newMissingCache loads the cache for the server at the given URL, dropping
+// any entries older than `lfs.fetchmissinghours`. A nil cache, which records
+// nothing, is returned if the URL is empty or the setting is zero, as it is by
+// default.
+func newMissingCache(url string) *missingCache {
+	hours := cfg.Git.Int("lfs.fetchmissinghours", 0)
+	if len(url) == 0 || hours <= 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	c := &missingCache{
+		path: filepath.Join(cfg.LFSStorageDir(), "missing", hex.EncodeToString(sum[:])),
+		ttl:  time.Duration(hours) * time.Hour,
+		oids: make(map[string]time.Time),
+	}
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+
+	since := time.Now().Add(-c.ttl)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			c.changed = true
+			continue
+		}
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			c.changed = true
+			continue
+		}
+		if at := time.Unix(secs, 0); at.After(since) {
+			c.oids[fields[0]] = at
+		} else {
+			c.changed = true
+		}
+	}
+	return c
+}
+
+// Contains returns whether the server recently reported not having the object
+// with the given OID.
+func (c *missingCache) Contains(oid string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.oids[oid]
+	return ok
+}
+
+// Add records that the server reported not having the object with the given
+// OID.
+func (c *missingCache) Add(oid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.oids[oid] = time.Now()
+	c.changed = true
+}
+
+// Remove forgets that the server reported not having the object with the given
+// OID, once it has been fetched.
+func (c *missingCache) Remove(oid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.oids[oid]; ok {
+		delete(c.oids, oid)
+		c.changed = true
+	}
+}
+
+// Save writes the cache back out, if it has changed. Failures are only traced,
+// since they only mean the server is asked again next time.
+func (c *missingCache) Save() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.changed {
+		return
+	}
+	if err := c.write(); err != nil {
+		tracerx.Printf("missing object cache: %v", err)
+		return
+	}
+	c.changed = false
+}
+
+func (c *missingCache) write() error {
+	if len(c.oids) == 0 {
+		if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := tools.MkdirAll(dir, cfg); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent fetches never
+	// see a partial cache.
+	tmp, err := tools.TempFile(dir, "missing", cfg)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for oid, at := range c.oids {
+		fmt.Fprintf(w, "%s %d\n", oid, at.Unix())
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return tools.RobustRename(tmp.Name(), c.path)
+}
@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// objectArchivePrefix is the directory within an object archive holding the
+// Git LFS objects, which are laid out as they are in the local store.
+const objectArchivePrefix = "objects"
+
+// objectOidRE matches the OID of a Git LFS object.
+var objectOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+var objectArchiveNameRE = regexp.MustCompile(`\Aobjects/([0-9a-f]{2})/([0-9a-f]{2})/([0-9a-f]{64})\z`)
+
+// objectArchiveCompression is how an object archive is compressed.
+type objectArchiveCompression int
+
+const (
+	archiveUncompressed objectArchiveCompression = iota
+	archiveGzip
+	// archiveZstd compresses with the zstd program, which must be
+	// installed, since Go has no zstd package of its own.
+	archiveZstd
+)
+
+// zstdMagic begins each zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// objectArchiveCompressionFor returns how an object archive written to the
+// given file should be compressed, based on its extension. Compression
+// formats which aren't supported are reported as an error.
+func objectArchiveCompressionFor(filename string) (objectArchiveCompression, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveGzip, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tar.zstd"),
+		strings.HasSuffix(lower, ".tzst"):
+		return archiveZstd, nil
+	case strings.HasSuffix(lower, ".xz"), strings.HasSuffix(lower, ".bz2"):
+		return archiveUncompressed, fmt.Errorf("unsupported compression for %q: write an uncompressed archive to stdout with \"-\" and pipe it to a compressor instead", filename)
+	}
+	return archiveUncompressed, nil
+}
+
+// objectArchiveWriter writes Git LFS objects from the local store into a tar
+// archive, optionally compressed with gzip or zstd.
+type objectArchiveWriter struct {
+	tw *tar.Writer
+	// compressor is the gzip writer or the input of the zstd program, if
+	// the archive is compressed.
+	compressor io.WriteCloser
+	zstd       *subprocess.Cmd
+
+	// Count and Size are the number and total size of the objects
+	// written.
+	Count int
+	Size  int64
+}
+
+func newObjectArchiveWriter(w io.Writer, compression objectArchiveCompression) (*objectArchiveWriter, error) {
+	aw := &objectArchiveWriter{}
+	switch compression {
+	case archiveGzip:
+		aw.compressor = gzip.NewWriter(w)
+		w = aw.compressor
+	case archiveZstd:
+		aw.zstd = subprocess.ExecCommand("zstd", "-q", "-c")
+		aw.zstd.Stdout = w
+		aw.zstd.Stderr = os.Stderr
+		stdin, err := aw.zstd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := aw.zstd.Start(); err != nil {
+			return nil, errors.Wrap(err, "could not run zstd")
+		}
+		aw.compressor = stdin
+		w = stdin
+	}
+	aw.tw = tar.NewWriter(w)
+	return aw, nil
+}
+
+// AddObject writes the object with the given OID and size from the local
+// store to the archive.
+func (w *objectArchiveWriter) AddObject(oid string, size int64) error {
+	f, err := os.Open(cfg.Filesystem().ObjectPathname(oid))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := path.Join(objectArchivePrefix, oid[0:2], oid[2:4], oid)
+	if err := w.addEntry(name, size, f); err != nil {
+		return errors.Wrapf(err, "could not add object %s", oid)
+	}
+
+	w.Count++
+	w.Size += size
+	return nil
+}
+
+// AddFile writes a file with the given name and contents to the archive,
+// outside of the objects directory.
+func (w *objectArchiveWriter) AddFile(name string, data []byte) error {
+	return w.addEntry(name, int64(len(data)), bytes.NewReader(data))
+}
+
+func (w *objectArchiveWriter) addEntry(name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w.tw, r, size)
+	return err
+}
+
+func (w *objectArchiveWriter) Close() error {
+	err := w.tw.Close()
+	if w.compressor != nil {
+		if cerr := w.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if w.zstd != nil {
+		if werr := w.zstd.Wait(); err == nil && werr != nil {
+			err = errors.Wrap(werr, "zstd")
+		}
+	}
+	return err
+}
+
+// objectArchiveReader reads an object archive, which may be compressed with
+// gzip or zstd, importing the objects it contains into the local store.
+type objectArchiveReader struct {
+	tr   *tar.Reader
+	zstd *zstdReader
+
+	// Imported is the number of objects added to the local store, and
+	// Present the number which were already there.
+	Imported int
+	Present  int
+}
+
+func newObjectArchiveReader(r io.Reader) (*objectArchiveReader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &objectArchiveReader{tr: tar.NewReader(gz)}, nil
+	case bytes.Equal(magic, zstdMagic):
+		cmd := subprocess.ExecCommand("zstd", "-q", "-d", "-c")
+		cmd.Stdin = br
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, errors.Wrap(err, "could not run zstd")
+		}
+		zr := &zstdReader{ReadCloser: stdout, cmd: cmd}
+		return &objectArchiveReader{tr: tar.NewReader(zr), zstd: zr}, nil
+	}
+	return &objectArchiveReader{tr: tar.NewReader(br)}, nil
+}
+
+// Close stops decompressing the archive, if it is compressed with zstd and has
+// not been read to the end.
+func (r *objectArchiveReader) Close() error {
+	if r == nil || r.zstd == nil || r.zstd.cmd.ProcessState != nil {
+		return nil
+	}
+	r.zstd.cmd.Process.Kill()
+	r.zstd.cmd.Wait()
+	return nil
+}
+
+// zstdReader reads the output of the zstd program, reporting its failure, such
+// as for a corrupt archive, at the end of the output.
+type zstdReader struct {
+	io.ReadCloser
+	cmd *subprocess.Cmd
+	err error
+}
+
+func (r *zstdReader) Read(b []byte) (int, error) {
+	if r.cmd.ProcessState != nil {
+		// The output was read to the end, and the pipe closed.
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+
+	n, err := r.ReadCloser.Read(b)
+	if err == io.EOF {
+		if werr := r.cmd.Wait(); werr != nil {
+			r.err = errors.Wrap(werr, "zstd")
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// Next imports objects from the archive until it reaches a file outside of
+// the objects directory, and returns its header, or io.EOF at the end of the
+// archive. The contents of the file may be read from the reader until Next is
+// called again.
+func (r *objectArchiveReader) Next() (*tar.Header, error) {
+	for {
+		hdr, err := r.tr.Next()
+		if err == io.EOF && r.zstd != nil {
+			// The end of the archive may come before the end of
+			// the compressed stream, where zstd reports whether
+			// it was intact.
+			if _, derr := io.Copy(ioutil.Discard, r.zstd); derr != nil {
+				return nil, derr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if !strings.HasPrefix(hdr.Name, objectArchivePrefix+"/") {
+			return hdr, nil
+		}
+
+		m := objectArchiveNameRE.FindStringSubmatch(hdr.Name)
+		if m == nil || hdr.Typeflag != tar.TypeReg || m[3][0:2] != m[1] || m[3][2:4] != m[2] {
+			return nil, fmt.Errorf("unexpected entry in archive: %q", hdr.Name)
+		}
+
+		if err := r.importObject(m[3], hdr.Size); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *objectArchiveReader) Read(b []byte) (int, error) {
+	return r.tr.Read(b)
+}
+
+// importObject copies the object with the given OID from the archive into the
+// local store, verifying its contents, unless it is present already.
+func (r *objectArchiveReader) importObject(oid string, size int64) error {
+	if cfg.LFSObjectExists(oid, size) {
+		r.Present++
+		return nil
+	}
+
+	tmp, err := lfs.TempFile(cfg, "archive")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hr := tools.NewHashingReader(r.tr)
+	n, err := io.Copy(tmp, hr)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "could not read object %s", oid)
+	}
+	if n != size || hr.Hash() != oid {
+		return fmt.Errorf("corrupt object %s in archive: expected %d bytes, got %d with OID %s", oid, size, n, hr.Hash())
+	}
+
+	dest, err := cfg.Filesystem().ObjectPath(oid)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return errors.Wrapf(err, "could not move object %s into %s", oid, filepath.Dir(dest))
+	}
+
+	r.Imported++
+	return nil
+}
+
+// openObjectArchiveInput opens the named object archive for reading, or
+// standard input if the name is empty or "-".
+func openObjectArchiveInput(name string) (io.ReadCloser, error) {
+	if len(name) == 0 || name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(name)
+}
+
+// createObjectArchiveOutput creates the named object archive for writing, or
+// returns standard output if the name is "-", along with how the archive
+// should be compressed.
+func createObjectArchiveOutput(name string) (io.WriteCloser, objectArchiveCompression, error) {
+	if name == "-" {
+		return os.Stdout, archiveUncompressed, nil
+	}
+
+	compression, err := objectArchiveCompressionFor(name)
+	if err != nil {
+		return nil, archiveUncompressed, err
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, archiveUncompressed, err
+	}
+	return f, compression, nil
+}
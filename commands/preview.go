@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	isatty "github.com/mattn/go-isatty"
+)
+
+const (
+	// previewMaxRows is the number of rows of a preview which are shown
+	// unless all of them are asked for. The rest are only counted.
+	previewMaxRows = 20
+)
+
+// preview describes the objects, commits, or refs which a destructive command
+// would change, so that they can be shown, and the change confirmed, before
+// anything is changed.
+type preview struct {
+	// summary describes the change as a whole, such as "prune: 2 file(s)
+	// would be pruned (1.2 KB)".
+	summary string
+	// question asks whether to go ahead with the change.
+	question string
+
+	// columns are the headings of the table of affected items, and rows
+	// the items themselves.
+	columns []string
+	rows    [][]string
+}
+
+// add adds a row to the table of affected items.
+func (p *preview) add(cells ...string) {
+	p.rows = append(p.rows, cells)
+}
+
+// render writes the summary and the table of affected items to w, with its
+// columns aligned. Unless all is true, only the first previewMaxRows rows are
+// listed.
+func (p *preview) render(w io.Writer, all bool) {
+	fmt.Fprintln(w, p.summary)
+	if len(p.rows) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "  %s\n", strings.Join(p.columns, "\t"))
+	for i, row := range p.rows {
+		if i == previewMaxRows && !all {
+			break
+		}
+		fmt.Fprintf(tw, "  %s\n", strings.Join(row, "\t"))
+	}
+	tw.Flush()
+
+	if n := len(p.rows) - previewMaxRows; n > 0 && !all {
+		fmt.Fprintf(w, "  ... and %d more\n", n)
+	}
+}
+
+// confirm returns whether to go ahead with the change. With dryRun, it renders
+// the preview to out and returns false. Otherwise, it returns true if yes is
+// given, or if "in" is not a terminal, so that scripts go ahead without being
+// asked, and renders the preview and asks the question if not.
+func (p *preview) confirm(in io.Reader, out io.Writer, dryRun, yes, all bool) bool {
+	if dryRun {
+		p.render(out, all)
+		return false
+	}
+	if yes || !isTerminal(in) {
+		return true
+	}
+
+	p.render(out, all)
+	return askYesNo(in, out, p.question)
+}
+
+// askYesNo writes the given question to out, and reads answers from in until
+// it is given "y" or "n". Anything else asks again, except that no answer at
+// all is taken to be "n".
+func askYesNo(in io.Reader, out io.Writer, question string) bool {
+	answer := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "%s [y/N] ", question)
+		s, err := answer.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return false
+			}
+			ExitWithError(errors.Wrap(err,
+				"fatal: could not read answer"))
+		}
+
+		switch strings.TrimSpace(s) {
+		case "n", "N", "":
+			return false
+		case "y", "Y":
+			return true
+		}
+
+		if !strings.HasSuffix(s, "\n") {
+			fmt.Fprintf(out, "\n")
+		}
+	}
+}
+
+// isTerminal returns whether the given reader is a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/git-lfs/git-lfs/v2/config"
@@ -36,6 +38,8 @@ func newSingleCheckout(gitEnv config.Environment, remote string) abstractCheckou
 		gitIndexer:    &gitIndexer{},
 		pathConverter: pathConverter,
 		manifest:      manifest,
+		root:          cfg.LocalWorkingDir(),
+		symlinkPolicy: cfg.SymlinkPolicy(),
 	}
 }
 
@@ -43,7 +47,10 @@ type abstractCheckout interface {
 	Manifest() *tq.Manifest
 	Skip() bool
 	Run(*lfs.WrappedPointer)
+	RunPlaceholder(*lfs.WrappedPointer)
 	RunToPath(*lfs.WrappedPointer, string) error
+	Skipped() int
+	Failed() int
 	Close()
 }
 
@@ -51,6 +58,20 @@ type singleCheckout struct {
 	gitIndexer    *gitIndexer
 	pathConverter lfs.PathConverter
 	manifest      *tq.Manifest
+	root          string
+	symlinkPolicy string
+
+	// skipped is the number of files which were not checked out because
+	// their content was not present locally, and failed the number which
+	// lfs.symlinkpolicy prevented from being checked out as an error.
+	skipped int
+	failed  int
+	mu      sync.Mutex
+
+	// placeholders are the paths, relative to the current directory, of
+	// the files to have placeholders written by the smudge filter, since
+	// their objects cannot be downloaded.
+	placeholders []string
 }
 
 func (c *singleCheckout) Manifest() *tq.Manifest {
@@ -63,39 +84,165 @@ func (c *singleCheckout) Skip() bool {
 
 func (c *singleCheckout) Run(p *lfs.WrappedPointer) {
 	cwdfilepath := c.pathConverter.Convert(p.Name)
+	if !c.canWrite(p, cwdfilepath, false) {
+		return
+	}
+
+	if err := c.RunToPath(p, cwdfilepath); err != nil {
+		if errors.IsDownloadDeclinedError(err) {
+			// acceptable error, data not local (fetch not run or include/exclude)
+			Error("Skipped checkout for %q, content not local. Use fetch to download.", p.Name)
+			c.mu.Lock()
+			c.skipped++
+			c.mu.Unlock()
+		} else {
+			FullError(fmt.Errorf("could not check out %q", p.Name))
+		}
+		return
+	}
+
+	// errors are only returned when the gitIndexer is starting a new cmd
+	if err := c.gitIndexer.Add(cwdfilepath); err != nil {
+		Panic(err, "Could not update the index")
+	}
+}
+
+// RunPlaceholder has the smudge filter write a placeholder for the given
+// pointer, whose object cannot be downloaded, into the working tree, as
+// lfs.missingcontent asks, once the other files are checked out. Having Git
+// check the file out keeps its index entry up to date without the placeholder
+// being cleaned as new content.
+func (c *singleCheckout) RunPlaceholder(p *lfs.WrappedPointer) {
+	cwdfilepath := c.pathConverter.Convert(p.Name)
+	if !c.canWrite(p, cwdfilepath, true) {
+		return
+	}
+
+	c.mu.Lock()
+	c.placeholders = append(c.placeholders, cwdfilepath)
+	c.mu.Unlock()
+}
+
+// canWrite returns whether the working tree file for the given pointer, at
+// the given path relative to the current directory, may be written: whether
+// it is missing, or still the pointer, or a placeholder to be replaced with
+// the object's content. Files which have other content are left alone.
+func (c *singleCheckout) canWrite(p *lfs.WrappedPointer, cwdfilepath string, placeholder bool) bool {
+	if err := c.checkPath(p.Name); err != nil {
+		if c.symlinkPolicy == config.SymlinkPolicyError {
+			Error("Could not check out %q: %v", p.Name, err)
+			c.mu.Lock()
+			c.failed++
+			c.mu.Unlock()
+		} else {
+			Error("warning: skipped checkout for %q: %v", p.Name, err)
+		}
+		return false
+	}
 
 	// Check the content - either missing or still this pointer (not exist is ok)
 	filepointer, err := lfs.DecodePointerFromFile(cwdfilepath)
 	if err != nil && !os.IsNotExist(err) {
 		if errors.IsNotAPointerError(err) || errors.IsBadPointerKeyError(err) {
-			// File has non-pointer content, leave it alone
-			return
+			// File has non-pointer content, leave it alone,
+			// unless it is a placeholder for the content.
+			return !placeholder && lfs.IsPlaceholderFile(cwdfilepath, p.Pointer)
 		}
 
 		LoggedError(err, "Checkout error: %s", err)
-		return
+		return false
 	}
 
 	if filepointer != nil && filepointer.Oid != p.Oid {
 		// User has probably manually reset a file to another commit
 		// while leaving it a pointer; don't mess with this
-		return
+		return false
 	}
+	return true
+}
 
-	if err := c.RunToPath(p, cwdfilepath); err != nil {
-		if errors.IsDownloadDeclinedError(err) {
-			// acceptable error, data not local (fetch not run or include/exclude)
-			Error("Skipped checkout for %q, content not local. Use fetch to download.", p.Name)
-		} else {
-			FullError(fmt.Errorf("could not check out %q", p.Name))
+// checkPath returns an error if the file with the given repository-relative
+// name should not be written into the working tree, because it is not a
+// regular file, or because it or one of its parent directories is a symbolic
+// link, which lfs.symlinkpolicy doesn't allow writing through.  This keeps
+// content from being written to unexpected places, such as outside of the
+// working tree.
+func (c *singleCheckout) checkPath(name string) error {
+	if len(c.root) == 0 {
+		return nil
+	}
+
+	path := c.root
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		path = filepath.Join(path, part)
+
+		fi, err := os.Lstat(path)
+		if err != nil {
+			// Missing directories are created, and other errors
+			// are reported when the file is written.
+			return nil
 		}
-		return
+
+		last := i == len(parts)-1
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if c.symlinkPolicy == config.SymlinkPolicyFollow && resolvesWithin(c.root, path) {
+				continue
+			}
+			if last {
+				return fmt.Errorf("it is a symbolic link")
+			}
+			return fmt.Errorf("it is beyond a symbolic link")
+		}
+		if last && !fi.Mode().IsRegular() {
+			return fmt.Errorf("it is not a regular file")
+		}
+	}
+	return nil
+}
+
+// resolvesWithin returns whether the given path, once any symbolic links are
+// resolved, is within the working tree at root, excluding the ".git" directory.
+// The path itself need not exist, as long as the directory it would be in does.
+func resolvesWithin(root, path string) bool {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
 	}
 
-	// errors are only returned when the gitIndexer is starting a new cmd
-	if err := c.gitIndexer.Add(cwdfilepath); err != nil {
-		Panic(err, "Could not update the index")
+	// Follow dangling links until reaching a path which doesn't exist, or
+	// give up as Linux does after 40 links.
+	target := path
+	for i := 0; ; i++ {
+		fi, err := os.Lstat(target)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			break
+		}
+		if i == 40 {
+			return false
+		}
+
+		link, err := os.Readlink(target)
+		if err != nil {
+			return false
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(target), link)
+		}
+		target = link
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(target)))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
 	}
+	rel = filepath.ToSlash(rel)
+	return rel != ".git" && !strings.HasPrefix(rel, ".git/")
 }
 
 // RunToPath checks out the pointer specified by p to the given path.  It does
@@ -105,10 +252,98 @@ func (c *singleCheckout) RunToPath(p *lfs.WrappedPointer, path string) error {
 	return gitfilter.SmudgeToFile(path, p.Pointer, false, c.manifest, nil)
 }
 
+// Skipped returns the number of files which were not checked out because their
+// content was not present locally.
+func (c *singleCheckout) Skipped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skipped
+}
+
+// Failed returns the number of files which were not checked out because
+// lfs.symlinkpolicy is "error" and they are at or beyond a symbolic link, or
+// are not regular files.
+func (c *singleCheckout) Failed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failed
+}
+
 func (c *singleCheckout) Close() {
 	if err := c.gitIndexer.Close(); err != nil {
 		LoggedError(err, "Error updating the git index:\n%s", c.gitIndexer.Output())
 	}
+
+	if len(c.placeholders) == 0 {
+		return
+	}
+
+	// Git doesn't check out files whose index entries are up to date, as
+	// those of pointers left in the working tree are.
+	for _, path := range c.placeholders {
+		os.Remove(path)
+	}
+
+	var output bytes.Buffer
+	cmd := git.CheckoutIndexFromStdin()
+	cmd.Stdin = strings.NewReader(strings.Join(c.placeholders, "\n") + "\n")
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		LoggedError(err, "Error writing placeholders:\n%s", output.String())
+	}
+}
+
+// parallelCheckout checks out files with a number of concurrent jobs, each of
+// which copies objects into the working tree with the given checkout, so that
+// writing files is not limited to a single thread.  Run only queues a file, so
+// Skipped may only be called after Close.
+type parallelCheckout struct {
+	abstractCheckout
+
+	// done, if not nil, is called by a job after each file is checked
+	// out.
+	done func(*lfs.WrappedPointer)
+
+	ch chan *lfs.WrappedPointer
+	wg sync.WaitGroup
+}
+
+func newParallelCheckout(c abstractCheckout, jobs int, done func(*lfs.WrappedPointer)) abstractCheckout {
+	pc := &parallelCheckout{
+		abstractCheckout: c,
+		done:             done,
+		ch:               make(chan *lfs.WrappedPointer, jobs),
+	}
+
+	pc.wg.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go pc.work()
+	}
+	return pc
+}
+
+func (c *parallelCheckout) work() {
+	defer c.wg.Done()
+
+	for p := range c.ch {
+		c.abstractCheckout.Run(p)
+		if c.done != nil {
+			c.done(p)
+		}
+	}
+}
+
+func (c *parallelCheckout) Run(p *lfs.WrappedPointer) {
+	c.ch <- p
+}
+
+// Close waits for the queued files to be checked out before closing the
+// underlying checkout.
+func (c *parallelCheckout) Close() {
+	close(c.ch)
+	c.wg.Wait()
+	c.abstractCheckout.Close()
 }
 
 type noOpCheckout struct {
@@ -127,8 +362,11 @@ func (c *noOpCheckout) RunToPath(p *lfs.WrappedPointer, path string) error {
 	return nil
 }
 
-func (c *noOpCheckout) Run(p *lfs.WrappedPointer) {}
-func (c *noOpCheckout) Close()                    {}
+func (c *noOpCheckout) Run(p *lfs.WrappedPointer)            {}
+func (c *noOpCheckout) RunPlaceholder(p *lfs.WrappedPointer) {}
+func (c *noOpCheckout) Skipped() int                         { return 0 }
+func (c *noOpCheckout) Failed() int                          { return 0 }
+func (c *noOpCheckout) Close()                               {}
 
 // Don't fire up the update-index command until we have at least one file to
 // give it. Otherwise git interprets the lack of arguments to mean param-less update-index
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// pushJournal records the OIDs of the objects known to be on a Git LFS server,
+// either because they were uploaded or because the server reported having
+// them, so that later runs of `git lfs push --all` need not ask about them
+// again. Each server has its own journal in ".git/lfs/pushed", named by the
+// SHA-256 hash of its URL, with one OID per line.
+type pushJournal struct {
+	path string
+	oids tools.StringSet
+	f    *os.File
+	mu   sync.Mutex
+}
+
+// newPushJournal loads the journal for the server at the given URL. A nil
+// journal, which records nothing, is returned if the URL is empty.
+func newPushJournal(url string) *pushJournal {
+	if len(url) == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	j := &pushJournal{
+		path: filepath.Join(cfg.LFSStorageDir(), "pushed", hex.EncodeToString(sum[:])),
+		oids: tools.NewStringSet(),
+	}
+
+	f, err := os.Open(j.path)
+	if err != nil {
+		return j
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Skip any partially-written line.
+		if oid := scanner.Text(); len(oid) == 64 {
+			j.oids.Add(oid)
+		}
+	}
+	return j
+}
+
+// Contains returns whether the object with the given OID is known to be on
+// the server.
+func (j *pushJournal) Contains(oid string) bool {
+	if j == nil {
+		return false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.oids.Contains(oid)
+}
+
+// Add records that the object with the given OID is on the server. Failures
+// are only traced, since they only mean the server is asked again next time.
+func (j *pushJournal) Add(oid string) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.oids.Add(oid) {
+		return
+	}
+	if j.f == nil {
+		if err := tools.MkdirAll(filepath.Dir(j.path), cfg); err != nil {
+			tracerx.Printf("push journal: %v", err)
+			return
+		}
+		f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			tracerx.Printf("push journal: %v", err)
+			return
+		}
+		j.f = f
+	}
+	if _, err := fmt.Fprintln(j.f, oid); err != nil {
+		tracerx.Printf("push journal: %v", err)
+	}
+}
+
+// Close closes the journal's file, if it was written to.
+func (j *pushJournal) Close() {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f != nil {
+		j.f.Close()
+		j.f = nil
+	}
+}
+
+// compactPushJournals rewrites each push journal with a single line for each
+// OID it records, dropping duplicate lines written by pushes which ran at the
+// same time and lines left partially written, and returns the number of lines
+// dropped. OIDs appended by a push while its journal is rewritten may be lost,
+// which only means the server is asked about them again.
+func compactPushJournals() (int, error) {
+	paths, err := filepath.Glob(filepath.Join(cfg.LFSStorageDir(), "pushed", "*"))
+	if err != nil {
+		return 0, err
+	}
+
+	var dropped int
+	for _, path := range paths {
+		n, err := compactPushJournal(path)
+		if err != nil {
+			return dropped, err
+		}
+		dropped += n
+	}
+	return dropped, nil
+}
+
+func compactPushJournal(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var oids []string
+	seen := tools.NewStringSet()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		if oid := scanner.Text(); len(oid) == 64 && seen.Add(oid) {
+			oids = append(oids, oid)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if len(oids) == lines {
+		return 0, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, oid := range oids {
+		fmt.Fprintln(w, oid)
+	}
+	err = w.Flush()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return lines - len(oids), nil
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/rubyist/tracerx"
+)
+
+// verifyBatchSize is the number of objects checked for in each batch request
+// when verifying a push.
+const verifyBatchSize = 100
+
+// isVerifyOnly returns whether objects pushed to the given remote ref should
+// only be verified to exist on the server, rather than uploaded, as
+// configured by lfs.pushverifyonly. Patterns may match either the short name
+// of the ref or its full name.
+func (c *uploadContext) isVerifyOnly(ref *git.Ref) bool {
+	if c.DryRun || ref == nil {
+		return false
+	}
+
+	for _, pattern := range c.verifyOnlyRefs {
+		for _, name := range []string{ref.Name, ref.Refspec()} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyLeftOrAll verifies locks for the objects that would be uploaded for
+// the given update, and asks the server whether it already has each of them,
+// without uploading anything. Objects which the server doesn't have are
+// reported by ReportErrors.
+func verifyLeftOrAll(g *lfs.GitScanner, ctx *uploadContext, bases []string, update *git.RefUpdate, pushAll bool) error {
+	tracerx.Printf("pre-push: verifying objects for %s without uploading", update.Right().Refspec())
+
+	var transfers []*tq.Transfer
+	names := make(map[string]string)
+	seen := tools.NewStringSet()
+
+	cb := func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ctx.addScannerError(err)
+			return
+		}
+
+		ctx.lockVerifier.LockedByThem(p.Name)
+		ctx.lockVerifier.LockedByUs(p.Name)
+
+		if seen.Contains(p.Oid) || p.Size == 0 || ctx.isPushed(p.Oid) {
+			return
+		}
+		seen.Add(p.Oid)
+
+		names[p.Oid] = p.Name
+		transfers = append(transfers, &tq.Transfer{
+			Name: p.Name,
+			Oid:  p.Oid,
+			Size: p.Size,
+		})
+	}
+
+	if err := scanLeftOrAll(g, cb, bases, update, pushAll); err != nil {
+		return err
+	}
+	if err := ctx.scannerError(); err != nil {
+		return err
+	}
+
+	for len(transfers) > 0 {
+		n := tools.MinInt(verifyBatchSize, len(transfers))
+
+		res, err := tq.Batch(ctx.Manifest, tq.Upload, ctx.Remote, update.Right(), transfers[:n])
+		if err != nil {
+			return err
+		}
+
+		for _, t := range res.Objects {
+			if t.Error != nil {
+				ctx.otherErrs = append(ctx.otherErrs,
+					fmt.Errorf("Unable to verify %s (%s): %s", names[t.Oid], t.Oid, t.Error))
+				continue
+			}
+
+			// The server only asks for objects it doesn't already
+			// have to be uploaded.
+			if a, _ := t.Rel("upload"); a != nil {
+				ctx.notOnServer[names[t.Oid]] = t.Oid
+			} else {
+				ctx.journal.Add(t.Oid)
+			}
+		}
+
+		transfers = transfers[n:]
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +19,8 @@ var (
 	commandFuncs []func() *cobra.Command
 	commandMu    sync.Mutex
 
-	rootVersion bool
+	rootVersion  bool
+	rootEventsFd int
 )
 
 // NewCommand creates a new 'git-lfs' sub command, given a command name and
@@ -91,6 +93,8 @@ Simply type ` + root.Name() + ` help [path to command] for full details.`,
 	root.SetUsageFunc(usageCommand)
 
 	root.Flags().BoolVarP(&rootVersion, "version", "v", false, "")
+	root.PersistentFlags().IntVar(&rootEventsFd, "events-fd", -1, "")
+	root.PersistentPreRun = setupEventsFd
 
 	canonicalizeEnvironment()
 
@@ -111,6 +115,15 @@ Simply type ` + root.Name() + ` help [path to command] for full details.`,
 	return 0
 }
 
+// setupEventsFd passes the file descriptor given by --events-fd, if any, to
+// the transfer queues, and any Git LFS commands run by Git, through the
+// environment.
+func setupEventsFd(cmd *cobra.Command, args []string) {
+	if rootEventsFd >= 0 {
+		os.Setenv("GIT_LFS_EVENTS_FD", strconv.Itoa(rootEventsFd))
+	}
+}
+
 func gitlfsCommand(cmd *cobra.Command, args []string) {
 	versionCommand(cmd, args)
 	if !rootVersion {
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+	"github.com/rubyist/tracerx"
+)
+
+// limitStorage evicts the least recently used objects from local storage while
+// it holds more than lfs.storage.maxsize, going by when each was last read or
+// written. Only objects which can be fetched again are evicted, so those which
+// have not been pushed to the prune remote, or are only staged or stashed, are
+// always kept, as are packed objects, which have no use times of their own.
+// It does nothing unless the setting is given, and since it only frees space,
+// failures are traced rather than reported.
+func limitStorage() {
+	maxSizeFmt, ok := cfg.Git.Get("lfs.storage.maxsize")
+	if !ok || len(maxSizeFmt) == 0 {
+		return
+	}
+	maxSize, err := humanize.ParseBytes(maxSizeFmt)
+	if err != nil {
+		Exit("Invalid lfs.storage.maxsize %q: %v", maxSizeFmt, err)
+	}
+
+	unlockMigrate := acquireOperationLock("migrate")
+	defer unlockMigrate()
+	defer acquireOperationLock("prune")()
+
+	f := cfg.Filesystem()
+	var total uint64
+	var loose []lruObject
+	err = f.EachObject(func(obj fs.Object) error {
+		total += uint64(obj.Size)
+		if fi, err := os.Stat(f.ObjectPathname(obj.Oid)); err == nil {
+			loose = append(loose, lruObject{Object: obj, used: tools.LastUsed(fi)})
+		}
+		return nil
+	})
+	if err != nil {
+		tracerx.Printf("storage: could not list objects: %v", err)
+		return
+	}
+	if total <= maxSize {
+		return
+	}
+
+	kept, err := unpushedObjects()
+	if err != nil {
+		tracerx.Printf("storage: not evicting objects, could not find unpushed ones: %v", err)
+		return
+	}
+
+	sort.Slice(loose, func(i, j int) bool {
+		return loose[i].used.Before(loose[j].used)
+	})
+
+	var evicted int
+	var freed uint64
+	for _, obj := range loose {
+		if total <= maxSize {
+			break
+		}
+		if kept.Contains(obj.Oid) {
+			continue
+		}
+
+		// Another process writing the object wants it, so keep it.
+		lock, err := f.TryLock(fs.ObjectLockName(obj.Oid))
+		if err != nil {
+			tracerx.Printf("storage: keeping %v: %v", obj.Oid, err)
+			continue
+		}
+		err = f.RemoveObject(obj.Oid)
+		lock.Unlock()
+		if err != nil {
+			tracerx.Printf("storage: could not evict %v: %v", obj.Oid, err)
+			continue
+		}
+
+		tracerx.Printf("storage: evicted %v, last used %v", obj.Oid, obj.used)
+		total -= uint64(obj.Size)
+		freed += uint64(obj.Size)
+		evicted++
+	}
+
+	if evicted > 0 {
+		Print("storage: %d object(s) evicted (%s) to keep local storage within %s", evicted, humanize.FormatBytes(freed), maxSizeFmt)
+	}
+	if total > maxSize {
+		Print("storage: local storage holds %s, more than %s, but the rest of it cannot be fetched again", humanize.FormatBytes(total), maxSizeFmt)
+	}
+}
+
+// lruObject is an object in the object directory along with when it was last
+// read or written.
+type lruObject struct {
+	fs.Object
+	used time.Time
+}
+
+// unpushedObjects returns the OIDs of the objects which cannot be fetched again:
+// those added by commits which have not been pushed to the prune remote, and
+// those only in the index or a stash.
+func unpushedObjects() (tools.StringSet, error) {
+	oids := tools.NewStringSet()
+	var scanErr error
+	add := func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			scanErr = err
+			return
+		}
+		oids.Add(p.Oid)
+	}
+
+	gitscanner := lfs.NewGitScanner(cfg, nil)
+	defer gitscanner.Close()
+
+	remote := lfs.NewFetchPruneConfig(cfg.Git).PruneRemoteName
+	if err := gitscanner.ScanUnpushed(remote, add); err != nil {
+		return nil, err
+	}
+	if err := gitscanner.ScanIndex("HEAD", add); err != nil {
+		return nil, err
+	}
+	if err := gitscanner.ScanStashed(add); err != nil {
+		return nil, err
+	}
+	return oids, scanErr
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// recurseSubmodulesArg is set by --recurse-submodules on the commands which
+// support it.
+var recurseSubmodulesArg bool
+
+// addRecurseSubmodulesFlag adds the --recurse-submodules flag to the given
+// command.
+func addRecurseSubmodulesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&recurseSubmodulesArg, "recurse-submodules", false, "Also run this command in each submodule, recursively")
+}
+
+// runInSubmodules runs the given command again in each initialized submodule,
+// recursively, if --recurse-submodules was given. As with "git lfs clone", a
+// new instance of Git LFS is run in each submodule, so that its own
+// configuration, including its .lfsconfig and endpoints, is used.
+//
+// Only the flags given to the command are passed on: refs, remotes, and paths
+// refer to the current repository, so each submodule uses its own defaults.
+func runInSubmodules(cmd *cobra.Command) {
+	if !recurseSubmodulesArg {
+		return
+	}
+
+	args := []string{"git", "lfs", cmd.Name()}
+	args = append(args, subprocess.ShellQuote(submoduleArgs(cmd))...)
+
+	c := subprocess.ExecCommand("git", "submodule", "foreach", "--recursive",
+		strings.Join(args, " "))
+	c.Env = append(c.Env, getAPIClient().SSHAuthSessionEnv()...)
+	c.Stderr = os.Stderr
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	if err := c.Run(); err != nil {
+		Exit("Error running 'git lfs %s' in submodules: %v", cmd.Name(), err)
+	}
+}
+
+// submodulePathFlags are the flags whose values are the names of files, which
+// are made absolute before they are passed on, since the command is run again
+// from within each submodule.
+var submodulePathFlags = map[string]bool{
+	"include-from": true,
+	"exclude-from": true,
+}
+
+// checkSubmodulePathArgs exits if any of the files given to the flags in
+// submodulePathFlags is standard input and --recurse-submodules was given,
+// since it can only be read once.
+func checkSubmodulePathArgs(cmd *cobra.Command) {
+	if !recurseSubmodulesArg {
+		return
+	}
+	for name := range submodulePathFlags {
+		values, _ := cmd.Flags().GetStringArray(name)
+		for _, v := range values {
+			if v == "-" {
+				Exit("Cannot combine --%s=- with --recurse-submodules", name)
+			}
+		}
+	}
+}
+
+// submoduleArgs returns the flags which were given to the command, other than
+// --recurse-submodules.
+func submoduleArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name == "recurse-submodules" {
+			return
+		}
+		if f.Value.Type() == "stringArray" {
+			// Flags which may be given more than once are passed
+			// on once for each value.
+			values, _ := cmd.Flags().GetStringArray(f.Name)
+			for _, v := range values {
+				if submodulePathFlags[f.Name] {
+					if abs, err := filepath.Abs(v); err == nil {
+						v = abs
+					}
+				}
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
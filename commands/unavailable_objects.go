@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"sort"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tq"
+)
+
+// unavailableObject is an object which the server refused to let Git LFS
+// download, because it does not have it or the user is not allowed to access
+// it.
+type unavailableObject struct {
+	Path   string
+	Oid    string
+	Reason string
+}
+
+// isUnavailableObjectError returns whether the given transfer error means that
+// the server does not have the object, or does not allow it to be downloaded.
+func isUnavailableObjectError(err error) bool {
+	oerr, ok := errors.Cause(err).(*tq.ObjectError)
+	return ok && (oerr.Missing() || oerr.Denied())
+}
+
+// isDeniedObjectError returns whether the given transfer error means that the
+// user is not allowed to access the object.
+func isDeniedObjectError(err error) bool {
+	oerr, ok := errors.Cause(err).(*tq.ObjectError)
+	return ok && oerr.Denied()
+}
+
+// unavailableObjects returns the objects which the given transfer errors are
+// about, with the reasons the server gave.
+func unavailableObjects(errs []error) []*unavailableObject {
+	objs := make([]*unavailableObject, 0, len(errs))
+	for _, err := range errs {
+		obj := &unavailableObject{Reason: err.Error()}
+		if terr, ok := err.(*tq.ObjectTransferError); ok {
+			obj.Path = terr.Name
+			obj.Oid = terr.Oid
+			obj.Reason = terr.Err.Message
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+// reportUnavailableObjects prints the objects which could not be downloaded,
+// with the reasons the server gave, under a heading saying why, such as
+// "denied by the server".
+func reportUnavailableObjects(command, why string, objs []*unavailableObject) {
+	if len(objs) == 0 {
+		return
+	}
+
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].Path < objs[j].Path
+	})
+
+	Error("%s: %d object(s) %s:", command, len(objs), why)
+	for _, obj := range objs {
+		Error("  %s (%s): %s", obj.Path, obj.Oid, obj.Reason)
+	}
+}
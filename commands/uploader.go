@@ -10,6 +10,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/lfs"
@@ -39,6 +40,13 @@ func uploadForRefUpdates(ctx *uploadContext, updates []*git.RefUpdate, pushAll b
 		}
 	}
 	for _, update := range updates {
+		if ctx.isVerifyOnly(update.Right()) {
+			if err := verifyLeftOrAll(gitscanner, ctx, rightSides, update, pushAll); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("ref %s:", update.Left().Name))
+			}
+			continue
+		}
+
 		// initialized here to prevent looped defer
 		q := ctx.NewQueue(
 			tq.RemoteRef(update.Right()),
@@ -55,7 +63,34 @@ func uploadForRefUpdates(ctx *uploadContext, updates []*git.RefUpdate, pushAll b
 }
 
 func uploadLeftOrAll(g *lfs.GitScanner, ctx *uploadContext, q *tq.TransferQueue, bases []string, update *git.RefUpdate, pushAll bool) error {
-	cb := ctx.gitScannerCallback(q)
+	if len(ctx.upstreamRemote) == 0 {
+		if err := scanLeftOrAll(g, ctx.gitScannerCallback(q), bases, update, pushAll); err != nil {
+			return err
+		}
+		return ctx.scannerError()
+	}
+
+	// Collect the pointers first, so that the upstream remote is asked
+	// about them in batches.
+	var pointers []*lfs.WrappedPointer
+	cb := func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ctx.addScannerError(err)
+		} else {
+			pointers = append(pointers, p)
+		}
+	}
+	if err := scanLeftOrAll(g, cb, bases, update, pushAll); err != nil {
+		return err
+	}
+	if err := ctx.scannerError(); err != nil {
+		return err
+	}
+	ctx.UploadPointers(q, pointers...)
+	return nil
+}
+
+func scanLeftOrAll(g *lfs.GitScanner, cb lfs.GitScannerFoundPointer, bases []string, update *git.RefUpdate, pushAll bool) error {
 	if pushAll {
 		if err := g.ScanRefWithDeleted(update.LeftCommitish(), cb); err != nil {
 			return err
@@ -70,7 +105,7 @@ func uploadLeftOrAll(g *lfs.GitScanner, ctx *uploadContext, q *tq.TransferQueue,
 			return err
 		}
 	}
-	return ctx.scannerError()
+	return nil
 }
 
 type uploadContext struct {
@@ -92,6 +127,10 @@ type uploadContext struct {
 	// pointers should allow pushing Git blobs
 	allowMissing bool
 
+	// verifyOnlyRefs holds the patterns of remote refs for which objects
+	// are only verified to exist on the server, and never uploaded
+	verifyOnlyRefs []string
+
 	// tracks errors from gitscanner callbacks
 	scannerErr error
 	errMu      sync.Mutex
@@ -100,6 +139,30 @@ type uploadContext struct {
 	missing   map[string]string
 	corrupt   map[string]string
 	otherErrs []error
+
+	// objects which the server still holds in quarantine pending a scan,
+	// which do not fail the push
+	quarantined []*tq.QuarantinedObjectError
+
+	// errors from inspectors which rejected objects, which are not sent
+	// to the server
+	rejected []error
+
+	// filename => oid, for objects which a verify-only ref needs but
+	// the server doesn't have
+	notOnServer map[string]string
+
+	// journal records the objects known to be on the server, and
+	// skipPushed is whether those are skipped rather than sent to it
+	journal    *pushJournal
+	skipPushed bool
+
+	// upstreamRemote is the remote whose LFS server is asked for the
+	// objects to be pushed, which are skipped if it has them, or empty
+	// if it is not asked; skippedUpstream counts the objects skipped
+	upstreamRemote   string
+	upstreamManifest *tq.Manifest
+	skippedUpstream  int
 }
 
 func newUploadContext(dryRun bool) *uploadContext {
@@ -116,6 +179,8 @@ func newUploadContext(dryRun bool) *uploadContext {
 		missing:      make(map[string]string),
 		corrupt:      make(map[string]string),
 		otherErrs:    make([]error, 0),
+		notOnServer:  make(map[string]string),
+		journal:      newPushJournal(getAPIClient().Endpoints.Endpoint("upload", remote).Url),
 	}
 
 	var sink io.Writer = os.Stdout
@@ -125,6 +190,7 @@ func newUploadContext(dryRun bool) *uploadContext {
 
 	ctx.logger = tasklog.NewLogger(sink,
 		tasklog.ForceProgress(cfg.ForceProgress()),
+		tasklog.PlainProgress(cfg.PlainProgress()),
 	)
 	ctx.meter = buildProgressMeter(ctx.DryRun, tq.Upload)
 	ctx.logger.Enqueue(ctx.meter)
@@ -133,10 +199,12 @@ func newUploadContext(dryRun bool) *uploadContext {
 }
 
 func (c *uploadContext) NewQueue(options ...tq.Option) *tq.TransferQueue {
-	return tq.NewTransferQueue(tq.Upload, c.Manifest, c.Remote, append(options,
+	return trackQueue(tq.NewTransferQueue(tq.Upload, c.Manifest, c.Remote, append(options,
 		tq.DryRun(c.DryRun),
 		tq.WithProgress(c.meter),
-	)...)
+		tq.WithPresentCallback(c.journal.Add),
+		tq.WithPendingFile(pendingQueueFile(tq.Upload)),
+	)...))
 }
 
 func (c *uploadContext) scannerError() error {
@@ -174,6 +242,15 @@ func (c *uploadContext) gitScannerCallback(tqueue *tq.TransferQueue) func(*lfs.W
 	}
 }
 
+// addRejected records that an inspector rejected an object with the given
+// error, so that the push fails.
+func (c *uploadContext) addRejected(err error) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	c.rejected = append(c.rejected, err)
+}
+
 // AddUpload adds the given oid to the set of oids that have been uploaded in
 // the current process.
 func (c *uploadContext) SetUploaded(oid string) {
@@ -186,6 +263,16 @@ func (c *uploadContext) HasUploaded(oid string) bool {
 	return c.uploadedOids.Contains(oid)
 }
 
+// isPushed returns whether the given oid is recorded as being on the server,
+// and need not be sent to it.
+func (c *uploadContext) isPushed(oid string) bool {
+	if c.skipPushed && c.journal.Contains(oid) {
+		tracerx.Printf("push: %s is recorded as being on the server, skipping", oid)
+		return true
+	}
+	return false
+}
+
 func (c *uploadContext) prepareUpload(unfiltered ...*lfs.WrappedPointer) []*lfs.WrappedPointer {
 	numUnfiltered := len(unfiltered)
 	uploadables := make([]*lfs.WrappedPointer, 0, numUnfiltered)
@@ -224,6 +311,10 @@ func (c *uploadContext) prepareUpload(unfiltered ...*lfs.WrappedPointer) []*lfs.
 
 		c.lockVerifier.LockedByUs(p.Name)
 
+		if canUpload && c.isPushed(p.Oid) {
+			continue
+		}
+
 		if canUpload {
 			// estimate in meter early (even if it's not going into
 			// uploadables), since we will call Skip() based on the
@@ -251,13 +342,25 @@ func (c *uploadContext) UploadPointers(q *tq.TransferQueue, unfiltered ...*lfs.W
 		return
 	}
 
-	pointers := c.prepareUpload(unfiltered...)
+	pointers := c.skipOnUpstream(c.prepareUpload(unfiltered...))
 	for _, p := range pointers {
 		t, err := c.uploadTransfer(p)
 		if err != nil && !errors.IsCleanPointerError(err) {
 			ExitWithError(err)
 		}
 
+		if !t.Missing {
+			oid := t.Oid
+			if err := inspectObject(config.InspectStageUpload, t.Name, oid, t.Size, func() (io.ReadCloser, error) {
+				return cfg.Filesystem().OpenObject(oid)
+			}); err != nil {
+				c.addRejected(err)
+				c.meter.Skip(t.Size)
+				c.SetUploaded(p.Oid)
+				continue
+			}
+		}
+
 		q.Add(t.Name, t.Path, t.Oid, t.Size, t.Missing, nil)
 		c.SetUploaded(p.Oid)
 	}
@@ -265,9 +368,12 @@ func (c *uploadContext) UploadPointers(q *tq.TransferQueue, unfiltered ...*lfs.W
 
 func (c *uploadContext) CollectErrors(tqueue *tq.TransferQueue) {
 	tqueue.Wait()
+	exitIfInterrupted(tqueue, tq.Upload)
 
 	for _, err := range tqueue.Errors() {
-		if malformed, ok := err.(*tq.MalformedObjectError); ok {
+		if quarantined, ok := errors.Cause(err).(*tq.QuarantinedObjectError); ok {
+			c.quarantined = append(c.quarantined, quarantined)
+		} else if malformed, ok := err.(*tq.MalformedObjectError); ok {
 			if malformed.Missing() {
 				c.missing[malformed.Name] = malformed.Oid
 			} else if malformed.Corrupt() {
@@ -281,11 +387,37 @@ func (c *uploadContext) CollectErrors(tqueue *tq.TransferQueue) {
 
 func (c *uploadContext) ReportErrors() {
 	c.meter.Finish()
+	c.journal.Close()
+	c.lockVerifier.UseCapabilities(c.Manifest.Capabilities())
+
+	if c.skippedUpstream > 0 {
+		Print("LFS: %d object(s) skipped, already on remote %q", c.skippedUpstream, c.upstreamRemote)
+	}
+
+	if len(c.quarantined) > 0 {
+		Print("LFS: %d object(s) uploaded, but quarantined by the remote pending a scan:", len(c.quarantined))
+		for _, q := range c.quarantined {
+			if len(q.Message) > 0 {
+				Print("  %s (%s): %s", q.Name, q.Oid, q.Message)
+			} else {
+				Print("  %s (%s)", q.Name, q.Oid)
+			}
+		}
+		Print("hint: These objects may not be available to others until the scan completes.")
+	}
 
 	for _, err := range c.otherErrs {
 		FullError(err)
 	}
 
+	if len(c.rejected) > 0 {
+		Print("LFS upload rejected:")
+		for _, err := range c.rejected {
+			Print("  %s", err)
+		}
+		os.Exit(failureExitCode())
+	}
+
 	if len(c.missing) > 0 || len(c.corrupt) > 0 {
 		var action string
 		if c.allowMissing {
@@ -308,12 +440,28 @@ func (c *uploadContext) ReportErrors() {
 				"hint: You can disable this check with: 'git config lfs.allowincompletepush true'",
 			}
 			Print(strings.Join(pushMissingHint, "\n"))
-			os.Exit(2)
+			os.Exit(failureExitCode())
+		}
+	}
+
+	if len(c.notOnServer) > 0 {
+		Print("LFS objects missing from remote:")
+		for name, oid := range c.notOnServer {
+			Print("  (missing) %s (%s)", name, oid)
+		}
+
+		verifyOnlyHint := []string{
+			"hint: Your push was rejected because pushes to this ref only verify that",
+			"hint: Git LFS objects are already on the remote, and never upload them.",
+			"hint: Upload the objects above through the permitted route (such as CI)",
+			"hint: and push again. See lfs.pushverifyonly in git-lfs-config(5).",
 		}
+		Print(strings.Join(verifyOnlyHint, "\n"))
+		os.Exit(failureExitCode())
 	}
 
 	if len(c.otherErrs) > 0 {
-		os.Exit(2)
+		os.Exit(failureExitCode())
 	}
 
 	if c.lockVerifier.HasUnownedLocks() {
@@ -355,7 +503,7 @@ func (c *uploadContext) uploadTransfer(p *lfs.WrappedPointer) (*tq.Transfer, err
 	filename := p.Name
 	oid := p.Oid
 
-	localMediaPath, err := c.gitfilter.ObjectPath(oid)
+	localMediaPath, err := cfg.Filesystem().UnpackedObjectPath(oid)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error uploading file %s (%s)", filename, oid)
 	}
@@ -426,6 +574,17 @@ func supportsLockingAPI(rawurl string) bool {
 	return false
 }
 
+// enableFor enables lock verification for the given lfsapi.Endpoint,
+// "endpoint".
+func enableFor(rawurl string) error {
+	tracerx.Printf("commands: enabling lock verification for %q", rawurl)
+
+	key := strings.Join([]string{"lfs", rawurl, "locksverify"}, ".")
+
+	_, err := cfg.SetGitLocalKey(key, "true")
+	return err
+}
+
 // disableFor disables lock verification for the given lfsapi.Endpoint,
 // "endpoint".
 func disableFor(rawurl string) error {
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"github.com/git-lfs/git-lfs/v2/lfs"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tq"
+	"github.com/rubyist/tracerx"
+)
+
+// defaultUpstreamRemote is the remote a fork is assumed to have been made
+// from unless lfs.upstreamremote is set.
+const defaultUpstreamRemote = "upstream"
+
+// enableUpstreamDedup makes the push skip the objects which the LFS server of
+// the upstream remote, set by lfs.upstreamremote, already has, for hosts
+// whose forks share storage with their upstream repository. It does nothing,
+// with a warning, if that remote does not exist, and silently if it uses the
+// same server as the remote being pushed to.
+func (c *uploadContext) enableUpstreamDedup() {
+	upstream, ok := cfg.Git.Get("lfs.upstreamremote")
+	if !ok || len(upstream) == 0 {
+		upstream = defaultUpstreamRemote
+	}
+
+	if !tools.NewStringSetFromSlice(cfg.Remotes()).Contains(upstream) {
+		Error("warning: not checking for objects upstream, since there is no remote %q", upstream)
+		return
+	}
+
+	endpoints := getAPIClient().Endpoints
+	if endpoints.Endpoint("download", upstream).Url == endpoints.Endpoint("upload", c.Remote).Url {
+		return
+	}
+
+	c.upstreamRemote = upstream
+	c.upstreamManifest = getTransferManifestOperationRemote("download", upstream)
+}
+
+// skipOnUpstream returns those of the given pointers which the upstream
+// remote's LFS server does not have, and so need to be uploaded. Objects the
+// server cannot be asked about are uploaded as usual.
+func (c *uploadContext) skipOnUpstream(pointers []*lfs.WrappedPointer) []*lfs.WrappedPointer {
+	if len(c.upstreamRemote) == 0 || len(pointers) == 0 {
+		return pointers
+	}
+
+	upstream := tools.NewStringSet()
+	for i := 0; i < len(pointers); i += verifyBatchSize {
+		batch := pointers[i:tools.MinInt(i+verifyBatchSize, len(pointers))]
+
+		transfers := make([]*tq.Transfer, 0, len(batch))
+		for _, p := range batch {
+			transfers = append(transfers, &tq.Transfer{
+				Name: p.Name,
+				Oid:  p.Oid,
+				Size: p.Size,
+			})
+		}
+
+		res, err := tq.Batch(c.upstreamManifest, tq.Download, c.upstreamRemote, nil, transfers)
+		if err != nil {
+			Error("warning: unable to check for objects on remote %q: %v", c.upstreamRemote, err)
+			return pointers
+		}
+
+		for _, t := range res.Objects {
+			// The server only offers objects it has for download.
+			if a, _ := t.Rel("download"); t.Error == nil && a != nil {
+				upstream.Add(t.Oid)
+			}
+		}
+	}
+
+	uploadables := make([]*lfs.WrappedPointer, 0, len(pointers))
+	for _, p := range pointers {
+		if !upstream.Contains(p.Oid) {
+			uploadables = append(uploadables, p)
+			continue
+		}
+
+		tracerx.Printf("push: %s is on remote %q, skipping", p.Oid, c.upstreamRemote)
+		c.meter.Skip(p.Size)
+		c.SetUploaded(p.Oid)
+		c.skippedUpstream++
+	}
+	return uploadables
+}
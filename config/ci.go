@@ -0,0 +1,69 @@
+package config
+
+import "strings"
+
+const (
+	// ciConcurrentTransfers is the default number of concurrent transfers
+	// used in CI mode, where network bandwidth is usually plentiful.
+	ciConcurrentTransfers = 16
+
+	// defaultConcurrentTransfers is the default number of concurrent
+	// transfers otherwise.
+	defaultConcurrentTransfers = 8
+)
+
+// ciEnvironmentVariables are set by common CI systems, and are used to detect
+// whether Git LFS is running in CI when lfs.ci is not set.
+var ciEnvironmentVariables = []string{
+	"CI",
+	"BUILDKITE",
+	"CIRCLECI",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+	"TRAVIS",
+}
+
+// IsCI returns whether Git LFS should behave as appropriate for a CI system,
+// as given by lfs.ci, or, if it is not set, whether any of the environment
+// variables set by common CI systems are present.
+func IsCI(gitEnv, osEnv Environment) bool {
+	if v, ok := gitEnv.Get("lfs.ci"); ok && len(v) > 0 {
+		return Bool(v, false)
+	}
+
+	for _, name := range ciEnvironmentVariables {
+		v, _ := osEnv.Get(name)
+		switch strings.ToLower(v) {
+		case "", "false", "0", "off", "no", "f":
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// ConcurrentTransfers returns the number of concurrent transfers to use, given
+// by lfs.concurrenttransfers, or a default that is higher in CI mode.
+func ConcurrentTransfers(gitEnv, osEnv Environment) int {
+	if v := gitEnv.Int("lfs.concurrenttransfers", 0); v > 0 {
+		return v
+	}
+	if IsCI(gitEnv, osEnv) {
+		return ciConcurrentTransfers
+	}
+	return defaultConcurrentTransfers
+}
+
+// CIMode returns whether Git LFS is running in CI mode. See IsCI.
+func (c *Configuration) CIMode() bool {
+	return IsCI(c.Git, c.Os)
+}
+
+// PlainProgress returns whether only the final progress line of each task
+// should be shown, which is the case in CI mode unless progress is forced.
+func (c *Configuration) PlainProgress() bool {
+	return c.CIMode() && !c.ForceProgress()
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIModeDetection(t *testing.T) {
+	for desc, c := range map[string]struct {
+		Git      map[string][]string
+		Os       map[string][]string
+		Expected bool
+	}{
+		"unset":                 {nil, nil, false},
+		"lfs.ci true":           {map[string][]string{"lfs.ci": {"true"}}, nil, true},
+		"CI true":               {nil, map[string][]string{"CI": {"true"}}, true},
+		"CI false":              {nil, map[string][]string{"CI": {"false"}}, false},
+		"JENKINS_URL":           {nil, map[string][]string{"JENKINS_URL": {"https://ci.example.com/"}}, true},
+		"lfs.ci false with CI":  {map[string][]string{"lfs.ci": {"false"}}, map[string][]string{"CI": {"true"}}, false},
+		"GITHUB_ACTIONS":        {nil, map[string][]string{"GITHUB_ACTIONS": {"true"}}, true},
+		"unrelated environment": {nil, map[string][]string{"CIPHER": {"1"}}, false},
+	} {
+		cfg := NewFrom(Values{Git: c.Git, Os: c.Os})
+		assert.Equal(t, c.Expected, cfg.CIMode(), desc)
+	}
+}
+
+func TestCIModeConcurrentTransfers(t *testing.T) {
+	cfg := NewFrom(Values{})
+	assert.Equal(t, 8, ConcurrentTransfers(cfg.Git, cfg.Os))
+
+	cfg = NewFrom(Values{Git: map[string][]string{"lfs.ci": {"true"}}})
+	assert.Equal(t, 16, ConcurrentTransfers(cfg.Git, cfg.Os))
+
+	cfg = NewFrom(Values{Git: map[string][]string{
+		"lfs.ci":                  {"true"},
+		"lfs.concurrenttransfers": {"3"},
+	}})
+	assert.Equal(t, 3, ConcurrentTransfers(cfg.Git, cfg.Os))
+}
+
+func TestCIModePlainProgress(t *testing.T) {
+	cfg := NewFrom(Values{Git: map[string][]string{"lfs.ci": {"true"}}})
+	assert.True(t, cfg.PlainProgress())
+
+	cfg = NewFrom(Values{
+		Git: map[string][]string{"lfs.ci": {"true"}},
+		Os:  map[string][]string{"GIT_LFS_FORCE_PROGRESS": {"1"}},
+	})
+	assert.False(t, cfg.PlainProgress())
+}
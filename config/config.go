@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -190,15 +191,105 @@ func (c *Configuration) TusTransfersAllowed() bool {
 }
 
 func (c *Configuration) FetchIncludePaths() []string {
-	patterns, _ := c.Git.Get("lfs.fetchinclude")
+	patterns, _ := c.Git.Get(FetchProfileKey(c.Git, "fetchinclude", "lfs.fetchinclude"))
 	return tools.CleanPaths(patterns, ",")
 }
 
 func (c *Configuration) FetchExcludePaths() []string {
-	patterns, _ := c.Git.Get("lfs.fetchexclude")
+	patterns, _ := c.Git.Get(FetchProfileKey(c.Git, "fetchexclude", "lfs.fetchexclude"))
 	return tools.CleanPaths(patterns, ",")
 }
 
+// FetchProfileSettings are the settings which a fetch profile may set, as
+// lfs.profile.<name>.<setting>.
+var FetchProfileSettings = []string{"fetchinclude", "fetchexclude", "recentrefsdays"}
+
+// FetchProfile returns the name of the fetch profile in use, as given by
+// lfs.profile, or the empty string if there is none.
+func (c *Configuration) FetchProfile() string {
+	name, _ := c.Git.Get("lfs.profile")
+	return name
+}
+
+// FetchProfiles returns the names of the fetch profiles which are defined,
+// in sorted order.
+func (c *Configuration) FetchProfiles() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for key := range c.Git.All() {
+		parts := strings.Split(key, ".")
+		if len(parts) < 4 || parts[0] != "lfs" || parts[1] != "profile" || !IsFetchProfileSetting(parts[len(parts)-1]) {
+			continue
+		}
+
+		name := strings.Join(parts[2:len(parts)-1], ".")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// IsFetchProfileSetting returns whether the given setting may be set by a
+// fetch profile.
+func IsFetchProfileSetting(setting string) bool {
+	for _, s := range FetchProfileSettings {
+		if s == setting {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchProfileKey returns the key to read for the given fetch setting:
+// lfs.profile.<name>.<setting> if the fetch profile named by lfs.profile sets
+// it, or else the given key.
+func FetchProfileKey(git Environment, setting, key string) string {
+	name, ok := git.Get("lfs.profile")
+	if !ok || len(name) == 0 {
+		return key
+	}
+
+	profileKey := fmt.Sprintf("lfs.profile.%s.%s", name, setting)
+	if _, ok := git.Get(profileKey); ok {
+		return profileKey
+	}
+	return key
+}
+
+// PushVerifyOnlyRefs returns the patterns of remote refs, given by
+// lfs.pushverifyonly, for which the pre-push hook should verify that objects
+// are already present on the server instead of uploading them.
+func (c *Configuration) PushVerifyOnlyRefs() []string {
+	patterns, _ := c.Git.Get("lfs.pushverifyonly")
+	return tools.CleanPaths(patterns, ",")
+}
+
+// PointerVersion returns the version of the pointer format which the clean
+// filter should write, as given by lfs.pointerversion. Version 2 pointers may
+// carry metadata, but cannot be read by older versions of Git LFS. The default
+// is 1, including if the value is invalid.
+func (c *Configuration) PointerVersion() int {
+	if v := c.Git.Int("lfs.pointerversion", 1); v == 2 {
+		return v
+	}
+	return 1
+}
+
+// PointerMetadata returns the list of metadata keys to record in version 2
+// pointers, as given by lfs.pointermetadata, and whether that setting was
+// present at all.
+func (c *Configuration) PointerMetadata() ([]string, bool) {
+	keys, ok := c.Git.Get("lfs.pointermetadata")
+	if !ok {
+		return nil, false
+	}
+	return tools.CleanPaths(keys, ","), true
+}
+
 func (c *Configuration) CurrentRef() *git.Ref {
 	c.loading.Lock()
 	defer c.loading.Unlock()
@@ -317,10 +408,93 @@ func (c *Configuration) SortedExtensions() ([]Extension, error) {
 	return SortExtensions(c.Extensions())
 }
 
+// Values of lfs.symlinkpolicy, which controls how Git LFS writes files into the
+// working tree at paths which are symbolic links, or are beneath one.
+const (
+	// SymlinkPolicySkip leaves such paths alone, with a warning.
+	SymlinkPolicySkip = "skip"
+	// SymlinkPolicyError leaves such paths alone, and reports an error.
+	SymlinkPolicyError = "error"
+	// SymlinkPolicyFollow writes through symbolic links which resolve to
+	// a path within the working tree, and skips the others.
+	SymlinkPolicyFollow = "follow"
+)
+
+// SymlinkPolicy returns the value of lfs.symlinkpolicy, which is
+// SymlinkPolicySkip if it is unset or invalid.
+func (c *Configuration) SymlinkPolicy() string {
+	v, _ := c.Git.Get("lfs.symlinkpolicy")
+	switch v = strings.ToLower(v); v {
+	case SymlinkPolicyError, SymlinkPolicyFollow:
+		return v
+	}
+	return SymlinkPolicySkip
+}
+
+// FSMonitorHook returns the hook named by core.fsmonitor, which Git LFS can
+// ask for the paths changed in the working tree, or the empty string if there
+// is none, lfs.fsmonitor is false, or core.fsmonitor is a boolean, as it is
+// when Git's built-in daemon is used.
+func (c *Configuration) FSMonitorHook() string {
+	if !c.Git.Bool("lfs.fsmonitor", true) {
+		return ""
+	}
+
+	hook, _ := c.Git.Get("core.fsmonitor")
+	switch strings.ToLower(hook) {
+	case "true", "1", "on", "yes", "t", "false", "0", "off", "no", "f":
+		return ""
+	}
+	return hook
+}
+
 func (c *Configuration) SkipDownloadErrors() bool {
 	return c.Os.Bool("GIT_LFS_SKIP_DOWNLOAD_ERRORS", false) || c.Git.Bool("lfs.skipdownloaderrors", false)
 }
 
+// OperationLockTimeout returns how long to wait for another Git LFS process
+// to release an operation lock, as set by lfs.operationlocktimeout.
+func (c *Configuration) OperationLockTimeout() time.Duration {
+	return time.Duration(c.Git.Int("lfs.operationlocktimeout", 300)) * time.Second
+}
+
+// AllowMissingObjects returns whether commands which download objects only
+// warn, rather than fail, when the server does not have some of the objects
+// or does not allow them to be downloaded. This is set with
+// lfs.allowmissing=warn, or lfs.missingcontent set to anything but "error".
+func (c *Configuration) AllowMissingObjects() bool {
+	return c.MissingContent() != MissingContentError
+}
+
+// Values of lfs.missingcontent, which controls what Git LFS writes into the
+// working tree for a file whose object the server does not have, or does not
+// allow to be downloaded.
+const (
+	// MissingContentError leaves the pointer, and fails.
+	MissingContentError = "error"
+	// MissingContentPointer leaves the pointer, with a warning.
+	MissingContentPointer = "pointer"
+	// MissingContentPlaceholder writes a file of zeros of the object's
+	// size, with a warning.
+	MissingContentPlaceholder = "placeholder"
+)
+
+// MissingContent returns the value of lfs.missingcontent. If that is unset or
+// invalid, it is MissingContentPointer with lfs.allowmissing=warn, and
+// MissingContentError otherwise.
+func (c *Configuration) MissingContent() string {
+	v, _ := c.Git.Get("lfs.missingcontent")
+	switch v = strings.ToLower(v); v {
+	case MissingContentError, MissingContentPointer, MissingContentPlaceholder:
+		return v
+	}
+
+	if v, _ := c.Git.Get("lfs.allowmissing"); strings.ToLower(v) == "warn" {
+		return MissingContentPointer
+	}
+	return MissingContentError
+}
+
 func (c *Configuration) SetLockableFilesReadOnly() bool {
 	return c.Os.Bool("GIT_LFS_SET_LOCKABLE_READONLY", true) && c.Git.Bool("lfs.setlockablereadonly", true)
 }
@@ -426,6 +600,13 @@ func (c *Configuration) Filesystem() *fs.Filesystem {
 
 	if c.fs == nil {
 		lfsdir, _ := c.Git.Get("lfs.storage")
+		readOnlyDir := ""
+		if c.Git.Bool("lfs.storage.readonly", false) {
+			// The configured storage is only read from, and
+			// everything written goes to the overlay instead.
+			readOnlyDir = lfsdir
+			lfsdir, _ = c.Git.Get("lfs.storage.overlay")
+		}
 		c.fs = fs.New(
 			c.Os,
 			c.LocalGitDir(),
@@ -433,11 +614,61 @@ func (c *Configuration) Filesystem() *fs.Filesystem {
 			lfsdir,
 			c.RepositoryPermissions(false),
 		)
+		c.fs.Backend, _ = c.Git.Get("lfs.storage.backend")
+		c.fs.TierDir = c.tierDir(c.fs.GitStorageDir)
+		c.fs.ReadOnlyDir = c.readOnlyDir(c.fs, readOnlyDir)
 	}
 
 	return c.fs
 }
 
+// readOnlyDir returns the object directory of the read-only object store in
+// the given storage directory, configured with lfs.storage and
+// lfs.storage.readonly, with a leading "~" expanded, and a relative path taken
+// to be relative to the Git storage directory, as usual; or the empty string
+// if there is none. A store which is the same as the overlay is ignored.
+func (c *Configuration) readOnlyDir(f *fs.Filesystem, dir string) string {
+	if len(dir) == 0 {
+		if c.Git.Bool("lfs.storage.readonly", false) {
+			tracerx.Printf("ignoring lfs.storage.readonly: lfs.storage is not set")
+		}
+		return ""
+	}
+	dir, err := tools.ExpandPath(dir, false)
+	if err != nil {
+		tracerx.Printf("ignoring lfs.storage.readonly: %v", err)
+		return ""
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(f.GitStorageDir, dir)
+	}
+	if filepath.Clean(dir) == filepath.Clean(f.LFSStorageDir) {
+		tracerx.Printf("ignoring lfs.storage.readonly: lfs.storage.overlay is the same directory")
+		return ""
+	}
+	return filepath.Join(dir, "objects")
+}
+
+// tierDir returns the secondary storage for objects configured with
+// lfs.tier.path, with a leading "~" expanded, and a relative path taken to be
+// relative to the given Git storage directory, as with lfs.storage; or the
+// empty string if there is none.
+func (c *Configuration) tierDir(gitStorageDir string) string {
+	dir, ok := c.Git.Get("lfs.tier.path")
+	if !ok || len(dir) == 0 {
+		return ""
+	}
+	dir, err := tools.ExpandPath(dir, false)
+	if err != nil {
+		tracerx.Printf("ignoring lfs.tier.path: %v", err)
+		return ""
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitStorageDir, dir)
+	}
+	return dir
+}
+
 func (c *Configuration) Cleanup() error {
 	if c == nil {
 		return nil
@@ -61,6 +61,24 @@ func TestRemoteBranchPushDefault(t *testing.T) {
 	assert.Equal(t, "c", cfg.PushRemote())
 }
 
+func TestSymlinkPolicy(t *testing.T) {
+	for value, expected := range map[string]string{
+		"":       SymlinkPolicySkip,
+		"skip":   SymlinkPolicySkip,
+		"error":  SymlinkPolicyError,
+		"Follow": SymlinkPolicyFollow,
+		"bogus":  SymlinkPolicySkip,
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string][]string{
+				"lfs.symlinkpolicy": []string{value},
+			},
+		})
+
+		assert.Equal(t, expected, cfg.SymlinkPolicy(), "lfs.symlinkpolicy=%q", value)
+	}
+}
+
 func TestLFSDefault(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string][]string{
@@ -380,3 +398,115 @@ func TestRemoteNameWithDotDefault(t *testing.T) {
 
 	assert.Equal(t, "name.with.dot", cfg.Remote())
 }
+
+func TestFSMonitorHook(t *testing.T) {
+	for value, expected := range map[string]string{
+		"":                          "",
+		"true":                      "",
+		"False":                     "",
+		".git/hooks/query-watchman": ".git/hooks/query-watchman",
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string][]string{"core.fsmonitor": {value}},
+		})
+		assert.Equal(t, expected, cfg.FSMonitorHook(), "core.fsmonitor=%q", value)
+	}
+
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"core.fsmonitor": {".git/hooks/query-watchman"},
+			"lfs.fsmonitor":  {"false"},
+		},
+	})
+	assert.Equal(t, "", cfg.FSMonitorHook())
+}
+
+func TestAllowMissingObjects(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"":     false,
+		"fail": false,
+		"warn": true,
+		"WARN": true,
+		"true": false,
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string][]string{"lfs.allowmissing": {value}},
+		})
+		assert.Equal(t, expected, cfg.AllowMissingObjects(), "lfs.allowmissing=%q", value)
+	}
+}
+
+func TestMissingContent(t *testing.T) {
+	for _, c := range []struct {
+		missingContent, allowMissing, expected string
+	}{
+		{"", "", MissingContentError},
+		{"", "warn", MissingContentPointer},
+		{"placeholder", "", MissingContentPlaceholder},
+		{"Pointer", "", MissingContentPointer},
+		{"error", "warn", MissingContentError},
+		{"wat", "", MissingContentError},
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string][]string{
+				"lfs.missingcontent": {c.missingContent},
+				"lfs.allowmissing":   {c.allowMissing},
+			},
+		})
+		assert.Equal(t, c.expected, cfg.MissingContent(),
+			"lfs.missingcontent=%q, lfs.allowmissing=%q", c.missingContent, c.allowMissing)
+	}
+}
+
+func TestFetchProfile(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.fetchinclude":                  {"docs"},
+			"lfs.fetchexclude":                  {"docs/old"},
+			"lfs.profile":                       {"art-team"},
+			"lfs.profile.art-team.fetchinclude": {"art,textures"},
+			"lfs.profile.minimal.fetchinclude":  {""},
+			"lfs.profile.full.recentrefsdays":   {"0"},
+			"lfs.profile.other.unknown":         {"x"},
+		},
+	})
+
+	assert.Equal(t, "art-team", cfg.FetchProfile())
+	assert.Equal(t, []string{"art-team", "full", "minimal"}, cfg.FetchProfiles())
+	assert.Equal(t, []string{"art", "textures"}, cfg.FetchIncludePaths())
+	assert.Equal(t, []string{"docs/old"}, cfg.FetchExcludePaths())
+	assert.Equal(t, "lfs.fetchrecentrefsdays", FetchProfileKey(cfg.Git, "recentrefsdays", "lfs.fetchrecentrefsdays"))
+}
+
+func TestFetchProfileKeyWithoutProfile(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.fetchinclude":                 {"docs"},
+			"lfs.profile.minimal.fetchinclude": {"art"},
+		},
+	})
+
+	assert.Equal(t, "", cfg.FetchProfile())
+	assert.Equal(t, []string{"docs"}, cfg.FetchIncludePaths())
+	assert.Equal(t, "lfs.fetchinclude", FetchProfileKey(cfg.Git, "fetchinclude", "lfs.fetchinclude"))
+}
+
+func TestInspectors(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.inspector.virus.path":     {"clamdscan"},
+			"lfs.inspector.virus.args":     {"--no-summary -"},
+			"lfs.inspector.license.path":   {"check-license"},
+			"lfs.inspector.license.stages": {"Upload, "},
+			"lfs.inspector.unset.args":     {"x"},
+			"lfs.inspector.empty.path":     {""},
+		},
+	})
+
+	inspectors := cfg.Inspectors()
+	assert.Len(t, inspectors, 2)
+	assert.Equal(t, Inspector{"license", "check-license", "", []string{"upload"}}, inspectors[0])
+	assert.Equal(t, Inspector{"virus", "clamdscan", "--no-summary -", []string{"clean", "upload"}}, inspectors[1])
+	assert.False(t, inspectors[0].RunsAt(InspectStageClean))
+	assert.True(t, inspectors[0].RunsAt(InspectStageUpload))
+}
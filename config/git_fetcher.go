@@ -31,8 +31,6 @@ func readGitConfig(configs ...*git.ConfigurationSource) (gf *GitFetcher, extensi
 				continue
 			}
 
-			allowed := !gc.OnlySafeKeys
-
 			// We don't need to change the case of the key here,
 			// since Git will already have canonicalized it for us.
 			key, val := pieces[0], pieces[1]
@@ -47,6 +45,11 @@ func readGitConfig(configs ...*git.ConfigurationSource) (gf *GitFetcher, extensi
 				uniqKeys[key] = pieces[0]
 			}
 
+			if gc.OnlySafeKeys && !safeInLfsconfig(key) {
+				ignored = append(ignored, key)
+				continue
+			}
+
 			parts := strings.Split(key, ".")
 			if len(parts) == 4 && parts[0] == "lfs" && parts[1] == "extension" {
 				// prop: lfs.extension.<name>.<prop>
@@ -58,19 +61,10 @@ func readGitConfig(configs ...*git.ConfigurationSource) (gf *GitFetcher, extensi
 
 				switch prop {
 				case "clean":
-					if gc.OnlySafeKeys {
-						ignored = append(ignored, key)
-						continue
-					}
 					ext.Clean = val
 				case "smudge":
-					if gc.OnlySafeKeys {
-						ignored = append(ignored, key)
-						continue
-					}
 					ext.Smudge = val
 				case "priority":
-					allowed = true
 					p, err := strconv.Atoi(val)
 					if err == nil && p >= 0 {
 						ext.Priority = p
@@ -79,21 +73,8 @@ func readGitConfig(configs ...*git.ConfigurationSource) (gf *GitFetcher, extensi
 
 				extensions[name] = ext
 			} else if len(parts) > 1 && parts[0] == "remote" {
-				if gc.OnlySafeKeys && (len(parts) == 3 && parts[2] != "lfsurl") {
-					ignored = append(ignored, key)
-					continue
-				}
-
-				allowed = true
 				remote := strings.Join(parts[1:len(parts)-1], ".")
 				uniqRemotes[remote] = remote == "origin"
-			} else if len(parts) > 2 && parts[len(parts)-1] == "access" {
-				allowed = true
-			}
-
-			if !allowed && keyIsUnsafe(key) {
-				ignored = append(ignored, key)
-				continue
 			}
 
 			vals[key] = append(vals[key], val)
@@ -173,6 +154,25 @@ func (g *GitFetcher) caseFoldKey(key string) string {
 	}, ".")
 }
 
+// safeInLfsconfig returns whether the given key may be set in the .lfsconfig
+// file, rather than being ignored there.
+func safeInLfsconfig(key string) bool {
+	parts := strings.Split(key, ".")
+	switch {
+	case len(parts) == 4 && parts[0] == "lfs" && parts[1] == "extension":
+		// prop: lfs.extension.<name>.<prop>
+		return parts[3] == "priority"
+	case len(parts) > 1 && parts[0] == "remote":
+		return len(parts) != 3 || parts[2] == "lfsurl"
+	case len(parts) > 2 && parts[len(parts)-1] == "access":
+		return true
+	case len(parts) > 3 && parts[0] == "lfs" && parts[1] == "profile":
+		// prop: lfs.profile.<name>.<setting>
+		return IsFetchProfileSetting(parts[len(parts)-1])
+	}
+	return !keyIsUnsafe(key)
+}
+
 func keyIsUnsafe(key string) bool {
 	for _, safe := range safeKeys {
 		if safe == key {
@@ -184,11 +184,17 @@ func keyIsUnsafe(key string) bool {
 
 var safeKeys = []string{
 	"lfs.allowincompletepush",
+	"lfs.allowmissing",
 	"lfs.fetchexclude",
 	"lfs.fetchinclude",
 	"lfs.gitprotocol",
 	"lfs.locksverify",
+	"lfs.missingcontent",
+	"lfs.pointermetadata",
+	"lfs.pointerversion",
+	"lfs.profile",
 	"lfs.pushurl",
+	"lfs.pushverifyonly",
 	"lfs.skipdownloaderrors",
 	"lfs.url",
 }
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// Stages at which inspectors may run, as given by lfs.inspector.<name>.stages.
+const (
+	// InspectStageClean is when a file is cleaned, before its content
+	// enters the local object store.
+	InspectStageClean = "clean"
+	// InspectStageUpload is when an object is pushed, before it is sent to
+	// the remote.
+	InspectStageUpload = "upload"
+)
+
+// An Inspector describes a command which inspects the content of Git LFS
+// objects, and which may annotate or reject them. Inspectors are parsed from
+// the Git config, as lfs.inspector.<name>.<setting>.
+type Inspector struct {
+	Name   string
+	Path   string
+	Args   string
+	Stages []string
+}
+
+// RunsAt returns whether the inspector runs at the given stage.
+func (i Inspector) RunsAt(stage string) bool {
+	for _, s := range i.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspectors returns the inspectors which are configured with a path, sorted
+// by name. Those with no stages configured run at every stage.
+func (c *Configuration) Inspectors() []Inspector {
+	inspectors := make([]Inspector, 0)
+	for key := range c.Git.All() {
+		parts := strings.Split(key, ".")
+		if len(parts) < 4 || parts[0] != "lfs" || parts[1] != "inspector" || parts[len(parts)-1] != "path" {
+			continue
+		}
+
+		name := strings.Join(parts[2:len(parts)-1], ".")
+		path, _ := c.Git.Get(key)
+		if len(path) == 0 {
+			continue
+		}
+		args, _ := c.Git.Get("lfs.inspector." + name + ".args")
+
+		stages := []string{InspectStageClean, InspectStageUpload}
+		if v, ok := c.Git.Get("lfs.inspector." + name + ".stages"); ok {
+			stages = stages[:0]
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.ToLower(strings.TrimSpace(s)); len(s) > 0 {
+					stages = append(stages, s)
+				}
+			}
+		}
+
+		inspectors = append(inspectors, Inspector{
+			Name:   name,
+			Path:   path,
+			Args:   args,
+			Stages: stages,
+		})
+	}
+
+	sort.Slice(inspectors, func(i, j int) bool { return inspectors[i].Name < inspectors[j].Name })
+	return inspectors
+}
@@ -0,0 +1,16 @@
+package config
+
+// IsOffline returns whether Git LFS is offline, in which case it makes no
+// network requests. This is set by GIT_LFS_OFFLINE, or if that is unset, by
+// lfs.offline.
+func IsOffline(gitEnv, osEnv Environment) bool {
+	if v, ok := osEnv.Get("GIT_LFS_OFFLINE"); ok && len(v) > 0 {
+		return Bool(v, false)
+	}
+	return gitEnv.Bool("lfs.offline", false)
+}
+
+// Offline returns whether Git LFS is offline. See IsOffline.
+func (c *Configuration) Offline() bool {
+	return IsOffline(c.Git, c.Os)
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffline(t *testing.T) {
+	for desc, c := range map[string]struct {
+		Git      map[string][]string
+		Os       map[string][]string
+		Expected bool
+	}{
+		"unset":                              {nil, nil, false},
+		"lfs.offline true":                   {map[string][]string{"lfs.offline": {"true"}}, nil, true},
+		"GIT_LFS_OFFLINE 1":                  {nil, map[string][]string{"GIT_LFS_OFFLINE": {"1"}}, true},
+		"GIT_LFS_OFFLINE 0 with lfs.offline": {map[string][]string{"lfs.offline": {"true"}}, map[string][]string{"GIT_LFS_OFFLINE": {"0"}}, false},
+		"GIT_LFS_OFFLINE empty":              {map[string][]string{"lfs.offline": {"true"}}, map[string][]string{"GIT_LFS_OFFLINE": {""}}, true},
+		"lfs.offline false":                  {map[string][]string{"lfs.offline": {"false"}}, nil, false},
+	} {
+		cfg := NewFrom(Values{Git: c.Git, Os: c.Os})
+		assert.Equal(t, c.Expected, cfg.Offline(), desc)
+	}
+}
@@ -0,0 +1,494 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+)
+
+// Setting is a value given to a key in the Git configuration or the
+// .lfsconfig file, along with where it was given.
+type Setting struct {
+	Key   string
+	Value string
+
+	// Origin is where the value was given, in the form "git config
+	// --show-origin" prints, such as "file:.git/config".
+	Origin string
+
+	// Ignored is set if the value was given in the .lfsconfig file to a
+	// key which may not be set there, and so is ignored.
+	Ignored bool
+}
+
+func (s *Setting) String() string {
+	return fmt.Sprintf("%s=%s", s.Key, s.Value)
+}
+
+// Settings returns all the values given to keys in the .lfsconfig file and the
+// Git configuration, in the order in which they are read, so that the last
+// value given to a key, which is not ignored, is the one in effect.
+func (c *Configuration) Settings() ([]*Setting, error) {
+	sources, err := c.gitConfig.SourcesWithOrigins(c.LocalWorkingDir(), ".lfsconfig")
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []*Setting
+	for _, source := range sources {
+		for i, line := range source.Lines {
+			pieces := strings.SplitN(line, "=", 2)
+			if len(pieces) < 2 {
+				continue
+			}
+
+			s := &Setting{Key: pieces[0], Value: pieces[1]}
+			if i < len(source.Origins) {
+				s.Origin = source.Origins[i]
+			}
+			s.Ignored = source.OnlySafeKeys && !safeInLfsconfig(s.Key)
+			settings = append(settings, s)
+		}
+	}
+	return settings, nil
+}
+
+// EffectiveSettings returns the settings among those given which are in
+// effect, being the last value given to each key which is not ignored, sorted
+// by key.
+func EffectiveSettings(settings []*Setting) []*Setting {
+	byKey := make(map[string]*Setting)
+	for _, s := range settings {
+		if !s.Ignored {
+			byKey[s.Key] = s
+		}
+	}
+
+	effective := make([]*Setting, 0, len(byKey))
+	for _, s := range byKey {
+		effective = append(effective, s)
+	}
+	sort.Slice(effective, func(i, j int) bool {
+		return effective[i].Key < effective[j].Key
+	})
+	return effective
+}
+
+// IsLFSKey returns whether the given key is one of Git LFS's own, which is
+// any in the "lfs" section, or one of those in the "remote" section which
+// begin with "lfs".
+func IsLFSKey(key string) bool {
+	if strings.HasPrefix(key, "lfs.") {
+		return true
+	}
+	if !strings.HasPrefix(key, "remote.") {
+		return false
+	}
+	return strings.HasPrefix(key[strings.LastIndex(key, ".")+1:], "lfs")
+}
+
+// valueKind is the kind of value a key takes.
+type valueKind int
+
+const (
+	anyValue valueKind = iota
+	boolValue
+	intValue
+	sizeValue
+	enumValue
+	enumListValue
+)
+
+// knownKey is a key which Git LFS reads.
+type knownKey struct {
+	// name is the key, with "*" standing for a subsection, such as the
+	// name of a remote, or a URL.
+	name string
+	kind valueKind
+
+	// min is the smallest value an intValue key may take.
+	min int
+
+	// values are those an enumValue key may take, or which an
+	// enumListValue key may list, separated by commas.
+	values []string
+
+	// multi is set if the key may be given more than one value, all of
+	// which are used.
+	multi bool
+}
+
+// urlKeys are the keys which may also be given for a URL, as
+// lfs.<url>.<key>, to apply to requests to it.
+var urlKeys = []*knownKey{
+	{name: "access", kind: enumValue, values: []string{"none", "basic", "private", "negotiate"}},
+	{name: "activitytimeout", kind: intValue},
+	{name: "concurrenttransfers", kind: intValue, min: 1},
+	{name: "contenttype", kind: boolValue},
+	{name: "credentialhelper"},
+	{name: "locksverify", kind: boolValue},
+	{name: "maxrequestspersecond"},
+	{name: "pinnedpubkey"},
+	{name: "standalonetransferagent"},
+}
+
+var knownKeys = []*knownKey{
+	{name: "lfs.allowincompletepush", kind: boolValue},
+	{name: "lfs.allowmissing", kind: enumValue, values: []string{"fail", "warn"}},
+	{name: "lfs.basictransfersonly", kind: boolValue},
+	{name: "lfs.bootstrap", kind: boolValue},
+	{name: "lfs.bootstrap.minobjects", kind: intValue},
+	{name: "lfs.cachecredentials", kind: boolValue},
+	{name: "lfs.ci", kind: boolValue},
+	{name: "lfs.customtransfer.*.args"},
+	{name: "lfs.customtransfer.*.concurrent", kind: boolValue},
+	{name: "lfs.customtransfer.*.direction", kind: enumValue, values: []string{"download", "upload", "both"}},
+	{name: "lfs.customtransfer.*.path"},
+	{name: "lfs.defaulttokenttl", kind: intValue},
+	{name: "lfs.dialtimeout", kind: intValue},
+	{name: "lfs.dnsdiscovery", kind: boolValue},
+	{name: "lfs.extension.*.clean"},
+	{name: "lfs.extension.*.priority", kind: intValue},
+	{name: "lfs.extension.*.smudge"},
+	{name: "lfs.fetchexclude"},
+	{name: "lfs.fetchfallbackremotes"},
+	{name: "lfs.fetchinclude"},
+	{name: "lfs.fetchmissinghours", kind: intValue},
+	{name: "lfs.fetchrecentalways", kind: boolValue},
+	{name: "lfs.fetchrecentcommitsdays", kind: intValue},
+	{name: "lfs.fetchrecentrefsdays", kind: intValue},
+	{name: "lfs.fetchrecentremoterefs", kind: boolValue},
+	{name: "lfs.forceprogress", kind: boolValue},
+	{name: "lfs.fsmonitor", kind: boolValue},
+	{name: "lfs.gctemp.auto", kind: boolValue},
+	{name: "lfs.gctemp.incompletedays", kind: intValue},
+	{name: "lfs.gctemp.tmphours", kind: intValue},
+	{name: "lfs.generic.url"},
+	{name: "lfs.gitprotocol"},
+	{name: "lfs.inspector.*.args"},
+	{name: "lfs.inspector.*.path"},
+	{name: "lfs.inspector.*.stages"},
+	{name: "lfs.ipfs.gateway"},
+	{name: "lfs.ipfs.resolver"},
+	{name: "lfs.ipversion", kind: enumValue, values: []string{"4", "6", "auto"}},
+	{name: "lfs.keepalive", kind: intValue},
+	{name: "lfs.largefilewarning", kind: boolValue},
+	{name: "lfs.lockignoredfiles", kind: boolValue},
+	{name: "lfs.maintenance.*.enabled", kind: boolValue},
+	{name: "lfs.maintenance.*.schedule", kind: enumValue, values: []string{"hourly", "daily", "weekly"}},
+	{name: "lfs.maintenance.repo", multi: true},
+	{name: "lfs.missingcontent", kind: enumValue, values: []string{MissingContentError, MissingContentPointer, MissingContentPlaceholder}},
+	{name: "lfs.offline", kind: boolValue},
+	{name: "lfs.operationlocktimeout", kind: intValue},
+	{name: "lfs.pack.maxobjectsize", kind: sizeValue},
+	{name: "lfs.pointermetadata", kind: enumListValue, values: []string{"content-type", "executable", "mtime"}},
+	{name: "lfs.pointerversion", kind: enumValue, values: []string{"1", "2"}},
+	{name: "lfs.prereceive.maxobjectsize", kind: sizeValue},
+	{name: "lfs.progress", kind: enumValue, values: []string{"meter", "tui"}},
+	{name: "lfs.profile"},
+	{name: "lfs.profile.*.fetchexclude"},
+	{name: "lfs.profile.*.fetchinclude"},
+	{name: "lfs.profile.*.recentrefsdays", kind: intValue},
+	{name: "lfs.pruneoffsetdays", kind: intValue},
+	{name: "lfs.pruneremotetocheck"},
+	{name: "lfs.pruneverifyremotealways", kind: boolValue},
+	{name: "lfs.pushupstreamdedup", kind: boolValue},
+	{name: "lfs.pushurl"},
+	{name: "lfs.pushverifyonly"},
+	{name: "lfs.repositoryformatversion", kind: intValue},
+	{name: "lfs.securetransport"},
+	{name: "lfs.setlockablereadonly", kind: boolValue},
+	{name: "lfs.skipdownloaderrors", kind: boolValue},
+	{name: "lfs.ssh.authcache", kind: boolValue},
+	{name: "lfs.ssh.authshare", kind: boolValue},
+	{name: "lfs.ssh.automultiplex", kind: boolValue},
+	{name: "lfs.ssh.retries", kind: intValue},
+	{name: "lfs.storage"},
+	{name: "lfs.storage.backend", kind: enumValue, values: []string{"loose", "pack"}},
+	{name: "lfs.storage.maxsize", kind: sizeValue},
+	{name: "lfs.storage.overlay"},
+	{name: "lfs.storage.readonly", kind: boolValue},
+	{name: "lfs.summaryfile"},
+	{name: "lfs.symlinkpolicy", kind: enumValue, values: []string{SymlinkPolicySkip, SymlinkPolicyError, SymlinkPolicyFollow}},
+	{name: "lfs.tier.days", kind: intValue},
+	{name: "lfs.tier.path"},
+	{name: "lfs.tlstimeout", kind: intValue},
+	{name: "lfs.torrenttransfers", kind: boolValue},
+	{name: "lfs.transfer.adaptive", kind: boolValue},
+	{name: "lfs.transfer.batchsize", kind: intValue, min: 1},
+	{name: "lfs.transfer.claimtimeout", kind: intValue},
+	{name: "lfs.transfer.enablehrefrewrite", kind: boolValue},
+	{name: "lfs.transfer.maxretries", kind: intValue, min: 1},
+	{name: "lfs.transfer.maxretrydelay", kind: intValue},
+	{name: "lfs.transfer.maxverifies", kind: intValue, min: 1},
+	{name: "lfs.transfer.quarantinetimeout", kind: intValue},
+	{name: "lfs.transfer.senddigest", kind: boolValue},
+	{name: "lfs.transfer.uploadclaims", kind: boolValue},
+	{name: "lfs.treecache", kind: boolValue},
+	{name: "lfs.tus.chunksize", kind: sizeValue},
+	{name: "lfs.tustransfers", kind: boolValue},
+	{name: "lfs.upstreamremote"},
+	{name: "lfs.url"},
+	{name: "remote.*.lfspushurl"},
+	{name: "remote.*.lfsurl"},
+	{name: "remote.lfsdefault"},
+	{name: "remote.lfspushdefault"},
+}
+
+func init() {
+	for _, k := range urlKeys {
+		plain, forURL := *k, *k
+		plain.name = "lfs." + k.name
+		forURL.name = "lfs.*." + k.name
+		knownKeys = append(knownKeys, &plain, &forURL)
+	}
+}
+
+// matches returns whether the given key is this one.
+func (k *knownKey) matches(key string) bool {
+	i := strings.Index(k.name, "*")
+	if i < 0 {
+		return key == k.name
+	}
+	prefix, suffix := k.name[:i], k.name[i+1:]
+	return len(key) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(key, prefix) && strings.HasSuffix(key, suffix)
+}
+
+// check returns a description of what is wrong with the given value of this
+// key, or the empty string if there is nothing.
+func (k *knownKey) check(value string) string {
+	switch k.kind {
+	case boolValue:
+		switch strings.ToLower(value) {
+		case "", "true", "1", "on", "yes", "t", "false", "0", "off", "no", "f":
+			return ""
+		}
+		return "not a boolean"
+	case intValue:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "not an integer"
+		}
+		if n < k.min {
+			return fmt.Sprintf("must be at least %d", k.min)
+		}
+	case sizeValue:
+		if _, err := humanize.ParseBytes(value); err != nil {
+			return "not a size, such as \"64KiB\""
+		}
+	case enumValue:
+		if !k.allows(value) {
+			return "must be one of " + strings.Join(k.values, ", ")
+		}
+	case enumListValue:
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); len(v) > 0 && !k.allows(v) {
+				return fmt.Sprintf("%q is not one of %s", v, strings.Join(k.values, ", "))
+			}
+		}
+	}
+	return ""
+}
+
+func (k *knownKey) allows(value string) bool {
+	for _, v := range k.values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupKey(key string) *knownKey {
+	for _, k := range knownKeys {
+		if k.matches(key) {
+			return k
+		}
+	}
+	return nil
+}
+
+// maxSuggestDistance is the most edits by which an unknown key may differ
+// from a known one for the known one to be suggested in its place.
+const maxSuggestDistance = 2
+
+// suggestKey returns the known key which the given unknown key is most likely
+// a misspelling of, or the empty string if there is none.
+func suggestKey(key string) string {
+	best, bestDistance := "", maxSuggestDistance+1
+	for _, k := range knownKeys {
+		candidate := k.name
+		if i := strings.Index(k.name, "*"); i >= 0 {
+			// Keep the subsection of the unknown key, which is
+			// more likely a remote's name or a URL than a typo.
+			j := strings.LastIndex(key, ".")
+			if j <= i || !strings.HasPrefix(key, k.name[:i]) {
+				continue
+			}
+			candidate = key[:j] + k.name[i+1:]
+		}
+
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Problem is something wrong with a setting, as found by Validate.
+type Problem struct {
+	Setting *Setting
+	Message string
+}
+
+func (p *Problem) String() string {
+	if len(p.Setting.Origin) == 0 {
+		return fmt.Sprintf("%s: %s", p.Setting, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Setting.Origin, p.Setting, p.Message)
+}
+
+// Validate checks the given settings, in the order Settings returns them, for
+// those of Git LFS's keys which it does not know, such as misspelled ones,
+// values which it cannot use, and settings which contradict each other or are
+// ignored because of others.
+func Validate(settings []*Setting) []*Problem {
+	var problems []*Problem
+	report := func(s *Setting, format string, args ...interface{}) {
+		problems = append(problems, &Problem{Setting: s, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := make(map[string]*Setting)
+	for _, s := range settings {
+		if !IsLFSKey(s.Key) {
+			continue
+		}
+		if s.Ignored {
+			report(s, "ignored, as it may not be set in .lfsconfig")
+			continue
+		}
+
+		k := lookupKey(s.Key)
+		if k == nil {
+			if suggestion := suggestKey(s.Key); len(suggestion) > 0 {
+				report(s, "unknown key; did you mean %s?", suggestion)
+			} else {
+				report(s, "unknown key")
+			}
+			continue
+		}
+
+		if msg := k.check(s.Value); len(msg) > 0 {
+			report(s, "invalid value: %s", msg)
+		}
+
+		if prev, ok := seen[s.Key]; ok && !k.multi && prev.Origin == s.Origin && prev.Value != s.Value {
+			report(s, "overrides %s given earlier in the same file", prev)
+		}
+		seen[s.Key] = s
+	}
+
+	return append(problems, conflicts(EffectiveSettings(settings))...)
+}
+
+// conflicts returns the problems with the given effective settings which arise
+// from the combination of them.
+func conflicts(effective []*Setting) []*Problem {
+	byKey := make(map[string]*Setting, len(effective))
+	for _, s := range effective {
+		byKey[s.Key] = s
+	}
+	isTrue := func(key string) bool {
+		s, ok := byKey[key]
+		return ok && Bool(s.Value, false)
+	}
+
+	var problems []*Problem
+	report := func(s *Setting, format string, args ...interface{}) {
+		problems = append(problems, &Problem{Setting: s, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if isTrue("lfs.tustransfers") && isTrue("lfs.basictransfersonly") {
+		report(byKey["lfs.tustransfers"], "ignored, as lfs.basictransfersonly is set")
+	}
+	if isTrue("lfs.torrenttransfers") && isTrue("lfs.basictransfersonly") {
+		report(byKey["lfs.torrenttransfers"], "ignored, as lfs.basictransfersonly is set")
+	}
+
+	if s, ok := byKey["lfs.dnsdiscovery"]; ok && Bool(s.Value, false) {
+		if _, ok := byKey["lfs.url"]; ok {
+			report(s, "ignored, as lfs.url is set")
+		}
+	}
+
+	if s, ok := byKey["lfs.profile"]; ok && len(s.Value) > 0 {
+		prefix := "lfs.profile." + s.Value + "."
+		defined := false
+		for key := range byKey {
+			if strings.HasPrefix(key, prefix) {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			report(s, "no lfs.profile.%s.* settings define this profile", s.Value)
+		}
+	}
+
+	if s, ok := byKey["lfs.securetransport"]; ok && strings.EqualFold(s.Value, "strict") {
+		for _, key := range []string{"lfs.url", "lfs.pushurl"} {
+			if u, ok := byKey[key]; ok {
+				if parsed, err := url.Parse(u.Value); err == nil && parsed.Scheme == "http" {
+					report(u, "not HTTPS, as lfs.securetransport=strict requires")
+				}
+			}
+		}
+	}
+
+	for _, s := range effective {
+		k := lookupKey(s.Key)
+		if k == nil || !strings.HasPrefix(k.name, "lfs.customtransfer.*.") || strings.HasSuffix(s.Key, ".path") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(s.Key, "lfs.customtransfer."), k.name[len("lfs.customtransfer.*"):])
+		if _, ok := byKey["lfs.customtransfer."+name+".path"]; !ok {
+			report(s, "ignored, as lfs.customtransfer.%s.path is not set", name)
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validationMessages(settings ...*Setting) []string {
+	var messages []string
+	for _, p := range Validate(settings) {
+		messages = append(messages, p.String())
+	}
+	return messages
+}
+
+func TestValidateAcceptsKnownSettings(t *testing.T) {
+	assert.Empty(t, validationMessages(
+		&Setting{Key: "lfs.url", Value: "https://lfs.example.com", Origin: "file:.lfsconfig"},
+		&Setting{Key: "lfs.concurrenttransfers", Value: "16", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.https://lfs.example.com/info/lfs.access", Value: "basic", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.customtransfer.agent.path", Value: "agent", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.customtransfer.agent.direction", Value: "Download", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.pointermetadata", Value: "content-type, mtime", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.pack.maxobjectsize", Value: "64KiB", Origin: "file:.git/config"},
+		&Setting{Key: "remote.origin.lfsurl", Value: "https://lfs.example.com", Origin: "file:.git/config"},
+		&Setting{Key: "core.bare", Value: "false", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.maintenance.repo", Value: "/a", Origin: "file:/home/u/.gitconfig"},
+		&Setting{Key: "lfs.maintenance.repo", Value: "/b", Origin: "file:/home/u/.gitconfig"},
+	))
+}
+
+func TestValidateUnknownKeys(t *testing.T) {
+	assert.Equal(t, []string{
+		"file:.git/config: lfs.concurenttransfers=4: unknown key; did you mean lfs.concurrenttransfers?",
+		"file:.git/config: lfs.https://lfs.example.com.acess=basic: unknown key; did you mean lfs.https://lfs.example.com.access?",
+		"file:.git/config: remote.origin.lfsurll=x: unknown key; did you mean remote.origin.lfsurl?",
+		"file:.git/config: lfs.nonsense=1: unknown key",
+	}, validationMessages(
+		&Setting{Key: "lfs.concurenttransfers", Value: "4", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.https://lfs.example.com.acess", Value: "basic", Origin: "file:.git/config"},
+		&Setting{Key: "remote.origin.lfsurll", Value: "x", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.nonsense", Value: "1", Origin: "file:.git/config"},
+	))
+}
+
+func TestValidateValues(t *testing.T) {
+	assert.Equal(t, []string{
+		"lfs.concurrenttransfers=abc: invalid value: not an integer",
+		"lfs.transfer.maxretries=0: invalid value: must be at least 1",
+		"lfs.tustransfers=maybe: invalid value: not a boolean",
+		"lfs.storage.backend=packed: invalid value: must be one of loose, pack",
+		"lfs.pointermetadata=mtime,size: invalid value: \"size\" is not one of content-type, executable, mtime",
+		"lfs.pack.maxobjectsize=big: invalid value: not a size, such as \"64KiB\"",
+	}, validationMessages(
+		&Setting{Key: "lfs.concurrenttransfers", Value: "abc"},
+		&Setting{Key: "lfs.transfer.maxretries", Value: "0"},
+		&Setting{Key: "lfs.tustransfers", Value: "maybe"},
+		&Setting{Key: "lfs.storage.backend", Value: "packed"},
+		&Setting{Key: "lfs.pointermetadata", Value: "mtime,size"},
+		&Setting{Key: "lfs.pack.maxobjectsize", Value: "big"},
+	))
+}
+
+func TestValidateIgnoredAndOverridden(t *testing.T) {
+	assert.Equal(t, []string{
+		"file:.lfsconfig: lfs.dialtimeout=3: ignored, as it may not be set in .lfsconfig",
+		"file:.git/config: lfs.url=https://b: overrides lfs.url=https://a given earlier in the same file",
+	}, validationMessages(
+		&Setting{Key: "lfs.dialtimeout", Value: "3", Origin: "file:.lfsconfig", Ignored: true},
+		&Setting{Key: "lfs.url", Value: "https://a", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.url", Value: "https://c", Origin: "file:/home/u/.gitconfig"},
+		&Setting{Key: "lfs.url", Value: "https://a", Origin: "file:.git/config"},
+		&Setting{Key: "lfs.url", Value: "https://b", Origin: "file:.git/config"},
+	))
+}
+
+func TestValidateConflicts(t *testing.T) {
+	assert.Equal(t, []string{
+		"lfs.tustransfers=true: ignored, as lfs.basictransfersonly is set",
+		"lfs.dnsdiscovery=true: ignored, as lfs.url is set",
+		"lfs.profile=art: no lfs.profile.art.* settings define this profile",
+		"lfs.url=http://lfs.example.com: not HTTPS, as lfs.securetransport=strict requires",
+		"lfs.customtransfer.agent.args=-v: ignored, as lfs.customtransfer.agent.path is not set",
+	}, validationMessages(
+		&Setting{Key: "lfs.basictransfersonly", Value: "true"},
+		&Setting{Key: "lfs.tustransfers", Value: "true"},
+		&Setting{Key: "lfs.dnsdiscovery", Value: "true"},
+		&Setting{Key: "lfs.url", Value: "http://lfs.example.com"},
+		&Setting{Key: "lfs.profile", Value: "art"},
+		&Setting{Key: "lfs.profile.full.fetchinclude", Value: ""},
+		&Setting{Key: "lfs.securetransport", Value: "strict"},
+		&Setting{Key: "lfs.customtransfer.agent.args", Value: "-v"},
+	))
+}
+
+func TestEffectiveSettings(t *testing.T) {
+	effective := EffectiveSettings([]*Setting{
+		{Key: "lfs.url", Value: "https://a", Origin: "file:.lfsconfig"},
+		{Key: "lfs.fetchinclude", Value: "a", Origin: "file:.lfsconfig"},
+		{Key: "lfs.url", Value: "https://b", Origin: "file:.git/config"},
+		{Key: "lfs.fetchinclude", Value: "b", Origin: "file:.lfsconfig.extra", Ignored: true},
+	})
+
+	assert.Equal(t, []*Setting{
+		{Key: "lfs.fetchinclude", Value: "a", Origin: "file:.lfsconfig"},
+		{Key: "lfs.url", Value: "https://b", Origin: "file:.git/config"},
+	}, effective)
+}
+
+func TestIsLFSKey(t *testing.T) {
+	assert.True(t, IsLFSKey("lfs.url"))
+	assert.True(t, IsLFSKey("remote.origin.lfsurl"))
+	assert.True(t, IsLFSKey("remote.lfsdefault"))
+	assert.False(t, IsLFSKey("remote.origin.url"))
+	assert.False(t, IsLFSKey("core.bare"))
+}
@@ -71,11 +71,17 @@ type CredentialHelperContext struct {
 	askpassCredHelper *AskPassCredentialHelper
 	cachingCredHelper *credentialCacher
 
+	lfsCredHelpers map[string]*lfsCredentialHelper
+	mu             sync.Mutex
+
 	urlConfig *config.URLConfig
 }
 
 func NewCredentialHelperContext(gitEnv config.Environment, osEnv config.Environment) *CredentialHelperContext {
-	c := &CredentialHelperContext{urlConfig: config.NewURLConfig(gitEnv)}
+	c := &CredentialHelperContext{
+		lfsCredHelpers: make(map[string]*lfsCredentialHelper),
+		urlConfig:      config.NewURLConfig(gitEnv),
+	}
 
 	c.netrcCredHelper = newNetrcCredentialHelper(osEnv)
 
@@ -102,7 +108,8 @@ func NewCredentialHelperContext(gitEnv config.Environment, osEnv config.Environm
 	}
 
 	c.commandCredHelper = &commandCredentialHelper{
-		SkipPrompt: osEnv.Bool("GIT_TERMINAL_PROMPT", false),
+		SkipPrompt:     osEnv.Bool("GIT_TERMINAL_PROMPT", false),
+		NonInteractive: config.IsCI(gitEnv, osEnv),
 	}
 
 	return c
@@ -127,9 +134,12 @@ func (ctxt *CredentialHelperContext) GetCredentialHelper(helper CredentialHelper
 		return CredentialHelperWrapper{CredentialHelper: helper, Input: input, Url: u}
 	}
 
-	helpers := make([]CredentialHelper, 0, 4)
+	helpers := make([]CredentialHelper, 0, 5)
+	if program, ok := ctxt.urlConfig.Get("lfs", rawurl, "credentialhelper"); ok && len(program) > 0 {
+		helpers = append(helpers, ctxt.lfsCredentialHelper(program))
+	}
 	if ctxt.netrcCredHelper != nil {
-		helpers = append(helpers, ctxt.netrcCredHelper)
+		helpers = append(helpers, ctxt.netrcCredHelper.forPath(u.Path))
 	}
 	if ctxt.cachingCredHelper != nil {
 		helpers = append(helpers, ctxt.cachingCredHelper)
@@ -143,6 +153,21 @@ func (ctxt *CredentialHelperContext) GetCredentialHelper(helper CredentialHelper
 	return CredentialHelperWrapper{CredentialHelper: NewCredentialHelpers(append(helpers, ctxt.commandCredHelper)), Input: input, Url: u}
 }
 
+// lfsCredentialHelper returns the LFS credential helper running the given
+// program, shared by every URL configured to use it so that credentials it
+// fills are reused until they expire.
+func (ctxt *CredentialHelperContext) lfsCredentialHelper(program string) *lfsCredentialHelper {
+	ctxt.mu.Lock()
+	defer ctxt.mu.Unlock()
+
+	h, ok := ctxt.lfsCredHelpers[program]
+	if !ok {
+		h = newLFSCredentialHelper(program)
+		ctxt.lfsCredHelpers[program] = h
+	}
+	return h
+}
+
 // AskPassCredentialHelper implements the CredentialHelper type for GIT_ASKPASS
 // and 'core.askpass' configuration values.
 type AskPassCredentialHelper struct {
@@ -276,6 +301,9 @@ func (a *AskPassCredentialHelper) args(prompt string) []string {
 
 type commandCredentialHelper struct {
 	SkipPrompt bool
+	// NonInteractive prevents Git from prompting for credentials on the
+	// terminal, as in CI mode, where there is nobody to answer.
+	NonInteractive bool
 }
 
 func (h *commandCredentialHelper) Fill(creds Creds) (Creds, error) {
@@ -301,6 +329,11 @@ func (h *commandCredentialHelper) exec(subcommand string, input Creds) (Creds, e
 	cmd := subprocess.ExecCommand("git", "credential", subcommand)
 	cmd.Stdin = bufferCreds(input)
 	cmd.Stdout = output
+	if h.NonInteractive {
+		// Limit the capacity so the shared environment isn't modified.
+		env := cmd.Env[:len(cmd.Env):len(cmd.Env)]
+		cmd.Env = append(env, "GIT_TERMINAL_PROMPT=0")
+	}
 	/*
 	   There is a reason we don't read from stderr here:
 	   Git's credential cache daemon helper does not close its stderr, so if this
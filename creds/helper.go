@@ -0,0 +1,169 @@
+package creds
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/rubyist/tracerx"
+)
+
+// lfsHelperExpiryMargin is how long before their expiry credentials returned
+// by an LFS credential helper are no longer reused, so that they do not expire
+// during a request.
+const lfsHelperExpiryMargin = 30 * time.Second
+
+// lfsCredentialHelper implements the CredentialHelper type for programs given
+// by the 'lfs.credentialhelper' configuration value. These are consulted only
+// by Git LFS, and never by Git itself.
+//
+// The program is run with "get", "store", or "erase" as its argument, and
+// speaks the same protocol as a Git credential helper, extended so that it may
+// return an "authtype" and "credential" (such as "Bearer" and a token) in place
+// of a username and password, and a "password_expiry_utc" time, in seconds
+// since the epoch, after which the credentials are asked for again.
+type lfsCredentialHelper struct {
+	// Program is the command run, by the shell, to fill credentials.
+	Program string
+
+	now    func() time.Time
+	filled map[string]Creds
+	mu     sync.Mutex
+}
+
+func newLFSCredentialHelper(program string) *lfsCredentialHelper {
+	return &lfsCredentialHelper{
+		Program: program,
+		now:     time.Now,
+		filled:  make(map[string]Creds),
+	}
+}
+
+// Fill implements CredentialHelper.Fill by returning the unexpired credentials
+// the program last filled for the same URL, or otherwise by running the
+// program with "get". If the program returns nothing, the next credential
+// helper is asked instead.
+func (h *lfsCredentialHelper) Fill(what Creds) (Creds, error) {
+	key := credCacheKey(what)
+
+	h.mu.Lock()
+	cached, ok := h.filled[key]
+	h.mu.Unlock()
+	if ok && !h.expired(cached) {
+		tracerx.Printf("creds: lfs credential helper cache (%q, %q, %q)",
+			what["protocol"], what["host"], what["path"])
+		return cached, nil
+	}
+
+	tracerx.Printf("creds: lfs credential helper get (%q, %q, %q)",
+		what["protocol"], what["host"], what["path"])
+	creds, err := h.exec("get", what)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds["password"]) == 0 && len(creds["credential"]) == 0 {
+		return nil, credHelperNoOp
+	}
+	if h.expired(creds) {
+		return nil, errors.Errorf("lfs credential helper %q returned expired credentials for %s://%s",
+			h.Program, what["protocol"], what["host"])
+	}
+
+	for k, v := range what {
+		if _, ok := creds[k]; !ok {
+			creds[k] = v
+		}
+	}
+
+	h.mu.Lock()
+	h.filled[key] = creds
+	h.mu.Unlock()
+	return creds, nil
+}
+
+// Approve implements CredentialHelper.Approve by running the program with
+// "store", if it filled the given credentials. Otherwise the next credential
+// helper is asked instead.
+func (h *lfsCredentialHelper) Approve(creds Creds) error {
+	if !h.owns(creds) {
+		return credHelperNoOp
+	}
+	_, err := h.exec("store", creds)
+	return err
+}
+
+// Reject implements CredentialHelper.Reject by forgetting the given
+// credentials and running the program with "erase", if it filled them.
+// Otherwise the next credential helper is asked instead.
+func (h *lfsCredentialHelper) Reject(creds Creds) error {
+	if !h.owns(creds) {
+		return credHelperNoOp
+	}
+
+	h.mu.Lock()
+	delete(h.filled, credCacheKey(creds))
+	h.mu.Unlock()
+
+	_, err := h.exec("erase", creds)
+	return err
+}
+
+// owns returns whether the given credentials were filled by this helper.
+func (h *lfsCredentialHelper) owns(creds Creds) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	filled, ok := h.filled[credCacheKey(creds)]
+	return ok && filled["password"] == creds["password"] &&
+		filled["credential"] == creds["credential"]
+}
+
+// expired returns whether the given credentials have expired, or will do so
+// within lfsHelperExpiryMargin.
+func (h *lfsCredentialHelper) expired(creds Creds) bool {
+	raw, ok := creds["password_expiry_utc"]
+	if !ok {
+		return false
+	}
+	expiry, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		tracerx.Printf("creds: ignoring invalid password_expiry_utc %q", raw)
+		return false
+	}
+	return !h.now().Add(lfsHelperExpiryMargin).Before(time.Unix(expiry, 0))
+}
+
+func (h *lfsCredentialHelper) exec(action string, input Creds) (Creds, error) {
+	in := make(Creds, len(input)+1)
+	for k, v := range input {
+		in[k] = v
+	}
+	in["capability[]"] = "authtype"
+
+	var output bytes.Buffer
+	name, args := subprocess.FormatForShell(h.Program, action)
+	cmd := subprocess.ExecCommand(name, args...)
+	cmd.Stdin = bufferCreds(in)
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("lfs credential helper %q %s error: %s", h.Program, action, err)
+	}
+
+	creds := make(Creds)
+	for _, line := range strings.Split(output.String(), "\n") {
+		pieces := strings.SplitN(strings.TrimRight(line, "\r"), "=", 2)
+		if len(pieces) < 2 || len(pieces[1]) < 1 {
+			continue
+		}
+		creds[pieces[0]] = pieces[1]
+	}
+	return creds, nil
+}
@@ -0,0 +1,96 @@
+package creds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLFSCredHelper returns an LFS credential helper running a script which
+// logs each action it is run with, and answers "get" with the given output.
+// The caller removes the returned directory.
+func newTestLFSCredHelper(t *testing.T, output string) (*lfsCredentialHelper, string, string) {
+	dir, err := ioutil.TempDir("", "lfs-credential-helper")
+	require.Nil(t, err)
+
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "helper.sh")
+	content := fmt.Sprintf("#!/bin/sh\ncat >/dev/null\necho \"$1\" >>%q\nif [ \"$1\" = get ]; then\nprintf %q\nfi\n", log, output)
+	require.Nil(t, ioutil.WriteFile(script, []byte(content), 0755))
+
+	return newLFSCredentialHelper(script), dir, log
+}
+
+func readHelperLog(t *testing.T, log string) []string {
+	data, err := ioutil.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.Nil(t, err)
+	return strings.Fields(string(data))
+}
+
+func TestLFSCredHelperFillBearer(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Unix()
+	helper, dir, log := newTestLFSCredHelper(t, fmt.Sprintf("authtype=Bearer\ncredential=token\npassword_expiry_utc=%d\n", expiry))
+	defer os.RemoveAll(dir)
+	input := Creds{"protocol": "https", "host": "example.com"}
+
+	creds, err := helper.Fill(input)
+	require.Nil(t, err)
+	assert.Equal(t, "Bearer", creds["authtype"])
+	assert.Equal(t, "token", creds["credential"])
+	assert.Equal(t, "example.com", creds["host"])
+
+	// Unexpired credentials are reused without running the helper again.
+	_, err = helper.Fill(input)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"get"}, readHelperLog(t, log))
+
+	// Expired credentials are filled again, and an error is returned if the
+	// helper returns expired credentials.
+	helper.now = func() time.Time { return time.Unix(expiry, 0) }
+	_, err = helper.Fill(input)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "expired credentials")
+	}
+	assert.Equal(t, []string{"get", "get"}, readHelperLog(t, log))
+}
+
+func TestLFSCredHelperFillEmpty(t *testing.T) {
+	helper, dir, _ := newTestLFSCredHelper(t, "")
+	defer os.RemoveAll(dir)
+
+	creds, err := helper.Fill(Creds{"protocol": "https", "host": "example.com"})
+	assert.Nil(t, creds)
+	assert.Equal(t, credHelperNoOp, err)
+}
+
+func TestLFSCredHelperApproveAndReject(t *testing.T) {
+	helper, dir, log := newTestLFSCredHelper(t, "username=user\npassword=pass\n")
+	defer os.RemoveAll(dir)
+	input := Creds{"protocol": "https", "host": "example.com"}
+
+	// Credentials filled by another helper are passed on.
+	other := Creds{"protocol": "https", "host": "example.com", "username": "user", "password": "other"}
+	assert.Equal(t, credHelperNoOp, helper.Approve(other))
+	assert.Equal(t, credHelperNoOp, helper.Reject(other))
+
+	creds, err := helper.Fill(input)
+	require.Nil(t, err)
+	assert.Nil(t, helper.Approve(creds))
+	assert.Nil(t, helper.Reject(creds))
+	assert.Equal(t, []string{"get", "store", "erase"}, readHelperLog(t, log))
+
+	// Rejected credentials are filled again.
+	_, err = helper.Fill(input)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"get", "store", "erase", "get"}, readHelperLog(t, log))
+}
@@ -1,6 +1,9 @@
 package creds
 
 import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
@@ -27,8 +30,85 @@ func ParseNetrc(osEnv config.Environment) (NetrcFinder, string, error) {
 		return &noFinder{}, nrcfilename, nil
 	}
 
-	f, err := netrc.ParseFile(nrcfilename)
-	return f, nrcfilename, err
+	data, err := ioutil.ReadFile(nrcfilename)
+	if err != nil {
+		return nil, nrcfilename, err
+	}
+	f, err := netrc.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nrcfilename, err
+	}
+	return &netrcFile{Netrc: f, tokens: parseNetrcTokens(data)}, nrcfilename, nil
+}
+
+// netrcFile is a parsed .netrc file, along with the "token" of each machine
+// that has one, which the netrc package does not understand.
+type netrcFile struct {
+	*netrc.Netrc
+	tokens map[string]string
+}
+
+// FindToken returns the token of the machine with the given name, or of the
+// default machine if the name is empty.
+func (f *netrcFile) FindToken(name string) string {
+	return f.tokens[name]
+}
+
+// netrcTokenFinder is implemented by a NetrcFinder which knows the tokens of
+// its machines.
+type netrcTokenFinder interface {
+	FindToken(string) string
+}
+
+// parseNetrcTokens returns the "token" of each machine in the given .netrc
+// file contents which has one, keyed by machine name, or by the empty string
+// for the default machine.
+func parseNetrcTokens(data []byte) map[string]string {
+	tokens := make(map[string]string)
+
+	var words []string
+	inMacro := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inMacro {
+			// A macro definition ends at the first empty line.
+			inMacro = len(line) > 0
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, word := range strings.Fields(line) {
+			words = append(words, word)
+			if word == "macdef" {
+				inMacro = true
+			}
+		}
+	}
+
+	var machine *string
+	for i := 0; i < len(words); i++ {
+		switch words[i] {
+		case "default":
+			name := ""
+			machine = &name
+		case "machine", "token":
+			if i+1 >= len(words) {
+				break
+			}
+			i++
+			if words[i-1] == "machine" {
+				name := words[i]
+				machine = &name
+			} else if machine != nil {
+				tokens[*machine] = words[i]
+			}
+		case "macdef":
+			machine = nil
+		}
+	}
+	return tokens
 }
 
 type noFinder struct{}
@@ -62,33 +142,109 @@ func newNetrcCredentialHelper(osEnv config.Environment) *netrcCredentialHelper {
 	return &netrcCredentialHelper{netrcFinder: netrcFinder, skip: make(map[string]bool)}
 }
 
+// forPath returns a credential helper which also matches the machines of the
+// .netrc file named by full URLs against the given URL path.
+func (c *netrcCredentialHelper) forPath(path string) CredentialHelper {
+	return &netrcPathCredentialHelper{netrcCredentialHelper: c, path: path}
+}
+
 func (c *netrcCredentialHelper) Fill(what Creds) (Creds, error) {
+	return c.fill(what, what["path"])
+}
+
+// fill returns the credentials of the most specific machine matching the
+// given credentials and URL path. Machines named by a URL, such as
+// "https://example.com/org", match URLs with the same scheme, host, and port,
+// and a path beginning with theirs; they are preferred over machines named by
+// the host alone.
+//
+// A machine with a "token" but no "password" is used for Bearer
+// authentication with the token.
+func (c *netrcCredentialHelper) fill(what Creds, path string) (Creds, error) {
 	host, err := getNetrcHostname(what["host"])
 	if err != nil {
 		return nil, credHelperNoOp
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.skip[host] {
-		return nil, credHelperNoOp
-	}
-	if machine := c.netrcFinder.FindMachine(host); machine != nil {
+
+	for _, name := range append(netrcURLNames(what, path), host) {
+		machine := c.netrcFinder.FindMachine(name)
+		if machine == nil || (machine.IsDefault() && name != host) {
+			continue
+		}
+
+		key := machine.Name
+		if machine.IsDefault() {
+			key = host
+		}
+		if c.skip[key] {
+			continue
+		}
+
 		creds := make(Creds)
-		creds["username"] = machine.Login
-		creds["password"] = machine.Password
 		creds["protocol"] = what["protocol"]
 		creds["host"] = what["host"]
 		creds["scheme"] = what["scheme"]
 		creds["path"] = what["path"]
 		creds["source"] = "netrc"
-		tracerx.Printf("netrc: git credential fill (%q, %q, %q)",
-			what["protocol"], what["host"], what["path"])
+		creds["netrc_machine"] = key
+
+		var token string
+		if finder, ok := c.netrcFinder.(netrcTokenFinder); ok {
+			token = finder.FindToken(machine.Name)
+		}
+		if len(machine.Password) == 0 && len(token) > 0 {
+			creds["authtype"] = "Bearer"
+			creds["credential"] = token
+		} else {
+			creds["username"] = machine.Login
+			creds["password"] = machine.Password
+		}
+
+		tracerx.Printf("netrc: git credential fill (%q, %q, %q) from machine %q",
+			what["protocol"], what["host"], what["path"], machine.Name)
 		return creds, nil
 	}
 
 	return nil, credHelperNoOp
 }
 
+// netrcURLNames returns the names by which machines may match the given
+// credentials and URL path as URLs, from the most specific to the least.
+func netrcURLNames(what Creds, path string) []string {
+	if len(what["protocol"]) == 0 || len(what["host"]) == 0 {
+		return nil
+	}
+
+	base := what["protocol"] + "://" + what["host"]
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) > 0 {
+			segments = append(segments, segment)
+		}
+	}
+
+	names := make([]string, 0, len(segments)+1)
+	for i := len(segments); i > 0; i-- {
+		name := base + "/" + strings.Join(segments[:i], "/")
+		names = append(names, name, name+"/")
+	}
+	return append(names, base, base+"/")
+}
+
+// netrcPathCredentialHelper is a netrcCredentialHelper matching against the
+// path of a particular URL, even if the path is not given to credential
+// helpers.
+type netrcPathCredentialHelper struct {
+	*netrcCredentialHelper
+	path string
+}
+
+func (c *netrcPathCredentialHelper) Fill(what Creds) (Creds, error) {
+	return c.fill(what, c.path)
+}
+
 func getNetrcHostname(hostname string) (string, error) {
 	if strings.Contains(hostname, ":") {
 		host, _, err := net.SplitHostPort(hostname)
@@ -102,16 +258,25 @@ func getNetrcHostname(hostname string) (string, error) {
 	return hostname, nil
 }
 
+// netrcSkipKey returns the key under which the machine that filled the given
+// credentials is skipped once they have been rejected.
+func netrcSkipKey(what Creds) (string, error) {
+	if key, ok := what["netrc_machine"]; ok {
+		return key, nil
+	}
+	return getNetrcHostname(what["host"])
+}
+
 func (c *netrcCredentialHelper) Approve(what Creds) error {
 	if what["source"] == "netrc" {
-		host, err := getNetrcHostname(what["host"])
+		key, err := netrcSkipKey(what)
 		if err != nil {
 			return credHelperNoOp
 		}
 		tracerx.Printf("netrc: git credential approve (%q, %q, %q)",
 			what["protocol"], what["host"], what["path"])
 		c.mu.Lock()
-		c.skip[host] = false
+		c.skip[key] = false
 		c.mu.Unlock()
 		return nil
 	}
@@ -120,7 +285,7 @@ func (c *netrcCredentialHelper) Approve(what Creds) error {
 
 func (c *netrcCredentialHelper) Reject(what Creds) error {
 	if what["source"] == "netrc" {
-		host, err := getNetrcHostname(what["host"])
+		key, err := netrcSkipKey(what)
 		if err != nil {
 			return credHelperNoOp
 		}
@@ -128,7 +293,7 @@ func (c *netrcCredentialHelper) Reject(what Creds) error {
 		tracerx.Printf("netrc: git credential reject (%q, %q, %q)",
 			what["protocol"], what["host"], what["path"])
 		c.mu.Lock()
-		c.skip[host] = true
+		c.skip[key] = true
 		c.mu.Unlock()
 		return nil
 	}
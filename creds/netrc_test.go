@@ -80,3 +80,96 @@ func (n *fakeNetrc) FindMachine(host string) *netrc.Machine {
 	}
 	return nil
 }
+
+const testNetrcURLs = `# multi-tenant host
+machine lfs.example.com login host password hostpass
+machine https://lfs.example.com/org/repo login repo password repopass
+machine https://lfs.example.com:8443/org
+  login token-user
+  token orgtoken
+default login anonymous password anonpass
+`
+
+func newTestNetrcURLHelper(t *testing.T) *netrcCredentialHelper {
+	f, err := netrc.Parse(strings.NewReader(testNetrcURLs))
+	if err != nil {
+		t.Fatalf("error parsing netrc: %s", err)
+	}
+	return &netrcCredentialHelper{
+		netrcFinder: &netrcFile{Netrc: f, tokens: parseNetrcTokens([]byte(testNetrcURLs))},
+		skip:        make(map[string]bool),
+	}
+}
+
+func TestNetrcWithURLs(t *testing.T) {
+	netrcHelper := newTestNetrcURLHelper(t)
+
+	tests := []struct {
+		host, path       string
+		username, bearer string
+	}{
+		{"lfs.example.com", "/org/repo.git/info/lfs", "host", ""},
+		{"lfs.example.com", "/org/repo/info/lfs", "repo", ""},
+		{"lfs.example.com", "/org/repository", "host", ""},
+		{"lfs.example.com:8443", "/org/other/info/lfs", "", "orgtoken"},
+		{"lfs.example.com:8443", "/elsewhere", "host", ""},
+		{"other.example.com", "/org/repo/info/lfs", "anonymous", ""},
+	}
+
+	for _, test := range tests {
+		what := Creds{"protocol": "https", "host": test.host}
+		creds, err := netrcHelper.forPath(test.path).Fill(what)
+		if err != nil {
+			t.Fatalf("%s%s: error retrieving netrc credentials: %s", test.host, test.path, err)
+		}
+		if creds["username"] != test.username {
+			t.Errorf("%s%s: bad username: %q", test.host, test.path, creds["username"])
+		}
+		if creds["credential"] != test.bearer {
+			t.Errorf("%s%s: bad token: %q", test.host, test.path, creds["credential"])
+		}
+		if len(test.bearer) > 0 && creds["authtype"] != "Bearer" {
+			t.Errorf("%s%s: bad authtype: %q", test.host, test.path, creds["authtype"])
+		}
+	}
+}
+
+func TestNetrcRejectURLFallsBackToHost(t *testing.T) {
+	netrcHelper := newTestNetrcURLHelper(t)
+	helper := netrcHelper.forPath("/org/repo/info/lfs")
+	what := Creds{"protocol": "https", "host": "lfs.example.com"}
+
+	creds, err := helper.Fill(what)
+	if err != nil || creds["username"] != "repo" {
+		t.Fatalf("expected credentials for repo, got %v (%v)", creds, err)
+	}
+	if err := helper.Reject(creds); err != nil {
+		t.Fatalf("error rejecting credentials: %s", err)
+	}
+
+	creds, err = helper.Fill(what)
+	if err != nil || creds["username"] != "host" {
+		t.Fatalf("expected credentials for host, got %v (%v)", creds, err)
+	}
+}
+
+func TestParseNetrcTokens(t *testing.T) {
+	tokens := parseNetrcTokens([]byte(`machine a login x token atoken
+macdef init
+machine b token ignored
+
+machine c
+token ctoken
+default token deftoken
+`))
+
+	expected := map[string]string{"a": "atoken", "c": "ctoken", "": "deftoken"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("bad tokens: %v", tokens)
+	}
+	for name, token := range expected {
+		if tokens[name] != token {
+			t.Errorf("bad token for %q: %q", name, tokens[name])
+		}
+	}
+}
@@ -72,14 +72,22 @@ func main() {
 	// man links
 	manlinkregex := regexp.MustCompile(`(git)(?:-(lfs))?-([a-z\-]+)\(\d\)`)
 	count := 0
+	seen := make(map[string]bool)
 	for _, f := range fs {
 		if match := fileregex.FindStringSubmatch(f.Name()); match != nil {
-			infof(os.Stderr, "%v\n", f.Name())
 			cmd := match[1]
 			if len(cmd) == 0 {
 				// This is git-lfs.1.ronn
 				cmd = "git-lfs"
 			}
+			if seen[cmd] {
+				// The files are sorted, so a command's page in
+				// section 1 comes before any of the same name in
+				// other sections, such as git-lfs-config.5.ronn.
+				continue
+			}
+			seen[cmd] = true
+			infof(os.Stderr, "%v\n", f.Name())
 			out.WriteString("\tManPages[\"" + cmd + "\"] = `")
 			contentf, err := os.Open(filepath.Join(manDir, f.Name()))
 			if err != nil {
@@ -103,6 +103,34 @@ func IsPointerScanError(err error) bool {
 	return false
 }
 
+// IsPointerSizeError indicates that a blob is too large to be an LFS pointer,
+// and so its contents were not read.
+func IsPointerSizeError(err error) bool {
+	if e, ok := err.(interface {
+		PointerSizeError() bool
+	}); ok {
+		return e.PointerSizeError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsPointerSizeError(parent)
+	}
+	return false
+}
+
+// IsSymlinkPointerError indicates that an LFS pointer is stored as the target
+// of a symbolic link, rather than as the contents of a file.
+func IsSymlinkPointerError(err error) bool {
+	if e, ok := err.(interface {
+		SymlinkPointerError() bool
+	}); ok {
+		return e.SymlinkPointerError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsSymlinkPointerError(parent)
+	}
+	return false
+}
+
 // IsBadPointerKeyError indicates that the parsed data has an invalid key.
 func IsBadPointerKeyError(err error) bool {
 	if e, ok := err.(interface {
@@ -170,6 +198,34 @@ func IsUnprocessableEntityError(err error) bool {
 	return false
 }
 
+// IsRequestEntityTooLargeError indicates that a request failed because of an
+// HTTP 413 response code.
+func IsRequestEntityTooLargeError(err error) bool {
+	if e, ok := err.(interface {
+		RequestEntityTooLargeError() bool
+	}); ok {
+		return e.RequestEntityTooLargeError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsRequestEntityTooLargeError(parent)
+	}
+	return false
+}
+
+// IsOfflineError indicates that an operation was not attempted because it
+// would have used the network while Git LFS is offline.
+func IsOfflineError(err error) bool {
+	if e, ok := err.(interface {
+		OfflineError() bool
+	}); ok {
+		return e.OfflineError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsOfflineError(parent)
+	}
+	return false
+}
+
 // IsRetriableError indicates the low level transfer had an error but the
 // caller may retry the operation.
 func IsRetriableError(err error) bool {
@@ -346,6 +402,40 @@ func NewNotAPointerError(err error) error {
 	return notAPointerError{newWrappedError(err, "Pointer file error")}
 }
 
+// Definitions for IsPointerSizeError()
+
+type pointerSizeError struct {
+	*wrappedError
+}
+
+func (e pointerSizeError) PointerSizeError() bool {
+	return true
+}
+
+func (e pointerSizeError) NotAPointerError() bool {
+	return true
+}
+
+func NewPointerSizeError(size, limit int64) error {
+	err := Errorf("blob of %d bytes is too large to be a pointer (limit %d bytes)", size, limit)
+	return pointerSizeError{newWrappedError(err, "Pointer file error")}
+}
+
+// Definitions for IsSymlinkPointerError()
+
+type symlinkPointerError struct {
+	*wrappedError
+}
+
+func (e symlinkPointerError) SymlinkPointerError() bool {
+	return true
+}
+
+func NewSymlinkPointerError(oid string) error {
+	err := Errorf("symbolic link target is a pointer to %s", oid)
+	return symlinkPointerError{newWrappedError(err, "Pointer file error")}
+}
+
 // Definitions for IsPointerScanError()
 
 type PointerScanError struct {
@@ -446,6 +536,34 @@ func NewUnprocessableEntityError(err error) error {
 	return unprocessableEntityError{newWrappedError(err, "")}
 }
 
+// Definitions for IsRequestEntityTooLargeError()
+
+type requestEntityTooLargeError struct {
+	*wrappedError
+}
+
+func (e requestEntityTooLargeError) RequestEntityTooLargeError() bool {
+	return true
+}
+
+func NewRequestEntityTooLargeError(err error) error {
+	return requestEntityTooLargeError{newWrappedError(err, "")}
+}
+
+// Definitions for IsOfflineError()
+
+type offlineError struct {
+	*wrappedError
+}
+
+func (e offlineError) OfflineError() bool {
+	return true
+}
+
+func NewOfflineError(err error) error {
+	return offlineError{newWrappedError(err, "")}
+}
+
 // Definitions for IsRetriableError()
 
 type retriableError struct {
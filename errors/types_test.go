@@ -30,6 +30,32 @@ func (e TimeoutError) Timeout() bool {
 	return true
 }
 
+func TestPointerSizeErrorIsNotAPointerError(t *testing.T) {
+	err := errors.NewPointerScanError(errors.NewPointerSizeError(2048, 1024), "deadbeef", "a.dat")
+
+	assert.True(t, errors.IsPointerScanError(err))
+	assert.True(t, errors.IsPointerSizeError(err))
+	assert.True(t, errors.IsNotAPointerError(err))
+	assert.False(t, errors.IsPointerSizeError(errors.NewNotAPointerError(nil)))
+}
+
+func TestSymlinkPointerError(t *testing.T) {
+	err := errors.NewPointerScanError(errors.NewSymlinkPointerError("abc"), "deadbeef", "a.dat")
+
+	assert.True(t, errors.IsPointerScanError(err))
+	assert.True(t, errors.IsSymlinkPointerError(err))
+	assert.False(t, errors.IsNotAPointerError(err))
+	assert.False(t, errors.IsPointerSizeError(err))
+}
+
+func TestOfflineError(t *testing.T) {
+	err := errors.Wrap(errors.NewOfflineError(errors.New("offline")), "batch")
+
+	assert.True(t, errors.IsOfflineError(err))
+	assert.False(t, errors.IsRetriableError(err))
+	assert.False(t, errors.IsOfflineError(errors.New("offline")))
+}
+
 func TestCanRetryOnTemporaryError(t *testing.T) {
 	err := &url.Error{Err: TemporaryError{}}
 	assert.True(t, errors.IsRetriableError(err))
@@ -73,13 +73,7 @@ func (f *Filter) Allows(filename string) bool {
 		return true
 	}
 
-	var included bool
-	for _, inc := range f.include {
-		if included = inc.Match(filename); included {
-			break
-		}
-	}
-
+	included, _ := match(f.include, filename)
 	if !included && len(f.include) > 0 {
 		tracerx.Printf("filepathfilter: rejecting %q via %v", filename, f.include)
 		return false
@@ -94,11 +88,9 @@ func (f *Filter) Allows(filename string) bool {
 		return false
 	}
 
-	for _, ex := range f.exclude {
-		if ex.Match(filename) {
-			tracerx.Printf("filepathfilter: rejecting %q via %q", filename, ex.String())
-			return false
-		}
+	if excluded, ex := match(f.exclude, filename); excluded {
+		tracerx.Printf("filepathfilter: rejecting %q via %q", filename, ex.String())
+		return false
 	}
 
 	// No patterns matched and our default value is true.
@@ -106,18 +98,130 @@ func (f *Filter) Allows(filename string) bool {
 	return true
 }
 
+// match returns whether the given filename is matched by the set of patterns,
+// and the pattern which decided it, following gitignore(5): the last pattern
+// to match a path wins, and patterns starting with "!" make the paths they
+// match not match again.  As in Git, a file inside a directory which is
+// matched is always matched, since Git never looks inside that directory, so
+// a "!" pattern cannot make it not match.
+func match(patterns []Pattern, filename string) (bool, Pattern) {
+	if len(patterns) == 0 {
+		return false, nil
+	}
+
+	name, isDir := chomp(filename)
+	for _, dir := range leadingDirs(name) {
+		if matched, p := lastMatch(patterns, dir, true, true); matched {
+			return true, p
+		}
+	}
+	return lastMatch(patterns, name, isDir, false)
+}
+
+// lastMatch returns whether the last of the given patterns to match the path
+// itself, ignoring its leading directories, is one which does not start with
+// "!", and that pattern.  Patterns which are not parsed as in gitignore(5)
+// only apply to the file itself, not to its leading directories.
+func lastMatch(patterns []Pattern, path string, isDir, leading bool) (bool, Pattern) {
+	var last Pattern
+	var negated bool
+
+	for _, p := range patterns {
+		if w, ok := p.(*wm); ok && !w.strict {
+			if w.matchPath(path, isDir) {
+				last, negated = w, w.negated
+			}
+		} else if !leading && p.Match(path) {
+			last, negated = p, false
+		}
+	}
+	return last != nil && !negated, last
+}
+
+// leadingDirs returns the directories leading to the given path, from the
+// outermost inwards, such as "a" and "a/b" for "a/b/c".
+func leadingDirs(path string) []string {
+	var dirs []string
+	for i := 0; i < len(path); i++ {
+		if path[i] == sep && i > 0 {
+			dirs = append(dirs, path[:i])
+		}
+	}
+	return dirs
+}
+
+// chomp removes a trailing separator from the given filename, which marks
+// it as a directory, and returns whether it did.
+func chomp(filename string) (string, bool) {
+	for _, suffix := range []string{string(sep), string(filepath.Separator)} {
+		if len(filename) > 1 && strings.HasSuffix(filename, suffix) {
+			return strings.TrimSuffix(filename, suffix), true
+		}
+	}
+	return filename, false
+}
+
 type wm struct {
-	w    *wildmatch.Wildmatch
-	p    string
-	dirs bool
+	w *wildmatch.Wildmatch
+	p string
+
+	// strict is whether the pattern is matched against the whole path
+	// exactly as given, rather than as in gitignore(5).
+	strict bool
+	// negated is whether the pattern started with "!", and so makes the
+	// paths it matches not match.
+	negated bool
+	// dirsOnly is whether the pattern ended with a separator, and so only
+	// matches directories.
+	dirsOnly bool
+	// basename is whether the pattern has no separators other than a
+	// trailing one, and so matches a file or directory name at any depth.
+	basename bool
+	// inside matches the directories whose contents, but not themselves,
+	// are matched by a pattern ending with "/**".
+	inside *wildmatch.Wildmatch
 }
 
+// Match returns whether the pattern matches the given filename, or, unless it
+// is strict, any of the directories leading to it.  A filename ending with a
+// separator is taken to be a directory.  Whether the pattern starts with "!"
+// is not taken into account.
 func (w *wm) Match(filename string) bool {
-	return w.w.Match(w.chomp(filename))
+	name, isDir := chomp(filename)
+	if w.strict {
+		return w.w.Match(name)
+	}
+
+	if w.matchPath(name, isDir) {
+		return true
+	}
+	for _, dir := range leadingDirs(name) {
+		if w.matchPath(dir, true) {
+			return true
+		}
+	}
+	return false
 }
 
-func (w *wm) chomp(filename string) string {
-	return strings.TrimSuffix(filename, string(filepath.Separator))
+// matchPath returns whether the pattern matches the given path itself,
+// ignoring its leading directories.
+func (w *wm) matchPath(path string, isDir bool) bool {
+	if w.strict {
+		return w.w.Match(path)
+	}
+
+	if w.dirsOnly && !isDir {
+		return false
+	}
+	if w.basename {
+		if i := strings.LastIndexByte(path, sep); i >= 0 {
+			path = path[i+1:]
+		}
+	}
+	if w.inside != nil && w.inside.Match(path) {
+		return false
+	}
+	return w.w.Match(path)
 }
 
 func (w *wm) String() string {
@@ -135,89 +239,64 @@ type patternOptions struct {
 type patternOption func(*patternOptions)
 
 // Strict is an option representing whether to strictly match wildmatch patterns
-// the way Git does.  If disabled, additional modifications are made to patterns
-// for backwards compatibility.
+// against whole paths.  If disabled, patterns are matched as in gitignore(5),
+// with additional modifications for backwards compatibility.
 func Strict(val bool) patternOption {
 	return func(args *patternOptions) {
 		args.strict = val
 	}
 }
 
+// NewPattern returns a Pattern matching paths against p.  Unless the Strict
+// option is given, p is matched as in gitignore(5): a leading "!" negates it
+// (use "\!" for a literal "!"), a trailing separator matches only
+// directories, a pattern with no other separators matches names at any
+// depth, and otherwise it matches paths from the root, where "**" matches any
+// number of directories.  For backwards compatibility, "*", ".", "./" and
+// ".\" match everything.
 func NewPattern(p string, setters ...patternOption) Pattern {
 	args := &patternOptions{strict: false}
 	for _, setter := range setters {
 		setter(args)
 	}
 
+	w := &wm{p: p, strict: args.strict}
 	pp := p
 
-	dirs := strings.Contains(pp, string(sep))
-
 	if !args.strict {
-
 		// Special case: the below patterns match anything according to existing
 		// behavior.
 		switch pp {
 		case `*`, `.`, `./`, `.\`:
-			pp = join("**", "*")
+			pp = "**"
 		}
 
-		dirs = strings.Contains(pp, string(sep))
-		rooted := strings.HasPrefix(pp, string(sep))
-		wild := strings.Contains(pp, "*")
-
-		if !dirs && !wild {
-			// Special case: if pp is a literal string (optionally including
-			// a character class), rewrite it is a substring match.
-			pp = join("**", pp, "**")
-		} else {
-			if dirs && !rooted {
-				// Special case: if there are any directory separators,
-				// rewrite "pp" as a substring match.
-				if !wild {
-					pp = join("**", pp, "**")
-				}
-			} else {
-				if rooted {
-					// Special case: if there are not any directory
-					// separators, rewrite "pp" as a substring
-					// match.
-					pp = join(pp, "**")
-				} else {
-					// Special case: if there are not any directory
-					// separators, rewrite "pp" as a substring
-					// match.
-					pp = join("**", pp)
-				}
-			}
+		if strings.HasPrefix(pp, "!") {
+			w.negated = true
+			pp = pp[1:]
+		} else if strings.HasPrefix(pp, `\!`) {
+			pp = pp[1:]
 		}
-	}
-	tracerx.Printf("filepathfilter: rewrite %q as %q (strict: %v)", p, pp, args.strict)
 
-	return &wm{
-		p: p,
-		w: wildmatch.NewWildmatch(
-			pp,
-			wildmatch.SystemCase,
-		),
-		dirs: dirs,
-	}
-}
+		if len(pp) > 1 && strings.HasSuffix(pp, string(sep)) {
+			w.dirsOnly = true
+			pp = strings.TrimSuffix(pp, string(sep))
+		}
 
-// join joins path elements together via the separator "sep" and produces valid
-// paths without multiple separators (unless multiple separators were included
-// in the original paths []string).
-func join(paths ...string) string {
-	var joined string
+		w.basename = !strings.Contains(pp, string(sep))
+		pp = strings.TrimPrefix(pp, string(sep))
 
-	for i, path := range paths {
-		joined = joined + path
-		if i != len(paths)-1 && !strings.HasSuffix(path, string(sep)) {
-			joined = joined + string(sep)
+		if strings.HasSuffix(pp, "/**") {
+			w.inside = wildmatch.NewWildmatch(
+				strings.TrimSuffix(pp, "/**"),
+				wildmatch.SystemCase,
+			)
 		}
 	}
+	tracerx.Printf("filepathfilter: rewrite %q as %q (strict: %v)", p, pp, args.strict)
 
-	return joined
+	w.w = wildmatch.NewWildmatch(pp, wildmatch.SystemCase)
+	return w
 }
 
 func convertToWildmatch(rawpatterns []string, setters ...patternOption) []Pattern {
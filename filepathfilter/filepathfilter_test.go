@@ -69,17 +69,19 @@ func TestPatternMatch(t *testing.T) {
 	refutePatternMatch(t, "sub/", "subfilename.txt")
 	refutePatternMatch(t, "/sub/", "subfilename.txt", "top/sub/filename.txt")
 
-	// nested path
+	// nested path, which is matched from the root as in gitignore(5)
 	assertPatternMatch(t, "top/sub",
 		"top/sub/filename.txt",
 		"top/sub/",
 		"top/sub",
+	)
+	refutePatternMatch(t, "top/sub",
 		"root/top/sub/filename.txt",
 		"root/top/sub/",
 		"root/top/sub",
 	)
 	assertPatternMatch(t, "top/sub/", "top/sub/filename.txt")
-	assertPatternMatch(t, "top/sub/", "root/top/sub/filename.txt")
+	refutePatternMatch(t, "top/sub/", "root/top/sub/filename.txt")
 
 	assertPatternMatch(t, "/top/sub", "top/sub/", "top/sub", "top/sub/filename.txt")
 	assertPatternMatch(t, "/top/sub/", "top/sub/filename.txt")
@@ -108,6 +110,57 @@ func TestPatternMatch(t *testing.T) {
 	assertPatternMatch(t, ".\\", "path.txt")
 }
 
+func TestPatternMatchDirectoriesOnly(t *testing.T) {
+	assertPatternMatch(t, "sub/", "sub/", "top/sub/", "sub/filename.txt")
+	refutePatternMatch(t, "sub/", "sub", "top/sub")
+
+	assertPatternMatch(t, "top/sub/", "top/sub/", "top/sub/filename.txt")
+	refutePatternMatch(t, "top/sub/", "top/sub")
+}
+
+func TestPatternMatchDoubleStar(t *testing.T) {
+	// leading "**/" matches in all directories
+	assertPatternMatch(t, "**/foo", "foo", "a/foo", "a/b/foo", "a/b/foo/bar")
+	assertPatternMatch(t, "**/foo/bar", "foo/bar", "a/b/foo/bar")
+	refutePatternMatch(t, "**/foo/bar", "foo/baz/bar")
+
+	// trailing "/**" matches everything inside, but not the directory
+	assertPatternMatch(t, "abc/**", "abc/x", "abc/x/y/z")
+	refutePatternMatch(t, "abc/**", "abc", "abc/", "x/abc/y", "abcd/x")
+
+	// "/**/" matches zero or more directories
+	assertPatternMatch(t, "a/**/b", "a/b", "a/x/b", "a/x/y/b", "a/x/b/c")
+	refutePatternMatch(t, "a/**/b", "x/a/b", "a/bc")
+
+	// other consecutive asterisks are regular asterisks
+	assertPatternMatch(t, "a**z", "abcz", "x/abcz")
+	refutePatternMatch(t, "/a**z", "a/z")
+}
+
+func TestPatternMatchCharacterClasses(t *testing.T) {
+	assertPatternMatch(t, "file[0-9].txt", "file1.txt", "sub/file9.txt")
+	refutePatternMatch(t, "file[0-9].txt", "filea.txt", "file10.txt")
+
+	assertPatternMatch(t, "file[!0-9].txt", "filea.txt")
+	refutePatternMatch(t, "file[!0-9].txt", "file1.txt")
+
+	assertPatternMatch(t, "file[[:alpha:]].txt", "fileb.txt")
+	refutePatternMatch(t, "file[[:alpha:]].txt", "file_.txt")
+
+	assertPatternMatch(t, "file?.txt", "file1.txt")
+	refutePatternMatch(t, "file?.txt", "file.txt", "file12.txt")
+}
+
+func TestPatternMatchNegation(t *testing.T) {
+	// negation is applied by the Filter; a pattern only matches
+	p := NewPattern("!*.txt")
+	assert.True(t, p.Match("filename.txt"))
+	assert.Equal(t, "!*.txt", p.String())
+
+	assertPatternMatch(t, `\!important.txt`, "!important.txt")
+	refutePatternMatch(t, `\!important.txt`, "important.txt")
+}
+
 func assertPatternMatch(t *testing.T, pattern string, filenames ...string) {
 	p := NewPattern(pattern)
 	for _, filename := range filenames {
@@ -129,6 +182,57 @@ type filterTest struct {
 	excludes        []string
 }
 
+func TestFilterAllowsWithNegation(t *testing.T) {
+	filter := New([]string{"*.dat", "!b*.dat"}, []string{"*.psd", "!keep.psd"})
+
+	assert.True(t, filter.Allows("a.dat"))
+	assert.True(t, filter.Allows("sub/a.dat"))
+	assert.False(t, filter.Allows("b.dat"))
+	assert.False(t, filter.Allows("sub/b.dat"))
+	assert.False(t, filter.Allows("a.txt"))
+
+	filter = New(nil, []string{"*.psd", "!keep.psd"})
+	assert.False(t, filter.Allows("art/image.psd"))
+	assert.True(t, filter.Allows("art/keep.psd"))
+	assert.True(t, filter.Allows("art/image.png"))
+}
+
+func TestFilterAllowsWithNegationOrder(t *testing.T) {
+	// the last matching pattern wins
+	filter := New(nil, []string{"!keep.psd", "*.psd"})
+	assert.False(t, filter.Allows("keep.psd"))
+
+	filter = New(nil, []string{"*.psd", "!keep.psd", "keep.psd"})
+	assert.False(t, filter.Allows("keep.psd"))
+}
+
+func TestFilterAllowsWithNegationInsideMatchedDirectory(t *testing.T) {
+	// as in Git, files cannot be re-included once their directory is
+	// excluded
+	filter := New(nil, []string{"build/", "!build/keep.txt"})
+	assert.False(t, filter.Allows("build/keep.txt"))
+	assert.False(t, filter.Allows("build/other.txt"))
+
+	// but can be if only the contents are excluded
+	filter = New(nil, []string{"build/*", "!build/keep.txt"})
+	assert.True(t, filter.Allows("build/keep.txt"))
+	assert.False(t, filter.Allows("build/other.txt"))
+	assert.False(t, filter.Allows("build/sub/other.txt"))
+
+	filter = New(nil, []string{"/*", "!/foo", "/foo/*", "!/foo/bar"})
+	assert.True(t, filter.Allows("foo/bar/baz.txt"))
+	assert.False(t, filter.Allows("foo/baz/bar.txt"))
+	assert.False(t, filter.Allows("other.txt"))
+}
+
+func TestFilterAllowsWithDirectoriesOnly(t *testing.T) {
+	filter := New(nil, []string{"logs/"})
+	assert.False(t, filter.Allows("logs/today.log"))
+	assert.False(t, filter.Allows("app/logs/today.log"))
+	assert.True(t, filter.Allows("logs"))
+	assert.True(t, filter.Allows("app/logs"))
+}
+
 func TestFilterReportsIncludePatterns(t *testing.T) {
 	filter := New([]string{"*.foo", "*.bar"}, nil)
 
@@ -0,0 +1,121 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+var anyCaseOidRE = regexp.MustCompile(`\A(?i)[0-9a-f]{64}\z`)
+
+// ErrObjectExists is returned by RepairObjectAnomaly when a different file
+// already exists at the canonical path for the object.
+var ErrObjectExists = errors.New("object already exists")
+
+// ObjectAnomaly is a file in the object directory which is not at the
+// canonical path for an object, such as one whose name has a different case
+// to its OID, which can appear on case-insensitive filesystems.
+type ObjectAnomaly struct {
+	// Name is the path of the file relative to the object directory,
+	// separated by slashes.
+	Name string
+	// Path is the full path of the file.
+	Path string
+	// Oid is the OID the name of the file refers to, normalized to
+	// lowercase, or empty if the name is not that of an object.
+	Oid string
+}
+
+// EachObjectAnomaly calls fn for each file in the object directory which is
+// not at the canonical path for an object, and is therefore not reported by
+// EachObject.
+func (f *Filesystem) EachObjectAnomaly(fn func(ObjectAnomaly) error) error {
+	root := f.LFSObjectDir()
+
+	var eachErr error
+	tools.FastWalkDir(root, func(parentDir string, info os.FileInfo, err error) {
+		if err != nil {
+			eachErr = err
+			return
+		}
+		if eachErr != nil || info.IsDir() {
+			return
+		}
+
+		name := relativeObjectName(root, parentDir, info.Name())
+		if isCanonicalObjectName(name) {
+			return
+		}
+
+		a := ObjectAnomaly{Name: name, Path: filepath.Join(parentDir, info.Name())}
+		if anyCaseOidRE.MatchString(info.Name()) {
+			a.Oid = normalizeOid(info.Name())
+		}
+		if err := fn(a); err != nil {
+			eachErr = err
+		}
+	})
+	return eachErr
+}
+
+// RepairObjectAnomaly moves the file described by "a" to the canonical path
+// for its OID. It is an error if the file is not named for an object, and
+// ErrObjectExists is returned if a different file already exists at that path.
+// The caller is responsible for verifying the content of the file first.
+func (f *Filesystem) RepairObjectAnomaly(a ObjectAnomaly) error {
+	if len(a.Oid) == 0 {
+		return fmt.Errorf("%s is not named for an object", a.Name)
+	}
+
+	dest, err := f.ObjectPath(a.Oid)
+	if err != nil {
+		return err
+	}
+
+	if destInfo, err := os.Stat(tools.LongPath(dest)); err == nil {
+		srcInfo, err := os.Stat(tools.LongPath(a.Path))
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(srcInfo, destInfo) {
+			return ErrObjectExists
+		}
+	}
+
+	// On case-insensitive filesystems, the file may be the same as the
+	// one at the canonical path, so move it out of the way first, in
+	// order that the case of its name is changed.
+	tmp := filepath.Join(f.TempDir(), "repair-"+a.Oid)
+	if err := tools.RobustRename(a.Path, tmp); err != nil {
+		return err
+	}
+	return tools.RobustRename(tmp, dest)
+}
+
+// relativeObjectName returns the slash-separated path of the file with the
+// given name in parentDir, relative to the object directory "root", or an
+// empty string if it is not within it.
+func relativeObjectName(root, parentDir, name string) string {
+	rel, err := filepath.Rel(root, filepath.Join(parentDir, name))
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// isCanonicalObjectName returns whether the given slash-separated path,
+// relative to the object directory, is the canonical path for an object.
+func isCanonicalObjectName(name string) bool {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 {
+		return false
+	}
+
+	oid := parts[2]
+	return oidRE.MatchString(oid) && parts[0] == oid[0:2] && parts[1] == oid[2:4]
+}
@@ -0,0 +1,89 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const anomalyTestOid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func newAnomalyTestFilesystem(t *testing.T) *Filesystem {
+	dir, err := ioutil.TempDir("", "fs-anomalies")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return &Filesystem{LFSStorageDir: dir, repoPerms: 0644}
+}
+
+func writeAnomalyTestFile(t *testing.T, f *Filesystem, name string) string {
+	path := filepath.Join(f.LFSObjectDir(), filepath.FromSlash(name))
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, ioutil.WriteFile(path, []byte("test"), 0644))
+	return path
+}
+
+func TestEachObjectAnomaly(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+
+	upper := strings.ToUpper(anomalyTestOid)
+	writeAnomalyTestFile(t, f, "4d/7a/"+anomalyTestOid)
+	writeAnomalyTestFile(t, f, "4d/7a/"+upper[0:4]+anomalyTestOid[4:])
+	writeAnomalyTestFile(t, f, "4D/7a/"+anomalyTestOid)
+	writeAnomalyTestFile(t, f, "4d/7a/junk")
+
+	var anomalies []ObjectAnomaly
+	require.Nil(t, f.EachObjectAnomaly(func(a ObjectAnomaly) error {
+		anomalies = append(anomalies, a)
+		return nil
+	}))
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Name < anomalies[j].Name
+	})
+
+	require.Len(t, anomalies, 3)
+	assert.Equal(t, "4D/7a/"+anomalyTestOid, anomalies[0].Name)
+	assert.Equal(t, anomalyTestOid, anomalies[0].Oid)
+	assert.Equal(t, "4d/7a/"+upper[0:4]+anomalyTestOid[4:], anomalies[1].Name)
+	assert.Equal(t, anomalyTestOid, anomalies[1].Oid)
+	assert.Equal(t, "4d/7a/junk", anomalies[2].Name)
+	assert.Equal(t, "", anomalies[2].Oid)
+
+	var objects []Object
+	require.Nil(t, f.EachObject(func(o Object) error {
+		objects = append(objects, o)
+		return nil
+	}))
+	assert.Equal(t, []Object{{Oid: anomalyTestOid, Size: 4}}, objects)
+}
+
+func TestRepairObjectAnomaly(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+
+	name := "4d/7a/" + strings.ToUpper(anomalyTestOid)
+	path := writeAnomalyTestFile(t, f, name)
+
+	require.Nil(t, f.RepairObjectAnomaly(ObjectAnomaly{Name: name, Path: path, Oid: anomalyTestOid}))
+	assert.True(t, f.ObjectExists(anomalyTestOid, 4))
+
+	// A different file at the canonical path is not replaced.
+	path = writeAnomalyTestFile(t, f, name)
+	err := f.RepairObjectAnomaly(ObjectAnomaly{Name: name, Path: path, Oid: anomalyTestOid})
+	assert.Equal(t, ErrObjectExists, err)
+	assert.FileExists(t, path)
+
+	err = f.RepairObjectAnomaly(ObjectAnomaly{Name: "4d/7a/junk"})
+	assert.EqualError(t, err, "4d/7a/junk is not named for an object")
+}
+
+func TestObjectPathnameNormalizesCase(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+
+	assert.Equal(t, f.ObjectPathname(anomalyTestOid), f.ObjectPathname(strings.ToUpper(anomalyTestOid)))
+}
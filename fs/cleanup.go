@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,7 +12,72 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+// TempPolicy determines which of the files left in the temporary and
+// incomplete directories are stale.
+type TempPolicy struct {
+	// TmpAge is how long a temporary file, or a download which is no
+	// longer being written to, is kept after it was last modified.
+	TmpAge time.Duration
+	// IncompleteAge is how long a partial download is kept, so that it
+	// can be resumed, after it was last modified.
+	IncompleteAge time.Duration
+}
+
+// DefaultTempPolicy is the policy used when no other is configured.
+var DefaultTempPolicy = TempPolicy{
+	TmpAge:        time.Hour,
+	IncompleteAge: 14 * 24 * time.Hour,
+}
+
+// StaleTempFile is a file in the temporary or incomplete directories which is
+// no longer needed, or a download abandoned without being saved for resuming.
+type StaleTempFile struct {
+	// Path is the full path of the file.
+	Path string
+	// Size is the size of the file in bytes.
+	Size int64
+	// Oid is the OID of the object the file was written for, if known.
+	Oid string
+	// Reason describes why the file is stale.
+	Reason string
+	// Resume is whether the file is an abandoned download, which is
+	// saved as a partial download rather than removed.
+	Resume bool
+}
+
+// IncompleteDir returns the directory in which partial downloads are stored
+// to be resumed. Unlike the other directories, it is not created here.
+func (f *Filesystem) IncompleteDir() string {
+	return filepath.Join(f.LFSStorageDir, "incomplete")
+}
+
+// EachStaleTempFile calls fn for each file in the temporary and incomplete
+// directories which is stale according to the given policy.
+func (f *Filesystem) EachStaleTempFile(policy TempPolicy, fn func(StaleTempFile) error) error {
+	if err := f.eachStaleTmpFile(policy, fn); err != nil {
+		return err
+	}
+	return f.eachStaleIncompleteFile(policy, fn)
+}
+
+// CleanStaleTempFile removes the given stale file or, if it is an abandoned
+// download, saves it as a partial download so that it can be resumed.
+func (f *Filesystem) CleanStaleTempFile(s StaleTempFile) error {
+	if s.Resume {
+		return tools.RobustRename(s.Path, f.partialDownloadPath(s.Oid))
+	}
+	return os.RemoveAll(tools.LongPath(s.Path))
+}
+
 func (f *Filesystem) cleanupTmp() error {
+	return f.eachStaleTmpFile(DefaultTempPolicy, func(s StaleTempFile) error {
+		tracerx.Printf("Removing %s tmp object file: %s", s.Reason, s.Path)
+		os.RemoveAll(tools.LongPath(s.Path))
+		return nil
+	})
+}
+
+func (f *Filesystem) eachStaleTmpFile(policy TempPolicy, fn func(StaleTempFile) error) error {
 	tmpdir := f.TempDir()
 	if len(tmpdir) == 0 {
 		return nil
@@ -24,12 +90,22 @@ func (f *Filesystem) cleanupTmp() error {
 
 	traversedDirectories := &sync.Map{}
 
+	// The directory is walked concurrently, so serialize calls to fn.
+	var walkMu sync.Mutex
 	var walkErr error
+	stale := func(s StaleTempFile) {
+		walkMu.Lock()
+		defer walkMu.Unlock()
+		if walkErr == nil {
+			walkErr = fn(s)
+		}
+	}
+
 	tools.FastWalkDir(tmpdir, func(parentDir string, info os.FileInfo, err error) {
 		if err != nil {
+			walkMu.Lock()
 			walkErr = err
-		}
-		if walkErr != nil {
+			walkMu.Unlock()
 			return
 		}
 		path := filepath.Join(parentDir, info.Name())
@@ -40,10 +116,8 @@ func (f *Filesystem) cleanupTmp() error {
 		parts := strings.SplitN(info.Name(), "-", 2)
 		oid := parts[0]
 		if len(parts) == 2 && len(oid) == 64 {
-			fi, err := os.Stat(f.ObjectPathname(oid))
-			if err == nil && !fi.IsDir() {
-				tracerx.Printf("Removing existing tmp object file: %s", path)
-				os.RemoveAll(path)
+			if f.objectFileExists(oid) {
+				stale(StaleTempFile{Path: path, Size: info.Size(), Oid: oid, Reason: "existing"})
 				return
 			}
 		}
@@ -72,12 +146,86 @@ func (f *Filesystem) cleanupTmp() error {
 			}
 		}
 
-		if time.Since(info.ModTime()) > time.Hour {
-			tracerx.Printf("Removing old tmp object file: %s", path)
-			os.RemoveAll(path)
+		if time.Since(info.ModTime()) > policy.TmpAge {
+			stale(StaleTempFile{Path: path, Size: info.Size(), Reason: "old"})
 			return
 		}
 	})
 
 	return walkErr
 }
+
+// eachStaleIncompleteFile calls fn for each stale file in the incomplete
+// directory, which holds partial downloads named "<oid>.part", with the
+// validators of their responses named "<oid>.validators", and the downloads in
+// progress, named by their OID and a random suffix.
+func (f *Filesystem) eachStaleIncompleteFile(policy TempPolicy, fn func(StaleTempFile) error) error {
+	dir := f.IncompleteDir()
+	infos, err := ioutil.ReadDir(tools.LongPath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		s := StaleTempFile{
+			Path: filepath.Join(dir, info.Name()),
+			Size: info.Size(),
+		}
+		if len(info.Name()) >= 64 && oidRE.MatchString(info.Name()[:64]) {
+			s.Oid = info.Name()[:64]
+		}
+		age := time.Since(info.ModTime())
+
+		switch {
+		case len(s.Oid) > 0 && f.objectFileExists(s.Oid):
+			s.Reason = "existing"
+		case len(s.Oid) > 0 && info.Name() == s.Oid+".part":
+			if age <= policy.IncompleteAge {
+				continue
+			}
+			s.Reason = "old partial download"
+		case len(s.Oid) > 0 && info.Name() == s.Oid+".validators":
+			// The validators of a partial download are kept for
+			// as long as it is.
+			if age <= policy.IncompleteAge && f.partialDownloadExists(s.Oid) {
+				continue
+			}
+			s.Reason = "old partial download"
+		case age <= policy.TmpAge:
+			continue
+		case len(s.Oid) > 0 && s.Size > 0 && !f.partialDownloadExists(s.Oid):
+			// The download was abandoned without being saved
+			// for resuming, such as when the process crashed.
+			s.Reason = "abandoned download"
+			s.Resume = true
+		default:
+			s.Reason = "old"
+		}
+
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Filesystem) partialDownloadPath(oid string) string {
+	return filepath.Join(f.IncompleteDir(), oid+".part")
+}
+
+func (f *Filesystem) partialDownloadExists(oid string) bool {
+	_, err := os.Stat(tools.LongPath(f.partialDownloadPath(oid)))
+	return err == nil
+}
+
+func (f *Filesystem) objectFileExists(oid string) bool {
+	fi, err := os.Stat(tools.LongPath(f.ObjectPathname(oid)))
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,119 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cleanupTestOid      = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	cleanupTestOtherOid = "6f8b9a1f3c0a2d6e1b3c5d7e9f0a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2"
+)
+
+func writeCleanupTestFile(t *testing.T, dir, name string, age time.Duration) string {
+	require.Nil(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(path, []byte("test"), 0644))
+
+	mtime := time.Now().Add(-age)
+	require.Nil(t, os.Chtimes(path, mtime, mtime))
+	return path
+}
+
+func staleTempFiles(t *testing.T, f *Filesystem) []StaleTempFile {
+	var stale []StaleTempFile
+	require.Nil(t, f.EachStaleTempFile(DefaultTempPolicy, func(s StaleTempFile) error {
+		stale = append(stale, s)
+		return nil
+	}))
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Path < stale[j].Path
+	})
+	return stale
+}
+
+func TestEachStaleTempFileInIncompleteDir(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	dir := f.IncompleteDir()
+
+	writeAnomalyTestFile(t, f, "4d/7a/"+cleanupTestOid)
+	existing := writeCleanupTestFile(t, dir, cleanupTestOid+"123", 0)
+	writeCleanupTestFile(t, dir, cleanupTestOtherOid+".part", 24*time.Hour)
+	writeCleanupTestFile(t, dir, cleanupTestOtherOid+"456", 0)
+	old := writeCleanupTestFile(t, dir, "junk", 2*time.Hour)
+
+	stale := staleTempFiles(t, f)
+	require.Len(t, stale, 2)
+	assert.Equal(t, existing, stale[0].Path)
+	assert.Equal(t, "existing", stale[0].Reason)
+	assert.False(t, stale[0].Resume)
+	assert.Equal(t, old, stale[1].Path)
+	assert.Equal(t, "old", stale[1].Reason)
+}
+
+func TestEachStaleTempFileExpiresPartialDownloads(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	part := writeCleanupTestFile(t, f.IncompleteDir(), cleanupTestOtherOid+".part", 15*24*time.Hour)
+
+	stale := staleTempFiles(t, f)
+	require.Len(t, stale, 1)
+	assert.Equal(t, part, stale[0].Path)
+	assert.Equal(t, "old partial download", stale[0].Reason)
+	assert.False(t, stale[0].Resume)
+}
+
+func TestEachStaleTempFileKeepsValidatorsWithPartialDownloads(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	dir := f.IncompleteDir()
+
+	writeCleanupTestFile(t, dir, cleanupTestOtherOid+".part", 24*time.Hour)
+	writeCleanupTestFile(t, dir, cleanupTestOtherOid+".validators", 24*time.Hour)
+	orphan := writeCleanupTestFile(t, dir, cleanupTestOid+".validators", 2*time.Hour)
+
+	stale := staleTempFiles(t, f)
+	require.Len(t, stale, 1)
+	assert.Equal(t, orphan, stale[0].Path)
+	assert.Equal(t, "old partial download", stale[0].Reason)
+	assert.False(t, stale[0].Resume)
+}
+
+func TestCleanStaleTempFileResumesAbandonedDownloads(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	abandoned := writeCleanupTestFile(t, f.IncompleteDir(), cleanupTestOtherOid+"789", 2*time.Hour)
+
+	stale := staleTempFiles(t, f)
+	require.Len(t, stale, 1)
+	assert.Equal(t, abandoned, stale[0].Path)
+	assert.True(t, stale[0].Resume)
+
+	require.Nil(t, f.CleanStaleTempFile(stale[0]))
+	_, err := os.Stat(abandoned)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(f.IncompleteDir(), cleanupTestOtherOid+".part"))
+	assert.Nil(t, err)
+
+	assert.Empty(t, staleTempFiles(t, f))
+}
+
+func TestEachStaleTempFileInTmpDir(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+
+	writeAnomalyTestFile(t, f, "4d/7a/"+cleanupTestOid)
+	existing := writeCleanupTestFile(t, f.TempDir(), cleanupTestOid+"-1", 0)
+	writeCleanupTestFile(t, f.TempDir(), cleanupTestOtherOid+"-2", 0)
+	old := writeCleanupTestFile(t, f.TempDir(), cleanupTestOtherOid+"-3", 2*time.Hour)
+
+	stale := staleTempFiles(t, f)
+	require.Len(t, stale, 2)
+	assert.Equal(t, existing, stale[0].Path)
+	assert.Equal(t, "existing", stale[0].Reason)
+	assert.Equal(t, old, stale[1].Path)
+	assert.Equal(t, "old", stale[1].Reason)
+}
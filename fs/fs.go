@@ -16,7 +16,7 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
-var oidRE = regexp.MustCompile(`\A[[:alnum:]]{64}`)
+var oidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
 
 // Environment is a copy of a subset of the interface
 // github.com/git-lfs/git-lfs/config.Environment.
@@ -36,16 +36,55 @@ type Filesystem struct {
 	GitStorageDir string   // parent of objects/lfs (may be same as GitDir but may not)
 	LFSStorageDir string   // parent of lfs objects and tmp dirs. Default: ".git/lfs"
 	ReferenceDirs []string // alternative local media dirs (relative to clone reference repo)
+	TierDir       string   // secondary storage for objects unused for a while (lfs.tier.path)
+	ReadOnlyDir   string   // shared object directory which is only read from (lfs.storage.readonly)
 	lfsobjdir     string
 	tmpdir        string
 	logdir        string
 	repoPerms     os.FileMode
 	mu            sync.Mutex
+
+	// Backend is the name of the ObjectStore objects are stored in at
+	// rest, as configured with lfs.storage.backend.
+	Backend string
+	store   ObjectStore
+	packs   *packStore
+	storeMu sync.Mutex
 }
 
+// EachObject calls fn for each object in the object directory or, if another
+// is configured, the ObjectStore. Files which are not at the canonical path
+// for an object are skipped; see EachObjectAnomaly. Objects in the read-only
+// object store are not included, so that commands which maintain the object
+// directory, such as prune, never touch them.
 func (f *Filesystem) EachObject(fn func(Object) error) error {
+	store := f.packedStore()
+	if store == nil {
+		return f.eachLooseObject(fn)
+	}
+
+	loose := make(map[string]bool)
+	err := f.eachLooseObject(func(o Object) error {
+		loose[o.Oid] = true
+		return fn(o)
+	})
+	if err != nil {
+		return err
+	}
+	return store.Each(func(o Object) error {
+		if loose[o.Oid] {
+			return nil
+		}
+		return fn(o)
+	})
+}
+
+// eachLooseObject calls fn for each object in the object directory.
+func (f *Filesystem) eachLooseObject(fn func(Object) error) error {
+	root := f.LFSObjectDir()
+
 	var eachErr error
-	tools.FastWalkDir(f.LFSObjectDir(), func(parentDir string, info os.FileInfo, err error) {
+	tools.FastWalkDir(root, func(parentDir string, info os.FileInfo, err error) {
 		if err != nil {
 			eachErr = err
 			return
@@ -53,7 +92,7 @@ func (f *Filesystem) EachObject(fn func(Object) error) error {
 		if eachErr != nil || info.IsDir() {
 			return
 		}
-		if oidRE.MatchString(info.Name()) {
+		if isCanonicalObjectName(relativeObjectName(root, parentDir, info.Name())) {
 			fn(Object{Oid: info.Name(), Size: info.Size()})
 		}
 	})
@@ -61,10 +100,18 @@ func (f *Filesystem) EachObject(fn func(Object) error) error {
 }
 
 func (f *Filesystem) ObjectExists(oid string, size int64) bool {
-	return tools.FileExistsOfSize(f.ObjectPathname(oid), size)
+	if tools.FileExistsOfSize(f.ObjectPathname(oid), size) {
+		return true
+	}
+	if store := f.packedStore(); store != nil && store.Has(oid, size) {
+		return true
+	}
+	path := f.ReadOnlyObjectPath(oid)
+	return len(path) > 0 && tools.FileExistsOfSize(path, size)
 }
 
 func (f *Filesystem) ObjectPath(oid string) (string, error) {
+	oid = normalizeOid(oid)
 	if len(oid) < 4 {
 		return "", fmt.Errorf("too short object ID: %q", oid)
 	}
@@ -75,7 +122,33 @@ func (f *Filesystem) ObjectPath(oid string) (string, error) {
 	return filepath.Join(dir, oid), nil
 }
 
+// UnpackedObjectPath returns the path of the object with the given OID in the
+// object directory, like ObjectPath, first unpacking it there if it is only
+// held by another backend. It is for the few callers which must hand a file
+// to something else, such as a transfer adapter; others read the object with
+// OpenObject. An object held only by the read-only object store is not copied
+// into the object directory; its path there is returned instead, and must only
+// be read from.
+func (f *Filesystem) UnpackedObjectPath(oid string) (string, error) {
+	path, err := f.ObjectPath(oid)
+	if err != nil {
+		return "", err
+	}
+	if !tools.FileExists(path) {
+		if err := f.unpackObject(normalizeOid(oid), path); err != nil {
+			return "", err
+		}
+	}
+	if !tools.FileExists(path) {
+		if ro := f.ReadOnlyObjectPath(oid); len(ro) > 0 && tools.FileExists(ro) {
+			return ro, nil
+		}
+	}
+	return path, nil
+}
+
 func (f *Filesystem) ObjectPathname(oid string) string {
+	oid = normalizeOid(oid)
 	return filepath.Join(f.localObjectDir(oid), oid)
 }
 
@@ -126,8 +199,19 @@ func (f *Filesystem) localObjectDir(oid string) string {
 	return filepath.Join(f.LFSObjectDir(), oid[0:2], oid[2:4])
 }
 
+// normalizeOid returns the given OID in lowercase, as objects are stored, so
+// that an OID in another case doesn't refer to a different file on a
+// case-sensitive filesystem.
+func normalizeOid(oid string) string {
+	return strings.ToLower(oid)
+}
+
+// ObjectReferencePaths returns the paths at which the object with the given OID
+// may be found outside the object directory, from which it is linked or copied
+// into it when needed: in the object directories of reference repositories,
+// and in secondary storage.
 func (f *Filesystem) ObjectReferencePaths(oid string) []string {
-	if len(f.ReferenceDirs) == 0 {
+	if len(f.ReferenceDirs) == 0 && len(f.TierDir) == 0 {
 		return nil
 	}
 
@@ -135,6 +219,9 @@ func (f *Filesystem) ObjectReferencePaths(oid string) []string {
 	for _, ref := range f.ReferenceDirs {
 		paths = append(paths, filepath.Join(ref, oid[0:2], oid[2:4], oid))
 	}
+	if path := f.TierObjectPath(oid); len(path) > 0 {
+		paths = append(paths, path)
+	}
 	return paths
 }
 
@@ -162,6 +249,12 @@ func (f *Filesystem) LogDir() string {
 	return f.logdir
 }
 
+// TreeCacheDir returns the directory in which the Git LFS files found in each
+// tree are cached. Unlike the other directories, it is not created here.
+func (f *Filesystem) TreeCacheDir() string {
+	return filepath.Join(f.LFSStorageDir, "cache", "trees")
+}
+
 func (f *Filesystem) TempDir() string {
 	f.mu.Lock()
 	defer f.mu.Unlock()
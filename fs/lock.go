@@ -0,0 +1,252 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// lockPollInterval is how often Lock tries again to acquire a lock held by
+// another process.
+const lockPollInterval = 100 * time.Millisecond
+
+// OperationLock is an advisory lock held by a Git LFS process while it
+// performs an operation, such as downloading an object or pruning, which
+// other Git LFS processes in the same repository must not perform at the
+// same time.
+//
+// Each lock is a file in the lock directory holding the LockInfo of its
+// holder, so that locks left behind by processes which exited without
+// releasing them can be recognized as stale and broken.
+type OperationLock struct {
+	path string
+}
+
+// LockInfo describes the holder of an operation lock.
+type LockInfo struct {
+	// Name is the name of the lock.
+	Name string `json:"-"`
+	// Pid and Hostname identify the process holding the lock.
+	Pid      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+	// Command is the Git LFS command the process is running.
+	Command string `json:"command"`
+	// CreatedAt is when the lock was acquired.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Stale returns whether the process holding the lock has exited. Locks held
+// by processes on other hosts, such as those sharing the repository over a
+// network filesystem, are never considered stale.
+func (i *LockInfo) Stale() bool {
+	if hostname, _ := os.Hostname(); hostname != i.Hostname {
+		return false
+	}
+	return !processExists(i.Pid)
+}
+
+// LockedError is returned when an operation lock is held by another process.
+type LockedError struct {
+	Info *LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%q is locked by process %d on %s (git lfs %s) since %s",
+		e.Info.Name, e.Info.Pid, e.Info.Hostname, e.Info.Command,
+		e.Info.CreatedAt.Format(time.RFC3339))
+}
+
+// IsLockedError returns whether the given error was returned because an
+// operation lock is held by another process.
+func IsLockedError(err error) bool {
+	_, ok := err.(*LockedError)
+	return ok
+}
+
+// LockDir returns the directory holding operation locks. Unlike most of the
+// other directories, it is not created here.
+func (f *Filesystem) LockDir() string {
+	return filepath.Join(f.LFSStorageDir, "oplocks")
+}
+
+// ObjectLockName returns the name of the lock held while the object with the
+// given OID is written to local storage.
+func ObjectLockName(oid string) string {
+	return "object-" + normalizeOid(oid)
+}
+
+func (f *Filesystem) lockPath(name string) string {
+	return filepath.Join(f.LockDir(), name+".lock")
+}
+
+// TryLock acquires the operation lock with the given name, breaking it first
+// if it is stale, and returns a *LockedError if another process holds it.
+func (f *Filesystem) TryLock(name string) (*OperationLock, error) {
+	dir := f.LockDir()
+	if err := tools.MkdirAll(dir, f); err != nil {
+		return nil, err
+	}
+
+	// The lock file is linked into place only once it is complete, so
+	// that other processes never read a partially written one.
+	tmp, err := ioutil.TempFile(dir, name+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = json.NewEncoder(tmp).Encode(newLockInfo())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	path := f.lockPath(name)
+	for {
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			return &OperationLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		info, err := f.readLock(name)
+		if os.IsNotExist(err) {
+			// Released in the meantime, so try again.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.Stale() {
+			return nil, &LockedError{Info: info}
+		}
+
+		tracerx.Printf("fs: breaking stale lock %q held by process %d", name, info.Pid)
+		if err := f.breakLock(name, info); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// Lock acquires the operation lock with the given name, waiting for up to the
+// given timeout for another process holding it to release it. It returns a
+// *LockedError if the lock is still held after the timeout.
+func (f *Filesystem) Lock(name string, timeout time.Duration) (*OperationLock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		lock, err := f.TryLock(name)
+		if err == nil || !IsLockedError(err) || !time.Now().Before(deadline) {
+			return lock, err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Unlock releases the lock.
+func (l *OperationLock) Unlock() error {
+	if l == nil {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// EachLock calls fn for each operation lock, in order of name.
+func (f *Filesystem) EachLock(fn func(*LockInfo) error) error {
+	matches, err := filepath.Glob(filepath.Join(f.LockDir(), "*.lock"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+
+	for _, match := range matches {
+		info, err := f.readLock(strings.TrimSuffix(filepath.Base(match), ".lock"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BreakLock removes the operation lock with the given name, whether or not it
+// is stale, so that another process may acquire it.
+func (f *Filesystem) BreakLock(name string) error {
+	return os.Remove(f.lockPath(name))
+}
+
+// breakLock removes the operation lock with the given name if it is still
+// held as described by info. Since another process may have broken the same
+// stale lock and acquired it since info was read, the lock file is moved
+// aside first, and put back if it has changed.
+func (f *Filesystem) breakLock(name string, info *LockInfo) error {
+	path := f.lockPath(name)
+	aside := fmt.Sprintf("%s.broken-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		return err
+	}
+	defer os.Remove(aside)
+
+	current, err := readLockFile(aside)
+	if err == nil && (current.Pid != info.Pid || current.Hostname != info.Hostname ||
+		!current.CreatedAt.Equal(info.CreatedAt)) {
+		// If the lock has been acquired again in the meantime, this
+		// fails, and the new holder keeps it.
+		os.Link(aside, path)
+	}
+	return nil
+}
+
+func (f *Filesystem) readLock(name string) (*LockInfo, error) {
+	info, err := readLockFile(f.lockPath(name))
+	if err != nil {
+		return nil, err
+	}
+	info.Name = name
+	return info, nil
+}
+
+func readLockFile(path string) (*LockInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &LockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("invalid lock file %q: %v", path, err)
+	}
+	return info, nil
+}
+
+func newLockInfo() *LockInfo {
+	hostname, _ := os.Hostname()
+
+	var command string
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	return &LockInfo{
+		Pid:       os.Getpid(),
+		Hostname:  hostname,
+		Command:   command,
+		CreatedAt: time.Now(),
+	}
+}
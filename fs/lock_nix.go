@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package fs
+
+import "syscall"
+
+// processExists returns whether a process with the given PID is running.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,122 @@
+package fs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestLock writes a lock file with the given name held by the given
+// process on this host.
+func writeTestLock(t *testing.T, f *Filesystem, name string, pid int) {
+	info := newLockInfo()
+	info.Pid = pid
+	data, err := json.Marshal(info)
+	require.Nil(t, err)
+
+	require.Nil(t, os.MkdirAll(f.LockDir(), 0755))
+	require.Nil(t, ioutil.WriteFile(f.lockPath(name), data, 0644))
+}
+
+// exitedPid returns the PID of a process which has exited.
+func exitedPid(t *testing.T) int {
+	cmd := exec.Command("git", "version")
+	require.Nil(t, cmd.Run())
+	return cmd.Process.Pid
+}
+
+func TestTryLock(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+
+	lock, err := f.TryLock("prune")
+	require.Nil(t, err)
+
+	_, err = f.TryLock("prune")
+	if assert.True(t, IsLockedError(err)) {
+		info := err.(*LockedError).Info
+		assert.Equal(t, "prune", info.Name)
+		assert.Equal(t, os.Getpid(), info.Pid)
+	}
+
+	// Other locks are independent.
+	other, err := f.TryLock(ObjectLockName(cleanupTestOid))
+	require.Nil(t, err)
+	require.Nil(t, other.Unlock())
+
+	require.Nil(t, lock.Unlock())
+	lock, err = f.TryLock("prune")
+	require.Nil(t, err)
+	require.Nil(t, lock.Unlock())
+}
+
+func TestTryLockBreaksStaleLock(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	writeTestLock(t, f, "prune", exitedPid(t))
+
+	lock, err := f.TryLock("prune")
+	require.Nil(t, err)
+
+	var infos []*LockInfo
+	require.Nil(t, f.EachLock(func(info *LockInfo) error {
+		infos = append(infos, info)
+		return nil
+	}))
+	if assert.Len(t, infos, 1) {
+		assert.Equal(t, os.Getpid(), infos[0].Pid)
+		assert.False(t, infos[0].Stale())
+	}
+	require.Nil(t, lock.Unlock())
+}
+
+func TestLockTimesOut(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	writeTestLock(t, f, "migrate", os.Getpid())
+
+	start := time.Now()
+	_, err := f.Lock("migrate", 3*lockPollInterval)
+	assert.True(t, IsLockedError(err))
+	assert.True(t, time.Since(start) >= 3*lockPollInterval)
+}
+
+func TestLockWaitsForRelease(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	held, err := f.TryLock("migrate")
+	require.Nil(t, err)
+
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		held.Unlock()
+	}()
+
+	lock, err := f.Lock("migrate", time.Minute)
+	require.Nil(t, err)
+	require.Nil(t, lock.Unlock())
+}
+
+func TestEachLockAndBreakLock(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	writeTestLock(t, f, "migrate", os.Getpid())
+	writeTestLock(t, f, "prune", exitedPid(t))
+
+	var names []string
+	var stale []bool
+	require.Nil(t, f.EachLock(func(info *LockInfo) error {
+		names = append(names, info.Name)
+		stale = append(stale, info.Stale())
+		return nil
+	}))
+	assert.Equal(t, []string{"migrate", "prune"}, names)
+	assert.Equal(t, []bool{false, true}, stale)
+
+	require.Nil(t, f.BreakLock("migrate"))
+	assert.True(t, os.IsNotExist(f.BreakLock("migrate")))
+	lock, err := f.TryLock("migrate")
+	require.Nil(t, err)
+	require.Nil(t, lock.Unlock())
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package fs
+
+import "os"
+
+// processExists returns whether a process with the given PID is running. On
+// Windows, finding a process opens it, which fails if it does not exist.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,256 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// ObjectStore stores the content of Git LFS objects at rest.
+//
+// Whatever the backend, objects are written to the object directory by
+// transfers and the clean filter before being moved into the backend by
+// MoveToStore. They are read from either with OpenObject, and are only
+// unpacked into the object directory by UnpackedObjectPath, for callers which
+// need a file.
+type ObjectStore interface {
+	// Name returns the name by which the backend is configured with
+	// lfs.storage.backend.
+	Name() string
+	// Has returns whether the store holds the object with the given OID
+	// and size.
+	Has(oid string, size int64) bool
+	// Open returns a reader of the content of the object with the given
+	// OID, or an error satisfying os.IsNotExist if there is none.
+	Open(oid string) (io.ReadCloser, error)
+	// Put stores the content of the file at the given path, which has
+	// already been verified, as the object with the given OID.
+	Put(oid, path string) error
+	// Remove removes the object with the given OID, if any.
+	Remove(oid string) error
+	// Each calls fn for each object in the store.
+	Each(fn func(Object) error) error
+}
+
+const (
+	// LooseBackend stores each object as a file in a directory sharded by
+	// the first four characters of its OID.
+	LooseBackend = "loose"
+	// PackBackend stores objects in a few large pack files, with an index
+	// of where each is. It takes the place of an SQLite-indexed store,
+	// which would need a database library that Git LFS does not depend on.
+	PackBackend = "pack"
+)
+
+// ObjectStore returns the backend configured with lfs.storage.backend.
+func (f *Filesystem) ObjectStore() (ObjectStore, error) {
+	f.storeMu.Lock()
+	defer f.storeMu.Unlock()
+
+	if f.store != nil {
+		return f.store, nil
+	}
+
+	switch f.Backend {
+	case "", LooseBackend:
+		f.store = &looseStore{fs: f}
+	case PackBackend:
+		f.store = newPackStore(f)
+	default:
+		return nil, fmt.Errorf("unknown object storage backend %q", f.Backend)
+	}
+	return f.store, nil
+}
+
+// packedStore returns the store in which objects may be held rather than in
+// the object directory, or nil. That is the configured backend, or with the
+// loose backend, the packs written by PackObject, if there are any.
+func (f *Filesystem) packedStore() ObjectStore {
+	store, err := f.ObjectStore()
+	if err != nil {
+		return nil
+	}
+	if _, loose := store.(*looseStore); !loose {
+		return store
+	}
+
+	packs := f.packStore()
+	if !tools.FileExists(packs.indexPath()) {
+		return nil
+	}
+	return packs
+}
+
+// packStore returns the packs in the storage directory, whether or not they
+// are the configured backend.
+func (f *Filesystem) packStore() *packStore {
+	if store, _ := f.ObjectStore(); store != nil {
+		if packs, ok := store.(*packStore); ok {
+			return packs
+		}
+	}
+
+	f.storeMu.Lock()
+	defer f.storeMu.Unlock()
+
+	if f.packs == nil {
+		f.packs = newPackStore(f)
+	}
+	return f.packs
+}
+
+// PackObject moves the object with the given OID from the object directory
+// into the packs, removing its file, whatever the configured backend. Packed
+// objects are read from the packs as if they were held by the backend.
+func (f *Filesystem) PackObject(oid string) error {
+	path := f.ObjectPathname(oid)
+	if err := f.packStore().Put(oid, path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// MoveToStore moves the object with the given OID from the object directory
+// into the configured backend, removing its file. It does nothing with the
+// loose backend.
+func (f *Filesystem) MoveToStore(oid string) error {
+	store, err := f.ObjectStore()
+	if err != nil {
+		return err
+	}
+	if _, loose := store.(*looseStore); loose {
+		return nil
+	}
+
+	path := f.ObjectPathname(oid)
+	if err := store.Put(oid, path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// OpenObject returns a reader of the content of the object with the given OID
+// from the object directory or, if it is not there, the configured backend, the
+// read-only object store or secondary storage, without unpacking or recalling
+// it.
+func (f *Filesystem) OpenObject(oid string) (io.ReadCloser, error) {
+	r, err := os.Open(f.ObjectPathname(oid))
+	if os.IsNotExist(err) {
+		if store := f.packedStore(); store != nil {
+			r, err := store.Open(oid)
+			if !os.IsNotExist(err) {
+				return r, err
+			}
+		}
+		if path := f.ReadOnlyObjectPath(oid); len(path) > 0 {
+			if r, err := os.Open(path); !os.IsNotExist(err) {
+				return r, err
+			}
+		}
+		if path := f.TierObjectPath(oid); len(path) > 0 {
+			return os.Open(path)
+		}
+	}
+	return r, err
+}
+
+// CompactObjectStore reclaims the space taken by objects removed from the
+// configured backend, if it needs to, and returns the number of bytes freed.
+func (f *Filesystem) CompactObjectStore() (int64, error) {
+	if packs, ok := f.packedStore().(*packStore); ok {
+		return packs.Compact()
+	}
+	return 0, nil
+}
+
+// RemoveObject removes the object with the given OID from both the object
+// directory and the configured backend.
+func (f *Filesystem) RemoveObject(oid string) error {
+	err := os.Remove(f.ObjectPathname(oid))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if store := f.packedStore(); store != nil {
+		return store.Remove(oid)
+	}
+	return err
+}
+
+// unpackObject writes the object with the given OID from the configured
+// backend to the given path in the object directory, if it is held there.
+func (f *Filesystem) unpackObject(oid, path string) error {
+	store := f.packedStore()
+	if store == nil {
+		return nil
+	}
+
+	r, err := store.Open(oid)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp, err := tools.TempFile(f.TempDir(), oid, f)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := tools.NewHashingReader(r)
+	_, err = io.Copy(tmp, hasher)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error unpacking object %s: %v", oid, err)
+	}
+	if actual := hasher.Hash(); actual != oid {
+		return fmt.Errorf("error unpacking object %s: content has OID %s", oid, actual)
+	}
+	return tools.RenameFileCopyPermissions(tmp.Name(), path)
+}
+
+// looseStore is the object directory itself.
+type looseStore struct {
+	fs *Filesystem
+}
+
+func (s *looseStore) Name() string {
+	return LooseBackend
+}
+
+func (s *looseStore) Has(oid string, size int64) bool {
+	return tools.FileExistsOfSize(s.fs.ObjectPathname(oid), size)
+}
+
+func (s *looseStore) Open(oid string) (io.ReadCloser, error) {
+	return os.Open(s.fs.ObjectPathname(oid))
+}
+
+func (s *looseStore) Put(oid, path string) error {
+	dest, err := s.fs.ObjectPath(oid)
+	if err != nil {
+		return err
+	}
+	if dest == path {
+		return nil
+	}
+	return tools.RenameFileCopyPermissions(path, dest)
+}
+
+func (s *looseStore) Remove(oid string) error {
+	err := os.Remove(s.fs.ObjectPathname(oid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *looseStore) Each(fn func(Object) error) error {
+	return s.fs.eachLooseObject(fn)
+}
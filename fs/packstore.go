@@ -0,0 +1,520 @@
+package fs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+const (
+	// packMaxSize is the size beyond which no more objects are added to a
+	// pack file, and a new one is started.
+	packMaxSize = 1 << 30
+	// packLockName is the name of the operation lock held while the packs
+	// or their index are written.
+	packLockName = "pack"
+	// packLockTimeout is how long to wait for another process writing the
+	// packs.
+	packLockTimeout = 5 * time.Minute
+
+	// packIndexRecordSize is the size of each record in the index: an
+	// operation, the binary OID, the pack number, and the offset and size
+	// of the object within it.
+	packIndexRecordSize = 1 + 32 + 4 + 8 + 8
+
+	packIndexAdd    = '+'
+	packIndexRemove = '-'
+)
+
+// packEntry is where an object is held in the packs.
+type packEntry struct {
+	pack   uint32
+	offset int64
+	size   int64
+}
+
+// packStore stores objects in pack files, which hold the content of objects
+// one after another, in the "packs" directory alongside the object directory.
+// The packs are indexed by a log of the objects added to and removed from
+// them, which is only appended to, so that it need not be rewritten as objects
+// are added, and is read again whenever it has changed. Removed objects take up
+// space in the packs until they are compacted.
+type packStore struct {
+	dir string
+	fs  *Filesystem
+
+	mu      sync.Mutex
+	entries map[string]*packEntry
+	// packEnds holds the end of the last object recorded in the index as
+	// written to each pack, including those since removed.
+	packEnds  map[uint32]int64
+	indexSize int64
+	indexMod  time.Time
+}
+
+func newPackStore(f *Filesystem) *packStore {
+	return &packStore{
+		dir: filepath.Join(f.LFSStorageDir, "packs"),
+		fs:  f,
+	}
+}
+
+func (s *packStore) Name() string {
+	return PackBackend
+}
+
+func (s *packStore) indexPath() string {
+	return filepath.Join(s.dir, "index")
+}
+
+func (s *packStore) packPath(pack uint32) string {
+	return filepath.Join(s.dir, fmt.Sprintf("pack-%d.pack", pack))
+}
+
+func (s *packStore) Has(oid string, size int64) bool {
+	entry, err := s.lookup(oid)
+	return err == nil && entry != nil && entry.size == size
+}
+
+func (s *packStore) Open(oid string) (io.ReadCloser, error) {
+	entry, err := s.lookup(oid)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, &os.PathError{Op: "open", Path: oid, Err: os.ErrNotExist}
+	}
+
+	f, err := os.Open(s.packPath(entry.pack))
+	if os.IsNotExist(err) {
+		// The packs have been compacted by another process since the
+		// index was read, so read it again.
+		s.mu.Lock()
+		s.entries = nil
+		s.mu.Unlock()
+		if entry, err = s.lookup(oid); err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, &os.PathError{Op: "open", Path: oid, Err: os.ErrNotExist}
+		}
+		f, err = os.Open(s.packPath(entry.pack))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &packObjectReader{
+		Reader: io.NewSectionReader(f, entry.offset, entry.size),
+		Closer: f,
+	}, nil
+}
+
+type packObjectReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (s *packStore) Put(oid, path string) error {
+	lock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if entry := s.entry(oid); entry != nil && entry.size == fi.Size() {
+		return nil
+	}
+
+	pack, offset, err := s.appendToPack(path)
+	if err != nil {
+		return err
+	}
+	return s.appendToIndex(packIndexAdd, oid, &packEntry{pack: pack, offset: offset, size: fi.Size()})
+}
+
+func (s *packStore) Remove(oid string) error {
+	lock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	if s.entry(oid) == nil {
+		return nil
+	}
+	return s.appendToIndex(packIndexRemove, oid, &packEntry{})
+}
+
+func (s *packStore) Each(fn func(Object) error) error {
+	s.mu.Lock()
+	if err := s.refreshLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	objects := make([]Object, 0, len(s.entries))
+	for oid, entry := range s.entries {
+		objects = append(objects, Object{Oid: oid, Size: entry.size})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Oid < objects[j].Oid })
+	for _, o := range objects {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the packs without the space taken by removed objects, and
+// returns the number of bytes freed.
+func (s *packStore) Compact() (int64, error) {
+	lock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return 0, err
+	}
+
+	var before int64
+	oldPacks, _ := filepath.Glob(filepath.Join(s.dir, "pack-*.pack"))
+	for _, path := range oldPacks {
+		if fi, err := os.Stat(path); err == nil {
+			before += fi.Size()
+		}
+	}
+
+	// Write the objects to new packs, numbered after the existing ones,
+	// with a new index, which replaces the old one only once complete.
+	s.mu.Lock()
+	entries := s.entries
+	s.mu.Unlock()
+
+	var live int64
+	oids := make([]string, 0, len(entries))
+	for oid, entry := range entries {
+		oids = append(oids, oid)
+		live += entry.size
+	}
+	sort.Strings(oids)
+
+	// There is nothing to reclaim unless objects have been removed.
+	if live == before {
+		return 0, nil
+	}
+
+	next := s.lastPack() + 1
+
+	index, err := ioutil.TempFile(s.dir, "index")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(index.Name())
+
+	indexw := bufio.NewWriter(index)
+	var after int64
+	var out *os.File
+	var outSize int64
+	for _, oid := range oids {
+		entry := entries[oid]
+		if out == nil || outSize >= packMaxSize {
+			if out != nil {
+				if err := out.Close(); err != nil {
+					return 0, err
+				}
+				next++
+			}
+			out, err = os.OpenFile(s.packPath(next), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if err != nil {
+				return 0, err
+			}
+			outSize = 0
+		}
+
+		in, err := os.Open(s.packPath(entry.pack))
+		if err != nil {
+			out.Close()
+			return 0, err
+		}
+		_, err = io.Copy(out, io.NewSectionReader(in, entry.offset, entry.size))
+		in.Close()
+		if err != nil {
+			out.Close()
+			return 0, err
+		}
+
+		newEntry := &packEntry{pack: next, offset: outSize, size: entry.size}
+		if _, err := indexw.Write(encodePackIndexRecord(packIndexAdd, oid, newEntry)); err != nil {
+			out.Close()
+			return 0, err
+		}
+		outSize += entry.size
+		after += entry.size
+	}
+	if out != nil {
+		if err := out.Close(); err != nil {
+			return 0, err
+		}
+	}
+	if err := indexw.Flush(); err != nil {
+		return 0, err
+	}
+	if err := index.Close(); err != nil {
+		return 0, err
+	}
+	if err := tools.RobustRename(index.Name(), s.indexPath()); err != nil {
+		return 0, err
+	}
+
+	for _, path := range oldPacks {
+		os.Remove(path)
+	}
+
+	s.mu.Lock()
+	s.entries = nil
+	err = s.refreshLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// lookup returns where the object with the given OID is held, or nil if it
+// is not, reading the index again if it has changed.
+func (s *packStore) lookup(oid string) (*packEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[normalizeOid(oid)]; ok {
+		return entry, nil
+	}
+	if err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	return s.entries[normalizeOid(oid)], nil
+}
+
+// entry returns where the object with the given OID is held according to
+// the index as last read, or nil if it is not.
+func (s *packStore) entry(oid string) *packEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[normalizeOid(oid)]
+}
+
+func (s *packStore) refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshLocked()
+}
+
+// refreshLocked reads the index again if it has changed since it was last
+// read. A partial record at the end, written by a process which was
+// interrupted, is ignored.
+func (s *packStore) refreshLocked() error {
+	fi, err := os.Stat(s.indexPath())
+	if os.IsNotExist(err) {
+		s.entries = make(map[string]*packEntry)
+		s.packEnds = make(map[uint32]int64)
+		s.indexSize = 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.entries != nil && fi.Size() == s.indexSize && fi.ModTime().Equal(s.indexMod) {
+		return nil
+	}
+
+	f, err := os.Open(s.indexPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries := make(map[string]*packEntry)
+	packEnds := make(map[uint32]int64)
+	r := bufio.NewReader(f)
+	record := make([]byte, packIndexRecordSize)
+	var size int64
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return err
+		}
+		size += packIndexRecordSize
+
+		op, oid, entry := decodePackIndexRecord(record)
+		switch op {
+		case packIndexAdd:
+			entries[oid] = entry
+			if end := entry.offset + entry.size; end > packEnds[entry.pack] {
+				packEnds[entry.pack] = end
+			}
+		case packIndexRemove:
+			delete(entries, oid)
+		default:
+			return fmt.Errorf("invalid pack index %q", s.indexPath())
+		}
+	}
+
+	s.entries = entries
+	s.packEnds = packEnds
+	s.indexSize = size
+	s.indexMod = fi.ModTime()
+	return nil
+}
+
+// appendToPack appends the content of the file at the given path to the last
+// pack, or a new one if it is full, and returns the pack and the offset at
+// which it was written. The index must have been read with the pack lock
+// held.
+func (s *packStore) appendToPack(path string) (uint32, int64, error) {
+	if err := tools.MkdirAll(s.dir, s.fs); err != nil {
+		return 0, 0, err
+	}
+
+	pack := s.lastPack()
+	s.mu.Lock()
+	full := s.packEnds[pack] >= packMaxSize
+	s.mu.Unlock()
+	if full {
+		pack++
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(s.packPath(pack), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Objects are written after the last one in the index, truncating
+	// anything after it, such as a partial object written by a process
+	// which was interrupted before recording it in the index.
+	s.mu.Lock()
+	offset := s.packEnds[pack]
+	s.mu.Unlock()
+	err = out.Truncate(offset)
+	if err == nil {
+		_, err = out.Seek(offset, io.SeekStart)
+	}
+	if err == nil {
+		_, err = io.Copy(out, in)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return pack, offset, nil
+}
+
+// appendToIndex appends a record of the given operation to the index.
+func (s *packStore) appendToIndex(op byte, oid string, entry *packEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.indexPath(), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	// Overwrite any partial record left by an interrupted process.
+	if _, err := f.Seek(s.indexSize, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	_, err = f.Write(encodePackIndexRecord(op, oid, entry))
+	if err == nil {
+		err = f.Truncate(s.indexSize + packIndexRecordSize)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	oid = normalizeOid(oid)
+	if op == packIndexAdd {
+		s.entries[oid] = entry
+		if end := entry.offset + entry.size; end > s.packEnds[entry.pack] {
+			s.packEnds[entry.pack] = end
+		}
+	} else {
+		delete(s.entries, oid)
+	}
+	s.indexSize += packIndexRecordSize
+	if fi, err := os.Stat(s.indexPath()); err == nil {
+		s.indexMod = fi.ModTime()
+	}
+	return nil
+}
+
+// lastPack returns the number of the last pack, or zero if there are none.
+func (s *packStore) lastPack() uint32 {
+	var last uint32
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "pack-*.pack"))
+	for _, match := range matches {
+		var n uint32
+		if _, err := fmt.Sscanf(filepath.Base(match), "pack-%d.pack", &n); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+func (s *packStore) lock() (*OperationLock, error) {
+	return s.fs.Lock(packLockName, packLockTimeout)
+}
+
+func encodePackIndexRecord(op byte, oid string, entry *packEntry) []byte {
+	record := make([]byte, packIndexRecordSize)
+	record[0] = op
+	hex.Decode(record[1:33], []byte(normalizeOid(oid)))
+	binary.BigEndian.PutUint32(record[33:37], entry.pack)
+	binary.BigEndian.PutUint64(record[37:45], uint64(entry.offset))
+	binary.BigEndian.PutUint64(record[45:53], uint64(entry.size))
+	return record
+}
+
+func decodePackIndexRecord(record []byte) (byte, string, *packEntry) {
+	return record[0], hex.EncodeToString(record[1:33]), &packEntry{
+		pack:   binary.BigEndian.Uint32(record[33:37]),
+		offset: int64(binary.BigEndian.Uint64(record[37:45])),
+		size:   int64(binary.BigEndian.Uint64(record[45:53])),
+	}
+}
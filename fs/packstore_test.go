@@ -0,0 +1,197 @@
+package fs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPackTestFilesystem(t *testing.T) *Filesystem {
+	f := newAnomalyTestFilesystem(t)
+	f.Backend = PackBackend
+	return f
+}
+
+// writeLooseTestObject writes an object with the given content into the
+// object directory, and returns its OID.
+func writeLooseTestObject(t *testing.T, f *Filesystem, content string) string {
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+
+	path := f.ObjectPathname(oid)
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return oid
+}
+
+func readTestObject(t *testing.T, f *Filesystem, oid string) string {
+	r, err := f.OpenObject(oid)
+	require.Nil(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+	return string(data)
+}
+
+func TestObjectStoreUnknownBackend(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	f.Backend = "sqlite"
+
+	_, err := f.ObjectStore()
+	assert.EqualError(t, err, `unknown object storage backend "sqlite"`)
+}
+
+func TestMoveToStoreLoose(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	oid := writeLooseTestObject(t, f, "loose")
+
+	require.Nil(t, f.MoveToStore(oid))
+	assert.FileExists(t, f.ObjectPathname(oid))
+}
+
+func TestPackStoreMoveAndOpen(t *testing.T) {
+	f := newPackTestFilesystem(t)
+	first := writeLooseTestObject(t, f, "first")
+	second := writeLooseTestObject(t, f, "second object")
+
+	require.Nil(t, f.MoveToStore(first))
+	require.Nil(t, f.MoveToStore(second))
+
+	assert.NoFileExists(t, f.ObjectPathname(first))
+	assert.True(t, f.ObjectExists(first, 5))
+	assert.False(t, f.ObjectExists(first, 6))
+	assert.Equal(t, "first", readTestObject(t, f, first))
+	assert.Equal(t, "second object", readTestObject(t, f, second))
+
+	// Another process sees the objects through the index.
+	other := newPackStore(f)
+	assert.True(t, other.Has(second, 13))
+}
+
+func TestPackStoreOverwritesPartialObject(t *testing.T) {
+	f := newPackTestFilesystem(t)
+	first := writeLooseTestObject(t, f, "first")
+	require.Nil(t, f.MoveToStore(first))
+
+	// A process interrupted while packing an object leaves part of it at
+	// the end of the pack, without recording it in the index.
+	packs := newPackStore(f)
+	out, err := os.OpenFile(packs.packPath(0), os.O_WRONLY|os.O_APPEND, 0644)
+	require.Nil(t, err)
+	_, err = out.Write([]byte("partial"))
+	require.Nil(t, err)
+	require.Nil(t, out.Close())
+
+	second := writeLooseTestObject(t, f, "second")
+	require.Nil(t, f.MoveToStore(second))
+
+	fi, err := os.Stat(packs.packPath(0))
+	require.Nil(t, err)
+	assert.Equal(t, int64(len("first")+len("second")), fi.Size())
+	assert.Equal(t, "first", readTestObject(t, f, first))
+	assert.Equal(t, "second", readTestObject(t, f, second))
+}
+
+func TestPackStoreUnpackedObjectPath(t *testing.T) {
+	f := newPackTestFilesystem(t)
+	oid := writeLooseTestObject(t, f, "unpack me")
+	require.Nil(t, f.MoveToStore(oid))
+
+	// Packed objects are only unpacked when asked for.
+	path, err := f.ObjectPath(oid)
+	require.Nil(t, err)
+	assert.NoFileExists(t, path)
+
+	path, err = f.UnpackedObjectPath(oid)
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, "unpack me", string(data))
+}
+
+func TestPackStoreEachObject(t *testing.T) {
+	f := newPackTestFilesystem(t)
+	packed := writeLooseTestObject(t, f, "packed")
+	require.Nil(t, f.MoveToStore(packed))
+	loose := writeLooseTestObject(t, f, "loose")
+
+	// An object both packed and unpacked is only listed once.
+	_, err := f.UnpackedObjectPath(packed)
+	require.Nil(t, err)
+
+	var oids []string
+	require.Nil(t, f.EachObject(func(obj Object) error {
+		oids = append(oids, obj.Oid)
+		return nil
+	}))
+
+	expected := []string{packed, loose}
+	sort.Strings(expected)
+	sort.Strings(oids)
+	assert.Equal(t, expected, oids)
+}
+
+func TestPackStoreRemoveAndCompact(t *testing.T) {
+	f := newPackTestFilesystem(t)
+	removed := writeLooseTestObject(t, f, "removed")
+	kept := writeLooseTestObject(t, f, "kept")
+	require.Nil(t, f.MoveToStore(removed))
+	require.Nil(t, f.MoveToStore(kept))
+
+	require.Nil(t, f.RemoveObject(removed))
+	assert.False(t, f.ObjectExists(removed, 7))
+
+	_, err := f.OpenObject(removed)
+	assert.True(t, os.IsNotExist(err))
+
+	freed, err := f.CompactObjectStore()
+	require.Nil(t, err)
+	assert.Equal(t, int64(7), freed)
+	assert.Equal(t, "kept", readTestObject(t, f, kept))
+
+	// Nothing is left to reclaim.
+	freed, err = f.CompactObjectStore()
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), freed)
+}
+
+func TestPackObjectLoose(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	packed := writeLooseTestObject(t, f, "small")
+	loose := writeLooseTestObject(t, f, "left loose")
+
+	require.Nil(t, f.PackObject(packed))
+	assert.NoFileExists(t, f.ObjectPathname(packed))
+
+	// Packed objects are read as if the packs were the backend.
+	assert.True(t, f.ObjectExists(packed, 5))
+	assert.Equal(t, "small", readTestObject(t, f, packed))
+
+	var oids []string
+	require.Nil(t, f.EachObject(func(obj Object) error {
+		oids = append(oids, obj.Oid)
+		return nil
+	}))
+	expected := []string{packed, loose}
+	sort.Strings(expected)
+	sort.Strings(oids)
+	assert.Equal(t, expected, oids)
+
+	path, err := f.UnpackedObjectPath(packed)
+	require.Nil(t, err)
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, "small", string(data))
+
+	require.Nil(t, f.RemoveObject(packed))
+	assert.False(t, f.ObjectExists(packed, 5))
+}
@@ -0,0 +1,28 @@
+package fs
+
+import (
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// ReadOnlyObjectPath returns the path of the object with the given OID in the
+// read-only object store configured with lfs.storage and
+// lfs.storage.readonly, or the empty string if there is none.
+func (f *Filesystem) ReadOnlyObjectPath(oid string) string {
+	if len(f.ReadOnlyDir) == 0 {
+		return ""
+	}
+	oid = normalizeOid(oid)
+	return filepath.Join(f.ReadOnlyDir, oid[0:2], oid[2:4], oid)
+}
+
+// IsReadOnlyObject returns whether the object with the given OID is held only
+// by the read-only object store, and so must not be moved or removed.
+func (f *Filesystem) IsReadOnlyObject(oid string) bool {
+	path := f.ReadOnlyObjectPath(oid)
+	if len(path) == 0 || tools.FileExists(f.ObjectPathname(oid)) {
+		return false
+	}
+	return tools.FileExists(path)
+}
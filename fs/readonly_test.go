@@ -0,0 +1,59 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReadOnlyTestFilesystem returns a filesystem whose read-only object store
+// holds an object with the given content, and that object's OID.
+func newReadOnlyTestFilesystem(t *testing.T, content string) (*Filesystem, string) {
+	shared := newAnomalyTestFilesystem(t)
+	oid := writeLooseTestObject(t, shared, content)
+
+	f := newAnomalyTestFilesystem(t)
+	f.ReadOnlyDir = shared.LFSObjectDir()
+	return f, oid
+}
+
+func TestReadOnlyObjectIsReadInPlace(t *testing.T) {
+	f, oid := newReadOnlyTestFilesystem(t, "shared")
+
+	assert.True(t, f.ObjectExists(oid, 6))
+	assert.False(t, f.ObjectExists(oid, 7))
+	assert.True(t, f.IsReadOnlyObject(oid))
+	assert.Equal(t, "shared", readTestObject(t, f, oid))
+
+	path, err := f.UnpackedObjectPath(oid)
+	require.Nil(t, err)
+	assert.Equal(t, f.ReadOnlyObjectPath(oid), path)
+	assert.NoFileExists(t, f.ObjectPathname(oid))
+}
+
+func TestReadOnlyObjectsAreLeftAlone(t *testing.T) {
+	f, oid := newReadOnlyTestFilesystem(t, "shared")
+	local := writeLooseTestObject(t, f, "local")
+
+	var oids []string
+	require.Nil(t, f.EachObject(func(o Object) error {
+		oids = append(oids, o.Oid)
+		return nil
+	}))
+	assert.Equal(t, []string{local}, oids)
+
+	f.RemoveObject(oid)
+	assert.FileExists(t, f.ReadOnlyObjectPath(oid))
+	assert.False(t, f.IsReadOnlyObject(local))
+}
+
+func TestOverlayObjectTakesPrecedence(t *testing.T) {
+	f, oid := newReadOnlyTestFilesystem(t, "shared")
+	writeLooseTestObject(t, f, "shared")
+
+	assert.False(t, f.IsReadOnlyObject(oid))
+	path, err := f.UnpackedObjectPath(oid)
+	require.Nil(t, err)
+	assert.Equal(t, f.ObjectPathname(oid), path)
+}
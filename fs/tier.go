@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// TierObjectPath returns the path of the object with the given OID in the
+// secondary storage configured with lfs.tier.path, or the empty string if
+// there is none.
+func (f *Filesystem) TierObjectPath(oid string) string {
+	if len(f.TierDir) == 0 {
+		return ""
+	}
+	oid = normalizeOid(oid)
+	return filepath.Join(f.TierDir, oid[0:2], oid[2:4], oid)
+}
+
+// TierObject moves the object with the given OID from the object directory to
+// secondary storage, where it is found by ObjectReferencePaths, and so is
+// recalled into the object directory by the next command which needs it. The
+// copy in secondary storage is kept on recall, so an object moved again need
+// not be copied again.
+func (f *Filesystem) TierObject(oid string) error {
+	dest := f.TierObjectPath(oid)
+	if len(dest) == 0 {
+		return fmt.Errorf("no secondary storage is configured with lfs.tier.path")
+	}
+
+	src := f.ObjectPathname(oid)
+	stat, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !tools.FileExistsOfSize(dest, stat.Size()) {
+		if err := f.copyToTier(src, dest, stat.Size()); err != nil {
+			return err
+		}
+	}
+	return os.Remove(src)
+}
+
+// copyToTier copies the object at src to dest in secondary storage, through a
+// temporary file there, so that an interrupted copy is never taken for the
+// object.
+func (f *Filesystem) copyToTier(src, dest string, size int64) error {
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(dest)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, in)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error copying object to %s: %v", dir, err)
+	}
+	if n != size {
+		return fmt.Errorf("error copying object to %s: wrote %d of %d bytes", dir, n, size)
+	}
+	return tools.RobustRename(tmp.Name(), dest)
+}
@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTierTestFilesystem(t *testing.T) *Filesystem {
+	f := newAnomalyTestFilesystem(t)
+	f.TierDir = filepath.Join(f.LFSStorageDir, "tier")
+	return f
+}
+
+func TestTierObjectMovesObject(t *testing.T) {
+	f := newTierTestFilesystem(t)
+	oid := writeLooseTestObject(t, f, "tiered")
+
+	require.Nil(t, f.TierObject(oid))
+	assert.NoFileExists(t, f.ObjectPathname(oid))
+	assert.FileExists(t, f.TierObjectPath(oid))
+	assert.Contains(t, f.ObjectReferencePaths(oid), f.TierObjectPath(oid))
+	assert.Equal(t, "tiered", readTestObject(t, f, oid))
+}
+
+func TestTierObjectKeepsExistingCopy(t *testing.T) {
+	f := newTierTestFilesystem(t)
+	oid := writeLooseTestObject(t, f, "tiered")
+
+	require.Nil(t, f.TierObject(oid))
+	writeLooseTestObject(t, f, "tiered")
+	require.Nil(t, f.TierObject(oid))
+
+	assert.NoFileExists(t, f.ObjectPathname(oid))
+	assert.Equal(t, "tiered", readTestObject(t, f, oid))
+}
+
+func TestTierObjectWithoutTierDir(t *testing.T) {
+	f := newAnomalyTestFilesystem(t)
+	oid := writeLooseTestObject(t, f, "loose")
+
+	assert.Empty(t, f.TierObjectPath(oid))
+	assert.NotNil(t, f.TierObject(oid))
+	assert.FileExists(t, f.ObjectPathname(oid))
+}
@@ -11,14 +11,20 @@ import (
 )
 
 func main() {
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt, os.Kill)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM)
 
 	var once sync.Once
 
 	go func() {
 		for {
 			sig := <-c
+			if commands.Interrupt(sig) {
+				// The command exits by itself once the
+				// transfers in progress have stopped.
+				fmt.Fprintf(os.Stderr, "\nStopping because of %q signal, once the transfers in progress have been saved. Interrupt again to exit immediately.\n", sig)
+				sig = <-c
+			}
 			once.Do(commands.Cleanup)
 			fmt.Fprintf(os.Stderr, "\nExiting because of %q signal.\n", sig)
 
@@ -13,8 +13,10 @@ import (
 )
 
 const (
-	LockableAttrib = "lockable"
-	FilterAttrib   = "filter"
+	LockableAttrib    = "lockable"
+	FilterAttrib      = "filter"
+	MinSizeAttrib     = "lfs-min-size"
+	ContentTypeAttrib = "lfs-content-type"
 )
 
 // AttributePath is a path entry in a gitattributes file which has the LFS filter
@@ -27,6 +29,10 @@ type AttributePath struct {
 	Lockable bool
 	// Path is handled by Git LFS (i.e., filter=lfs)
 	Tracked bool
+	// Conditions holds the lfs-min-size and lfs-content-type attributes
+	// given for the path, if any, with the values "git check-attr" would
+	// report for them.
+	Conditions map[string]string
 }
 
 type AttributeSource struct {
@@ -119,6 +125,7 @@ func AttrPathsFromReader(mp *gitattr.MacroProcessor, path, workingDir string, rd
 		lockable := false
 		tracked := false
 		hasFilter := false
+		var conditions map[string]string
 
 		for _, attr := range line.Attrs {
 			if attr.K == FilterAttrib {
@@ -126,6 +133,11 @@ func AttrPathsFromReader(mp *gitattr.MacroProcessor, path, workingDir string, rd
 				tracked = attr.V == "lfs"
 			} else if attr.K == LockableAttrib && attr.V == "true" {
 				lockable = true
+			} else if attr.K == MinSizeAttrib || attr.K == ContentTypeAttrib {
+				if conditions == nil {
+					conditions = make(map[string]string)
+				}
+				conditions[attr.K] = checkAttrValue(attr)
 			}
 		}
 
@@ -139,10 +151,11 @@ func AttrPathsFromReader(mp *gitattr.MacroProcessor, path, workingDir string, rd
 		}
 
 		paths = append(paths, AttributePath{
-			Path:     pattern,
-			Source:   source,
-			Lockable: lockable,
-			Tracked:  tracked,
+			Path:       pattern,
+			Source:     source,
+			Lockable:   lockable,
+			Tracked:    tracked,
+			Conditions: conditions,
 		})
 	}
 
@@ -151,6 +164,20 @@ func AttrPathsFromReader(mp *gitattr.MacroProcessor, path, workingDir string, rd
 	return paths
 }
 
+// checkAttrValue returns the value "git check-attr" reports for the given
+// attribute.
+func checkAttrValue(attr *gitattr.Attr) string {
+	switch {
+	case attr.Unspecified:
+		return "unspecified"
+	case attr.V == "true":
+		return "set"
+	case attr.V == "false":
+		return "unset"
+	}
+	return attr.V
+}
+
 // GetAttributeFilter returns a list of entries in .gitattributes which are
 // configured with the filter=lfs attribute as a file path filter which
 // file paths can be matched against
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+)
+
+// CatFileSession is a long-lived `git cat-file` process which reports the
+// type and size of objects, so that one process can serve any number of
+// scans, rather than one being started for each. Where supported, requests
+// are sent with `--batch-command --buffer` and flushed together, a batch at a
+// time. It is safe for concurrent use.
+type CatFileSession struct {
+	cmd          *subprocess.BufferedCmd
+	batchCommand bool
+	closed       bool
+	mu           sync.Mutex
+}
+
+// NewCatFileSession starts a new `git cat-file` process.
+func NewCatFileSession() (*CatFileSession, error) {
+	batchCommand := IsGitVersionAtLeast("2.36.0")
+
+	args := []string{"cat-file", "--batch-check"}
+	if batchCommand {
+		args = []string{"cat-file", "--batch-command", "--buffer"}
+	}
+
+	cmd, err := gitNoLFSBuffered(args...)
+	if err != nil {
+		return nil, err
+	}
+	return &CatFileSession{cmd: cmd, batchCommand: batchCommand}, nil
+}
+
+// Check requests the type and size of each of the given objects, and calls fn
+// with a reader from which one line of `git cat-file --batch-check` output
+// can be read for each, in order. fn must read all of them.
+func (s *CatFileSession) Check(oids []string, fn func(r io.Reader) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("git cat-file: session closed")
+	}
+
+	// Write in the background, since Git may block writing its output
+	// until it is read.
+	werr := make(chan error, 1)
+	go func() {
+		w := bufio.NewWriter(s.cmd.Stdin)
+		for _, oid := range oids {
+			if s.batchCommand {
+				w.WriteString("info ")
+			}
+			w.WriteString(oid)
+			w.WriteByte('\n')
+		}
+		if s.batchCommand {
+			w.WriteString("flush\n")
+		}
+		werr <- w.Flush()
+	}()
+
+	err := fn(s.cmd.Stdout)
+	if wErr := <-werr; err == nil && wErr != nil {
+		err = errors.Wrap(wErr, "git cat-file")
+	}
+	return err
+}
+
+// Close stops the process, returning any error it reported.
+func (s *CatFileSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.cmd.Stdin.Close()
+	stderr, _ := ioutil.ReadAll(s.cmd.Stderr)
+	if err := s.cmd.Wait(); err != nil {
+		return errors.Errorf("error in git cat-file: %v %v", err, string(stderr))
+	}
+	return nil
+}
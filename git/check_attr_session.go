@@ -0,0 +1,85 @@
+package git
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+)
+
+// CheckAttrSession is a long-lived `git check-attr` process which reports the
+// values of a fixed set of attributes for paths, so that the filters need not
+// start one for each file. It is safe for concurrent use.
+type CheckAttrSession struct {
+	cmd    *subprocess.BufferedCmd
+	attrs  []string
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewCheckAttrSession starts a new `git check-attr` process reporting the
+// given attributes.
+func NewCheckAttrSession(attrs ...string) (*CheckAttrSession, error) {
+	args := append([]string{"check-attr", "--stdin", "-z"}, attrs...)
+	cmd, err := gitNoLFSBuffered(args...)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckAttrSession{cmd: cmd, attrs: attrs}, nil
+}
+
+// Check returns the values of the session's attributes for the given path,
+// relative to the root of the working tree. Attributes which are set or unset
+// have the values "set" and "unset", and those which are unspecified are left
+// out.
+func (s *CheckAttrSession) Check(path string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, errors.New("git check-attr: session closed")
+	}
+
+	if _, err := s.cmd.Stdin.Write([]byte(path + "\x00")); err != nil {
+		return nil, errors.Wrap(err, "git check-attr")
+	}
+
+	// Each attribute is reported as "<path> NUL <attribute> NUL <info>
+	// NUL".
+	values := make(map[string]string, len(s.attrs))
+	for range s.attrs {
+		var fields [3]string
+		for i := range fields {
+			field, err := s.cmd.Stdout.ReadString(0)
+			if err != nil {
+				return nil, errors.Wrap(err, "git check-attr")
+			}
+			fields[i] = strings.TrimSuffix(field, "\x00")
+		}
+
+		if fields[2] != "unspecified" {
+			values[fields[1]] = fields[2]
+		}
+	}
+	return values, nil
+}
+
+// Close stops the process, returning any error it reported.
+func (s *CheckAttrSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.cmd.Stdin.Close()
+	stderr, _ := ioutil.ReadAll(s.cmd.Stderr)
+	if err := s.cmd.Wait(); err != nil {
+		return errors.Errorf("error in git check-attr: %v %v", err, string(stderr))
+	}
+	return nil
+}
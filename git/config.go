@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -56,7 +57,10 @@ func ParseConfigLines(lines string, onlySafeKeys bool) *ConfigurationSource {
 }
 
 type ConfigurationSource struct {
-	Lines        []string
+	Lines []string
+	// Origins, if set, gives where each of the lines was read from, in the
+	// form "git config --show-origin" prints, such as "file:.git/config".
+	Origins      []string
 	OnlySafeKeys bool
 }
 
@@ -72,6 +76,16 @@ func (c *Configuration) FindGlobal(key string) string {
 	return output
 }
 
+// FindGlobalAll returns all of the git config values in global scope for the
+// key
+func (c *Configuration) FindGlobalAll(key string) []string {
+	output, _ := c.gitConfig("--global", "--get-all", key)
+	if len(output) == 0 {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
 // FindSystem returns the git config value in system scope for the key
 func (c *Configuration) FindSystem(key string) string {
 	output, _ := c.gitConfig("--system", key)
@@ -95,6 +109,18 @@ func (c *Configuration) SetGlobal(key, val string) (string, error) {
 	return c.gitConfigWrite("--global", "--replace-all", key, val)
 }
 
+// AddGlobal adds a git config value for the key in the global config, keeping
+// any it already has
+func (c *Configuration) AddGlobal(key, val string) (string, error) {
+	return c.gitConfigWrite("--global", "--add", key, val)
+}
+
+// UnsetGlobalValue removes the given git config value for the key from the
+// global config
+func (c *Configuration) UnsetGlobalValue(key, val string) (string, error) {
+	return c.gitConfigWrite("--global", "--unset-all", key, "^"+regexp.QuoteMeta(val)+"$")
+}
+
 // SetSystem sets the git config value for the key in the system config
 func (c *Configuration) SetSystem(key, val string) (string, error) {
 	return c.gitConfigWrite("--system", "--replace-all", key, val)
@@ -140,26 +166,43 @@ func (c *Configuration) Sources(dir string, optionalFilename string) ([]*Configu
 	if err != nil {
 		return nil, err
 	}
+	return c.sources(gitconfig, dir, optionalFilename)
+}
 
+// SourcesWithOrigins works as Sources, except that the sources also give
+// where each of their lines was read from.
+func (c *Configuration) SourcesWithOrigins(dir string, optionalFilename string) ([]*ConfigurationSource, error) {
+	gitconfig, err := c.SourceWithOrigins()
+	if err != nil {
+		return nil, err
+	}
+	return c.sources(gitconfig, dir, optionalFilename)
+}
+
+func (c *Configuration) sources(gitconfig *ConfigurationSource, dir string, optionalFilename string) ([]*ConfigurationSource, error) {
 	bare, err := IsBare()
 	if err != nil {
 		return nil, err
 	}
 
+	// The optional file's conditional includes are evaluated by Git LFS,
+	// against the remotes in the repository's configuration.
+	in := newConfigIncluder(c, gitconfig)
+
 	// First try to read from the working directory and then the index if
 	// the file is missing from the working directory.
 	var fileconfig *ConfigurationSource
 	if !bare {
-		fileconfig, err = c.FileSource(filepath.Join(dir, optionalFilename))
+		fileconfig, err = c.fileSource(in, filepath.Join(dir, optionalFilename), dir)
 		if err != nil {
 			if !os.IsNotExist(err) {
 				return nil, err
 			}
-			fileconfig, _ = c.RevisionSource(fmt.Sprintf(":%s", optionalFilename))
+			fileconfig, _ = in.revisionSource(fmt.Sprintf(":%s", optionalFilename))
 		}
 	}
 	if fileconfig == nil {
-		fileconfig, _ = c.RevisionSource(fmt.Sprintf("HEAD:%s", optionalFilename))
+		fileconfig, _ = in.revisionSource(fmt.Sprintf("HEAD:%s", optionalFilename))
 	}
 
 	configs := make([]*ConfigurationSource, 0, 2)
@@ -170,24 +213,24 @@ func (c *Configuration) Sources(dir string, optionalFilename string) ([]*Configu
 	return append(configs, gitconfig), nil
 }
 
+// FileSource reads the configuration file with the given name, along with the
+// files it includes, including those whose "includeIf" conditions Git does
+// not evaluate for a file read on its own.
 func (c *Configuration) FileSource(filename string) (*ConfigurationSource, error) {
-	if _, err := os.Stat(filename); err != nil {
-		return nil, err
-	}
+	return c.fileSource(newConfigIncluder(c, nil), filename, "")
+}
 
-	out, err := c.gitConfig("-l", "-f", filename)
-	if err != nil {
+func (c *Configuration) fileSource(in *configIncluder, filename, root string) (*ConfigurationSource, error) {
+	if _, err := os.Stat(filename); err != nil {
 		return nil, err
 	}
-	return ParseConfigLines(out, true), nil
+	return in.fileSource(filename, root)
 }
 
+// RevisionSource reads the configuration in the given blob, along with the
+// blobs it includes from the same tree, which Git does not read itself.
 func (c *Configuration) RevisionSource(revision string) (*ConfigurationSource, error) {
-	out, err := c.gitConfig("-l", "--blob", revision)
-	if err != nil {
-		return nil, err
-	}
-	return ParseConfigLines(out, true), nil
+	return newConfigIncluder(c, nil).revisionSource(revision)
 }
 
 func (c *Configuration) Source() (*ConfigurationSource, error) {
@@ -198,6 +241,25 @@ func (c *Configuration) Source() (*ConfigurationSource, error) {
 	return ParseConfigLines(out, false), nil
 }
 
+// SourceWithOrigins works as Source, except that the source also gives where
+// each of its lines was read from.
+func (c *Configuration) SourceWithOrigins() (*ConfigurationSource, error) {
+	out, err := c.gitConfig("--show-origin", "-z", "-l")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each setting is given as its origin and then its key, followed by
+	// a newline and its value if it has one, each ending with a NUL.
+	source := &ConfigurationSource{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		source.Lines = append(source.Lines, strings.Replace(fields[i+1], "\n", "=", 1))
+		source.Origins = append(source.Origins, fields[i])
+	}
+	return source, nil
+}
+
 func (c *Configuration) gitConfig(args ...string) (string, error) {
 	args = append([]string{"config", "--includes"}, args...)
 	cmd := subprocess.ExecCommand("git", args...)
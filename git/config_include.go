@@ -0,0 +1,357 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/wildmatch"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// maxIncludeDepth is the deepest that includes are followed, as in
+	// Git, so that a file which includes itself is not read forever.
+	maxIncludeDepth = 10
+)
+
+// configIncluder reads the files included by a configuration file which Git
+// does not read includes from itself, such as a blob, or evaluate all the
+// conditions of, such as a file read with --file.
+type configIncluder struct {
+	c *Configuration
+
+	// remoteURLs are the URLs of the remotes in the repository's own
+	// configuration, which "hasconfig:remote.*.url:" conditions match.
+	remoteURLs []string
+
+	gitDir *string
+	branch *string
+}
+
+// newConfigIncluder returns a configIncluder which evaluates conditions
+// against the repository, and the remotes in the given configuration.
+func newConfigIncluder(c *Configuration, gitconfig *ConfigurationSource) *configIncluder {
+	in := &configIncluder{c: c}
+	if gitconfig != nil {
+		in.remoteURLs = remoteURLsIn(gitconfig.Lines)
+	}
+	return in
+}
+
+// fileSource reads the configuration file with the given name, along with the
+// files it includes, in the places they are included. If root is not empty,
+// the file is in the working tree with that root, such as ".lfsconfig", and
+// only files in the working tree may be included.
+func (in *configIncluder) fileSource(filename, root string) (*ConfigurationSource, error) {
+	out, err := in.c.gitConfig("--no-includes", "-l", "-f", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, origins := in.expand(strings.Split(out, "\n"), fileLocation{filename: filename, root: root}, 0)
+	return &ConfigurationSource{Lines: lines, Origins: origins, OnlySafeKeys: true}, nil
+}
+
+// revisionSource reads the configuration in the blob with the given name,
+// such as "HEAD:.lfsconfig", along with the blobs it includes, which are
+// found by their paths in the same tree.
+func (in *configIncluder) revisionSource(revision string) (*ConfigurationSource, error) {
+	out, err := in.c.gitConfig("--no-includes", "-l", "--blob", revision)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, origins := in.expand(strings.Split(out, "\n"), blobLocation{revision}, 0)
+	return &ConfigurationSource{Lines: lines, Origins: origins, OnlySafeKeys: true}, nil
+}
+
+// configLocation is where a configuration file was read from, which the
+// files it includes are found relative to.
+type configLocation interface {
+	// dir returns the directory of the file, if it is in the file
+	// system, for "gitdir:./" conditions.
+	dir() (string, bool)
+
+	// origin returns the location in the form "git config --show-origin"
+	// prints it, such as "file:.lfsconfig" or "blob:HEAD:.lfsconfig".
+	origin() string
+
+	// include returns the lines of the file included with the given
+	// path, and its location.
+	include(in *configIncluder, p string) ([]string, configLocation, error)
+}
+
+type fileLocation struct {
+	filename string
+	// root is the root of the working tree to which includes are
+	// confined, or empty if they are not.
+	root string
+}
+
+func (l fileLocation) dir() (string, bool) {
+	return filepath.Dir(l.filename), true
+}
+
+func (l fileLocation) origin() string {
+	return "file:" + l.filename
+}
+
+func (l fileLocation) include(in *configIncluder, p string) ([]string, configLocation, error) {
+	if len(l.root) > 0 && (filepath.IsAbs(p) || path.IsAbs(p) || strings.HasPrefix(p, "~")) {
+		return nil, nil, fmt.Errorf("only paths in the working tree may be included from %s", l.filename)
+	}
+
+	p, err := tools.ExpandPath(p, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(l.filename), p)
+	}
+	if len(l.root) > 0 && !inWorkingTree(l.root, p) {
+		return nil, nil, fmt.Errorf("only paths in the working tree may be included from %s", l.filename)
+	}
+
+	out, err := in.c.gitConfig("--no-includes", "-l", "-f", p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strings.Split(out, "\n"), fileLocation{filename: p, root: l.root}, nil
+}
+
+// inWorkingTree returns whether the file at the given path, once any symbolic
+// links are resolved, is within the working tree with the given root.
+func inWorkingTree(root, p string) bool {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	p, err = filepath.EvalSymlinks(p)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+type blobLocation struct {
+	revision string
+}
+
+func (l blobLocation) dir() (string, bool) {
+	return "", false
+}
+
+func (l blobLocation) origin() string {
+	return "blob:" + l.revision
+}
+
+func (l blobLocation) include(in *configIncluder, p string) ([]string, configLocation, error) {
+	parts := strings.SplitN(l.revision, ":", 2)
+	if len(parts) < 2 || path.IsAbs(p) || strings.HasPrefix(p, "~") {
+		return nil, nil, fmt.Errorf("only paths in the same tree may be included from %s", l.revision)
+	}
+
+	revision := parts[0] + ":" + path.Join(path.Dir(parts[1]), p)
+	out, err := in.c.gitConfig("--no-includes", "-l", "--blob", revision)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strings.Split(out, "\n"), blobLocation{revision}, nil
+}
+
+// expand replaces the "include.path" and "includeIf.<condition>.path" lines
+// among the given lines, read from the given location, with the lines of the
+// files they include, if their conditions are met. It also returns the
+// origin of each line.
+func (in *configIncluder) expand(lines []string, loc configLocation, depth int) ([]string, []string) {
+	expanded := make([]string, 0, len(lines))
+	origins := make([]string, 0, len(lines))
+	for _, line := range lines {
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) < 2 {
+			expanded = append(expanded, line)
+			origins = append(origins, loc.origin())
+			continue
+		}
+
+		key, val := pieces[0], pieces[1]
+		var condition string
+		switch {
+		case key == "include.path":
+		case strings.HasPrefix(key, "includeif.") && strings.HasSuffix(key, ".path"):
+			condition = strings.TrimSuffix(strings.TrimPrefix(key, "includeif."), ".path")
+			if len(condition) == 0 || !in.matches(condition, loc, expanded) {
+				continue
+			}
+		default:
+			expanded = append(expanded, line)
+			origins = append(origins, loc.origin())
+			continue
+		}
+
+		if depth >= maxIncludeDepth {
+			tracerx.Printf("git: ignoring include of %q beyond depth %d", val, maxIncludeDepth)
+			continue
+		}
+
+		included, iloc, err := loc.include(in, val)
+		if err != nil {
+			tracerx.Printf("git: ignoring include of %q: %s", val, err)
+			continue
+		}
+		ilines, iorigins := in.expand(included, iloc, depth+1)
+		expanded = append(expanded, ilines...)
+		origins = append(origins, iorigins...)
+	}
+	return expanded, origins
+}
+
+// matches returns whether the given condition of an "includeIf" section in a
+// file read from the given location is met, given the lines read before it.
+// Unknown conditions are never met, as in Git.
+func (in *configIncluder) matches(condition string, loc configLocation, before []string) bool {
+	kind := condition
+	var pattern string
+	if i := strings.Index(condition, ":"); i >= 0 {
+		kind, pattern = condition[:i], condition[i+1:]
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		return in.matchesGitDir(pattern, kind == "gitdir/i", loc)
+	case "onbranch":
+		return in.matchesBranch(pattern)
+	case "hasconfig":
+		const prefix = "remote.*.url:"
+		if !strings.HasPrefix(pattern, prefix) {
+			return false
+		}
+		pattern = strings.TrimPrefix(pattern, prefix)
+		urls := append(remoteURLsIn(before), in.remoteURLs...)
+		// Wildmatch skips empty path components, such as the one
+		// after a URL's scheme, so repeated slashes are squeezed in
+		// both the pattern and the URLs.
+		w := wildmatch.NewWildmatch(squeezeSlashes(pattern))
+		for _, u := range urls {
+			if w.Match(squeezeSlashes(u)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (in *configIncluder) matchesGitDir(pattern string, caseFold bool, loc configLocation) bool {
+	gitDir := in.loadGitDir()
+	if len(gitDir) == 0 || len(pattern) == 0 {
+		return false
+	}
+
+	if strings.HasPrefix(pattern, "~/") {
+		expanded, err := tools.ExpandPath(pattern, false)
+		if err != nil {
+			return false
+		}
+		if strings.HasSuffix(pattern, "/") {
+			// Joining the home directory drops the trailing slash.
+			expanded += "/"
+		}
+		pattern = filepath.ToSlash(expanded)
+	} else if strings.HasPrefix(pattern, "./") {
+		dir, ok := loc.dir()
+		if !ok {
+			return false
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return false
+		}
+		pattern = filepath.ToSlash(abs) + pattern[1:]
+	} else if !path.IsAbs(pattern) && !filepath.IsAbs(pattern) {
+		pattern = "**/" + pattern
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	if caseFold {
+		return wildmatch.NewWildmatch(pattern, wildmatch.CaseFold).Match(gitDir)
+	}
+	return wildmatch.NewWildmatch(pattern).Match(gitDir)
+}
+
+func (in *configIncluder) matchesBranch(pattern string) bool {
+	branch := in.loadBranch()
+	if len(branch) == 0 || len(pattern) == 0 {
+		return false
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return wildmatch.NewWildmatch(pattern).Match(branch)
+}
+
+// loadGitDir returns the absolute path of the repository's Git directory, in
+// the form Git matches "gitdir:" conditions against, or the empty string if
+// there is none.
+func (in *configIncluder) loadGitDir() string {
+	if in.gitDir == nil {
+		dir := in.c.GitDir
+		if len(dir) == 0 {
+			dir, _ = GitDir()
+		}
+		if len(dir) > 0 {
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+			if real, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = real
+			}
+		}
+		dir = filepath.ToSlash(dir)
+		in.gitDir = &dir
+	}
+	return *in.gitDir
+}
+
+// loadBranch returns the name of the current branch, even if it has no
+// commits yet, or the empty string if there is none.
+func (in *configIncluder) loadBranch() string {
+	if in.branch == nil {
+		branch, err := gitSimple("symbolic-ref", "-q", "--short", "HEAD")
+		if err != nil {
+			branch = ""
+		}
+		in.branch = &branch
+	}
+	return *in.branch
+}
+
+// remoteURLsIn returns the values of the "remote.<name>.url" keys among the
+// given lines.
+func remoteURLsIn(lines []string) []string {
+	var urls []string
+	for _, line := range lines {
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) == 2 && strings.HasPrefix(pieces[0], "remote.") && strings.HasSuffix(pieces[0], ".url") {
+			urls = append(urls, pieces[1])
+		}
+	}
+	return urls
+}
+
+func squeezeSlashes(s string) string {
+	for strings.Contains(s, "//") {
+		s = strings.Replace(s, "//", "/", -1)
+	}
+	return s
+}
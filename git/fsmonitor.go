@@ -0,0 +1,41 @@
+package git
+
+import (
+	"bytes"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+)
+
+// FSMonitorChanges asks the fsmonitor hook named by core.fsmonitor for the
+// paths in the working tree at dir which have changed since token, using
+// version 2 of the hook protocol described in githooks(5). It returns the
+// token to pass next time, along with the changed paths, or all set if the
+// hook couldn't tell which paths changed and every one should be examined.
+func FSMonitorChanges(dir, hook, token string) (next string, paths []string, all bool, err error) {
+	name, args := subprocess.FormatForShellQuotedArgs(hook, []string{"2", token})
+	cmd := subprocess.ExecCommand(name, args...)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", nil, false, errors.Wrap(err, "fsmonitor hook")
+	}
+
+	fields := bytes.Split(out, []byte{0})
+	if len(fields[0]) == 0 {
+		return "", nil, false, errors.New("fsmonitor hook: no token")
+	}
+	next = string(fields[0])
+
+	for _, field := range fields[1:] {
+		switch path := string(field); path {
+		case "":
+		case "/":
+			all = true
+		default:
+			paths = append(paths, path)
+		}
+	}
+	return next, paths, all, nil
+}
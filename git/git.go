@@ -224,11 +224,23 @@ func gitBuffered(args ...string) (*subprocess.BufferedCmd, error) {
 	return subprocess.BufferedExec("git", args...)
 }
 
-func CatFile() (*subprocess.BufferedCmd, error) {
-	return gitNoLFSBuffered("cat-file", "--batch-check")
-}
-
-func DiffIndex(ref string, cached bool, refresh bool) (*bufio.Scanner, error) {
+// Archive returns a command which writes a tar archive of the given tree-ish,
+// limited to the given paths, if any. Git LFS filters are disabled, so any
+// pointer files are included as they are.
+func Archive(treeish, prefix string, paths []string) *subprocess.Cmd {
+	args := []string{"archive", "--format=tar"}
+	if len(prefix) > 0 {
+		args = append(args, "--prefix="+prefix)
+	}
+	args = append(args, treeish, "--")
+	return gitNoLFS(append(args, paths...)...)
+}
+
+// DiffIndex runs git diff-index against the given ref, comparing it with the
+// index if cached is set, or the working tree otherwise. If any paths are
+// given, only those are compared; they are relative to the top of the working
+// tree, wherever the command is run.
+func DiffIndex(ref string, cached bool, refresh bool, paths ...string) (*bufio.Scanner, error) {
 	if refresh {
 		_, err := gitSimple("update-index", "-q", "--refresh")
 		if err != nil {
@@ -241,6 +253,12 @@ func DiffIndex(ref string, cached bool, refresh bool) (*bufio.Scanner, error) {
 		args = append(args, "--cached")
 	}
 	args = append(args, ref)
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, path := range paths {
+			args = append(args, ":(top,literal)"+path)
+		}
+	}
 
 	cmd, err := gitBuffered(args...)
 	if err != nil {
@@ -264,6 +282,31 @@ func HashObject(r io.Reader) (string, error) {
 	return string(bytes.TrimSpace(out)), nil
 }
 
+// CommitTree creates a commit of the given tree, with the given parents and
+// message, as 'git commit-tree' does, and returns its ID. If "sign" is true,
+// the commit is signed with the key "keyID", or with the committer's default
+// key if "keyID" is empty.
+func CommitTree(tree []byte, parents [][]byte, message string, sign bool, keyID string) ([]byte, error) {
+	args := []string{"commit-tree", hex.EncodeToString(tree)}
+	for _, parent := range parents {
+		args = append(args, "-p", hex.EncodeToString(parent))
+	}
+	if sign {
+		args = append(args, "-S"+keyID)
+	}
+
+	cmd := gitNoLFS(args...)
+	cmd.Stdin = strings.NewReader(message)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error creating commit: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return hex.DecodeString(string(bytes.TrimSpace(out)))
+}
+
 func Log(args ...string) (*subprocess.BufferedCmd, error) {
 	logArgs := append([]string{"log"}, args...)
 	return gitNoLFSBuffered(logArgs...)
@@ -291,6 +334,12 @@ func LsTree(ref string) (*subprocess.BufferedCmd, error) {
 	)
 }
 
+// ResolveTree returns the OID of the tree of the given tree-ish, such as a
+// commit or ref.
+func ResolveTree(treeish string) (string, error) {
+	return gitNoLFSSimple("rev-parse", "--verify", "--quiet", treeish+"^{tree}")
+}
+
 func ResolveRef(ref string) (*Ref, error) {
 	outp, err := gitNoLFSSimple("rev-parse", ref, "--symbolic-full-name", ref)
 	if err != nil {
@@ -482,7 +531,46 @@ func LocalRefs() ([]*Ref, error) {
 		refs = append(refs, &Ref{name, rtype, parts[0]})
 	}
 
-	return refs, cmd.Wait()
+	// git show-ref exits with status 1 if there are no refs at all, as in
+	// a new repository.
+	err = cmd.Wait()
+	if e, ok := err.(*exec.ExitError); ok {
+		if ws, ok := e.ProcessState.Sys().(syscall.WaitStatus); ok && ws.ExitStatus() == 1 {
+			return refs, nil
+		}
+	}
+	return refs, err
+}
+
+// CreateRefs creates, or moves, each of the given refs to point to its Sha, in
+// a single transaction, with the given reason for the reflog. It returns an
+// error if any were encountered, in which case none of the refs are changed.
+func CreateRefs(refs []*Ref, reason string) error {
+	return CreateRefsIn("", refs, reason)
+}
+
+// CreateRefsIn creates, or moves, each of the given refs as CreateRefs does,
+// within the given working directory "wd".
+func CreateRefsIn(wd string, refs []*Ref, reason string) error {
+	var input bytes.Buffer
+	for _, ref := range refs {
+		fmt.Fprintf(&input, "update %s %s\n", ref.Refspec(), ref.Sha)
+	}
+
+	args := []string{"update-ref", "--stdin"}
+	if len(reason) > 0 {
+		args = append(args, "-m", reason)
+	}
+
+	cmd := gitNoLFS(args...)
+	cmd.Dir = wd
+	cmd.Stdin = &input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error updating refs: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
 }
 
 // UpdateRef moves the given ref to a new sha with a given reason (and creates a
@@ -579,10 +667,23 @@ func RewriteLocalPathAsURL(path string) string {
 	return fmt.Sprintf("file://%s%s", slash, filepath.ToSlash(path))
 }
 
+// StagedBlob returns the content of the blob staged in the index for the given
+// path, relative to the top of the working tree.
+func StagedBlob(path string) (string, error) {
+	return gitNoLFSSimple("cat-file", "blob", ":"+path)
+}
+
 func UpdateIndexFromStdin() *subprocess.Cmd {
 	return git("update-index", "-q", "--refresh", "--stdin")
 }
 
+// CheckoutIndexFromStdin returns a command which checks out the files whose
+// paths it reads from standard input from the index, overwriting them, and
+// updates their index entries.
+func CheckoutIndexFromStdin() *subprocess.Cmd {
+	return git("checkout-index", "--force", "-u", "--stdin")
+}
+
 // RecentBranches returns branches with commit dates on or after the given date/time
 // Return full Ref type for easier detection of duplicate SHAs etc
 // since: refs with commits on or after this date will be included
@@ -1101,6 +1202,28 @@ func Checkout(treeish string, paths []string, force bool) error {
 	return err
 }
 
+// AddRenormalize performs an invocation of `git-add(1)` with the
+// `--renormalize` option for the given paths, so that their contents are
+// staged again according to the current attributes, even if the files are
+// otherwise unchanged since they were last staged.
+func AddRenormalize(paths []string) error {
+	const batchSize = 100
+
+	for len(paths) > 0 {
+		n := len(paths)
+		if n > batchSize {
+			n = batchSize
+		}
+
+		args := append([]string{"add", "--renormalize", "--"}, paths[:n]...)
+		if _, err := gitNoLFSSimple(args...); err != nil {
+			return err
+		}
+		paths = paths[n:]
+	}
+	return nil
+}
+
 // CachedRemoteRefs returns the list of branches & tags for a remote which are
 // currently cached locally. No remote request is made to verify them.
 func CachedRemoteRefs(remoteName string) ([]*Ref, error) {
@@ -1346,6 +1469,24 @@ func GetFilesChanged(from, to string) ([]string, error) {
 // IsFileModified returns whether the filepath specified is modified according
 // to `git status`. A file is modified if it has uncommitted changes in the
 // working copy or the index. This includes being untracked.
+// IsIgnored returns whether the given path is ignored by Git, according to
+// the .gitignore files and the other sources of ignore patterns.
+func IsIgnored(path string) (bool, error) {
+	cmd := gitNoLFS("check-ignore", "-q", "--", path)
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+
+	// git check-ignore exits with status 1 if the path is not ignored.
+	if e, ok := err.(*exec.ExitError); ok {
+		if ws, ok := e.ProcessState.Sys().(syscall.WaitStatus); ok && ws.ExitStatus() == 1 {
+			return false, nil
+		}
+	}
+	return false, lfserrors.Wrap(err, "Failed to call git check-ignore")
+}
+
 func IsFileModified(filepath string) (bool, error) {
 
 	args := []string{
@@ -1402,9 +1543,32 @@ func IsWorkingCopyDirty() (bool, error) {
 	return len(out) != 0, nil
 }
 
+// ObjectDatabase returns the object database of the repository in gitdir, or
+// the one given by GIT_OBJECT_DIRECTORY, as Git does, such as when it runs the
+// pre-receive hook with the pushed objects in a quarantine directory.
 func ObjectDatabase(osEnv, gitEnv Environment, gitdir, tempdir string) (*gitobj.ObjectDatabase, error) {
-	var options []gitobj.Option
+	dir, _ := osEnv.Get("GIT_OBJECT_DIRECTORY")
+	if len(dir) == 0 {
+		dir = filepath.Join(gitdir, "objects")
+	}
 	alternates, _ := osEnv.Get("GIT_ALTERNATE_OBJECT_DIRECTORIES")
+	return objectDatabase(gitEnv, dir, alternates, tempdir)
+}
+
+// OverlayObjectDatabase returns an object database which writes new objects
+// to the object directory "dir", rather than to the repository's own, and
+// reads objects from either of them, as Git does once "dir" is added to the
+// repository's alternates.
+func OverlayObjectDatabase(osEnv, gitEnv Environment, gitdir, dir, tempdir string) (*gitobj.ObjectDatabase, error) {
+	alternates := filepath.Join(gitdir, "objects")
+	if env, _ := osEnv.Get("GIT_ALTERNATE_OBJECT_DIRECTORIES"); env != "" {
+		alternates += string(os.PathListSeparator) + env
+	}
+	return objectDatabase(gitEnv, dir, alternates, tempdir)
+}
+
+func objectDatabase(gitEnv Environment, dir, alternates, tempdir string) (*gitobj.ObjectDatabase, error) {
+	var options []gitobj.Option
 	if alternates != "" {
 		options = append(options, gitobj.Alternates(alternates))
 	}
@@ -1412,7 +1576,7 @@ func ObjectDatabase(osEnv, gitEnv Environment, gitdir, tempdir string) (*gitobj.
 	if hashAlgo != "" {
 		options = append(options, gitobj.ObjectFormat(gitobj.ObjectFormatAlgorithm(hashAlgo)))
 	}
-	odb, err := gitobj.FromFilesystem(filepath.Join(gitdir, "objects"), tempdir, options...)
+	odb, err := gitobj.FromFilesystem(dir, tempdir, options...)
 	if err != nil {
 		return nil, err
 	}
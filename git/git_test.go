@@ -1,6 +1,8 @@
 package git_test // to avoid import cycles
 
 import (
+	"bufio"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -448,6 +450,37 @@ func TestGitAndRootDirs(t *testing.T) {
 	assert.True(t, os.SameFile(expected, actual))
 }
 
+func TestCheckAttrSession(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	attrs := "*.bin filter=lfs lfs-min-size=1M\nsmall/*.bin -lfs-min-size\nflag.bin lfs-min-size\n"
+	assert.Nil(t, ioutil.WriteFile(".gitattributes", []byte(attrs), 0644))
+
+	session, err := NewCheckAttrSession("filter", "lfs-min-size")
+	assert.Nil(t, err)
+
+	for path, expected := range map[string]map[string]string{
+		"a.bin":         {"filter": "lfs", "lfs-min-size": "1M"},
+		"dir/b.bin":     {"filter": "lfs", "lfs-min-size": "1M"},
+		"small/c.bin":   {"filter": "lfs", "lfs-min-size": "unset"},
+		"flag.bin":      {"filter": "lfs", "lfs-min-size": "set"},
+		"file with.txt": {},
+	} {
+		values, err := session.Check(path)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, values, path)
+	}
+
+	assert.Nil(t, session.Close())
+	_, err = session.Check("a.bin")
+	assert.NotNil(t, err)
+}
+
 func TestGetTrackedFiles(t *testing.T) {
 	repo := test.NewRepo(t)
 	repo.Pushd()
@@ -805,3 +838,48 @@ func TestIsZeroObjectID(t *testing.T) {
 	assert.Equal(t, IsZeroObjectID("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"), false)
 	assert.Equal(t, IsZeroObjectID("473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"), false)
 }
+
+func TestCatFileSession(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+			},
+		},
+	})
+
+	tree, err := ResolveTree("master")
+	assert.Nil(t, err)
+	missing := "0000000000000000000000000000000000000001"
+
+	session, err := NewCatFileSession()
+	assert.Nil(t, err)
+
+	// The same session serves more than one batch.
+	for i := 0; i < 2; i++ {
+		err = session.Check([]string{tree, missing}, func(r io.Reader) error {
+			br := bufio.NewReader(r)
+			line, err := br.ReadString('\n')
+			assert.Nil(t, err)
+			assert.Equal(t, tree+" tree", line[:len(tree)+5])
+
+			line, err = br.ReadString('\n')
+			assert.Nil(t, err)
+			assert.Equal(t, missing+" missing\n", line)
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, session.Close())
+	assert.NotNil(t, session.Check([]string{tree}, func(r io.Reader) error {
+		return nil
+	}))
+}
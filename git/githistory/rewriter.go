@@ -1,6 +1,7 @@
 package githistory
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -36,6 +37,8 @@ type Rewriter struct {
 	db *gitobj.ObjectDatabase
 	// l is the *tasklog.Logger to which updates are written.
 	l *tasklog.Logger
+	// dir is the directory in which Git is run, if not the root of db.
+	dir string
 }
 
 // RewriteOptions is an options type given to the Rewrite() function.
@@ -51,6 +54,17 @@ type RewriteOptions struct {
 	// original graph onto the migrated one. If true, the refs will be
 	// moved, and a reflog entry will be created.
 	UpdateRefs bool
+	// ReplaceRefs specifies whether the Rewriter should, rather than move
+	// refs onto the migrated graph, create a replace ref,
+	// refs/replace/<commit>, for each rewritten commit, so that Git shows
+	// the rewritten commit in place of the original one without changing
+	// the commit which any ref points to. It takes precedence over
+	// UpdateRefs.
+	ReplaceRefs bool
+	// ConfirmFn, if set, is called once the commits have been rewritten,
+	// and before any refs are moved, with a description of the rewrite.
+	// The refs are only moved if it returns true.
+	ConfirmFn ConfirmFn
 
 	// Verbose mode prints migrated objects.
 	Verbose bool
@@ -75,6 +89,9 @@ type RewriteOptions struct {
 	// been reassembled by calling the above BlobFn on all existing tree
 	// entries.
 	TreeCallbackFn TreeCallbackFn
+	// CommitCallbackFn specifies a function to rewrite commits whose trees
+	// or parents have changed, before they are written.
+	CommitCallbackFn CommitCallbackFn
 }
 
 // blobFn returns a useable BlobRewriteFn, either the one that was given in the
@@ -95,6 +112,15 @@ func (r *RewriteOptions) treePreFn() TreePreCallbackFn {
 	return r.TreePreCallbackFn
 }
 
+// commitFn returns a useable CommitCallbackFn, either the one that was given
+// in the *RewriteOptions, or a noopCommitFn.
+func (r *RewriteOptions) commitFn() CommitCallbackFn {
+	if r.CommitCallbackFn == nil {
+		return noopCommitFn
+	}
+	return r.CommitCallbackFn
+}
+
 // treeFn returns a useable TreeRewriteFn, either the one that was given in the
 // *RewriteOptions, or a noopTreeFn.
 func (r *RewriteOptions) treeFn() TreeCallbackFn {
@@ -138,7 +164,6 @@ type TreePreCallbackFn func(path string, t *gitobj.Tree) error
 // written to the object database instead of one generated from calling BlobFn
 // on all of the tree entries.
 //
-//
 // TreeCallbackFn can be nil, and will therefore exhibit behavior equivalent to
 // only calling the BlobFn on existing tree entries.
 //
@@ -146,6 +171,35 @@ type TreePreCallbackFn func(path string, t *gitobj.Tree) error
 // Rewrite() invocation.
 type TreeCallbackFn func(path string, t *gitobj.Tree) (*gitobj.Tree, error)
 
+// CommitCallbackFn specifies a function to call before writing a re-written
+// commit to the object database, with the ID of the original commit, the
+// original commit itself, and the re-written one. It can return a modified commit to be written instead, for instance
+// with its signature removed or replaced, since changing the commit
+// invalidates any signature copied from the original.
+//
+// It is not called for commits which are unchanged by the rewrite.
+//
+// If the CommitCallbackFn returns an error, it will be returned from the
+// Rewrite() invocation.
+type CommitCallbackFn func(oid []byte, original, rewritten *gitobj.Commit) (*gitobj.Commit, error)
+
+// ConfirmFn specifies a function to call before moving refs onto the rewritten
+// history, which may prevent them from being moved by returning false. It is
+// given the number of commits which were changed by the rewrite, and the refs
+// which would be moved.
+type ConfirmFn func(rewritten int, changes []*RefChange) bool
+
+// RefChange is the move of a ref onto the rewritten history, or, with
+// ReplaceRefs, the creation of a replace ref.
+type RefChange struct {
+	// Ref is the ref to be moved or created.
+	Ref *git.Ref
+	// From is the commit which the ref points to, or, if it is a tag, the
+	// commit which the tag points to, and To is its rewritten counterpart.
+	// From is nil for a replace ref, which is new.
+	From, To []byte
+}
+
 type rewriterOption func(*Rewriter)
 
 var (
@@ -166,6 +220,17 @@ var (
 		))
 	}
 
+	// WithRoot is an optional argument given to the NewRewriter
+	// constructor function to run Git in the given directory, rather than
+	// in the root of the *ObjectDatabase, which may not be within the
+	// repository if it writes objects to another object directory. An
+	// empty directory keeps the root of the *ObjectDatabase.
+	WithRoot = func(dir string) rewriterOption {
+		return func(r *Rewriter) {
+			r.dir = dir
+		}
+	}
+
 	// WithLogger logs updates caused by the *git/githistory.Rewriter to the
 	// be given to the provided logger, "l".
 	WithLogger = func(l *tasklog.Logger) rewriterOption {
@@ -183,6 +248,10 @@ var (
 	// noopTreeFn is a no-op implementation of the TreeRewriteFn. It returns
 	// the tree that it was given, and returns no error.
 	noopTreeFn = func(path string, t *gitobj.Tree) (*gitobj.Tree, error) { return t, nil }
+	// noopCommitFn is a no-op implementation of the CommitCallbackFn. It
+	// returns the re-written commit that it was given, and returns no
+	// error.
+	noopCommitFn = func(oid []byte, original, rewritten *gitobj.Commit) (*gitobj.Commit, error) { return rewritten, nil }
 )
 
 // NewRewriter constructs a *Rewriter from the given *ObjectDatabase instance.
@@ -211,7 +280,7 @@ func (r *Rewriter) Rewrite(opt *RewriteOptions) ([]byte, error) {
 	}
 
 	var perc *tasklog.PercentageTask
-	if opt.UpdateRefs {
+	if opt.UpdateRefs || opt.ReplaceRefs {
 		perc = r.l.Percentage("migrate: Rewriting commits", uint64(len(commits)))
 	} else {
 		perc = r.l.Percentage("migrate: Examining commits", uint64(len(commits)))
@@ -234,6 +303,8 @@ func (r *Rewriter) Rewrite(opt *RewriteOptions) ([]byte, error) {
 	// Keep track of the last commit that we rewrote. Callers often want
 	// this so that they can perform a git-update-ref(1).
 	var tip []byte
+	var rewritten int
+	var replacements []*RefChange
 	for _, oid := range commits {
 		// Load the original commit to access the data necessary in
 		// order to rewrite it.
@@ -292,10 +363,22 @@ func (r *Rewriter) Rewrite(opt *RewriteOptions) ([]byte, error) {
 			newSha = make([]byte, len(oid))
 			copy(newSha, oid)
 		} else {
+			rewrittenCommit, err = opt.commitFn()(oid, original, rewrittenCommit)
+			if err != nil {
+				return nil, err
+			}
+
 			newSha, err = r.db.WriteCommit(rewrittenCommit)
 			if err != nil {
 				return nil, err
 			}
+			rewritten++
+			if opt.ReplaceRefs {
+				replacements = append(replacements, &RefChange{
+					Ref: git.ParseRef(fmt.Sprintf("refs/replace/%x", oid), ""),
+					To:  newSha,
+				})
+			}
 			if objectMapFile != nil {
 				if _, err := fmt.Fprintf(objectMapFile, "%x,%x\n", oid, newSha); err != nil {
 					return nil, err
@@ -314,13 +397,28 @@ func (r *Rewriter) Rewrite(opt *RewriteOptions) ([]byte, error) {
 		tip = newSha
 	}
 
+	if opt.ReplaceRefs {
+		if opt.ConfirmFn != nil && !opt.ConfirmFn(rewritten, replacements) {
+			return tip, nil
+		}
+
+		if err := r.createReplaceRefs(replacements); err != nil {
+			return nil, errors.Wrap(err, "could not create replace refs")
+		}
+		return tip, nil
+	}
+
 	if opt.UpdateRefs {
 		refs, err := r.refsToMigrate()
 		if err != nil {
 			return nil, errors.Wrap(err, "could not find refs to update")
 		}
 
-		root, _ := r.db.Root()
+		if opt.ConfirmFn != nil && !opt.ConfirmFn(rewritten, r.refChanges(refs)) {
+			return tip, nil
+		}
+
+		root, _ := r.root()
 
 		updater := &refUpdater{
 			CacheFn: r.uncacheCommit,
@@ -339,6 +437,28 @@ func (r *Rewriter) Rewrite(opt *RewriteOptions) ([]byte, error) {
 	return tip, err
 }
 
+// root returns the directory in which Git is run, and whether there is one.
+func (r *Rewriter) root() (string, bool) {
+	if len(r.dir) > 0 {
+		return r.dir, true
+	}
+	return r.db.Root()
+}
+
+// createReplaceRefs creates the given replace refs, all at once, since there is
+// one for each rewritten commit.
+func (r *Rewriter) createReplaceRefs(replacements []*RefChange) error {
+	t := r.l.Waiter("migrate: Creating replace refs")
+	defer t.Complete()
+
+	refs := make([]*git.Ref, 0, len(replacements))
+	for _, replacement := range replacements {
+		refs = append(refs, git.ParseRef(replacement.Ref.Refspec(), hex.EncodeToString(replacement.To)))
+	}
+	root, _ := r.root()
+	return git.CreateRefsIn(root, refs, "")
+}
+
 // rewriteTree is a recursive function which rewrites a tree given by the ID
 // "sha" and path "path". It uses the given BlobRewriteFn to rewrite all blobs
 // within the tree, either calling that function or recurring down into subtrees
@@ -537,7 +657,7 @@ func (r *Rewriter) refsToMigrate() ([]*git.Ref, error) {
 	var refs []*git.Ref
 	var err error
 
-	if root, ok := r.db.Root(); ok {
+	if root, ok := r.root(); ok {
 		refs, err = git.AllRefsIn(root)
 	} else {
 		refs, err = git.AllRefs()
@@ -559,6 +679,40 @@ func (r *Rewriter) refsToMigrate() ([]*git.Ref, error) {
 	return local, nil
 }
 
+// refChanges returns the changes which moving the given refs onto the
+// rewritten history would make, leaving out those refs which would not move.
+func (r *Rewriter) refChanges(refs []*git.Ref) []*RefChange {
+	var changes []*RefChange
+	seen := make(map[string]struct{})
+	for _, ref := range refs {
+		if _, ok := seen[ref.Refspec()]; ok {
+			continue
+		}
+		seen[ref.Refspec()] = struct{}{}
+
+		from, err := hex.DecodeString(ref.Sha)
+		if err != nil {
+			continue
+		}
+		if ref.Type == git.RefTypeLocalTag {
+			// Peel annotated tags, which are rewritten to point to
+			// the rewritten commit.
+			for {
+				tag, err := r.db.Tag(from)
+				if err != nil || tag == nil {
+					break
+				}
+				from = tag.Object
+			}
+		}
+
+		if to, ok := r.uncacheCommit(from); ok && !bytes.Equal(from, to) {
+			changes = append(changes, &RefChange{Ref: ref, From: from, To: to})
+		}
+	}
+	return changes
+}
+
 // scannerOpts returns a *git.ScanRefsOptions instance to be given to the
 // *git.RevListScanner.
 //
@@ -576,7 +730,7 @@ func (r *Rewriter) scannerOpts() *git.ScanRefsOptions {
 		Names:       make(map[string]string),
 	}
 
-	if root, ok := r.db.Root(); ok {
+	if root, ok := r.root(); ok {
 		opts.WorkingDir = root
 	}
 	return opts
@@ -463,6 +463,137 @@ func TestHistoryRewriterUpdatesRefs(t *testing.T) {
 	AssertCommitParent(t, db, c2, c3)
 }
 
+func TestHistoryRewriterCreatesReplaceRefs(t *testing.T) {
+	db := DatabaseFromFixture(t, "linear-history.git")
+	r := NewRewriter(db)
+
+	original := HexDecode(t, "e669b63f829bfb0b91fc52a5bcea53dd7977a0ee")
+
+	var changes []*RefChange
+	tip, err := r.Rewrite(&RewriteOptions{
+		Include: []string{"refs/heads/master"},
+
+		UpdateRefs:  true,
+		ReplaceRefs: true,
+		ConfirmFn: func(n int, c []*RefChange) bool {
+			changes = c
+			return true
+		},
+
+		BlobFn: func(path string, b *gitobj.Blob) (*gitobj.Blob, error) {
+			suffix := strings.NewReader("_suffix")
+
+			return &gitobj.Blob{
+				Contents: io.MultiReader(b.Contents, suffix),
+				Size:     b.Size + int64(suffix.Len()),
+			}, nil
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, changes, 3)
+
+	AssertRef(t, db, "refs/heads/master", original)
+	AssertRef(t, db, "refs/replace/e669b63f829bfb0b91fc52a5bcea53dd7977a0ee", tip)
+	for _, c := range changes {
+		assert.Nil(t, c.From)
+		AssertRef(t, db, c.Ref.Refspec(), c.To)
+	}
+}
+
+func TestHistoryRewriterConfirmsRefUpdates(t *testing.T) {
+	db := DatabaseFromFixture(t, "linear-history.git")
+	r := NewRewriter(db)
+
+	original := HexDecode(t, "e669b63f829bfb0b91fc52a5bcea53dd7977a0ee")
+
+	var rewritten int
+	var changes []*RefChange
+	tip, err := r.Rewrite(&RewriteOptions{
+		Include: []string{"refs/heads/master"},
+
+		UpdateRefs: true,
+		ConfirmFn: func(n int, c []*RefChange) bool {
+			rewritten, changes = n, c
+			return false
+		},
+
+		BlobFn: func(path string, b *gitobj.Blob) (*gitobj.Blob, error) {
+			suffix := strings.NewReader("_suffix")
+
+			return &gitobj.Blob{
+				Contents: io.MultiReader(b.Contents, suffix),
+				Size:     b.Size + int64(suffix.Len()),
+			}, nil
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, rewritten)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "master", changes[0].Ref.Name)
+		assert.Equal(t, original, changes[0].From)
+		assert.Equal(t, tip, changes[0].To)
+	}
+
+	AssertRef(t, db, "refs/heads/master", original)
+}
+
+func TestHistoryRewriterCommitCallbacks(t *testing.T) {
+	db := DatabaseFromFixture(t, "linear-history.git")
+	r := NewRewriter(db)
+
+	var originals []string
+	tip, err := r.Rewrite(&RewriteOptions{
+		Include: []string{"refs/heads/master"},
+
+		BlobFn: func(path string, b *gitobj.Blob) (*gitobj.Blob, error) {
+			suffix := strings.NewReader("_suffix")
+
+			return &gitobj.Blob{
+				Contents: io.MultiReader(b.Contents, suffix),
+				Size:     b.Size + int64(suffix.Len()),
+			}, nil
+		},
+
+		CommitCallbackFn: func(oid []byte, original, rewritten *gitobj.Commit) (*gitobj.Commit, error) {
+			originals = append(originals, strings.TrimSpace(original.Message))
+
+			rewritten.Message = "rewritten: " + original.Message
+			return rewritten, nil
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, originals, 3)
+
+	c, err := db.Commit(tip)
+	assert.Nil(t, err)
+	assert.Equal(t, "rewritten: "+originals[2], strings.TrimSpace(c.Message))
+}
+
+func TestHistoryRewriterCommitCallbackPropagatesErrors(t *testing.T) {
+	db := DatabaseFromFixture(t, "linear-history.git")
+	r := NewRewriter(db)
+
+	_, err := r.Rewrite(&RewriteOptions{
+		Include: []string{"refs/heads/master"},
+
+		BlobFn: func(path string, b *gitobj.Blob) (*gitobj.Blob, error) {
+			return &gitobj.Blob{
+				Contents: strings.NewReader("changed"),
+				Size:     int64(len("changed")),
+			}, nil
+		},
+
+		CommitCallbackFn: func(oid []byte, original, rewritten *gitobj.Commit) (*gitobj.Commit, error) {
+			return nil, errors.New("commit callback")
+		},
+	})
+
+	assert.EqualError(t, err, "commit callback")
+}
+
 func TestHistoryRewriterReturnsFilter(t *testing.T) {
 	f := filepathfilter.New([]string{"a"}, []string{"b"})
 	r := NewRewriter(nil, WithFilter(f))
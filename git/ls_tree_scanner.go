@@ -12,9 +12,16 @@ import (
 type TreeBlob struct {
 	Oid      string
 	Size     int64
+	Mode     int32
 	Filename string
 }
 
+// IsSymlink returns whether the blob is the target of a symbolic link, rather
+// than the contents of a file.
+func (t TreeBlob) IsSymlink() bool {
+	return t.Mode == 0120000
+}
+
 type LsTreeScanner struct {
 	s    *bufio.Scanner
 	tree *TreeBlob
@@ -62,9 +69,14 @@ func (s *LsTreeScanner) next() (*TreeBlob, bool) {
 		return nil, hasNext
 	}
 
+	mode, err := strconv.ParseInt(attrs[0], 8, 32)
+	if err != nil {
+		return nil, hasNext
+	}
+
 	oid := attrs[2]
 	filename := parts[1]
-	return &TreeBlob{Oid: oid, Size: sz, Filename: filename}, hasNext
+	return &TreeBlob{Oid: oid, Size: sz, Mode: int32(mode), Filename: filename}, hasNext
 }
 
 func scanNullLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
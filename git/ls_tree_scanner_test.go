@@ -31,6 +31,20 @@ func TestLsTreeParser(t *testing.T) {
 	assertScannerDone(t, scanner)
 }
 
+func TestLsTreeParserModes(t *testing.T) {
+	stdout := "100755 blob d899f6551a51cf19763c5955c7a06a2726f018e9      42	run.sh\000120000 blob 4d343e022e11a8618db494dc3c501e80c7e18197       6	link.dat"
+	scanner := NewLsTreeScanner(strings.NewReader(stdout))
+
+	assertNextScan(t, scanner)
+	assert.Equal(t, int32(0100755), scanner.TreeBlob().Mode)
+	assert.False(t, scanner.TreeBlob().IsSymlink())
+
+	assertNextScan(t, scanner)
+	assert.Equal(t, int32(0120000), scanner.TreeBlob().Mode)
+	assert.True(t, scanner.TreeBlob().IsSymlink())
+	assertScannerDone(t, scanner)
+}
+
 func assertNextTreeBlob(t *testing.T, scanner *LsTreeScanner, oid, filename string) {
 	assertNextScan(t, scanner)
 	b := scanner.TreeBlob()
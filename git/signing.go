@@ -0,0 +1,118 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// Signer signs commits as Git does, using the program and key given by the
+// gpg.format, gpg.program, gpg.<format>.program, and user.signingKey
+// settings.
+type Signer struct {
+	format  string
+	program string
+	key     string
+}
+
+// NewSigner returns a Signer configured by the given Git environment, which
+// signs with the key of the given committer identity ("Name <email>") if
+// user.signingKey is not set, as Git does for OpenPGP signatures.
+func NewSigner(env Environment, committer string) (*Signer, error) {
+	format, ok := env.Get("gpg.format")
+	if !ok || len(format) == 0 {
+		format = "openpgp"
+	}
+
+	program, _ := env.Get(fmt.Sprintf("gpg.%s.program", format))
+	if len(program) == 0 && format == "openpgp" {
+		program, _ = env.Get("gpg.program")
+	}
+	if len(program) == 0 {
+		switch format {
+		case "openpgp":
+			program = "gpg"
+		case "x509":
+			program = "gpgsm"
+		case "ssh":
+			program = "ssh-keygen"
+		default:
+			return nil, fmt.Errorf("unsupported gpg.format %q", format)
+		}
+	}
+
+	key, _ := env.Get("user.signingkey")
+	if len(key) == 0 {
+		if format == "ssh" {
+			return nil, fmt.Errorf("user.signingKey must be set to sign with gpg.format=ssh")
+		}
+		key = committer
+	}
+
+	return &Signer{format: format, program: program, key: key}, nil
+}
+
+// Sign returns a detached signature of the given payload, in the form in which
+// Git stores it in the "gpgsig" header of a commit.
+func (s *Signer) Sign(payload []byte) ([]byte, error) {
+	if s.format == "ssh" {
+		return s.signSSH(payload)
+	}
+
+	cmd := subprocess.ExecCommand(s.program, "--status-fd=2", "-bsau", s.key)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	sig, err := cmd.Output()
+	if err != nil || !bytes.Contains(stderr.Bytes(), []byte("[GNUPG:] SIG_CREATED ")) {
+		return nil, fmt.Errorf("%s failed to sign the data: %s", s.program, strings.TrimSpace(stderr.String()))
+	}
+	return sig, nil
+}
+
+// signSSH signs the given payload with ssh-keygen, which reads the payload
+// from, and writes the signature next to, a file. As in Git, user.signingKey
+// is either the path of a key, or a public key whose private key is held by
+// ssh-agent, optionally prefixed with "key::".
+func (s *Signer) signSSH(payload []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "git-lfs-sign")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-Y", "sign", "-n", "git", "-f"}
+	if key := strings.TrimPrefix(s.key, "key::"); key != s.key || strings.HasPrefix(key, "ssh-") {
+		keyFile := filepath.Join(dir, "key.pub")
+		if err := ioutil.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		args = append(args, keyFile, "-U")
+	} else {
+		keyFile, err := tools.ExpandPath(s.key, false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, keyFile)
+	}
+
+	payloadFile := filepath.Join(dir, "payload")
+	if err := ioutil.WriteFile(payloadFile, payload, 0600); err != nil {
+		return nil, err
+	}
+
+	cmd := subprocess.ExecCommand(s.program, append(args, payloadFile)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed to sign the data: %s", s.program, strings.TrimSpace(stderr.String()))
+	}
+	return ioutil.ReadFile(payloadFile + ".sig")
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signingEnv map[string]string
+
+func (e signingEnv) Get(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestNewSignerDefaults(t *testing.T) {
+	s, err := NewSigner(signingEnv{}, "A U Thor <author@example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, &Signer{format: "openpgp", program: "gpg", key: "A U Thor <author@example.com>"}, s)
+}
+
+func TestNewSignerUsesConfiguredProgramAndKey(t *testing.T) {
+	s, err := NewSigner(signingEnv{
+		"gpg.program":     "gpg2",
+		"user.signingkey": "ABCD1234",
+	}, "A U Thor <author@example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, &Signer{format: "openpgp", program: "gpg2", key: "ABCD1234"}, s)
+
+	s, err = NewSigner(signingEnv{
+		"gpg.format":      "x509",
+		"gpg.program":     "gpg2",
+		"user.signingkey": "ABCD1234",
+	}, "A U Thor <author@example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, &Signer{format: "x509", program: "gpgsm", key: "ABCD1234"}, s)
+}
+
+func TestNewSignerSSHRequiresKey(t *testing.T) {
+	_, err := NewSigner(signingEnv{"gpg.format": "ssh"}, "A U Thor <author@example.com>")
+	assert.EqualError(t, err, "user.signingKey must be set to sign with gpg.format=ssh")
+
+	s, err := NewSigner(signingEnv{
+		"gpg.format":      "ssh",
+		"gpg.ssh.program": "/usr/local/bin/ssh-keygen",
+		"user.signingkey": "~/.ssh/id_ed25519",
+	}, "A U Thor <author@example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, &Signer{format: "ssh", program: "/usr/local/bin/ssh-keygen", key: "~/.ssh/id_ed25519"}, s)
+}
+
+func TestNewSignerRejectsUnknownFormat(t *testing.T) {
+	_, err := NewSigner(signingEnv{"gpg.format": "pgp"}, "A U Thor <author@example.com>")
+	assert.EqualError(t, err, `unsupported gpg.format "pgp"`)
+}
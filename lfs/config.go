@@ -34,7 +34,7 @@ func NewFetchPruneConfig(git config.Environment) FetchPruneConfig {
 	}
 
 	return FetchPruneConfig{
-		FetchRecentRefsDays:           git.Int("lfs.fetchrecentrefsdays", 7),
+		FetchRecentRefsDays:           git.Int(config.FetchProfileKey(git, "recentrefsdays", "lfs.fetchrecentrefsdays"), 7),
 		FetchRecentRefsIncludeRemotes: git.Bool("lfs.fetchrecentremoterefs", true),
 		FetchRecentCommitsDays:        git.Int("lfs.fetchrecentcommitsdays", 0),
 		FetchRecentAlways:             git.Bool("lfs.fetchrecentalways", false),
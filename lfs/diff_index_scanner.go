@@ -124,11 +124,13 @@ type DiffIndexScanner struct {
 // operation would be undesirable due to the possibility of corruption. It can
 // also be disabled where another operation will have refreshed the index.
 //
+// If any "paths" are given, only differences in those paths are scanned for.
+//
 // If any error was encountered in starting the command or closing its `stdin`,
 // that error will be returned immediately. Otherwise, a `*DiffIndexScanner`
 // will be returned with a `nil` error.
-func NewDiffIndexScanner(ref string, cached bool, refresh bool) (*DiffIndexScanner, error) {
-	scanner, err := git.DiffIndex(ref, cached, refresh)
+func NewDiffIndexScanner(ref string, cached bool, refresh bool, paths ...string) (*DiffIndexScanner, error) {
+	scanner, err := git.DiffIndex(ref, cached, refresh, paths...)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,8 @@
 package lfs
 
 import (
+	"path/filepath"
+
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/fs"
 	"github.com/git-lfs/git-lfs/v2/git"
@@ -10,6 +12,10 @@ import (
 type GitFilter struct {
 	cfg *config.Configuration
 	fs  *fs.Filesystem
+
+	// attrs looks up the tracking condition attributes of files, and is
+	// started when first needed.
+	attrs *git.CheckAttrSession
 }
 
 // NewGitFilter initializes a new *GitFilter
@@ -24,3 +30,30 @@ func (f *GitFilter) ObjectPath(oid string) (string, error) {
 func (f *GitFilter) RemoteRef() *git.Ref {
 	return git.NewRefUpdate(f.cfg.Git, f.cfg.PushRemote(), f.cfg.CurrentRef(), nil).Right()
 }
+
+// TrackingConditions returns the conditions which the file with the given
+// name, relative to the root of the working tree, must meet to be stored as a
+// Git LFS object, or nil if there are none.
+func (f *GitFilter) TrackingConditions(fileName string) (*TrackingConditions, error) {
+	if f.attrs == nil {
+		attrs, err := git.NewCheckAttrSession(TrackingConditionAttrs...)
+		if err != nil {
+			return nil, err
+		}
+		f.attrs = attrs
+	}
+
+	values, err := f.attrs.Check(filepath.ToSlash(fileName))
+	if err != nil {
+		return nil, err
+	}
+	return NewTrackingConditions(values)
+}
+
+// Close stops any processes started by the filter.
+func (f *GitFilter) Close() error {
+	if f.attrs == nil {
+		return nil
+	}
+	return f.attrs.Close()
+}
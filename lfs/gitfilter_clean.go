@@ -56,6 +56,15 @@ func (f *GitFilter) Clean(reader io.Reader, fileName string, fileSize int64, cb
 	}
 
 	pointer := NewPointer(oid, size, exts)
+	if f.cfg.PointerVersion() >= 2 && size > 0 {
+		fields, ok := f.cfg.PointerMetadata()
+		if !ok {
+			fields = DefaultPointerMetadata
+		}
+		if meta := pointerMetadata(fields, fileName, tmp.Name()); len(meta) > 0 {
+			pointer.Metadata = meta
+		}
+	}
 	return &cleanedAsset{tmp.Name(), pointer}, err
 }
 
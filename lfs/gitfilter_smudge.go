@@ -8,6 +8,7 @@ import (
 
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/fs"
 	"github.com/git-lfs/git-lfs/v2/tools"
 	"github.com/git-lfs/git-lfs/v2/tools/humanize"
 	"github.com/git-lfs/git-lfs/v2/tq"
@@ -15,30 +16,32 @@ import (
 )
 
 func (f *GitFilter) SmudgeToFile(filename string, ptr *Pointer, download bool, manifest *tq.Manifest, cb tools.CopyCallback) error {
-	tools.MkdirAll(filepath.Dir(filename), f.cfg)
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return fmt.Errorf("could not produce absolute path for %q", filename)
+	}
+
+	tools.MkdirAll(filepath.Dir(abs), f.cfg)
 
-	if stat, _ := os.Stat(filename); stat != nil && stat.Mode()&0200 == 0 {
-		if err := os.Chmod(filename, stat.Mode()|0200); err != nil {
+	// Paths in deep working trees may exceed MAX_PATH on Windows.
+	longAbs := tools.LongPath(abs)
+	if stat, _ := os.Stat(longAbs); stat != nil && stat.Mode()&0200 == 0 {
+		if err := os.Chmod(longAbs, stat.Mode()|0200); err != nil {
 			return errors.Wrap(err,
 				"Could not restore write permission")
 		}
 
 		// When we're done, return the file back to its normal
 		// permission bits.
-		defer os.Chmod(filename, stat.Mode())
-	}
-
-	abs, err := filepath.Abs(filename)
-	if err != nil {
-		return fmt.Errorf("could not produce absolute path for %q", filename)
+		defer os.Chmod(longAbs, stat.Mode())
 	}
 
-	file, err := os.Create(abs)
+	file, err := os.Create(longAbs)
 	if err != nil {
 		return fmt.Errorf("could not create working directory file: %v", err)
 	}
-	defer file.Close()
 	if _, err := f.Smudge(file, ptr, filename, download, manifest, cb); err != nil {
+		defer file.Close()
 		if errors.IsDownloadDeclinedError(err) {
 			// write placeholder data instead
 			file.Seek(0, io.SeekStart)
@@ -48,7 +51,59 @@ func (f *GitFilter) SmudgeToFile(filename string, ptr *Pointer, download bool, m
 			return fmt.Errorf("could not write working directory file: %v", err)
 		}
 	}
-	return nil
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not write working directory file: %v", err)
+	}
+	return f.ApplyPointerMetadata(abs, ptr)
+}
+
+// WritePlaceholder writes a placeholder for the object the given pointer
+// refers to, for when the object cannot be downloaded, but its file needs to
+// be present with the right size: as many zeros as the object is long. It
+// returns the number of bytes written.
+func WritePlaceholder(writer io.Writer, ptr *Pointer) (int64, error) {
+	return io.CopyN(writer, zeroReader{}, ptr.Size)
+}
+
+// IsPlaceholderFile returns whether the file with the given name is a
+// placeholder, as written by WritePlaceholder, for the object the given
+// pointer refers to, which should be replaced once the object is present.
+func IsPlaceholderFile(filename string, ptr *Pointer) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if stat, err := file.Stat(); err != nil || stat.Size() != ptr.Size || ptr.Size == 0 {
+		return false
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(buf)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false
+			}
+		}
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// zeroReader reads an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
 }
 
 func (f *GitFilter) Smudge(writer io.Writer, ptr *Pointer, workingfile string, download bool, manifest *tq.Manifest, cb tools.CopyCallback) (int64, error) {
@@ -63,8 +118,7 @@ func (f *GitFilter) Smudge(writer io.Writer, ptr *Pointer, workingfile string, d
 	if statErr == nil && stat != nil {
 		fileSize := stat.Size()
 		if fileSize != ptr.Size {
-			tracerx.Printf("Removing %s, size %d is invalid", mediafile, fileSize)
-			os.RemoveAll(mediafile)
+			f.removeInvalidObject(ptr, mediafile)
 			stat = nil
 		}
 	}
@@ -73,8 +127,10 @@ func (f *GitFilter) Smudge(writer io.Writer, ptr *Pointer, workingfile string, d
 
 	if ptr.Size == 0 {
 		return 0, nil
-	} else if statErr != nil || stat == nil {
-		if download {
+	} else if (statErr != nil || stat == nil) && !f.fs.ObjectExists(ptr.Oid, ptr.Size) {
+		if download && f.awaitDownload(ptr) {
+			n, err = f.readLocalFile(writer, ptr, mediafile, workingfile, cb)
+		} else if download {
 			n, err = f.downloadFile(writer, ptr, workingfile, mediafile, manifest, cb)
 		} else {
 			return 0, errors.NewDownloadDeclinedError(statErr, "smudge")
@@ -90,6 +146,43 @@ func (f *GitFilter) Smudge(writer io.Writer, ptr *Pointer, workingfile string, d
 	return n, nil
 }
 
+// removeInvalidObject removes the object the given pointer refers to, which
+// has the wrong size, unless another process holds its object lock, in which
+// case that process is replacing it, and it is left alone.
+func (f *GitFilter) removeInvalidObject(ptr *Pointer, mediafile string) {
+	lock, err := f.fs.TryLock(fs.ObjectLockName(ptr.Oid))
+	if err != nil {
+		tracerx.Printf("Not removing %s: %v", mediafile, err)
+		return
+	}
+	defer lock.Unlock()
+
+	if stat, err := os.Stat(mediafile); err == nil && stat.Size() != ptr.Size {
+		tracerx.Printf("Removing %s, size %d is invalid", mediafile, stat.Size())
+		os.RemoveAll(mediafile)
+	}
+}
+
+// awaitDownload waits for any other process downloading the object the given
+// pointer refers to, such as a smudge filter checking out the same file in
+// another working tree, to finish, and returns whether the object is then
+// present. This way, concurrent smudges of one object download it only once,
+// and the others read it from local storage.
+func (f *GitFilter) awaitDownload(ptr *Pointer) bool {
+	lock, err := f.fs.Lock(fs.ObjectLockName(ptr.Oid), f.cfg.OperationLockTimeout())
+	if err != nil {
+		tracerx.Printf("smudge: not waiting for download of %s: %v", ptr.Oid, err)
+		return false
+	}
+	lock.Unlock()
+
+	if !f.fs.ObjectExists(ptr.Oid, ptr.Size) {
+		return false
+	}
+	tracerx.Printf("smudge: %s was downloaded by another process", ptr.Oid)
+	return true
+}
+
 func (f *GitFilter) downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string, manifest *tq.Manifest, cb tools.CopyCallback) (int64, error) {
 	fmt.Fprintf(os.Stderr, "Downloading %s (%s)\n", workingfile, humanize.FormatBytes(uint64(ptr.Size)))
 
@@ -123,7 +216,14 @@ func (f *GitFilter) downloadFile(writer io.Writer, ptr *Pointer, workingfile, me
 }
 
 func (f *GitFilter) readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile string, cb tools.CopyCallback) (int64, error) {
-	reader, err := tools.RobustOpen(mediafile)
+	var reader io.ReadCloser
+	file, err := tools.RobustOpen(mediafile)
+	if err == nil {
+		reader = file
+	} else if os.IsNotExist(err) {
+		// Objects held by another backend are read from it directly.
+		reader, err = f.fs.OpenObject(ptr.Oid)
+	}
 	if err != nil {
 		return 0, errors.Wrapf(err, "error opening media file")
 	}
@@ -0,0 +1,44 @@
+package lfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlaceholder(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WritePlaceholder(&buf, NewPointer("oid", 40000, nil))
+	require.Nil(t, err)
+
+	assert.Equal(t, int64(40000), n)
+	assert.Equal(t, make([]byte, 40000), buf.Bytes())
+}
+
+func TestIsPlaceholderFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placeholder")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	placeholder := filepath.Join(dir, "placeholder.dat")
+	f, err := os.Create(placeholder)
+	require.Nil(t, err)
+	_, err = WritePlaceholder(f, NewPointer("oid", 40000, nil))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	content := make([]byte, 40000)
+	content[39999] = 1
+	other := filepath.Join(dir, "other.dat")
+	require.Nil(t, ioutil.WriteFile(other, content, 0644))
+
+	assert.True(t, IsPlaceholderFile(placeholder, NewPointer("oid", 40000, nil)))
+	assert.False(t, IsPlaceholderFile(placeholder, NewPointer("oid", 39999, nil)))
+	assert.False(t, IsPlaceholderFile(other, NewPointer("oid", 40000, nil)))
+	assert.False(t, IsPlaceholderFile(filepath.Join(dir, "missing.dat"), NewPointer("oid", 40000, nil)))
+}
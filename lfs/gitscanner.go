@@ -8,6 +8,7 @@ import (
 
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
+	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/rubyist/tracerx"
 )
 
@@ -32,6 +33,10 @@ type GitScanner struct {
 	started time.Time
 	mu      sync.Mutex
 	cfg     *config.Configuration
+
+	// catFile is shared by all scans of the history, and started by the
+	// first of them.
+	catFile *git.CatFileSession
 }
 
 type GitScannerFoundPointer func(*WrappedPointer, error)
@@ -58,9 +63,34 @@ func (s *GitScanner) Close() {
 	}
 
 	s.closed = true
+	if s.catFile != nil {
+		if err := s.catFile.Close(); err != nil {
+			tracerx.Printf("scan: %v", err)
+		}
+	}
 	tracerx.PerformanceSince("scan", s.started)
 }
 
+// catFileSession returns the session shared by the scans of the history, or
+// nil if it couldn't be started, in which case each scan starts its own.
+func (s *GitScanner) catFileSession() *git.CatFileSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	if s.catFile == nil {
+		session, err := git.NewCatFileSession()
+		if err != nil {
+			tracerx.Printf("scan: could not start git cat-file: %v", err)
+			return nil
+		}
+		s.catFile = session
+	}
+	return s.catFile
+}
+
 // RemoteForPush sets up this *GitScanner to scan for objects to push to the
 // given remote. Needed for ScanLeftToRemote().
 func (s *GitScanner) RemoteForPush(r string) error {
@@ -206,7 +236,19 @@ func (s *GitScanner) ScanTree(ref string) error {
 	if err != nil {
 		return err
 	}
-	return runScanTree(callback, ref, s.Filter, s.cfg.GitEnv(), s.cfg.OSEnv())
+	return runScanTree(callback, ref, s.Filter, newTreeCache(s.cfg), s.cfg.GitEnv(), s.cfg.OSEnv())
+}
+
+// ScanTrackedTree takes a ref and returns WrappedPointer objects for the files
+// in the tree at that ref which are tracked by Git LFS according to the
+// .gitattributes files in that tree. Like ScanTree, multiple files with the
+// same content are all reported.
+func (s *GitScanner) ScanTrackedTree(ref string) error {
+	callback, err := firstGitScannerCallback(s.FoundPointer)
+	if err != nil {
+		return err
+	}
+	return runScanTrackedTree(callback, ref, s.Filter, s.cfg.GitEnv(), s.cfg.OSEnv())
 }
 
 // ScanUnpushed scans history for all LFS pointers which have been added but not
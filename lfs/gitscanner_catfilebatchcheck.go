@@ -2,54 +2,74 @@ package lfs
 
 import (
 	"bufio"
-	"fmt"
-	"io/ioutil"
+	"io"
 	"strconv"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/git"
 )
 
+// catFileBatchCheckSize is the largest number of objects whose type and size
+// are requested from 'git cat-file' at once.
+const catFileBatchCheckSize = 1000
+
 // runCatFileBatchCheck uses 'git cat-file --batch-check' to get the type and
 // size of a git object. Any object that isn't of type blob and under the
 // blobSizeCutoff will be ignored, unless it's a locked file. revs is a channel
 // over which strings containing git sha1s will be sent. It returns a channel
-// from which sha1 strings can be read.
-func runCatFileBatchCheck(smallRevCh chan string, lockableCh chan string, lockableSet *lockableNameSet, revs *StringChannelWrapper, errCh chan error) error {
-	cmd, err := git.CatFile()
-	if err != nil {
-		return err
+// from which sha1 strings can be read. The given session is used, if any,
+// or else one is started for this scan alone.
+func runCatFileBatchCheck(smallRevCh chan string, lockableCh chan string, lockableSet *lockableNameSet, revs *StringChannelWrapper, errCh chan error, session *git.CatFileSession) error {
+	owned := session == nil
+	if owned {
+		var err error
+		if session, err = git.NewCatFileSession(); err != nil {
+			return err
+		}
 	}
 
 	go func() {
-		scanner := &catFileBatchCheckScanner{s: bufio.NewScanner(cmd.Stdout), limit: blobSizeCutoff}
-		for r := range revs.Results {
-			cmd.Stdin.Write([]byte(r + "\n"))
-			hasNext := scanner.Scan()
-			if err := scanner.Err(); err != nil {
-				errCh <- err
-			} else if b := scanner.LFSBlobOID(); len(b) > 0 {
-				smallRevCh <- b
-			} else if b := scanner.GitBlobOID(); len(b) > 0 {
-				if name, ok := lockableSet.Check(b); ok {
-					lockableCh <- name
-				}
+		for {
+			batch, ok := readBatch(revs.Results, catFileBatchCheckSize)
+			if !ok {
+				break
 			}
 
-			if !hasNext {
+			err := session.Check(batch, func(r io.Reader) error {
+				scanner := &catFileBatchCheckScanner{s: bufio.NewScanner(r), limit: blobSizeCutoff}
+				for range batch {
+					if !scanner.Scan() {
+						if err := scanner.Err(); err != nil {
+							return err
+						}
+						return io.ErrUnexpectedEOF
+					}
+					if b := scanner.LFSBlobOID(); len(b) > 0 {
+						smallRevCh <- b
+					} else if b := scanner.GitBlobOID(); len(b) > 0 {
+						if name, ok := lockableSet.Check(b); ok {
+							lockableCh <- name
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				errCh <- err
 				break
 			}
 		}
 
+		// Drain any remaining revisions if the session failed.
+		for range revs.Results {
+		}
 		if err := revs.Wait(); err != nil {
 			errCh <- err
 		}
-		cmd.Stdin.Close()
-
-		stderr, _ := ioutil.ReadAll(cmd.Stderr)
-		err := cmd.Wait()
-		if err != nil {
-			errCh <- fmt.Errorf("error in git cat-file --batch-check: %v %v", err, string(stderr))
+		if owned {
+			if err := session.Close(); err != nil {
+				errCh <- err
+			}
 		}
 		close(smallRevCh)
 		close(errCh)
@@ -58,6 +78,29 @@ func runCatFileBatchCheck(smallRevCh chan string, lockableCh chan string, lockab
 	return nil
 }
 
+// readBatch reads up to n values from ch, waiting only for the first. It
+// returns false once ch is closed and there are no more values.
+func readBatch(ch <-chan string, n int) ([]string, bool) {
+	first, ok := <-ch
+	if !ok {
+		return nil, false
+	}
+
+	batch := []string{first}
+	for len(batch) < n {
+		select {
+		case r, ok := <-ch:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, r)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
 type catFileBatchCheckScanner struct {
 	s          *bufio.Scanner
 	limit      int
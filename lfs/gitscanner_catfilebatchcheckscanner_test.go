@@ -60,3 +60,29 @@ func assertScannerDone(t *testing.T, scanner genericScanner) {
 	assert.False(t, scanner.Scan())
 	assert.Nil(t, scanner.Err())
 }
+
+func TestReadBatch(t *testing.T) {
+	ch := make(chan string, 5)
+	for _, s := range []string{"a", "b", "c"} {
+		ch <- s
+	}
+
+	batch, ok := readBatch(ch, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, batch)
+
+	// Only the values already sent are read.
+	batch, ok = readBatch(ch, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"c"}, batch)
+
+	ch <- "d"
+	close(ch)
+	batch, ok = readBatch(ch, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"d"}, batch)
+
+	batch, ok = readBatch(ch, 2)
+	assert.False(t, ok)
+	assert.Nil(t, batch)
+}
@@ -61,7 +61,7 @@ func scanIndex(cb GitScannerFoundPointer, ref string, f *filepathfilter.Filter,
 		close(allRevsErr)
 	}()
 
-	smallShas, _, err := catFileBatchCheck(allRevs, nil)
+	smallShas, _, err := catFileBatchCheck(allRevs, nil, nil)
 	if err != nil {
 		return err
 	}
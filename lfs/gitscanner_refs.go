@@ -47,7 +47,7 @@ func scanRefsToChan(scanner *GitScanner, pointerCb GitScannerFoundPointer, inclu
 	}
 
 	lockableSet := &lockableNameSet{opt: opt, set: scanner.PotentialLockables}
-	smallShas, batchLockableCh, err := catFileBatchCheck(revs, lockableSet)
+	smallShas, batchLockableCh, err := catFileBatchCheck(revs, lockableSet, scanner.catFileSession())
 	if err != nil {
 		return err
 	}
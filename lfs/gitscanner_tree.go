@@ -5,19 +5,42 @@ import (
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/filepathfilter"
 	"github.com/git-lfs/git-lfs/v2/git"
 	"github.com/git-lfs/git-lfs/v2/git/gitattr"
+	"github.com/git-lfs/wildmatch"
 )
 
-func runScanTree(cb GitScannerFoundPointer, ref string, filter *filepathfilter.Filter, gitEnv, osEnv config.Environment) error {
+func runScanTree(cb GitScannerFoundPointer, ref string, filter *filepathfilter.Filter, cache *treeCache, gitEnv, osEnv config.Environment) error {
+	// When caching, the whole tree is scanned, so that the cached entry
+	// can be used however the tree is filtered next time.
+	var tree string
+	var found []*WrappedPointer
+	if cache != nil {
+		tree, _ = git.ResolveTree(ref)
+	}
+	if len(tree) > 0 {
+		if pointers, ok := cache.Get(tree); ok {
+			for _, p := range pointers {
+				if filter.Allows(p.Name) {
+					cb(p, nil)
+				}
+			}
+			return nil
+		}
+		ref = tree
+	}
+
 	// We don't use the nameMap approach here since that's imprecise when >1 file
 	// can be using the same content
+	// Symbolic links are checked out by Git itself, so even if the target
+	// of one looks like a pointer, it is not a Git LFS file.
 	treeShas, err := lsTreeBlobs(ref, func(t *git.TreeBlob) bool {
-		return t != nil && t.Size < blobSizeCutoff && filter.Allows(t.Filename)
+		return t != nil && t.Size < blobSizeCutoff && !t.IsSymlink() && (len(tree) > 0 || filter.Allows(t.Filename))
 	})
 	if err != nil {
 		return err
@@ -29,11 +52,19 @@ func runScanTree(cb GitScannerFoundPointer, ref string, filter *filepathfilter.F
 	}
 
 	for p := range pcw.Results {
+		if len(tree) > 0 {
+			found = append(found, p.copy())
+			if !filter.Allows(p.Name) {
+				continue
+			}
+		}
 		cb(p, nil)
 	}
 
 	if err := pcw.Wait(); err != nil {
 		cb(nil, err)
+	} else if len(tree) > 0 {
+		cache.Put(tree, found)
 	}
 	return nil
 }
@@ -125,17 +156,23 @@ func lsTreeBlobs(ref string, predicate func(*git.TreeBlob) bool) (*TreeBlobChann
 	return NewTreeBlobChannelWrapper(blobs, errchan), nil
 }
 
-func catFileBatchTreeForPointers(treeblobs *TreeBlobChannelWrapper, gitEnv, osEnv config.Environment) (map[string]*WrappedPointer, *filepathfilter.Filter, error) {
+// catFileBatchTreeForPointers reads the pointers in the given tree blobs,
+// along with the entries for the files which should be pointers from the
+// .gitattributes files among them. Every other blob has a nil entry in the
+// returned map, and the contents of those too large to be pointers are never
+// read. The tree entry for each blob is returned too.
+func catFileBatchTreeForPointers(treeblobs *TreeBlobChannelWrapper, gitEnv, osEnv config.Environment) (map[string]*WrappedPointer, map[string]git.TreeBlob, []git.AttributePath, error) {
 	pscanner, err := NewPointerScanner(gitEnv, osEnv)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	oscanner, err := git.NewObjectScanner(gitEnv, osEnv)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	pointers := make(map[string]*WrappedPointer)
+	blobs := make(map[string]git.TreeBlob)
 
 	paths := make([]git.AttributePath, 0)
 	processor := gitattr.NewMacroProcessor()
@@ -156,9 +193,10 @@ func catFileBatchTreeForPointers(treeblobs *TreeBlobChannelWrapper, gitEnv, osEn
 			}
 
 			if err := oscanner.Err(); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		} else if t.Size < blobSizeCutoff {
+			blobs[t.Filename] = t
 			hasNext = pscanner.Scan(t.Oid)
 
 			// It's intentional that we insert nil for
@@ -171,9 +209,10 @@ func catFileBatchTreeForPointers(treeblobs *TreeBlobChannelWrapper, gitEnv, osEn
 			pointers[t.Filename] = p
 
 			if err := pscanner.Err(); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		} else {
+			blobs[t.Filename] = t
 			pointers[t.Filename] = nil
 		}
 
@@ -188,25 +227,112 @@ func catFileBatchTreeForPointers(treeblobs *TreeBlobChannelWrapper, gitEnv, osEn
 		// Deal with nested error from incoming treeblobs
 		err := treeblobs.Wait()
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
 	if err = pscanner.Close(); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if err = oscanner.Close(); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	patterns := make([]filepathfilter.Pattern, 0, len(paths))
+	return pointers, blobs, paths, nil
+}
+
+// attributePattern returns the pattern matching the files an entry in
+// .gitattributes applies to.
+func attributePattern(path git.AttributePath) filepathfilter.Pattern {
+	// Convert all separators to `/` before creating a pattern to
+	// avoid characters being escaped in situations like `subtree\*.md`
+	return filepathfilter.NewPattern(filepath.ToSlash(path.Path), filepathfilter.Strict(true))
+}
+
+// attributeTrackingConditions returns the conditions set for the file with
+// the given name by the lfs-min-size and lfs-content-type attributes of the
+// entries in .gitattributes, later ones taking precedence, or nil if there
+// are none.
+func attributeTrackingConditions(paths []git.AttributePath, name string) *TrackingConditions {
+	values := make(map[string]string)
+	for _, path := range paths {
+		if len(path.Conditions) == 0 || !attributePattern(path).Match(name) {
+			continue
+		}
+
+		for k, v := range path.Conditions {
+			if v == "unspecified" {
+				delete(values, k)
+			} else {
+				values[k] = v
+			}
+		}
+	}
+
+	conditions, _ := NewTrackingConditions(values)
+	return conditions
+}
+
+// attributeTracked returns whether the file with the given name has the
+// filter=lfs attribute in the given entries from .gitattributes, later ones
+// taking precedence. Entries which only set the lockable attribute do not
+// change whether a file is tracked.
+func attributeTracked(paths []git.AttributePath, name string) bool {
+	tracked := false
 	for _, path := range paths {
-		// Convert all separators to `/` before creating a pattern to
-		// avoid characters being escaped in situations like `subtree\*.md`
-		patterns = append(patterns, filepathfilter.NewPattern(filepath.ToSlash(path.Path), filepathfilter.Strict(true)))
+		if path.Lockable && !path.Tracked {
+			continue
+		}
+		if attributeMatches(path, name) {
+			tracked = path.Tracked
+		}
+	}
+	return tracked
+}
+
+// attributeMatches returns whether the entry from .gitattributes applies to the
+// file with the given name, as Git would match it: relative to the directory
+// of the .gitattributes file, and against the file's base name if the pattern
+// has no separators.
+func attributeMatches(attr git.AttributePath, name string) bool {
+	pattern := filepath.ToSlash(attr.Path)
+	if attr.Source != nil {
+		if dir := path.Dir(filepath.ToSlash(attr.Source.Path)); dir != "." {
+			if !strings.HasPrefix(name, dir+"/") {
+				return false
+			}
+			name = strings.TrimPrefix(name, dir+"/")
+			pattern = strings.TrimPrefix(pattern, dir+"/")
+		}
+	}
+	return wildmatch.NewWildmatch(pattern, wildmatch.Basename, wildmatch.SystemCase).Match(name)
+}
+
+// runScanTrackedTree calls cb for each pointer in the tree at ref which the
+// given filter allows and which is tracked by Git LFS according to the
+// .gitattributes files in that tree, rather than those in the working tree.
+// Files which only look like pointers are left out, since Git would not
+// smudge them.
+func runScanTrackedTree(cb GitScannerFoundPointer, ref string, filter *filepathfilter.Filter, gitEnv, osEnv config.Environment) error {
+	treeShas, err := lsTreeBlobs(ref, func(t *git.TreeBlob) bool {
+		return t != nil && !t.IsSymlink() &&
+			(path.Base(t.Filename) == ".gitattributes" || filter.Allows(t.Filename))
+	})
+	if err != nil {
+		return err
+	}
+
+	pointers, _, paths, err := catFileBatchTreeForPointers(treeShas, gitEnv, osEnv)
+	if err != nil {
+		return err
 	}
 
-	return pointers, filepathfilter.NewFromPatterns(patterns, nil), nil
+	for name, p := range pointers {
+		if p != nil && filter.Allows(name) && attributeTracked(paths, name) {
+			cb(p, nil)
+		}
+	}
+	return nil
 }
 
 func runScanTreeForPointers(cb GitScannerFoundPointer, tree string, gitEnv, osEnv config.Environment) error {
@@ -217,21 +343,44 @@ func runScanTreeForPointers(cb GitScannerFoundPointer, tree string, gitEnv, osEn
 		return err
 	}
 
-	pointers, filter, err := catFileBatchTreeForPointers(treeShas, gitEnv, osEnv)
+	pointers, blobs, paths, err := catFileBatchTreeForPointers(treeShas, gitEnv, osEnv)
 	if err != nil {
 		return err
 	}
 
+	patterns := make([]filepathfilter.Pattern, 0, len(paths))
+	for _, path := range paths {
+		patterns = append(patterns, attributePattern(path))
+	}
+	filter := filepathfilter.NewFromPatterns(patterns, nil)
+
 	for name, p := range pointers {
 		// This file matches the patterns in .gitattributes, so it
 		// should be a pointer.  If it is not, then it is a plain Git
-		// blob, which we report as an error.
-		if filter.Allows(name) {
-			if p == nil {
-				cb(nil, errors.NewPointerScanError(errors.NewNotAPointerError(nil), tree, name))
-			} else {
-				cb(p, nil)
-			}
+		// blob, which we report as an error.  Blobs too large to be
+		// pointers are distinguished from those which are small enough
+		// to be, but aren't, as the latter may be damaged pointers.
+		// Symbolic links are not filtered by Git, so they are expected
+		// not to be pointers, and one which is would be checked out as
+		// a link to a pointer.  Files which may not meet the conditions
+		// set by the lfs-min-size and lfs-content-type attributes are
+		// expected to be plain blobs too.
+		if !filter.Allows(name) {
+			continue
+		}
+
+		blob := blobs[name]
+		switch {
+		case blob.IsSymlink() && p != nil:
+			cb(nil, errors.NewPointerScanError(errors.NewSymlinkPointerError(p.Oid), tree, name))
+		case blob.IsSymlink():
+		case p != nil:
+			cb(p, nil)
+		case attributeTrackingConditions(paths, name).MayBeBlob(blob.Size):
+		case blob.Size >= blobSizeCutoff:
+			cb(nil, errors.NewPointerScanError(errors.NewPointerSizeError(blob.Size, blobSizeCutoff), tree, name))
+		default:
+			cb(nil, errors.NewPointerScanError(errors.NewNotAPointerError(nil), tree, name))
 		}
 	}
 	return nil
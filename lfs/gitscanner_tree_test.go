@@ -0,0 +1,34 @@
+package lfs
+
+import (
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeTracked(t *testing.T) {
+	root := &git.AttributeSource{Path: ".gitattributes"}
+	sub := &git.AttributeSource{Path: "sub/.gitattributes"}
+	paths := []git.AttributePath{
+		{Path: "*.dat", Source: root, Tracked: true},
+		{Path: "docs/*.bin", Source: root, Tracked: true},
+		{Path: "*.dat", Source: root, Lockable: true},
+		{Path: "sub/*.txt", Source: sub, Tracked: true},
+		{Path: "sub/plain.dat", Source: sub},
+	}
+
+	for name, tracked := range map[string]bool{
+		"a.dat":            true,
+		"dir/b.dat":        true,
+		"docs/a.bin":       true,
+		"other/docs/a.bin": false,
+		"a.txt":            false,
+		"sub/a.txt":        true,
+		"sub/dir/a.txt":    true,
+		"sub/plain.dat":    false,
+		"sub/other.dat":    true,
+	} {
+		assert.Equal(t, tracked, attributeTracked(paths, name), name)
+	}
+}
@@ -0,0 +1,86 @@
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/rubyist/tracerx"
+)
+
+// InspectObject runs each of the given inspectors which runs at the given
+// stage on the content of the object with the given OID and size, for the
+// file with the given path. The content is read from the reader returned by
+// open, which is called once for each inspector.
+//
+// Each inspector is given the content on its standard input, with the stage,
+// path, OID and size in the GIT_LFS_INSPECT_STAGE, GIT_LFS_INSPECT_PATH,
+// GIT_LFS_INSPECT_OID and GIT_LFS_INSPECT_SIZE environment variables. Each
+// line it writes to its standard output is an annotation, which is returned
+// prefixed with its name. If it exits with a non-zero status, the object is
+// rejected, and an error is returned whose message includes its standard
+// error, without running any further inspectors.
+func InspectObject(inspectors []config.Inspector, stage, path, oid string, size int64, open func() (io.ReadCloser, error)) ([]string, error) {
+	var annotations []string
+	for _, inspector := range inspectors {
+		if !inspector.RunsAt(stage) {
+			continue
+		}
+
+		lines, err := runInspector(inspector, stage, path, oid, size, open)
+		for _, line := range lines {
+			annotations = append(annotations, fmt.Sprintf("%s: %s", inspector.Name, line))
+		}
+		if err != nil {
+			return annotations, err
+		}
+	}
+	return annotations, nil
+}
+
+func runInspector(inspector config.Inspector, stage, path, oid string, size int64, open func() (io.ReadCloser, error)) ([]string, error) {
+	r, err := open()
+	if err != nil {
+		return nil, errors.Wrapf(err, "inspector %s: could not read %s (%s)", inspector.Name, path, oid)
+	}
+	defer r.Close()
+
+	name, args := subprocess.FormatForShell(subprocess.ShellQuoteSingle(inspector.Path), inspector.Args)
+	cmd := subprocess.ExecCommand(name, args...)
+	cmd.Env = append(cmd.Env,
+		"GIT_LFS_INSPECT_STAGE="+stage,
+		"GIT_LFS_INSPECT_PATH="+path,
+		"GIT_LFS_INSPECT_OID="+oid,
+		fmt.Sprintf("GIT_LFS_INSPECT_SIZE=%d", size),
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	tracerx.Printf("inspect: %s %s (%s) with %s", stage, path, oid, inspector.Name)
+	err = cmd.Run()
+
+	var annotations []string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 {
+			annotations = append(annotations, line)
+		}
+	}
+
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if len(reason) == 0 {
+			reason = err.Error()
+		}
+		return annotations, errors.Errorf("inspector %s rejected %s (%s): %s", inspector.Name, path, oid, reason)
+	}
+	return annotations, nil
+}
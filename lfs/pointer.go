@@ -23,11 +23,16 @@ var (
 		"https://hawser.github.com/spec/v1",  // pre-release
 		"https://git-lfs.github.com/spec/v1", // public launch
 	}
+	v2Aliases = []string{
+		"https://git-lfs.github.com/spec/v2",
+	}
 	latest      = "https://git-lfs.github.com/spec/v1"
+	latestV2    = "https://git-lfs.github.com/spec/v2"
 	oidType     = "sha256"
 	oidRE       = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
 	matcherRE   = regexp.MustCompile("git-media|hawser|git-lfs")
 	extRE       = regexp.MustCompile(`\Aext-\d{1}-\w+`)
+	metaRE      = regexp.MustCompile(`\Ameta-[a-z0-9.-]+\z`)
 	pointerKeys = []string{"version", "oid", "size"}
 )
 
@@ -38,6 +43,11 @@ type Pointer struct {
 	OidType    string
 	Extensions []*PointerExtension
 	Canonical  bool
+
+	// Metadata holds the optional metadata keys of a version 2 pointer,
+	// without their "meta-" prefix. A pointer with any metadata is always
+	// encoded as a version 2 pointer.
+	Metadata map[string]string
 }
 
 // A PointerExtension is parsed from the Git LFS Pointer file.
@@ -55,7 +65,7 @@ func (p ByPriority) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 func (p ByPriority) Less(i, j int) bool { return p[i].Priority < p[j].Priority }
 
 func NewPointer(oid string, size int64, exts []*PointerExtension) *Pointer {
-	return &Pointer{latest, oid, size, oidType, exts, true}
+	return &Pointer{latest, oid, size, oidType, exts, true, nil}
 }
 
 func NewPointerExtension(name string, priority int, oid string) *PointerExtension {
@@ -71,16 +81,35 @@ func (p *Pointer) Encoded() string {
 		return ""
 	}
 
+	version := latest
+	if len(p.Metadata) > 0 {
+		version = latestV2
+	}
+
 	var buffer bytes.Buffer
-	buffer.WriteString(fmt.Sprintf("version %s\n", latest))
+	buffer.WriteString(fmt.Sprintf("version %s\n", version))
 	for _, ext := range p.Extensions {
 		buffer.WriteString(fmt.Sprintf("ext-%d-%s %s:%s\n", ext.Priority, ext.Name, ext.OidType, ext.Oid))
 	}
+	for _, key := range p.MetadataKeys() {
+		buffer.WriteString(fmt.Sprintf("meta-%s %s\n", key, p.Metadata[key]))
+	}
 	buffer.WriteString(fmt.Sprintf("oid %s:%s\n", p.OidType, p.Oid))
 	buffer.WriteString(fmt.Sprintf("size %d\n", p.Size))
 	return buffer.String()
 }
 
+// MetadataKeys returns the keys of the pointer's metadata, in the sorted order
+// in which they are encoded.
+func (p *Pointer) MetadataKeys() []string {
+	keys := make([]string, 0, len(p.Metadata))
+	for key := range p.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func EmptyPointer() *Pointer {
 	oid := hex.EncodeToString(sha256.New().Sum(nil))
 	return NewPointer(oid, 0, nil)
@@ -160,12 +189,24 @@ func verifyVersion(version string) error {
 			return nil
 		}
 	}
+	if isV2Version(version) {
+		return nil
+	}
 
 	return errors.New("Invalid version: " + version)
 }
 
+func isV2Version(version string) bool {
+	for _, v := range v2Aliases {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 func decodeKV(data []byte) (*Pointer, error) {
-	kvps, exts, err := decodeKVData(data)
+	kvps, exts, meta, err := decodeKVData(data)
 	if err != nil {
 		if errors.IsBadPointerKeyError(err) {
 			return nil, errors.StandardizeBadPointerError(err)
@@ -208,7 +249,15 @@ func decodeKV(data []byte) (*Pointer, error) {
 		sort.Sort(ByPriority(extensions))
 	}
 
-	return NewPointer(oid, size, extensions), nil
+	p := NewPointer(oid, size, extensions)
+	if len(meta) > 0 {
+		p.Metadata = make(map[string]string, len(meta))
+		for key, value := range meta {
+			p.Metadata[strings.TrimPrefix(key, "meta-")] = value
+		}
+		p.Version = latestV2
+	}
+	return p, nil
 }
 
 func parseOid(value string) (string, error) {
@@ -258,7 +307,7 @@ func validatePointerExtensions(exts []*PointerExtension) error {
 	return nil
 }
 
-func decodeKVData(data []byte) (kvps map[string]string, exts map[string]string, err error) {
+func decodeKVData(data []byte) (kvps map[string]string, exts map[string]string, meta map[string]string, err error) {
 	kvps = make(map[string]string)
 
 	if !matcherRE.Match(data) {
@@ -290,6 +339,15 @@ func decodeKVData(data []byte) (kvps map[string]string, exts map[string]string,
 		}
 
 		if expected := pointerKeys[line]; key != expected {
+			// Metadata keys are only permitted in version 2
+			// pointers, which older clients refuse to parse.
+			if metaRE.MatchString(key) && isV2Version(kvps["version"]) {
+				if meta == nil {
+					meta = make(map[string]string)
+				}
+				meta[key] = value
+				continue
+			}
 			if !extRE.Match([]byte(key)) {
 				err = errors.NewBadPointerKeyError(expected, key)
 				return
@@ -0,0 +1,149 @@
+package lfs
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+const (
+	// MetadataContentType is the metadata key holding the media type of
+	// the object's contents, as in an HTTP Content-Type header.
+	MetadataContentType = "content-type"
+	// MetadataExecutable is the metadata key holding whether the file was
+	// executable when it was cleaned, as either "true" or "false".
+	MetadataExecutable = "executable"
+	// MetadataMtime is the metadata key holding the modification time of
+	// the file when it was cleaned, in RFC 3339 format.
+	MetadataMtime = "mtime"
+)
+
+// DefaultPointerMetadata is the set of metadata keys recorded in version 2
+// pointers unless lfs.pointermetadata is set.
+var DefaultPointerMetadata = []string{MetadataContentType, MetadataExecutable}
+
+// pointerMetadata returns the metadata to record for the file named fileName,
+// whose contents have been written to the temporary file tmpName. Only the
+// keys in fields are recorded; any which cannot be determined, for instance
+// because fileName does not exist on disk, are omitted.
+func pointerMetadata(fields []string, fileName, tmpName string) map[string]string {
+	meta := make(map[string]string)
+
+	var stat os.FileInfo
+	if len(fileName) > 0 {
+		stat, _ = os.Stat(fileName)
+	}
+
+	for _, field := range fields {
+		switch field {
+		case MetadataContentType:
+			if ct := detectContentType(fileName, tmpName); len(ct) > 0 {
+				meta[field] = ct
+			}
+		case MetadataExecutable:
+			if stat != nil {
+				meta[field] = fmt.Sprintf("%t", stat.Mode()&0111 != 0)
+			}
+		case MetadataMtime:
+			if stat != nil {
+				meta[field] = stat.ModTime().UTC().Format(time.RFC3339)
+			}
+		}
+	}
+
+	return meta
+}
+
+// detectContentType returns the media type of the given file, preferring the
+// type registered for its extension, and otherwise sniffing the first bytes of
+// its contents from tmpName.
+func detectContentType(fileName, tmpName string) string {
+	if ct := mime.TypeByExtension(filepath.Ext(fileName)); len(ct) > 0 {
+		return ct
+	}
+
+	f, err := os.Open(tmpName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// ValidatePointerMetadata returns an error if any of the well-known metadata
+// keys of the given pointer have invalid values. Unknown keys are preserved
+// as-is and are not validated.
+func ValidatePointerMetadata(p *Pointer) error {
+	for _, key := range p.MetadataKeys() {
+		value := p.Metadata[key]
+		if len(value) == 0 {
+			return errors.Errorf("empty value for metadata key %q", key)
+		}
+
+		switch key {
+		case MetadataContentType:
+			if _, _, err := mime.ParseMediaType(value); err != nil {
+				return errors.Errorf("invalid %s %q: %s", key, value, err)
+			}
+		case MetadataExecutable:
+			if value != "true" && value != "false" {
+				return errors.Errorf("invalid %s %q: must be \"true\" or \"false\"", key, value)
+			}
+		case MetadataMtime:
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return errors.Errorf("invalid %s %q: %s", key, value, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyPointerMetadata updates the file named filename to reflect the
+// modification time recorded in the given pointer, if any.
+//
+// The executable bit is only applied when core.fileMode is false, since
+// otherwise Git itself tracks the mode of the file, and changing it would make
+// the file appear modified.
+func (f *GitFilter) ApplyPointerMetadata(filename string, ptr *Pointer) error {
+	if len(ptr.Metadata) == 0 {
+		return nil
+	}
+
+	if ptr.Metadata[MetadataExecutable] == "true" && !f.cfg.Git.Bool("core.filemode", true) {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+
+		// Grant execute permission to whoever may read the file.
+		mode := stat.Mode()
+		if err := os.Chmod(filename, mode|(mode&0444)>>2); err != nil {
+			return errors.Wrap(err, "could not set executable bit")
+		}
+	}
+
+	if value, ok := ptr.Metadata[MetadataMtime]; ok {
+		mtime, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s %q", MetadataMtime, value)
+		}
+		if err := os.Chtimes(filename, time.Now(), mtime); err != nil {
+			return errors.Wrap(err, "could not set modification time")
+		}
+	}
+
+	return nil
+}
@@ -347,3 +347,102 @@ size 177735`,
 func assertEqualWithExample(t *testing.T, example string, expected, actual interface{}) {
 	assert.Equal(t, expected, actual, "Example:\n%s", strings.TrimSpace(example))
 }
+
+func TestEncodeMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	pointer := NewPointer("booya", 12345, []*PointerExtension{
+		NewPointerExtension("foo", 0, "foo_oid"),
+	})
+	pointer.Metadata = map[string]string{
+		"mtime":        "2021-01-02T03:04:05Z",
+		"content-type": "image/png",
+		"executable":   "false",
+	}
+	_, err := EncodePointer(&buf, pointer)
+	assert.Nil(t, err)
+
+	bufReader := bufio.NewReader(&buf)
+	assertLine(t, bufReader, "version https://git-lfs.github.com/spec/v2\n")
+	assertLine(t, bufReader, "ext-0-foo sha256:foo_oid\n")
+	assertLine(t, bufReader, "meta-content-type image/png\n")
+	assertLine(t, bufReader, "meta-executable false\n")
+	assertLine(t, bufReader, "meta-mtime 2021-01-02T03:04:05Z\n")
+	assertLine(t, bufReader, "oid sha256:booya\n")
+	assertLine(t, bufReader, "size 12345\n")
+
+	line, err := bufReader.ReadString('\n')
+	if err == nil {
+		t.Fatalf("More to read: %s", line)
+	}
+	assert.Equal(t, "EOF", err.Error())
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v2
+meta-content-type image/png
+meta-platform.foo some value
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	assertEqualWithExample(t, ex, nil, err)
+	assertEqualWithExample(t, ex, latestV2, p.Version)
+	assertEqualWithExample(t, ex, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", p.Oid)
+	assertEqualWithExample(t, ex, int64(12345), p.Size)
+	assertEqualWithExample(t, ex, map[string]string{
+		"content-type": "image/png",
+		"platform.foo": "some value",
+	}, p.Metadata)
+	assertEqualWithExample(t, ex, true, p.Canonical)
+	assertEqualWithExample(t, ex, ex, p.Encoded())
+}
+
+func TestDecodeMetadataUnsorted(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v2
+meta-mtime 2021-01-02T03:04:05Z
+meta-executable true
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	assertEqualWithExample(t, ex, nil, err)
+	assertEqualWithExample(t, ex, "true", p.Metadata["executable"])
+	assertEqualWithExample(t, ex, false, p.Canonical)
+}
+
+func TestDecodeMetadataInV1Pointer(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v1
+meta-content-type image/png
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	assert.Nil(t, p)
+	assert.True(t, errors.IsBadPointerKeyError(err))
+}
+
+func TestValidatePointerMetadata(t *testing.T) {
+	p := NewPointer("booya", 12345, nil)
+	assert.Nil(t, ValidatePointerMetadata(p))
+
+	p.Metadata = map[string]string{
+		"content-type": "text/plain; charset=utf-8",
+		"executable":   "true",
+		"mtime":        "2021-01-02T03:04:05Z",
+		"unknown":      "anything",
+	}
+	assert.Nil(t, ValidatePointerMetadata(p))
+
+	for key, value := range map[string]string{
+		"content-type": "not a media type",
+		"executable":   "yes",
+		"mtime":        "yesterday",
+		"unknown":      "",
+	} {
+		p.Metadata = map[string]string{key: value}
+		assert.NotNil(t, ValidatePointerMetadata(p), "expected %s=%q to be invalid", key, value)
+	}
+}
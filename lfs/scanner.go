@@ -35,11 +35,11 @@ type WrappedPointer struct {
 // under the blobSizeCutoff will be ignored. revs is a channel over
 // which strings containing git sha1s will be sent. It returns a channel
 // from which sha1 strings can be read.
-func catFileBatchCheck(revs *StringChannelWrapper, lockableSet *lockableNameSet) (*StringChannelWrapper, chan string, error) {
+func catFileBatchCheck(revs *StringChannelWrapper, lockableSet *lockableNameSet, session *git.CatFileSession) (*StringChannelWrapper, chan string, error) {
 	smallRevCh := make(chan string, chanBufSize)
 	lockableCh := make(chan string, chanBufSize)
 	errCh := make(chan error, 2) // up to 2 errors, one from each goroutine
-	if err := runCatFileBatchCheck(smallRevCh, lockableCh, lockableSet, revs, errCh); err != nil {
+	if err := runCatFileBatchCheck(smallRevCh, lockableCh, lockableSet, revs, errCh, session); err != nil {
 		return nil, nil, err
 	}
 	return NewStringChannelWrapper(smallRevCh, errCh), lockableCh, nil
@@ -99,3 +99,13 @@ type TreeBlobChannelWrapper struct {
 func NewTreeBlobChannelWrapper(treeBlobChan <-chan git.TreeBlob, errorChan <-chan error) *TreeBlobChannelWrapper {
 	return &TreeBlobChannelWrapper{tools.NewBaseChannelWrapper(errorChan), treeBlobChan}
 }
+
+// copy returns a copy of the pointer, which isn't affected by changes to p.
+func (p *WrappedPointer) copy() *WrappedPointer {
+	c := *p
+	if p.Pointer != nil {
+		ptr := *p.Pointer
+		c.Pointer = &ptr
+	}
+	return &c
+}
@@ -6,11 +6,14 @@ package lfs_test // to avoid import cycles
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/filepathfilter"
+	"github.com/git-lfs/git-lfs/v2/git"
 	. "github.com/git-lfs/git-lfs/v2/lfs"
 	test "github.com/git-lfs/git-lfs/v2/t/cmd/util"
 	"github.com/stretchr/testify/assert"
@@ -205,3 +208,71 @@ func scanPreviousVersions(t *testing.T, ref string, since time.Time) ([]*Wrapped
 	err := gitscanner.ScanPreviousVersions(ref, since, nil)
 	return pointers, err
 }
+
+func TestScanTreeCache(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	outputs := repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+				{Filename: "folder/nested.txt", Size: 40},
+			},
+		},
+	})
+
+	cfg := config.New()
+	scanTree := func(filter *filepathfilter.Filter) []*WrappedPointer {
+		var pointers []*WrappedPointer
+		gitscanner := NewGitScanner(cfg, func(p *WrappedPointer, err error) {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pointers = append(pointers, p)
+		})
+		gitscanner.Filter = filter
+		assert.Nil(t, gitscanner.ScanTree("master"))
+		gitscanner.Close()
+		sort.Sort(test.WrappedPointersByOid(pointers))
+		return pointers
+	}
+
+	// A filtered scan still caches every pointer in the tree.
+	filtered := scanTree(filepathfilter.New([]string{"folder"}, nil))
+	if assert.Len(t, filtered, 1) {
+		assert.Equal(t, "folder/nested.txt", filtered[0].Name)
+	}
+
+	tree, err := git.ResolveTree("master")
+	assert.Nil(t, err)
+	assert.FileExists(t, filepath.Join(cfg.Filesystem().TreeCacheDir(), tree[0:2], tree[2:]))
+
+	expected := []*WrappedPointer{
+		{Name: "file1.txt", Pointer: outputs[0].Files[0]},
+		{Name: "folder/nested.txt", Pointer: outputs[0].Files[1]},
+	}
+	sort.Sort(test.WrappedPointersByOid(expected))
+
+	cached := scanTree(nil)
+	if assert.Len(t, cached, 2) {
+		for i, p := range cached {
+			assert.Equal(t, expected[i].Name, p.Name)
+			assert.Equal(t, expected[i].Oid, p.Oid)
+			assert.Equal(t, expected[i].Size, p.Size)
+		}
+	}
+
+	removed, err := PruneTreeCache(cfg, time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, removed)
+
+	removed, err = PruneTreeCache(cfg, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+}
@@ -0,0 +1,151 @@
+package lfs
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
+)
+
+// TrackingConditionAttrs are the attributes which make only some of the files
+// handled by Git LFS stored as Git LFS objects: lfs-min-size gives the size
+// below which files are stored as plain Git blobs, and lfs-content-type the
+// comma-separated content types of the files which are stored as objects,
+// such as "image/*", or with a leading "!", of those which are not.
+var TrackingConditionAttrs = []string{git.MinSizeAttrib, git.ContentTypeAttrib}
+
+// sniffLen is the number of bytes from the start of a file used to detect its
+// content type.
+const sniffLen = 512
+
+// TrackingConditions are the conditions, given by the lfs-min-size and
+// lfs-content-type attributes, which a file handled by Git LFS must meet to be
+// stored as a Git LFS object. Other files are stored as plain Git blobs.
+type TrackingConditions struct {
+	// MinSize is the size of the smallest file stored as an object.
+	MinSize int64
+	// ContentTypes are the patterns of the content types of the files
+	// stored as objects, or with a leading "!", of those which are not.
+	ContentTypes []string
+}
+
+// NewTrackingConditions returns the conditions given by the values of the
+// attributes for a file, or nil if there are none.
+func NewTrackingConditions(attrs map[string]string) (*TrackingConditions, error) {
+	c := &TrackingConditions{}
+
+	if v, ok := attrs[git.MinSizeAttrib]; ok && v != "set" && v != "unset" {
+		size, err := parseMinSize(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s attribute %q", git.MinSizeAttrib, v)
+		}
+		c.MinSize = size
+	}
+
+	if v, ok := attrs[git.ContentTypeAttrib]; ok && v != "set" && v != "unset" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); len(t) > 0 {
+				c.ContentTypes = append(c.ContentTypes, strings.ToLower(t))
+			}
+		}
+	}
+
+	if c.MinSize == 0 && len(c.ContentTypes) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// parseMinSize parses a size with one of the suffixes Git allows in its
+// configuration, "k", "m" or "g", or with a unit such as "KB" or "MiB".
+func parseMinSize(s string) (int64, error) {
+	if n := len(s); n > 1 {
+		if shift, ok := map[byte]uint{'k': 10, 'm': 20, 'g': 30}[s[n-1]|0x20]; ok {
+			if size, err := strconv.ParseInt(s[:n-1], 10, 64); err == nil {
+				return size << shift, nil
+			}
+		}
+	}
+
+	size, err := humanize.ParseBytes(s)
+	return int64(size), err
+}
+
+// AllowsSize returns whether a file of the given size may be stored as an
+// object. Files which also have the right content type are.
+func (c *TrackingConditions) AllowsSize(size int64) bool {
+	return c == nil || size >= c.MinSize
+}
+
+// AllowsFile returns whether the given file, of the given size, is stored as
+// an object, reading the start of it only if its content type is needed.
+func (c *TrackingConditions) AllowsFile(filename string, size int64) (bool, error) {
+	if !c.AllowsSize(size) {
+		return false, nil
+	}
+	if c == nil || len(c.ContentTypes) == 0 {
+		return true, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return c.Allows(size, head[:n]), nil
+}
+
+// Allows returns whether a file of the given size, starting with the given
+// bytes, is stored as an object.
+func (c *TrackingConditions) Allows(size int64, head []byte) bool {
+	if !c.AllowsSize(size) {
+		return false
+	}
+	if c == nil || len(c.ContentTypes) == 0 {
+		return true
+	}
+
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	contentType := http.DetectContentType(head)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+
+	var included, hasIncludes bool
+	for _, pattern := range c.ContentTypes {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		matched, _ := path.Match(pattern, contentType)
+
+		if negated && matched {
+			return false
+		} else if !negated {
+			hasIncludes = true
+			included = included || matched
+		}
+	}
+	return included || !hasIncludes
+}
+
+// MayBeBlob returns whether a file of the given size may have been stored as
+// a plain Git blob because it did not meet the conditions. Since its content
+// is not at hand, any file may have been if there are content type
+// conditions.
+func (c *TrackingConditions) MayBeBlob(size int64) bool {
+	return c != nil && (!c.AllowsSize(size) || len(c.ContentTypes) > 0)
+}
@@ -0,0 +1,63 @@
+package lfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTrackingConditions(t *testing.T) {
+	for desc, c := range map[string]struct {
+		attrs    map[string]string
+		expected *TrackingConditions
+	}{
+		"none":          {map[string]string{}, nil},
+		"set and unset": {map[string]string{"lfs-min-size": "set", "lfs-content-type": "unset"}, nil},
+		"git suffix":    {map[string]string{"lfs-min-size": "1M"}, &TrackingConditions{MinSize: 1 << 20}},
+		"lower suffix":  {map[string]string{"lfs-min-size": "512k"}, &TrackingConditions{MinSize: 512 << 10}},
+		"unit":          {map[string]string{"lfs-min-size": "2MB"}, &TrackingConditions{MinSize: 2000000}},
+		"bytes":         {map[string]string{"lfs-min-size": "100"}, &TrackingConditions{MinSize: 100}},
+		"content types": {
+			map[string]string{"lfs-content-type": "image/*,!Text/*"},
+			&TrackingConditions{ContentTypes: []string{"image/*", "!text/*"}},
+		},
+	} {
+		conditions, err := NewTrackingConditions(c.attrs)
+		require.Nil(t, err, desc)
+		assert.Equal(t, c.expected, conditions, desc)
+	}
+
+	_, err := NewTrackingConditions(map[string]string{"lfs-min-size": "big"})
+	assert.NotNil(t, err)
+}
+
+func TestTrackingConditionsAllows(t *testing.T) {
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+	text := []byte("hello, world\n")
+
+	var none *TrackingConditions
+	assert.True(t, none.Allows(0, text))
+	assert.False(t, none.MayBeBlob(0))
+
+	size := &TrackingConditions{MinSize: 100}
+	assert.False(t, size.Allows(99, png))
+	assert.True(t, size.Allows(100, png))
+	assert.True(t, size.MayBeBlob(99))
+	assert.False(t, size.MayBeBlob(100))
+
+	images := &TrackingConditions{ContentTypes: []string{"image/*"}}
+	assert.True(t, images.Allows(1000, png))
+	assert.False(t, images.Allows(1000, text))
+	assert.True(t, images.MayBeBlob(1000))
+
+	notText := &TrackingConditions{ContentTypes: []string{"!text/*"}}
+	assert.True(t, notText.Allows(1000, png))
+	assert.False(t, notText.Allows(1000, text))
+
+	both := &TrackingConditions{MinSize: 100, ContentTypes: []string{"image/png", "application/octet-stream"}}
+	assert.False(t, both.Allows(99, png))
+	assert.True(t, both.Allows(100, png))
+	assert.True(t, both.Allows(100, []byte{0, 1, 2, 3}))
+	assert.False(t, both.Allows(100, text))
+}
@@ -0,0 +1,129 @@
+package lfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// treeCacheVersion is recorded in each cached entry, and should be increased
+// whenever the entries, or which Git LFS files are found in a tree, change, so
+// that older entries are ignored.
+const treeCacheVersion = 1
+
+// treeCache stores the Git LFS files found in each tree scanned by ScanTree,
+// so that the trees need not be read again. Since the tree with a given OID
+// never changes, neither do the entries, which are only removed by
+// PruneTreeCache once they haven't been used for some time.
+type treeCache struct {
+	dir string
+	cfg *config.Configuration
+}
+
+type treeCacheEntry struct {
+	Version  int               `json:"version"`
+	Pointers []*WrappedPointer `json:"pointers"`
+}
+
+// newTreeCache returns the cache for the current repository, or nil if
+// lfs.treecache is false.
+func newTreeCache(cfg *config.Configuration) *treeCache {
+	if !cfg.InRepo() || !cfg.Git.Bool("lfs.treecache", true) {
+		return nil
+	}
+	return &treeCache{dir: cfg.Filesystem().TreeCacheDir(), cfg: cfg}
+}
+
+func (c *treeCache) path(tree string) string {
+	return filepath.Join(c.dir, tree[0:2], tree[2:])
+}
+
+// Get returns the Git LFS files in the given tree, if they are cached.
+func (c *treeCache) Get(tree string) ([]*WrappedPointer, bool) {
+	path := c.path(tree)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry treeCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Version != treeCacheVersion {
+		tracerx.Printf("tree cache: ignoring entry for %s", tree)
+		return nil, false
+	}
+
+	// Record the use, so that the entry isn't pruned.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	tracerx.Printf("tree cache: found %d pointer(s) in %s", len(entry.Pointers), tree)
+	return entry.Pointers, true
+}
+
+// Put records the Git LFS files in the given tree. Failures are only traced,
+// since the tree can always be scanned again.
+func (c *treeCache) Put(tree string, pointers []*WrappedPointer) {
+	if err := c.put(tree, pointers); err != nil {
+		tracerx.Printf("tree cache: could not store entry for %s: %v", tree, err)
+	}
+}
+
+func (c *treeCache) put(tree string, pointers []*WrappedPointer) error {
+	data, err := json.Marshal(&treeCacheEntry{
+		Version:  treeCacheVersion,
+		Pointers: pointers,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(tree)
+	if err := tools.MkdirAll(filepath.Dir(path), c.cfg); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent readers never
+	// see a partial entry.
+	tmp, err := tools.TempFile(filepath.Dir(path), "tree", c.cfg)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return tools.RobustRename(tmp.Name(), path)
+}
+
+// PruneTreeCache removes the cached entries for trees which have not been
+// scanned since the given time, and returns how many were removed.
+func PruneTreeCache(cfg *config.Configuration, since time.Time) (int, error) {
+	var removed int
+	err := filepath.Walk(cfg.Filesystem().TreeCacheDir(), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() && fi.ModTime().Before(since) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/fs"
 	"github.com/git-lfs/git-lfs/v2/tools"
 )
 
@@ -188,6 +189,17 @@ func IsWindows() bool {
 }
 
 func CopyFileContents(cfg *config.Configuration, src string, dst string) error {
+	in, err := tools.RobustOpen(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return copyContents(cfg, in, dst)
+}
+
+// copyContents writes the content read from in to a temporary file, which then
+// replaces dst.
+func copyContents(cfg *config.Configuration, in io.Reader, dst string) error {
 	tmp, err := TempFile(cfg, filepath.Base(dst))
 	if err != nil {
 		return err
@@ -196,11 +208,6 @@ func CopyFileContents(cfg *config.Configuration, src string, dst string) error {
 		tmp.Close()
 		os.Remove(tmp.Name())
 	}()
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
 	_, err = io.Copy(tmp, in)
 	if err != nil {
 		return err
@@ -209,7 +216,7 @@ func CopyFileContents(cfg *config.Configuration, src string, dst string) error {
 	if err != nil {
 		return err
 	}
-	return os.Rename(tmp.Name(), dst)
+	return tools.RobustRename(tmp.Name(), dst)
 }
 
 func LinkOrCopy(cfg *config.Configuration, src string, dst string) error {
@@ -223,6 +230,22 @@ func LinkOrCopy(cfg *config.Configuration, src string, dst string) error {
 	return CopyFileContents(cfg, src, dst)
 }
 
+// LinkOrCopyObject links or copies the object with the given OID in the given
+// local storage to dst, reading it from the configured backend if it is not in
+// the object directory, rather than unpacking it there.
+func LinkOrCopyObject(cfg *config.Configuration, f *fs.Filesystem, oid string, dst string) error {
+	if src := f.ObjectPathname(oid); tools.FileExists(src) {
+		return LinkOrCopy(cfg, src, dst)
+	}
+
+	in, err := f.OpenObject(oid)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return copyContents(cfg, in, dst)
+}
+
 // TempFile creates a temporary file in the temporary directory specified by the
 // configuration that has the proper permissions for the repository.  On
 // success, it returns an open, non-nil *os.File, and the caller is responsible
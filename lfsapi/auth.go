@@ -57,6 +57,12 @@ func (c *Client) DoAPIRequestWithAuth(remote string, req *http.Request) (*http.R
 }
 
 func (c *Client) doWithAuth(remote string, access creds.Access, req *http.Request, via []*http.Request) (*http.Response, error) {
+	// Refuse the request before looking up any credentials, so that they
+	// are never sent over an insecure connection.
+	if err := c.client.CheckSecureTransport(req.URL); err != nil {
+		return nil, err
+	}
+
 	req.Header = c.client.ExtraHeadersFor(req)
 
 	credWrapper, err := c.getCreds(remote, access, req)
@@ -115,9 +121,11 @@ func (c *Client) doWithCreds(req *http.Request, credWrapper creds.CredentialHelp
 //
 // 1. NTLM access is handled elsewhere.
 // 2. Existing Authorization or ?token query tells LFS that the request is ready.
-// 3. Netrc based on the hostname.
-// 4. URL authentication on the Endpoint URL or the Git Remote URL.
-// 5. Git Credential Helper, potentially prompting the user.
+// 3. LFS credential helper from "lfs.URL.credentialhelper", which may return
+//    a bearer token rather than a username and password.
+// 4. Netrc based on the hostname.
+// 5. URL authentication on the Endpoint URL or the Git Remote URL.
+// 6. Git Credential Helper, potentially prompting the user.
 //
 // There are three URLs in play, that make this a little confusing.
 //
@@ -158,7 +166,7 @@ func (c *Client) getCreds(remote string, access creds.Access, req *http.Request)
 		err = credWrapper.FillCreds()
 		if err == nil {
 			tracerx.Printf("Filled credentials for %s", credsURL)
-			setRequestAuth(req, credWrapper.Creds["username"], credWrapper.Creds["password"])
+			setRequestAuthFromCreds(req, credWrapper.Creds)
 		}
 		return credWrapper, err
 	}
@@ -289,6 +297,17 @@ func setRequestAuthFromURL(req *http.Request, u *url.URL) bool {
 	return false
 }
 
+// setRequestAuthFromCreds sets the Authorization header of the given request
+// from the given credentials, which either hold an "authtype" and "credential",
+// as returned by an LFS credential helper, or a username and password.
+func setRequestAuthFromCreds(req *http.Request, c creds.Creds) {
+	if authtype, credential := c["authtype"], c["credential"]; len(authtype) > 0 && len(credential) > 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", authtype, credential))
+		return
+	}
+	setRequestAuth(req, c["username"], c["password"])
+}
+
 func setRequestAuth(req *http.Request, user, pass string) {
 	if len(user) == 0 && len(pass) == 0 {
 		return
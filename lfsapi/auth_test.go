@@ -749,3 +749,14 @@ func TestClientRedirectReauthenticate(t *testing.T) {
 	assert.EqualValues(t, 2, called1)
 	assert.EqualValues(t, 1, called2)
 }
+
+func TestSetRequestAuthFromCreds(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.com/repo/info/lfs", nil)
+	require.Nil(t, err)
+
+	setRequestAuthFromCreds(req, creds.Creds{"authtype": "Bearer", "credential": "token"})
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	setRequestAuthFromCreds(req, creds.Creds{"username": "user", "password": "pass"})
+	assert.Equal(t, basicAuth("user", "pass"), req.Header.Get("Authorization"))
+}
@@ -0,0 +1,92 @@
+package lfsapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+)
+
+type bootstrapRef struct {
+	Name string `json:"name,omitempty"`
+}
+
+type bootstrapRequest struct {
+	Ref *bootstrapRef `json:"ref,omitempty"`
+}
+
+// Bootstrap describes a bundle of a repository's objects, as advertised by the
+// optional "POST /objects/bootstrap" endpoint, which a new clone can download
+// in one request before fetching the rest of the objects it needs as usual.
+type Bootstrap struct {
+	// Href is the URL of the bundle, and Header any headers to send when
+	// downloading it, as with a download action.
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header,omitempty"`
+	// Size is the size of the bundle in bytes, if the server says.
+	Size int64 `json:"size,omitempty"`
+}
+
+// Bootstrap asks the server for the given remote for a bundle of the objects
+// for the given ref. An error for which errors.IsNotImplementedError() is true
+// is returned if the server does not offer one.
+func (c *Client) Bootstrap(remote string, ref *git.Ref) (*Bootstrap, error) {
+	e := c.Endpoints.Endpoint("download", remote)
+	bReq := &bootstrapRequest{}
+	if ref != nil {
+		bReq.Ref = &bootstrapRef{Name: ref.Refspec()}
+	}
+
+	req, err := c.NewRequest("POST", e, "objects/bootstrap", bReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req = c.LogRequest(req, "lfs.bootstrap")
+	res, err := c.DoAPIRequestWithAuth(remote, req)
+	if res != nil {
+		switch res.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return nil, errors.NewNotImplementedError(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bootstrap{}
+	if err := lfshttp.DecodeJSON(res, b); err != nil {
+		return nil, err
+	}
+	if len(b.Href) == 0 {
+		return nil, fmt.Errorf("bootstrap response has no href")
+	}
+	return b, nil
+}
+
+// OpenBootstrap starts downloading the given bundle, returning its contents.
+// The bundle is downloaded like an object, with the headers the server gave
+// rather than the client's credentials.
+func (c *Client) OpenBootstrap(b *Bootstrap) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", b.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range b.Header {
+		req.Header.Set(key, value)
+	}
+
+	req = c.LogRequest(req, "lfs.bootstrap.download")
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("could not download bootstrap bundle: %s", res.Status)
+	}
+	return res.Body, nil
+}
@@ -0,0 +1,77 @@
+package lfsapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrap(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/repo/lfs/objects/bootstrap":
+			assert.Equal(t, "POST", req.Method)
+
+			var bReq bootstrapRequest
+			require.Nil(t, json.NewDecoder(req.Body).Decode(&bReq))
+			assert.Equal(t, "refs/heads/main", bReq.Ref.Name)
+
+			w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+			json.NewEncoder(w).Encode(&Bootstrap{
+				Href:   srv.URL + "/bundle",
+				Header: map[string]string{"X-Token": "secret"},
+				Size:   6,
+			})
+		case "/bundle":
+			assert.Equal(t, "GET", req.Method)
+			assert.Equal(t, "secret", req.Header.Get("X-Token"))
+			w.Write([]byte("bundle"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(lfshttp.NewContext(git.NewReadOnlyConfig("", ""), nil, map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+
+	b, err := c.Bootstrap("origin", &git.Ref{Name: "main", Type: git.RefTypeLocalBranch})
+	require.Nil(t, err)
+	assert.Equal(t, srv.URL+"/bundle", b.Href)
+	assert.EqualValues(t, 6, b.Size)
+
+	r, err := c.OpenBootstrap(b)
+	require.Nil(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+	assert.Equal(t, "bundle", string(data))
+}
+
+func TestBootstrapNotImplemented(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(lfshttp.NewContext(git.NewReadOnlyConfig("", ""), nil, map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+
+	b, err := c.Bootstrap("origin", nil)
+	assert.Nil(t, b)
+	assert.True(t, errors.IsNotImplementedError(err))
+}
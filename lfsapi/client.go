@@ -46,10 +46,23 @@ func (c *Client) ConcurrentTransfers() int {
 	return c.client.ConcurrentTransfers
 }
 
+// Offline returns whether Git LFS is offline, in which case the client makes
+// no requests.
+func (c *Client) Offline() bool {
+	return c.client.Offline
+}
+
 func (c *Client) LogHTTPStats(w io.WriteCloser) {
 	c.client.LogHTTPStats(w)
 }
 
+// SSHAuthSessionEnv returns the environment variables with which child
+// processes should be run so that the Git LFS commands they run share
+// git-lfs-authenticate responses with this one, if any.
+func (c *Client) SSHAuthSessionEnv() []string {
+	return c.client.SSHAuthSessionEnv()
+}
+
 func (c *Client) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,292 @@
+package lfsapi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+const (
+	// dnsTypeHTTPS is the type of HTTPS records, as defined by RFC 9460.
+	dnsTypeHTTPS = 65
+	dnsClassIN   = 1
+
+	// httpsParamPort is the key of the "port" SvcParam of an HTTPS record.
+	httpsParamPort = 3
+
+	dnsQueryTimeout = 2 * time.Second
+	resolvConfPath  = "/etc/resolv.conf"
+)
+
+// httpsRecord is an HTTPS record, as defined by RFC 9460. Only the parts used
+// to find a server are kept.
+type httpsRecord struct {
+	// Priority is 0 for a record in AliasMode, and otherwise orders
+	// records in ServiceMode, lowest first.
+	Priority uint16
+	// Target is the name of the server, or "." for the owner of the
+	// record itself.
+	Target string
+	// Port is the port given by the "port" SvcParam, or 0 if there is
+	// none.
+	Port uint16
+}
+
+// lookupHTTPS returns the HTTPS records for the given name, in the order in
+// which they should be tried, by asking the name servers in
+// /etc/resolv.conf directly, since the standard library's resolver cannot look
+// them up. Where there is no such file, as on Windows, it returns an error.
+func lookupHTTPS(name string) ([]*httpsRecord, error) {
+	servers, err := resolvConfServers(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		var records []*httpsRecord
+		records, err = queryHTTPS(server, name)
+		if err == nil {
+			return records, nil
+		}
+	}
+	return nil, err
+}
+
+// resolvConfServers returns the addresses of the name servers in the given
+// resolv.conf(5) file.
+func resolvConfServers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		// Any zone, as in "fe80::1%eth0", is kept for dialing.
+		if ip := net.ParseIP(strings.SplitN(fields[1], "%", 2)[0]); ip != nil {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, errors.Errorf("no name servers in %s", path)
+	}
+	return servers, nil
+}
+
+// queryHTTPS asks the name server at the given address for the HTTPS records
+// of the given name, over UDP, or over TCP if the answer is too large.
+func queryHTTPS(server, name string) ([]*httpsRecord, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query, err := newDNSQuery(id, name, dnsTypeHTTPS)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := exchangeDNS("udp", server, query)
+	if err == nil && len(res) > 3 && res[2]&0x02 != 0 {
+		// The answer was truncated.
+		res, err = exchangeDNS("tcp", server, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseHTTPSResponse(id, res)
+}
+
+func exchangeDNS(network, server string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, dnsQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsQueryTimeout))
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	// Over TCP, each message is preceded by its length.
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// newDNSQuery returns a DNS query, with recursion desired, for the records of
+// the given type for the given name.
+func newDNSQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	msg[2] = 0x01 // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, errors.Errorf("invalid DNS name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, byte(qtype>>8), byte(qtype), 0, dnsClassIN)
+	return msg, nil
+}
+
+// parseHTTPSResponse returns the HTTPS records in the answer section of the
+// given response to the query with the given ID, sorted so that records in
+// AliasMode come first, followed by those in ServiceMode by priority.
+func parseHTTPSResponse(id uint16, msg []byte) ([]*httpsRecord, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg) != id || msg[2]&0x80 == 0 {
+		return nil, errors.New("invalid DNS response")
+	}
+	switch rcode := msg[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		return nil, errors.New("no such host")
+	default:
+		return nil, errors.Errorf("DNS error %d", rcode)
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	var err error
+	for i := 0; i < qdcount; i++ {
+		if _, off, err = readDNSName(msg, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+
+	var records []*httpsRecord
+	for i := 0; i < ancount; i++ {
+		if _, off, err = readDNSName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(msg) {
+			return nil, errors.New("truncated DNS response")
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		class := binary.BigEndian.Uint16(msg[off+2:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, errors.New("truncated DNS response")
+		}
+		if rtype == dnsTypeHTTPS && class == dnsClassIN {
+			record, err := parseHTTPSRecord(msg, off, off+rdlen)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+		off += rdlen
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Priority < records[j].Priority
+	})
+	return records, nil
+}
+
+// parseHTTPSRecord parses the data of an HTTPS record, which runs from start
+// to end in the given message.
+func parseHTTPSRecord(msg []byte, start, end int) (*httpsRecord, error) {
+	if end-start < 3 {
+		return nil, errors.New("invalid HTTPS record")
+	}
+	record := &httpsRecord{Priority: binary.BigEndian.Uint16(msg[start:])}
+
+	target, off, err := readDNSName(msg[:end], start+2)
+	if err != nil {
+		return nil, err
+	}
+	record.Target = target
+
+	for off+4 <= end {
+		key := binary.BigEndian.Uint16(msg[off:])
+		size := int(binary.BigEndian.Uint16(msg[off+2:]))
+		off += 4
+		if off+size > end {
+			return nil, errors.New("invalid HTTPS record")
+		}
+		if key == httpsParamPort && size == 2 {
+			record.Port = binary.BigEndian.Uint16(msg[off:])
+		}
+		off += size
+	}
+	return record, nil
+}
+
+// readDNSName reads the possibly compressed name at the given offset of the
+// given message, and returns it with a trailing dot, along with the offset
+// which follows it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errors.New("invalid DNS name")
+		}
+		size := int(msg[off])
+		switch {
+		case size == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case size&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("invalid DNS name")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		case size&0xc0 != 0:
+			return "", 0, errors.New("invalid DNS name")
+		default:
+			if off+1+size > len(msg) {
+				return "", 0, errors.New("invalid DNS name")
+			}
+			labels = append(labels, string(msg[off+1:off+1+size]))
+			off += 1 + size
+		}
+	}
+}
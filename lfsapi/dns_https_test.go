@@ -0,0 +1,116 @@
+package lfsapi
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// httpsAnswer returns an answer holding an HTTPS record with the given
+// priority, target and port, whose owner is the name in the question.
+func httpsAnswer(priority uint16, target string, port uint16) []byte {
+	rdata := make([]byte, 2)
+	binary.BigEndian.PutUint16(rdata, priority)
+	name, _ := newDNSQuery(0, target+".", 0)
+	rdata = append(rdata, name[12:len(name)-4]...)
+	if port > 0 {
+		// An "alpn" param, which is skipped, then "port".
+		rdata = append(rdata, 0, 1, 0, 3, 2, 'h', '2')
+		rdata = append(rdata, 0, httpsParamPort, 0, 2, byte(port>>8), byte(port))
+	}
+
+	answer := []byte{0xc0, 12, 0, dnsTypeHTTPS, 0, dnsClassIN, 0, 0, 0, 60}
+	answer = append(answer, byte(len(rdata)>>8), byte(len(rdata)))
+	return append(answer, rdata...)
+}
+
+// serveHTTPSRecords answers one query for HTTPS records over UDP with the
+// given answers, and returns the address of the server and the name asked
+// for.
+func serveHTTPSRecords(t *testing.T, answers ...[]byte) (string, <-chan string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	names := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		query := buf[:n]
+		name, _, _ := readDNSName(query, 12)
+		names <- name
+
+		res := append([]byte{}, query...)
+		res[2] |= 0x80 // QR
+		binary.BigEndian.PutUint16(res[6:], uint16(len(answers)))
+		for _, a := range answers {
+			res = append(res, a...)
+		}
+		conn.WriteTo(res, addr)
+	}()
+	return conn.LocalAddr().String(), names
+}
+
+func TestQueryHTTPS(t *testing.T) {
+	server, names := serveHTTPSRecords(t,
+		httpsAnswer(2, "backup.git-server.com", 0),
+		httpsAnswer(1, "lfs.git-server.com", 8443),
+	)
+
+	records, err := queryHTTPS(server, "_lfs._tcp.git-server.com")
+	require.Nil(t, err)
+	assert.Equal(t, "_lfs._tcp.git-server.com.", <-names)
+	assert.Equal(t, []*httpsRecord{
+		{Priority: 1, Target: "lfs.git-server.com.", Port: 8443},
+		{Priority: 2, Target: "backup.git-server.com.", Port: 0},
+	}, records)
+}
+
+func TestParseHTTPSResponseErrors(t *testing.T) {
+	query, err := newDNSQuery(7, "git-server.com", dnsTypeHTTPS)
+	require.Nil(t, err)
+
+	_, err = parseHTTPSResponse(7, query)
+	assert.NotNil(t, err, "not a response")
+
+	res := append([]byte{}, query...)
+	res[2] |= 0x80
+	res[3] |= 3
+	_, err = parseHTTPSResponse(7, res)
+	assert.EqualError(t, err, "no such host")
+
+	res[3] &^= 0x0f
+	binary.BigEndian.PutUint16(res[6:], 1)
+	_, err = parseHTTPSResponse(7, append(res, httpsAnswer(1, "lfs.git-server.com", 443)[:8]...))
+	assert.NotNil(t, err, "truncated answer")
+
+	_, err = parseHTTPSResponse(8, res)
+	assert.NotNil(t, err, "wrong ID")
+}
+
+func TestResolvConfServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolv")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "resolv.conf")
+	require.Nil(t, ioutil.WriteFile(path, []byte(
+		"# comment\nsearch example.com\nnameserver 192.0.2.53\nnameserver fe80::1%eth0\nnameserver bogus\n"), 0644))
+
+	servers, err := resolvConfServers(path)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"192.0.2.53:53", "[fe80::1%eth0]:53"}, servers)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("search example.com\n"), 0644))
+	_, err = resolvConfServers(path)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,166 @@
+package lfsapi
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/git-lfs/git-lfs/v2/ssh"
+	"github.com/rubyist/tracerx"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// dnsDiscoveryService is the service whose SRV or HTTPS records, at
+	// "_lfs._tcp.<host>", give the LFS server for the Git server <host>.
+	dnsDiscoveryService = "lfs"
+)
+
+// discoverEndpoint returns the given endpoint, derived from the URL of a Git
+// remote, with its host replaced by the one in the SRV or HTTPS record for its
+// LFS service, if lfs.dnsdiscovery is enabled and there is one. That allows the
+// LFS server for a Git server to be moved by changing its DNS records,
+// rather than the configuration of every repository.
+//
+// If the host changes, the endpoint's SSH metadata is dropped, since
+// git-lfs-authenticate on the Git server cannot authenticate requests to
+// another host.
+func (e *endpointGitFinder) discoverEndpoint(ep lfshttp.Endpoint) lfshttp.Endpoint {
+	if !e.gitEnv.Bool("lfs.dnsdiscovery", false) {
+		return ep
+	}
+
+	u, err := url.Parse(ep.Url)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return ep
+	}
+
+	hostname := u.Hostname()
+	if len(hostname) == 0 || net.ParseIP(hostname) != nil {
+		return ep
+	}
+
+	host, ok := e.lookupEndpointHost(hostname)
+	if !ok {
+		return ep
+	}
+
+	u.Scheme = "https"
+	u.Host = host
+	tracerx.Printf("api: using LFS server %s for %s from DNS", host, hostname)
+	ep.Url = u.String()
+	if u.Hostname() != hostname {
+		ep.SSHMetadata = ssh.SSHMetadata{}
+	}
+	return ep
+}
+
+// lookupEndpointHost returns the host and port given in the SRV record for
+// the LFS service of the given host or, if it has none, in its HTTPS record,
+// remembering the result for later endpoints.
+func (e *endpointGitFinder) lookupEndpointHost(hostname string) (string, bool) {
+	e.discoveryMu.Lock()
+	defer e.discoveryMu.Unlock()
+
+	if host, ok := e.discovered[hostname]; ok {
+		return host, len(host) > 0
+	}
+
+	host := e.lookupSRVHost(hostname)
+	if len(host) == 0 {
+		host = e.lookupHTTPSHost(hostname)
+	}
+	e.discovered[hostname] = host
+	return host, len(host) > 0
+}
+
+// lookupSRVHost returns the host and port given in the SRV record at
+// "_lfs._tcp.<host>" for the given host, or the empty string if there is
+// none.
+func (e *endpointGitFinder) lookupSRVHost(hostname string) string {
+	_, records, err := e.lookupSRV(dnsDiscoveryService, "tcp", hostname)
+	if err != nil {
+		tracerx.Printf("api: no LFS server for %s in DNS: %s", hostname, err)
+		return ""
+	}
+
+	// Records are sorted by priority, and shuffled by weight within it,
+	// so the first acceptable one is the one to use.
+	for _, record := range records {
+		if host, ok := discoveredHost(record.Target, record.Port, hostname); ok {
+			return host
+		}
+	}
+	return ""
+}
+
+// lookupHTTPSHost returns the host and port given in the HTTPS record at
+// "_lfs._tcp.<host>" for the given host, or the empty string if there is
+// none. A target of "." stands for the host itself, and the port defaults to
+// 443.
+func (e *endpointGitFinder) lookupHTTPSHost(hostname string) string {
+	records, err := e.lookupHTTPS("_" + dnsDiscoveryService + "._tcp." + hostname)
+	if err != nil {
+		tracerx.Printf("api: no LFS server for %s in DNS HTTPS records: %s", hostname, err)
+		return ""
+	}
+
+	for _, record := range records {
+		target := record.Target
+		if target == "." {
+			target = hostname
+		}
+		port := record.Port
+		if port == 0 {
+			port = 443
+		}
+		if host, ok := discoveredHost(target, port, hostname); ok {
+			return host
+		}
+	}
+	return ""
+}
+
+// discoveredHost returns the host and port to use for the given target and
+// port from a DNS record for the given host, if it is acceptable, leaving out
+// the default port.
+func discoveredHost(target string, port uint16, hostname string) (string, bool) {
+	target = strings.TrimSuffix(target, ".")
+	if len(target) == 0 {
+		return "", false
+	}
+	if !inDiscoveryDomain(target, hostname) {
+		tracerx.Printf("api: ignoring LFS server %s for %s from DNS, which is outside its domain", target, hostname)
+		return "", false
+	}
+	if port != 443 {
+		return net.JoinHostPort(target, strconv.Itoa(int(port))), true
+	}
+	return target, true
+}
+
+// inDiscoveryDomain returns whether the given SRV target may serve as the LFS
+// server for the given host: that is, whether it is the host itself or within
+// it, or is within the same registered domain, such as "lfs.example.com" for
+// "git.example.com". Registered domains are found with the Public Suffix List,
+// so that a host such as "foo.co.uk" or "foo.github.io" doesn't accept targets
+// belonging to other owners under "co.uk" or "github.io". This keeps DNS
+// records, which may not be authenticated, from sending credentials for the
+// Git server to an unrelated one.
+func inDiscoveryDomain(target, hostname string) bool {
+	target = strings.ToLower(target)
+	hostname = strings.ToLower(hostname)
+	if target == hostname || strings.HasSuffix(target, "."+hostname) {
+		return true
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		// The host is itself a public suffix, or is not a domain
+		// name, so only it and the hosts within it are accepted.
+		return false
+	}
+	return target == domain || strings.HasSuffix(target, "."+domain)
+}
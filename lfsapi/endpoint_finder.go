@@ -2,6 +2,7 @@ package lfsapi
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"path"
@@ -42,6 +43,11 @@ type endpointGitFinder struct {
 	accessMu  sync.Mutex
 	urlAccess map[string]creds.AccessMode
 	urlConfig *config.URLConfig
+
+	discoveryMu sync.Mutex
+	discovered  map[string]string
+	lookupSRV   func(service, proto, name string) (string, []*net.SRV, error)
+	lookupHTTPS func(name string) ([]*httpsRecord, error)
 }
 
 func NewEndpointFinder(ctx lfshttp.Context) EndpointFinder {
@@ -56,6 +62,9 @@ func NewEndpointFinder(ctx lfshttp.Context) EndpointFinder {
 		aliases:     make(map[string]string),
 		pushAliases: make(map[string]string),
 		urlAccess:   make(map[string]creds.AccessMode),
+		discovered:  make(map[string]string),
+		lookupSRV:   net.LookupSRV,
+		lookupHTTPS: lookupHTTPS,
 	}
 
 	e.urlConfig = config.NewURLConfig(e.gitEnv)
@@ -118,7 +127,7 @@ func (e *endpointGitFinder) RemoteEndpoint(operation, remote string) lfshttp.End
 
 	// finally fall back on git remote url (also supports pushurl)
 	if url := e.GitRemoteURL(remote, operation == "upload"); url != "" {
-		return e.NewEndpointFromCloneURL(operation, url)
+		return e.discoverEndpoint(e.NewEndpointFromCloneURL(operation, url))
 	}
 
 	return lfshttp.Endpoint{}
@@ -187,6 +196,10 @@ func (e *endpointGitFinder) NewEndpoint(operation, rawurl string) lfshttp.Endpoi
 		return endpointFromGitUrl(u, e)
 	case "file":
 		return lfshttp.EndpointFromFileUrl(u)
+	case "scp":
+		// Objects in a directory over SSH, which the scp transfer
+		// adapter handles without a Git LFS server.
+		return lfshttp.Endpoint{Url: rawurl}
 	case "":
 		// If it looks like a local path, it probably is.
 		if _, err := os.Stat(rawurl); err == nil {
@@ -2,11 +2,13 @@ package lfsapi
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
 	"runtime"
 	"testing"
 
 	"github.com/git-lfs/git-lfs/v2/creds"
+	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/lfshttp"
 	"github.com/git-lfs/git-lfs/v2/ssh"
 	"github.com/stretchr/testify/assert"
@@ -231,6 +233,16 @@ func TestBareSSHEndpointAddsLfsSuffix(t *testing.T) {
 	assert.Equal(t, "", e.SSHMetadata.Port)
 }
 
+func TestScpEndpointIsPassedThrough(t *testing.T) {
+	finder := NewEndpointFinder(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.url": "scp://git@example.com:2222/srv/lfs",
+	}))
+
+	e := finder.Endpoint("upload", "")
+	assert.Equal(t, "scp://git@example.com:2222/srv/lfs", e.Url)
+	assert.Equal(t, "", e.SSHMetadata.UserAndHost)
+}
+
 func TestBareSSSHEndpointWithCustomPortInBrackets(t *testing.T) {
 	finder := NewEndpointFinder(lfshttp.NewContext(nil, nil, map[string]string{
 		"remote.origin.url": "[git@example.com:2222]:foo/bar.git",
@@ -726,3 +738,191 @@ func TestNewEndpointFromCloneURLWithConfig(t *testing.T) {
 		}
 	}
 }
+
+func newDiscoveryFinder(t *testing.T, gitConf map[string]string, records map[string][]*net.SRV) (EndpointFinder, *int) {
+	finder := NewEndpointFinder(lfshttp.NewContext(nil, nil, gitConf))
+	lookups := 0
+	finder.(*endpointGitFinder).lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		lookups++
+		assert.Equal(t, "lfs", service)
+		assert.Equal(t, "tcp", proto)
+		if srvs, ok := records[name]; ok {
+			return "_lfs._tcp." + name + ".", srvs, nil
+		}
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	finder.(*endpointGitFinder).lookupHTTPS = func(name string) ([]*httpsRecord, error) {
+		return nil, errors.New("no such host")
+	}
+	return finder, &lookups
+}
+
+func TestEndpointDiscoveredInDNS(t *testing.T) {
+	finder, lookups := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, map[string][]*net.SRV{
+		"git-server.com": {
+			{Target: "lfs.git-server.com.", Port: 8443, Priority: 10},
+			{Target: "backup.git-server.com.", Port: 443, Priority: 20},
+		},
+	})
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://lfs.git-server.com:8443/foo/bar.git/info/lfs", e.Url)
+
+	e = finder.Endpoint("upload", "")
+	assert.Equal(t, "https://lfs.git-server.com:8443/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, 1, *lookups)
+}
+
+func TestEndpointDiscoveredInDNSOmitsDefaultPort(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "git@git-server.com:foo/bar.git",
+		"lfs.dnsdiscovery":  "true",
+	}, map[string][]*net.SRV{
+		"git-server.com": {{Target: "lfs.git-server.com.", Port: 443}},
+	})
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://lfs.git-server.com/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, "", e.SSHMetadata.UserAndHost)
+}
+
+func TestEndpointDiscoveredInDNSKeepsSSHForSameHost(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "git@git-server.com:foo/bar.git",
+		"lfs.dnsdiscovery":  "true",
+	}, map[string][]*net.SRV{
+		"git-server.com": {{Target: "git-server.com.", Port: 8443}},
+	})
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://git-server.com:8443/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, "git@git-server.com", e.SSHMetadata.UserAndHost)
+}
+
+func TestEndpointDiscoveredInDNSOutsideDomain(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git.git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, map[string][]*net.SRV{
+		"git.git-server.com": {
+			{Target: "lfs.attacker.com.", Port: 443, Priority: 10},
+			{Target: "git-server.com.evil.com.", Port: 443, Priority: 15},
+			{Target: "lfs.git-server.com.", Port: 443, Priority: 20},
+		},
+	})
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://lfs.git-server.com/foo/bar.git/info/lfs", e.Url)
+}
+
+func TestEndpointDiscoveryDomain(t *testing.T) {
+	for _, c := range []struct {
+		target, hostname string
+		ok               bool
+	}{
+		{"git-server.com", "git-server.com", true},
+		{"lfs.git-server.com", "git-server.com", true},
+		{"LFS.Git-Server.com", "git-server.com", true},
+		{"lfs.git-server.com", "git.git-server.com", true},
+		{"git-server.com", "git.git-server.com", true},
+		{"lfs.other.git-server.com", "git.git-server.com", true},
+		{"evil-git-server.com", "git-server.com", false},
+		{"lfs.other.com", "git-server.com", false},
+		{"com", "git-server.com", false},
+		{"lfs.git-server.com", "git-server.co.uk", false},
+		{"lfs.git-server.co.uk", "git-server.co.uk", true},
+		{"lfs.attacker.co.uk", "git-server.co.uk", false},
+		{"co.uk", "git-server.co.uk", false},
+		{"lfs.x.github.io", "x.github.io", true},
+		{"attacker.github.io", "x.github.io", false},
+		{"lfs.attacker.github.io", "lfs.x.github.io", false},
+		{"lfs.localhost", "localhost", true},
+		{"other", "localhost", false},
+	} {
+		assert.Equal(t, c.ok, inDiscoveryDomain(c.target, c.hostname), "%s for %s", c.target, c.hostname)
+	}
+}
+
+func TestEndpointDiscoveredInDNSHTTPSRecord(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, nil)
+	var names []string
+	finder.(*endpointGitFinder).lookupHTTPS = func(name string) ([]*httpsRecord, error) {
+		names = append(names, name)
+		return []*httpsRecord{
+			{Priority: 1, Target: "lfs.attacker.com.", Port: 443},
+			{Priority: 2, Target: "lfs.git-server.com.", Port: 8443},
+		}, nil
+	}
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://lfs.git-server.com:8443/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, []string{"_lfs._tcp.git-server.com"}, names)
+}
+
+func TestEndpointDiscoveredInDNSHTTPSRecordForSelf(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, nil)
+	finder.(*endpointGitFinder).lookupHTTPS = func(name string) ([]*httpsRecord, error) {
+		return []*httpsRecord{{Priority: 1, Target: "."}}, nil
+	}
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://git-server.com/foo/bar.git/info/lfs", e.Url)
+}
+
+func TestEndpointDiscoveryPrefersSRVRecords(t *testing.T) {
+	finder, _ := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, map[string][]*net.SRV{
+		"git-server.com": {{Target: "srv.git-server.com.", Port: 443}},
+	})
+	finder.(*endpointGitFinder).lookupHTTPS = func(name string) ([]*httpsRecord, error) {
+		t.Errorf("unexpected HTTPS lookup of %s", name)
+		return nil, nil
+	}
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://srv.git-server.com/foo/bar.git/info/lfs", e.Url)
+}
+
+func TestEndpointNotDiscoveredInDNS(t *testing.T) {
+	finder, lookups := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, nil)
+
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://git-server.com/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, 1, *lookups)
+}
+
+func TestEndpointDiscoveryDisabledOrOverridden(t *testing.T) {
+	records := map[string][]*net.SRV{
+		"git-server.com": {{Target: "lfs.git-server.com.", Port: 443}},
+	}
+
+	finder, lookups := newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+	}, records)
+	e := finder.Endpoint("download", "")
+	assert.Equal(t, "https://git-server.com/foo/bar.git/info/lfs", e.Url)
+	assert.Equal(t, 0, *lookups)
+
+	finder, lookups = newDiscoveryFinder(t, map[string]string{
+		"remote.origin.url": "https://git-server.com/foo/bar",
+		"lfs.url":           "https://other-server.com/foo/bar",
+		"lfs.dnsdiscovery":  "true",
+	}, records)
+	e = finder.Endpoint("download", "")
+	assert.Equal(t, "https://other-server.com/foo/bar", e.Url)
+	assert.Equal(t, 0, *lookups)
+}
@@ -9,7 +9,7 @@ import (
 func (c *Client) doWithNegotiate(req *http.Request, credWrapper creds.CredentialHelperWrapper) (*http.Response, error) {
 	// There are two possibilities here if we're using Negotiate
 	// authentication.  One is that we're using Kerberos, which we try
-	// first.  The other is that we're using NTLM, which we no longer
-	// support.  Fail in that case.
+	// first.  The other is that we're using NTLM, which is only
+	// supported on Windows, where SSPI chooses between them.
 	return c.doWithAccess(req, "", nil, creds.NegotiateAccess)
 }
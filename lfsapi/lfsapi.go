@@ -51,7 +51,7 @@ func (c *Client) Context() lfshttp.Context {
 // server is not using an SSH remote or the git-lfs-transfer style of SSH
 // remote.
 func (c *Client) SSHTransfer(operation, remote string) *ssh.SSHTransfer {
-	if len(operation) == 0 {
+	if len(operation) == 0 || c.Offline() {
 		return nil
 	}
 	endpoint := c.Endpoints.Endpoint(operation, remote)
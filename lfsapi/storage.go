@@ -0,0 +1,49 @@
+package lfsapi
+
+import (
+	"net/http"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+)
+
+// StorageUsage is the usage of a Git LFS server's storage by a repository, as
+// reported by the optional "GET /storage" endpoint.
+type StorageUsage struct {
+	// Objects is the number of objects stored, and Size their total size
+	// in bytes.
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+	// Quota is the most the repository may store in bytes, if it is
+	// limited, or zero otherwise.
+	Quota int64 `json:"quota,omitempty"`
+}
+
+// StorageUsage asks the server for the given remote how much storage the
+// repository uses. An error for which errors.IsNotImplementedError() is true
+// is returned if the server does not support reporting this.
+func (c *Client) StorageUsage(remote string) (*StorageUsage, error) {
+	e := c.Endpoints.Endpoint("download", remote)
+	req, err := c.NewRequest("GET", e, "storage", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req = c.LogRequest(req, "lfs.storage")
+	res, err := c.DoAPIRequestWithAuth(remote, req)
+	if res != nil {
+		switch res.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return nil, errors.NewNotImplementedError(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &StorageUsage{}
+	if err := lfshttp.DecodeJSON(res, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
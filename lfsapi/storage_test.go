@@ -0,0 +1,49 @@
+package lfsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/git"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "GET", req.Method)
+		assert.Equal(t, "/repo/lfs/storage", req.URL.Path)
+
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.Write([]byte(`{"objects":3,"size":1024,"quota":4096}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(lfshttp.NewContext(git.NewReadOnlyConfig("", ""), nil, map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+
+	usage, err := c.StorageUsage("origin")
+	require.Nil(t, err)
+	assert.Equal(t, &StorageUsage{Objects: 3, Size: 1024, Quota: 4096}, usage)
+}
+
+func TestStorageUsageNotImplemented(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(lfshttp.NewContext(git.NewReadOnlyConfig("", ""), nil, map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+
+	usage, err := c.StorageUsage("origin")
+	assert.Nil(t, usage)
+	assert.True(t, errors.IsNotImplementedError(err))
+}
@@ -1,22 +1,35 @@
 package lfshttp
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/tools"
 	"github.com/rubyist/tracerx"
 )
 
 // isCertVerificationDisabledForHost returns whether SSL certificate verification
-// has been disabled for the given host, or globally
+// has been disabled for the given host, or globally. Verification is never
+// disabled when lfs.securetransport is strict.
 func isCertVerificationDisabledForHost(c *Client, host string) bool {
+	if c.StrictTransport {
+		return false
+	}
+
 	hostSslVerify, _ := c.uc.Get("http", fmt.Sprintf("https://%v", host), "sslverify")
 	if hostSslVerify == "false" {
 		return true
@@ -34,6 +47,88 @@ func isClientCertEnabledForHost(c *Client, host string) bool {
 	return hostSslKeyOk && hostSslCertOk
 }
 
+// getPinnedPubKeysForHost returns the SHA-256 hashes of the public keys which
+// the given host is pinned to by lfs.<url>.pinnedpubkey or
+// http.<url>.pinnedpubkey, if any, the former taking precedence. As with curl,
+// the value is either a list of base64-encoded hashes of the key's
+// SubjectPublicKeyInfo separated by semicolons, each prefixed with "sha256//",
+// or the path to a file containing a single public key in PEM or DER format.
+func getPinnedPubKeysForHost(c *Client, host string) ([][]byte, error) {
+	name := "lfs.pinnedpubkey"
+	value, ok := c.uc.Get("lfs", fmt.Sprintf("https://%v/", host), "pinnedpubkey")
+	if !ok {
+		name = "http.pinnedpubkey"
+		value, ok = c.uc.Get("http", fmt.Sprintf("https://%v/", host), "pinnedpubkey")
+	}
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(value, "sha256//") {
+		path, err := tools.ExpandPath(value, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not expand %s path %q", name, value)
+		}
+		key, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %s file %q", name, value)
+		}
+		if block, _ := pem.Decode(key); block != nil {
+			if block.Type != "PUBLIC KEY" {
+				return nil, errors.Errorf("%s file %q does not contain a public key", name, value)
+			}
+			key = block.Bytes
+		}
+		if _, err := x509.ParsePKIXPublicKey(key); err != nil {
+			return nil, errors.Wrapf(err, "invalid public key in %s file %q", name, value)
+		}
+
+		sum := sha256.Sum256(key)
+		return [][]byte{sum[:]}, nil
+	}
+
+	var pins [][]byte
+	for _, pin := range strings.Split(value, ";") {
+		pin = strings.TrimSpace(pin)
+		if !strings.HasPrefix(pin, "sha256//") {
+			return nil, errors.Errorf("invalid %s hash %q: must begin with \"sha256//\"", name, pin)
+		}
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256//"))
+		if err != nil || len(sum) != sha256.Size {
+			return nil, errors.Errorf("invalid %s hash %q", name, pin)
+		}
+		pins = append(pins, sum)
+	}
+	return pins, nil
+}
+
+// verifyPinnedPubKey returns a function for use as
+// tls.Config.VerifyPeerCertificate which checks that the public key of the
+// server's certificate matches one of the given pinned hashes.
+func verifyPinnedPubKey(host string, pins [][]byte) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.Errorf("no certificate presented by %s", host)
+		}
+
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(sum[:], pin) {
+				return nil
+			}
+		}
+
+		tracerx.Printf("http: public key of %s has hash sha256//%s", host,
+			base64.StdEncoding.EncodeToString(sum[:]))
+		return errors.Errorf("public key of %s does not match the pinned public key", host)
+	}
+}
+
 // decryptPEMBlock decrypts an encrypted PEM block representing a private key,
 // prompting for credentials using the credential helper, and returns a
 // decrypted PEM block representing that same private key.
@@ -100,6 +195,81 @@ func getClientCertForHost(c *Client, host string) *tls.Certificate {
 	return &certobj
 }
 
+// clientCertSource provides the client certificate configured for a host,
+// reloading it whenever the certificate or key file changes on disk. This
+// allows short-lived certificates to be rotated without interrupting
+// long-running operations, since each new connection picks up the current
+// certificate.
+type clientCertSource struct {
+	c    *Client
+	host string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certStat os.FileInfo
+	keyStat  os.FileInfo
+}
+
+func newClientCertSource(c *Client, host string) *clientCertSource {
+	s := &clientCertSource{c: c, host: host}
+	s.reload()
+	return s
+}
+
+// GetClientCertificate implements the tls.Config callback of the same name,
+// returning the most recently loaded certificate. If the files have changed
+// but cannot be loaded, perhaps because they are still being rewritten, the
+// previous certificate continues to be used.
+func (s *clientCertSource) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.changed() {
+		s.reload()
+	}
+	if s.cert == nil {
+		// Sending no certificate lets the server decide whether one
+		// is required.
+		return &tls.Certificate{}, nil
+	}
+	return s.cert, nil
+}
+
+// changed returns whether the certificate or key file differ from when they
+// were last loaded.
+func (s *clientCertSource) changed() bool {
+	certStat, keyStat := s.stat()
+	return !sameFile(s.certStat, certStat) || !sameFile(s.keyStat, keyStat)
+}
+
+func (s *clientCertSource) reload() {
+	certStat, keyStat := s.stat()
+	s.certStat, s.keyStat = certStat, keyStat
+
+	if cert := getClientCertForHost(s.c, s.host); cert != nil {
+		if s.cert != nil {
+			tracerx.Printf("http: reloaded client cert for %s", s.host)
+		}
+		s.cert = cert
+	}
+}
+
+func (s *clientCertSource) stat() (certStat, keyStat os.FileInfo) {
+	hostSslKey, _ := s.c.uc.Get("http", fmt.Sprintf("https://%v/", s.host), "sslKey")
+	hostSslCert, _ := s.c.uc.Get("http", fmt.Sprintf("https://%v/", s.host), "sslCert")
+
+	certStat, _ = os.Stat(hostSslCert)
+	keyStat, _ = os.Stat(hostSslKey)
+	return certStat, keyStat
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
 // getRootCAsForHost returns a certificate pool for that specific host (which may
 // be "host:port" loaded from either the gitconfig or from a platform-specific
 // source which is not included by default in the golang certificate search)
@@ -127,26 +297,110 @@ func appendRootCAsForHostFromGitconfig(osEnv, gitEnv config.Environment, pool *x
 		return pool
 	}
 
-	// Accumulate certs from all these locations:
+	if path, isDir := rootCALocationForHost(osEnv, gitEnv, host); isDir {
+		return appendCertsFromFilesInDir(pool, path)
+	} else if len(path) > 0 {
+		return appendCertsFromFile(pool, path)
+	}
+	return pool
+}
+
+// rootCALocationForHost returns the file or directory, as given by isDir,
+// which CA certificates for the given host are read from, if any.
+func rootCALocationForHost(osEnv, gitEnv config.Environment, host string) (path string, isDir bool) {
+	url := fmt.Sprintf("https://%v/", host)
+	uc := config.NewURLConfig(gitEnv)
 
 	// GIT_SSL_CAINFO first
 	if cafile, _ := osEnv.Get("GIT_SSL_CAINFO"); len(cafile) > 0 {
-		return appendCertsFromFile(pool, cafile)
+		return cafile, false
 	}
 	// http.<url>/.sslcainfo or http.<url>.sslcainfo
 	if cafile, ok := uc.Get("http", url, "sslcainfo"); ok {
-		return appendCertsFromFile(pool, cafile)
+		return cafile, false
 	}
 	// GIT_SSL_CAPATH
 	if cadir, _ := osEnv.Get("GIT_SSL_CAPATH"); len(cadir) > 0 {
-		return appendCertsFromFilesInDir(pool, cadir)
+		return cadir, true
 	}
-	// http.sslcapath
-	if cadir, ok := gitEnv.Get("http.sslcapath"); ok {
-		return appendCertsFromFilesInDir(pool, cadir)
+	// http.<url>.sslcapath or http.sslcapath
+	if cadir, ok := uc.Get("http", url, "sslcapath"); ok {
+		return cadir, true
 	}
+	return "", false
+}
 
-	return pool
+// rootCAsCheckInterval is how often the CA certificates for a host are checked
+// for changes.
+const rootCAsCheckInterval = time.Second
+
+// rootCAsState is what was last seen of the CA certificates for a host.
+type rootCAsState struct {
+	stamp   string
+	checked time.Time
+}
+
+// rootCAsStamp returns the names, sizes and modification times of the files
+// which CA certificates for the given host are read from, which change when
+// the certificates do, and whether there are any such files.
+func rootCAsStamp(c *Client, host string) (string, bool) {
+	path, isDir := rootCALocationForHost(c.osEnv, c.gitEnv, host)
+	if len(path) == 0 {
+		return "", false
+	}
+	if p, err := tools.TranslateCygwinPath(path); err == nil {
+		path = p
+	}
+
+	paths := []string{path}
+	if isDir {
+		paths = nil
+		files, _ := ioutil.ReadDir(path)
+		for _, f := range files {
+			paths = append(paths, filepath.Join(path, f.Name()))
+		}
+	}
+
+	var stamp strings.Builder
+	for _, p := range paths {
+		// Stat follows symbolic links, so that a change to the
+		// certificate a link in a directory points to is noticed.
+		if fi, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&stamp, "%s %d %d\n", p, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return stamp.String(), true
+}
+
+// rootCAsChanged returns whether the CA certificates for the given host have
+// changed since the HTTP client for it was made, so that a long-running
+// operation trusts a CA added to a file or directory, such as http.sslcapath,
+// or stops trusting one removed from it, without restarting. It checks at most
+// once every rootCAsCheckInterval. The client's mutex must be held.
+func (c *Client) rootCAsChanged(hd hostData) bool {
+	state, ok := c.rootCAs[hd]
+	if !ok || time.Since(state.checked) < rootCAsCheckInterval {
+		return false
+	}
+
+	stamp, _ := rootCAsStamp(c, hd.host)
+	state.checked = time.Now()
+	return stamp != state.stamp
+}
+
+// watchRootCAs records the CA certificates which the HTTP client for the given
+// host was made with, if they are read from a file or directory. The client's
+// mutex must be held.
+func (c *Client) watchRootCAs(hd hostData) {
+	stamp, ok := rootCAsStamp(c, hd.host)
+	if !ok {
+		delete(c.rootCAs, hd)
+		return
+	}
+	if c.rootCAs == nil {
+		c.rootCAs = make(map[hostData]*rootCAsState)
+	}
+	c.rootCAs[hd] = &rootCAsState{stamp: stamp, checked: time.Now()}
 }
 
 func appendCertsFromFilesInDir(pool *x509.CertPool, dir string) *x509.CertPool {
@@ -1,16 +1,29 @@
 package lfshttp
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/git-lfs/git-lfs/v2/creds"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var testCert = `-----BEGIN CERTIFICATE-----
@@ -283,3 +296,224 @@ func TestCertVerifyDisabledHostConfig(t *testing.T) {
 		assert.False(t, tr.TLSClientConfig.InsecureSkipVerify)
 	}
 }
+
+func TestCertVerifyNotDisabledWithStrictTransport(t *testing.T) {
+	c, err := NewClient(NewContext(nil, map[string]string{
+		"GIT_SSL_NO_VERIFY": "1",
+	}, map[string]string{
+		"http.sslverify": "false",
+		"http.https://specifichost.com/.sslverify": "false",
+		"lfs.securetransport":                      "strict",
+	}))
+	assert.Nil(t, err)
+
+	for _, host := range []string{"specifichost.com", "otherhost.com"} {
+		httpClient := clientForHost(c, host)
+		tr, ok := httpClient.Transport.(*http.Transport)
+		if assert.True(t, ok) {
+			assert.False(t, tr.TLSClientConfig.InsecureSkipVerify)
+			assert.EqualValues(t, tls.VersionTLS12, tr.TLSClientConfig.MinVersion)
+		}
+	}
+}
+
+func TestPinnedPubKey(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.Nil(t, err)
+
+	spki := srv.Certificate().RawSubjectPublicKeyInfo
+	sum := sha256.Sum256(spki)
+	pin := "sha256//" + base64.StdEncoding.EncodeToString(sum[:])
+	otherSum := sha256.Sum256([]byte("other"))
+	otherPin := "sha256//" + base64.StdEncoding.EncodeToString(otherSum[:])
+
+	keyFile, err := ioutil.TempFile("", "pinnedpubkey")
+	require.Nil(t, err)
+	defer os.Remove(keyFile.Name())
+	pem.Encode(keyFile, &pem.Block{Type: "PUBLIC KEY", Bytes: spki})
+	keyFile.Close()
+
+	for desc, c := range map[string]struct {
+		Pin string
+		OK  bool
+		// Section is the section of the key the pin is given in,
+		// "http" if empty.
+		Section string
+	}{
+		"matching hash":            {pin, true, ""},
+		"matching hash in list":    {otherPin + ";" + pin, true, ""},
+		"mismatched hash":          {otherPin, false, ""},
+		"matching public key file": {keyFile.Name(), true, ""},
+		"matching hash for lfs":    {pin, true, "lfs"},
+		"mismatched hash for lfs":  {otherPin, false, "lfs"},
+	} {
+		section := c.Section
+		if len(section) == 0 {
+			section = "http"
+		}
+		client, err := NewClient(NewContext(nil, nil, map[string]string{
+			"http.sslverify": "false",
+			fmt.Sprintf("%s.https://%s/.pinnedpubkey", section, u.Host): c.Pin,
+		}))
+		require.Nil(t, err)
+
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.Nil(t, err)
+
+		res, err := client.Do(req)
+		if c.OK {
+			if assert.Nil(t, err, desc) {
+				assert.Equal(t, 200, res.StatusCode, desc)
+			}
+		} else {
+			assert.NotNil(t, err, desc)
+		}
+	}
+}
+
+func TestPinnedPubKeyInvalid(t *testing.T) {
+	for _, pin := range []string{
+		"sha256//not-base64!",
+		"sha256//" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"sha1//abcd",
+		"/does/not/exist",
+	} {
+		c, err := NewClient(NewContext(nil, nil, map[string]string{
+			"http.pinnedpubkey": pin,
+		}))
+		require.Nil(t, err)
+
+		_, err = c.Transport(&url.URL{Scheme: "https", Host: "example.com"}, creds.BasicAccess)
+		assert.NotNil(t, err, pin)
+	}
+}
+
+func TestPinnedPubKeyForLFSTakesPrecedence(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	otherSum := sha256.Sum256([]byte("other"))
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"http.sslverify":                    "false",
+		"http.pinnedpubkey":                 "sha256//" + base64.StdEncoding.EncodeToString(otherSum[:]),
+		"lfs." + srv.URL + "/.pinnedpubkey": "sha256//" + base64.StdEncoding.EncodeToString(sum[:]),
+	}))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+}
+
+func TestRootCAsReloadedOnChange(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "testcertdir")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "cert1.pem"), []byte(testCert), 0644))
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"http.sslcapath": dir,
+	}))
+	require.Nil(t, err)
+
+	get := func() error {
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.Nil(t, err)
+		res, err := c.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	// The server's certificate is not trusted until its CA is added.
+	assert.NotNil(t, get())
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "cert2.pem"), cert, 0644))
+	for _, state := range c.rootCAs {
+		state.checked = time.Now().Add(-rootCAsCheckInterval)
+	}
+	assert.Nil(t, get())
+}
+
+func TestClientCertReloadedOnChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client-cert")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	first := writeClientCert(t, certFile, keyFile, "first", time.Now().Add(-time.Minute))
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"http.sslcert": certFile,
+		"http.sslkey":  keyFile,
+	}))
+	require.Nil(t, err)
+
+	tr, err := c.Transport(&url.URL{Scheme: "https", Host: "example.com"}, creds.BasicAccess)
+	require.Nil(t, err)
+	getCert := tr.(*http.Transport).TLSClientConfig.GetClientCertificate
+	require.NotNil(t, getCert)
+
+	cert, err := getCert(nil)
+	require.Nil(t, err)
+	assert.Equal(t, first, cert.Certificate[0])
+
+	second := writeClientCert(t, certFile, keyFile, "second", time.Now())
+
+	cert, err = getCert(nil)
+	require.Nil(t, err)
+	assert.Equal(t, second, cert.Certificate[0])
+
+	// A partially written certificate keeps the previous one in use.
+	require.Nil(t, ioutil.WriteFile(certFile, []byte("garbage"), 0644))
+
+	cert, err = getCert(nil)
+	require.Nil(t, err)
+	assert.Equal(t, second, cert.Certificate[0])
+}
+
+// writeClientCert writes a new self-signed certificate and its key, setting
+// the modification time of both files, and returns the DER-encoded
+// certificate.
+func writeClientCert(t *testing.T, certFile, keyFile, name string, mtime time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.Nil(t, os.Chtimes(certFile, mtime, mtime))
+	require.Nil(t, os.Chtimes(keyFile, mtime, mtime))
+
+	return der
+}
@@ -16,7 +16,6 @@ import (
 	"sync"
 	"time"
 
-	spnego "github.com/dpotapov/go-spnego"
 	"github.com/git-lfs/git-lfs/v2/config"
 	"github.com/git-lfs/git-lfs/v2/creds"
 	"github.com/git-lfs/git-lfs/v2/errors"
@@ -52,6 +51,15 @@ type Client struct {
 	ConcurrentTransfers int
 	SkipSSLVerify       bool
 
+	// StrictTransport is set when lfs.securetransport is "strict", in
+	// which case only HTTPS URLs may be used, TLS 1.2 or later is
+	// required, and certificate verification cannot be disabled.
+	StrictTransport bool
+
+	// Offline is set when Git LFS is offline, as given by lfs.offline or
+	// GIT_LFS_OFFLINE, in which case no requests are made.
+	Offline bool
+
 	Verbose          bool
 	DebuggingVerbose bool
 	VerboseOut       io.Writer
@@ -59,8 +67,22 @@ type Client struct {
 	hostClients map[hostData]*http.Client
 	clientMu    sync.Mutex
 
+	// rootCAs is what was last seen of the CA certificates for each host
+	// whose HTTP client was made with some from a file or directory.
+	rootCAs map[hostData]*rootCAsState
+
+	limiters  map[string]*requestLimiter
+	limiterMu sync.Mutex
+
 	httpLogger *syncLogger
 
+	// httpDumpDir is the directory which requests and responses are
+	// written to, as given by GIT_LFS_DUMP_HTTP, if any.
+	httpDumpDir string
+
+	keyLog     *os.File
+	keyLogOnce sync.Once
+
 	gitEnv config.Environment
 	osEnv  config.Environment
 	uc     *config.URLConfig
@@ -68,6 +90,11 @@ type Client struct {
 	credHelperContext *creds.CredentialHelperContext
 
 	sshTries int
+
+	// sshShare is the file through which git-lfs-authenticate responses
+	// are shared with the other Git LFS commands of the same Git
+	// operation, if enabled with lfs.ssh.authshare.
+	sshShare *sshDiskCache
 }
 
 func NewClient(ctx Context) (*Client, error) {
@@ -80,19 +107,34 @@ func NewClient(ctx Context) (*Client, error) {
 
 	cacheCreds := gitEnv.Bool("lfs.cachecredentials", true)
 	var sshResolver SSHResolver = &sshAuthClient{os: osEnv, git: gitEnv}
+	var sshShare *sshDiskCache
+	if path := sshDiskCachePath(osEnv, gitEnv); len(path) > 0 {
+		sshResolver = withSSHDiskCache(sshResolver, path, 0)
+	} else if cacheCreds {
+		session := sshShareSession(osEnv)
+		if path := sshSharedCachePath(osEnv, gitEnv, session); len(path) > 0 {
+			sshShare = newSSHDiskCache(sshResolver, path, sshShareMaxAge)
+			sshShare.session = session
+			sshResolver = sshShare
+		}
+	}
 	if cacheCreds {
 		sshResolver = withSSHCache(sshResolver)
 	}
 
 	c := &Client{
 		SSH:                 sshResolver,
+		sshShare:            sshShare,
 		DialTimeout:         gitEnv.Int("lfs.dialtimeout", 0),
 		KeepaliveTimeout:    gitEnv.Int("lfs.keepalive", 0),
 		TLSTimeout:          gitEnv.Int("lfs.tlstimeout", 0),
-		ConcurrentTransfers: gitEnv.Int("lfs.concurrenttransfers", 8),
+		ConcurrentTransfers: config.ConcurrentTransfers(gitEnv, osEnv),
 		SkipSSLVerify:       !gitEnv.Bool("http.sslverify", true) || osEnv.Bool("GIT_SSL_NO_VERIFY", false),
+		StrictTransport:     isStrictTransport(gitEnv),
+		Offline:             config.IsOffline(gitEnv, osEnv),
 		Verbose:             osEnv.Bool("GIT_CURL_VERBOSE", false),
 		DebuggingVerbose:    osEnv.Bool("LFS_DEBUG_HTTP", false),
+		httpDumpDir:         httpDumpDir(osEnv),
 		gitEnv:              gitEnv,
 		osEnv:               osEnv,
 		uc:                  config.NewURLConfig(gitEnv),
@@ -103,6 +145,26 @@ func NewClient(ctx Context) (*Client, error) {
 	return c, nil
 }
 
+func httpDumpDir(osEnv config.Environment) string {
+	dir, _ := osEnv.Get("GIT_LFS_DUMP_HTTP")
+	return dir
+}
+
+func isStrictTransport(gitEnv config.Environment) bool {
+	v, _ := gitEnv.Get("lfs.securetransport")
+	return strings.EqualFold(v, "strict")
+}
+
+// CheckSecureTransport returns an error if requests to the given URL are not
+// permitted because lfs.securetransport is "strict" and the URL does not use
+// HTTPS.
+func (c *Client) CheckSecureTransport(u *url.URL) error {
+	if !c.StrictTransport || u.Scheme == "https" {
+		return nil
+	}
+	return errors.Errorf("lfs.securetransport is strict: refusing to send request to %s over %s", u.Host, u.Scheme)
+}
+
 func (c *Client) GitEnv() config.Environment {
 	return c.gitEnv
 }
@@ -201,12 +263,32 @@ func (c *Client) do(req *http.Request, remote string, via []*http.Request, mode
 	return res, c.handleResponse(res)
 }
 
+// SSHAuthSessionEnv returns the environment variables with which child
+// processes should be run so that the Git LFS commands they run share
+// git-lfs-authenticate responses with this one, if lfs.ssh.authshare is set.
+func (c *Client) SSHAuthSessionEnv() []string {
+	if c.sshShare == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s=%s", sshShareSessionEnv, c.sshShare.session)}
+}
+
 // Close closes any resources that this client opened.
 func (c *Client) Close() error {
+	if c.keyLog != nil {
+		c.keyLog.Close()
+	}
+	if c.sshShare != nil {
+		c.sshShare.cleanup()
+	}
 	return c.httpLogger.Close()
 }
 
 func (c *Client) sshResolveWithRetries(e Endpoint, method string) (*sshAuthResponse, error) {
+	if c.Offline {
+		return nil, NewOfflineError(e.SSHMetadata.UserAndHost)
+	}
+
 	var sshRes sshAuthResponse
 	var err error
 
@@ -274,6 +356,17 @@ func (c *Client) extraHeaders(u *url.URL) map[string][]string {
 }
 
 func (c *Client) DoWithRedirect(cli *http.Client, req *http.Request, remote string, via []*http.Request) (*http.Request, *http.Response, error) {
+	if c.Offline {
+		return nil, nil, NewOfflineError(req.URL.Scheme + "://" + req.URL.Host)
+	}
+
+	// This is checked for each request, so that redirects are checked
+	// as well.
+	if err := c.CheckSecureTransport(req.URL); err != nil {
+		return nil, nil, err
+	}
+	c.waitForRateLimit(req.URL)
+
 	tracedReq, err := c.traceRequest(req)
 	if err != nil {
 		return nil, nil, err
@@ -331,6 +424,11 @@ func (c *Client) DoWithRedirect(cli *http.Client, req *http.Request, remote stri
 	}
 
 	redirectTo := res.Header.Get("Location")
+	if len(redirectTo) == 0 {
+		// Not a redirect at all, such as a "308 Resume Incomplete"
+		// response to a resumable upload, which the caller handles.
+		return nil, res, nil
+	}
 	locurl, err := url.Parse(redirectTo)
 	if err == nil && !locurl.IsAbs() {
 		locurl = req.URL.ResolveReference(locurl)
@@ -435,11 +533,12 @@ func (c *Client) Transport(u *url.URL, access creds.AccessMode) (http.RoundTripp
 		KeepAlive: time.Duration(keepalivetime) * time.Second,
 		DualStack: true,
 	}
+	eyeballs := newEyeballsDialer(dialer, c.ipVersion())
 
 	if activityTimeout > 0 {
 		activityDuration := time.Duration(activityTimeout) * time.Second
 		tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			c, err := dialer.DialContext(ctx, network, addr)
+			c, err := eyeballs.DialContext(ctx, network, addr)
 			if c == nil {
 				return c, err
 			}
@@ -450,20 +549,25 @@ func (c *Client) Transport(u *url.URL, access creds.AccessMode) (http.RoundTripp
 			return &deadlineConn{Timeout: activityDuration, Conn: c}, err
 		}
 	} else {
-		tr.DialContext = dialer.DialContext
+		tr.DialContext = eyeballs.DialContext
 	}
 
 	tr.TLSClientConfig = &tls.Config{
 		Renegotiation: tls.RenegotiateFreelyAsClient,
 	}
 
+	if c.StrictTransport {
+		tr.TLSClientConfig.MinVersion = tls.VersionTLS12
+	}
+
+	if w := c.keyLogWriter(); w != nil {
+		tr.TLSClientConfig.KeyLogWriter = w
+	}
+
 	if isClientCertEnabledForHost(c, host) {
 		tracerx.Printf("http: client cert for %s", host)
-		cert := getClientCertForHost(c, host)
-		if cert != nil {
-			tr.TLSClientConfig.Certificates = []tls.Certificate{*cert}
-			tr.TLSClientConfig.BuildNameToCertificate()
-		}
+		source := newClientCertSource(c, host)
+		tr.TLSClientConfig.GetClientCertificate = source.GetClientCertificate
 	}
 
 	if isCertVerificationDisabledForHost(c, host) {
@@ -472,16 +576,40 @@ func (c *Client) Transport(u *url.URL, access creds.AccessMode) (http.RoundTripp
 		tr.TLSClientConfig.RootCAs = getRootCAsForHost(c, host)
 	}
 
+	pins, err := getPinnedPubKeysForHost(c, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) > 0 {
+		tracerx.Printf("http: pinned public key for %s", host)
+		tr.TLSClientConfig.VerifyPeerCertificate = verifyPinnedPubKey(host, pins)
+	}
+
 	if err := c.configureProtocols(u, tr); err != nil {
 		return nil, err
 	}
 
-	if access == creds.NegotiateAccess {
-		// This technically copies a mutex, but we know since we've just created
-		// the object that this mutex is unlocked.
-		return &spnego.Transport{Transport: *tr}, nil
+	proxyScheme := proxyAuthMethod(u, c.uc, c.osEnv)
+	if len(proxyScheme) > 0 {
+		tracerx.Printf("http: authenticating to proxies for %s with %s", host, proxyScheme)
+		tr.Proxy = negotiatingProxyFromClient(c)
+		tr.DialContext = negotiatingProxyDialer(c, proxyScheme, tr.DialContext)
 	}
-	return tr, nil
+
+	var rt http.RoundTripper = tr
+	if access == creds.NegotiateAccess || len(proxyScheme) > 0 {
+		rt = &negotiateTransport{
+			Transport:     tr,
+			origin:        access == creds.NegotiateAccess,
+			proxyScheme:   proxyScheme,
+			newNegotiator: newNegotiator,
+		}
+	}
+	if len(c.httpDumpDir) > 0 {
+		tracerx.Printf("http: writing requests to %s to %q", host, c.httpDumpDir)
+		rt = &dumpTransport{RoundTripper: rt, c: c}
+	}
+	return rt, nil
 }
 
 func (c *Client) HttpClient(u *url.URL, access creds.AccessMode) (*http.Client, error) {
@@ -497,7 +625,13 @@ func (c *Client) HttpClient(u *url.URL, access creds.AccessMode) (*http.Client,
 	hd := hostData{host: host, mode: access}
 
 	if client, ok := c.hostClients[hd]; ok {
-		return client, nil
+		if !c.rootCAsChanged(hd) {
+			return client, nil
+		}
+		tracerx.Printf("http: CA certificates for %s changed, reloading", host)
+		if tr, ok := client.Transport.(*http.Transport); ok {
+			tr.CloseIdleConnections()
+		}
 	}
 
 	tr, err := c.Transport(u, access)
@@ -522,6 +656,7 @@ func (c *Client) HttpClient(u *url.URL, access creds.AccessMode) (*http.Client,
 	}
 
 	c.hostClients[hd] = httpClient
+	c.watchRootCAs(hd)
 	if c.VerboseOut == nil {
 		c.VerboseOut = os.Stderr
 	}
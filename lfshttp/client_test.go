@@ -176,6 +176,28 @@ func TestClientRedirect(t *testing.T) {
 	assert.EqualError(t, err, "lfsapi/client: refusing insecure redirect, https->http")
 }
 
+func TestClientResumeIncompleteIsNotRedirect(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&called, 1)
+		w.Header().Set("Range", "bytes=0-99")
+		w.WriteHeader(308)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil)
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("PUT", srv.URL+"/upload", nil)
+	require.Nil(t, err)
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 308, res.StatusCode)
+	assert.Equal(t, "bytes=0-99", res.Header.Get("Range"))
+	assert.EqualValues(t, 1, called)
+}
+
 func TestNewClient(t *testing.T) {
 	c, err := NewClient(NewContext(nil, nil, map[string]string{
 		"lfs.dialtimeout":         "151",
@@ -239,6 +261,42 @@ func TestNewClientWithOSSSLVerify(t *testing.T) {
 	}
 }
 
+func TestClientStrictTransport(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&called, 1)
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"lfs.securetransport": "strict",
+	}))
+	require.Nil(t, err)
+	assert.True(t, c.StrictTransport)
+
+	req, err := http.NewRequest("GET", srv.URL+"/ok", nil)
+	require.Nil(t, err)
+
+	res, err := c.Do(req)
+	assert.Nil(t, res)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "lfs.securetransport is strict")
+	}
+	assert.EqualValues(t, 0, called)
+
+	c, err = NewClient(NewContext(nil, nil, map[string]string{
+		"lfs.securetransport": "default",
+	}))
+	require.Nil(t, err)
+	assert.False(t, c.StrictTransport)
+
+	res, err = c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.EqualValues(t, 1, called)
+}
+
 func TestNewRequest(t *testing.T) {
 	tests := [][]string{
 		{"https://example.com", "a", "https://example.com/a"},
@@ -0,0 +1,203 @@
+package lfshttp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+// connectionAttemptDelay is how long to wait for a connection attempt to one
+// address before starting another to the next, as recommended by RFC 8305.
+const connectionAttemptDelay = 250 * time.Millisecond
+
+// eyeballsDialer connects to hosts as RFC 8305 ("Happy Eyeballs Version 2")
+// describes: it tries the host's addresses in turn, alternating between IPv6
+// and IPv4, starting another attempt each time one has not connected within
+// connectionAttemptDelay or has failed, and keeps the first connection made.
+// An address which does not answer, as happens where IPv6 is broken, so holds
+// up connecting by no more than connectionAttemptDelay. If lfs.ipversion is
+// "4" or "6", only addresses of that version are used.
+type eyeballsDialer struct {
+	// ipVersion is "4", "6" or "" for both.
+	ipVersion string
+	timeout   time.Duration
+
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func newEyeballsDialer(dialer *net.Dialer, ipVersion string) *eyeballsDialer {
+	if ipVersion == "auto" {
+		ipVersion = ""
+	}
+	return &eyeballsDialer{
+		ipVersion: ipVersion,
+		timeout:   dialer.Timeout,
+		lookup:    net.DefaultResolver.LookupIPAddr,
+		dial:      dialer.DialContext,
+	}
+}
+
+func (d *eyeballsDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" {
+		return d.dial(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !d.allows(ip) {
+			return nil, errors.Errorf("lfs.ipversion is %s, but %s is not an IPv%s address", d.ipVersion, host, d.ipVersion)
+		}
+		return d.dial(ctx, network, addr)
+	}
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := d.sortAddrs(addrs)
+	if len(ips) == 0 {
+		return nil, errors.Errorf("no IPv%s address found for %s", d.ipVersion, host)
+	}
+	return d.race(ctx, host, port, ips)
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// race connects to each of the given addresses in turn, starting the next
+// attempt when the last has failed or has gone on for connectionAttemptDelay,
+// and returns the first connection made, closing any others.
+func (d *eyeballsDialer) race(ctx context.Context, host, port string, ips []net.IP) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(ips))
+	next, pending := 0, 0
+	var delay <-chan time.Time
+	startNext := func() {
+		ip := ips[next]
+		next, pending = next+1, pending+1
+		go func() {
+			conn, err := d.dial(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+			results <- dialResult{conn, err}
+		}()
+		delay = nil
+		if next < len(ips) {
+			delay = time.After(connectionAttemptDelay)
+		}
+	}
+
+	var firstErr error
+	for startNext(); pending > 0; {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if pending > 0 {
+					go closeLateConnections(results, pending)
+				}
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if next < len(ips) {
+				startNext()
+			}
+		case <-delay:
+			tracerx.Printf("http: no connection to %s within %v, trying %s", host, connectionAttemptDelay, ips[next])
+			startNext()
+		case <-ctx.Done():
+			go closeLateConnections(results, pending)
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			return nil, firstErr
+		}
+	}
+	return nil, firstErr
+}
+
+// closeLateConnections closes the connections made by attempts which finish
+// after another has connected.
+func closeLateConnections(results <-chan dialResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
+
+// sortAddrs returns the addresses of the allowed versions, alternating
+// between IPv6 and IPv4 addresses, starting with the version of the first, as
+// RFC 8305 section 4 recommends.
+func (d *eyeballsDialer) sortAddrs(addrs []net.IPAddr) []net.IP {
+	var first, second []net.IP
+	firstIs4 := false
+	for i, a := range addrs {
+		if !d.allows(a.IP) {
+			continue
+		}
+		is4 := a.IP.To4() != nil
+		if len(first) == 0 && len(second) == 0 {
+			firstIs4 = is4
+		}
+		if is4 == firstIs4 {
+			first = append(first, addrs[i].IP)
+		} else {
+			second = append(second, addrs[i].IP)
+		}
+	}
+
+	ips := make([]net.IP, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ips = append(ips, first[i])
+		}
+		if i < len(second) {
+			ips = append(ips, second[i])
+		}
+	}
+	return ips
+}
+
+func (d *eyeballsDialer) allows(ip net.IP) bool {
+	switch d.ipVersion {
+	case "4":
+		return ip.To4() != nil
+	case "6":
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// ipVersion returns the IP version which lfs.ipversion limits connections to,
+// being "4", "6" or "auto".
+func (c *Client) ipVersion() string {
+	v, _ := c.gitEnv.Get("lfs.ipversion")
+	v = strings.ToLower(strings.TrimSpace(v))
+	switch v {
+	case "4", "6":
+		return v
+	case "", "auto":
+	default:
+		tracerx.Printf("http: unknown lfs.ipversion %q, using auto", v)
+	}
+	return "auto"
+}
@@ -0,0 +1,146 @@
+package lfshttp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAddrs(ips ...string) []net.IPAddr {
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs
+}
+
+func TestEyeballsDialerSortsAddrs(t *testing.T) {
+	addrs := testAddrs("2001:db8::1", "2001:db8::2", "2001:db8::3", "192.0.2.1", "192.0.2.2")
+
+	d := &eyeballsDialer{}
+	assert.Equal(t, []net.IP{
+		net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.1"),
+		net.ParseIP("2001:db8::2"), net.ParseIP("192.0.2.2"),
+		net.ParseIP("2001:db8::3"),
+	}, d.sortAddrs(addrs))
+
+	d = &eyeballsDialer{ipVersion: "4"}
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, d.sortAddrs(addrs))
+
+	d = &eyeballsDialer{ipVersion: "6"}
+	assert.Len(t, d.sortAddrs(addrs), 3)
+}
+
+// blackholeDial returns a dial function which connects to the given listener
+// when asked for any address in good, and otherwise never answers, as with
+// IPv6 addresses where IPv6 is broken. It records the addresses asked for.
+func blackholeDial(ln net.Listener, good map[string]bool, dialed *[]string, mu *sync.Mutex) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		*dialed = append(*dialed, addr)
+		mu.Unlock()
+
+		if good[addr] {
+			var d net.Dialer
+			return d.DialContext(ctx, network, ln.Addr().String())
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func TestEyeballsDialerFallsBackFromBrokenIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	var mu sync.Mutex
+	var dialed []string
+	d := &eyeballsDialer{
+		lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return testAddrs("2001:db8::1", "192.0.2.1"), nil
+		},
+		dial: blackholeDial(ln, map[string]bool{"192.0.2.1:80": true}, &dialed, &mu),
+	}
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", "example.com:80")
+	require.Nil(t, err)
+	conn.Close()
+
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= connectionAttemptDelay, "connected after %v", elapsed)
+	assert.True(t, elapsed < 5*connectionAttemptDelay, "connected after %v", elapsed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"[2001:db8::1]:80", "192.0.2.1:80"}, dialed)
+}
+
+func TestEyeballsDialerTriesNextAddrOnFailure(t *testing.T) {
+	var dialed []string
+	d := &eyeballsDialer{
+		lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return testAddrs("2001:db8::1", "192.0.2.1"), nil
+		},
+		dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	start := time.Now()
+	_, err := d.DialContext(context.Background(), "tcp", "example.com:80")
+	require.NotNil(t, err)
+	assert.True(t, time.Since(start) < connectionAttemptDelay)
+	assert.Equal(t, []string{"[2001:db8::1]:80", "192.0.2.1:80"}, dialed)
+}
+
+func TestEyeballsDialerIPVersion(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	var mu sync.Mutex
+	var dialed []string
+	d := &eyeballsDialer{
+		ipVersion: "4",
+		lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return testAddrs("2001:db8::1", "192.0.2.1"), nil
+		},
+		dial: blackholeDial(ln, map[string]bool{"192.0.2.1:80": true}, &dialed, &mu),
+	}
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", "example.com:80")
+	require.Nil(t, err)
+	conn.Close()
+	assert.True(t, time.Since(start) < connectionAttemptDelay)
+	assert.Equal(t, []string{"192.0.2.1:80"}, dialed)
+
+	_, err = d.DialContext(context.Background(), "tcp", "[2001:db8::1]:80")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "lfs.ipversion")
+}
+
+func TestClientIPVersion(t *testing.T) {
+	for value, expected := range map[string]string{
+		"":     "auto",
+		"auto": "auto",
+		"4":    "4",
+		"6":    "6",
+		"5":    "auto",
+	} {
+		c, err := NewClient(NewContext(nil, nil, map[string]string{
+			"lfs.ipversion": value,
+		}))
+		require.Nil(t, err)
+		assert.Equal(t, expected, c.ipVersion(), "lfs.ipversion=%q", value)
+	}
+}
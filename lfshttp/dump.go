@@ -0,0 +1,258 @@
+package lfshttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// httpDumpBodyLimit is how much of each request and response body is
+	// kept in an HTTP dump.
+	httpDumpBodyLimit = 4096
+
+	redacted = "<redacted>"
+)
+
+// httpDumpSeq numbers the HTTP dumps written by this process.
+var httpDumpSeq uint64
+
+var (
+	// sensitiveHeaderRE matches the names of headers whose values are
+	// redacted in HTTP dumps.
+	sensitiveHeaderRE = regexp.MustCompile(`(?i)authorization|cookie|token|secret|signature|password|credential|api-?key`)
+
+	// sensitiveJSONRE matches JSON members with sensitive names, such as the
+	// headers in the actions of a Batch API response, whose values are
+	// redacted in HTTP dumps.
+	sensitiveJSONRE = regexp.MustCompile(`(?i)("[^"]*(?:authorization|cookie|token|secret|signature|password|credential|api-?key)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+	// queryRE matches the query strings of URLs in bodies, which often
+	// hold signatures, as in the hrefs of actions for presigned storage
+	// URLs.
+	queryRE = regexp.MustCompile(`(https?://[^\s"'?#]*)\?[^\s"'#]*`)
+)
+
+// dumpTransport writes each request made through it and its response to a
+// file of their own in a directory, given by GIT_LFS_DUMP_HTTP, for debugging
+// problems with proxies and other middleboxes. The dumps are sanitized: the
+// values of headers and JSON members which might hold credentials and of URL
+// query parameters are redacted, bodies which are not text are left out, and
+// other bodies are truncated to httpDumpBodyLimit bytes.
+type dumpTransport struct {
+	http.RoundTripper
+	c *Client
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d := t.c.newHTTPDump()
+	if d == nil {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	var reqBody *dumpBody
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = &dumpBody{ReadCloser: req.Body, traceable: isTraceableContent(req.Header)}
+		r := new(http.Request)
+		*r = *req
+		r.Body = reqBody
+		req = r
+	}
+
+	res, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		d.finish(req, reqBody, nil, nil, err)
+		return res, err
+	}
+
+	resBody := &dumpBody{ReadCloser: res.Body, traceable: isTraceableContent(res.Header)}
+	resBody.done = func() { d.finish(req, reqBody, res, resBody, nil) }
+	res.Body = resBody
+	return res, nil
+}
+
+// httpDump is the file a request and its response are written to.
+type httpDump struct {
+	path string
+	once sync.Once
+}
+
+// newHTTPDump returns the dump which the next request is written to, or nil if
+// the dump directory cannot be made.
+func (c *Client) newHTTPDump() *httpDump {
+	if err := os.MkdirAll(c.httpDumpDir, 0700); err != nil {
+		tracerx.Printf("http: cannot make HTTP dump directory %q: %v", c.httpDumpDir, err)
+		return nil
+	}
+
+	n := atomic.AddUint64(&httpDumpSeq, 1)
+	name := fmt.Sprintf("%s-%d-%04d.http", time.Now().Format("20060102T150405"), os.Getpid(), n)
+	return &httpDump{path: filepath.Join(c.httpDumpDir, name)}
+}
+
+func (d *httpDump) finish(req *http.Request, reqBody *dumpBody, res *http.Response, resBody *dumpBody, err error) {
+	d.once.Do(func() {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "> %s %s %s\n", req.Method, sanitizeURL(req.URL), req.Proto)
+		host := req.Host
+		if len(host) == 0 {
+			host = req.URL.Host
+		}
+		fmt.Fprintf(&buf, "> Host: %s\n", host)
+		writeDumpHeaders(&buf, ">", req.Header)
+		reqBody.writeTo(&buf, ">")
+
+		if err != nil {
+			fmt.Fprintf(&buf, "\n! %s\n", err)
+		} else {
+			fmt.Fprintf(&buf, "\n< %s %s\n", res.Proto, res.Status)
+			writeDumpHeaders(&buf, "<", res.Header)
+			resBody.writeTo(&buf, "<")
+		}
+
+		if werr := ioutil.WriteFile(d.path, buf.Bytes(), 0600); werr != nil {
+			tracerx.Printf("http: cannot write HTTP dump %q: %v", d.path, werr)
+		}
+	})
+}
+
+func writeDumpHeaders(w io.Writer, direction string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s %s: %s\n", direction, k, sanitizeHeader(k, v))
+		}
+	}
+}
+
+// sanitizeHeader returns the value of the given header as it is written to a
+// dump, keeping only the scheme of credentials in Authorization headers.
+func sanitizeHeader(name, value string) string {
+	if !sensitiveHeaderRE.MatchString(name) {
+		return queryRE.ReplaceAllString(value, "$1?"+redacted)
+	}
+	if strings.HasSuffix(strings.ToLower(name), "authorization") {
+		if i := strings.IndexByte(value, ' '); i > 0 {
+			return value[:i+1] + redacted
+		}
+	}
+	return redacted
+}
+
+// sanitizeURL returns the URL without its user information and with the values
+// of its query parameters redacted.
+func sanitizeURL(u *url.URL) string {
+	s := *u
+	s.User = nil
+	if len(s.RawQuery) > 0 {
+		q := s.Query()
+		for k := range q {
+			q[k] = []string{redacted}
+		}
+		s.RawQuery = q.Encode()
+	}
+	return s.String()
+}
+
+// dumpBody keeps the first httpDumpBodyLimit bytes of a body as it is read,
+// and calls done when it has been read or closed.
+type dumpBody struct {
+	io.ReadCloser
+	traceable bool
+	done      func()
+
+	mu   sync.Mutex
+	kept bytes.Buffer
+	size int64
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	b.size += int64(n)
+	if keep := httpDumpBodyLimit - b.kept.Len(); b.traceable && keep > 0 {
+		if keep > n {
+			keep = n
+		}
+		b.kept.Write(p[:keep])
+	}
+	b.mu.Unlock()
+
+	if err == io.EOF && b.done != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *dumpBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done != nil {
+		b.done()
+	}
+	return err
+}
+
+func (b *dumpBody) writeTo(w io.Writer, direction string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.traceable {
+		fmt.Fprintf(w, "%s [%d bytes of body not shown]\n", direction, b.size)
+		return
+	}
+
+	body := sensitiveJSONRE.ReplaceAllString(b.kept.String(), `$1"`+redacted+`"`)
+	body = queryRE.ReplaceAllString(body, "$1?"+redacted)
+	fmt.Fprintf(w, "%s\n%s\n", direction, body)
+	if b.size > int64(b.kept.Len()) {
+		fmt.Fprintf(w, "%s [%d more bytes not shown]\n", direction, b.size-int64(b.kept.Len()))
+	}
+}
+
+// keyLogWriter returns the file which TLS secrets are written to, as given by
+// GIT_LFS_SSLKEYLOGFILE, so that captured TLS traffic can be decrypted, or nil
+// if there is none. It is opened once for each client.
+func (c *Client) keyLogWriter() io.Writer {
+	c.keyLogOnce.Do(func() {
+		path, _ := c.osEnv.Get("GIT_LFS_SSLKEYLOGFILE")
+		if len(path) == 0 {
+			return
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			tracerx.Printf("http: cannot open GIT_LFS_SSLKEYLOGFILE %q: %v", path, err)
+			return
+		}
+		tracerx.Printf("http: writing TLS secrets to %q", path)
+		c.keyLog = f
+	})
+
+	if c.keyLog == nil {
+		return nil
+	}
+	return c.keyLog
+}
@@ -0,0 +1,129 @@
+package lfshttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPDumpIsSanitized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.Header().Set("Set-Cookie", "session=cookie-secret")
+		w.Write([]byte(`{"objects":[{"oid":"abc","actions":{"download":{` +
+			`"href":"https://storage.example.com/abc?X-Amz-Signature=href-secret",` +
+			`"header":{"Authorization":"Bearer header-secret"}}}}]}`))
+		w.Write([]byte(strings.Repeat(" ", httpDumpBodyLimit)))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "http-dump")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := NewClient(NewContext(nil, map[string]string{
+		"GIT_LFS_DUMP_HTTP": dir,
+	}, nil))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("POST", srv.URL+"/objects/batch?token=query-secret",
+		bytes.NewReader([]byte(`{"operation":"download"}`)))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	req.Header.Set("Authorization", "Basic auth-secret")
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.http"))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+
+	data, err := ioutil.ReadFile(files[0])
+	require.Nil(t, err)
+	dump := string(data)
+
+	assert.Contains(t, dump, "> POST "+srv.URL+"/objects/batch?token=%3Credacted%3E")
+	assert.Contains(t, dump, "> Authorization: Basic <redacted>")
+	assert.Contains(t, dump, `{"operation":"download"}`)
+	assert.Contains(t, dump, "< HTTP/1.1 200 OK")
+	assert.Contains(t, dump, "< Set-Cookie: <redacted>")
+	assert.Contains(t, dump, `"href":"https://storage.example.com/abc?<redacted>"`)
+	assert.Contains(t, dump, `"Authorization":"<redacted>"`)
+	assert.Contains(t, dump, "more bytes not shown")
+	for _, secret := range []string{"query-secret", "auth-secret", "cookie-secret", "href-secret", "header-secret"} {
+		assert.NotContains(t, dump, secret)
+	}
+}
+
+func TestHTTPDumpLeavesOutBinaryBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("object content"))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "http-dump")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := NewClient(NewContext(nil, map[string]string{
+		"GIT_LFS_DUMP_HTTP": dir,
+	}, nil))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL+"/objects/abc", nil)
+	require.Nil(t, err)
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.http"))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+
+	data, err := ioutil.ReadFile(files[0])
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "< [14 bytes of body not shown]")
+	assert.NotContains(t, string(data), "object content")
+}
+
+func TestSSLKeyLogFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	f, err := ioutil.TempFile("", "sslkeylog")
+	require.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	c, err := NewClient(NewContext(nil, map[string]string{
+		"GIT_LFS_SSLKEYLOGFILE": f.Name(),
+		"GIT_SSL_NO_VERIFY":     "1",
+	}, nil))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Nil(t, c.Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "CLIENT_")
+}
@@ -0,0 +1,236 @@
+package lfshttp
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	negotiateScheme = "Negotiate"
+	ntlmScheme      = "NTLM"
+
+	// maxNegotiateRounds is the most times a request is sent while
+	// authenticating to the server and proxy at once. Kerberos needs one
+	// round with each, and NTLM two, after the first which asks for them.
+	maxNegotiateRounds = 6
+)
+
+// negotiator produces the tokens a client sends in an HTTP Negotiate or NTLM
+// authentication exchange with a server or proxy. The exchange may take
+// several rounds, each answering a challenge sent in the previous response,
+// all of which must be sent over the same connection.
+type negotiator interface {
+	// Step returns the token to send in reply to the given challenge,
+	// which is nil at the start of the exchange, or nil if no more tokens
+	// are needed.
+	Step(challenge []byte) ([]byte, error)
+
+	// Release frees the resources held for the exchange.
+	Release()
+}
+
+// negotiatorFunc starts an exchange with the given scheme with the server or
+// proxy with the given host name.
+type negotiatorFunc func(scheme, host string) (negotiator, error)
+
+// negotiation is an exchange in progress with a server or proxy.
+type negotiation struct {
+	negotiator
+
+	scheme string
+	host   string
+}
+
+// startNegotiation starts an exchange with the given scheme with the server or
+// proxy with the given host name.
+func startNegotiation(newNegotiator negotiatorFunc, scheme, host string) (*negotiation, error) {
+	tracerx.Printf("http: authenticating to %s with %s", host, scheme)
+	n, err := newNegotiator(scheme, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s authentication to %s", scheme, host)
+	}
+	return &negotiation{negotiator: n, scheme: scheme, host: host}, nil
+}
+
+// reply sets the given authorization header to the token which answers the
+// given challenge, returning false if there is none to send.
+func (n *negotiation) reply(challenge []byte, h http.Header, key string) (bool, error) {
+	token, err := n.Step(challenge)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s authentication to %s", n.scheme, n.host)
+	}
+	if len(token) == 0 {
+		return false, nil
+	}
+	h.Set(key, n.scheme+" "+base64.StdEncoding.EncodeToString(token))
+	return true, nil
+}
+
+// answer sets the given authorization header to the token which answers the
+// challenge in the given authentication header of a response, returning false
+// if there is none to send, such as when the server or proxy rejected the
+// last token.
+func (n *negotiation) answer(res http.Header, resKey string, req http.Header, reqKey string) (bool, error) {
+	challenge, ok := authChallenge(res, resKey, n.scheme)
+	if !ok || len(challenge) == 0 {
+		return false, nil
+	}
+	return n.reply(challenge, req, reqKey)
+}
+
+func (n *negotiation) release() {
+	if n != nil {
+		n.Release()
+	}
+}
+
+// offeredScheme returns the Negotiate or NTLM scheme offered in the given
+// authentication header, preferring the given one if both are, or the empty
+// string if neither is.
+func offeredScheme(h http.Header, key, preferred string) string {
+	other := negotiateScheme
+	if strings.EqualFold(preferred, negotiateScheme) {
+		preferred, other = negotiateScheme, ntlmScheme
+	} else {
+		preferred = ntlmScheme
+	}
+
+	if _, ok := authChallenge(h, key, preferred); ok {
+		return preferred
+	}
+	if _, ok := authChallenge(h, key, other); ok {
+		return other
+	}
+	return ""
+}
+
+// authChallenge returns the decoded token given with the given scheme in the
+// given authentication header, and whether the scheme was offered at all.
+func authChallenge(h http.Header, key, scheme string) ([]byte, bool) {
+	for _, value := range h[http.CanonicalHeaderKey(key)] {
+		for _, offer := range strings.Split(value, ",") {
+			fields := strings.Fields(offer)
+			if len(fields) == 0 || !strings.EqualFold(fields[0], scheme) {
+				continue
+			}
+			if len(fields) < 2 {
+				return nil, true
+			}
+
+			token, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				tracerx.Printf("http: ignoring malformed %s challenge: %s", scheme, err)
+				return nil, true
+			}
+			return token, true
+		}
+	}
+	return nil, false
+}
+
+// negotiateTransport is an http.RoundTripper which authenticates its requests
+// with the Negotiate or NTLM scheme to the server, the proxy, or both at once,
+// sending each request again for as many rounds as the exchanges take.
+type negotiateTransport struct {
+	*http.Transport
+
+	// origin is set if requests are authenticated to the server, which is
+	// done from the first request, as with lfs.<url>.access "negotiate".
+	origin bool
+
+	// proxyScheme is the scheme preferred to authenticate requests to the
+	// proxy they are sent to, which is done if it asks with a 407
+	// response, or the empty string if they are not.
+	proxyScheme string
+
+	newNegotiator negotiatorFunc
+}
+
+func (t *negotiateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := new(http.Request)
+	*out = *req
+	out.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		out.Header[k] = v
+	}
+
+	rewind := rewindableBody(out)
+	if out.Body != req.Body {
+		defer req.Body.Close()
+	}
+
+	var origin, proxy *negotiation
+	defer func() {
+		origin.release()
+		proxy.release()
+	}()
+
+	if t.origin {
+		var err error
+		if origin, err = startNegotiation(t.newNegotiator, negotiateScheme, req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		if _, err := origin.reply(nil, out.Header, "Authorization"); err != nil {
+			return nil, err
+		}
+	}
+
+	for round := 1; ; round++ {
+		res, err := t.Transport.RoundTrip(out)
+		if err != nil {
+			return nil, err
+		}
+
+		next := false
+		switch {
+		case res.StatusCode == 407 && len(t.proxyScheme) > 0:
+			var proxyURL *url.URL
+			if proxyURL, err = t.Proxy(out); err == nil && proxyURL != nil {
+				next, err = answerProxyChallenge(&proxy, t.newNegotiator, res, out.Header, proxyURL.Hostname(), t.proxyScheme)
+			}
+		case res.StatusCode == 401 && origin != nil:
+			next, err = origin.answer(res.Header, "Www-Authenticate", out.Header, "Authorization")
+		}
+
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		if !next || round >= maxNegotiateRounds || !rewind() {
+			return res, nil
+		}
+
+		// Read the rest of the response, so that the connection is
+		// used again for the next round.
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+}
+
+// rewindableBody prepares the body of the given request to be sent more than
+// once, returning a function which rewinds it, and reports whether it could.
+// The transport does not close such a body, so the caller must close the
+// original when it is done.
+func rewindableBody(req *http.Request) func() bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() bool { return true }
+	}
+
+	seeker, ok := req.Body.(io.Seeker)
+	if !ok {
+		return func() bool { return false }
+	}
+
+	req.Body = ioutil.NopCloser(req.Body)
+	return func() bool {
+		_, err := seeker.Seek(0, io.SeekStart)
+		return err == nil
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package lfshttp
+
+import (
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	spnego "github.com/dpotapov/go-spnego"
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+// krb5Negotiator authenticates with the Kerberos ticket in the current user's
+// credential cache, which takes a single round.
+type krb5Negotiator struct {
+	host string
+	done bool
+}
+
+func newNegotiator(scheme, host string) (negotiator, error) {
+	if !strings.EqualFold(scheme, negotiateScheme) {
+		return nil, errors.Errorf("%s authentication is only supported on Windows", scheme)
+	}
+	return &krb5Negotiator{host: host}, nil
+}
+
+func (n *krb5Negotiator) Step(challenge []byte) ([]byte, error) {
+	if n.done {
+		return nil, nil
+	}
+	n.done = true
+
+	req := &http.Request{
+		URL:    &url.URL{Scheme: "http", Host: net.JoinHostPort(n.host, "80")},
+		Header: make(http.Header),
+	}
+	if err := spnego.New().SetSPNEGOHeader(req); err != nil {
+		return nil, err
+	}
+
+	auth := strings.TrimPrefix(req.Header.Get("Authorization"), negotiateScheme+" ")
+	return base64.StdEncoding.DecodeString(auth)
+}
+
+func (n *krb5Negotiator) Release() {}
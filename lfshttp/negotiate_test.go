@@ -0,0 +1,283 @@
+package lfshttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNegotiator sends the tokens "token-1" to "token-N", each after the
+// first in reply to the challenge "challenge-N" for the one before.
+type fakeNegotiator struct {
+	scheme   string
+	host     string
+	rounds   int
+	sent     int
+	released bool
+}
+
+func (n *fakeNegotiator) Step(challenge []byte) ([]byte, error) {
+	if n.sent > 0 && string(challenge) != fmt.Sprintf("challenge-%d", n.sent) {
+		return nil, fmt.Errorf("unexpected challenge %q", challenge)
+	}
+	if n.sent >= n.rounds {
+		return nil, nil
+	}
+	n.sent++
+	return []byte(fmt.Sprintf("token-%d", n.sent)), nil
+}
+
+func (n *fakeNegotiator) Release() {
+	n.released = true
+}
+
+func fakeNegotiators(rounds int, started *[]*fakeNegotiator) negotiatorFunc {
+	return func(scheme, host string) (negotiator, error) {
+		n := &fakeNegotiator{scheme: scheme, host: host, rounds: rounds}
+		*started = append(*started, n)
+		return n, nil
+	}
+}
+
+func authHeader(scheme, token string) string {
+	return scheme + " " + base64.StdEncoding.EncodeToString([]byte(token))
+}
+
+func TestAuthChallenge(t *testing.T) {
+	h := make(http.Header)
+	h.Add("Proxy-Authenticate", `Basic realm="proxy"`)
+	h.Add("Proxy-Authenticate", "NTLM, "+authHeader("Negotiate", "challenge"))
+
+	token, ok := authChallenge(h, "Proxy-Authenticate", "negotiate")
+	assert.True(t, ok)
+	assert.Equal(t, "challenge", string(token))
+
+	token, ok = authChallenge(h, "Proxy-Authenticate", "NTLM")
+	assert.True(t, ok)
+	assert.Nil(t, token)
+
+	_, ok = authChallenge(h, "Www-Authenticate", "Negotiate")
+	assert.False(t, ok)
+
+	assert.Equal(t, "NTLM", offeredScheme(h, "Proxy-Authenticate", "NTLM"))
+	assert.Equal(t, "Negotiate", offeredScheme(h, "Proxy-Authenticate", "Negotiate"))
+	assert.Equal(t, "", offeredScheme(make(http.Header), "Proxy-Authenticate", "Negotiate"))
+}
+
+func TestProxyAuthMethod(t *testing.T) {
+	u, _ := url.Parse("https://git-server.com/repo")
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"http.proxyauthmethod": "ntlm",
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, "NTLM", proxyAuthMethod(u, c.uc, c.osEnv))
+
+	c, err = NewClient(NewContext(nil, map[string]string{
+		"GIT_HTTP_PROXY_AUTHMETHOD": "Negotiate",
+	}, map[string]string{
+		"http.proxyauthmethod": "ntlm",
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, "Negotiate", proxyAuthMethod(u, c.uc, c.osEnv))
+
+	c, err = NewClient(NewContext(nil, nil, map[string]string{
+		"http.proxyauthmethod": "anyauth",
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, "", proxyAuthMethod(u, c.uc, c.osEnv))
+}
+
+func TestNegotiateTransportAuthenticatesToServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case authHeader("Negotiate", "token-1"):
+			w.Header().Set("Www-Authenticate", authHeader("Negotiate", "challenge-1"))
+			w.WriteHeader(401)
+		case authHeader("Negotiate", "token-2"):
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "body", string(body))
+			w.WriteHeader(200)
+		default:
+			w.Header().Set("Www-Authenticate", "Negotiate")
+			w.WriteHeader(401)
+		}
+	}))
+	defer srv.Close()
+
+	var started []*fakeNegotiator
+	tr := &negotiateTransport{
+		Transport:     &http.Transport{},
+		origin:        true,
+		newNegotiator: fakeNegotiators(2, &started),
+	}
+
+	req, err := http.NewRequest("POST", srv.URL, nil)
+	require.Nil(t, err)
+	req.Body = NewByteBody([]byte("body"))
+	req.ContentLength = 4
+
+	res, err := tr.RoundTrip(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+
+	require.Len(t, started, 1)
+	assert.Equal(t, "Negotiate", started[0].scheme)
+	assert.Equal(t, "127.0.0.1", started[0].host)
+	assert.True(t, started[0].released)
+}
+
+func TestNegotiateTransportAuthenticatesToProxyAndServer(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "git-server.com", r.URL.Host)
+
+		switch r.Header.Get("Proxy-Authorization") {
+		case authHeader("NTLM", "token-1"):
+			w.Header().Set("Proxy-Authenticate", authHeader("NTLM", "challenge-1"))
+			w.WriteHeader(407)
+			return
+		case authHeader("NTLM", "token-2"):
+		default:
+			w.Header().Add("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.Header().Add("Proxy-Authenticate", "Negotiate, NTLM")
+			w.WriteHeader(407)
+			return
+		}
+
+		// Behind the proxy, the server authenticates the request too.
+		switch r.Header.Get("Authorization") {
+		case authHeader("Negotiate", "token-1"):
+			w.Header().Set("Www-Authenticate", authHeader("Negotiate", "challenge-1"))
+			w.WriteHeader(401)
+		case authHeader("Negotiate", "token-2"):
+			w.WriteHeader(200)
+		default:
+			w.WriteHeader(401)
+		}
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.Nil(t, err)
+
+	var started []*fakeNegotiator
+	tr := &negotiateTransport{
+		Transport:     &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+		origin:        true,
+		proxyScheme:   "NTLM",
+		newNegotiator: fakeNegotiators(2, &started),
+	}
+
+	req, err := http.NewRequest("GET", "http://git-server.com/repo.git/info/lfs", nil)
+	require.Nil(t, err)
+
+	res, err := tr.RoundTrip(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+
+	require.Len(t, started, 2)
+	assert.Equal(t, "Negotiate", started[0].scheme)
+	assert.Equal(t, "git-server.com", started[0].host)
+	assert.Equal(t, "NTLM", started[1].scheme)
+	assert.Equal(t, "127.0.0.1", started[1].host)
+}
+
+// serveProxyTunnel accepts a single connection, and answers the CONNECT
+// requests sent over it with the given function until it returns 200, after
+// which it writes "hello" through the tunnel, or one closes the connection.
+func serveProxyTunnel(t *testing.T, l net.Listener, handle func(r *http.Request) *http.Response) {
+	conn, err := l.Accept()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	for {
+		r, err := http.ReadRequest(br)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "CONNECT", r.Method)
+		assert.Equal(t, "git-server.com:443", r.Host)
+
+		res := handle(r)
+		res.ProtoMajor, res.ProtoMinor = 1, 1
+		res.Request = r
+		res.Write(conn)
+		if res.StatusCode == 200 {
+			conn.Write([]byte("hello"))
+			return
+		}
+		if res.Close {
+			return
+		}
+	}
+}
+
+func TestDialProxyTunnelAuthenticates(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+
+	go serveProxyTunnel(t, l, func(r *http.Request) *http.Response {
+		res := &http.Response{StatusCode: 407, Header: make(http.Header)}
+		switch r.Header.Get("Proxy-Authorization") {
+		case authHeader("Negotiate", "token-1"):
+			res.Header.Set("Proxy-Authenticate", authHeader("Negotiate", "challenge-1"))
+		case authHeader("Negotiate", "token-2"):
+			res.StatusCode = 200
+		default:
+			res.Header.Set("Proxy-Authenticate", "Negotiate")
+		}
+		return res
+	})
+
+	var started []*fakeNegotiator
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	dialer := &net.Dialer{}
+	conn, err := dialProxyTunnel(context.Background(), dialer.DialContext, proxyURL, "git-server.com:443", "Negotiate", fakeNegotiators(2, &started))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	data, err := ioutil.ReadAll(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	require.Len(t, started, 1)
+	assert.Equal(t, "127.0.0.1", started[0].host)
+	assert.True(t, started[0].released)
+}
+
+func TestDialProxyTunnelExplainsFailure(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+
+	go serveProxyTunnel(t, l, func(r *http.Request) *http.Response {
+		res := &http.Response{StatusCode: 407, Header: make(http.Header), Close: true}
+		res.Header.Set("Proxy-Authenticate", `Basic realm="proxy"`)
+		return res
+	})
+
+	var started []*fakeNegotiator
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	dialer := &net.Dialer{}
+	_, err = dialProxyTunnel(context.Background(), dialer.DialContext, proxyURL, "git-server.com:443", "NTLM", fakeNegotiators(2, &started))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), `proxy authentication failed (proxy offered Basic realm="proxy")`)
+	assert.Len(t, started, 0)
+}
@@ -0,0 +1,95 @@
+package lfshttp
+
+import (
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/alexbrainman/sspi"
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+// sspiNegotiator authenticates as the current user with the Windows SSPI
+// package of the same name as the scheme, so that single sign-on works the
+// same way with servers and proxies alike, whether they use Kerberos or NTLM.
+type sspiNegotiator struct {
+	cred    *sspi.Credentials
+	ctx     *sspi.Context
+	target  *uint16
+	maxSize uint32
+	done    bool
+}
+
+func newNegotiator(scheme, host string) (negotiator, error) {
+	pkg := sspi.NEGOSSP_NAME
+	if strings.EqualFold(scheme, ntlmScheme) {
+		pkg = sspi.NTLMSP_NAME
+	}
+
+	info, err := sspi.QueryPackageInfo(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := syscall.UTF16PtrFromString("HTTP/" + canonicalHostname(host))
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := sspi.AcquireCredentials("", pkg, sspi.SECPKG_CRED_OUTBOUND, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sspiNegotiator{
+		cred:    cred,
+		ctx:     sspi.NewClientContext(cred, sspi.ISC_REQ_CONNECTION),
+		target:  target,
+		maxSize: info.MaxToken,
+	}, nil
+}
+
+func (n *sspiNegotiator) Step(challenge []byte) ([]byte, error) {
+	if n.done {
+		return nil, nil
+	}
+
+	token := make([]byte, n.maxSize)
+	var in, out [1]sspi.SecBuffer
+	in[0].Set(sspi.SECBUFFER_TOKEN, challenge)
+	out[0].Set(sspi.SECBUFFER_TOKEN, token)
+	inDesc := sspi.NewSecBufferDesc(in[:])
+	outDesc := sspi.NewSecBufferDesc(out[:])
+
+	switch ret := n.ctx.Update(n.target, outDesc, inDesc); ret {
+	case sspi.SEC_E_OK:
+		n.done = true
+	case sspi.SEC_I_COMPLETE_NEEDED, sspi.SEC_I_COMPLETE_AND_CONTINUE:
+		if ret := sspi.CompleteAuthToken(n.ctx.Handle, outDesc); ret != sspi.SEC_E_OK {
+			return nil, errors.Wrap(ret, "sspi")
+		}
+	case sspi.SEC_I_CONTINUE_NEEDED:
+	default:
+		return nil, errors.Wrap(ret, "sspi")
+	}
+	return token[:out[0].BufferSize], nil
+}
+
+func (n *sspiNegotiator) Release() {
+	n.ctx.Release()
+	n.cred.Release()
+}
+
+// canonicalHostname returns the canonical name of the given host, which its
+// service principal name is based on, or the host itself if it has none.
+func canonicalHostname(host string) string {
+	addrs, err := net.LookupHost(host)
+	if err != nil || len(addrs) == 0 {
+		return host
+	}
+	names, err := net.LookupAddr(addrs[0])
+	if err != nil || len(names) == 0 {
+		return host
+	}
+	return strings.TrimRight(names[0], ".")
+}
@@ -0,0 +1,25 @@
+package lfshttp
+
+import (
+	"sync/atomic"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+// offlineRefusals counts the connections refused by this process because Git
+// LFS is offline.
+var offlineRefusals int32
+
+// NewOfflineError returns the error for an attempt to connect to the given host
+// while Git LFS is offline, and counts it so that RefusedOffline reports it.
+func NewOfflineError(host string) error {
+	atomic.AddInt32(&offlineRefusals, 1)
+	return errors.NewOfflineError(errors.Errorf("Git LFS is offline, so will not connect to %s (see lfs.offline and GIT_LFS_OFFLINE)", host))
+}
+
+// RefusedOffline returns whether any connection has been refused because Git
+// LFS is offline, so that commands which fail can say why with their exit
+// status.
+func RefusedOffline() bool {
+	return atomic.LoadInt32(&offlineRefusals) > 0
+}
@@ -1,11 +1,17 @@
 package lfshttp
 
 import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/errors"
 	"golang.org/x/net/http/httpproxy"
 )
 
@@ -89,3 +95,176 @@ func getProxyServers(u *url.URL, urlCfg *config.URLConfig, osEnv config.Environm
 
 	return
 }
+
+// proxyAuthMethod returns the scheme with which to authenticate to proxies
+// for the given URL, from GIT_HTTP_PROXY_AUTHMETHOD or http.proxyAuthMethod,
+// if it is one Git LFS authenticates with itself: "Negotiate" or "NTLM".
+// Otherwise, it returns the empty string, and proxies are left to Go.
+func proxyAuthMethod(u *url.URL, urlCfg *config.URLConfig, osEnv config.Environment) string {
+	method, _ := osEnv.Get("GIT_HTTP_PROXY_AUTHMETHOD")
+	if len(method) == 0 && urlCfg != nil {
+		method, _ = urlCfg.Get("http", u.String(), "proxyauthmethod")
+	}
+
+	switch strings.ToLower(method) {
+	case "negotiate":
+		return negotiateScheme
+	case "ntlm":
+		return ntlmScheme
+	}
+	return ""
+}
+
+// negotiatingProxyFromClient works as proxyFromClient, except that HTTPS
+// requests are not sent through HTTP proxies, as they are tunnelled through
+// them by dialProxyTunnel instead, which authenticates to them as Go cannot.
+func negotiatingProxyFromClient(c *Client) func(req *http.Request) (*url.URL, error) {
+	proxy := proxyFromClient(c)
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req)
+		if err == nil && u != nil && u.Scheme == "http" && req.URL.Scheme == "https" {
+			return nil, nil
+		}
+		return u, err
+	}
+}
+
+// negotiatingProxyDialer returns a dial function which connects to the given
+// address through a tunnel through the HTTP proxy for HTTPS requests to it,
+// if there is one, authenticating to it with the given scheme, or uses the
+// given dial function otherwise, such as to connect to the proxy itself for
+// HTTP requests.
+func negotiatingProxyDialer(c *Client, scheme string, dial dialFunc) dialFunc {
+	proxy := proxyFromClient(c)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		proxyURL, err := proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL == nil || proxyURL.Scheme != "http" || proxyAddr(proxyURL) == addr {
+			return dial(ctx, network, addr)
+		}
+		return dialProxyTunnel(ctx, dial, proxyURL, addr, scheme, newNegotiator)
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func proxyAddr(u *url.URL) string {
+	if len(u.Port()) > 0 {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// dialProxyTunnel connects to the given address through a tunnel through the
+// given HTTP proxy, authenticating to it with the given scheme if it asks,
+// over the same connection for all the rounds the exchange takes, as NTLM
+// requires. The proxy may also offer the other of Negotiate or NTLM instead.
+func dialProxyTunnel(ctx context.Context, dial dialFunc, proxyURL *url.URL, addr, scheme string, newNegotiator negotiatorFunc) (net.Conn, error) {
+	var neg *negotiation
+	defer func() { neg.release() }()
+
+	var conn net.Conn
+	var br *bufio.Reader
+	connect := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	connect.Header.Set("User-Agent", UserAgent)
+
+	for round := 1; ; round++ {
+		if conn == nil {
+			var err error
+			if conn, err = dial(ctx, "tcp", proxyAddr(proxyURL)); err != nil {
+				return nil, err
+			}
+			br = bufio.NewReader(conn)
+		}
+
+		if err := connect.Write(conn); err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "proxyconnect %s", proxyURL.Host)
+		}
+		res, err := http.ReadResponse(br, connect)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "proxyconnect %s", proxyURL.Host)
+		}
+
+		if res.StatusCode == 200 {
+			if br.Buffered() > 0 {
+				return &bufferedConn{Conn: conn, r: br}, nil
+			}
+			return conn, nil
+		}
+
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+		res.Body.Close()
+		if res.Close {
+			conn.Close()
+			conn = nil
+		}
+
+		next := false
+		if res.StatusCode == 407 && round < maxNegotiateRounds {
+			next, err = answerProxyChallenge(&neg, newNegotiator, res, connect.Header, proxyURL.Hostname(), scheme)
+		}
+		if err != nil || !next {
+			if conn != nil {
+				conn.Close()
+			}
+			if err == nil {
+				err = proxyConnectError(proxyURL, addr, res)
+			}
+			return nil, err
+		}
+	}
+}
+
+// answerProxyChallenge sets the Proxy-Authorization header to the token which
+// answers the challenge in the given response from the proxy, starting the
+// exchange if need be. It returns false if there is none to send.
+func answerProxyChallenge(neg **negotiation, newNegotiator negotiatorFunc, res *http.Response, h http.Header, host, scheme string) (bool, error) {
+	if *neg != nil {
+		return (*neg).answer(res.Header, "Proxy-Authenticate", h, "Proxy-Authorization")
+	}
+
+	offered := offeredScheme(res.Header, "Proxy-Authenticate", scheme)
+	if len(offered) == 0 {
+		return false, nil
+	}
+
+	var err error
+	if *neg, err = startNegotiation(newNegotiator, offered, host); err != nil {
+		return false, err
+	}
+	return (*neg).reply(nil, h, "Proxy-Authorization")
+}
+
+// proxyConnectError explains why the given proxy refused to make a tunnel to
+// the given address.
+func proxyConnectError(proxyURL *url.URL, addr string, res *http.Response) error {
+	if res.StatusCode != 407 {
+		return errors.Errorf("proxyconnect %s: proxy refused connection to %s: %s", proxyURL.Host, addr, res.Status)
+	}
+
+	offered := res.Header["Proxy-Authenticate"]
+	if len(offered) == 0 {
+		return errors.Errorf("proxyconnect %s: proxy authentication failed", proxyURL.Host)
+	}
+	return errors.Errorf("proxyconnect %s: proxy authentication failed (proxy offered %s)", proxyURL.Host, strings.Join(offered, ", "))
+}
+
+// bufferedConn is a connection with data already read into a buffer, which
+// is read before the rest.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,66 @@
+package lfshttp
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rubyist/tracerx"
+)
+
+// requestLimiter spaces out requests so that no more than a given number are
+// sent per second.
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRequestLimiter(perSecond float64) *requestLimiter {
+	return &requestLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Reserve returns how long to wait before sending the next request.
+func (l *requestLimiter) Reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return wait
+}
+
+// waitForRateLimit waits until a request to the given URL may be sent, if
+// lfs.<url>.maxrequestspersecond limits how many requests are sent to its
+// host. Requests to each host share one limit.
+func (c *Client) waitForRateLimit(u *url.URL) {
+	v, ok := c.uc.Get("lfs", u.String(), "maxrequestspersecond")
+	if !ok {
+		return
+	}
+	perSecond, err := strconv.ParseFloat(v, 64)
+	if err != nil || perSecond <= 0 {
+		return
+	}
+
+	key := u.Scheme + "://" + u.Host
+	c.limiterMu.Lock()
+	if c.limiters == nil {
+		c.limiters = make(map[string]*requestLimiter)
+	}
+	l, ok := c.limiters[key]
+	if !ok {
+		l = newRequestLimiter(perSecond)
+		c.limiters[key] = l
+	}
+	c.limiterMu.Unlock()
+
+	if wait := l.Reserve(time.Now()); wait > 0 {
+		tracerx.Printf("http: waiting %v to send request to %s (lfs.maxrequestspersecond)", wait, key)
+		time.Sleep(wait)
+	}
+}
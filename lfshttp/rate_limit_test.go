@@ -0,0 +1,48 @@
+package lfshttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimiterSpacesRequests(t *testing.T) {
+	l := newRequestLimiter(4)
+	now := time.Unix(0, 0)
+
+	assert.Equal(t, time.Duration(0), l.Reserve(now))
+	assert.Equal(t, 250*time.Millisecond, l.Reserve(now))
+	assert.Equal(t, 500*time.Millisecond, l.Reserve(now))
+
+	// Time spent idle does not build up a burst.
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, time.Duration(0), l.Reserve(now))
+	assert.Equal(t, 250*time.Millisecond, l.Reserve(now))
+}
+
+func TestClientMaxRequestsPerSecond(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(NewContext(nil, nil, map[string]string{
+		"lfs." + srv.URL + ".maxrequestspersecond": "20",
+	}))
+	require.Nil(t, err)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", srv.URL+"/objects", nil)
+		require.Nil(t, err)
+		res, err := c.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+	}
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	assert.Len(t, c.limiters, 1)
+}
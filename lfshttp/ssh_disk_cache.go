@@ -0,0 +1,274 @@
+package lfshttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/rubyist/tracerx"
+)
+
+// sshDiskCacheEntry is a git-lfs-authenticate response stored in the on-disk
+// cache, with the absolute time at which it expires.
+type sshDiskCacheEntry struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// sshShareMaxAge is how long git-lfs-authenticate responses are shared
+// between the Git LFS processes run for a single Git operation, such as the
+// pre-push hook and the filter processes of a "git push".
+const sshShareMaxAge = time.Minute
+
+// sshDiskCache stores git-lfs-authenticate responses in a file readable only
+// by the current user, so that they can be reused by later commands until they
+// expire, instead of each command connecting over SSH again. Nothing else is
+// stored: the access mode of each endpoint is kept in lfs.<url>.access, and
+// the headers of batch actions are only good for their own transfer.
+//
+// If maxAge is non-zero, responses are reused for at most that long, and are
+// stored even if they do not expire; otherwise responses which do not expire
+// are never stored.
+type sshDiskCache struct {
+	path   string
+	maxAge time.Duration
+	ssh    SSHResolver
+	mu     sync.Mutex
+
+	// session identifies the Git operation whose responses are shared,
+	// if maxAge is non-zero.
+	session string
+	// created is whether this process created the cache file, and so
+	// should remove it once done.
+	created bool
+}
+
+func withSSHDiskCache(ssh SSHResolver, path string, maxAge time.Duration) SSHResolver {
+	return newSSHDiskCache(ssh, path, maxAge)
+}
+
+func newSSHDiskCache(ssh SSHResolver, path string, maxAge time.Duration) *sshDiskCache {
+	return &sshDiskCache{path: path, maxAge: maxAge, ssh: ssh}
+}
+
+// sshDiskCachePath returns the path of the on-disk cache of git-lfs-authenticate
+// responses, if enabled with "lfs.ssh.authcache", or the empty string.
+func sshDiskCachePath(osEnv, gitEnv config.Environment) string {
+	if !gitEnv.Bool("lfs.ssh.authcache", false) {
+		return ""
+	}
+
+	if dir, ok := osEnv.Get("XDG_CACHE_HOME"); ok && len(dir) > 0 {
+		return filepath.Join(dir, "git-lfs", "ssh-auth.json")
+	}
+	if home, ok := osEnv.Get("HOME"); ok && len(home) > 0 {
+		return filepath.Join(home, ".cache", "git-lfs", "ssh-auth.json")
+	}
+	return ""
+}
+
+// sshShareSessionEnv names the environment variable which identifies the Git
+// operation whose Git LFS processes share git-lfs-authenticate responses. It
+// is passed by the first such process to those it runs; see
+// Client.SSHAuthSessionEnv.
+const sshShareSessionEnv = "GIT_LFS_SSH_AUTH_SESSION"
+
+// sshShareSession returns the identifier of the Git operation for which
+// git-lfs-authenticate responses are shared: the one inherited through
+// sshShareSessionEnv, if any, or otherwise the PID of the invoking Git process.
+func sshShareSession(osEnv config.Environment) string {
+	if session, ok := osEnv.Get(sshShareSessionEnv); ok && len(session) > 0 {
+		return session
+	}
+	return strconv.Itoa(os.Getppid())
+}
+
+// sshSharedCachePath returns the path of the file through which
+// git-lfs-authenticate responses are shared for sshShareMaxAge within the
+// given Git operation, if sharing is enabled with "lfs.ssh.authshare" and
+// there is a runtime directory private to the current user, or the empty
+// string.
+func sshSharedCachePath(osEnv, gitEnv config.Environment, session string) string {
+	if !gitEnv.Bool("lfs.ssh.authshare", false) {
+		return ""
+	}
+
+	if dir, ok := osEnv.Get("XDG_RUNTIME_DIR"); ok && len(dir) > 0 {
+		return filepath.Join(dir, "git-lfs", fmt.Sprintf("ssh-auth-shared-%s.json", session))
+	}
+	return ""
+}
+
+func (c *sshDiskCache) Resolve(e Endpoint, method string) (sshAuthResponse, error) {
+	if len(e.SSHMetadata.UserAndHost) == 0 {
+		return sshAuthResponse{}, nil
+	}
+
+	key := strings.Join([]string{e.SSHMetadata.UserAndHost, e.SSHMetadata.Port, e.SSHMetadata.Path, method}, "//")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.load()
+	if entry, ok := entries[key]; ok {
+		if entry.ExpiresAt.After(time.Now().Add(5 * time.Second)) {
+			tracerx.Printf("ssh disk cache: %s git-lfs-authenticate %s %s",
+				e.SSHMetadata.UserAndHost, e.SSHMetadata.Path, endpointOperation(e, method))
+			return sshAuthResponse{
+				Href:      entry.Href,
+				Header:    entry.Header,
+				ExpiresAt: entry.ExpiresAt,
+				createdAt: time.Now(),
+			}, nil
+		}
+		tracerx.Printf("ssh disk cache expired: %s git-lfs-authenticate %s %s",
+			e.SSHMetadata.UserAndHost, e.SSHMetadata.Path, endpointOperation(e, method))
+	}
+
+	res, err := c.ssh.Resolve(e, method)
+	if err != nil {
+		return res, err
+	}
+
+	now := time.Now()
+	expiresAt, _ := res.IsExpiredWithin(0)
+	if c.maxAge > 0 && (expiresAt.IsZero() || expiresAt.After(now.Add(c.maxAge))) {
+		expiresAt = now.Add(c.maxAge)
+	}
+	if expiresAt.IsZero() || !expiresAt.After(now) {
+		return res, nil
+	}
+
+	for k, entry := range entries {
+		if !entry.ExpiresAt.After(now) {
+			delete(entries, k)
+		}
+	}
+	entries[key] = &sshDiskCacheEntry{
+		Href:      res.Href,
+		Header:    res.Header,
+		ExpiresAt: expiresAt,
+	}
+	c.save(entries)
+	return res, nil
+}
+
+// load returns the entries in the cache file, or none if it does not exist or
+// cannot be read. A cache file which other users may read is ignored, since it
+// may have been tampered with.
+func (c *sshDiskCache) load() map[string]*sshDiskCacheEntry {
+	entries := make(map[string]*sshDiskCacheEntry)
+
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return entries
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		tracerx.Printf("ssh disk cache: ignoring %q, which is accessible by other users", c.path)
+		return entries
+	}
+
+	data, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		tracerx.Printf("ssh disk cache: unable to read %q: %v", c.path, err)
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		tracerx.Printf("ssh disk cache: ignoring invalid %q: %v", c.path, err)
+		return make(map[string]*sshDiskCacheEntry)
+	}
+	return entries
+}
+
+// save replaces the cache file with one holding the given entries, readable
+// only by the current user.
+func (c *sshDiskCache) save(entries map[string]*sshDiskCacheEntry) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		tracerx.Printf("ssh disk cache: unable to encode entries: %v", err)
+		return
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		tracerx.Printf("ssh disk cache: unable to create %q: %v", dir, err)
+		return
+	}
+
+	f, err := ioutil.TempFile(dir, "ssh-auth")
+	if err != nil {
+		tracerx.Printf("ssh disk cache: unable to write %q: %v", c.path, err)
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0600)
+	}
+	exists := false
+	if _, serr := os.Stat(c.path); serr == nil {
+		exists = true
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		tracerx.Printf("ssh disk cache: unable to write %q: %v", c.path, err)
+	} else if !exists {
+		c.created = true
+	}
+
+	if c.maxAge > 0 {
+		c.removeStale(dir)
+	}
+}
+
+// cleanup removes the cache file of responses shared within a Git operation if
+// this process created it, since the process which started sharing them is
+// done with them, along with any such files left behind by other Git
+// operations. Files kept for longer, without a maxAge, are left alone.
+func (c *sshDiskCache) cleanup() {
+	if c.maxAge == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.created {
+		os.Remove(c.path)
+		c.created = false
+	}
+	c.removeStale(filepath.Dir(c.path))
+}
+
+// removeStale removes the files left in dir by the Git operations which shared
+// git-lfs-authenticate responses, once every response in them has expired.
+func (c *sshDiskCache) removeStale(dir string) {
+	paths, err := filepath.Glob(filepath.Join(dir, "ssh-auth-shared-*.json"))
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-c.maxAge)
+	for _, path := range paths {
+		if path == c.path {
+			continue
+		}
+		if fi, err := os.Stat(path); err == nil && fi.ModTime().Before(cutoff) {
+			os.Remove(path)
+		}
+	}
+}
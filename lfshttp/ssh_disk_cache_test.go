@@ -0,0 +1,261 @@
+package lfshttp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	sshp "github.com/git-lfs/git-lfs/v2/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sshDiskCacheEndpoint = Endpoint{
+	SSHMetadata: sshp.SSHMetadata{
+		UserAndHost: "userandhost",
+		Port:        "1",
+		Path:        "path",
+	},
+}
+
+func newTestSSHDiskCachePath(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "ssh-disk-cache")
+	require.Nil(t, err)
+	return dir, filepath.Join(dir, "git-lfs", "ssh-auth.json")
+}
+
+func TestSSHDiskCacheResolveAcrossInstances(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{
+		Href:      "real",
+		Header:    map[string]string{"Authorization": "token"},
+		ExpiresIn: 3600,
+	}
+
+	res, err := withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		require.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	// A later command reads the response from disk.
+	delete(ssh.responses, "userandhost")
+	res, err = withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+	assert.Equal(t, "token", res.Header["Authorization"])
+
+	// Other operations are not answered from the cache.
+	res, err = withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "get")
+	require.Nil(t, err)
+	assert.Equal(t, "", res.Href)
+}
+
+func TestSSHDiskCacheSkipsResponsesWithoutExpiry(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real"}
+
+	res, err := withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSSHDiskCacheResolvesExpiredEntries(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	cache := withSSHDiskCache(newFakeResolver(), path, 0).(*sshDiskCache)
+	cache.save(map[string]*sshDiskCacheEntry{
+		"userandhost//1//path//post": {
+			Href:      "cache",
+			ExpiresAt: time.Now().Add(time.Second),
+		},
+	})
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real", ExpiresIn: 3600}
+
+	res, err := withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+}
+
+func TestSSHDiskCacheIgnoresInsecureFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	cache := withSSHDiskCache(newFakeResolver(), path, 0).(*sshDiskCache)
+	cache.save(map[string]*sshDiskCacheEntry{
+		"userandhost//1//path//post": {
+			Href:      "cache",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	})
+	require.Nil(t, os.Chmod(path, 0644))
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real"}
+
+	res, err := withSSHDiskCache(ssh, path, 0).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+}
+
+func TestSSHDiskCachePath(t *testing.T) {
+	gitEnv := make(testEnv)
+	osEnv := testEnv{"HOME": "/home/user"}
+	assert.Equal(t, "", sshDiskCachePath(osEnv, gitEnv))
+
+	gitEnv["lfs.ssh.authcache"] = "true"
+	assert.Equal(t, filepath.Join("/home/user", ".cache", "git-lfs", "ssh-auth.json"), sshDiskCachePath(osEnv, gitEnv))
+
+	osEnv["XDG_CACHE_HOME"] = "/cache"
+	assert.Equal(t, filepath.Join("/cache", "git-lfs", "ssh-auth.json"), sshDiskCachePath(osEnv, gitEnv))
+}
+
+func TestSSHDiskCacheSharesResponsesWithoutExpiry(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real"}
+
+	res, err := withSSHDiskCache(ssh, path, time.Minute).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+
+	delete(ssh.responses, "userandhost")
+	res, err = withSSHDiskCache(ssh, path, time.Minute).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	assert.Equal(t, "real", res.Href)
+	assert.True(t, res.ExpiresAt.Before(time.Now().Add(time.Minute)))
+}
+
+func TestSSHDiskCacheSharesResponsesForMaxAge(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real", ExpiresIn: 3600}
+
+	_, err := withSSHDiskCache(ssh, path, time.Minute).Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+
+	entries := withSSHDiskCache(ssh, path, time.Minute).(*sshDiskCache).load()
+	entry, ok := entries["userandhost//1//path//post"]
+	require.True(t, ok)
+	assert.True(t, entry.ExpiresAt.Before(time.Now().Add(time.Minute+time.Second)))
+}
+
+func TestSSHSharedCachePath(t *testing.T) {
+	gitEnv := make(testEnv)
+	osEnv := make(testEnv)
+	assert.Equal(t, "", sshSharedCachePath(osEnv, gitEnv, "1234"))
+
+	osEnv["XDG_RUNTIME_DIR"] = "/run/user/1000"
+	assert.Equal(t, "", sshSharedCachePath(osEnv, gitEnv, "1234"))
+
+	gitEnv["lfs.ssh.authshare"] = "true"
+	assert.Equal(t, filepath.Join("/run/user/1000", "git-lfs", "ssh-auth-shared-1234.json"), sshSharedCachePath(osEnv, gitEnv, "1234"))
+}
+
+func TestSSHShareSession(t *testing.T) {
+	osEnv := make(testEnv)
+	assert.Equal(t, strconv.Itoa(os.Getppid()), sshShareSession(osEnv))
+
+	osEnv[sshShareSessionEnv] = "abc"
+	assert.Equal(t, "abc", sshShareSession(osEnv))
+}
+
+func TestSSHDiskCacheRemovesStaleSharedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-auth-shared")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	stale := filepath.Join(dir, "ssh-auth-shared-1.json")
+	fresh := filepath.Join(dir, "ssh-auth-shared-2.json")
+	require.Nil(t, ioutil.WriteFile(stale, []byte("{}"), 0600))
+	require.Nil(t, ioutil.WriteFile(fresh, []byte("{}"), 0600))
+	old := time.Now().Add(-2 * time.Minute)
+	require.Nil(t, os.Chtimes(stale, old, old))
+
+	c := &sshDiskCache{path: filepath.Join(dir, "ssh-auth-shared-3.json"), maxAge: time.Minute}
+	c.save(make(map[string]*sshDiskCacheEntry))
+
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(fresh)
+	assert.Nil(t, err)
+	_, err = os.Stat(c.path)
+	assert.Nil(t, err)
+}
+
+func TestSSHDiskCacheCleanupRemovesCreatedFile(t *testing.T) {
+	dir, path := newTestSSHDiskCachePath(t)
+	defer os.RemoveAll(dir)
+
+	ssh := newFakeResolver()
+	ssh.responses["userandhost"] = sshAuthResponse{Href: "real"}
+
+	c := newSSHDiskCache(ssh, path, time.Minute)
+	_, err := c.Resolve(sshDiskCacheEndpoint, "post")
+	require.Nil(t, err)
+	_, err = os.Stat(path)
+	require.Nil(t, err)
+
+	// Another process using the file does not remove it.
+	other := newSSHDiskCache(ssh, path, time.Minute)
+	_, err = other.Resolve(sshDiskCacheEndpoint, "get")
+	require.Nil(t, err)
+	other.cleanup()
+	_, err = os.Stat(path)
+	assert.Nil(t, err)
+
+	c.cleanup()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestClientSSHAuthSessionEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-auth-session")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := NewClient(NewContext(nil, map[string]string{
+		"XDG_RUNTIME_DIR":  dir,
+		sshShareSessionEnv: "abc",
+	}, map[string]string{
+		"lfs.ssh.authshare": "true",
+	}))
+	require.Nil(t, err)
+	defer c.Close()
+
+	assert.Equal(t, []string{sshShareSessionEnv + "=abc"}, c.SSHAuthSessionEnv())
+	assert.Equal(t, "", os.Getenv(sshShareSessionEnv))
+
+	c, err = NewClient(NewContext(nil, nil, nil))
+	require.Nil(t, err)
+	defer c.Close()
+	assert.Empty(t, c.SSHAuthSessionEnv())
+}
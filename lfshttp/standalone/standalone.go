@@ -247,11 +247,6 @@ func (h *fileHandler) download(oid string, size int64) (string, string, error) {
 		return oid, "", errors.Errorf("remote missing object %s", oid)
 	}
 
-	src, err := h.remoteConfig.Filesystem().ObjectPath(oid)
-	if err != nil {
-		return oid, "", err
-	}
-
 	tmp, err := ioutil.TempFile(h.tempdir, "download")
 	if err != nil {
 		return oid, "", err
@@ -259,7 +254,7 @@ func (h *fileHandler) download(oid string, size int64) (string, string, error) {
 	tmp.Close()
 	os.Remove(tmp.Name())
 	path := tmp.Name()
-	return oid, path, lfs.LinkOrCopy(h.config, src, path)
+	return oid, path, lfs.LinkOrCopyObject(h.config, h.remoteConfig.Filesystem(), oid, path)
 }
 
 // standaloneFailure reports a fatal error.
@@ -0,0 +1,190 @@
+// Package client talks to Git LFS servers: it makes Batch API requests, and
+// uploads and downloads objects with the same transfer queue, configuration,
+// and authentication as the git-lfs command.
+//
+// Unlike the packages Git LFS uses internally, this package's API is stable,
+// and is intended for use by other programs, such as CI agents and tools which
+// need to transfer Git LFS objects without running git-lfs. See docs/api in
+// the Git LFS source for the protocol used.
+package client
+
+import (
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/tq"
+)
+
+// Options configures a Client.
+type Options struct {
+	// Dir is a directory in the Git repository whose configuration and
+	// local Git LFS storage are used. If empty, the current directory is
+	// used.
+	Dir string
+	// Remote is the name or URL of the Git remote whose Git LFS server is
+	// used. If empty, the remote is chosen as by the git-lfs command.
+	Remote string
+}
+
+// Object identifies a Git LFS object.
+type Object struct {
+	Oid  string
+	Size int64
+}
+
+// Action is how to upload, download, or verify an object, as returned by the
+// Batch API.
+type Action struct {
+	Href   string
+	Header map[string]string
+	// ExpiresAt is when the action expires, or the zero time if it does
+	// not.
+	ExpiresAt time.Time
+}
+
+// ObjectError is an error for a single object returned by the Batch API.
+type ObjectError struct {
+	Code    int
+	Message string
+}
+
+func (e *ObjectError) Error() string {
+	return e.Message
+}
+
+// BatchObject is the Batch API's response for a single object.
+type BatchObject struct {
+	Object
+	// Actions are the actions to take for the object, keyed by name, such
+	// as "download", "upload", or "verify". It is empty for uploads of
+	// objects which the server already has.
+	Actions map[string]*Action
+	// Error is set if the server cannot transfer the object.
+	Error *ObjectError
+}
+
+// Client makes requests to the Git LFS server of a single remote.
+type Client struct {
+	cfg    *config.Configuration
+	api    *lfsapi.Client
+	remote string
+}
+
+// New returns a Client configured by the given options.
+func New(opts Options) (*Client, error) {
+	cfg := config.New()
+	if len(opts.Dir) > 0 {
+		cfg = config.NewIn(opts.Dir, "")
+	}
+	if len(opts.Remote) > 0 {
+		if err := cfg.SetValidRemote(opts.Remote); err != nil {
+			return nil, errors.Wrapf(err, "invalid remote %q", opts.Remote)
+		}
+	}
+
+	api, err := lfsapi.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cfg: cfg, api: api, remote: cfg.Remote()}, nil
+}
+
+// Endpoint returns the URL of the Git LFS API used for the given operation,
+// either "upload" or "download".
+func (c *Client) Endpoint(operation string) string {
+	return c.api.Endpoints.Endpoint(operation, c.remote).Url
+}
+
+// Batch makes a Batch API request for the given operation, either "upload" or
+// "download", and objects, and returns the server's response for each object.
+func (c *Client) Batch(operation string, objects []Object) ([]*BatchObject, error) {
+	dir, err := direction(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]*tq.Transfer, 0, len(objects))
+	for _, o := range objects {
+		transfers = append(transfers, &tq.Transfer{Oid: o.Oid, Size: o.Size})
+	}
+
+	res, err := tq.Batch(c.manifest(operation), dir, c.remote, nil, transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchObject, 0, len(res.Objects))
+	for _, t := range res.Objects {
+		result := &BatchObject{
+			Object:  Object{Oid: t.Oid, Size: t.Size},
+			Actions: make(map[string]*Action, len(t.Actions)),
+		}
+		for name, a := range t.Actions {
+			expiresAt := a.ExpiresAt
+			if a.ExpiresIn != 0 {
+				expiresAt = time.Now().Add(time.Duration(a.ExpiresIn) * time.Second)
+			}
+			result.Actions[name] = &Action{Href: a.Href, Header: a.Header, ExpiresAt: expiresAt}
+		}
+		if t.Error != nil {
+			result.Error = &ObjectError{Code: t.Error.Code, Message: t.Error.Message}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Download downloads the given objects into local Git LFS storage, skipping
+// any which are already there. The error returned describes each object that
+// could not be downloaded.
+func (c *Client) Download(objects []Object) error {
+	q := tq.NewTransferQueue(tq.Download, c.manifest("download"), c.remote)
+	for _, o := range objects {
+		if c.cfg.LFSObjectExists(o.Oid, o.Size) {
+			continue
+		}
+		path, err := c.cfg.Filesystem().ObjectPath(o.Oid)
+		q.Add(o.Oid, path, o.Oid, o.Size, false, err)
+	}
+	q.Wait()
+	return errors.Combine(q.Errors())
+}
+
+// Upload uploads the given objects from local Git LFS storage. The error
+// returned describes each object that could not be uploaded.
+func (c *Client) Upload(objects []Object) error {
+	q := tq.NewTransferQueue(tq.Upload, c.manifest("upload"), c.remote)
+	for _, o := range objects {
+		path, err := c.cfg.Filesystem().UnpackedObjectPath(o.Oid)
+		q.Add(o.Oid, path, o.Oid, o.Size, !c.cfg.LFSObjectExists(o.Oid, o.Size), err)
+	}
+	q.Wait()
+	return errors.Combine(q.Errors())
+}
+
+// ObjectPath returns the path at which the object with the given OID is, or
+// would be, stored in local Git LFS storage.
+func (c *Client) ObjectPath(oid string) string {
+	return c.cfg.Filesystem().ObjectPathname(oid)
+}
+
+// Close releases any resources held by the client.
+func (c *Client) Close() error {
+	return c.api.Close()
+}
+
+func (c *Client) manifest(operation string) *tq.Manifest {
+	return tq.NewManifest(c.cfg.Filesystem(), c.api, operation, c.remote)
+}
+
+func direction(operation string) (tq.Direction, error) {
+	switch operation {
+	case "upload":
+		return tq.Upload, nil
+	case "download":
+		return tq.Download, nil
+	}
+	return tq.Download, errors.Errorf("unknown operation %q", operation)
+}
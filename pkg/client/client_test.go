@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRepo returns a new Git repository using the Git LFS server at the
+// given URL. The caller removes the returned directory.
+func newTestRepo(t *testing.T, url string) string {
+	dir, err := ioutil.TempDir("", "lfs-client")
+	require.Nil(t, err)
+
+	for _, args := range [][]string{
+		{"init", dir},
+		{"-C", dir, "config", "lfs.url", url},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		require.Nil(t, err, string(out))
+	}
+	return dir
+}
+
+func TestClientBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/objects/batch" {
+			w.WriteHeader(404)
+			return
+		}
+
+		var req struct {
+			Operation string `json:"operation"`
+			Objects   []struct {
+				Oid  string `json:"oid"`
+				Size int64  `json:"size"`
+			} `json:"objects"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "download", req.Operation)
+		if assert.Equal(t, 2, len(req.Objects)) {
+			assert.Equal(t, "a", req.Objects[0].Oid)
+			assert.Equal(t, int64(1), req.Objects[0].Size)
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.Write([]byte(`{"objects":[` +
+			`{"oid":"a","size":1,"actions":{"download":{"href":"https://example.com/a","header":{"Key":"value"},"expires_in":3600}}},` +
+			`{"oid":"b","size":2,"error":{"code":404,"message":"not found"}}]}`))
+	}))
+	defer srv.Close()
+
+	dir := newTestRepo(t, srv.URL+"/api")
+	defer os.RemoveAll(dir)
+
+	c, err := New(Options{Dir: dir})
+	require.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, srv.URL+"/api", c.Endpoint("download"))
+
+	objects, err := c.Batch("download", []Object{{Oid: "a", Size: 1}, {Oid: "b", Size: 2}})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(objects))
+
+	assert.Equal(t, Object{Oid: "a", Size: 1}, objects[0].Object)
+	assert.Nil(t, objects[0].Error)
+	if action := objects[0].Actions["download"]; assert.NotNil(t, action) {
+		assert.Equal(t, "https://example.com/a", action.Href)
+		assert.Equal(t, "value", action.Header["Key"])
+		assert.False(t, action.ExpiresAt.IsZero())
+	}
+
+	assert.Equal(t, Object{Oid: "b", Size: 2}, objects[1].Object)
+	if assert.NotNil(t, objects[1].Error) {
+		assert.Equal(t, 404, objects[1].Error.Code)
+		assert.Equal(t, "not found", objects[1].Error.Message)
+	}
+}
+
+func TestClientBatchUnknownOperation(t *testing.T) {
+	dir := newTestRepo(t, "https://example.com/api")
+	defer os.RemoveAll(dir)
+
+	c, err := New(Options{Dir: dir})
+	require.Nil(t, err)
+	defer c.Close()
+
+	_, err = c.Batch("delete", nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "unknown operation")
+	}
+}
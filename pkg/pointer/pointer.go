@@ -0,0 +1,89 @@
+// Package pointer parses and writes Git LFS pointer files, the small text
+// files stored in Git in place of the content of large files.
+//
+// Unlike the packages Git LFS uses internally, this package's API is stable,
+// and is intended for use by other programs, such as Git LFS servers and tools
+// which analyze repositories. See docs/spec.md in the Git LFS source for the
+// format of pointer files.
+package pointer
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfs"
+)
+
+// Pointer is a parsed Git LFS pointer file.
+type Pointer struct {
+	// Oid is the SHA-256 hash of the content, as lowercase hexadecimal.
+	Oid string
+	// Size is the size of the content in bytes.
+	Size int64
+	// Extensions are the pointer extensions which were applied to the
+	// content, in order of priority.
+	Extensions []*Extension
+	// Metadata holds the optional metadata keys of the pointer, without
+	// their "meta-" prefix.
+	Metadata map[string]string
+}
+
+// Extension is a pointer extension applied to the content of a pointer, as
+// configured with "lfs.extension.<name>".
+type Extension struct {
+	Name     string
+	Priority int
+	// Oid is the SHA-256 hash of the content given to the extension.
+	Oid string
+}
+
+// New returns a pointer to content with the given OID and size.
+func New(oid string, size int64) *Pointer {
+	return &Pointer{Oid: oid, Size: size}
+}
+
+// Parse parses the given pointer file contents. If they are not a valid
+// pointer, the error returned satisfies IsNotAPointer.
+func Parse(data []byte) (*Pointer, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// Decode parses a pointer file read from the given reader. If it is not a
+// valid pointer, the error returned satisfies IsNotAPointer.
+func Decode(r io.Reader) (*Pointer, error) {
+	p, err := lfs.DecodePointer(r)
+	if err != nil {
+		return nil, err
+	}
+
+	exts := make([]*Extension, 0, len(p.Extensions))
+	for _, ext := range p.Extensions {
+		exts = append(exts, &Extension{Name: ext.Name, Priority: ext.Priority, Oid: ext.Oid})
+	}
+	return &Pointer{Oid: p.Oid, Size: p.Size, Extensions: exts, Metadata: p.Metadata}, nil
+}
+
+// IsNotAPointer returns whether the given error was returned because data
+// parsed as a pointer was not one.
+func IsNotAPointer(err error) bool {
+	return errors.IsNotAPointerError(err) || errors.IsBadPointerKeyError(err)
+}
+
+// Encode writes the pointer file for p to the given writer, returning the
+// number of bytes written.
+func (p *Pointer) Encode(w io.Writer) (int, error) {
+	return w.Write([]byte(p.String()))
+}
+
+// String returns the contents of the pointer file for p. As with Git LFS
+// itself, the pointer to empty content is the empty string.
+func (p *Pointer) String() string {
+	exts := make([]*lfs.PointerExtension, 0, len(p.Extensions))
+	for _, ext := range p.Extensions {
+		exts = append(exts, lfs.NewPointerExtension(ext.Name, ext.Priority, ext.Oid))
+	}
+	lp := lfs.NewPointer(p.Oid, p.Size, exts)
+	lp.Metadata = p.Metadata
+	return lp.Encoded()
+}
@@ -0,0 +1,55 @@
+package pointer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte("version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:" + testOid + "\n" +
+		"size 12345\n"))
+	require.Nil(t, err)
+	assert.Equal(t, testOid, p.Oid)
+	assert.Equal(t, int64(12345), p.Size)
+	assert.Empty(t, p.Extensions)
+}
+
+func TestParseNotAPointer(t *testing.T) {
+	p, err := Parse([]byte("this is not a git-lfs file!"))
+	assert.Nil(t, p)
+	assert.True(t, IsNotAPointer(err))
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	p := New(testOid, 12345)
+	p.Extensions = []*Extension{
+		{Name: "foo", Priority: 0, Oid: "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{Name: "bar", Priority: 1, Oid: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+	}
+
+	var buf bytes.Buffer
+	n, err := p.Encode(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, buf.Len(), n)
+	assert.Equal(t, "version https://git-lfs.github.com/spec/v1\n"+
+		"ext-0-foo sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff\n"+
+		"ext-1-bar sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\n"+
+		"oid sha256:"+testOid+"\n"+
+		"size 12345\n", buf.String())
+
+	parsed, err := Decode(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, p.Oid, parsed.Oid)
+	assert.Equal(t, p.Size, parsed.Size)
+	assert.Equal(t, p.Extensions, parsed.Extensions)
+}
+
+func TestEncodeEmpty(t *testing.T) {
+	assert.Equal(t, "", New("", 0).String())
+}
@@ -51,6 +51,7 @@ func main() {
 	//   lfs-ssh-echo -- git@127.0.0.1 "git-lfs-transfer REPO OPERATION"
 	//   lfs-ssh-echo git@127.0.0.1 "git-upload-pack REPO"
 	//   lfs-ssh-echo git@127.0.0.1 "git-receive-pack REPO"
+	//   lfs-ssh-echo -- git@127.0.0.1 "sh -c SCRIPT git-lfs-scp PATH"
 	if len(os.Args) < 3 {
 		fmt.Fprintf(os.Stderr, "got %d args: %v", len(os.Args), os.Args)
 		os.Exit(1)
@@ -79,14 +80,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	// just "git-lfs-(authenticate|transfer) REPO OPERATION" or "git-(upload|receive)-pack REPO"
+	// just "git-lfs-(authenticate|transfer) REPO OPERATION", "git-(upload|receive)-pack REPO"
+	// or "sh -c SCRIPT ARGS..." from the scp adapter
 	remoteCmd := strings.Split(os.Args[offset+1], " ")
 	if len(remoteCmd) < 2 {
 		fmt.Fprintf(os.Stderr, "bad command line: %s\nargs: %v", remoteCmd, os.Args)
 		os.Exit(1)
 	}
 
-	if remoteCmd[0] == "git-lfs-transfer" || remoteCmd[0] == "git-upload-pack" || remoteCmd[0] == "git-receive-pack" {
+	if remoteCmd[0] == "git-lfs-transfer" || remoteCmd[0] == "git-upload-pack" || remoteCmd[0] == "git-receive-pack" || remoteCmd[0] == "sh" {
 		err := spawnCommand(os.Args[offset+1])
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error running command %q: %v", remoteCmd[0], err)
@@ -0,0 +1,39 @@
+//go:build testtools
+// +build testtools
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/rpc/jsonrpc"
+	"os"
+)
+
+// lfstest-daemon-call calls a method of the "git lfs daemon" listening on the
+// given socket with the given JSON parameters, and prints the JSON result.
+func main() {
+	if len(os.Args) < 3 || len(os.Args) > 4 {
+		fmt.Fprintf(os.Stderr, "Usage: %s SOCKET METHOD [PARAMS]\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	params := json.RawMessage("{}")
+	if len(os.Args) == 4 {
+		params = json.RawMessage(os.Args[3])
+	}
+
+	client, err := jsonrpc.Dial("unix", os.Args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting: %v\n", err)
+		os.Exit(3)
+	}
+	defer client.Close()
+
+	var result json.RawMessage
+	if err := client.Call(os.Args[2], params, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error calling %s: %v\n", os.Args[2], err)
+		os.Exit(4)
+	}
+	fmt.Println(string(result))
+}
@@ -4,9 +4,11 @@
 package main
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -60,6 +62,7 @@ var (
 		"status-batch-resume-206", "batch-resume-fail-fallback", "return-expired-action", "return-expired-action-forever", "return-invalid-size",
 		"object-authenticated", "storage-download-retry", "storage-upload-retry", "storage-upload-retry-later", "unknown-oid",
 		"send-verify-action", "send-deprecated-links", "redirect-storage-upload", "storage-compress",
+		"storage-upload-require-digest",
 	}
 
 	reqCookieReposRE = regexp.MustCompile(`\A/require-cookie-`)
@@ -94,6 +97,7 @@ func main() {
 	})
 
 	mux.HandleFunc("/storage/", storageHandler)
+	mux.HandleFunc("/bootstrap", bootstrapHandler)
 	mux.HandleFunc("/verify", verifyHandler)
 	mux.HandleFunc("/redirect307/", redirect307Handler)
 	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
@@ -234,6 +238,8 @@ func lfsHandler(w http.ResponseWriter, r *http.Request, id string) {
 
 		if strings.HasSuffix(r.URL.String(), "batch") {
 			lfsBatchHandler(w, r, id, repo)
+		} else if strings.HasSuffix(r.URL.Path, "/objects/bootstrap") {
+			lfsBootstrapHandler(w, r, repo)
 		} else {
 			locksHandler(w, r, repo)
 		}
@@ -242,6 +248,8 @@ func lfsHandler(w http.ResponseWriter, r *http.Request, id string) {
 	case "GET":
 		if strings.Contains(r.URL.String(), "/locks") {
 			locksHandler(w, r, repo)
+		} else if strings.HasSuffix(r.URL.Path, "/storage") && !strings.HasPrefix(repo, "storage-unsupported") {
+			lfsStorageUsageHandler(w, r, repo)
 		} else {
 			w.WriteHeader(404)
 			w.Write([]byte("lock request"))
@@ -251,6 +259,70 @@ func lfsHandler(w http.ResponseWriter, r *http.Request, id string) {
 	}
 }
 
+// lfsStorageUsageHandler reports the objects stored for the repository, with a
+// quota of 1 MiB.
+func lfsStorageUsageHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	objects, size := largeObjects.Usage(repo)
+	by, _ := json.Marshal(map[string]int64{
+		"objects": int64(objects),
+		"size":    size,
+		"quota":   1024 * 1024,
+	})
+	w.WriteHeader(200)
+	w.Write(by)
+}
+
+// lfsBootstrapHandler offers a bundle of the objects stored for repositories
+// whose names start with "bootstrap", which must be downloaded with the header
+// it gives.
+func lfsBootstrapHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	if !strings.HasPrefix(repo, "bootstrap") {
+		writeLFSError(w, 404, "no bootstrap bundle")
+		return
+	}
+
+	by, _ := json.Marshal(&lfsLink{
+		Href:   server.URL + "/bootstrap?r=" + repo,
+		Header: map[string]string{"X-Bootstrap-Repo": repo},
+	})
+	w.WriteHeader(200)
+	w.Write(by)
+}
+
+// bootstrapHandler serves a gzipped bundle of the objects stored for the
+// repository, except those whose contents start with "nobundle".
+func bootstrapHandler(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("r")
+	if r.Header.Get("X-Bootstrap-Repo") != repo {
+		w.WriteHeader(403)
+		return
+	}
+
+	objects := largeObjects.All(repo)
+	oids := make([]string, 0, len(objects))
+	for oid, by := range objects {
+		if !bytes.HasPrefix(by, []byte("nobundle")) {
+			oids = append(oids, oid)
+		}
+	}
+	sort.Strings(oids)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, oid := range oids {
+		by := objects[oid]
+		tw.WriteHeader(&tar.Header{
+			Name:     fmt.Sprintf("objects/%s/%s/%s", oid[0:2], oid[2:4], oid),
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(by)),
+		})
+		tw.Write(by)
+	}
+	tw.Close()
+	gz.Close()
+}
+
 func lfsUrl(repo, oid string, redirect bool) string {
 	if redirect {
 		return server.URL + "/redirect307/objects/" + oid + "?r=" + repo
@@ -356,8 +428,16 @@ func (r *batchReq) RefName() string {
 }
 
 type batchResp struct {
-	Transfer string      `json:"transfer,omitempty"`
-	Objects  []lfsObject `json:"objects"`
+	Transfer     string             `json:"transfer,omitempty"`
+	Objects      []lfsObject        `json:"objects"`
+	Message      string             `json:"message,omitempty"`
+	Capabilities *batchCapabilities `json:"capabilities,omitempty"`
+}
+
+type batchCapabilities struct {
+	Transfers    []string `json:"transfers,omitempty"`
+	MaxBatchSize int      `json:"max_batch_size,omitempty"`
+	Locking      *bool    `json:"locking,omitempty"`
 }
 
 func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
@@ -466,7 +546,10 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 				addAction = false
 			}
 		} else {
-			if exists {
+			// Objects in "upload-claimed" repositories are
+			// uploaded as though another client were uploading
+			// them too, and so may have arrived since this request.
+			if exists && !strings.HasSuffix(repo, "upload-claimed") {
 				// not an error but don't add an action
 				addAction = false
 			}
@@ -539,6 +622,20 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 
 	ores := batchResp{Transfer: transferChoice, Objects: res}
 
+	if strings.HasSuffix(repo, "batch-notice") {
+		ores.Message = "Maintenance is scheduled for tonight.\x07"
+		w.Header().Add("X-Lfs-Notice", "This server is deprecated.")
+	}
+
+	if strings.HasPrefix(repo, "capabilities") {
+		locking := false
+		ores.Capabilities = &batchCapabilities{
+			Transfers:    []string{"basic"},
+			MaxBatchSize: 1,
+			Locking:      &locking,
+		}
+	}
+
 	by, err := json.Marshal(ores)
 	if err != nil {
 		log.Fatal(err)
@@ -622,6 +719,14 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasSuffix(repo, "verify-quarantined") {
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"message":"scanning for viruses"}`))
+		return
+	}
+
 	var max int
 	if matches := verifyRetryRe.FindStringSubmatch(repo); len(matches) < 2 {
 		return
@@ -644,6 +749,35 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+var (
+	cmu         sync.Mutex
+	claimCounts = make(map[string]int)
+)
+
+// uploadClaimHandler answers a client asking about an object before uploading
+// it: the object is claimed by another upload the first time, and may be
+// uploaded after that, unless the server already has it.
+func uploadClaimHandler(w http.ResponseWriter, repo, oid string) {
+	if largeObjects.Has(repo, oid) {
+		w.WriteHeader(200)
+		return
+	}
+
+	key := strings.Join([]string{repo, oid}, ":")
+
+	cmu.Lock()
+	claimCounts[key] = claimCounts[key] + 1
+	count := claimCounts[key]
+	cmu.Unlock()
+
+	if count == 1 {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(409)
+		return
+	}
+	w.WriteHeader(404)
+}
+
 // handles any /storage/{oid} requests
 func storageHandler(w http.ResponseWriter, r *http.Request) {
 	id, ok := reqId(w)
@@ -707,6 +841,12 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 				w.Write([]byte("not encoded"))
 				return
 			}
+		case "storage-upload-require-digest":
+			if len(r.Header.Get("Content-MD5")) == 0 || len(r.Header.Get("Digest")) == 0 {
+				w.WriteHeader(400)
+				w.Write([]byte("missing Content-MD5 or Digest"))
+				return
+			}
 		}
 
 		if testingChunkedTransferEncoding(r) {
@@ -732,6 +872,19 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Check any checksums sent, as object stores do.
+		md5sum := md5.Sum(buf.Bytes())
+		if v := r.Header.Get("Content-MD5"); len(v) > 0 && v != base64.StdEncoding.EncodeToString(md5sum[:]) {
+			w.WriteHeader(400)
+			w.Write([]byte("Content-MD5 mismatch"))
+			return
+		}
+		if v := r.Header.Get("Digest"); len(v) > 0 && v != "SHA-256="+base64.StdEncoding.EncodeToString(hash.Sum(nil)) {
+			w.WriteHeader(400)
+			w.Write([]byte("Digest mismatch"))
+			return
+		}
+
 		largeObjects.Set(repo, oid, buf.Bytes())
 
 	case "GET":
@@ -764,7 +917,10 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 				}
 			} else if len(by) == len("status-batch-resume-206") && string(by) == "status-batch-resume-206" {
 				// Resume if header includes range, otherwise deliberately interrupt
-				if rangeHdr := r.Header.Get("Range"); rangeHdr != "" {
+				w.Header().Set("ETag", `"resume-206"`)
+				if ifRange := r.Header.Get("If-Range"); ifRange != "" && ifRange != `"resume-206"` {
+					// Content changed, so send all of it
+				} else if rangeHdr := r.Header.Get("Range"); rangeHdr != "" {
 					regex := regexp.MustCompile(`bytes=(\d+)\-.*`)
 					match := regex.FindStringSubmatch(rangeHdr)
 					if match != nil && len(match) > 1 {
@@ -775,6 +931,39 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 				} else {
 					byteLimit = 10
 				}
+			} else if string(by) == "batch-resume-etag-changed" || string(by) == "batch-resume-immutable" {
+				// Deliberately interrupt the first download, and
+				// resume from a Range request unless the If-Range
+				// header shows the content has changed since.
+				etag := `"v1"`
+				if string(by) == "batch-resume-immutable" {
+					w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+				} else if r.Header.Get("Range") != "" {
+					etag = `"v2"`
+				}
+				w.Header().Set("ETag", etag)
+
+				rangeHdr := r.Header.Get("Range")
+				ifRange := r.Header.Get("If-Range")
+				if rangeHdr == "" {
+					byteLimit = 10
+				} else if ifRange == "" || ifRange == etag {
+					regex := regexp.MustCompile(`bytes=(\d+)\-.*`)
+					match := regex.FindStringSubmatch(rangeHdr)
+					if match != nil && len(match) > 1 {
+						statusCode = 206
+						resumeAt, _ = strconv.ParseInt(match[1], 10, 32)
+						w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", resumeAt, len(by), resumeAt-int64(len(by))))
+					}
+				}
+			} else if string(by) == "storage-download-digest" || string(by) == "storage-download-bad-md5" {
+				md5sum := md5.Sum(by)
+				if string(by) == "storage-download-bad-md5" {
+					md5sum = md5.Sum([]byte("something else"))
+				}
+				sha256sum := sha256.Sum256(by)
+				w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+				w.Header().Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sha256sum[:]))
 			} else if len(by) == len("batch-resume-fail-fallback") && string(by) == "batch-resume-fail-fallback" {
 				// Fail any Range: request even though we said we supported it
 				// To make sure client can fall back
@@ -841,6 +1030,11 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 
 		w.WriteHeader(404)
 	case "HEAD":
+		if strings.HasSuffix(repo, "upload-claimed") {
+			uploadClaimHandler(w, repo, oid)
+			return
+		}
+
 		// tus.io
 		if !validateTusHeaders(r, id) {
 			w.WriteHeader(400)
@@ -1472,6 +1666,17 @@ func (s *lfsStorage) Get(repo, oid string) ([]byte, bool) {
 	return by, ok
 }
 
+// All returns a copy of the objects stored for the repository, by OID.
+func (s *lfsStorage) All(repo string) map[string][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	objects := make(map[string][]byte, len(s.objects[repo]))
+	for oid, by := range s.objects[repo] {
+		objects[oid] = by
+	}
+	return objects
+}
+
 func (s *lfsStorage) Has(repo, oid string) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -1484,6 +1689,18 @@ func (s *lfsStorage) Has(repo, oid string) bool {
 	return ok
 }
 
+// Usage returns the number of objects stored for the repository and their
+// total size.
+func (s *lfsStorage) Usage(repo string) (int, int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var size int64
+	for _, by := range s.objects[repo] {
+		size += int64(len(by))
+	}
+	return len(s.objects[repo]), size
+}
+
 func (s *lfsStorage) Set(repo, oid string, by []byte) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
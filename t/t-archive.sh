@@ -0,0 +1,127 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+setup_archive_repo () {
+  local reponame="$1"
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "a content" > a.dat
+  printf "b content" > dir/b.dat
+  printf "plain" > plain.txt
+  git add .gitattributes a.dat dir plain.txt
+  git commit -m "initial commit"
+  git push origin main
+}
+
+begin_test "archive: tar"
+(
+  set -e
+
+  reponame="archive-tar"
+  setup_archive_repo "$reponame"
+
+  # content which isn't present locally is downloaded
+  rm -rf .git/lfs/objects
+
+  GIT_LFS_FORCE_PROGRESS=1 git lfs archive -o ../out.tar HEAD 2>&1 | tee archive.log
+  grep "Downloading LFS objects: 100% (2/2)" archive.log
+
+  mkdir ../tar-out
+  tar -C ../tar-out -xf ../out.tar
+  [ "a content" = "$(cat ../tar-out/a.dat)" ]
+  [ "b content" = "$(cat ../tar-out/dir/b.dat)" ]
+  [ "plain" = "$(cat ../tar-out/plain.txt)" ]
+  cmp .gitattributes ../tar-out/.gitattributes
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+
+  # the archive can be written to stdout, with a prefix and paths
+  git lfs archive --prefix=proj/ HEAD dir > ../stdout.tar
+  mkdir ../stdout-out
+  tar -C ../stdout-out -xf ../stdout.tar
+  [ "b content" = "$(cat ../stdout-out/proj/dir/b.dat)" ]
+  [ ! -e ../stdout-out/proj/a.dat ]
+)
+end_test
+
+begin_test "archive: zip"
+(
+  set -e
+
+  reponame="archive-zip"
+  setup_archive_repo "$reponame"
+
+  git lfs archive -o ../out.zip HEAD
+
+  mkdir ../zip-out
+  unzip -d ../zip-out ../out.zip
+  [ "a content" = "$(cat ../zip-out/a.dat)" ]
+  [ "b content" = "$(cat ../zip-out/dir/b.dat)" ]
+  [ "plain" = "$(cat ../zip-out/plain.txt)" ]
+
+  # the commit ID is recorded in the archive comment, as with "git archive"
+  unzip -z ../out.zip | grep "$(git rev-parse HEAD)"
+
+  git lfs archive --format=zip HEAD > ../stdout.zip
+  cmp ../out.zip ../stdout.zip
+)
+end_test
+
+begin_test "archive: untracked pointers"
+(
+  set -e
+
+  reponame="archive-untracked"
+  setup_archive_repo "$reponame"
+
+  # a file which looks like a pointer, but which the .gitattributes in the
+  # tree do not track, is archived as it is
+  git cat-file -p :a.dat > looks-like-pointer.txt
+  git add looks-like-pointer.txt
+  git commit -m "add looks-like-pointer.txt"
+
+  git lfs archive -o ../untracked.tar HEAD
+
+  mkdir ../untracked-out
+  tar -C ../untracked-out -xf ../untracked.tar
+  [ "a content" = "$(cat ../untracked-out/a.dat)" ]
+  cmp looks-like-pointer.txt ../untracked-out/looks-like-pointer.txt
+
+  # attributes are read from the archived tree, not the working tree
+  git lfs untrack "*.dat"
+  git lfs archive -o ../untracked2.tar HEAD
+  mkdir ../untracked-out2
+  tar -C ../untracked-out2 -xf ../untracked2.tar
+  [ "a content" = "$(cat ../untracked-out2/a.dat)" ]
+)
+end_test
+
+begin_test "archive: errors"
+(
+  set -e
+
+  reponame="archive-errors"
+  setup_archive_repo "$reponame"
+
+  git lfs archive --format=rar HEAD 2>&1 | tee archive.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected archive to fail ..."
+    exit 1
+  fi
+  grep "unknown archive format \"rar\"" archive.log
+
+  git lfs archive -o ../missing.tar does-not-exist 2>&1 | tee archive.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected archive to fail ..."
+    exit 1
+  fi
+  grep "not a valid tree-ish" archive.log
+  [ ! -e ../missing.tar ]
+)
+end_test
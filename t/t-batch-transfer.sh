@@ -149,3 +149,64 @@ begin_test "batch transfers with ssh endpoint (git-lfs-transfer)"
   git lfs fsck
 )
 end_test
+
+begin_test "batch transfers show server notices once"
+(
+  set -e
+
+  reponame="batch-notice"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  git push origin main 2>&1 | tee push.log
+  [ "1" -eq "$(grep -c "remote: Maintenance is scheduled for tonight." push.log)" ]
+  [ "1" -eq "$(grep -c "remote: This server is deprecated." push.log)" ]
+  # The bell character sent by the server is not printed.
+  [ "0" -eq "$(grep -c $'\a' push.log)" ]
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs pull 2>&1 | tee pull.log
+  [ "1" -eq "$(grep -c "remote: Maintenance is scheduled for tonight." pull.log)" ]
+  [ "1" -eq "$(grep -c "remote: This server is deprecated." pull.log)" ]
+
+  cd ..
+  git clone "$GITSERVER/$reponame" "$reponame-smudge" 2>&1 | tee clone.log
+  [ "1" -eq "$(grep -c "Maintenance is scheduled for tonight." clone.log)" ]
+)
+end_test
+
+begin_test "batch transfers adapt to the capabilities advertised by the server"
+(
+  set -e
+
+  reponame="capabilities"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  printf "c" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+
+  endpoint="$(repo_endpoint "$GITSERVER" "$reponame")"
+  [ -z "$(git config "lfs.$endpoint.locksverify")" ]
+
+  GIT_TRACE=1 git push origin main 2>&1 | tee push.log
+  grep "tq: limiting batch size to 1 for the server" push.log
+
+  # The server says it doesn't support locking, so stop asking it.
+  [ "false" = "$(git config "lfs.$endpoint.locksverify")" ]
+  assert_server_object "$reponame" "$(calc_oid "c")"
+)
+end_test
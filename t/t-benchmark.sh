@@ -0,0 +1,77 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "benchmark"
+(
+  set -e
+
+  reponame="benchmark"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs benchmark --size=1MB 2>&1 | tee benchmark.log
+  grep "Benchmarking with 1.0 MB of data" benchmark.log
+  grep "hash .*/s  typical: >= 200 MB/s" benchmark.log
+  grep "disk write .*/s  typical: >= 100 MB/s" benchmark.log
+  grep "Use --network" benchmark.log
+  [ "0" -eq "$(grep -c "upload" benchmark.log)" ]
+
+  # No temporary files are left behind.
+  [ -z "$(find .git/lfs/tmp -type f)" ]
+)
+end_test
+
+begin_test "benchmark --network"
+(
+  set -e
+
+  reponame="benchmark-network"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs benchmark --size=1MB --network --yes 2>&1 | tee benchmark.log
+  grep "upload .*/s  typical: >= 10 MB/s" benchmark.log
+  grep "download .*/s  typical: >= 10 MB/s" benchmark.log
+  [ "0" -eq "$(grep -c "failed" benchmark.log)" ]
+
+  # The synthetic object is not kept locally.
+  [ -z "$(find .git/lfs/objects -type f)" ]
+)
+end_test
+
+begin_test "benchmark --network requires --yes"
+(
+  set -e
+
+  reponame="benchmark-network-confirm"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs benchmark --size=1MB --network 2>&1 | tee benchmark.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs benchmark --network' to fail ..."
+    exit 1
+  fi
+  grep "permanently uploads a new 1.0 MB object" benchmark.log
+  grep "pass --yes to confirm" benchmark.log
+  [ "0" -eq "$(grep -c "Benchmarking" benchmark.log)" ]
+  [ "0" -eq "$(grep -c "typical" benchmark.log)" ]
+)
+end_test
+
+begin_test "benchmark --network with failing remote"
+(
+  set -e
+
+  reponame="benchmark-network-fail"
+  git init "$reponame"
+  cd "$reponame"
+  git remote add origin "http://127.0.0.1:1/nonexistent"
+  git config lfs.url "http://127.0.0.1:1/nonexistent.git/info/lfs"
+
+  git lfs benchmark --size=1MB --network --yes 2>&1 | tee benchmark.log
+  grep "upload .*failed:" benchmark.log
+  grep "download .*failed: skipped because upload failed" benchmark.log
+)
+end_test
@@ -0,0 +1,87 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+# setup_bootstrap_repo creates a repository with two objects which the test
+# server puts in its bootstrap bundle, and one which it leaves out, and clones
+# it to "<reponame>-clone" without checking the objects out.
+setup_bootstrap_repo() {
+  local reponame="$1"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  printf "nobundle" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add objects"
+  git push origin main
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+}
+
+begin_test "bootstrap: fetch imports the bundle and fetches the rest"
+(
+  set -e
+
+  setup_bootstrap_repo "bootstrap-fetch"
+
+  # Fetches of fewer objects than lfs.bootstrap.minobjects use the batch API.
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "^bootstrap:" fetch.log && exit 1
+  grep "api: batch 3 files" fetch.log
+
+  rm -rf .git/lfs/objects
+  git config lfs.bootstrap.minobjects 3
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "bootstrap: 2 object(s) imported from bundle" fetch.log
+  grep "api: batch 1 files" fetch.log
+  assert_local_object "$(calc_oid "a")" 1
+  assert_local_object "$(calc_oid "b")" 1
+  assert_local_object "$(calc_oid "nobundle")" 8
+
+  # Once objects are present, the bundle isn't downloaded again.
+  rm -rf .git/lfs/objects/"$(calc_oid "nobundle" | cut -c1-2)"
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "^bootstrap:" fetch.log && exit 1
+  assert_local_object "$(calc_oid "nobundle")" 8
+)
+end_test
+
+begin_test "bootstrap: pull imports the bundle"
+(
+  set -e
+
+  setup_bootstrap_repo "bootstrap-pull"
+
+  git config lfs.bootstrap.minobjects 1
+  git lfs pull 2>&1 | tee pull.log
+  grep "bootstrap: 2 object(s) imported from bundle" pull.log
+  [ "a" = "$(cat a.dat)" ]
+  [ "nobundle" = "$(cat c.dat)" ]
+)
+end_test
+
+begin_test "bootstrap: servers without bundles"
+(
+  set -e
+
+  setup_bootstrap_repo "nobootstrap"
+
+  git config lfs.bootstrap.minobjects 1
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "POST .*/objects/bootstrap" fetch.log
+  grep "^bootstrap:" fetch.log && exit 1
+  grep "warning" fetch.log && exit 1
+  assert_local_object "$(calc_oid "a")" 1
+
+  rm -rf .git/lfs/objects
+  git config lfs.bootstrap false
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "objects/bootstrap" fetch.log && exit 1
+  assert_local_object "$(calc_oid "a")" 1
+)
+end_test
@@ -0,0 +1,103 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+setup_bundle_repo () {
+  local reponame="$1"
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a content" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "initial commit"
+  git tag v1
+
+  printf "b content" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+}
+
+begin_test "bundle: create and unbundle"
+(
+  set -e
+
+  reponame="bundle-create-unbundle"
+  setup_bundle_repo "$reponame"
+
+  git bundle create ../repo.bundle main
+  git lfs bundle create ../lfs.bundle main 2>&1 | tee create.log
+  grep "Bundled 2 object(s)" create.log
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone repo.bundle "$reponame-unbundle"
+  cd "$reponame-unbundle"
+
+  git lfs bundle unbundle ../lfs.bundle 2>&1 | tee unbundle.log
+  grep "Unbundled 2 object(s), 0 already present" unbundle.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+
+  git lfs checkout
+  [ "a content" = "$(cat a.dat)" ]
+  [ "b content" = "$(cat b.dat)" ]
+)
+end_test
+
+begin_test "bundle: create with range and stdin"
+(
+  set -e
+
+  reponame="bundle-range-stdin"
+  setup_bundle_repo "$reponame"
+
+  git lfs bundle create ../range.tgz v1..main
+  tar -tzf ../range.tgz | tee contents.log
+  grep "lfs-bundle.json" contents.log
+  grep "$(calc_oid "b content")" contents.log
+  grep "$(calc_oid "a content")" contents.log && exit 1
+
+  calc_oid "a content" | git lfs bundle create ../stdin.bundle --stdin
+  tar -tf ../stdin.bundle | tee contents.log
+  grep "$(calc_oid "a content")" contents.log
+  grep "$(calc_oid "b content")" contents.log && exit 1
+
+  rm -rf .git/lfs/objects
+  git lfs bundle create ../missing.bundle main 2>&1 | tee create.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected create with missing objects to fail"
+    exit 1
+  fi
+  grep "2 object(s) not present locally" create.log
+  [ ! -e ../missing.bundle ]
+)
+end_test
+
+begin_test "bundle: unbundle detects incomplete bundle"
+(
+  set -e
+
+  reponame="bundle-incomplete"
+  setup_bundle_repo "$reponame"
+
+  git lfs bundle create ../full.bundle main
+
+  mkdir ../partial
+  tar -C ../partial -xf ../full.bundle
+  oid="$(calc_oid "b content")"
+  rm "../partial/objects/${oid:0:2}/${oid:2:2}/$oid"
+  tar -C ../partial -cf ../partial.bundle lfs-bundle.json objects
+
+  rm -rf .git/lfs/objects
+  git lfs bundle unbundle ../partial.bundle 2>&1 | tee unbundle.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected unbundle of incomplete bundle to fail"
+    exit 1
+  fi
+  grep "bundle is incomplete: 1 object(s) missing" unbundle.log
+  grep "$oid" unbundle.log
+  assert_local_object "$(calc_oid "a content")" 9
+)
+end_test
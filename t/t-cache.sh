@@ -0,0 +1,239 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+setup_cache_repo () {
+  local reponame="$1"
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a content" > a.dat
+  printf "b content" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "initial commit"
+  git push origin main
+}
+
+begin_test "cache: pack and unpack"
+(
+  set -e
+
+  reponame="cache-pack-unpack"
+  setup_cache_repo "$reponame"
+
+  printf "old content" > old.dat
+  git add old.dat
+  git commit -m "add old.dat"
+  git rm old.dat
+  git commit -m "remove old.dat"
+
+  git lfs cache pack -o ../cache.tgz 2>&1 | tee pack.log
+  grep "Packed 2 object(s)" pack.log
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-restore"
+  cd "$reponame-restore"
+
+  # the remote is unavailable, so the objects must come from the cache
+  git config lfs.url "http://127.0.0.1:1/nonexistent"
+
+  git lfs cache unpack ../cache.tgz 2>&1 | tee unpack.log
+  grep "Unpacked 2 object(s), 0 already present" unpack.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+  refute_local_object "$(calc_oid "old content")"
+
+  git lfs checkout
+  [ "a content" = "$(cat a.dat)" ]
+  [ "b content" = "$(cat b.dat)" ]
+
+  # unpacking again from stdin finds the objects present already
+  git lfs cache unpack < ../cache.tgz 2>&1 | tee unpack.log
+  grep "Unpacked 0 object(s), 2 already present" unpack.log
+)
+end_test
+
+begin_test "cache: pack skips missing objects"
+(
+  set -e
+
+  reponame="cache-pack-missing"
+  setup_cache_repo "$reponame"
+
+  rm -rf .git/lfs/objects/$(calc_oid "b content" | cut -b 1-2)
+
+  git lfs cache pack -o - 2>pack.log > ../cache.tar
+  grep "Packed 1 object(s)" pack.log
+  grep "1 object(s) not present locally" pack.log
+
+  tar -tf ../cache.tar | tee contents.log
+  oid="$(calc_oid "a content")"
+  grep "objects/${oid:0:2}/${oid:2:2}/$oid" contents.log
+  [ 1 -eq "$(wc -l < contents.log)" ]
+
+  git lfs cache pack -o ../cache.tar.xz 2>&1 | tee pack.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected pack with unsupported compression to fail"
+    exit 1
+  fi
+  grep "unsupported compression" pack.log
+)
+end_test
+
+begin_test "cache: pack and unpack with zstd"
+(
+  set -e
+
+  if ! command -v zstd >/dev/null; then
+    echo "skipping: zstd is not installed"
+    exit 0
+  fi
+
+  reponame="cache-zstd"
+  setup_cache_repo "$reponame"
+
+  git lfs cache pack -o ../cache.tar.zst 2>&1 | tee pack.log
+  grep "Packed 2 object(s)" pack.log
+  zstd -dc ../cache.tar.zst | tar -tf - | tee contents.log
+  [ 2 -eq "$(wc -l < contents.log)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs cache unpack ../cache.tar.zst 2>&1 | tee unpack.log
+  grep "Unpacked 2 object(s), 0 already present" unpack.log
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+
+  # A corrupt archive is reported, rather than taken to be empty.
+  head -c 20 ../cache.tar.zst > ../truncated.tar.zst
+  git lfs cache unpack ../truncated.tar.zst 2>&1 | tee unpack.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected unpack of a truncated archive to fail"
+    exit 1
+  fi
+)
+end_test
+
+begin_test "cache: unpack rejects corrupt objects"
+(
+  set -e
+
+  reponame="cache-unpack-corrupt"
+  setup_cache_repo "$reponame"
+
+  oid="$(calc_oid "a content")"
+  mkdir -p "../corrupt/objects/${oid:0:2}/${oid:2:2}"
+  printf "not a content" > "../corrupt/objects/${oid:0:2}/${oid:2:2}/$oid"
+  tar -C ../corrupt -cf ../corrupt.tar objects
+
+  rm -rf .git/lfs/objects
+
+  git lfs cache unpack ../corrupt.tar 2>&1 | tee unpack.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected unpack of corrupt object to fail"
+    exit 1
+  fi
+  grep "corrupt object $oid" unpack.log
+  refute_local_object "$oid"
+)
+end_test
+
+begin_test "cache: incremental export with manifest"
+(
+  set -e
+
+  reponame="cache-incremental"
+  setup_cache_repo "$reponame"
+
+  git lfs cache export --ref main -o ../base.tar --manifest ../base.manifest
+  [ 2 -eq "$(wc -l < ../base.manifest)" ]
+  grep "$(calc_oid "a content") 9" ../base.manifest
+  grep "$(calc_oid "b content") 9" ../base.manifest
+
+  printf "c content" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  git lfs cache export --ref main -o ../delta.tar --since ../base.manifest \
+    --manifest ../delta.manifest 2>&1 | tee export.log
+  grep "Packed 1 object(s)" export.log
+  [ 3 -eq "$(wc -l < ../delta.manifest)" ]
+
+  tar -tf ../delta.tar | tee contents.log
+  grep "$(calc_oid "c content")" contents.log
+  [ 1 -eq "$(wc -l < contents.log)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs cache import ../base.tar
+  git lfs cache import ../delta.tar 2>&1 | tee import.log
+  grep "Unpacked 1 object(s), 0 already present" import.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+  assert_local_object "$(calc_oid "c content")" 9
+)
+end_test
+
+begin_test "cache: incremental export and import with zstd"
+(
+  set -e
+
+  if ! command -v zstd >/dev/null; then
+    echo "skipping: zstd is not installed"
+    exit 0
+  fi
+
+  reponame="cache-incremental-zstd"
+  setup_cache_repo "$reponame"
+
+  git lfs cache export --ref main -o ../base.tar.zst --manifest ../base.manifest
+
+  printf "c content" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  git lfs cache export --ref main -o ../delta.tzst --since ../base.manifest 2>&1 | tee export.log
+  grep "Packed 1 object(s)" export.log
+  [ "$(head -c 4 ../delta.tzst | od -An -tx1 | tr -d ' \n')" = "28b52ffd" ]
+
+  rm -rf .git/lfs/objects
+  git lfs cache import ../base.tar.zst
+  git lfs cache import < ../delta.tzst 2>&1 | tee import.log
+  grep "Unpacked 1 object(s), 0 already present" import.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+  assert_local_object "$(calc_oid "c content")" 9
+)
+end_test
+
+begin_test "cache: hint about archives not ignored by Git"
+(
+  set -e
+
+  reponame="cache-ignore-hint"
+  setup_cache_repo "$reponame"
+
+  mkdir ci
+  git lfs cache pack -o ci/cache.tar --manifest ci/cache.manifest 2>&1 | tee pack.log
+  grep "hint: \"ci/cache.tar\" is in the working tree but is not ignored by Git." pack.log
+  grep "hint:   echo '/ci/cache.tar' >> .gitignore" pack.log
+  grep "hint:   echo '/ci/cache.manifest' >> .gitignore" pack.log
+
+  # paths are reported relative to the top of the working tree
+  cd ci
+  git lfs cache pack -o cache.tar 2>&1 | tee ../pack.log
+  grep "hint:   echo '/ci/cache.tar' >> .gitignore" ../pack.log
+  cd ..
+
+  printf "/ci/\n" >> .gitignore
+  git lfs cache pack -o ci/cache.tar 2>&1 | tee pack.log
+  [ "0" -eq "$(grep -c "hint:" pack.log)" ]
+
+  # nor is there a hint for files outside the working tree
+  git lfs cache pack -o ../cache-outside.tar 2>&1 | tee pack.log
+  [ "0" -eq "$(grep -c "hint:" pack.log)" ]
+)
+end_test
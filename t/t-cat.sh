@@ -0,0 +1,92 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "cat"
+(
+  set -e
+
+  reponame="cat"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "first" > a.dat
+  printf "plain" > plain.txt
+  git add .gitattributes a.dat plain.txt
+  git commit -m "first"
+
+  printf "second" > a.dat
+  git add a.dat
+  git commit -m "second"
+
+  [ "second" = "$(git lfs cat HEAD:a.dat)" ]
+  [ "first" = "$(git lfs cat HEAD~1:a.dat)" ]
+  [ "second" = "$(git lfs cat :a.dat)" ]
+
+  # blobs which aren't pointers are written unchanged
+  [ "plain" = "$(git lfs cat HEAD:plain.txt)" ]
+
+  # as with Git, paths starting with "./" or "../" are relative to the current
+  # directory
+  mkdir -p dir
+  pushd dir > /dev/null
+    [ "second" = "$(git lfs cat HEAD:../a.dat)" ]
+  popd > /dev/null
+)
+end_test
+
+begin_test "cat: downloads missing content"
+(
+  set -e
+
+  reponame="cat-download"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "old" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "old"
+  printf "new" > a.dat
+  git add a.dat
+  git commit -m "new"
+  git push origin main
+
+  assert_server_object "$reponame" "$(calc_oid "old")"
+
+  rm -rf .git/lfs/objects
+  [ "old" = "$(git lfs cat HEAD~1:a.dat)" ]
+  assert_local_object "$(calc_oid "old")" 3
+)
+end_test
+
+begin_test "cat: errors"
+(
+  set -e
+
+  reponame="cat-errors"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "data" > dir/a.dat
+  git add .gitattributes dir
+  git commit -m "initial commit"
+
+  git lfs cat HEAD:missing.dat 2>&1 | tee cat.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected cat to fail ..."
+    exit 1
+  fi
+  grep "could not resolve \"HEAD:missing.dat\"" cat.log
+
+  git lfs cat HEAD:dir 2>&1 | tee cat.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected cat to fail ..."
+    exit 1
+  fi
+  grep "\"HEAD:dir\" is a tree, not a blob" cat.log
+)
+end_test
@@ -93,16 +93,28 @@ begin_test "checkout"
   [ "$contents" = "$(cat folder1/nested.dat)" ]
   [ "$contents" = "$(cat folder2/nested.dat)" ]
 
-  echo "test checkout with missing data doesn't fail"
+  echo "test checkout with missing data excluded from fetching doesn't fail"
   git push origin main
   rm -rf .git/lfs/objects
   rm file*.dat
-  git lfs checkout
+  git -c lfs.fetchexclude="*.dat" lfs checkout 2>&1 | tee checkout.log
+  grep "Skipped checkout for 3 file(s) with content not available locally." checkout.log
   [ "$(pointer $contents_oid $contentsize)" = "$(cat file1.dat)" ]
   [ "$(pointer $contents_oid $contentsize)" = "$(cat file2.dat)" ]
   [ "$(pointer $contents_oid $contentsize)" = "$(cat file3.dat)" ]
   [ "$contents" = "$(cat folder1/nested.dat)" ]
   [ "$contents" = "$(cat folder2/nested.dat)" ]
+
+  echo "test checkout downloads missing data in a batch"
+  rm -rf .git/lfs/objects
+  rm file*.dat
+  GIT_TRACE=1 git lfs checkout 2>&1 | tee checkout.log
+  grep "tq: sending batch of size 1" checkout.log
+  [ "1" -eq "$(grep -c "tq: sending batch" checkout.log)" ]
+  [ "0" -eq "$(grep -c "Skipped checkout" checkout.log)" ]
+  [ "$contents" = "$(cat file1.dat)" ]
+  [ "$contents" = "$(cat file2.dat)" ]
+  [ "$contents" = "$(cat file3.dat)" ]
 )
 end_test
 
@@ -226,6 +238,188 @@ begin_test "checkout: conflicts"
 end_test
 
 
+begin_test "checkout: --to directory"
+(
+  set -e
+
+  reponame="checkout-to-directory"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  mkdir -p a/b
+  printf "one" > a/one.dat
+  printf "two" > a/b/two.dat
+  printf "three" > "three four.dat"
+  printf "plain" > plain.txt
+  git add .gitattributes a "three four.dat" plain.txt
+  git commit -m "initial commit"
+
+  git lfs checkout --to out a/one.dat "three four.dat"
+  [ "one" = "$(cat out/a/one.dat)" ]
+  [ "three" = "$(cat "out/three four.dat")" ]
+  [ ! -e out/a/b/two.dat ]
+
+  git lfs checkout --to dir/ a
+  [ "one" = "$(cat dir/a/one.dat)" ]
+  [ "two" = "$(cat dir/a/b/two.dat)" ]
+
+  printf 'a/b/two.dat\0three four.dat' | git lfs checkout --stdin --to stdin
+  [ "two" = "$(cat stdin/a/b/two.dat)" ]
+  [ "three" = "$(cat "stdin/three four.dat")" ]
+  [ ! -e stdin/a/one.dat ]
+
+  git lfs checkout --stdin a/one.dat 2>&1 < /dev/null | tee checkout.log
+  grep -- "--stdin requires --to" checkout.log
+
+  # Missing content is reported and not written as a pointer.
+  rm -rf .git/lfs/objects
+  git lfs checkout --to missing a/one.dat plain.txt 2>&1 | tee checkout.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected checkout to fail ..."
+    exit 1
+  fi
+  grep "content not available locally" checkout.log
+  [ ! -e missing/a/one.dat ]
+)
+end_test
+
+begin_test "checkout: with --jobs"
+(
+  set -e
+
+  reponame="checkout-jobs"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  for dir in a b c; do
+    mkdir "$dir"
+    for i in 1 2 3 4 5; do
+      printf "%s" "$dir$i" > "$dir/$i.dat"
+    done
+  done
+  git add .gitattributes */*.dat
+  git commit -m "add files"
+
+  # leave one object missing, which is skipped
+  rm -r a b c
+  missing_oid="$(calc_oid "c5")"
+  rm ".git/lfs/objects/${missing_oid:0:2}/${missing_oid:2:2}/$missing_oid"
+  GIT_LFS_SKIP_SMUDGE=1 git checkout -- .
+
+  git lfs checkout -j 0 2>&1 | tee checkout.log
+  [ "0" != "${PIPESTATUS[0]}" ]
+  grep "\-\-jobs must be at least 1" checkout.log
+
+  git lfs checkout -j 4 2>&1 | tee checkout.log
+  grep "Skipped checkout for 1 file(s)" checkout.log
+
+  for dir in a b c; do
+    for i in 1 2 3 4 5; do
+      [ "$dir$i" = "c5" ] && continue
+      [ "$dir$i" = "$(cat "$dir/$i.dat")" ]
+    done
+  done
+  grep "oid sha256:$missing_oid" c/5.dat
+)
+end_test
+
+begin_test "checkout: with symbolic links"
+(
+  set -e
+
+  reponame="checkout-symlinks"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "a" > dir/a.dat
+  printf "b" > b.dat
+  git add .gitattributes dir b.dat
+  git commit -m "add files"
+
+  # replace the files with links, one to a directory inside the working tree
+  # and one to a file outside it
+  rm -r dir b.dat
+  mkdir inside
+  ln -s inside dir
+  ln -s ../outside.dat b.dat
+
+  git lfs checkout 2>&1 | tee checkout.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  grep "warning: skipped checkout for \"dir/a.dat\": it is beyond a symbolic link" checkout.log
+  grep "warning: skipped checkout for \"b.dat\": it is a symbolic link" checkout.log
+  [ ! -e inside/a.dat ]
+  [ ! -e ../outside.dat ]
+
+  git -c lfs.symlinkpolicy=error lfs checkout 2>&1 | tee checkout.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep "Could not check out \"dir/a.dat\": it is beyond a symbolic link" checkout.log
+  grep "Could not check out 2 file(s); see lfs.symlinkpolicy" checkout.log
+  [ ! -e inside/a.dat ]
+  [ ! -e ../outside.dat ]
+
+  # only the link resolving inside the working tree is followed
+  git -c lfs.symlinkpolicy=follow lfs checkout 2>&1 | tee checkout.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  [ "a" = "$(cat inside/a.dat)" ]
+  grep "warning: skipped checkout for \"b.dat\": it is a symbolic link" checkout.log
+  [ ! -e ../outside.dat ]
+  [ -L b.dat ]
+)
+end_test
+
+begin_test "checkout: warns about case collisions"
+(
+  set -e
+
+  reponame="checkout-case-collisions"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "upper" > A.dat
+  printf "lower" > a.dat
+  git add .gitattributes A.dat a.dat
+  git commit -m "add files"
+
+  git -c core.ignorecase=false lfs checkout 2>&1 | tee checkout.log
+  [ 0 -eq "$(grep -c "differ only in case" checkout.log)" ]
+
+  git -c core.ignorecase=true lfs checkout 2>&1 | tee checkout.log
+  grep "warning: \"A.dat\" ($(calc_oid "upper")) will be overwritten by \"a.dat\" ($(calc_oid "lower")), since their paths differ only in case" checkout.log
+)
+end_test
+
+begin_test "checkout: caches tree scans"
+(
+  set -e
+
+  reponame="checkout-tree-cache"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  tree="$(git rev-parse HEAD^{tree})"
+
+  GIT_TRACE=1 git lfs checkout 2>&1 | tee checkout.log
+  grep "'ls-tree'" checkout.log
+  [ -f ".git/lfs/cache/trees/${tree:0:2}/${tree:2}" ]
+
+  GIT_TRACE=1 git lfs checkout 2>&1 | tee checkout.log
+  [ 0 -eq "$(grep -c "'ls-tree'" checkout.log)" ]
+
+  GIT_TRACE=1 git -c lfs.treecache=false lfs checkout 2>&1 | tee checkout.log
+  grep "'ls-tree'" checkout.log
+)
+end_test
+
 begin_test "checkout: GIT_WORK_TREE"
 (
   set -e
@@ -0,0 +1,62 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "ci: concurrent transfers"
+(
+  set -e
+
+  reponame="ci-concurrency"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs env | grep "ConcurrentTransfers=8"
+  CI=true git lfs env | grep "ConcurrentTransfers=16"
+  GITHUB_ACTIONS=true git lfs env | grep "ConcurrentTransfers=16"
+
+  git config lfs.ci false
+  CI=true git lfs env | grep "ConcurrentTransfers=8"
+
+  git config lfs.ci true
+  git lfs env | grep "ConcurrentTransfers=16"
+  git -c lfs.concurrenttransfers=3 lfs env | grep "ConcurrentTransfers=3"
+)
+end_test
+
+begin_test "ci: summary file"
+(
+  set -e
+
+  reponame="ci-summary"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "bb" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "initial commit"
+
+  # no summary is written outside of CI mode by default
+  git push origin main
+  [ ! -e .git/lfs/summary.jsonl ]
+
+  printf "ccc" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  CI=true git push origin main
+  summary=".git/lfs/summary.jsonl"
+  [ 1 -eq "$(wc -l < "$summary")" ]
+  grep '"operation":"upload"' "$summary"
+  grep '"remote":"origin"' "$summary"
+  grep '"ref":"refs/heads/main"' "$summary"
+  grep '"objects":1,"completed":1,"skipped":0,"bytes":3,"errors":0' "$summary"
+
+  # lfs.summaryfile writes a summary in any mode
+  rm -rf .git/lfs/objects
+  git -c lfs.summaryfile="$TRASHDIR/fetch-summary.jsonl" lfs fetch
+  grep '"operation":"download"' "$TRASHDIR/fetch-summary.jsonl"
+  grep '"objects":3,"completed":3,"skipped":0,"bytes":6,"errors":0' "$TRASHDIR/fetch-summary.jsonl"
+)
+end_test
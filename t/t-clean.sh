@@ -87,3 +87,26 @@ begin_test "clean stdin"
   fi
 )
 end_test
+
+begin_test "clean --batch"
+(
+  set -e
+  clean_setup "batch"
+
+  expected="$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)"
+  expected_size="$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9 | wc -c | tr -d ' ')"
+
+  printf "9 a.dat\nwhatever\n%s dir/b c.dat\n%s\n" "$expected_size" "$expected" |
+    git lfs clean --batch > clean.log
+
+  [ "$(printf "%s\n%s\n%s\n%s" "$expected_size" "$expected" "$expected_size" "$expected")" = "$(cat clean.log)" ]
+  assert_local_object "cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411" 9
+
+  printf "9 a.dat\nwhat" | git lfs clean --batch 2>&1 | tee clean.log
+  if [ "0" -eq "${PIPESTATUS[1]}" ]; then
+    echo >&2 "fatal: expected clean --batch to fail on truncated input"
+    exit 1
+  fi
+  grep "unexpected end of input" clean.log
+)
+end_test
@@ -276,3 +276,155 @@ begin_test "config respects include.* directives when GIT_CONFIG is set"
   grep "Endpoint=http://other-url/rest (auth=none)" env.log
 )
 end_test
+
+begin_test "config: .lfsconfig conditional includes"
+(
+  set -e
+
+  reponame="lfsconfig-includeif"
+  mkdir "$reponame"
+  cd "$reponame"
+
+  git init
+  git remote add origin "https://git-server.com/foo/bar"
+
+  cat >.lfsconfig <<EOF
+[lfs]
+	url = http://default
+[includeIf "hasconfig:remote.*.url:https://git-server.com/**"]
+	path = lfsconfig.remote
+[includeIf "gitdir:$reponame/"]
+	path = lfsconfig.gitdir
+[includeIf "gitdir:other-repo/"]
+	path = lfsconfig.other
+[includeIf "onbranch:main"]
+	path = lfsconfig.branch
+EOF
+  git config -f lfsconfig.remote lfs.url http://remote-url
+  git config -f lfsconfig.gitdir lfs.fetchinclude "gitdir*"
+  git config -f lfsconfig.other lfs.fetchexclude "other*"
+  git config -f lfsconfig.branch lfs.fetchexclude "branch*"
+
+  git checkout -b topic
+  git lfs env 2>&1 | tee env.log
+  grep "Endpoint=http://remote-url (auth=none)" env.log
+  grep "FetchInclude=gitdir\*" env.log
+  grep "FetchExclude=" env.log && exit 1
+
+  git checkout -b main
+  git lfs env 2>&1 | tee env.log
+  grep "FetchExclude=branch\*" env.log
+
+  # Includes are read from the index when the files are not checked out.
+  git add .lfsconfig lfsconfig.*
+  git commit -m "add .lfsconfig"
+  rm .lfsconfig lfsconfig.*
+  git remote set-url origin "https://other-server.com/foo/bar"
+
+  git lfs env 2>&1 | tee env.log
+  grep "Endpoint=http://default (auth=none)" env.log
+  grep "FetchInclude=gitdir\*" env.log
+  grep "FetchExclude=branch\*" env.log
+)
+end_test
+
+begin_test "config: .lfsconfig includes only files in the working tree"
+(
+  set -e
+
+  reponame="lfsconfig-include-outside"
+  mkdir "$reponame"
+  cd "$reponame"
+
+  git init
+  mkdir sub
+  git config -f sub/lfsconfig.inside lfs.fetchinclude "inside*"
+  git config -f ../lfsconfig.outside lfs.fetchexclude "outside*"
+  git config -f "$HOME/lfsconfig.home" lfs.url "http://home"
+
+  cat >.lfsconfig <<EOF
+[include]
+	path = sub/lfsconfig.inside
+	path = ../lfsconfig.outside
+	path = $(cd .. && pwd)/lfsconfig.outside
+	path = ~/lfsconfig.home
+EOF
+
+  git lfs env 2>&1 | tee env.log
+  grep "FetchInclude=inside\*" env.log
+  grep "FetchExclude=outside" env.log && exit 1
+  grep "http://home" env.log && exit 1
+
+  # Nor through a symbolic link.
+  ln -s ../../lfsconfig.outside sub/link
+  printf "[include]\n\tpath = sub/link\n" >.lfsconfig
+  git lfs env 2>&1 | tee env.log
+  grep "FetchExclude=outside" env.log && exit 1
+
+  # The repository's own configuration may still include any file.
+  git config include.path "$HOME/lfsconfig.home"
+  git lfs env 2>&1 | tee env.log
+  grep "http://home" env.log
+)
+end_test
+
+begin_test "config: git lfs config lists settings and where they were set"
+(
+  set -e
+
+  reponame="config-list"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config -f .lfsconfig lfs.fetchinclude "docs"
+  git config -f .lfsconfig lfs.url "https://lfsconfig.example.com"
+  git config lfs.url "$GITSERVER/$reponame.git/info/lfs"
+  git config core.bare false
+
+  git lfs config 2>&1 | tee config.log
+  grep "^file:.*/\.lfsconfig	lfs.fetchinclude=docs$" config.log
+  grep "^file:.git/config	lfs.url=$GITSERVER/$reponame.git/info/lfs$" config.log
+  grep "lfsconfig.example.com" config.log && exit 1
+  grep "core.bare" config.log && exit 1
+
+  git add .lfsconfig
+  git commit -m "add .lfsconfig"
+  rm .lfsconfig
+
+  git lfs config 2>&1 | tee config.log
+  grep "^blob::.lfsconfig	lfs.fetchinclude=docs$" config.log
+)
+end_test
+
+begin_test "config: git lfs config --validate"
+(
+  set -e
+
+  reponame="config-validate"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config lfs.url "$GITSERVER/$reponame.git/info/lfs"
+  git lfs config --validate 2>&1 | tee validate.log
+  grep "Git LFS configuration OK" validate.log
+
+  git config lfs.concurenttransfers 4
+  git config lfs.transfer.maxretries none
+  git config lfs.basictransfersonly true
+  git config lfs.tustransfers true
+  git config -f .lfsconfig lfs.dialtimeout 5
+  git config lfs.pushurl "http://127.0.0.1:1/info/lfs"
+
+  git lfs config --validate >validate.log 2>&1 && exit 1
+  cat validate.log
+  grep "^file:.git/config: lfs.concurenttransfers=4: unknown key; did you mean lfs.concurrenttransfers?$" validate.log
+  grep "^file:.git/config: lfs.transfer.maxretries=none: invalid value: not an integer$" validate.log
+  grep "^file:.git/config: lfs.tustransfers=true: ignored, as lfs.basictransfersonly is set$" validate.log
+  grep "^file:.*/\.lfsconfig: lfs.dialtimeout=5: ignored, as it may not be set in .lfsconfig$" validate.log
+  grep "^endpoint http://127.0.0.1:1/info/lfs (upload): unreachable: " validate.log
+  grep "endpoint $GITSERVER" validate.log && exit 1
+  grep "configuration OK" validate.log && exit 1
+
+  true
+)
+end_test
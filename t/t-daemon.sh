@@ -0,0 +1,119 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+# start_daemon starts "git lfs daemon" on a socket in a short temporary
+# directory, since the paths of test repositories may be too long for Unix
+# sockets, and waits for it to listen.
+start_daemon() {
+  socketdir="$(mktemp -d /tmp/lfs-daemon.XXXXXX)"
+  socket="$socketdir/daemon.sock"
+  git lfs daemon --socket "$socket" > daemon.log 2>&1 &
+
+  for i in $(seq 1 50); do
+    [ -S "$socket" ] && return 0
+    sleep 0.1
+  done
+  cat daemon.log
+  exit 1
+}
+
+begin_test "daemon: status, fetch, has, and shutdown"
+(
+  set -e
+
+  reponame="daemon"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="daemon"
+  oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  rm -rf .git/lfs/objects
+  refute_local_object "$oid"
+
+  start_daemon
+
+  lfstest-daemon-call "$socket" Daemon.Status | tee status.json
+  grep "\"remote\":\"origin\"" status.json
+
+  lfstest-daemon-call "$socket" Daemon.Has "{\"oids\":[\"$oid\"]}" | tee has.json
+  grep "\"$oid\":false" has.json
+
+  lfstest-daemon-call "$socket" Daemon.Fetch '{"refs":["main"],"wait":true}' | tee fetch.json
+  grep "\"errors\":null" fetch.json
+  assert_local_object "$oid" "${#contents}"
+
+  lfstest-daemon-call "$socket" Daemon.Has "{\"oids\":[\"$oid\"]}" | tee has.json
+  grep "\"$oid\":true" has.json
+
+  lfstest-daemon-call "$socket" Daemon.Status | tee status.json
+  grep "\"fetched\":1" status.json
+
+  # Invalid OIDs are refused without taking the daemon down.
+  for bad in ab ../..; do
+    if lfstest-daemon-call "$socket" Daemon.Has "{\"oids\":[\"$bad\"]}" 2> has.log; then
+      exit 1
+    fi
+    grep "invalid OID" has.log
+    if lfstest-daemon-call "$socket" Daemon.Fetch "{\"objects\":[{\"oid\":\"$bad\",\"size\":1}],\"wait\":true}" 2> fetch.log; then
+      exit 1
+    fi
+    grep "invalid OID" fetch.log
+  done
+  lfstest-daemon-call "$socket" Daemon.Status
+
+  lfstest-daemon-call "$socket" Daemon.Shutdown
+  wait
+  [ ! -e "$socket" ]
+  rm -rf "$socketdir"
+)
+end_test
+
+begin_test "daemon: only one daemon runs per socket"
+(
+  set -e
+
+  reponame="daemon-single"
+  git init "$reponame"
+  cd "$reponame"
+
+  start_daemon
+
+  git lfs daemon --socket "$socket" > second.log 2>&1 && exit 1
+  grep "already running" second.log
+
+  lfstest-daemon-call "$socket" Daemon.Shutdown
+  wait
+  rm -rf "$socketdir"
+)
+end_test
+
+begin_test "daemon: fetch reports missing objects"
+(
+  set -e
+
+  reponame="daemon-missing"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  start_daemon
+
+  oid="$(calc_oid "missing")"
+  lfstest-daemon-call "$socket" Daemon.Fetch "{\"objects\":[{\"oid\":\"$oid\",\"size\":7}],\"wait\":true}" | tee fetch.json
+  grep "$oid" fetch.json
+  refute_local_object "$oid"
+
+  lfstest-daemon-call "$socket" Daemon.Status | tee status.json
+  grep "\"failed\":1" status.json
+
+  lfstest-daemon-call "$socket" Daemon.Shutdown
+  wait
+  rm -rf "$socketdir"
+)
+end_test
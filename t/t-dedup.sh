@@ -105,3 +105,70 @@ begin_test "dedup dirty workdir"
   echo "$result" | grep 'Working tree is dirty. Please commit or reset your change.'
 )
 end_test
+
+begin_test "dedup (--dry-run)"
+(
+  set -e
+
+  reponame="dedup_dry_run"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  echo "test data" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  result=$(git lfs dedup --dry-run 2>&1) && true
+  if ( echo $result | grep "This system does not support deduplication." ); then
+    exit
+  fi
+
+  echo "$result" | grep "dedup: 1 file(s) would be de-duplicated"
+  echo "$result" | grep "a.dat"
+  [ 0 -eq "$(echo "$result" | grep -c "Success:")" ]
+)
+end_test
+
+begin_test "dedup --analyze"
+(
+  set -e
+
+  reponame="dedup_analyze"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "shared content" > a.dat
+  mkdir copy
+  printf "shared content" > copy/a.dat
+  printf "unique content" > b.dat
+  git add .gitattributes a.dat copy/a.dat b.dat
+  git commit -m "first commit"
+  git tag v1
+
+  git checkout -b other
+  printf "other content" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+  git checkout main
+
+  shared="$(calc_oid "shared content")"
+  unique="$(calc_oid "unique content")"
+  other="$(calc_oid "other content")"
+
+  before="$(git status --porcelain)"
+  git lfs dedup --analyze 2>&1 | tee analyze.log
+
+  grep "dedup: 1 object(s) are checked out at more than one path, with 1 extra copies (14 B)" analyze.log
+  grep "${shared:0:10}  14 B  a.dat, copy/a.dat" analyze.log
+  grep "dedup: 2 object(s) are identical across 3 local branch(es) and tag(s), and stored once (28 B)" analyze.log
+  grep "${unique:0:10}  14 B  main, other, v1" analyze.log
+  [ "0" -eq "$(grep -c "${other:0:10}" analyze.log)" ]
+  grep "dedup: 3 file(s) could be replaced with clones of their objects, saving up to 42 B" analyze.log
+  grep "copy/a.dat" analyze.log
+
+  # Nothing is changed.
+  [ "$before" = "$(git status --porcelain | grep -v analyze.log)" ]
+)
+end_test
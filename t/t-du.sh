@@ -0,0 +1,80 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "du"
+(
+  set -e
+
+  reponame="du"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p dir
+  printf "aaaa" > dir/a.dat
+  printf "bb" > b.dat
+  git add .gitattributes dir/a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+
+  git checkout -b other
+  printf "cccccccc" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+  git checkout main
+
+  git lfs du 2>&1 | tee du.log
+  grep "Local storage: 14 B, 3 object(s)" du.log
+  grep "reclaimable by prune: 0 B, 0 object(s)" du.log
+  grep "refs/heads/main	6 B, 2 object(s)" du.log
+  grep "refs/heads/other	14 B, 3 object(s)" du.log
+  grep "dir/	4 B, 1 object(s)" du.log
+  grep "b.dat	2 B, 1 object(s)" du.log
+  grep "within a week	14 B, 3 object(s)" du.log
+
+  git lfs du other 2>&1 | tee du.log
+  grep "refs/heads/other	14 B, 3 object(s)" du.log
+  [ "0" -eq "$(grep -c "refs/heads/main" du.log)" ]
+
+  git lfs du --json main > du.json
+  grep '"local":{"objects":3,"size":14}' du.json
+  grep '"refs":\[{"ref":"refs/heads/main","objects":2,"size":6}\]' du.json
+  [ "0" -eq "$(grep -c "remote" du.json)" ]
+)
+end_test
+
+begin_test "du --remote"
+(
+  set -e
+
+  reponame="du-remote"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "aaaa" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  git lfs du --remote 2>&1 | tee du.log
+  grep "Remote storage (origin): 4 B of 1.0 MB quota, 1 object(s)" du.log
+
+  git lfs du --remote --json > du.json
+  grep '"remote":{"remote":"origin","objects":1,"size":4,"quota":1048576}' du.json
+)
+end_test
+
+begin_test "du --remote: server without storage usage"
+(
+  set -e
+
+  reponame="storage-unsupported-du"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs du --remote 2>&1 | tee du.log
+  grep "Remote storage: not available: the server does not report its storage usage" du.log
+  grep "Local storage: 0 B, 0 object(s)" du.log
+)
+end_test
@@ -0,0 +1,78 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "events: push writes object events to --events-fd"
+(
+  set -e
+
+  reponame="events-push"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="events push"
+  oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs push --events-fd 3 origin main 3> ../events-push.log
+  cat ../events-push.log
+
+  for event in queued started progress verified; do
+    grep "\"event\":\"$event\",\"direction\":\"upload\",\"oid\":\"$oid\",\"name\":\"a.dat\",\"size\":11" ../events-push.log
+  done
+  grep '"event":"progress".*"bytes":11' ../events-push.log
+
+  # Objects which the server already has are skipped.
+  git push origin main
+  cd ..
+  clone_repo "$reponame" "$reponame-clone"
+  git lfs push --all --events-fd 3 origin main 3> ../events-push.log
+  cat ../events-push.log
+  grep "\"event\":\"skipped\",\"direction\":\"upload\",\"oid\":\"$oid\"" ../events-push.log
+)
+end_test
+
+begin_test "events: fetch writes object events to GIT_LFS_EVENTS_FD"
+(
+  set -e
+
+  reponame="events-fetch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="events fetch"
+  oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  printf "missing" > b.dat
+  missing="$(calc_oid "missing")"
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin main
+  delete_server_object "$reponame" "$missing"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  GIT_LFS_EVENTS_FD=3 git lfs fetch 3> ../events-fetch.log 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch to fail"
+    exit 1
+  fi
+  cat ../events-fetch.log
+
+  for event in queued started progress verified; do
+    grep "\"event\":\"$event\",\"direction\":\"download\",\"oid\":\"$oid\",\"name\":\"a.dat\"" ../events-fetch.log
+  done
+  grep "\"event\":\"failed\",\"direction\":\"download\",\"oid\":\"$missing\",\"name\":\"b.dat\",\"size\":7,\"error\":" ../events-fetch.log
+
+  # Without a descriptor, no events are written.
+  rm -rf .git/lfs/objects
+  git lfs fetch 3> ../events-fetch.log 2>&1 | tee fetch.log || true
+  assert_local_object "$oid" 12
+  [ ! -s ../events-fetch.log ]
+)
+end_test
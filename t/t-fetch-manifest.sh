@@ -0,0 +1,152 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+setup_manifest_repo () {
+  local reponame="$1"
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a content" > a.dat
+  printf "b content" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "initial commit"
+  git push origin main
+
+  rm -rf .git/lfs/objects
+}
+
+manifest_entry () {
+  printf '{"path": "%s", "oid": "%s", "size": %d}' "$1" "$(calc_oid "$2")" "${#2}"
+}
+
+begin_test "fetch --manifest: downloads exactly the listed objects"
+(
+  set -e
+
+  reponame="fetch-manifest"
+  setup_manifest_repo "$reponame"
+
+  printf '{"objects": [{"oid": "%s", "size": 9}]}' "$(calc_oid "a content")" > ../manifest.json
+
+  git lfs fetch --manifest ../manifest.json 2>&1 | tee fetch.log
+  grep "Fetching 1 object(s) from manifest" fetch.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  refute_local_object "$(calc_oid "b content")"
+
+  git lfs fetch --manifest ../manifest.json origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected --manifest with refs to fail"
+    exit 1
+  fi
+  grep "Cannot combine --manifest" fetch.log
+)
+end_test
+
+begin_test "fetch --manifest --verify: matches tree"
+(
+  set -e
+
+  reponame="fetch-manifest-verify"
+  setup_manifest_repo "$reponame"
+
+  printf '{"objects": [%s, %s]}' "$(manifest_entry a.dat "a content")" \
+    "$(manifest_entry b.dat "b content")" > ../manifest.json
+
+  git lfs fetch --manifest ../manifest.json --verify 2>&1 | tee fetch.log
+  grep "Verified 2 object(s) against manifest" fetch.log
+
+  assert_local_object "$(calc_oid "a content")" 9
+  assert_local_object "$(calc_oid "b content")" 9
+)
+end_test
+
+begin_test "fetch --manifest --verify: detects differences"
+(
+  set -e
+
+  reponame="fetch-manifest-differ"
+  setup_manifest_repo "$reponame"
+
+  # a.dat has different content in the tree, and b.dat is missing
+  printf '{"objects": [%s, %s]}' "$(manifest_entry a.dat "b content")" \
+    "$(manifest_entry c.dat "a content")" > ../manifest.json
+
+  git lfs fetch --manifest ../manifest.json --verify 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected verification to fail"
+    exit 1
+  fi
+  grep "a.dat: main has object $(calc_oid "a content"), manifest has $(calc_oid "b content")" fetch.log
+  grep "c.dat: not a Git LFS file in main" fetch.log
+  grep "b.dat: not in manifest" fetch.log
+  grep "verification against manifest" fetch.log
+
+  # a corrupt object in the local store is detected
+  printf '{"objects": [%s, %s]}' "$(manifest_entry a.dat "a content")" \
+    "$(manifest_entry b.dat "b content")" > ../manifest.json
+  git lfs fetch --manifest ../manifest.json
+  oid="$(calc_oid "a content")"
+  chmod +w ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+  printf "x content" > ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+
+  git lfs fetch --manifest ../manifest.json --verify 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected verification of corrupt object to fail"
+    exit 1
+  fi
+  grep "object $oid: content has 9 bytes with OID $(calc_oid "x content")" fetch.log
+
+  # --verify requires paths, and a manifest
+  printf '{"objects": [{"oid": "%s", "size": 9}]}' "$oid" > ../nopath.json
+  git lfs fetch --manifest ../nopath.json --verify 2>&1 | tee fetch.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep "which --verify requires" fetch.log
+
+  git lfs fetch --verify 2>&1 | tee fetch.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep -- "--verify requires --manifest" fetch.log
+)
+end_test
+
+begin_test "fetch --write-manifest: pins tree for later verification"
+(
+  set -e
+
+  reponame="fetch-write-manifest"
+  setup_manifest_repo "$reponame"
+
+  mkdir dir
+  printf "c content" > dir/c.dat
+  git add dir/c.dat
+  git commit -m "add dir/c.dat"
+  git push origin main
+  rm -rf .git/lfs/objects
+
+  git lfs fetch --write-manifest=../pinned.json origin main 2>&1 | tee fetch.log
+  grep "Wrote manifest for refs/heads/main to ../pinned.json" fetch.log
+  grep "\"ref\": \"$(git rev-parse main)\"" ../pinned.json
+  grep "\"path\": \"dir/c.dat\"" ../pinned.json
+  [ 3 -eq "$(grep -c '"oid"' ../pinned.json)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --manifest=../pinned.json --verify 2>&1 | tee fetch.log
+  grep "Verified 3 object(s) against manifest" fetch.log
+
+  # the pinned manifest no longer matches once a file changes
+  printf "new content" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+
+  git lfs fetch --manifest=../pinned.json --verify 2>&1 | tee fetch.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep "a.dat: main has object $(calc_oid "new content")" fetch.log
+
+  git lfs fetch --write-manifest=../pinned.json --all 2>&1 | tee fetch.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep "requires a single ref" fetch.log
+)
+end_test
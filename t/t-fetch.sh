@@ -260,6 +260,55 @@ begin_test "fetch with include filter overriding exclude filter"
 )
 end_test
 
+begin_test "fetch with negated include/exclude filters"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+  git lfs fetch -I "" -X "*.dat,!b.dat" origin main newbranch
+  refute_local_object "$contents_oid"
+  assert_local_object "$b_oid" 1
+
+  rm -rf .git/lfs/objects
+  git lfs fetch -I "*.dat,!a*" -X "" origin main newbranch
+  refute_local_object "$contents_oid"
+  assert_local_object "$b_oid" 1
+)
+end_test
+
+begin_test "fetch with include/exclude filters from files"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  printf "# sparse profile\n\na.dat\nb.dat\n" > ../include.txt
+  printf "b*\n" > ../exclude.txt
+
+  git lfs fetch --include-from=../include.txt -X "" origin main newbranch
+  assert_local_object "$contents_oid" 1
+  assert_local_object "$b_oid" 1
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --include-from=../include.txt --exclude-from=../exclude.txt \
+    origin main newbranch
+  assert_local_object "$contents_oid" 1
+  refute_local_object "$b_oid"
+
+  rm -rf .git/lfs/objects
+  printf "b.dat\0" | git lfs fetch --include-from=- -X "" origin main newbranch
+  refute_local_object "$contents_oid"
+  assert_local_object "$b_oid" 1
+
+  git lfs fetch --include-from=../missing.txt origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch with a missing pattern file to fail"
+    exit 1
+  fi
+  grep "could not read patterns from" fetch.log
+)
+end_test
+
 begin_test "fetch with missing object"
 (
   set -e
@@ -281,6 +330,71 @@ begin_test "fetch with missing object"
 )
 end_test
 
+begin_test "fetch does not ask again for recently missing objects"
+(
+  set -e
+
+  reponame="fetch-missing-cache"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "%s" "$contents" > a.dat
+  printf "%s" "$b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin main
+
+  delete_server_object "$reponame" "$b_oid"
+  rm -rf .git/lfs/objects
+
+  # missing objects are not recorded by default
+  git lfs fetch origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo "expected fetch to fail"
+    exit 1
+  fi
+  grep "fetch: 1 object(s) permanently missing from the server:" fetch.log
+  [ ! -d .git/lfs/missing ]
+
+  git config lfs.fetchmissinghours 24
+  git lfs fetch origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo "expected fetch to fail"
+    exit 1
+  fi
+  grep "fetch: 1 object(s) permanently missing from the server:" fetch.log
+  grep "  b.dat ($b_oid)" fetch.log
+  grep "$b_oid" .git/lfs/missing/*
+  assert_local_object "$contents_oid" 1
+
+  GIT_TRACE=1 git lfs fetch origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo "expected fetch to fail"
+    exit 1
+  fi
+  grep "fetch: $b_oid is recorded as missing from the server, skipping" fetch.log
+  [ 0 -eq "$(grep -c "objects/batch" fetch.log)" ]
+  grep "fetch: 1 object(s) permanently missing from the server:" fetch.log
+  grep "fetch: 1 of these were not requested again" fetch.log
+
+  # a zero lifetime always asks the server
+  GIT_TRACE=1 git -c lfs.fetchmissinghours=0 lfs fetch origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo "expected fetch to fail"
+    exit 1
+  fi
+  grep "objects/batch" fetch.log
+  grep "fetch: 1 object(s) permanently missing from the server:" fetch.log
+  [ 0 -eq "$(grep -c "were not requested again" fetch.log)" ]
+
+  # the record is dropped once the object is present
+  printf "%s" "$b" | git lfs clean >/dev/null
+  git lfs fetch origin main
+  [ 0 -eq "$(cat .git/lfs/missing/* 2>/dev/null | grep -c "$b_oid")" ]
+)
+end_test
+
 begin_test "fetch-all"
 (
   set -e
@@ -639,3 +753,47 @@ begin_test "fetch fails when LFS directory has wrong permissions"
   grep "error trying to create local storage directory" fetch.log
 )
 end_test
+
+begin_test "fetch falls back to other remotes for missing objects"
+(
+  set -e
+
+  reponame="fetch-fallback-remotes"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-upstream"
+  clone_repo "$reponame" "$reponame"
+  git remote add upstream "$GITSERVER/$reponame-upstream"
+
+  git lfs track "*.dat"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # The object is only on the upstream's server.
+  git push upstream main
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+  assert_server_object "$reponame-upstream" "$contents_oid"
+  refute_server_object "$reponame" "$contents_oid"
+  rm -rf .git/lfs/objects
+
+  git lfs fetch origin main 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo "expected fetch to fail"
+    exit 1
+  fi
+  grep "fetch: 1 object(s) permanently missing from the server:" fetch.log
+  refute_local_object "$contents_oid"
+
+  git config lfs.fetchfallbackremotes "upstream, unknown"
+  git lfs fetch origin main 2>&1 | tee fetch.log
+  grep "ignoring unknown remote \"unknown\"" fetch.log
+  [ 0 -eq "$(grep -c "permanently missing" fetch.log)" ]
+  assert_local_object "$contents_oid" 1
+
+  rm -rf .git/lfs/objects a.dat
+  GIT_LFS_SKIP_SMUDGE=1 git checkout -- a.dat
+  git lfs pull origin 2>&1 | tee pull.log
+  [ "$contents" = "$(cat a.dat)" ]
+  [ -z "$(git status --porcelain a.dat)" ]
+)
+end_test
@@ -137,3 +137,37 @@ begin_test "filter process: hash-object --stdin --path does not hang"
   git add .
 )
 end_test
+
+begin_test "filter process: clone delays smudging of missing objects"
+(
+  set -e
+
+  # Git only delays smudging during checkout from version 2.15.0.
+  ensure_git_version_isnt $VERSION_LOWER "2.15.0"
+
+  reponame="filter_process_delay"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  for name in a b c; do
+    printf "contents_%s" "$name" > "$name.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "add files"
+  git push origin main
+
+  cd ..
+  GIT_TRACE_PACKET=1 git clone "$GITSERVER/$reponame" "$reponame-assert" 2>&1 | tee clone.log
+
+  grep "packet: *clone> can-delay=1" clone.log
+  grep "packet: *clone< status=delayed" clone.log
+  grep "packet: *clone> command=list_available_blobs" clone.log
+
+  cd "$reponame-assert"
+  for name in a b c; do
+    [ "contents_$name" = "$(cat "$name.dat")" ]
+  done
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+)
+end_test
@@ -180,6 +180,30 @@ begin_test "fsck detects invalid pointers"
 )
 end_test
 
+begin_test "fsck distinguishes pointer-sized non-pointers"
+(
+  set -e
+
+  reponame="fsck-pointer-sized"
+  setup_invalid_pointers
+
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:nope\n" > damaged.dat
+  git \
+    -c "filter.lfs.process=" \
+    -c "filter.lfs.clean=cat" \
+    -c "filter.lfs.required=false" \
+    add damaged.dat
+  git commit -m "third commit"
+
+  git lfs fsck --pointers >test.log 2>&1 && exit 1
+
+  grep 'pointer: suspiciousGitObject: "damaged.dat".*is small enough to be a pointer but was not a valid one' test.log
+  grep 'pointer: unexpectedGitObject: "large.dat".*should have been a pointer but was not' test.log
+  [ 0 -eq $(grep -c 'unexpectedGitObject: "damaged.dat"' test.log) ]
+  [ 0 -eq $(grep -c 'suspiciousGitObject: "large.dat"' test.log) ]
+)
+end_test
+
 begin_test "fsck operates on specified refs"
 (
   set -e
@@ -204,3 +228,180 @@ begin_test "fsck operates on specified refs"
   true
 )
 end_test
+
+begin_test "fsck repairs mixed-case object names"
+(
+  set -e
+
+  reponame="fsck-mixed-case"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "a content" > a.dat
+  printf "b content" > b.dat
+  printf "c content" > c.dat
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  aOid="$(calc_oid "a content")"
+  aDir=".git/lfs/objects/${aOid:0:2}/${aOid:2:2}"
+  aUpper="$(echo "$aOid" | tr 'a-f' 'A-F')"
+  mv "$aDir/$aOid" "$aDir/$aUpper"
+
+  # a corrupt duplicate is moved aside
+  bOid="$(calc_oid "b content")"
+  bDir=".git/lfs/objects/${bOid:0:2}/${bOid:2:2}"
+  bUpper="$(echo "$bOid" | tr 'a-f' 'A-F')"
+  cp "$bDir/$bOid" "$bDir/$bUpper"
+  chmod +w "$bDir/$bUpper"
+  printf "CORRUPTION" >> "$bDir/$bUpper"
+
+  # an intact duplicate is removed
+  cOid="$(calc_oid "c content")"
+  cDir=".git/lfs/objects/${cOid:0:2}/${cOid:2:2}"
+  cUpper="$(echo "$cOid" | tr 'a-f' 'A-F')"
+  cp "$cDir/$cOid" "$cDir/$cUpper"
+
+  # check HEAD only, since checking the index would run the clean filter,
+  # writing a.dat's object again
+  git lfs fsck --dry-run --objects HEAD 2>&1 | tee fsck.log
+  grep "objects: invalidName: ${aOid:0:2}/${aOid:2:2}/$aUpper is not a valid object path" fsck.log
+  grep "objects: invalidName: ${bOid:0:2}/${bOid:2:2}/$bUpper is not a valid object path" fsck.log
+  grep "objects: invalidName: ${cOid:0:2}/${cOid:2:2}/$cUpper is not a valid object path" fsck.log
+  [ -e "$aDir/$aUpper" ]
+  [ ! -e "$aDir/$aOid" ]
+
+  git lfs fsck --objects HEAD 2>&1 | tee fsck.log
+  grep "objects: repair: renamed ${aOid:0:2}/${aOid:2:2}/$aUpper to ${aOid:0:2}/${aOid:2:2}/$aOid" fsck.log
+  grep "objects: repair: moving ${bOid:0:2}/${bOid:2:2}/$bUpper to" fsck.log
+  grep "objects: repair: removing ${cOid:0:2}/${cOid:2:2}/$cUpper, a duplicate of ${cOid:0:2}/${cOid:2:2}/$cOid" fsck.log
+  [ "$aOid" = "$(calc_oid_file "$aDir/$aOid")" ]
+  [ "$bOid" = "$(calc_oid_file "$bDir/$bOid")" ]
+  [ -e ".git/lfs/bad/$bUpper" ]
+  [ ! -e "$cDir/$cUpper" ]
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck --objects)" ]
+)
+end_test
+
+begin_test "fsck --connectivity"
+(
+  set -e
+
+  reponame="fsck-connectivity"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat" "*.log"
+  mkdir logs
+  printf "a content" > a.dat
+  printf "b content" > b.dat
+  printf "log content" > logs/c.log
+  git add .gitattributes *.dat logs
+  git commit -m "first commit"
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck --connectivity)" ]
+
+  # an object which no commit refers to
+  printf "stray content" | git lfs clean >/dev/null
+  strayOid="$(calc_oid "stray content")"
+  assert_local_object "$strayOid" 13
+
+  # a referenced object which is missing; the working tree copy is removed
+  # too, since scanning the index would otherwise run the clean filter on it
+  bOid="$(calc_oid "b content")"
+  rm b.dat ".git/lfs/objects/${bOid:0:2}/${bOid:2:2}/$bOid"
+
+  # a missing object which lfs.fetchexclude accounts for
+  cOid="$(calc_oid "log content")"
+  rm logs/c.log ".git/lfs/objects/${cOid:0:2}/${cOid:2:2}/$cOid"
+  git config lfs.fetchexclude "logs"
+
+  git lfs fsck --connectivity 2>&1 | tee fsck.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fsck --connectivity to fail"
+    exit 1
+  fi
+  grep "connectivity: unreachableObject: $strayOid (13 B) is not referenced by any scanned ref" fsck.log
+  grep "connectivity: missingObject: b.dat ($bOid) is referenced but not present locally" fsck.log
+  grep "connectivity: 1 unreachable object(s)" fsck.log
+  grep "connectivity: 1 missing object(s)" fsck.log
+  [ "0" -eq "$(grep -c "c.log" fsck.log)" ]
+
+  # --connectivity alone doesn't check objects or pointers
+  [ "0" -eq "$(grep -c "objects:" fsck.log)" ]
+
+  # objects in the index count as referenced
+  printf "staged content" > d.dat
+  git add d.dat
+  git lfs fsck --connectivity 2>&1 | tee fsck.log || true
+  [ "0" -eq "$(grep -c "$(calc_oid "staged content")" fsck.log)" ]
+)
+end_test
+
+begin_test "fsck --pointers with symbolic links"
+(
+  set -e
+
+  reponame="fsck-symlinks"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "a content" > a.dat
+  ln -s a.dat link.dat
+  git add .gitattributes a.dat link.dat
+  git commit -m "first commit"
+
+  # an ordinary symbolic link in a tracked path isn't a problem
+  [ "Git LFS fsck OK" = "$(git lfs fsck --pointers)" ]
+
+  # a symbolic link whose target is a pointer is
+  aOid="$(calc_oid "a content")"
+  pointer="$(pointer "$aOid" 9)"
+  blob="$(printf "%s" "$pointer" | git hash-object -w --stdin)"
+  git update-index --add --cacheinfo "120000,$blob,pointer.dat"
+  git commit -m "second commit"
+
+  git lfs fsck --pointers >test.log 2>&1 && exit 1
+
+  grep 'pointer: symlinkPointer: "pointer.dat".*is a symbolic link whose target is a pointer' test.log
+  [ 0 -eq $(grep -c '"link.dat"' test.log) ]
+)
+end_test
+
+begin_test "fsck reports case collisions"
+(
+  set -e
+
+  reponame="fsck-case-collisions"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "upper" > A.dat
+  printf "lower" > a.dat
+  printf "upper" > README
+  printf "lower" > readme
+  git add .gitattributes *.dat README readme
+  git commit -m "first commit"
+
+  # collisions are only a problem for Git LFS files when Git knows the
+  # filesystem is case-insensitive
+  git config core.ignorecase false
+  [ "Git LFS fsck OK" = "$(git lfs fsck --pointers)" ]
+
+  git config core.ignorecase true
+  git lfs fsck --pointers >test.log 2>&1 && exit 1
+  grep "pointer: caseCollision: \"A.dat\" (object $(calc_oid "upper")) would be overwritten by \"a.dat\" (object $(calc_oid "lower"))" test.log
+  [ 1 -eq $(grep -c "caseCollision" test.log) ]
+
+  # --paranoid checks every path, regardless of the filesystem
+  git config core.ignorecase false
+  git lfs fsck --paranoid >test.log 2>&1 && exit 1
+  grep "pointer: caseCollision: \"A.dat\"" test.log
+  grep "pointer: caseCollision: \"README\" (blob $(git rev-parse HEAD:README)) would be overwritten by \"readme\" (blob $(git rev-parse HEAD:readme))" test.log
+  [ 2 -eq $(grep -c "caseCollision" test.log) ]
+)
+end_test
@@ -0,0 +1,68 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+reponame="gc-temp"
+
+begin_test "gc-temp"
+(
+  set -e
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  other_oid="$(calc_oid "other")"
+  mkdir -p .git/lfs/tmp .git/lfs/incomplete
+  printf "a" > ".git/lfs/incomplete/${contents_oid}456"
+  printf "o" > ".git/lfs/tmp/$other_oid-2"
+  printf "o" > ".git/lfs/incomplete/${other_oid}123"
+  touch -t 200001010000 ".git/lfs/incomplete/${other_oid}123"
+
+  git lfs gc-temp --dry-run --verbose 2>&1 | tee gc-temp.log
+  grep "gc-temp: 1 file(s) would be removed (1 B), 1 abandoned download(s) would be saved for resuming" gc-temp.log
+  grep "incomplete/${contents_oid}456 (existing, 1 B)" gc-temp.log
+  grep "incomplete/${other_oid}123 (abandoned download, 1 B)" gc-temp.log
+  [ -f ".git/lfs/incomplete/${contents_oid}456" ]
+  [ -f ".git/lfs/incomplete/${other_oid}123" ]
+
+  git lfs gc-temp 2>&1 | tee gc-temp.log
+  grep "gc-temp: removed 1 file(s) (1 B), saved 1 abandoned download(s) for resuming" gc-temp.log
+  [ ! -e ".git/lfs/incomplete/${contents_oid}456" ]
+  [ -f ".git/lfs/tmp/$other_oid-2" ]
+  [ ! -e ".git/lfs/incomplete/${other_oid}123" ]
+  [ -f ".git/lfs/incomplete/$other_oid.part" ]
+
+  # old partial downloads expire
+  touch -t 200001010000 ".git/lfs/incomplete/$other_oid.part"
+  git lfs gc-temp 2>&1 | tee gc-temp.log
+  grep "gc-temp: removed 1 file(s) (1 B), saved 0 abandoned download(s) for resuming" gc-temp.log
+  [ ! -e ".git/lfs/incomplete/$other_oid.part" ]
+)
+end_test
+
+begin_test "gc-temp runs automatically at most once a day"
+(
+  set -e
+
+  cd "$reponame"
+  contents_oid="$(calc_oid "a")"
+
+  printf "a" > ".git/lfs/incomplete/${contents_oid}789"
+  git lfs ls-files
+  [ -f ".git/lfs/incomplete/${contents_oid}789" ]
+
+  touch -t 200001010000 .git/lfs/gc-temp
+  git -c lfs.gctemp.auto=false lfs ls-files
+  [ -f ".git/lfs/incomplete/${contents_oid}789" ]
+
+  git lfs ls-files
+  [ ! -e ".git/lfs/incomplete/${contents_oid}789" ]
+)
+end_test
@@ -0,0 +1,87 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+# write_inspector writes an inspector to the given path which rejects content
+# containing "virus", and annotates the rest with its stage, path, OID and
+# size.
+write_inspector () {
+  cat > "$1" <<-'EOF'
+	#!/bin/sh
+	if grep -q virus; then
+	  echo "found a virus" >&2
+	  exit 1
+	fi
+	echo "scanned $GIT_LFS_INSPECT_STAGE $GIT_LFS_INSPECT_PATH $GIT_LFS_INSPECT_OID $GIT_LFS_INSPECT_SIZE"
+	EOF
+  chmod +x "$1"
+}
+
+begin_test "inspect: clean annotates and rejects objects"
+(
+  set -e
+
+  reponame="inspect-clean"
+  git init "$reponame"
+  cd "$reponame"
+
+  write_inspector ../scan.sh
+  git config lfs.inspector.scan.path "$(cd .. && pwd)/scan.sh"
+
+  git lfs track "*.dat"
+  printf "clean content" > a.dat
+  oid="$(calc_oid "clean content")"
+  git add .gitattributes a.dat 2>&1 | tee add.log
+  grep "Git LFS: a.dat: scan: scanned clean a.dat $oid 13" add.log
+  assert_local_object "$oid" 13
+
+  printf "a virus" > b.dat
+  git add b.dat 2>&1 | tee add.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected add to fail"
+    exit 1
+  fi
+  grep "inspector scan rejected b.dat" add.log
+  grep "found a virus" add.log
+  refute_local_object "$(calc_oid "a virus")"
+
+  # Inspectors which only run at upload are not run on clean.
+  git config lfs.inspector.scan.stages upload
+  git add b.dat
+  assert_local_object "$(calc_oid "a virus")" 7
+)
+end_test
+
+begin_test "inspect: upload rejects objects"
+(
+  set -e
+
+  reponame="inspect-upload"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "clean content" > a.dat
+  printf "a virus" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  write_inspector ../scan.sh
+  git config lfs.inspector.scan.path "$(cd .. && pwd)/scan.sh"
+  git config lfs.inspector.scan.stages upload
+
+  git push origin main 2>&1 | tee push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push to fail"
+    exit 1
+  fi
+  grep "LFS upload rejected:" push.log
+  grep "inspector scan rejected b.dat" push.log
+  grep "Git LFS: a.dat: scan: scanned upload a.dat" push.log
+  refute_server_object "$reponame" "$(calc_oid "a virus")"
+
+  git config lfs.inspector.scan.stages clean
+  git push origin main
+  assert_server_object "$reponame" "$(calc_oid "a virus")"
+)
+end_test
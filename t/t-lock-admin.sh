@@ -0,0 +1,78 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+# write_lock writes an operation lock with the given name held by the process
+# with the given PID on this host.
+write_lock() {
+  mkdir -p .git/lfs/oplocks
+  printf '{"pid":%d,"hostname":"%s","command":"test","created_at":"2021-01-01T00:00:00Z"}\n' \
+    "$2" "$(hostname)" > ".git/lfs/oplocks/$1.lock"
+}
+
+begin_test "lock-admin: lists and breaks stale locks"
+(
+  set -e
+
+  reponame="lock-admin-stale"
+  git init "$reponame"
+  cd "$reponame"
+
+  [ -z "$(git lfs lock-admin)" ]
+
+  # The PID of a shell which has exited.
+  pid="$(sh -c 'echo $$')"
+  write_lock prune "$pid"
+
+  git lfs lock-admin | tee locks.log
+  grep "prune	process $pid on" locks.log
+  grep "(stale)" locks.log
+
+  git lfs lock-admin --break | tee break.log
+  grep "Broke lock \"prune\"" break.log
+  [ ! -e .git/lfs/oplocks/prune.lock ]
+)
+end_test
+
+begin_test "lock-admin: prune waits for a held lock"
+(
+  set -e
+
+  reponame="lock-admin-held"
+  git init "$reponame"
+  cd "$reponame"
+  git commit --allow-empty -m "initial commit"
+
+  # This shell holds the lock.
+  write_lock prune "$$"
+
+  git -c lfs.operationlocktimeout=0 lfs prune > prune.log 2>&1 && exit 1
+  grep "\"prune\" is locked by process $$" prune.log
+  grep "git lfs lock-admin --break prune" prune.log
+
+  # Only stale locks are broken without being named.
+  git lfs lock-admin --break
+  [ -e .git/lfs/oplocks/prune.lock ]
+
+  git lfs lock-admin --break prune
+  git -c lfs.operationlocktimeout=0 lfs prune
+  [ -z "$(git lfs lock-admin)" ]
+)
+end_test
+
+begin_test "lock-admin: stale locks are broken automatically"
+(
+  set -e
+
+  reponame="lock-admin-auto"
+  git init "$reponame"
+  cd "$reponame"
+  git commit --allow-empty -m "initial commit"
+
+  pid="$(sh -c 'echo $$')"
+  write_lock migrate "$pid"
+
+  git -c lfs.operationlocktimeout=0 lfs prune
+  [ -z "$(git lfs lock-admin)" ]
+)
+end_test
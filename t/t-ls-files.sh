@@ -515,4 +515,50 @@ begin_test "ls-files: not affected by lfs.fetchexclude"
   git config lfs.fetchexclude '*'
   [ "6bbd052ab0 * missing.dat" = "$(git lfs ls-files)" ]
 )
-end_test
\ No newline at end of file
+end_test
+begin_test "ls-files: --worktree"
+(
+  set -e
+
+  reponame="ls-files-worktree"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  for name in a b c d e; do
+    printf "%s" "$name" > "$name.dat"
+  done
+  git add *.dat
+  git commit -m "add files"
+
+  # b.dat is left as its pointer, c.dat is changed without changing its size,
+  # d.dat is replaced by another pointer, and e.dat is removed.
+  git show HEAD:b.dat > b.dat
+  printf "x" > c.dat
+  git lfs pointer --file=a.dat > d.dat
+  rm e.dat
+
+  git lfs ls-files --worktree 2>&1 | tee ls.log
+  grep "ca978112ca \* a.dat \[hydrated\]" ls.log
+  grep "3e23e81600 - b.dat \[pointer\]" ls.log
+  grep "2e7d2c03a9 \* c.dat \[modified\]" ls.log
+  grep "18ac3e7343 - d.dat \[modified\]" ls.log
+  grep "3f79bb7b43 - e.dat \[missing\]" ls.log
+
+  git lfs ls-files --name-only --worktree-state=pointer,missing 2>&1 | tee ls.log
+  [ "$(printf "b.dat [pointer]\ne.dat [missing]")" = "$(cat ls.log)" ]
+
+  git lfs ls-files --debug --worktree -I c.dat 2>&1 | tee ls.log
+  grep "worktree: modified" ls.log
+
+  git lfs ls-files --worktree-state=smudged 2>&1 | tee ls.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected an unknown state to fail ..."
+    exit 1
+  fi
+  grep "unknown --worktree-state \"smudged\"" ls.log
+)
+end_test
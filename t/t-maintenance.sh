@@ -0,0 +1,193 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "maintenance pack: packs small objects"
+(
+  set -e
+
+  reponame="maintenance-pack"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  small="small"
+  small_oid="$(calc_oid "$small")"
+  printf "%s" "$small" > small.dat
+  large="$(printf 'x%.0s' $(seq 1 2048))"
+  large_oid="$(calc_oid "$large")"
+  printf "%s" "$large" > large.dat
+  git add .gitattributes small.dat large.dat
+  git commit -m "add files"
+
+  git lfs maintenance pack --max-size=1KiB | tee pack.log
+  grep "1 object(s) packed" pack.log
+  refute_local_object "$small_oid"
+  assert_local_object "$large_oid" 2048
+
+  git lfs fsck
+  git push origin main
+  assert_server_object "$reponame" "$small_oid"
+
+  rm small.dat
+  git checkout -- small.dat
+  [ "$small" = "$(cat small.dat)" ]
+)
+end_test
+
+begin_test "maintenance pack: uses lfs.pack.maxobjectsize"
+(
+  set -e
+
+  reponame="maintenance-pack-config"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  contents="small"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git config lfs.pack.maxobjectsize 1
+  git lfs maintenance pack | tee pack.log
+  grep "0 object(s) packed" pack.log
+  assert_local_object "$contents_oid" 5
+
+  git config lfs.pack.maxobjectsize bogus
+  git lfs maintenance pack 2>&1 | tee pack.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs maintenance pack' to fail ..."
+    exit 1
+  fi
+  grep "Invalid maximum object size \"bogus\"" pack.log
+)
+end_test
+
+begin_test "maintenance run: runs tasks due at a schedule"
+(
+  set -e
+
+  reponame="maintenance-run"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git commit --allow-empty -m "initial commit"
+
+  git lfs maintenance run --schedule=hourly | tee run.log
+  grep "maintenance: running prefetch" run.log
+  [ 0 -eq "$(grep -c "maintenance: running prune" run.log)" ]
+
+  git lfs maintenance run --schedule=weekly | tee run.log
+  grep "maintenance: running gc-temp" run.log
+  grep "maintenance: running verify" run.log
+  [ 0 -eq "$(grep -c "maintenance: running prune" run.log)" ]
+  [ 0 -eq "$(grep -c "maintenance: running pack" run.log)" ]
+
+  git config lfs.maintenance.prune.enabled true
+  git config lfs.maintenance.prune.schedule weekly
+  git lfs maintenance run --schedule=daily | tee run.log
+  [ 0 -eq "$(grep -c "maintenance: running prune" run.log)" ]
+  git lfs maintenance run --schedule=weekly | tee run.log
+  grep "maintenance: running prune" run.log
+
+  git config lfs.maintenance.prefetch.enabled false
+  git config lfs.maintenance.pack.enabled true
+  git config lfs.maintenance.pack.schedule hourly
+  git lfs maintenance run --schedule=hourly | tee run.log
+  [ 0 -eq "$(grep -c "maintenance: running prefetch" run.log)" ]
+  grep "maintenance: running pack" run.log
+
+  git lfs maintenance run --schedule=monthly 2>&1 | tee run.log
+  grep "Invalid schedule \"monthly\"" run.log
+  git lfs maintenance run --task=bogus 2>&1 | tee run.log
+  grep "Unknown maintenance task \"bogus\"" run.log
+)
+end_test
+
+begin_test "maintenance run: compacts push journals"
+(
+  set -e
+
+  reponame="maintenance-pushed-journal"
+  git init "$reponame"
+  cd "$reponame"
+  git commit --allow-empty -m "initial commit"
+
+  oid="$(calc_oid "journal")"
+  mkdir -p .git/lfs/pushed
+  printf "%s\n%s\n%s" "$oid" "$oid" "${oid:0:10}" > .git/lfs/pushed/server
+
+  git lfs maintenance run --task=pushed-journal | tee run.log
+  grep "pushed-journal: dropped 2 redundant line(s)" run.log
+  [ "$oid" = "$(cat .git/lfs/pushed/server)" ]
+)
+end_test
+
+begin_test "maintenance start and stop: schedule registered repositories"
+(
+  set -e
+
+  # Stand in for the user's crontab.
+  mkdir -p bin
+  cat > bin/crontab <<-\SCRIPT
+#!/bin/sh
+if [ "$1" = "-l" ]; then
+  if [ -f "$HOME/crontab.broken" ]; then
+    echo "crontab: not allowed to read crontab" >&2
+    exit 1
+  fi
+  if [ ! -f "$HOME/crontab" ]; then
+    echo "no crontab for $(whoami)" >&2
+    exit 1
+  fi
+  cat "$HOME/crontab"
+else
+  cat > "$HOME/crontab"
+fi
+SCRIPT
+  chmod +x bin/crontab
+  export PATH="$(pwd)/bin:$PATH"
+  printf "# mine\n\n0 0 * * * other\n\n" > "$HOME/crontab"
+  cp "$HOME/crontab" "$HOME/crontab.orig"
+
+  reponame="maintenance-start"
+  git init "$reponame"
+  cd "$reponame"
+  git commit --allow-empty -m "initial commit"
+
+  git lfs maintenance start
+  git lfs maintenance start
+  [ "$(pwd)" = "$(git config --global --get-all lfs.maintenance.repo)" ]
+  grep "0 0 \* \* \* other" "$HOME/crontab"
+  [ 1 -eq "$(grep -c "maintenance run --registered --schedule=hourly" "$HOME/crontab")" ]
+  grep "maintenance run --registered --schedule=weekly" "$HOME/crontab"
+
+  (cd .. && git lfs maintenance run --registered --task=gc-temp) | tee run.log
+  grep "maintenance: $(pwd)" run.log
+  grep "gc-temp: removed" run.log
+
+  git lfs maintenance stop
+  [ -z "$(git config --global --get-all lfs.maintenance.repo)" ]
+  [ 0 -eq "$(grep -c "maintenance run" "$HOME/crontab")" ]
+  cmp "$HOME/crontab.orig" "$HOME/crontab"
+
+  # A crontab which cannot be read is left alone.
+  touch "$HOME/crontab.broken"
+  git lfs maintenance start 2>&1 | tee start.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs maintenance start' to fail ..."
+    exit 1
+  fi
+  grep "not allowed to read crontab" start.log
+  rm "$HOME/crontab.broken"
+  cmp "$HOME/crontab.orig" "$HOME/crontab"
+
+  # Having no crontab yet is not an error.
+  rm "$HOME/crontab"
+  git lfs maintenance start
+  grep "maintenance run --registered --schedule=daily" "$HOME/crontab"
+  git lfs maintenance stop
+  [ ! -s "$HOME/crontab" ]
+)
+end_test
@@ -0,0 +1,123 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+setup_manifest_repo () {
+  set -e
+
+  reponame="$1"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "a content" > a.dat
+  printf "b content" > dir/b.dat
+  printf "plain" > plain.txt
+  git add .gitattributes a.dat dir plain.txt
+  git commit -m "initial commit"
+  git tag v1.0
+}
+
+begin_test "manifest: sha256sums"
+(
+  set -e
+
+  setup_manifest_repo "manifest-sha256sums"
+
+  git lfs manifest v1.0 > ../SHA256SUMS
+  [ "$(sha256sum a.dat dir/b.dat)" = "$(cat ../SHA256SUMS)" ]
+  sha256sum -c ../SHA256SUMS
+
+  # The objects need not be present locally.
+  rm -rf .git/lfs/objects
+  git lfs manifest -o ../objectless.sums v1.0
+  cmp ../SHA256SUMS ../objectless.sums
+
+  # Paths are relative to the current directory.
+  cd dir
+  git lfs manifest v1.0 . | tee ../../dir.sums
+  [ "$(calc_oid "b content")  dir/b.dat" = "$(cat ../../dir.sums)" ]
+)
+end_test
+
+begin_test "manifest: in-toto"
+(
+  set -e
+
+  setup_manifest_repo "manifest-in-toto"
+
+  git lfs manifest --format=in-toto -o ../statement.json v1.0
+  cat ../statement.json
+
+  grep '"_type": "https://in-toto.io/Statement/v1"' ../statement.json
+  grep '"predicateType": "https://git-lfs.github.com/spec/manifest/v1"' ../statement.json
+  grep '"name": "dir/b.dat"' ../statement.json
+  grep "\"sha256\": \"$(calc_oid "a content")\"" ../statement.json
+  grep '"ref": "v1.0"' ../statement.json
+  grep "\"commit\": \"$(git rev-parse v1.0)\"" ../statement.json
+  [ 0 -eq "$(grep -c "plain.txt" ../statement.json)" ]
+)
+end_test
+
+begin_test "manifest: --verify"
+(
+  set -e
+
+  setup_manifest_repo "manifest-verify"
+
+  git lfs manifest --verify -o ../SHA256SUMS v1.0
+  [ 2 -eq "$(wc -l < ../SHA256SUMS)" ]
+
+  oid="$(calc_oid "b content")"
+  delete_local_object "$oid"
+  rm ../SHA256SUMS
+  git lfs manifest --verify -o ../SHA256SUMS v1.0 2>&1 | tee manifest.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected manifest --verify to fail ..."
+    exit 1
+  fi
+  grep "manifest: missingObject: dir/b.dat ($oid) is not present locally" manifest.log
+  [ ! -e ../SHA256SUMS ]
+)
+end_test
+
+begin_test "manifest: --signature"
+(
+  set -e
+
+  setup_manifest_repo "manifest-signature"
+
+  rm -f ../signing-key ../signing-key.pub
+  ssh-keygen -q -t ed25519 -N "" -C "signing key" -f ../signing-key
+  git config gpg.format ssh
+  git config user.signingkey "$(cd .. && pwd)/signing-key"
+  echo "signer $(cat ../signing-key.pub)" > ../allowed-signers
+
+  git lfs manifest -o ../SHA256SUMS --signature=../SHA256SUMS.sig v1.0
+  ssh-keygen -Y verify -f ../allowed-signers -I signer -n git \
+    -s ../SHA256SUMS.sig < ../SHA256SUMS
+)
+end_test
+
+begin_test "manifest: errors"
+(
+  set -e
+
+  setup_manifest_repo "manifest-errors"
+
+  git lfs manifest --format=json v1.0 2>&1 | tee manifest.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected an unknown format to fail ..."
+    exit 1
+  fi
+  grep "unknown manifest format \"json\"" manifest.log
+
+  git lfs manifest no-such-ref 2>&1 | tee manifest.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected an unknown tree-ish to fail ..."
+    exit 1
+  fi
+  grep "not a valid tree-ish: \"no-such-ref\"" manifest.log
+)
+end_test
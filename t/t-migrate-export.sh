@@ -465,3 +465,23 @@ begin_test "migrate export (invalid pointer)"
   git lfs migrate export --include="*" --everything --yes
 )
 end_test
+
+begin_test "migrate export (--dry-run)"
+(
+  set -e
+
+  setup_multiple_local_branches_tracked
+
+  md_oid="$(calc_oid "$(cat a.md)")"
+  main="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate export --include="*.md" --dry-run 2>&1 | tee migrate.log
+  grep "migrate: [0-9]* commit(s) rewritten, 1 ref(s) would be updated" migrate.log
+  grep "refs/heads/main  *$main" migrate.log
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  assert_local_object "$md_oid" "140"
+  [ -z "$(git status --porcelain -uno)" ]
+)
+end_test
@@ -263,3 +263,118 @@ EOF
   assert_local_object "$bar_oid" "3"
 )
 end_test
+
+begin_test "migrate import --no-rewrite (--dry-run)"
+(
+  set -e
+
+  setup_local_branch_with_gitattrs
+
+  main="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import --no-rewrite --dry-run *.txt 2>&1 | tee migrate.log
+  grep "migrate: 1 commit(s) added, 1 ref(s) would be updated" migrate.log
+  grep "refs/heads/main  *$main" migrate.log
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+)
+end_test
+
+begin_test "migrate import --no-rewrite (--stdin)"
+(
+  set -e
+
+  setup_multiple_local_branches_with_gitattrs
+
+  txt_oid="$(calc_oid "$(git cat-file -p :a.txt)")"
+  md_oid="$(calc_oid "$(git cat-file -p :a.md)")"
+
+  printf "a.txt\n" | git lfs migrate import --no-rewrite --stdin --yes
+
+  assert_pointer "refs/heads/main" "a.txt" "$txt_oid" "120"
+  refute_pointer "refs/heads/main" "a.md"
+
+  printf "a.md\0" | git lfs migrate import --no-rewrite --stdin -z --yes
+
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  [ "a.md: convert to Git LFS" = "$(git log -1 --pretty=format:%s)" ]
+
+  printf "" | git lfs migrate import --no-rewrite --stdin 2>&1 | tee ../migrate.log
+  if [ "0" -eq "${PIPESTATUS[1]}" ]; then
+    echo >&2 "fatal: expected an empty list of files to fail ..."
+    exit 1
+  fi
+  grep "expected one or more files with --no-rewrite" ../migrate.log
+
+  printf "a.txt\n" | git lfs migrate import --stdin 2>&1 | tee ../migrate.log
+  if [ "0" -eq "${PIPESTATUS[1]}" ]; then
+    echo >&2 "fatal: expected --stdin without --no-rewrite to fail ..."
+    exit 1
+  fi
+  grep "fatal: --stdin requires --no-rewrite" ../migrate.log
+)
+end_test
+
+begin_test "migrate import --no-rewrite (message template)"
+(
+  set -e
+
+  setup_multiple_local_branches_with_gitattrs
+
+  git lfs migrate import --no-rewrite --yes \
+    -m "Convert %n file(s) to 100%% Git LFS: %f" a.txt a.md
+
+  [ "Convert 2 file(s) to 100% Git LFS: a.txt,a.md" = "$(git log -1 --pretty=format:%s)" ]
+)
+end_test
+
+begin_test "migrate import --no-rewrite (--gpg-sign)"
+(
+  set -e
+
+  setup_local_branch_with_gitattrs
+
+  txt_oid="$(calc_oid "$(git cat-file -p :a.txt)")"
+
+  cat > ../fake-gpg <<-\EOF
+	#!/bin/sh
+	cat >/dev/null
+	echo "[GNUPG:] SIG_CREATED D 1 8 00 0 0 $*" >&2
+	printf -- "-----BEGIN PGP SIGNATURE-----\n\n$*\n-----END PGP SIGNATURE-----\n"
+	EOF
+  chmod +x ../fake-gpg
+  git config gpg.program "$(cd .. && pwd)/fake-gpg"
+
+  git lfs migrate import --no-rewrite --yes --gpg-sign=ABCD1234 a.txt
+
+  assert_pointer "refs/heads/main" "a.txt" "$txt_oid" "120"
+  git cat-file -p refs/heads/main | grep "^gpgsig -----BEGIN PGP SIGNATURE-----"
+  git cat-file -p refs/heads/main | grep "ABCD1234"
+  [ "a.txt: convert to Git LFS" = "$(git log -1 --pretty=format:%s)" ]
+  git fsck
+)
+end_test
+
+begin_test "migrate import --no-rewrite (bare repository without working copy)"
+(
+  set -e
+
+  setup_local_branch_with_gitattrs
+
+  txt_oid="$(calc_oid "$(git cat-file -p :a.txt)")"
+  main="$(git rev-parse refs/heads/main)"
+
+  repo="$(pwd)"
+  cd ..
+  rm -rf bare.git
+  git clone --bare "$repo" bare.git
+  cd bare.git
+
+  echo "a.txt" | git lfs migrate import --no-rewrite --stdin --yes
+
+  assert_pointer "refs/heads/main" "a.txt" "$txt_oid" "120"
+  assert_local_object "$txt_oid" "120"
+  [ "$main" = "$(git rev-parse refs/heads/main~1)" ]
+  git fsck
+)
+end_test
@@ -1001,3 +1001,23 @@ begin_test "migrate import (non-standard refs)"
   assert_local_object "$md_feature_oid" "30"
 )
 end_test
+
+begin_test "migrate import (--dry-run)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+  feature="$(git rev-parse refs/heads/my-feature)"
+
+  git lfs migrate import --everything --dry-run 2>&1 | tee migrate.log
+  grep "migrate: [0-9]* commit(s) rewritten, 2 ref(s) would be updated" migrate.log
+  grep "refs/heads/main  *$main" migrate.log
+  grep "refs/heads/my-feature  *$feature" migrate.log
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  [ "$feature" = "$(git rev-parse refs/heads/my-feature)" ]
+  [ -z "$(git status --porcelain -uno)" ]
+)
+end_test
@@ -0,0 +1,108 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/fixtures/migrate.sh"
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "migrate import (--replace)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+  feature="$(git rev-parse refs/heads/my-feature)"
+  md_main_oid="$(calc_oid "$(git cat-file -p refs/heads/main:a.md)")"
+  md_feature_oid="$(calc_oid "$(git cat-file -p refs/heads/my-feature:a.md)")"
+
+  git lfs migrate import --everything --include="*.md" --replace 2>&1 | tee ../migrate.log
+  grep "migrate: Creating replace refs" ../migrate.log
+
+  # No ref has moved, but Git shows the rewritten history in their place.
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  [ "$feature" = "$(git rev-parse refs/heads/my-feature)" ]
+  [ 2 -eq "$(git for-each-ref refs/replace | wc -l)" ]
+  git rev-parse "refs/replace/$main"
+  git rev-parse "refs/replace/$feature"
+
+  assert_pointer "refs/heads/main" "a.md" "$md_main_oid" "140"
+  assert_pointer "refs/heads/my-feature" "a.md" "$md_feature_oid" "30"
+  git --no-replace-objects cat-file -p refs/heads/main:a.md | grep "spec/v1" && exit 1
+  [ -z "$(git status --porcelain -uno)" ]
+
+  # New commits are made on top of the rewritten trees.
+  base64 < /dev/urandom | head -c 50 > a.md
+  md_new_oid="$(calc_oid "$(cat a.md)")"
+  git add a.md
+  git commit -m "change a.md after the migration"
+
+  [ "$main" = "$(git rev-parse refs/heads/main~1)" ]
+  GIT_NO_REPLACE_OBJECTS=1 assert_pointer "refs/heads/main" "a.md" "$md_new_oid" "50"
+  GIT_NO_REPLACE_OBJECTS=1 git cat-file -p refs/heads/main:.gitattributes | grep "^\*.md filter=lfs"
+  git fsck
+)
+end_test
+
+begin_test "migrate import (--replace, --dry-run)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  git lfs migrate import --include="*.md" --replace --dry-run 2>&1 | tee ../migrate.log
+  grep "migrate: 1 commit(s) rewritten, 1 replace ref(s) would be created" ../migrate.log
+  grep "refs/replace/$(git rev-parse refs/heads/main)" ../migrate.log
+
+  [ -z "$(git for-each-ref refs/replace)" ]
+)
+end_test
+
+begin_test "migrate import (--replace, --object-dir)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+  md_oid="$(calc_oid "$(git cat-file -p refs/heads/main:a.md)")"
+  objects="$(cd .. && pwd)/lfs-objects"
+  rm -rf "$objects"
+
+  git lfs migrate import --include="*.md" --replace --object-dir="$objects"
+
+  grep -x "$objects" .git/objects/info/alternates
+
+  # The rewritten commit is only in the alternate object directory.
+  rewritten="$(git rev-parse "refs/replace/$main")"
+  [ -f "$objects/${rewritten:0:2}/${rewritten:2}" ]
+  [ ! -f ".git/objects/${rewritten:0:2}/${rewritten:2}" ]
+
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  git fsck
+
+  # The alternate is only added once.
+  git lfs migrate import --include="*.txt" --replace --object-dir="$objects"
+  [ 1 -eq "$(grep -cx "$objects" .git/objects/info/alternates)" ]
+)
+end_test
+
+begin_test "migrate import (--replace with incompatible options)"
+(
+  set -e
+
+  setup_local_branch_with_gitattrs
+
+  git lfs migrate import --replace --no-rewrite a.txt 2>&1 | tee ../migrate.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected --replace with --no-rewrite to fail ..."
+    exit 1
+  fi
+  grep "fatal: --replace and --no-rewrite cannot be combined" ../migrate.log
+
+  git lfs migrate import --object-dir=../objects 2>&1 | tee ../migrate.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected --object-dir without --replace to fail ..."
+    exit 1
+  fi
+  grep "fatal: --object-dir requires --replace" ../migrate.log
+)
+end_test
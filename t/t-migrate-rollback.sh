@@ -0,0 +1,143 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/fixtures/migrate.sh"
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "migrate rollback"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+  feature="$(git rev-parse refs/heads/my-feature)"
+  md_oid="$(calc_oid "$(git cat-file -p :a.md)")"
+
+  git lfs migrate import --everything
+
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  [ "$main" != "$(git rev-parse refs/heads/main)" ]
+
+  git lfs migrate rollback 2>&1 | tee ../rollback.log
+  grep "migrate: Rolling back refs" ../rollback.log
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  [ "$feature" = "$(git rev-parse refs/heads/my-feature)" ]
+  refute_pointer "refs/heads/main" "a.md"
+  [ "$md_oid" = "$(calc_oid "$(cat a.md)")" ]
+  [ -z "$(git status --porcelain -uno)" ]
+
+  git lfs migrate rollback 2>&1 | tee ../rollback.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected rollback to fail ..."
+    exit 1
+  fi
+  grep "migrate: there is no migration to roll back" ../rollback.log
+)
+end_test
+
+begin_test "migrate rollback (branch created since)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import
+  git branch created-since main
+
+  git lfs migrate rollback
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  [ "$main" = "$(git rev-parse refs/heads/created-since)" ]
+)
+end_test
+
+begin_test "migrate rollback (moved ref)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  git lfs migrate import
+  git commit --allow-empty -m "after the migration"
+  moved="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate rollback 2>&1 | tee ../rollback.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected rollback to fail ..."
+    exit 1
+  fi
+  grep "refs/heads/main has changed since the migration" ../rollback.log
+
+  [ "$moved" = "$(git rev-parse refs/heads/main)" ]
+)
+end_test
+
+begin_test "migrate rollback (--dry-run)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import
+  migrated="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate rollback --dry-run 2>&1 | tee ../rollback.log
+  grep "migrate: 1 ref(s) would be rolled back" ../rollback.log
+  grep "refs/heads/main  *$migrated  *$main" ../rollback.log
+  [ "$migrated" = "$(git rev-parse refs/heads/main)" ]
+
+  git lfs migrate rollback
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+)
+end_test
+
+begin_test "migrate rollback (successive imports)"
+(
+  set -e
+
+  setup_multiple_local_branches
+
+  main="$(git rev-parse refs/heads/main)"
+  md_oid="$(calc_oid "$(git cat-file -p :a.md)")"
+  txt_oid="$(calc_oid "$(git cat-file -p :a.txt)")"
+
+  git lfs migrate import --include="*.md"
+  after_md="$(git rev-parse refs/heads/main)"
+  git lfs migrate import --include="*.txt"
+
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  assert_pointer "refs/heads/main" "a.txt" "$txt_oid" "120"
+
+  git lfs migrate rollback
+  [ "$after_md" = "$(git rev-parse refs/heads/main)" ]
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  refute_pointer "refs/heads/main" "a.txt"
+
+  git lfs migrate rollback
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  refute_pointer "refs/heads/main" "a.md"
+)
+end_test
+
+begin_test "migrate rollback (--no-rewrite)"
+(
+  set -e
+
+  setup_local_branch_with_gitattrs
+
+  main="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import --no-rewrite --yes a.txt
+  [ "$main" = "$(git rev-parse refs/heads/main~1)" ]
+
+  git lfs migrate rollback
+
+  [ "$main" = "$(git rev-parse refs/heads/main)" ]
+  refute_pointer "refs/heads/main" "a.txt"
+)
+end_test
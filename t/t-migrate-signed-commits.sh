@@ -0,0 +1,123 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/fixtures/migrate.sh"
+. "$(dirname "$0")/testlib.sh"
+
+# SSH signatures need Git 2.34.0 or later.
+ensure_git_version_isnt $VERSION_LOWER "2.34.0"
+
+# setup_signed_commits creates a repository like setup_multiple_local_branches,
+# with an SSH signing key, in which each commit on "main" is signed.
+setup_signed_commits() {
+  set -e
+
+  reponame="migrate-signed-commits"
+  remove_and_create_local_repo "$reponame"
+
+  rm -f ../signing-key ../signing-key.pub
+  ssh-keygen -q -t ed25519 -N "" -C "signing key" -f ../signing-key
+  git config gpg.format ssh
+  git config user.signingkey "$(cd .. && pwd)/signing-key"
+  echo "$(git config user.email) $(cat ../signing-key.pub)" > ../allowed-signers
+  git config gpg.ssh.allowedSignersFile "$(cd .. && pwd)/allowed-signers"
+
+  base64 < /dev/urandom | head -c 120 > a.txt
+  git add a.txt
+  git commit -S -m "initial commit"
+
+  base64 < /dev/urandom | head -c 140 > a.md
+  git add a.md
+  git commit -S -m "add a.md"
+
+  git verify-commit refs/heads/main
+  git verify-commit refs/heads/main~1
+}
+
+begin_test "migrate import (--signed-commits=keep)"
+(
+  set -e
+
+  setup_signed_commits
+
+  git lfs migrate import --include="*.md" 2>&1 | tee ../migrate.log
+  grep "migrate: warning: the signatures of 2 rewritten commit(s) are no longer valid" ../migrate.log
+  grep "add a.md" ../migrate.log
+
+  git cat-file -p refs/heads/main | grep "^gpgsig "
+  if git verify-commit refs/heads/main || git verify-commit refs/heads/main~1; then
+    echo >&2 "fatal: expected the kept signatures to be invalid ..."
+    exit 1
+  fi
+)
+end_test
+
+begin_test "migrate import (--signed-commits=strip)"
+(
+  set -e
+
+  setup_signed_commits
+
+  original="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import --everything --signed-commits=strip 2>&1 | tee ../migrate.log
+  grep "migrate: warning: removed the signatures of 2 rewritten commit(s)" ../migrate.log
+  grep "$original  *add a.md" ../migrate.log
+
+  [ 0 -eq "$(git cat-file -p refs/heads/main | grep -c "^gpgsig ")" ]
+  [ 0 -eq "$(git cat-file -p refs/heads/main~1 | grep -c "^gpgsig ")" ]
+  git fsck
+)
+end_test
+
+begin_test "migrate import (--signed-commits=resign)"
+(
+  set -e
+
+  setup_signed_commits
+
+  md_oid="$(calc_oid "$(git cat-file -p :a.md)")"
+
+  git lfs migrate import --everything --signed-commits=resign 2>&1 | tee ../migrate.log
+  grep "migrate: re-signed 2 rewritten commit(s) with your signing key" ../migrate.log
+
+  assert_pointer "refs/heads/main" "a.md" "$md_oid" "140"
+  git verify-commit refs/heads/main
+  git verify-commit refs/heads/main~1
+  git fsck
+)
+end_test
+
+begin_test "migrate export (--signed-commits=resign)"
+(
+  set -e
+
+  setup_signed_commits
+
+  git lfs migrate import --everything --signed-commits=strip
+  git commit -S --allow-empty -m "signed after import"
+  git lfs migrate export --everything --include="*.md" --signed-commits=resign 2>&1 | tee ../migrate.log
+  grep "migrate: re-signed 1 rewritten commit(s) with your signing key" ../migrate.log
+
+  refute_pointer "refs/heads/main" "a.md"
+  git verify-commit refs/heads/main
+)
+end_test
+
+begin_test "migrate import (--signed-commits with an unknown mode)"
+(
+  set -e
+
+  setup_signed_commits
+
+  original="$(git rev-parse refs/heads/main)"
+
+  git lfs migrate import --signed-commits=verbatim 2>&1 | tee ../migrate.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected an unknown mode to fail ..."
+    exit 1
+  fi
+  grep "unknown --signed-commits mode \"verbatim\"" ../migrate.log
+
+  [ "$original" = "$(git rev-parse refs/heads/main)" ]
+)
+end_test
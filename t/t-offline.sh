@@ -0,0 +1,114 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "offline: fetch fails with status 3, pull uses local objects"
+(
+  set -e
+
+  reponame="offline-fetch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="offline"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  # All objects are local, so pulling needs nothing from the server.
+  GIT_LFS_OFFLINE=1 git lfs pull 2>&1 | tee pull.log
+  [ "$contents" = "$(cat a.dat)" ]
+
+  rm -rf .git/lfs/objects
+
+  set +e
+  GIT_LFS_OFFLINE=1 git lfs fetch 2>&1 | tee fetch.log
+  status="${PIPESTATUS[0]}"
+  set -e
+  [ "3" -eq "$status" ]
+  grep "Git LFS is offline" fetch.log
+  refute_local_object "$contents_oid"
+
+  # The configuration works as well, unless the environment overrides it.
+  set +e
+  git -c lfs.offline=true lfs fetch 2>&1 | tee fetch.log
+  status="${PIPESTATUS[0]}"
+  set -e
+  [ "3" -eq "$status" ]
+
+  GIT_LFS_OFFLINE=0 git -c lfs.offline=true lfs fetch
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "offline: push and lock fail with status 3"
+(
+  set -e
+
+  reponame="offline-push"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="offline push"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  GIT_LFS_OFFLINE=1 git lfs push origin main 2>&1 | tee push.log
+  status="${PIPESTATUS[0]}"
+  set -e
+  [ "3" -eq "$status" ]
+  grep "Git LFS is offline" push.log
+  refute_server_object "$reponame" "$contents_oid"
+
+  set +e
+  GIT_LFS_OFFLINE=1 git lfs lock a.dat 2>&1 | tee lock.log
+  status="${PIPESTATUS[0]}"
+  set -e
+  [ "3" -eq "$status" ]
+  grep "Git LFS is offline" lock.log
+)
+end_test
+
+begin_test "offline: prune --verify-remote keeps reachable objects"
+(
+  set -e
+
+  reponame="offline-prune"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  old="old content"
+  old_oid="$(calc_oid "$old")"
+  printf "%s" "$old" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  new="new content"
+  new_oid="$(calc_oid "$new")"
+  printf "%s" "$new" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+  git push origin main
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+
+  GIT_LFS_OFFLINE=1 git lfs prune --verify-remote 2>&1 | tee prune.log
+  grep "1 reachable object(s) could not be verified with the remote and were kept" prune.log
+  assert_local_object "$old_oid" "${#old}"
+  assert_local_object "$new_oid" "${#new}"
+
+  git lfs prune --verify-remote 2>&1 | tee prune.log
+  grep "1 verified with remote" prune.log
+  refute_local_object "$old_oid"
+)
+end_test
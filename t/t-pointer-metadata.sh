@@ -0,0 +1,179 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "pointer metadata: version 1 by default"
+(
+  set -e
+
+  reponame="pointer-metadata-default"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.txt"
+  echo "hello" > a.txt
+  git add .gitattributes a.txt
+
+  git cat-file -p :a.txt | grep "version https://git-lfs.github.com/spec/v1"
+  [ "0" -eq "$(git cat-file -p :a.txt | grep -c "^meta-")" ]
+)
+end_test
+
+begin_test "pointer metadata: clean writes version 2 pointers"
+(
+  set -e
+
+  reponame="pointer-metadata-clean"
+  git init $reponame
+  cd $reponame
+
+  git config lfs.pointerversion 2
+  git lfs track "*.txt" "*.sh"
+  echo "hello" > a.txt
+  printf '#!/bin/sh\necho hi\n' > b.sh
+  chmod +x b.sh
+  git add .gitattributes a.txt b.sh
+
+  git cat-file -p :a.txt | tee pointer.log
+  grep "version https://git-lfs.github.com/spec/v2" pointer.log
+  grep "meta-content-type text/plain; charset=utf-8" pointer.log
+  grep "meta-executable false" pointer.log
+  [ "0" -eq "$(grep -c "meta-mtime" pointer.log)" ]
+
+  git cat-file -p :b.sh | grep "meta-executable true"
+
+  git config lfs.pointermetadata "mtime"
+  touch -d "2001-02-03T04:05:06Z" a.txt
+  git add a.txt
+  git cat-file -p :a.txt | tee pointer.log
+  grep "meta-mtime 2001-02-03T04:05:06Z" pointer.log
+  [ "0" -eq "$(grep -c "meta-content-type" pointer.log)" ]
+
+  git commit -m "initial commit"
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+)
+end_test
+
+begin_test "pointer metadata: checkout applies metadata"
+(
+  set -e
+
+  reponame="pointer-metadata-smudge"
+  git init $reponame
+  cd $reponame
+
+  git config lfs.pointerversion 2
+  git config lfs.pointermetadata "executable,mtime"
+  git config core.filemode false
+  git lfs track "*.sh"
+  printf '#!/bin/sh\necho hi\n' > a.sh
+  chmod +x a.sh
+  touch -d "2001-02-03T04:05:06Z" a.sh
+  git add .gitattributes a.sh
+  git commit -m "initial commit"
+
+  rm a.sh
+  git lfs checkout a.sh
+
+  [ -x a.sh ]
+  [ "2001-02-03" = "$(date -u -r a.sh +%Y-%m-%d)" ]
+)
+end_test
+
+begin_test "pointer metadata: filter process applies metadata"
+(
+  set -e
+
+  reponame="pointer-metadata-filter-process"
+  git init $reponame
+  cd $reponame
+
+  git config lfs.pointerversion 2
+  git config lfs.pointermetadata "executable,mtime"
+  git config core.filemode false
+  git lfs track "*.sh"
+  printf '#!/bin/sh\necho hi\n' > a.sh
+  chmod +x a.sh
+  touch -d "2001-02-03T04:05:06Z" a.sh
+  git add .gitattributes a.sh
+  git commit -m "initial commit"
+
+  rm a.sh
+  git checkout -- a.sh
+
+  [ "#!/bin/sh" = "$(head -n 1 a.sh)" ]
+  [ -x a.sh ]
+  [ "2001-02-03" = "$(date -u -r a.sh +%Y-%m-%d)" ]
+
+  # Contents smudged for other purposes leave the working tree alone.
+  touch -d "2011-12-13T14:15:16Z" a.sh
+  git cat-file --filters HEAD:a.sh | grep "echo hi"
+  [ "2011-12-13" = "$(date -u -r a.sh +%Y-%m-%d)" ]
+)
+end_test
+
+begin_test "pointer metadata: smudge does not apply metadata"
+(
+  set -e
+
+  reponame="pointer-metadata-smudge-filter"
+  git init $reponame
+  cd $reponame
+
+  git config lfs.pointerversion 2
+  git config lfs.pointermetadata "executable,mtime"
+  git config core.filemode false
+  git lfs track "*.sh"
+  printf '#!/bin/sh\necho hi\n' > a.sh
+  chmod +x a.sh
+  touch -d "2001-02-03T04:05:06Z" a.sh
+  git add .gitattributes a.sh
+  git commit -m "initial commit"
+
+  # Without the filter process, Git writes the file only after "git lfs
+  # smudge" has exited, so the metadata cannot be applied.
+  export HOME="$TRASHDIR/$reponame-home"
+  mkdir "$HOME"
+  git config --global filter.lfs.smudge "git-lfs smudge -- %f"
+  git config --global filter.lfs.required true
+  rm a.sh
+  git checkout -- a.sh
+
+  [ "#!/bin/sh" = "$(head -n 1 a.sh)" ]
+  [ ! -x a.sh ]
+  [ "2001-02-03" != "$(date -u -r a.sh +%Y-%m-%d)" ]
+)
+end_test
+
+begin_test "pointer metadata: fsck detects invalid metadata"
+(
+  set -e
+
+  reponame="pointer-metadata-fsck"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  echo "hello" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "initial commit"
+
+  oid="$(calc_oid "hello")"
+  printf 'version https://git-lfs.github.com/spec/v2\nmeta-executable maybe\noid sha256:%s\nsize 5\n' "$oid" > b.dat
+  git \
+    -c "filter.lfs.process=" \
+    -c "filter.lfs.clean=cat" \
+    -c "filter.lfs.required=false" \
+    add b.dat
+  git commit -m "invalid metadata"
+
+  set +e
+  git lfs fsck --pointers >test.log 2>&1
+  RET=$?
+  set -e
+
+  cat test.log
+  [ "$RET" -eq 1 ]
+  grep "pointer: invalidPointerMetadata: Pointer for $oid.*has invalid metadata: invalid executable \"maybe\"" test.log
+)
+end_test
@@ -437,3 +437,132 @@ begin_test "pointer --check (with invalid arguments)"
   true
 )
 end_test
+
+begin_test "pointer --batch"
+(
+  set -e
+
+  reponame="pointer---batch"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "simple" > some-file
+  printf "abc" > other-file
+
+  printf "some-file\nother-file\n" | git lfs pointer --batch > batch.json 2>batch.err
+  [ ! -s batch.err ]
+  [ "2" -eq "$(wc -l < batch.json)" ]
+
+  expected='{"file":"some-file","pointer":"version https://git-lfs.github.com/spec/v1\noid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868\nsize 7\n","oid":"6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868","size":7}'
+  [ "$expected" = "$(head -n 1 batch.json)" ]
+  tail -n 1 batch.json | grep '"file":"other-file"'
+  tail -n 1 batch.json | grep "\"oid\":\"$(calc_oid "abc")\""
+  tail -n 1 batch.json | grep '"size":3}'
+)
+end_test
+
+begin_test "pointer --batch with missing file"
+(
+  set -e
+
+  reponame="pointer---batch-missing"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "simple" > some-file
+
+  set +e
+  printf "missing-file\nsome-file\n" | git lfs pointer --batch > batch.json
+  status=$?
+  set -e
+
+  [ "1" -eq "$status" ]
+  head -n 1 batch.json | grep '"file":"missing-file","error":'
+  tail -n 1 batch.json | grep '"file":"some-file","pointer":'
+)
+end_test
+
+begin_test "pointer --batch comparing pointers"
+(
+  set -e
+
+  reponame="pointer---batch-compare"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "simple" > some-file
+  printf "version https://git-lfs.github.com/spec/v1
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868
+size 7
+" > good-pointer
+  printf "version https://git-lfs.github.com/spec/v1
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868
+size 123
+" > bad-pointer
+
+  printf "some-file\tgood-pointer\n" | git lfs pointer --batch | grep '"matches":true'
+
+  set +e
+  printf "some-file\tgood-pointer\nsome-file\tbad-pointer\n" | git lfs pointer --batch > batch.json
+  status=$?
+  set -e
+
+  [ "1" -eq "$status" ]
+  head -n 1 batch.json | grep '"matches":true'
+  tail -n 1 batch.json | grep '"matches":false'
+)
+end_test
+
+begin_test "pointer --batch --check"
+(
+  set -e
+
+  reponame="pointer---batch-check"
+  git init "$reponame"
+  cd "$reponame"
+
+  printf "version https://git-lfs.github.com/spec/v1
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868
+size 7
+" > pointer
+  printf "version https://git-lfs.github.com/spec/v1\r
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868\r
+size 7\r
+" > crlf-pointer
+  echo "not a pointer" > not-a-pointer
+
+  printf "pointer\ncrlf-pointer\n" | git lfs pointer --batch --check > batch.json
+  head -n 1 batch.json | grep '"valid":true,"canonical":true'
+  tail -n 1 batch.json | grep '"valid":true,"canonical":false'
+
+  set +e
+  printf "pointer\ncrlf-pointer\n" | git lfs pointer --batch --check --strict > /dev/null
+  status=$?
+  printf "pointer\nnot-a-pointer\n" | git lfs pointer --batch --check > batch.json
+  status2=$?
+  set -e
+
+  [ "1" -eq "$status" ]
+  [ "1" -eq "$status2" ]
+  grep '"file":"not-a-pointer","valid":false}' batch.json
+)
+end_test
+
+begin_test "pointer --batch (with invalid arguments)"
+(
+  set -e
+
+  reponame="pointer---batch-invalid"
+  git init "$reponame"
+  cd "$reponame"
+
+  touch a.txt
+
+  echo a.txt | git lfs pointer --batch --file a.txt && exit 1
+  echo a.txt | git lfs pointer --batch --stdin && exit 1
+  echo a.txt | git lfs pointer --batch --strict && exit 1
+
+  # Make the result of the subshell a success.
+  true
+)
+end_test
@@ -457,6 +457,63 @@ begin_test "pre-push reject missing pointers (lfs.allowincompletepush default)"
 )
 end_test
 
+begin_test "pre-push with verify-only ref (lfs.pushverifyonly)"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-verify-only"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" verify-only
+
+  git config lfs.pushverifyonly "release/*, main"
+
+  contents="verify only"
+  contents_oid=$(calc_oid "$contents")
+  git lfs track "*.dat"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # the object is not uploaded, and the push fails with guidance
+  echo "refs/heads/main main refs/heads/main 0000000000000000000000000000000000000000" |
+    git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  if [ "0" -eq "${PIPESTATUS[1]}" ]; then
+    echo >&2 "fatal: expected pre-push to fail ..."
+    exit 1
+  fi
+  grep "LFS objects missing from remote:" push.log
+  grep "(missing) a.dat ($contents_oid)" push.log
+  grep "lfs.pushverifyonly" push.log
+  refute_server_object "$reponame" "$contents_oid"
+
+  # patterns match the full ref name too
+  echo "refs/heads/main main refs/heads/release/1.0 0000000000000000000000000000000000000000" |
+    git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  if [ "0" -eq "${PIPESTATUS[1]}" ]; then
+    echo >&2 "fatal: expected pre-push to fail ..."
+    exit 1
+  fi
+  refute_server_object "$reponame" "$contents_oid"
+
+  # other refs still upload as usual
+  echo "refs/heads/main main refs/heads/feature 0000000000000000000000000000000000000000" |
+    git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  grep "Uploading LFS objects: 100% (1/1), 11 B" push.log
+  assert_server_object "$reponame" "$contents_oid"
+
+  # once the server has the object, the verify-only push succeeds without
+  # needing local content
+  rm -rf .git/lfs/objects
+  echo "refs/heads/main main refs/heads/main 0000000000000000000000000000000000000000" |
+    git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  [ -z "$(grep -i "missing\|error" push.log)" ]
+)
+end_test
+
 begin_test "pre-push multiple branches"
 (
   set -e
@@ -0,0 +1,134 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+# setup_pre_receive_check creates a bare repository, "$reponame.git", whose
+# pre-receive hook runs `git lfs pre-receive-check` with the given arguments,
+# and a clone of it, "$reponame", in which it leaves the current directory.
+setup_pre_receive_check() {
+  set -e
+
+  rm -rf "$reponame.git" "$reponame"
+  git init --bare "$reponame.git"
+  mkdir -p "$reponame.git/hooks"
+  printf '#!/bin/sh\nexec git lfs pre-receive-check %s\n' "$*" \
+    > "$reponame.git/hooks/pre-receive"
+  chmod +x "$reponame.git/hooks/pre-receive"
+
+  git clone "$reponame.git" "$reponame"
+  cd "$reponame"
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+}
+
+begin_test "pre-receive-check: large files"
+(
+  set -e
+
+  reponame="pre-receive-check-large"
+  setup_pre_receive_check --max-size=1KB
+
+  base64 < /dev/urandom | head -c 2048 > large.bin
+  base64 < /dev/urandom | head -c 512 > small.bin
+  git add large.bin small.bin
+  git commit -m "add large.bin"
+
+  GIT_LFS_SKIP_PUSH=1 git push origin main 2>&1 | tee ../push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push of large.bin to fail ..."
+    exit 1
+  fi
+  grep "refs/heads/main: largeObject: \"large.bin\" (blob $(git rev-parse :large.bin)) is 2.0 KB, larger than 1.0 KB" ../push.log
+  grep "Git LFS: push rejected: 1 problem(s) found" ../push.log
+  [ 0 -eq "$(grep -c "small.bin" ../push.log)" ]
+  [ "$(git rev-parse HEAD~1)" = "$(git -C ../$reponame.git rev-parse refs/heads/main)" ]
+
+  # Files stored with Git LFS may be as large as they like.
+  git rm --cached large.bin
+  mv large.bin large.dat
+  git add large.dat
+  git commit --amend -m "add large.dat"
+  mkdir -p "../$reponame.git/lfs/objects"
+  cp -r .git/lfs/objects/. "../$reponame.git/lfs/objects"
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+)
+end_test
+
+begin_test "pre-receive-check: large files in existing history"
+(
+  set -e
+
+  reponame="pre-receive-check-existing"
+  setup_pre_receive_check --max-size=1KB
+
+  base64 < /dev/urandom | head -c 2048 > large.bin
+  git add large.bin
+  git commit -m "add large.bin"
+  mv "../$reponame.git/hooks/pre-receive" ../pre-receive
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+  mv ../pre-receive "../$reponame.git/hooks/pre-receive"
+
+  # Only the files which a push adds are checked.
+  git checkout -b feature
+  echo "feature" > feature.txt
+  git add feature.txt
+  git commit -m "add feature.txt"
+  GIT_LFS_SKIP_PUSH=1 git push origin feature main:copy
+
+  # Deleting a branch checks nothing.
+  GIT_LFS_SKIP_PUSH=1 git push origin :copy
+)
+end_test
+
+begin_test "pre-receive-check: missing objects"
+(
+  set -e
+
+  reponame="pre-receive-check-missing"
+  setup_pre_receive_check
+
+  contents="missing"
+  oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add a.dat
+  git commit -m "add a.dat"
+
+  GIT_LFS_SKIP_PUSH=1 git push origin main 2>&1 | tee ../push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push of a missing object to fail ..."
+    exit 1
+  fi
+  grep "refs/heads/main: missingObject: \"a.dat\" ($oid) has not been uploaded to Git LFS" ../push.log
+
+  mkdir -p "../$reponame.git/lfs/objects"
+  cp -r .git/lfs/objects/. "../$reponame.git/lfs/objects"
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+  [ "$(git rev-parse HEAD)" = "$(git -C ../$reponame.git rev-parse refs/heads/main)" ]
+)
+end_test
+
+begin_test "pre-receive-check: non-canonical pointers"
+(
+  set -e
+
+  reponame="pre-receive-check-pointers"
+  setup_pre_receive_check --pointers
+
+  printf "version https://git-lfs.github.com/spec/v1\r\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\r\nsize 12345\r\n" > a.txt
+  blob="$(git hash-object -w a.txt)"
+  git update-index --add --cacheinfo 100644 "$blob" a.txt
+  git commit -m "add a.txt"
+
+  GIT_LFS_SKIP_PUSH=1 git push origin main 2>&1 | tee ../push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push of a non-canonical pointer to fail ..."
+    exit 1
+  fi
+  grep "refs/heads/main: nonCanonicalPointer: \"a.txt\" (blob $blob) is not a canonical pointer" ../push.log
+
+  # With --pointers only, the missing object is not reported.
+  [ 0 -eq "$(grep -c "missingObject" ../push.log)" ]
+)
+end_test
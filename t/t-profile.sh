@@ -0,0 +1,85 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "profile: use switches the objects fetched"
+(
+  set -e
+
+  reponame="profile-use"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir art docs
+  art="art"
+  art_oid="$(calc_oid "$art")"
+  printf "%s" "$art" > art/a.dat
+  docs="docs"
+  docs_oid="$(calc_oid "$docs")"
+  printf "%s" "$docs" > docs/d.dat
+
+  git config -f .lfsconfig lfs.profile.art-team.fetchinclude "art"
+  git config -f .lfsconfig lfs.profile.minimal.fetchinclude "docs"
+  git config -f .lfsconfig lfs.profile.full.fetchinclude ""
+  git add .gitattributes .lfsconfig art docs
+  git commit -m "add files"
+  git push origin main
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs profile | tee profile.log
+  grep "  art-team" profile.log
+  grep "  minimal" profile.log
+  grep "    fetchinclude = docs" profile.log
+
+  git lfs profile use art-team | tee use.log
+  grep "Using profile \"art-team\"" use.log
+  [ "art-team" = "$(git config lfs.profile)" ]
+  git lfs profile list | grep "^\* art-team"
+
+  git lfs fetch
+  assert_local_object "$art_oid" 3
+  refute_local_object "$docs_oid"
+
+  rm -rf .git/lfs/objects
+  git config lfs.fetchinclude "art"
+  git lfs profile use minimal
+  git lfs fetch
+  refute_local_object "$art_oid"
+  assert_local_object "$docs_oid" 4
+
+  git lfs profile use full
+  git lfs fetch
+  assert_local_object "$art_oid" 3
+  assert_local_object "$docs_oid" 4
+)
+end_test
+
+begin_test "profile: use with an unknown profile"
+(
+  set -e
+
+  reponame="profile-unknown"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs profile use full 2>&1 | tee use.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs profile use' to fail"
+    exit 1
+  fi
+  grep "Unknown profile \"full\": no profiles are defined" use.log
+
+  git config lfs.profile.minimal.recentrefsdays 0
+  git lfs profile use full 2>&1 | tee use.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs profile use' to fail"
+    exit 1
+  fi
+  grep "Unknown profile \"full\": must be one of minimal" use.log
+  [ -z "$(git config lfs.profile)" ]
+)
+end_test
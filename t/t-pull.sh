@@ -342,6 +342,141 @@ begin_test "pull: with missing object"
 )
 end_test
 
+begin_test "pull: with --jobs"
+(
+  set -e
+
+  reponame="pull-jobs"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  for dir in a b c; do
+    mkdir "$dir"
+    for i in 1 2 3 4 5; do
+      printf "%s" "$dir$i" > "$dir/$i.dat"
+    done
+  done
+  printf "%s" "a1" > same.dat
+  git add .gitattributes *.dat */*.dat
+  git commit -m "add files"
+  git push origin main
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs pull --jobs=0 2>&1 | tee pull.log
+  [ "0" != "${PIPESTATUS[0]}" ]
+  grep "\-\-jobs must be at least 1" pull.log
+
+  git lfs pull --jobs=4
+
+  for dir in a b c; do
+    for i in 1 2 3 4 5; do
+      [ "$dir$i" = "$(cat "$dir/$i.dat")" ]
+      assert_local_object "$(calc_oid "$dir$i")" 2
+    done
+  done
+  [ "a1" = "$(cat same.dat)" ]
+
+  # the index was updated for every file
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+)
+end_test
+
+begin_test "pull: with denied and missing objects"
+(
+  set -e
+
+  reponame="pull-denied-objects"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "ok" > ok.dat
+  printf "status-batch-403" > denied.dat
+  printf "gone" > gone.dat
+  git add .gitattributes *.dat
+  git commit -m "add files"
+
+  # only ok.dat is uploaded; the server denies access to denied.dat
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+  git lfs push --object-id origin "$(calc_oid "ok")"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs pull 2>&1 | tee pull.log
+  [ "0" != "${PIPESTATUS[0]}" ]
+  grep "pull: 1 object(s) permanently missing from the server:" pull.log
+  grep "  gone.dat ($(calc_oid "gone")): " pull.log
+  grep "pull: 1 object(s) denied by the server:" pull.log
+  grep "  denied.dat ($(calc_oid "status-batch-403")): welp" pull.log
+  [ "ok" = "$(cat ok.dat)" ]
+
+  rm ok.dat
+  git config lfs.allowmissing warn
+  git lfs pull 2>&1 | tee pull.log
+  grep "pull: leaving their files as pointers" pull.log
+  [ "ok" = "$(cat ok.dat)" ]
+  grep "version https://git-lfs" denied.dat
+
+  # the smudge filter leaves pointers in place too
+  rm denied.dat gone.dat
+  git checkout -- denied.dat gone.dat 2>&1 | tee checkout.log
+  grep "warning: leaving pointer for denied.dat" checkout.log
+  grep "version https://git-lfs" gone.dat
+)
+end_test
+
+begin_test "pull: with lfs.missingcontent=placeholder"
+(
+  set -e
+
+  reponame="pull-missing-placeholder"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "ok" > ok.dat
+  printf "status-batch-403" > denied.dat
+  printf "gone" > gone.dat
+  git add .gitattributes *.dat
+  git commit -m "add files"
+
+  GIT_LFS_SKIP_PUSH=1 git push origin main
+  git lfs push --object-id origin "$(calc_oid "ok")"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git config lfs.missingcontent placeholder
+  git lfs pull 2>&1 | tee ../pull-placeholder.log
+  grep 'writing placeholders for their files, as lfs.missingcontent is "placeholder"' ../pull-placeholder.log
+  [ "ok" = "$(cat ok.dat)" ]
+  [ 16 -eq "$(wc -c < denied.dat)" ]
+  [ 0 -eq "$(tr -d '\000' < denied.dat | wc -c)" ]
+  [ 4 -eq "$(wc -c < gone.dat)" ]
+
+  # placeholders are not changes to their files
+  sleep 1
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+  git add -A
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+
+  # and are replaced once their objects can be downloaded
+  cd "../$reponame"
+  git lfs push --object-id origin "$(calc_oid "gone")"
+  cd "../$reponame-clone"
+  git lfs pull
+  [ "gone" = "$(cat gone.dat)" ]
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+)
+end_test
+
 begin_test "pull: outside git repository"
 (
   set +e
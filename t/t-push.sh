@@ -872,3 +872,63 @@ begin_test "storage upload with compression"
   popd
 )
 end_test
+
+begin_test "push --all skips objects recorded as pushed"
+(
+  set -e
+
+  push_repo_setup "push-all-journal"
+  echo "push b" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+
+  git lfs push --all origin 2>&1 | tee push.log
+  grep "Uploading LFS objects: 100% (2/2)" push.log
+  [ 2 -eq "$(cat .git/lfs/pushed/* | wc -l)" ]
+
+  GIT_TRACE=1 git lfs push --all origin 2>&1 | tee push.log
+  [ 2 -eq "$(grep -c "is recorded as being on the server, skipping" push.log)" ]
+  [ 0 -eq "$(grep -c "objects/batch" push.log)" ]
+
+  # --dry-run still lists them
+  git lfs push --all --dry-run origin 2>&1 | tee push.log
+  [ 2 -eq "$(grep -c "^push " push.log)" ]
+
+  GIT_TRACE=1 git lfs push --all --force-check origin 2>&1 | tee push.log
+  grep "objects/batch" push.log
+  [ 0 -eq "$(grep -c "is recorded as being on the server" push.log)" ]
+
+  # objects the server already has are recorded too
+  rm -r .git/lfs/pushed
+  git lfs push --all origin
+  [ 2 -eq "$(cat .git/lfs/pushed/* | wc -l)" ]
+)
+end_test
+
+begin_test "push --upstream-dedup skips objects the upstream remote has"
+(
+  set -e
+
+  setup_remote_repo "push-upstream-dedup-upstream"
+  push_repo_setup "push-upstream-dedup"
+  echo "push b" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+
+  oid_a="$(calc_oid "push a\n")"
+  oid_b="$(calc_oid "push b\n")"
+
+  git remote add upstream "$GITSERVER/push-upstream-dedup-upstream"
+  git lfs push --object-id upstream "$oid_a"
+
+  git lfs push --upstream-dedup origin main 2>&1 | tee push.log
+  grep 'LFS: 1 object(s) skipped, already on remote "upstream"' push.log
+  refute_server_object "push-upstream-dedup" "$oid_a"
+  assert_server_object "push-upstream-dedup" "$oid_b"
+
+  # a missing upstream remote only warns
+  git config lfs.upstreamremote missing
+  git lfs push --upstream-dedup origin main 2>&1 | tee push.log
+  grep 'not checking for objects upstream, since there is no remote "missing"' push.log
+)
+end_test
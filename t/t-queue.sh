@@ -0,0 +1,112 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "queue: retry downloads which failed"
+(
+  set -e
+
+  reponame="queue-download"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  # Push the commit, but not the objects.
+  git push --no-verify origin main
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  git lfs fetch 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch to fail"
+    exit 1
+  fi
+
+  git lfs queue list | tee list.log
+  [ "2" -eq "$(grep -c "^download origin" list.log)" ]
+  grep "^download origin a.dat ($(calc_oid "a" | head -c 10), 1 B): .*does not exist" list.log
+  [ -z "$(git lfs queue list --upload)" ]
+
+  git lfs queue retry 2>&1 | tee retry.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected retry to fail"
+    exit 1
+  fi
+  [ "2" -eq "$(git lfs queue list | grep -c "^download")" ]
+
+  (cd "../$reponame" && git lfs push --all origin main)
+
+  git lfs queue retry --download
+  [ -z "$(git lfs queue list)" ]
+  [ ! -e .git/lfs/queue/download.json ]
+  assert_local_object "$(calc_oid "a")" 1
+  assert_local_object "$(calc_oid "b")" 1
+)
+end_test
+
+begin_test "queue: retry uploads which failed"
+(
+  set -e
+
+  reponame="queue-upload"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # Break the upload by corrupting the local copy of the object, then retry
+  # once it is restored.
+  oid="$(calc_oid "a")"
+  path=".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+  mv "$path" "$path.bak"
+  printf "ab" > "$path"
+
+  git push origin main 2>&1 | tee push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push to fail"
+    exit 1
+  fi
+
+  git lfs queue list --upload | tee list.log
+  grep "^upload origin a.dat (${oid:0:10}, 1 B): corrupt object" list.log
+  [ -z "$(git lfs queue list --download)" ]
+
+  mv "$path.bak" "$path"
+  git lfs queue retry --upload
+  [ -z "$(git lfs queue list)" ]
+  assert_server_object "$reponame" "$oid"
+
+  git lfs queue clear
+)
+end_test
+
+begin_test "queue: clear"
+(
+  set -e
+
+  reponame="queue-clear"
+  git init "$reponame"
+  cd "$reponame"
+
+  mkdir -p .git/lfs/queue
+  printf '[{"name":"a.dat","oid":"%s","size":1,"remote":"origin"}]\n' "$(calc_oid "a")" > .git/lfs/queue/upload.json
+
+  git lfs queue | tee list.log
+  grep "^upload origin a.dat ($(calc_oid "a" | head -c 10), 1 B): not transferred" list.log
+
+  git lfs queue clear --download
+  [ -e .git/lfs/queue/upload.json ]
+  git lfs queue clear
+  [ ! -e .git/lfs/queue/upload.json ]
+  [ -z "$(git lfs queue list)" ]
+)
+end_test
@@ -0,0 +1,87 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "repack: fails with loose backend"
+(
+  set -e
+
+  reponame="repack-loose"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs repack 2>&1 | tee repack.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs repack' to fail ..."
+    exit 1
+  fi
+  grep "Objects are already stored loose" repack.log
+)
+end_test
+
+begin_test "repack: packs objects and reads them without unpacking"
+(
+  set -e
+
+  reponame="repack-pack"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="packed"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git config lfs.storage.backend pack
+  git lfs repack | tee repack.log
+  grep "1 object(s) moved into pack storage" repack.log
+  refute_local_object "$contents_oid"
+  [ -s .git/lfs/packs/index ]
+
+  git lfs fsck
+
+  rm a.dat
+  git checkout -- a.dat
+  [ "$contents" = "$(cat a.dat)" ]
+  refute_local_object "$contents_oid"
+
+  # Uploads need the object's file, so it is unpacked.
+  git push origin main
+  assert_server_object "$reponame" "$contents_oid"
+  assert_local_object "$contents_oid" 6
+)
+end_test
+
+begin_test "repack: prune reclaims space in packs"
+(
+  set -e
+
+  reponame="repack-prune"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "old" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add old a.dat"
+  printf "new" > a.dat
+  git add a.dat
+  git commit -m "add new a.dat"
+  git push origin main
+
+  git config lfs.storage.backend pack
+  git lfs repack
+  [ "6" -eq "$(cat .git/lfs/packs/*.pack | wc -c)" ]
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+  git lfs prune
+
+  # Only the object in the current checkout is left in the packs.
+  [ "new" = "$(cat .git/lfs/packs/*.pack)" ]
+  git lfs fsck
+)
+end_test
@@ -0,0 +1,48 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+reponame="replay"
+
+begin_test "replay"
+(
+  set -e
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_LFS_RECORD="$TRASHDIR/record" git push origin main
+
+  recording="$(ls "$TRASHDIR"/record/batch-*.json | head -n 1)"
+  grep '"operation": "upload"' "$recording"
+  grep "\"oid\":\"$(calc_oid "a")\"" "$TRASHDIR/record/transfers.jsonl"
+
+  # Replaying the upload finds that the server now has the object, so it
+  # sends no actions for it.
+  git lfs replay "$recording" 2>&1 | tee replay.log
+  grep "replay: upload batch of 1 object(s) to" replay.log
+  grep "$(calc_oid "a"): no actions, recorded actions upload" replay.log
+  grep "replay: 1 object(s) in the response, 1 differ from the recording" replay.log
+)
+end_test
+
+begin_test "replay: invalid recording"
+(
+  set -e
+
+  cd "$reponame"
+  echo "{}" > bad.json
+
+  git lfs replay bad.json 2>&1 | tee replay.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "expected replay to fail"
+    exit 1
+  fi
+  grep "invalid batch recording" replay.log
+)
+end_test
@@ -37,8 +37,10 @@ begin_test "resume-http-range"
   # now fetch again, this should try to resume and server should send remainder
   # this time (it does not cut short when Range is requested)
   GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchresume.log
+  grep "xfer: resuming download of \"$contents_oid\" if unchanged since \"resume-206\"" fetchresume.log
   grep "xfer: server accepted resume" fetchresume.log
   assert_local_object "$contents_oid" "${#contents}"
+  [ ! -e ".git/lfs/incomplete/$contents_oid.validators" ]
 
 )
 end_test
@@ -126,3 +128,77 @@ begin_test "resume-http-range-retry"
   assert_local_object "$contents_oid" "${#contents}"
 )
 end_test
+
+begin_test "resume-http-range-etag-changed"
+(
+  set -e
+
+  reponame="resume-http-range-etag-changed"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" $reponame
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \"\*.dat\"" track.log
+
+  # This string announces to server that we want it to abort the download part
+  # way, and then give the object a new ETag, as if it had changed.
+  contents="batch-resume-etag-changed"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "%s" "$contents" > a.dat
+  git add a.dat
+  git add .gitattributes
+  git commit -m "add a.dat" 2>&1 | tee commit.log
+  git push origin main
+
+  assert_server_object "$reponame" "$contents_oid"
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetchinterrupted.log
+  refute_local_object "$contents_oid"
+  [ -e ".git/lfs/incomplete/$contents_oid.validators" ]
+
+  # The resumed download should be sent in full, since the ETag changed.
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchresume.log
+  grep "xfer: resuming download of \"$contents_oid\" if unchanged since \"v1\"" fetchresume.log
+  grep "xfer: failed to resume download for \"$contents_oid\" from byte 10: expected status code 206, received 200" fetchresume.log
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "resume-http-range-immutable"
+(
+  set -e
+
+  reponame="resume-http-range-immutable"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" $reponame
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \"\*.dat\"" track.log
+
+  # This string announces to server that we want it to abort the download part
+  # way, marking the content as immutable.
+  contents="batch-resume-immutable"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "%s" "$contents" > a.dat
+  git add a.dat
+  git add .gitattributes
+  git commit -m "add a.dat" 2>&1 | tee commit.log
+  git push origin main
+
+  assert_server_object "$reponame" "$contents_oid"
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetchinterrupted.log
+  refute_local_object "$contents_oid"
+
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchresume.log
+  grep "xfer: resuming download of \"$contents_oid\" without revalidation, as it is immutable" fetchresume.log
+  grep "xfer: server accepted resume" fetchresume.log
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
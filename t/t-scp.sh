@@ -0,0 +1,72 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "scp: push and fetch objects in a directory over SSH"
+(
+  set -e
+
+  reponame="scp-push-fetch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  store="$TRASHDIR/$reponame-store"
+  git config lfs.url "scp://git@127.0.0.1$store"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add objects"
+
+  GIT_TRACE=1 git push origin main 2>&1 | tee push.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  grep "Uploading LFS objects: 100% (2/2)" push.log
+  grep "lfs-ssh-echo .*git-lfs-scp" push.log
+
+  # Lock verification needs a Git LFS server.
+  grep "locks/verify$" push.log && false
+
+  oid="$(calc_oid "a")"
+  [ "a" = "$(cat "$store/${oid:0:2}/${oid:2:2}/$oid")" ]
+  [ 2 -eq "$(find "$store" -type f | wc -l)" ]
+
+  # Pushing again leaves the objects as they are.
+  git push origin main 2>&1 | tee push2.log
+  [ 2 -eq "$(find "$store" -type f | wc -l)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  assert_local_object "$oid" 1
+  assert_local_object "$(calc_oid "b")" 1
+)
+end_test
+
+begin_test "scp: missing object"
+(
+  set -e
+
+  reponame="scp-missing"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  store="$TRASHDIR/$reponame-store"
+  git config lfs.url "scp://git@127.0.0.1$store"
+
+  git lfs track "*.dat"
+  printf "missing" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add object"
+  git push origin main
+
+  oid="$(calc_oid "missing")"
+  rm "$store/${oid:0:2}/${oid:2:2}/$oid"
+  rm -rf .git/lfs/objects
+
+  git lfs fetch 2>&1 | tee fetch.log
+  [ ${PIPESTATUS[0]} != "0" ]
+  grep "object $oid is not on the remote" fetch.log
+  refute_local_object "$oid"
+)
+end_test
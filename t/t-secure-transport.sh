@@ -0,0 +1,36 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "secure transport: strict refuses plain HTTP"
+(
+  set -e
+
+  reponame="secure-transport-strict"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git config lfs.securetransport strict
+
+  set +e
+  GIT_TERMINAL_PROMPT=0 git push origin main 2>&1 | tee push.log
+  status="${PIPESTATUS[0]}"
+  set -e
+
+  [ "0" -ne "$status" ]
+  grep "lfs.securetransport is strict: refusing to send request to .* over http" push.log
+  [ "0" -eq "$(grep -c "CREDS RECV" push.log)" ]
+  refute_server_object "$reponame" "$contents_oid"
+
+  git config lfs.securetransport default
+  git push origin main
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
@@ -250,3 +250,75 @@ begin_test "smudge no ref, non-origin"
   [ "smudge a" = "$(cat a.dat)" ]
 )
 end_test
+
+begin_test "smudge --batch"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-batch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" batch
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  rm -rf .git/lfs/objects
+
+  pointer="$(pointer fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254 9)"
+  pointer_size="$(printf "%s\n" "$pointer" | wc -c | tr -d ' ')"
+
+  printf "%s a.dat\n%s\n%s b.dat\n%s\n8 c.txt\nnot lfs\n" \
+    "$pointer_size" "$pointer" "$pointer_size" "$pointer" |
+    git lfs smudge --batch > smudge.log
+
+  [ "$(printf "9\nsmudge a\n9\nsmudge a\n8\nnot lfs")" = "$(cat smudge.log)" ]
+  assert_local_object "fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254" 9
+
+  rm -rf .git/lfs/objects
+  printf "%s a.dat\n%s\n" "$pointer_size" "$pointer" |
+    git lfs smudge --skip --batch > smudge.log
+  [ "$(printf "%s\n%s" "$pointer_size" "$pointer")" = "$(cat smudge.log)" ]
+)
+end_test
+
+begin_test "smudge waits for a concurrent download"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-concurrent"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" concurrent
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  oid="fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254"
+  cp ".git/lfs/objects/fc/f5/$oid" object
+  rm -rf .git/lfs/objects
+
+  # Pretend that this shell is downloading the object in another working
+  # tree.
+  mkdir -p .git/lfs/oplocks
+  printf '{"pid":%d,"hostname":"%s","command":"smudge","created_at":"2021-01-01T00:00:00Z"}\n' \
+    "$$" "$(hostname)" > ".git/lfs/oplocks/object-$oid.lock"
+
+  pointer "$oid" 9 | GIT_TRACE=1 git lfs smudge a.dat > smudge.log 2> trace.log &
+  smudge_pid=$!
+
+  sleep 1
+  mkdir -p .git/lfs/objects/fc/f5
+  cp object ".git/lfs/objects/fc/f5/$oid"
+  rm ".git/lfs/oplocks/object-$oid.lock"
+  wait "$smudge_pid"
+
+  [ "smudge a" = "$(cat smudge.log)" ]
+  grep "was downloaded by another process" trace.log
+  [ 0 -eq "$(grep -c "Downloading a.dat" trace.log)" ]
+)
+end_test
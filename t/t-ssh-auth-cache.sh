@@ -0,0 +1,103 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "ssh auth share: git-lfs-authenticate result reused within a Git operation"
+(
+  set -e
+
+  reponame="ssh-auth-share"
+  setup_remote_repo_with_file "$reponame" "f.dat"
+  clone_repo "$reponame" "$reponame"
+
+  sshurl="${GITSERVER/http:\/\//ssh://git@}/$reponame"
+  git config lfs.url "$sshurl"
+
+  export XDG_RUNTIME_DIR="$TRASHDIR/runtime"
+  mkdir -m 0700 "$XDG_RUNTIME_DIR"
+
+  # Responses are not shared by default.
+  GIT_TRACE=1 GIT_LFS_SSH_AUTH_SESSION=one git lfs locks 2>trace.log
+  grep "lfs-ssh-echo.*git-lfs-authenticate /$reponame download" trace.log
+  [ 0 -eq "$(ls "$XDG_RUNTIME_DIR/git-lfs" | grep -c ssh-auth-shared)" ]
+
+  # The command which stored them removes them when it is done, so a later
+  # command does not reuse them.
+  git config lfs.ssh.authshare true
+  GIT_TRACE=1 GIT_LFS_SSH_AUTH_SESSION=one git lfs locks 2>trace.log
+  grep "lfs-ssh-echo.*git-lfs-authenticate /$reponame download" trace.log
+  [ ! -e "$XDG_RUNTIME_DIR/git-lfs/ssh-auth-shared-one.json" ]
+
+  GIT_TRACE=1 GIT_LFS_SSH_AUTH_SESSION=one git lfs locks 2>trace.log
+  grep "lfs-ssh-echo.*git-lfs-authenticate /$reponame download" trace.log
+  [ 0 -eq "$(ls "$XDG_RUNTIME_DIR/git-lfs" | grep -c ssh-auth-shared)" ]
+)
+end_test
+
+begin_test "ssh auth share: git-lfs-authenticate result reused in submodules"
+(
+  set -e
+
+  reponame="ssh-auth-share-submodules"
+  setup_remote_repo_with_file "$reponame" "f.dat"
+  clone_repo "$reponame" "$reponame"
+
+  git submodule add "$GITSERVER/$reponame" sub
+  git commit -m "add submodule"
+
+  sshurl="${GITSERVER/http:\/\//ssh://git@}/$reponame"
+  git config lfs.url "$sshurl"
+  git -C sub config lfs.url "$sshurl"
+  git config lfs.ssh.authshare true
+  git -C sub config lfs.ssh.authshare true
+  rm -rf .git/lfs/objects .git/modules/sub/lfs/objects
+
+  export XDG_RUNTIME_DIR="$TRASHDIR/runtime-submodules"
+  mkdir -m 0700 "$XDG_RUNTIME_DIR"
+
+  # The commands run in the submodule do not trace, so record each SSH
+  # connection instead.
+  printf '#!/bin/sh\necho "$*" >> "%s"\nexec lfs-ssh-echo "$@"\n' \
+    "$TRASHDIR/ssh.log" > "$TRASHDIR/ssh-log"
+  chmod +x "$TRASHDIR/ssh-log"
+
+  GIT_SSH="$TRASHDIR/ssh-log" GIT_SSH_VARIANT=ssh \
+    git lfs fetch --recurse-submodules 2>&1 | tee fetch.log
+  grep "Entering 'sub'" fetch.log
+  [ 1 -eq "$(grep -c "git-lfs-authenticate /$reponame download" "$TRASHDIR/ssh.log")" ]
+  [ 0 -eq "$(ls "$XDG_RUNTIME_DIR/git-lfs" | grep -c ssh-auth-shared)" ]
+
+  cd sub
+  assert_local_object "$(calc_oid_file f.dat)" 6
+)
+end_test
+
+begin_test "ssh auth cache: expiring git-lfs-authenticate result stored on disk"
+(
+  set -e
+
+  reponame="ssh-auth-cache"
+  setup_remote_repo_with_file "$reponame" "f.dat"
+  clone_repo "$reponame" "$reponame"
+
+  sshurl="${GITSERVER/http:\/\//ssh://git@}/$reponame"
+  git config lfs.url "$sshurl"
+  git config lfs.ssh.authcache true
+
+  export XDG_CACHE_HOME="$TRASHDIR/cache"
+
+  # Responses which do not expire are not stored.
+  GIT_TRACE=1 git lfs locks 2>trace.log
+  grep "lfs-ssh-echo.*git-lfs-authenticate /$reponame download" trace.log
+  [ ! -f "$XDG_CACHE_HOME/git-lfs/ssh-auth.json" ]
+
+  git config lfs.defaulttokenttl 3600
+  GIT_TRACE=1 git lfs locks 2>trace.log
+  grep "lfs-ssh-echo.*git-lfs-authenticate /$reponame download" trace.log
+  [ -f "$XDG_CACHE_HOME/git-lfs/ssh-auth.json" ]
+
+  GIT_TRACE=1 git lfs locks 2>trace.log
+  grep "ssh disk cache: git@127.0.0.1 git-lfs-authenticate /$reponame download" trace.log
+  [ "0" -eq "$(grep -c "lfs-ssh-echo.*git-lfs-authenticate" trace.log)" ]
+)
+end_test
@@ -593,3 +593,92 @@ Objects not staged for commit:"
   [ "$expected" = "$actual" ]
 )
 end_test
+
+begin_test "status: with core.fsmonitor"
+(
+  set -e
+
+  reponame="status-fsmonitor"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  # the hook reports the NUL-separated paths in .git/changed
+  cat > .git/hooks/query-changes <<-'HOOK'
+	#!/bin/sh
+	printf "token\0"
+	cat .git/changed
+	HOOK
+  chmod +x .git/hooks/query-changes
+  : > .git/changed
+  git config core.fsmonitor .git/hooks/query-changes
+
+  # with no earlier run, the whole working tree is compared
+  GIT_TRACE=1 git lfs status 2>&1 | tee status.log
+  grep "'diff-index' '-M' 'HEAD'$" status.log
+
+  printf "A" > a.dat
+  printf "B" > b.dat
+  printf "a.dat\0" > .git/changed
+  GIT_TRACE=1 git lfs status 2>&1 | tee status.log
+  grep "'diff-index' '-M' 'HEAD' '--' ':(top,literal)a.dat'$" status.log
+
+  # paths which differed last time are compared again
+  : > .git/changed
+  GIT_TRACE=1 git lfs status 2>&1 | tee status.log
+  grep "'diff-index' '-M' 'HEAD' '--' ':(top,literal)a.dat'$" status.log
+
+  printf "/\0" > .git/changed
+  GIT_TRACE=1 git lfs status 2>&1 | tee status.log
+  grep "'diff-index' '-M' 'HEAD'$" status.log
+  git lfs status --porcelain | tee status.log
+  grep " M a.dat" status.log
+  grep " M b.dat" status.log
+
+  : > .git/changed
+  GIT_TRACE=1 git -c lfs.fsmonitor=false lfs status 2>&1 | tee status.log
+  grep "'diff-index' '-M' 'HEAD'$" status.log
+  [ 0 -eq "$(grep -c "query-changes" status.log)" ]
+)
+end_test
+
+begin_test "status: with core.fsmonitor in a subdirectory"
+(
+  set -e
+
+  reponame="status-fsmonitor-subdir"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  mkdir sub
+  printf "a" > sub/a.dat
+  git add .gitattributes sub/a.dat
+  git commit -m "add sub/a.dat"
+
+  # the hook reports the NUL-separated paths in .git/changed
+  cat > .git/hooks/query-changes <<-'HOOK'
+	#!/bin/sh
+	printf "token\0"
+	cat .git/changed
+	HOOK
+  chmod +x .git/hooks/query-changes
+  : > .git/changed
+  git config core.fsmonitor .git/hooks/query-changes
+  git lfs status
+
+  # the paths reported by the hook are relative to the top of the working
+  # tree, not to the current directory
+  printf "A" > sub/a.dat
+  printf "sub/a.dat\0" > .git/changed
+  cd sub
+  GIT_TRACE=1 git lfs status --porcelain 2>&1 | tee status.log
+  grep "':(top,literal)sub/a.dat'$" status.log
+  grep " M sub/a.dat" status.log
+)
+end_test
@@ -0,0 +1,57 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "storage maxsize: evicts the least recently used pushed objects"
+(
+  set -e
+
+  reponame="storage-maxsize"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "aaaaaaaaaa" > a.dat
+  printf "bbbbbbbbbb" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin main
+
+  printf "cccccccccc" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  a_oid="$(calc_oid "aaaaaaaaaa")"
+  b_oid="$(calc_oid "bbbbbbbbbb")"
+  c_oid="$(calc_oid "cccccccccc")"
+  objects="$(git lfs env | grep LocalMediaDir | cut -d= -f2)"
+  touch -d "3 days ago" "$objects/${a_oid:0:2}/${a_oid:2:2}/$a_oid"
+  touch -d "2 days ago" "$objects/${b_oid:0:2}/${b_oid:2:2}/$b_oid"
+  touch -d "5 days ago" "$objects/${c_oid:0:2}/${c_oid:2:2}/$c_oid"
+
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "evicted" fetch.log && exit 1
+
+  # c.dat is the oldest, but has not been pushed.
+  git config lfs.storage.maxsize 25B
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "1 object(s) evicted (10 B)" fetch.log
+  refute_local_object "$a_oid"
+  assert_local_object "$b_oid" 10
+  assert_local_object "$c_oid" 10
+
+  # Pulling fetches a.dat again, which makes b.dat the oldest pushed object.
+  rm a.dat
+  git lfs pull 2>&1 | tee pull.log
+  grep "1 object(s) evicted (10 B)" pull.log
+  [ "aaaaaaaaaa" = "$(cat a.dat)" ]
+  assert_local_object "$a_oid" 10
+  refute_local_object "$b_oid"
+  assert_local_object "$c_oid" 10
+
+  git config lfs.storage.maxsize 5B
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "but the rest of it cannot be fetched again" fetch.log
+  assert_local_object "$c_oid" 10
+)
+end_test
@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "storage readonly: reads shared objects and writes to the overlay"
+(
+  set -e
+
+  reponame="storage-readonly"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  shared="shared content"
+  shared_oid="$(calc_oid "$shared")"
+  printf "%s" "$shared" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+
+  store="$TRASHDIR/$reponame-store"
+  mkdir "$store"
+  cp -R .git/lfs/objects "$store/objects"
+  find "$store" | sort > "$TRASHDIR/$reponame-store.before"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+  git config lfs.storage "$store"
+  git config lfs.storage.readonly true
+
+  # The shared object is checked out without being downloaded or copied.
+  rm a.dat
+  git checkout -- a.dat
+  [ "$shared" = "$(cat a.dat)" ]
+  refute_local_object "$shared_oid"
+
+  git lfs env | grep "LocalMediaDir=$(native_path "$(pwd)/.git/lfs/objects")"
+
+  local="local content"
+  local_oid="$(calc_oid "$local")"
+  printf "%s" "$local" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+  assert_local_object "$local_oid" "${#local}"
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+  git lfs prune
+
+  find "$store" | sort > "$TRASHDIR/$reponame-store.after"
+  diff -u "$TRASHDIR/$reponame-store.before" "$TRASHDIR/$reponame-store.after"
+  assert_local_object "$local_oid" "${#local}"
+)
+end_test
+
+begin_test "storage readonly: uses the configured overlay"
+(
+  set -e
+
+  reponame="storage-readonly-overlay"
+  git init "$reponame"
+  cd "$reponame"
+
+  mkdir -p "$TRASHDIR/$reponame-store/objects"
+  git config lfs.storage "$TRASHDIR/$reponame-store"
+  git config lfs.storage.readonly true
+  git config lfs.storage.overlay "$TRASHDIR/$reponame-overlay"
+
+  git lfs track "*.dat"
+  contents="overlay"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  [ -f "$TRASHDIR/$reponame-overlay/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" ]
+  [ -z "$(find "$TRASHDIR/$reponame-store" -type f)" ]
+)
+end_test
@@ -62,3 +62,155 @@ begin_test "submodule with submodule.recurse = true"
   fi
 )
 end_test
+
+lfsname="submodule-recurse-lfs"
+reponame="submodule-recurse-root"
+submodname1="submodule-recurse-level1"
+submodname2="submodule-recurse-level2"
+
+contents_root="root content"
+contents_sub1="level 1 content"
+contents_sub2="level 2 content"
+
+begin_test "submodule recursion: setup"
+(
+  set -e
+
+  # the innermost submodule stores its objects elsewhere, as given by its own
+  # .lfsconfig
+  setup_remote_repo "$lfsname"
+
+  setup_remote_repo "$submodname2"
+  clone_repo "$submodname2" submod2
+  git config -f .lfsconfig lfs.url "$GITSERVER/$lfsname.git/info/lfs"
+  git lfs track "*.dat"
+  printf "%s" "$contents_sub2" > sub2.dat
+  git add .lfsconfig .gitattributes sub2.dat
+  git commit -m "level 2"
+  git push origin main
+  assert_server_object "$lfsname" "$(calc_oid "$contents_sub2")"
+  refute_server_object "$submodname2" "$(calc_oid "$contents_sub2")"
+
+  setup_remote_repo "$submodname1"
+  clone_repo "$submodname1" submod1
+  git lfs track "*.dat"
+  printf "%s" "$contents_sub1" > sub1.dat
+  git submodule add "$GITSERVER/$submodname2" sub2
+  git add .gitattributes sub1.dat sub2
+  git commit -m "level 1"
+  git push origin main
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" root
+  git lfs track "*.dat"
+  printf "%s" "$contents_root" > root.dat
+  git submodule add "$GITSERVER/$submodname1" sub1
+  git add .gitattributes root.dat sub1
+  git commit -m "root"
+  git push origin main
+)
+end_test
+
+begin_test "submodule recursion: fetch and checkout"
+(
+  set -e
+
+  GIT_LFS_SKIP_SMUDGE=1 git clone --recursive "$GITSERVER/$reponame" fetch-checkout
+  cd fetch-checkout
+
+  git lfs fetch --recurse-submodules 2>&1 | tee fetch.log
+  grep "Entering 'sub1'" fetch.log
+  grep "Entering 'sub1/sub2'" fetch.log
+
+  assert_local_object "$(calc_oid "$contents_root")" "${#contents_root}"
+  (cd sub1 && assert_local_object "$(calc_oid "$contents_sub1")" "${#contents_sub1}")
+  (cd sub1/sub2 && assert_local_object "$(calc_oid "$contents_sub2")" "${#contents_sub2}")
+
+  # the pointer files are still in place
+  grep "oid sha256" sub1/sub2/sub2.dat
+
+  git lfs checkout --recurse-submodules
+  [ "$contents_root" = "$(cat root.dat)" ]
+  [ "$contents_sub1" = "$(cat sub1/sub1.dat)" ]
+  [ "$contents_sub2" = "$(cat sub1/sub2/sub2.dat)" ]
+
+  git lfs checkout --recurse-submodules --to out --ours root.dat 2>&1 | tee checkout.log
+  [ "0" -ne "${PIPESTATUS[0]}" ]
+  grep "Cannot combine --to with --recurse-submodules" checkout.log
+)
+end_test
+
+begin_test "submodule recursion: fetch passes pattern files"
+(
+  set -e
+
+  GIT_LFS_SKIP_SMUDGE=1 git clone --recursive "$GITSERVER/$reponame" fetch-pattern-files
+  cd fetch-pattern-files
+
+  printf "sub1.dat\n" > "$TRASHDIR/fetch-pattern-files-exclude"
+  mkdir dir
+  cd dir
+
+  # a relative path is found from within each submodule
+  git lfs fetch --recurse-submodules --exclude-from=../../fetch-pattern-files-exclude 2>&1 | tee fetch.log
+  grep "Entering '../sub1/sub2'" fetch.log
+  cd ..
+
+  assert_local_object "$(calc_oid "$contents_root")" "${#contents_root}"
+  (cd sub1 && refute_local_object "$(calc_oid "$contents_sub1")")
+  (cd sub1/sub2 && assert_local_object "$(calc_oid "$contents_sub2")" "${#contents_sub2}")
+
+  printf "sub1.dat\n" | git lfs fetch --recurse-submodules --exclude-from=- 2>&1 | tee fetch.log
+  [ "0" -ne "${PIPESTATUS[1]}" ]
+  grep "Cannot combine --exclude-from=- with --recurse-submodules" fetch.log
+  [ "0" -eq "$(grep -c "Entering" fetch.log)" ]
+)
+end_test
+
+begin_test "submodule recursion: pull passes flags"
+(
+  set -e
+
+  GIT_LFS_SKIP_SMUDGE=1 git clone --recursive "$GITSERVER/$reponame" pull
+  cd pull
+
+  # flags are passed on, but not the remote
+  git lfs pull --recurse-submodules --exclude="sub1.dat" origin 2>&1 | tee pull.log
+  grep "Entering 'sub1/sub2'" pull.log
+
+  [ "$contents_root" = "$(cat root.dat)" ]
+  grep "oid sha256" sub1/sub1.dat
+  [ "$contents_sub2" = "$(cat sub1/sub2/sub2.dat)" ]
+
+  git lfs pull --recurse-submodules
+  [ "$contents_sub1" = "$(cat sub1/sub1.dat)" ]
+)
+end_test
+
+begin_test "submodule recursion: prune"
+(
+  set -e
+
+  git clone --recursive "$GITSERVER/$reponame" prune
+  cd prune
+
+  # an object no longer referenced in the nested submodule is pruned
+  cd sub1/sub2
+  git checkout -q main
+  printf "new content" > sub2.dat
+  git commit -qam "change sub2.dat"
+  git lfs push origin main
+  git push -q origin main
+  cd ../..
+
+  old="$(calc_oid "$contents_sub2")"
+  (cd sub1/sub2 && assert_local_object "$old" "${#contents_sub2}")
+
+  git lfs prune --recurse-submodules 2>&1 | tee prune.log
+  grep "Entering 'sub1'" prune.log
+  grep "Entering 'sub1/sub2'" prune.log
+
+  (cd sub1/sub2 && refute_local_object "$old")
+  assert_local_object "$(calc_oid "$contents_root")" "${#contents_root}"
+)
+end_test
@@ -0,0 +1,72 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "test-data"
+(
+  set -e
+
+  git lfs test-data -n 12 -c 4 -b 2 -s "10B-100B:3,1KB-2KB" -e bin,dat generated 2>&1 |
+    tee test-data.log
+  grep "Generated 12 object(s)" test-data.log
+
+  cd generated
+  [ "main" = "$(git rev-parse --abbrev-ref HEAD)" ]
+  git rev-parse --verify branch-1
+  [ "2" = "$(git rev-list --count main)" ]
+  [ "2" = "$(git rev-list --count main..branch-1)" ]
+
+  grep '^\*\.bin filter=lfs diff=lfs merge=lfs -text$' .gitattributes
+  grep '^\*\.dat filter=lfs diff=lfs merge=lfs -text$' .gitattributes
+
+  [ "12" = "$(find .git/lfs/objects -type f | wc -l | tr -d ' ')" ]
+
+  git lfs fsck
+  git lfs ls-files --all | tee ls-files.log
+  [ -n "$(cat ls-files.log)" ]
+)
+end_test
+
+begin_test "test-data is deterministic"
+(
+  set -e
+
+  git lfs test-data -n 10 -c 3 -b 3 --seed 42 first
+  git lfs test-data -n 10 -c 3 -b 3 --seed 42 second
+  git lfs test-data -n 10 -c 3 -b 3 --seed 43 third
+
+  for ref in main branch-1 branch-2; do
+    [ "$(git -C first rev-parse "$ref")" = "$(git -C second rev-parse "$ref")" ]
+  done
+  [ "$(git -C first rev-parse main)" != "$(git -C third rev-parse main)" ]
+)
+end_test
+
+begin_test "test-data refuses non-empty directory"
+(
+  set -e
+
+  mkdir not-empty
+  touch not-empty/file
+
+  git lfs test-data not-empty 2>&1 | tee test-data.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs test-data' to fail ..."
+    exit 1
+  fi
+  grep "already exists and is not empty" test-data.log
+)
+end_test
+
+begin_test "test-data rejects invalid size distribution"
+(
+  set -e
+
+  git lfs test-data -s "2KB-1KB" invalid 2>&1 | tee test-data.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs test-data' to fail ..."
+    exit 1
+  fi
+  grep "Invalid size distribution" test-data.log
+)
+end_test
@@ -0,0 +1,58 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "tier: fails without secondary storage"
+(
+  set -e
+
+  reponame="tier-unconfigured"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs tier 2>&1 | tee tier.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs tier' to fail ..."
+    exit 1
+  fi
+  grep "set lfs.tier.path" tier.log
+)
+end_test
+
+begin_test "tier: moves unused objects and recalls them"
+(
+  set -e
+
+  reponame="tier-move"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="tiered"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git config lfs.tier.path "$TRASHDIR/$reponame-tier"
+
+  git lfs tier | tee tier.log
+  grep "0 object(s) moved" tier.log
+  assert_local_object "$contents_oid" 6
+
+  git lfs tier --days=0 --dry-run | tee tier.log
+  grep "1 object(s) would be moved" tier.log
+  assert_local_object "$contents_oid" 6
+
+  git lfs tier --days=0 --verbose | tee tier.log
+  grep "$contents_oid" tier.log
+  grep "1 object(s) moved to $TRASHDIR/$reponame-tier" tier.log
+  refute_local_object "$contents_oid"
+  [ -f "$TRASHDIR/$reponame-tier/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" ]
+
+  rm a.dat
+  git checkout -- a.dat
+  [ "$contents" = "$(cat a.dat)" ]
+  assert_local_object "$contents_oid" 6
+)
+end_test
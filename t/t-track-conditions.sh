@@ -0,0 +1,88 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "track conditions: lfs-min-size keeps small files in Git"
+(
+  set -e
+
+  reponame="track-conditions-min-size"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "*.bin filter=lfs diff=lfs merge=lfs -text lfs-min-size=1k" > .gitattributes
+  printf "small" > small.bin
+  large="$(printf 'x%.0s' $(seq 1 2048))"
+  large_oid="$(calc_oid "$large")"
+  printf "%s" "$large" > large.bin
+
+  git add .gitattributes small.bin large.bin
+  git commit -m "add files"
+
+  [ "small" = "$(git cat-file -p :small.bin)" ]
+  git cat-file -p :large.bin | grep "oid sha256:$large_oid"
+  assert_local_object "$large_oid" 2048
+
+  git lfs ls-files | tee ls-files.log
+  grep "large.bin" ls-files.log
+  [ 0 -eq "$(grep -c "small.bin" ls-files.log)" ]
+
+  rm small.bin large.bin
+  git checkout -- . 2>&1 | tee checkout.log
+  [ 0 -eq "$(grep -c "should have been pointers" checkout.log)" ]
+  [ "small" = "$(cat small.bin)" ]
+  [ "$large" = "$(cat large.bin)" ]
+
+  git lfs fsck --pointers
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+)
+end_test
+
+begin_test "track conditions: lfs-content-type keeps text files in Git"
+(
+  set -e
+
+  reponame="track-conditions-content-type"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "*.dat filter=lfs diff=lfs merge=lfs -text lfs-content-type=!text/*" > .gitattributes
+  printf "plain text\n" > notes.dat
+  printf "\x89PNG\x0d\x0a\x1a\x0a\x00\x00\x00\x0dIHDR" > image.dat
+
+  git add .gitattributes notes.dat image.dat
+  git commit -m "add files"
+
+  [ "plain text" = "$(git cat-file -p :notes.dat)" ]
+  git cat-file -p :image.dat | grep "version https://git-lfs.github.com/spec/v1"
+
+  git lfs fsck --pointers
+)
+end_test
+
+begin_test "track conditions: fsck reports files without conditions"
+(
+  set -e
+
+  reponame="track-conditions-fsck"
+  git init "$reponame"
+  cd "$reponame"
+
+  echo "*.bin filter=lfs diff=lfs merge=lfs -text lfs-min-size=1k" > .gitattributes
+  printf "small" > small.bin
+  git add .gitattributes small.bin
+  git commit -m "add files"
+  git lfs fsck --pointers
+
+  echo "*.bin filter=lfs diff=lfs merge=lfs -text" > .gitattributes
+  git add .gitattributes
+  git commit -m "drop condition"
+
+  git lfs fsck --pointers 2>&1 | tee fsck.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fsck to fail"
+    exit 1
+  fi
+  grep "small.bin" fsck.log
+)
+end_test
@@ -0,0 +1,103 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "transfer-agent: upload and download"
+(
+  set -e
+
+  reponame="transfer-agent"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  contents="transfer agent"
+  oid="$(calc_oid "$contents")"
+  size="${#contents}"
+  printf "%s" "$contents" > "$TRASHDIR/object"
+
+  printf '%s\n' \
+    '{"event":"init","operation":"upload","remote":"origin"}' \
+    "{\"event\":\"upload\",\"oid\":\"$oid\",\"size\":$size,\"path\":\"$TRASHDIR/object\"}" \
+    '{"event":"terminate"}' | git lfs transfer-agent | tee upload.log
+
+  [ "{}" = "$(head -n 1 upload.log)" ]
+  grep "\"event\":\"progress\",\"oid\":\"$oid\",\"bytesSoFar\":$size" upload.log
+  grep "\"event\":\"complete\",\"oid\":\"$oid\"}" upload.log
+  assert_server_object "$reponame" "$oid"
+
+  refute_local_object "$oid"
+  printf '%s\n' \
+    '{"event":"init","operation":"download","remote":"origin"}' \
+    "{\"event\":\"download\",\"oid\":\"$oid\",\"size\":$size}" \
+    '{"event":"terminate"}' | git lfs transfer-agent | tee download.log
+
+  grep "\"event\":\"complete\",\"oid\":\"$oid\",\"path\":" download.log
+  [ 0 -eq "$(grep -c "\"error\"" download.log)" ]
+  assert_local_object "$oid" "$size"
+)
+end_test
+
+begin_test "transfer-agent: requests sent together are batched"
+(
+  set -e
+
+  reponame="transfer-agent-batch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  requests=""
+  for contents in a b c; do
+    oid="$(calc_oid "$contents")"
+    printf "%s" "$contents" > "$TRASHDIR/object-$contents"
+    requests="$requests{\"event\":\"upload\",\"oid\":\"$oid\",\"size\":1,\"path\":\"$TRASHDIR/object-$contents\"}
+"
+  done
+
+  printf '%s\n%s%s\n' \
+    '{"event":"init","operation":"upload"}' \
+    "$requests" \
+    '{"event":"terminate"}' > requests.jsonl
+  GIT_TRACE=1 git lfs transfer-agent < requests.jsonl > upload.log 2> trace.log
+  cat upload.log
+
+  [ 3 -eq "$(grep -c '"event":"complete"' upload.log)" ]
+  [ 0 -eq "$(grep -c "\"error\"" upload.log)" ]
+  grep "tq: sending batch of size 3" trace.log
+  for contents in a b c; do
+    assert_server_object "$reponame" "$(calc_oid "$contents")"
+  done
+)
+end_test
+
+begin_test "transfer-agent: missing object"
+(
+  set -e
+
+  reponame="transfer-agent-missing"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  oid="$(calc_oid "missing")"
+  printf '%s\n' \
+    '{"event":"init","operation":"download"}' \
+    "{\"event\":\"download\",\"oid\":\"$oid\",\"size\":7}" \
+    '{"event":"terminate"}' | git lfs transfer-agent | tee download.log
+
+  grep "\"event\":\"complete\",\"oid\":\"$oid\",\"error\":{\"code\":2" download.log
+  refute_local_object "$oid"
+)
+end_test
+
+begin_test "transfer-agent: invalid operation"
+(
+  set -e
+
+  reponame="transfer-agent-invalid"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  echo '{"event":"init","operation":"copy"}' | git lfs transfer-agent > init.log && exit 1
+  grep "\"code\":32" init.log
+  grep "unknown operation" init.log
+)
+end_test
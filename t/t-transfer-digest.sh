@@ -0,0 +1,68 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "transfer digest: upload sends digests with lfs.transfer.senddigest"
+(
+  set -e
+
+  reponame="transfer-digest-upload"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  # This string announces to the server that it should reject uploads without
+  # a Content-MD5 and Digest header.
+  contents="storage-upload-require-digest"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin main 2>&1 | tee push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push without digests to fail"
+    exit 1
+  fi
+  refute_server_object "$reponame" "$contents_oid"
+
+  git -c lfs.transfer.senddigest=true push origin main 2>&1 | tee push.log
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
+
+begin_test "transfer digest: download checks digests"
+(
+  set -e
+
+  reponame="transfer-digest-download"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  # These strings announce to the server that it should send Content-MD5 and
+  # Digest headers with downloads, and a wrong Content-MD5 for the latter.
+  good="storage-download-digest"
+  good_oid="$(calc_oid "$good")"
+  bad="storage-download-bad-md5"
+  bad_oid="$(calc_oid "$bad")"
+  printf "%s" "$good" > good.dat
+  printf "%s" "$bad" > bad.dat
+  git add .gitattributes good.dat bad.dat
+  git commit -m "add files"
+  git push origin main
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch to fail"
+    exit 1
+  fi
+
+  assert_local_object "$good_oid" "${#good}"
+  refute_local_object "$bad_oid"
+  grep "Content-MD5 mismatch for bad.dat" fetch.log
+)
+end_test
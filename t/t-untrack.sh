@@ -176,3 +176,48 @@ begin_test "untrack works with GIT_WORK_TREE"
   [ ! -s "$reponame/.gitattributes" ]
 )
 end_test
+
+begin_test "untrack --restore"
+(
+  set -e
+
+  reponame="untrack-restore"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat" "*.bin"
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  printf "%s" "$contents" > a.dat
+  mkdir dir
+  printf "%s" "b" > dir/b.dat
+  printf "%s" "c" > c.bin
+  git add .gitattributes a.dat dir/b.dat c.bin
+  git commit -m "add files"
+  git push origin main
+
+  # Make sure one file is a pointer with its object only on the remote.
+  rm -rf .git/lfs/objects a.dat
+  GIT_LFS_SKIP_SMUDGE=1 git checkout -- a.dat
+  git lfs pointer --check --file a.dat
+
+  git lfs untrack --restore "*.dat" | tee untrack.log
+  grep "Untracking \"\*.dat\"" untrack.log
+  grep "Restoring \"a.dat\"" untrack.log
+  grep "Restoring \"dir/b.dat\"" untrack.log
+
+  [ "a" = "$(cat a.dat)" ]
+  [ "b" = "$(cat dir/b.dat)" ]
+  assert_local_object "$contents_oid" 1
+
+  [ "a" = "$(git cat-file -p :a.dat)" ]
+  [ "b" = "$(git cat-file -p :dir/b.dat)" ]
+  git cat-file -p :c.bin | grep "oid sha256:"
+  [ "0" -eq "$(git cat-file -p :.gitattributes | grep -c "\*.dat")" ]
+  git cat-file -p :.gitattributes | grep "\*.bin"
+
+  git commit -m "restore dat files"
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+  [ "a" = "$(git cat-file -p HEAD:a.dat)" ]
+)
+end_test
@@ -0,0 +1,62 @@
+#!/usr/bin/env bash
+
+. "$(dirname "$0")/testlib.sh"
+
+begin_test "upload claim: waits while another upload is in progress"
+(
+  set -e
+
+  reponame="upload-claim-wait-upload-claimed"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config lfs.transfer.uploadclaims true
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  contents_short_oid="$(echo "$contents_oid" | head -c 7)"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin main 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  grep "claim $contents_short_oid: uploading elsewhere, checking again in 1s" push.log
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
+
+begin_test "upload claim: skips objects uploaded by another client"
+(
+  set -e
+
+  reponame="upload-claim-skip-upload-claimed"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  contents_short_oid="$(echo "$contents_oid" | head -c 7)"
+  printf "%s" "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin main
+  assert_server_object "$reponame" "$contents_oid"
+
+  # The server asks for the object again, as though it had not yet arrived
+  # from another client when the batch request was made.
+  git config lfs.transfer.uploadclaims true
+  GIT_TRACE=1 GIT_CURL_VERBOSE=1 git lfs push --object-id origin "$contents_oid" 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  grep "claim $contents_short_oid: already uploaded" push.log
+  [ "0" -eq "$(grep -c "> PUT" push.log)" ]
+
+  git config lfs.transfer.uploadclaims false
+  GIT_TRACE=1 GIT_CURL_VERBOSE=1 git lfs push --object-id origin "$contents_oid" 2>&1 | tee push.log
+  [ "0" -eq "$(grep -c "> HEAD" push.log)" ]
+  grep "> PUT" push.log
+)
+end_test
@@ -123,3 +123,35 @@ begin_test "verify with retries (bad .gitconfig)"
   [ "2" -eq "$(grep -c "verify $contents_short_oid attempt" push.log)" ]
 )
 end_test
+
+begin_test "verify with quarantined object"
+(
+  set -e
+
+  reponame="verify-quarantined"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config "lfs.transfer.quarantinetimeout" "2"
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  contents="send-verify-action"
+  contents_oid="$(calc_oid "$contents")"
+  contents_short_oid="$(echo "$contents_oid" | head -c 7)"
+  printf "%s" "$contents" > a.dat
+
+  git add a.dat
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin main 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  grep "verify $contents_short_oid quarantined, retrying in 1s: scanning for viruses" push.log
+  grep "LFS: 1 object(s) uploaded, but quarantined by the remote pending a scan:" push.log
+  grep "a.dat ($contents_oid): scanning for viruses" push.log
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
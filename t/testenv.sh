@@ -157,6 +157,10 @@ unset GIT_WORK_TREE
 unset GIT_EXEC_PATH
 unset GIT_CHERRY_PICK_HELP
 
+# Don't enable CI mode just because the tests are being run in CI.
+unset CI BUILDKITE CIRCLECI GITHUB_ACTIONS GITLAB_CI JENKINS_URL \
+  TEAMCITY_VERSION TF_BUILD TRAVIS
+
 mkdir -p "$TMPDIR"
 mkdir -p "$TRASHDIR"
 
@@ -15,6 +15,11 @@ import (
 
 const (
 	DefaultLoggingThrottle = 200 * time.Millisecond
+
+	// clearLine and clearToEnd are the ANSI escape sequences which clear
+	// the rest of the current line, and the rest of the screen.
+	clearLine  = "\x1b[K"
+	clearToEnd = "\x1b[J"
 )
 
 // Logger logs a series of tasks to an io.Writer, processing each task in order
@@ -33,6 +38,10 @@ type Logger struct {
 	// forceProgress forces progress status even when stdout is not a tty
 	forceProgress bool
 
+	// plainProgress suppresses progress status, even when stdout is a tty,
+	// so that only the final line of each task is logged
+	plainProgress bool
+
 	// throttle is the minimum amount of time that must pass between each
 	// instant data is logged.
 	throttle time.Duration
@@ -57,6 +66,14 @@ func ForceProgress(v bool) Option {
 	}
 }
 
+// PlainProgress returns an options function that configures the logger to log
+// only the final line of each task, without intermediate progress status.
+func PlainProgress(v bool) Option {
+	return func(l *Logger) {
+		l.plainProgress = v
+	}
+}
+
 // NewLogger returns a new *Logger instance that logs to "sink" and uses the
 // current terminal width as the width of the line. Will log progress status if
 // stdout is a terminal or if forceProgress is true
@@ -250,17 +267,38 @@ func (l *Logger) logTask(task Task) {
 	logAll := !task.Throttled()
 	var last time.Time
 
+	// If the Task can describe its progress in more detail, such as
+	// with a line for each item in progress, and the sink is a terminal,
+	// those lines are shown below each update, and cleared once the Task
+	// is done.
+	var details func() []string
+	if v, ok := task.(interface {
+		Details() []string
+	}); ok && l.tty {
+		details = v.Details
+	}
+	shown := false
+
 	var update *Update
 	for update = range task.Updates() {
-		if !tty(os.Stdout) && !l.forceProgress {
+		if l.plainProgress || (!tty(os.Stdout) && !l.forceProgress) {
 			continue
 		}
 		if logAll || l.throttle == 0 || !update.Throttled(last.Add(l.throttle)) {
-			l.logLine(update.S)
+			if details != nil {
+				l.logDetails(update.S, details())
+				shown = true
+			} else {
+				l.logLine(update.S)
+			}
 			last = update.At
 		}
 	}
 
+	if shown {
+		l.log(clearToEnd)
+	}
+
 	if update != nil {
 		// If a task sent no updates, the last recorded update will be
 		// nil. Given this, only log a message when there was at least
@@ -290,6 +328,30 @@ func (l *Logger) logLine(str string) (n int, err error) {
 	return l.log(str + padding + "\r")
 }
 
+// logDetails writes a complete line, followed by the given detail lines, and
+// moves the cursor back to the beginning of the first line, so that the next
+// update overwrites them all. Any lines left over from the previous update are
+// cleared, and detail lines are truncated to the width of the terminal so that
+// none of them wrap.
+func (l *Logger) logDetails(str string, details []string) (n int, err error) {
+	width := l.widthFn()
+
+	var b strings.Builder
+	b.WriteString(str)
+	b.WriteString(clearLine + "\n")
+	for _, line := range details {
+		if width > 1 && len(line) >= width {
+			line = line[:width-1]
+		}
+		b.WriteString(line)
+		b.WriteString(clearLine + "\n")
+	}
+	b.WriteString(clearToEnd)
+	fmt.Fprintf(&b, "\x1b[%dA\r", len(details)+1)
+
+	return l.log(b.String())
+}
+
 // log writes a string verbatim to the sink.
 //
 // It returns the number of bytes "n" written to the sink and the error "err",
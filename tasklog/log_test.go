@@ -59,6 +59,25 @@ func TestLoggerLogsSuppressesProgress(t *testing.T) {
 	assert.Equal(t, "second, done.\n", buf.String())
 }
 
+func TestLoggerLogsPlainProgress(t *testing.T) {
+	var buf bytes.Buffer
+
+	task := make(chan *Update)
+	go func() {
+		task <- &Update{"first", time.Now(), false}
+		task <- &Update{"second", time.Now(), false}
+		close(task)
+	}()
+
+	l := NewLogger(&buf, ForceProgress(true), PlainProgress(true))
+	l.throttle = 0
+	l.widthFn = func() int { return 0 }
+	l.Enqueue(ChanTask(task))
+	l.Close()
+
+	assert.Equal(t, "second, done.\n", buf.String())
+}
+
 func TestLoggerLogsMultipleTasksInOrder(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -207,3 +226,48 @@ func TestLoggerHandlesSilentTasks(t *testing.T) {
 
 	assert.Equal(t, "", buf.String())
 }
+
+type DetailedChanTask struct {
+	ChanTask
+	details []string
+}
+
+func (e DetailedChanTask) Details() []string { return e.details }
+
+func TestLoggerLogsDetailsOnTerminal(t *testing.T) {
+	var buf bytes.Buffer
+
+	task := make(chan *Update)
+	go func() {
+		task <- &Update{"first", time.Now(), false}
+		close(task)
+	}()
+
+	l := NewLogger(&buf, ForceProgress(true))
+	l.throttle = 0
+	l.tty = true
+	l.widthFn = func() int { return 6 }
+	l.Enqueue(DetailedChanTask{ChanTask(task), []string{"a.dat", "long.dat"}})
+	l.Close()
+
+	assert.Equal(t, "first\x1b[K\na.dat\x1b[K\nlong.\x1b[K\n\x1b[J\x1b[3A\r"+
+		"\x1b[Jfirst, done.\n", buf.String())
+}
+
+func TestLoggerOmitsDetailsWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+
+	task := make(chan *Update)
+	go func() {
+		task <- &Update{"first", time.Now(), false}
+		close(task)
+	}()
+
+	l := NewLogger(&buf, ForceProgress(true))
+	l.throttle = 0
+	l.widthFn = func() int { return 0 }
+	l.Enqueue(DetailedChanTask{ChanTask(task), []string{"a.dat"}})
+	l.Close()
+
+	assert.Equal(t, "first\rfirst, done.\n", buf.String())
+}
@@ -0,0 +1,17 @@
+package tools
+
+import (
+	"os"
+	"time"
+)
+
+// LastUsed returns when the file with the given info was last read or written,
+// as far as the filesystem records it. Filesystems mounted with "noatime", or
+// "relatime", may record reads late or not at all, so this is never earlier
+// than the file's modification time.
+func LastUsed(fi os.FileInfo) time.Time {
+	if atime := accessTime(fi); atime.After(fi.ModTime()) {
+		return atime
+	}
+	return fi.ModTime()
+}
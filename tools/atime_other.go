@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!windows
+
+package tools
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime is not known on this platform, so only modification times are
+// used.
+func accessTime(fi os.FileInfo) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rubyist/tracerx"
+)
+
+// InsufficientSpaceError is returned by CheckFreeSpace when a volume does not
+// have enough free space for the data to be written to it.
+type InsufficientSpaceError struct {
+	// Dir is a directory on the volume, as given to CheckFreeSpace.
+	Dir string
+	// Needed is the number of bytes to be written to the volume, and
+	// Available is the number of bytes free on it.
+	Needed    uint64
+	Available uint64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("not enough free space for %q: %d bytes needed, %d bytes available", e.Dir, e.Needed, e.Available)
+}
+
+// CheckFreeSpace returns an *InsufficientSpaceError if any volume lacks the
+// free space for the given numbers of bytes to be written to the directories
+// on it, which need not be distinct or exist yet. The space for directories on the same
+// volume is added together. Volumes whose free space cannot be determined,
+// such as on unsupported platforms, are assumed to have enough.
+func CheckFreeSpace(needs map[string]uint64) error {
+	dirs := make([]string, 0, len(needs))
+	for dir := range needs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	volumes := make(map[string]*InsufficientSpaceError)
+	order := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if needs[dir] == 0 {
+			continue
+		}
+
+		existing := existingAncestor(dir)
+		id, err := volumeID(existing)
+		if err != nil {
+			tracerx.Printf("could not determine volume of %q: %v", dir, err)
+			continue
+		}
+		if v, ok := volumes[id]; ok {
+			v.Needed += needs[dir]
+			continue
+		}
+
+		free, err := freeSpace(existing)
+		if err != nil {
+			tracerx.Printf("could not determine free space for %q: %v", dir, err)
+			continue
+		}
+		volumes[id] = &InsufficientSpaceError{Dir: dir, Needed: needs[dir], Available: free}
+		order = append(order, id)
+	}
+
+	for _, id := range order {
+		if v := volumes[id]; v.Needed > v.Available {
+			return v
+		}
+	}
+	return nil
+}
+
+// existingAncestor returns the nearest of dir and its parents which exists,
+// since the directories to be written to may not have been created yet.
+func existingAncestor(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
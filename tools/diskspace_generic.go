@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package tools
+
+import "github.com/git-lfs/git-lfs/v2/errors"
+
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.New("unsupported platform")
+}
+
+func volumeID(dir string) (string, error) {
+	return "", errors.New("unsupported platform")
+}
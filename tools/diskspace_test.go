@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFreeSpaceWithNothingNeeded(t *testing.T) {
+	assert.Nil(t, CheckFreeSpace(map[string]uint64{
+		"/this/does/not/exist": 0,
+	}))
+}
+
+func TestCheckFreeSpaceWithSmallNeed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskspace")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, CheckFreeSpace(map[string]uint64{
+		dir:                          1,
+		filepath.Join(dir, "a", "b"): 1,
+	}))
+}
+
+func TestCheckFreeSpaceWithHugeNeed(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skip("free space is not known on this platform")
+	}
+
+	dir, err := ioutil.TempDir("", "diskspace")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The space needed for both directories is added together, since they
+	// are on the same volume.
+	need := uint64(1) << 62
+	err = CheckFreeSpace(map[string]uint64{
+		dir:                       need,
+		filepath.Join(dir, "new"): need,
+	})
+	require.NotNil(t, err)
+
+	serr, ok := err.(*InsufficientSpaceError)
+	require.True(t, ok)
+	assert.Equal(t, dir, serr.Dir)
+	assert.Equal(t, 2*need, serr.Needed)
+}
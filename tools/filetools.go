@@ -22,7 +22,7 @@ import (
 
 // FileOrDirExists determines if a file/dir exists, returns IsDir() results too.
 func FileOrDirExists(path string) (exists bool, isDir bool) {
-	fi, err := os.Stat(path)
+	fi, err := os.Stat(LongPath(path))
 	if err != nil {
 		return false, false
 	} else {
@@ -44,7 +44,7 @@ func DirExists(path string) bool {
 
 // FileExistsOfSize determines if a file exists and is of a specific size.
 func FileExistsOfSize(path string, sz int64) bool {
-	fi, err := os.Stat(path)
+	fi, err := os.Stat(LongPath(path))
 
 	if err != nil {
 		return false
@@ -69,13 +69,13 @@ func ResolveSymlinks(path string) string {
 // RenameFileCopyPermissions moves srcfile to destfile, replacing destfile if
 // necessary and also copying the permissions of destfile if it already exists
 func RenameFileCopyPermissions(srcfile, destfile string) error {
-	info, err := os.Stat(destfile)
+	info, err := os.Stat(LongPath(destfile))
 	if os.IsNotExist(err) {
 		// no original file
 	} else if err != nil {
 		return err
 	} else {
-		if err := os.Chmod(srcfile, info.Mode()); err != nil {
+		if err := os.Chmod(LongPath(srcfile), info.Mode()); err != nil {
 			return fmt.Errorf("can't set filemode on file %q: %v", srcfile, err)
 		}
 	}
@@ -126,7 +126,7 @@ type repositoryPermissionFetcher interface {
 func MkdirAll(path string, config repositoryPermissionFetcher) error {
 	umask := 0777 & ^config.RepositoryPermissions(true)
 	return doWithUmask(int(umask), func() error {
-		return os.MkdirAll(path, config.RepositoryPermissions(true))
+		return os.MkdirAll(LongPath(path), config.RepositoryPermissions(true))
 	})
 }
 
@@ -216,7 +216,7 @@ func ExpandConfigPath(path, defaultPath string) (string, error) {
 // VerifyFileHash reads a file and verifies whether the SHA is correct
 // Returns an error if there is a problem
 func VerifyFileHash(oid, path string) error {
-	f, err := os.Open(path)
+	f, err := os.Open(LongPath(path))
 	if err != nil {
 		return err
 	}
@@ -427,6 +427,7 @@ func join(paths ...string) string {
 // All other bits are unaffected.
 // On Windows, all the write bits are set since Windows doesn't support Unix permissions.
 func SetFileWriteFlag(path string, writeEnabled bool) error {
+	path = LongPath(path)
 	stat, err := os.Stat(path)
 	if err != nil {
 		return err
@@ -456,7 +457,7 @@ func SetFileWriteFlag(path string, writeEnabled bool) error {
 // This function is designed to handle only temporary files that will be renamed
 // into place later somewhere within the Git repository.
 func TempFile(dir, pattern string, cfg repositoryPermissionFetcher) (*os.File, error) {
-	tmp, err := ioutil.TempFile(dir, pattern)
+	tmp, err := ioutil.TempFile(LongPath(dir), pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,12 @@ package tools
 
 import "path/filepath"
 
+// LongPath returns path unchanged, since only Windows limits the length of
+// paths given to file operations.
+func LongPath(path string) string {
+	return path
+}
+
 func CanonicalizeSystemPath(path string) (string, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
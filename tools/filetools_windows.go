@@ -4,11 +4,44 @@
 package tools
 
 import (
+	"path/filepath"
+	"strings"
+
 	"golang.org/x/sys/windows"
 )
 
+// maxShortPath is the length from which a path may be too long for Windows
+// APIs without the `\\?\` prefix.  Directories are limited to MAX_PATH, less
+// the 12 characters of an 8.3 file name, so this is 260 - 12.
+const maxShortPath = 248
+
+// LongPath returns a form of path which Windows file operations accept even if
+// it is longer than MAX_PATH, by making it absolute and adding the `\\?\`
+// prefix, or `\\?\UNC\` for a UNC path such as `\\server\share\dir`.  Paths
+// which are short enough once made absolute, are already prefixed, or can't be
+// made absolute are returned unchanged.
+//
+// The os package already does this for absolute paths other than UNC paths,
+// but not for relative paths, which Windows resolves against the current
+// directory before applying the limit.
+func LongPath(path string) string {
+	if len(path) == 0 || strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil || len(abs) < maxShortPath {
+		return path
+	}
+
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
 func openSymlink(path string) (windows.Handle, error) {
-	p, err := windows.UTF16PtrFromString(path)
+	p, err := windows.UTF16PtrFromString(LongPath(path))
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,61 @@
+//go:build windows
+// +build windows
+
+package tools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongPathLeavesShortPathsAlone(t *testing.T) {
+	assert.Equal(t, "", LongPath(""))
+	assert.Equal(t, `C:\short\path`, LongPath(`C:\short\path`))
+	assert.Equal(t, `short\path`, LongPath(`short\path`))
+}
+
+func TestLongPathPrefixesLongPaths(t *testing.T) {
+	long := `C:\` + strings.Repeat(`directory\`, 30) + "file"
+
+	assert.Equal(t, `\\?\`+long, LongPath(long))
+	assert.Equal(t, `\\?\`+long, LongPath(filepath.ToSlash(long)))
+	assert.Equal(t, `\\?\`+long, LongPath(`\\?\`+long))
+}
+
+func TestLongPathPrefixesLongUNCPaths(t *testing.T) {
+	long := `\\server\share\` + strings.Repeat(`directory\`, 30) + "file"
+
+	assert.Equal(t, `\\?\UNC\server\share\`+strings.Repeat(`directory\`, 30)+"file", LongPath(long))
+}
+
+func TestLongPathFileOperations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "long-path")
+	require.NoError(t, err)
+	defer os.RemoveAll(LongPath(dir))
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(cwd)
+
+	// A relative path, which the os package doesn't make long itself.
+	rel := filepath.Join(strings.Repeat(`directory\`, 30), "file")
+	require.NoError(t, MkdirAll(filepath.Dir(rel), &testPermissions{}))
+	require.NoError(t, ioutil.WriteFile(LongPath(rel), []byte("contents"), 0644))
+
+	assert.True(t, FileExistsOfSize(rel, 8))
+	require.NoError(t, RobustRename(rel, rel+".renamed"))
+	assert.True(t, FileExists(rel+".renamed"))
+}
+
+type testPermissions struct{}
+
+func (p *testPermissions) RepositoryPermissions(executable bool) os.FileMode {
+	return 0777
+}
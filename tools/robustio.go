@@ -6,9 +6,9 @@ package tools
 import "os"
 
 func RobustRename(oldpath, newpath string) error {
-	return os.Rename(oldpath, newpath)
+	return os.Rename(LongPath(oldpath), LongPath(newpath))
 }
 
 func RobustOpen(name string) (*os.File, error) {
-	return os.Open(name)
+	return os.Open(LongPath(name))
 }
@@ -34,7 +34,7 @@ func isEphemeralError(err error) bool {
 func RobustRename(oldpath, newpath string) error {
 	return retry.Do(
 		func() error {
-			return os.Rename(oldpath, newpath)
+			return os.Rename(LongPath(oldpath), LongPath(newpath))
 		},
 		retry.RetryIf(isEphemeralError),
 		retry.LastErrorOnly(true),
@@ -45,7 +45,7 @@ func RobustOpen(name string) (*os.File, error) {
 	var result *os.File
 	return result, retry.Do(
 		func() error {
-			f, err := os.Open(name)
+			f, err := os.Open(LongPath(name))
 			result = f
 			return err
 		},
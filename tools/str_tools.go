@@ -2,6 +2,7 @@ package tools
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -120,3 +121,36 @@ var (
 func Undent(str string) string {
 	return tabRe.ReplaceAllString(str, "")
 }
+
+// CaseCollisions returns the groups of paths in "paths" which differ only in
+// case, and so would refer to the same file on a case-insensitive filesystem.
+// Each group is sorted in the order Git would write its paths to the working
+// tree, so that all but the last would be overwritten, and the groups are
+// sorted by their first path.
+func CaseCollisions(paths []string) [][]string {
+	sorted := make([]string, len(paths))
+	copy(sorted, paths)
+	sort.Strings(sorted)
+
+	var keys []string
+	groups := make(map[string][]string)
+	for _, path := range sorted {
+		key := strings.ToLower(path)
+		if g, ok := groups[key]; ok {
+			if g[len(g)-1] != path {
+				groups[key] = append(g, path)
+			}
+			continue
+		}
+		keys = append(keys, key)
+		groups[key] = []string{path}
+	}
+
+	var collisions [][]string
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			collisions = append(collisions, groups[key])
+		}
+	}
+	return collisions
+}
@@ -164,3 +164,15 @@ func TestUndentRemovesPreservesLinebreaks(t *testing.T) {
 	assert.Equal(t, "foo  \n", Undent("  foo  \n"))
 	assert.Equal(t, "\nfoo  \n", Undent("\n  foo  \n"))
 }
+
+func TestCaseCollisions(t *testing.T) {
+	assert.Nil(t, CaseCollisions(nil))
+	assert.Nil(t, CaseCollisions([]string{"a.dat", "b.dat", "a.dat"}))
+
+	assert.Equal(t, [][]string{
+		{"A.dat", "a.dat"},
+		{"Dir/b.dat", "dir/B.dat", "dir/b.dat"},
+	}, CaseCollisions([]string{
+		"dir/b.dat", "a.dat", "c.dat", "Dir/b.dat", "A.dat", "dir/B.dat",
+	}))
+}
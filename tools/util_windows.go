@@ -62,12 +62,12 @@ func CheckCloneFileSupported(dir string) (supported bool, err error) {
 }
 
 func CloneFileByPath(dst, src string) (success bool, err error) {
-	dstFile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE, 0666) // No truncate version of os.Create
+	dstFile, err := os.OpenFile(LongPath(dst), os.O_RDWR|os.O_CREATE, 0666) // No truncate version of os.Create
 	if err != nil {
 		return
 	}
 
-	srcFile, err := os.Open(src)
+	srcFile, err := os.Open(LongPath(src))
 	if err != nil {
 		return
 	}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/git-lfs/git-lfs/v2/fs"
 	"github.com/git-lfs/git-lfs/v2/lfsapi"
@@ -31,6 +32,9 @@ type adapterBase struct {
 	jobWait *sync.WaitGroup
 	// WaitGroup to serialise the first transfer response to perform login if needed
 	authWait sync.WaitGroup
+	// concurrency limits how many workers transfer at once, if
+	// lfs.transfer.adaptive is set
+	concurrency *adaptiveConcurrency
 }
 
 // transferImplementation must be implemented to provide the actual upload/download
@@ -54,6 +58,9 @@ type transferImplementation interface {
 const (
 	enableHrefRewriteKey     = "lfs.transfer.enablehrefrewrite"
 	defaultEnableHrefRewrite = false
+
+	operationLockTimeoutKey     = "lfs.operationlocktimeout"
+	defaultOperationLockTimeout = 300
 )
 
 func newAdapterBase(f *fs.Filesystem, name string, dir Direction, ti transferImplementation) *adapterBase {
@@ -84,6 +91,9 @@ func (a *adapterBase) Begin(cfg AdapterConfig, cb ProgressCallback) error {
 	maxConcurrency := cfg.ConcurrentTransfers()
 
 	a.Trace("xfer: adapter %q Begin() with %d workers", a.Name(), maxConcurrency)
+	if maxConcurrency > 1 && a.apiClient.GitEnv().Bool(adaptiveConcurrencyKey, false) {
+		a.concurrency = newAdaptiveConcurrency(maxConcurrency)
+	}
 
 	a.workerWait.Add(maxConcurrency)
 	a.authWait.Add(1)
@@ -174,14 +184,24 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 		}
 		a.Trace("xfer: adapter %q worker %d processing job for %q", a.Name(), workerNum, t.Oid)
 
+		if a.concurrency != nil {
+			a.concurrency.Acquire()
+		}
+
 		// Actual transfer happens here
 		var err error
 		if t.Size < 0 {
 			err = fmt.Errorf("object %q has invalid size (got: %d)", t.Oid, t.Size)
+		} else if a.direction == Download {
+			err = a.lockedDownload(ctx, t, authCallback)
 		} else {
 			err = a.transferImpl.DoTransfer(ctx, t, a.cb, authCallback)
 		}
 
+		if a.concurrency != nil {
+			a.concurrency.Release(t.Size, err)
+		}
+
 		// Mark the job as completed, and alter all listeners
 		job.Done(err)
 
@@ -196,6 +216,33 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 	a.workerWait.Done()
 }
 
+// lockedDownload downloads the given object while holding its object lock, so
+// that other Git LFS processes in the same repository do not download it at
+// the same time, and skips it if one already has.
+func (a *adapterBase) lockedDownload(ctx interface{}, t *Transfer, authOkFunc func()) error {
+	if a.fs == nil {
+		return a.transferImpl.DoTransfer(ctx, t, a.cb, authOkFunc)
+	}
+
+	timeout := time.Duration(a.apiClient.GitEnv().Int(operationLockTimeoutKey, defaultOperationLockTimeout)) * time.Second
+	lock, err := a.fs.Lock(fs.ObjectLockName(t.Oid), timeout)
+	if err != nil {
+		// The lock is only advisory, and a download which races with
+		// another is still saved safely, so go ahead without it.
+		tracerx.Printf("xfer: downloading %q without lock: %v", t.Oid, err)
+	}
+	defer lock.Unlock()
+
+	if t.Path == a.fs.ObjectPathname(t.Oid) && a.fs.ObjectExists(t.Oid, t.Size) {
+		tracerx.Printf("xfer: %q was downloaded by another process", t.Oid)
+		if a.cb != nil {
+			return a.cb(t.Name, t.Size, t.Size, int(t.Size))
+		}
+		return nil
+	}
+	return a.transferImpl.DoTransfer(ctx, t, a.cb, authOkFunc)
+}
+
 var httpRE = regexp.MustCompile(`\Ahttps?://`)
 
 func (a *adapterBase) newHTTPRequest(method string, rel *Action) (*http.Request, error) {
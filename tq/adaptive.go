@@ -0,0 +1,158 @@
+package tq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	adaptiveConcurrencyKey = "lfs.transfer.adaptive"
+
+	// adaptiveWindow is how long the throughput at one level of
+	// concurrency is measured for before the level is changed.
+	adaptiveWindow = 2 * time.Second
+
+	// adaptiveInitialLimit is how many transfers run at once to begin
+	// with when the concurrency is adaptive.
+	adaptiveInitialLimit = 2
+)
+
+// adaptiveConcurrency limits how many of an adapter's workers transfer objects
+// at once, changing the limit with the throughput, errors and "429 Too Many
+// Requests" responses seen. The limit doubles while each increase brings more
+// throughput, and then goes up one at a time while that still helps, and down
+// one when throughput drops; retriable errors halve it, and 429 responses drop
+// it to one. It never goes above the number of workers, which is
+// lfs.concurrenttransfers.
+type adaptiveConcurrency struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	limit, max, active int
+	// slowStart is whether the limit is still being doubled.
+	slowStart bool
+
+	windowStart time.Time
+	bytes       int64
+	completed   int
+	failures    int
+	throttled   int
+
+	// lastRate is the throughput in the last window, in bytes per second.
+	lastRate float64
+
+	now func() time.Time
+}
+
+func newAdaptiveConcurrency(max int) *adaptiveConcurrency {
+	c := &adaptiveConcurrency{
+		limit:     adaptiveInitialLimit,
+		max:       max,
+		slowStart: true,
+		now:       time.Now,
+	}
+	if c.limit > max {
+		c.limit = max
+	}
+	c.cond = sync.NewCond(&c.mu)
+	c.windowStart = c.now()
+	return c
+}
+
+// Acquire waits until another transfer may start, and counts it as active.
+func (c *adaptiveConcurrency) Acquire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.active >= c.limit {
+		c.cond.Wait()
+	}
+	c.active++
+}
+
+// Release marks a transfer of size bytes as finished with the given error, and
+// changes the limit if enough has been seen since it last changed.
+func (c *adaptiveConcurrency) Release(size int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	_, throttled := errors.IsRetriableLaterError(err)
+	switch {
+	case err == nil:
+		c.bytes += size
+		c.completed++
+	case throttled:
+		c.throttled++
+	case errors.IsRetriableError(err):
+		// Only errors which retrying might fix say anything about
+		// the load, unlike missing objects, for example.
+		c.failures++
+	}
+
+	if now := c.now(); c.throttled > 0 || c.failures > 0 || now.Sub(c.windowStart) >= adaptiveWindow {
+		c.adjust(now)
+	}
+	c.cond.Broadcast()
+}
+
+// Limit returns the number of transfers which may run at once.
+func (c *adaptiveConcurrency) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+func (c *adaptiveConcurrency) adjust(now time.Time) {
+	elapsed := now.Sub(c.windowStart).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(c.bytes) / elapsed
+	}
+
+	limit := c.limit
+	switch {
+	case c.throttled > 0:
+		limit = 1
+		c.slowStart = false
+	case c.failures > 0:
+		limit /= 2
+		c.slowStart = false
+	case c.completed == 0:
+		// Nothing finished, so there is nothing to go by yet.
+		return
+	case c.lastRate == 0 || rate > c.lastRate*1.1:
+		if c.slowStart {
+			limit *= 2
+		} else {
+			limit++
+		}
+	case rate < c.lastRate*0.9:
+		limit--
+		c.slowStart = false
+	default:
+		c.slowStart = false
+	}
+
+	if limit > c.max {
+		limit = c.max
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	if limit != c.limit {
+		tracerx.Printf("tq: adaptive concurrency %d -> %d (%.0f B/s, %d failed, %d throttled)",
+			c.limit, limit, rate, c.failures, c.throttled)
+	}
+
+	c.limit = limit
+	c.lastRate = rate
+	c.windowStart = now
+	c.bytes = 0
+	c.completed = 0
+	c.failures = 0
+	c.throttled = 0
+}
@@ -0,0 +1,106 @@
+package tq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	lfserrors "github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestAdaptiveConcurrency returns an adaptiveConcurrency with a clock which
+// only moves when the returned function is called.
+func newTestAdaptiveConcurrency(max int) (*adaptiveConcurrency, func(time.Duration)) {
+	now := time.Unix(0, 0)
+	c := newAdaptiveConcurrency(max)
+	c.now = func() time.Time { return now }
+	c.windowStart = now
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+// transferWindow runs a window of transfers at the current limit, of size
+// bytes each, the last of which finishes as the window ends.
+func transferWindow(c *adaptiveConcurrency, advance func(time.Duration), size int64) {
+	n := c.Limit()
+	for i := 0; i < n; i++ {
+		c.Acquire()
+	}
+	for i := 0; i < n-1; i++ {
+		c.Release(size, nil)
+	}
+	advance(adaptiveWindow)
+	c.Release(size, nil)
+}
+
+func TestAdaptiveConcurrencyRampsUpWhileThroughputGrows(t *testing.T) {
+	c, advance := newTestAdaptiveConcurrency(16)
+	assert.Equal(t, 2, c.Limit())
+
+	// Each transfer gets the same bandwidth, so more at once is faster.
+	transferWindow(c, advance, 1000)
+	assert.Equal(t, 4, c.Limit())
+	transferWindow(c, advance, 1000)
+	assert.Equal(t, 8, c.Limit())
+	transferWindow(c, advance, 1000)
+	assert.Equal(t, 16, c.Limit())
+	transferWindow(c, advance, 1000)
+	assert.Equal(t, 16, c.Limit())
+}
+
+func TestAdaptiveConcurrencySettlesWhenThroughputStops(t *testing.T) {
+	c, advance := newTestAdaptiveConcurrency(16)
+
+	// Throughput is the same however many transfers run at once, so
+	// only the first increase, with nothing to compare it to, is made.
+	for i := 0; i < 6; i++ {
+		transferWindow(c, advance, int64(4000/c.Limit()))
+	}
+	assert.Equal(t, 4, c.Limit())
+	assert.False(t, c.slowStart)
+}
+
+func TestAdaptiveConcurrencyBacksOffOnErrors(t *testing.T) {
+	c, advance := newTestAdaptiveConcurrency(16)
+	transferWindow(c, advance, 1000)
+	transferWindow(c, advance, 1000)
+	assert.Equal(t, 8, c.Limit())
+
+	c.Acquire()
+	c.Release(1000, lfserrors.NewRetriableError(errors.New("connection reset")))
+	assert.Equal(t, 4, c.Limit())
+
+	// Errors which say nothing about the load are ignored.
+	c.Acquire()
+	c.Release(1000, errors.New("object not found"))
+	assert.Equal(t, 4, c.Limit())
+
+	c.Acquire()
+	c.Release(1000, lfserrors.NewRetriableLaterError(errors.New("too many requests"), "1"))
+	assert.Equal(t, 1, c.Limit())
+}
+
+func TestAdaptiveConcurrencyLimitsActiveTransfers(t *testing.T) {
+	c, _ := newTestAdaptiveConcurrency(16)
+	c.Acquire()
+	c.Acquire()
+
+	started := make(chan struct{})
+	go func() {
+		c.Acquire()
+		close(started)
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("expected third transfer to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Release(1, nil)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected third transfer to start")
+	}
+}
@@ -1,7 +1,9 @@
 package tq
 
 import (
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/git"
@@ -10,6 +12,10 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+// noticeHeader is the name of the header in which a server may send an
+// informational message to be shown to the user.
+const noticeHeader = "X-Lfs-Notice"
+
 type tqClient struct {
 	maxRetries int
 	*lfsapi.Client
@@ -29,7 +35,45 @@ type batchRequest struct {
 type BatchResponse struct {
 	Objects             []*Transfer `json:"objects"`
 	TransferAdapterName string      `json:"transfer"`
-	endpoint            lfshttp.Endpoint
+	// Message is an optional informational message from the server, such
+	// as an announcement of upcoming maintenance, to be shown to the user.
+	Message string `json:"message,omitempty"`
+	// Capabilities optionally describes what the server supports.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+	endpoint     lfshttp.Endpoint
+	// notices holds the values of any X-Lfs-Notice headers sent along
+	// with the response.
+	notices []string
+}
+
+// Notices returns the informational messages sent by the server in this
+// response, from both the X-Lfs-Notice header(s) and the "message" field, one
+// per line. Non-printable characters are removed, so that the server cannot
+// send escape sequences to the user's terminal.
+func (r *BatchResponse) Notices() []string {
+	var notices []string
+	for _, n := range append(r.notices, r.Message) {
+		for _, line := range strings.Split(n, "\n") {
+			line = strings.TrimSpace(strings.Map(printableNoticeRune, line))
+			if len(line) > 0 {
+				notices = append(notices, line)
+			}
+		}
+	}
+	return notices
+}
+
+// printableNoticeRune returns the given rune of a server notice, a space in
+// place of a tab, or -1 to remove it if it cannot be printed.
+func printableNoticeRune(r rune) rune {
+	switch {
+	case r == '\t':
+		return ' '
+	case unicode.IsPrint(r):
+		return r
+	default:
+		return -1
+	}
 }
 
 func Batch(m *Manifest, dir Direction, remote string, remoteRef *git.Ref, objects []*Transfer) (*BatchResponse, error) {
@@ -37,12 +81,20 @@ func Batch(m *Manifest, dir Direction, remote string, remoteRef *git.Ref, object
 		return &BatchResponse{}, nil
 	}
 
-	return m.batchClient().Batch(remote, &batchRequest{
+	bReq := &batchRequest{
 		Operation:            dir.String(),
 		Objects:              objects,
 		TransferAdapterNames: m.GetAdapterNames(dir),
 		Ref:                  &batchRef{Name: remoteRef.Refspec()},
-	})
+	}
+
+	start := time.Now()
+	bRes, err := m.batchClient().Batch(remote, bReq)
+	if m.recorder != nil {
+		e := m.APIClient().Endpoints.Endpoint(bReq.Operation, remote)
+		m.recorder.RecordBatch(remote, e.Url, bReq, bRes, err, time.Since(start))
+	}
+	return bRes, err
 }
 
 type BatchClient interface {
@@ -99,6 +151,8 @@ func (c *tqClient) Batch(remote string, bReq *batchRequest) (*BatchResponse, err
 		return nil, lfshttp.NewStatusCodeError(res)
 	}
 
+	bRes.notices = res.Header[noticeHeader]
+
 	for _, obj := range bRes.Objects {
 		obj.Missing = missing[obj.Oid]
 		for _, a := range obj.Actions {
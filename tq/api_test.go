@@ -142,6 +142,105 @@ func TestAPIBatchEmptyObjects(t *testing.T) {
 	assert.Equal(t, 0, len(bRes.Objects))
 }
 
+func TestAPIBatchNotices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bReq := &batchRequest{}
+		err := json.NewDecoder(r.Body).Decode(bReq)
+		r.Body.Close()
+		assert.Nil(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Lfs-Notice", "maintenance tonight")
+		w.Header().Add("X-Lfs-Notice", " ")
+
+		writeLoader, resWriter := gojsonschema.NewWriterLoader(w)
+		err = json.NewEncoder(resWriter).Encode(&BatchResponse{
+			Objects: bReq.Objects,
+			Message: "this endpoint is deprecated",
+		})
+
+		assert.Nil(t, err)
+		assertSchema(t, batchResSchema, writeLoader)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c}
+	bRes, err := tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{
+			&Transfer{Oid: "a", Size: 1},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		"maintenance tonight",
+		"this endpoint is deprecated",
+	}, bRes.Notices())
+}
+
+func TestBatchResponseNoticesRemoveNonPrintable(t *testing.T) {
+	bRes := &BatchResponse{
+		Message: "first\x1b[2J line\r\nsecond\tline\x07\n\x1b\n",
+		notices: []string{"caf\u00e9 \x1b]0;title\x07closed"},
+	}
+	assert.Equal(t, []string{
+		"caf\u00e9 ]0;titleclosed",
+		"first[2J line",
+		"second line",
+	}, bRes.Notices())
+}
+
+func TestAPIBatchCapabilities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bReq := &batchRequest{}
+		err := json.NewDecoder(r.Body).Decode(bReq)
+		r.Body.Close()
+		assert.Nil(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		locking := true
+		writeLoader, resWriter := gojsonschema.NewWriterLoader(w)
+		err = json.NewEncoder(resWriter).Encode(&BatchResponse{
+			Objects: bReq.Objects,
+			Capabilities: &Capabilities{
+				Transfers:      []string{"basic", "tus"},
+				MaxBatchSize:   50,
+				ChunkedUploads: true,
+				Locking:        &locking,
+			},
+		})
+
+		assert.Nil(t, err)
+		assertSchema(t, batchResSchema, writeLoader)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c}
+	bRes, err := tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{
+			&Transfer{Oid: "a", Size: 1},
+		},
+	})
+	require.Nil(t, err)
+	require.NotNil(t, bRes.Capabilities)
+	assert.Equal(t, []string{"basic", "tus"}, bRes.Capabilities.Transfers)
+	assert.Equal(t, 50, bRes.Capabilities.MaxBatchSize)
+	assert.True(t, bRes.Capabilities.ChunkedUploads)
+	if assert.NotNil(t, bRes.Capabilities.Locking) {
+		assert.True(t, *bRes.Capabilities.Locking)
+	}
+}
+
 var (
 	batchReqSchema *sourcedSchema
 	batchResSchema *sourcedSchema
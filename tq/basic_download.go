@@ -1,6 +1,7 @@
 package tq
 
 import (
+	"crypto/md5"
 	"fmt"
 	"hash"
 	"io"
@@ -61,6 +62,7 @@ func (a *basicDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progr
 
 	// Attempt to resume download. No error checking here. If we fail, we'll simply download from the start
 	tools.RobustRename(a.downloadFilename(t), f.Name())
+	validators := readDownloadValidators(a.validatorsFilename(t))
 
 	// Open temp file. It is either empty or partially downloaded
 	f, err = os.OpenFile(f.Name(), os.O_RDWR, 0644)
@@ -91,14 +93,19 @@ func (a *basicDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progr
 			hash = nil
 		}
 	}
+	if fromByte == 0 {
+		validators = &downloadValidators{}
+	}
 
-	err = a.download(t, cb, authOkFunc, f, fromByte, hash)
+	err = a.download(t, cb, authOkFunc, f, fromByte, hash, validators)
 
 	if err != nil {
 		f.Close()
 		// Rename file so next download can resume from where we stopped.
 		// No error checking here, if rename fails then file will be deleted and there just will be no download resuming
-		tools.RobustRename(f.Name(), a.downloadFilename(t))
+		if tools.RobustRename(f.Name(), a.downloadFilename(t)) == nil {
+			writeDownloadValidators(a.validatorsFilename(t), validators)
+		}
 	}
 
 	return err
@@ -109,8 +116,16 @@ func (a *basicDownloadAdapter) downloadFilename(t *Transfer) string {
 	return filepath.Join(a.tempDir(), t.Oid+".part")
 }
 
-// download starts or resumes and download. dlFile is expected to be an existing file open in RW mode
-func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOkFunc func(), dlFile *os.File, fromByte int64, hash hash.Hash) error {
+// Returns path where the validators of the response to a partial download are
+// stored, so that resuming it can check the object has not changed
+func (a *basicDownloadAdapter) validatorsFilename(t *Transfer) string {
+	return filepath.Join(a.tempDir(), t.Oid+".validators")
+}
+
+// download starts or resumes and download. dlFile is expected to be an existing file open in RW mode.
+// validators are those of the response the partial download came from, and are replaced with those
+// of the new response
+func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOkFunc func(), dlFile *os.File, fromByte int64, hash hash.Hash, validators *downloadValidators) error {
 	rel, err := t.Rel("download")
 	if err != nil {
 		return err
@@ -127,6 +142,15 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 	if fromByte > 0 {
 		// We could just use a start byte, but since we know the length be specific
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", fromByte, t.Size-1))
+
+		// Only resume if the content has not changed since the partial
+		// download, otherwise the server sends all of it again
+		if ifRange := validators.ifRange(); len(ifRange) > 0 {
+			req.Header.Set("If-Range", ifRange)
+			tracerx.Printf("xfer: resuming download of %q if unchanged since %s", t.Oid, ifRange)
+		} else if validators.Immutable {
+			tracerx.Printf("xfer: resuming download of %q without revalidation, as it is immutable", t.Oid)
+		}
 	}
 
 	req = a.apiClient.LogRequest(req, "lfs.data.download")
@@ -147,7 +171,7 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 			if err := dlFile.Truncate(0); err != nil {
 				return err
 			}
-			return a.download(t, cb, authOkFunc, dlFile, 0, nil)
+			return a.download(t, cb, authOkFunc, dlFile, 0, nil, validators)
 		}
 
 		// Special-cae status code 429 - retry after certain time
@@ -210,11 +234,20 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 				// sent everything. Don't re-request, use this one from byte 0
 			} else {
 				// re-request needed
-				return a.download(t, cb, authOkFunc, dlFile, fromByte, hash)
+				return a.download(t, cb, authOkFunc, dlFile, fromByte, hash, validators)
 			}
 		}
 	}
 
+	validators.setFromResponse(res)
+
+	// Fail early if the server's checksum of the object shows it is not
+	// the one asked for
+	digests := responseDigests(res, res.Header)
+	if err := digests.checkOid(t); err != nil {
+		return err
+	}
+
 	// Signal auth OK on success response, before starting download to free up
 	// other workers immediately
 	if authOkFunc != nil {
@@ -222,7 +255,8 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 	}
 
 	var hasher *tools.HashingReader
-	httpReader := tools.NewRetriableReader(res.Body)
+	bodyMD5 := md5.New()
+	httpReader := tools.NewRetriableReader(io.TeeReader(res.Body, bodyMD5))
 
 	if fromByte > 0 && hash != nil {
 		// pre-load hashing reader with previous content
@@ -244,6 +278,13 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 		return errors.Wrapf(err, "cannot write data to tempfile %q", dlfilename)
 	}
 
+	digests.mergeTrailers(res)
+	if err := digests.check(t, bodyMD5, fromByte == 0); err != nil {
+		// The content is corrupt, so do not resume from it
+		dlFile.Truncate(0)
+		return err
+	}
+
 	if actual := hasher.Hash(); actual != t.Oid {
 		return fmt.Errorf("expected OID %s, got %s after %d bytes written", t.Oid, actual, written)
 	}
@@ -49,6 +49,14 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progres
 		return errors.Errorf("No upload action for object: %s", t.Oid)
 	}
 
+	if a.apiClient.GitEnv().Bool(uploadClaimsKey, defaultUploadClaims) && a.awaitUploadClaim(t, rel) {
+		if authOkFunc != nil {
+			authOkFunc()
+		}
+		advanceCallbackProgress(cb, t, t.Size)
+		return verifyUpload(a.apiClient, a.remote, t)
+	}
+
 	req, err := a.newHTTPRequest("PUT", rel)
 	if err != nil {
 		return err
@@ -72,6 +80,12 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progres
 		return err
 	}
 
+	if a.apiClient.GitEnv().Bool(sendDigestKey, defaultSendDigest) {
+		if err := setUploadDigests(req, t, f); err != nil {
+			return err
+		}
+	}
+
 	// Ensure progress callbacks made while uploading
 	// Wrap callback to give name context
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
@@ -81,7 +95,8 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progres
 		return nil
 	}
 
-	cbr := tools.NewFileBodyWithCallback(f, t.Size, ccb)
+	verifier := newVerifyingReader(tools.NewFileBody(f), t)
+	cbr := tools.NewBodyWithCallback(verifier, t.Size, ccb)
 	var reader lfsapi.ReadSeekCloser = cbr
 
 	// Signal auth was ok on first read; this frees up other workers to start
@@ -97,6 +112,13 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progres
 	req = a.apiClient.LogRequest(req, "lfs.data.upload")
 	res, err := a.makeRequest(t, req)
 	if err != nil {
+		if verifier.err != nil {
+			// The object's content changed, so retrying would
+			// fail again.
+			cbr.ResetProgress()
+			return verifier.err
+		}
+
 		if errors.IsUnprocessableEntityError(err) {
 			// If we got an HTTP 422, we do _not_ want to retry the
 			// request later below, because it is likely that the
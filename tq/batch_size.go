@@ -0,0 +1,94 @@
+package tq
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+// fastBatchTime is the longest a full batch request may take for the batch
+// size to be grown.
+const fastBatchTime = time.Second
+
+// batchSizer holds the number of objects to send in each batch request. It
+// starts at its maximum, is halved whenever the server rejects a batch as too
+// large or a batch request times out, and is doubled again, up to the maximum,
+// whenever a full batch completes quickly.
+type batchSizer struct {
+	size int
+	max  int
+	mu   sync.Mutex
+}
+
+func newBatchSizer(max int) *batchSizer {
+	return &batchSizer{size: max, max: max}
+}
+
+// Size returns the current batch size.
+func (b *batchSizer) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Shrink halves the batch size if the given batch request error shows that the
+// batch was too large, and returns whether it did so.
+func (b *batchSizer) Shrink(err error) bool {
+	if !errors.IsRequestEntityTooLargeError(err) && !isTimeout(err) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size <= 1 {
+		return false
+	}
+	b.size /= 2
+	tracerx.Printf("tq: shrinking batch size to %d: %s", b.size, err)
+	return true
+}
+
+// Grow doubles the batch size, up to its maximum, if a batch request for the
+// given number of objects was full and completed within fastBatchTime.
+func (b *batchSizer) Grow(n int, elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n < b.size || b.size >= b.max || elapsed > fastBatchTime {
+		return
+	}
+	b.size *= 2
+	if b.size > b.max {
+		b.size = b.max
+	}
+	tracerx.Printf("tq: growing batch size to %d", b.size)
+}
+
+// Limit lowers the maximum batch size, and the current size if necessary, to
+// the given most objects the server accepts in a batch.
+func (b *batchSizer) Limit(max int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if max <= 0 || max >= b.max {
+		return
+	}
+	b.max = max
+	if b.size > max {
+		b.size = max
+	}
+	tracerx.Printf("tq: limiting batch size to %d for the server", max)
+}
+
+// isTimeout returns whether the given error is the result of a network
+// timeout.
+func isTimeout(err error) bool {
+	if cause, ok := errors.Cause(err).(net.Error); ok {
+		return cause.Timeout()
+	}
+	return false
+}
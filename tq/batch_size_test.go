@@ -0,0 +1,91 @@
+package tq
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestBatchSizerShrinksOnRequestEntityTooLarge(t *testing.T) {
+	b := newBatchSizer(100)
+
+	assert.True(t, b.Shrink(errors.NewRequestEntityTooLargeError(errors.New("too large"))))
+	assert.Equal(t, 50, b.Size())
+}
+
+func TestBatchSizerShrinksOnTimeout(t *testing.T) {
+	b := newBatchSizer(100)
+
+	err := errors.Wrap(&url.Error{Op: "Post", URL: "https://example.com", Err: timeoutError{}}, "batch response")
+	assert.True(t, b.Shrink(err))
+	assert.Equal(t, 50, b.Size())
+}
+
+func TestBatchSizerDoesNotShrinkOnOtherErrors(t *testing.T) {
+	b := newBatchSizer(100)
+
+	assert.False(t, b.Shrink(errors.New("other")))
+	assert.Equal(t, 100, b.Size())
+}
+
+func TestBatchSizerDoesNotShrinkBelowOne(t *testing.T) {
+	b := newBatchSizer(2)
+	err := errors.NewRequestEntityTooLargeError(errors.New("too large"))
+
+	assert.True(t, b.Shrink(err))
+	assert.Equal(t, 1, b.Size())
+	assert.False(t, b.Shrink(err))
+	assert.Equal(t, 1, b.Size())
+}
+
+func TestBatchSizerGrowsUpToMaximum(t *testing.T) {
+	b := newBatchSizer(100)
+	err := errors.NewRequestEntityTooLargeError(errors.New("too large"))
+	b.Shrink(err)
+	b.Shrink(err)
+	assert.Equal(t, 25, b.Size())
+
+	b.Grow(25, time.Millisecond)
+	assert.Equal(t, 50, b.Size())
+	b.Grow(50, time.Millisecond)
+	assert.Equal(t, 100, b.Size())
+	b.Grow(100, time.Millisecond)
+	assert.Equal(t, 100, b.Size())
+}
+
+func TestBatchSizerDoesNotGrowForSlowOrPartialBatches(t *testing.T) {
+	b := newBatchSizer(100)
+	b.Shrink(errors.NewRequestEntityTooLargeError(errors.New("too large")))
+
+	b.Grow(50, 2*fastBatchTime)
+	assert.Equal(t, 50, b.Size())
+	b.Grow(10, time.Millisecond)
+	assert.Equal(t, 50, b.Size())
+}
+
+func TestBatchSizerLimitLowersMaximum(t *testing.T) {
+	b := newBatchSizer(100)
+
+	b.Limit(30)
+	assert.Equal(t, 30, b.Size())
+
+	b.Grow(30, time.Millisecond)
+	assert.Equal(t, 30, b.Size())
+}
+
+func TestBatchSizerLimitDoesNotRaiseMaximum(t *testing.T) {
+	b := newBatchSizer(100)
+
+	b.Limit(0)
+	b.Limit(200)
+	assert.Equal(t, 100, b.Size())
+}
@@ -0,0 +1,54 @@
+package tq
+
+import (
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// Capabilities describes what a Git LFS server supports, as advertised in the
+// optional "capabilities" object of its batch responses, so that the client
+// can tune itself to each server without being configured to.
+type Capabilities struct {
+	// Transfers holds the names of the transfer adapters which the server
+	// supports, in addition to "basic", which all servers support.
+	Transfers []string `json:"transfers,omitempty"`
+	// MaxBatchSize is the most objects the server accepts in one batch
+	// request, or zero if it does not say.
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+	// ChunkedUploads is whether the server accepts basic uploads sent
+	// with chunked transfer encoding.
+	ChunkedUploads bool `json:"chunked_uploads,omitempty"`
+	// Locking is whether the server supports the File Locking API, or nil
+	// if it does not say.
+	Locking *bool `json:"locking,omitempty"`
+}
+
+// SupportsTransfer returns whether the server supports the transfer adapter
+// with the given name. All adapters are assumed to be supported if the server
+// does not list them.
+func (c *Capabilities) SupportsTransfer(name string) bool {
+	if c == nil || len(c.Transfers) == 0 || name == BasicAdapterName {
+		return true
+	}
+	return tools.NewStringSetFromSlice(c.Transfers).Contains(name)
+}
+
+// setCapabilities records the capabilities advertised by the server, which
+// are used for the rest of the manifest's transfers.
+func (m *Manifest) setCapabilities(c *Capabilities) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.capabilities == nil {
+		tracerx.Printf("tq: server capabilities: transfers=%v max_batch_size=%d chunked_uploads=%t", c.Transfers, c.MaxBatchSize, c.ChunkedUploads)
+	}
+	m.capabilities = c
+}
+
+// Capabilities returns the capabilities advertised by the server in the batch
+// responses so far, or nil if it has not advertised any.
+func (m *Manifest) Capabilities() *Capabilities {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.capabilities
+}
@@ -0,0 +1,154 @@
+package tq
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	sendDigestKey     = "lfs.transfer.senddigest"
+	defaultSendDigest = false
+)
+
+// contentDigests are the checksums of an object's content which a server
+// gives in the Content-MD5 and Digest headers or trailers of a download's
+// response, so that corruption is detected as soon as possible.
+type contentDigests struct {
+	// bodyMD5 is the MD5 checksum of the body of the response, from
+	// Content-MD5, which covers only the range sent when resuming.
+	bodyMD5 []byte
+	// md5 and sha256 are the checksums of the whole object, from Digest.
+	md5    []byte
+	sha256 []byte
+}
+
+// responseDigests reads the checksums from the given headers or trailers of
+// the given response. MD5 checksums are ignored if the body has a content
+// coding, since they are of the encoded body.
+func responseDigests(res *http.Response, h http.Header) *contentDigests {
+	d := parseContentDigests(h)
+	if res.Uncompressed || len(res.Header.Get("Content-Encoding")) > 0 {
+		d.bodyMD5, d.md5 = nil, nil
+	}
+	return d
+}
+
+// parseContentDigests reads the checksums from the given headers or trailers.
+// Those which are malformed, or use algorithms other than MD5 and SHA-256, are
+// ignored.
+func parseContentDigests(h http.Header) *contentDigests {
+	d := &contentDigests{}
+
+	if v := h.Get("Content-MD5"); len(v) > 0 {
+		d.bodyMD5 = decodeDigest("Content-MD5", v, md5.Size)
+	}
+
+	for _, value := range h["Digest"] {
+		for _, instance := range strings.Split(value, ",") {
+			parts := strings.SplitN(strings.TrimSpace(instance), "=", 2)
+			if len(parts) < 2 {
+				continue
+			}
+
+			switch strings.ToUpper(parts[0]) {
+			case "MD5":
+				d.md5 = decodeDigest("Digest MD5", parts[1], md5.Size)
+			case "SHA-256":
+				d.sha256 = decodeDigest("Digest SHA-256", parts[1], 32)
+			}
+		}
+	}
+	return d
+}
+
+func decodeDigest(name, value string, size int) []byte {
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(sum) != size {
+		tracerx.Printf("xfer: ignoring malformed %s checksum %q", name, value)
+		return nil
+	}
+	return sum
+}
+
+// mergeTrailers adds the checksums sent in the trailers of the given
+// response, which are only known once its body has been read, unless they
+// were sent as headers.
+func (d *contentDigests) mergeTrailers(res *http.Response) {
+	o := responseDigests(res, res.Trailer)
+	if d.bodyMD5 == nil {
+		d.bodyMD5 = o.bodyMD5
+	}
+	if d.md5 == nil {
+		d.md5 = o.md5
+	}
+	if d.sha256 == nil {
+		d.sha256 = o.sha256
+	}
+}
+
+// checkOid returns an error if the SHA-256 checksum of the object does not
+// match its OID, which can be checked before any of it is read.
+func (d *contentDigests) checkOid(t *Transfer) error {
+	if d.sha256 == nil {
+		return nil
+	}
+	if actual := hex.EncodeToString(d.sha256); actual != t.Oid {
+		return errors.Errorf("server's Digest for %s has SHA-256 %s, expected OID %s", t.Name, actual, t.Oid)
+	}
+	return nil
+}
+
+// check returns an error if the MD5 checksums of the body of the response, or
+// of the whole object if it was sent whole, do not match those given.
+func (d *contentDigests) check(t *Transfer, bodyMD5 hash.Hash, whole bool) error {
+	if err := d.checkOid(t); err != nil {
+		return err
+	}
+
+	sum := bodyMD5.Sum(nil)
+	if d.bodyMD5 != nil && !bytes.Equal(d.bodyMD5, sum) {
+		return errors.Errorf("Content-MD5 mismatch for %s: expected %s, got %s",
+			t.Name, base64.StdEncoding.EncodeToString(d.bodyMD5), base64.StdEncoding.EncodeToString(sum))
+	}
+	if whole && d.md5 != nil && !bytes.Equal(d.md5, sum) {
+		return errors.Errorf("Digest MD5 mismatch for %s: expected %s, got %s",
+			t.Name, base64.StdEncoding.EncodeToString(d.md5), base64.StdEncoding.EncodeToString(sum))
+	}
+	return nil
+}
+
+// setUploadDigests sets the Content-MD5 and Digest headers of an upload of
+// the given object, as lfs.transfer.senddigest asks, unless the server's
+// upload action set them already. The SHA-256 checksum is the object's OID,
+// while the MD5 checksum is calculated from the file.
+func setUploadDigests(req *http.Request, t *Transfer, f *os.File) error {
+	if len(req.Header.Get("Digest")) == 0 {
+		sum, err := hex.DecodeString(t.Oid)
+		if err != nil {
+			return errors.Wrap(err, "upload digest")
+		}
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum))
+	}
+
+	if len(req.Header.Get("Content-MD5")) == 0 {
+		h := md5.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return errors.Wrap(err, "upload digest")
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "upload digest rewind")
+		}
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+	return nil
+}
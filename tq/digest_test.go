@@ -0,0 +1,104 @@
+package tq
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digestTestOid is the OID of "hello, world".
+const digestTestOid = "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"
+
+func digestTestMD5(content string) string {
+	sum := md5.Sum([]byte(content))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestParseContentDigests(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-MD5", digestTestMD5("hello"))
+	h.Add("Digest", "sha-256=Ccp+TqpuiunH0mEWcSkYSINkTQffuny/vEyKLgg2DVs=, unixsum=30637")
+	h.Add("Digest", "MD5="+digestTestMD5("hello, world"))
+
+	d := parseContentDigests(h)
+	assert.Len(t, d.bodyMD5, md5.Size)
+	assert.Len(t, d.md5, md5.Size)
+	assert.Len(t, d.sha256, 32)
+	assert.Nil(t, d.checkOid(&Transfer{Oid: digestTestOid}))
+	assert.NotNil(t, d.checkOid(&Transfer{Oid: "abc"}))
+
+	h = http.Header{}
+	h.Set("Content-MD5", "not base64!")
+	h.Set("Digest", "SHA-256=aGVsbG8=")
+	d = parseContentDigests(h)
+	assert.Nil(t, d.bodyMD5)
+	assert.Nil(t, d.sha256)
+}
+
+func TestContentDigestsCheck(t *testing.T) {
+	tr := &Transfer{Name: "a.dat", Oid: digestTestOid}
+	body := md5.New()
+	body.Write([]byte("world"))
+
+	h := http.Header{}
+	h.Set("Content-MD5", digestTestMD5("world"))
+	h.Set("Digest", "MD5="+digestTestMD5("hello, world"))
+	d := parseContentDigests(h)
+
+	// Only the range sent is checked when resuming.
+	assert.Nil(t, d.check(tr, body, false))
+
+	err := d.check(tr, body, true)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Digest MD5 mismatch for a.dat")
+
+	h.Set("Content-MD5", digestTestMD5("hello"))
+	err = parseContentDigests(h).check(tr, body, false)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Content-MD5 mismatch for a.dat")
+}
+
+func TestContentDigestsTrailers(t *testing.T) {
+	res := &http.Response{Header: http.Header{}, Trailer: http.Header{}}
+	d := responseDigests(res, res.Header)
+	assert.Nil(t, d.bodyMD5)
+
+	res.Trailer.Set("Content-MD5", digestTestMD5("hello"))
+	d.mergeTrailers(res)
+	assert.NotNil(t, d.bodyMD5)
+
+	res.Header.Set("Content-Encoding", "gzip")
+	d = responseDigests(res, res.Header)
+	d.mergeTrailers(res)
+	assert.Nil(t, d.bodyMD5)
+}
+
+func TestSetUploadDigests(t *testing.T) {
+	f, err := ioutil.TempFile("", "upload-digest")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.WriteString("hello, world")
+	require.Nil(t, err)
+	_, err = f.Seek(0, 0)
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("PUT", "https://example.com/", nil)
+	require.Nil(t, err)
+	require.Nil(t, setUploadDigests(req, &Transfer{Oid: digestTestOid}, f))
+
+	assert.Equal(t, digestTestMD5("hello, world"), req.Header.Get("Content-MD5"))
+	assert.Equal(t, "SHA-256=Ccp+TqpuiunH0mEWcSkYSINkTQffuny/vEyKLgg2DVs=", req.Header.Get("Digest"))
+
+	// The file is rewound for the upload.
+	data, err := ioutil.ReadAll(f)
+	require.Nil(t, err)
+	assert.Equal(t, "hello, world", string(data))
+}
@@ -0,0 +1,93 @@
+package tq
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rubyist/tracerx"
+)
+
+// downloadValidators are the validators given in the response to a download,
+// saved alongside its partially downloaded file, so that a resumed download
+// continues from that file only if the object's content on the server has not
+// changed since, instead of mixing old and new content.
+type downloadValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// Immutable is set if the response was marked with "Cache-Control:
+	// immutable", in which case the content never changes, and a resumed
+	// download needs no revalidation.
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// setFromResponse records the validators given in the given response.
+func (v *downloadValidators) setFromResponse(res *http.Response) {
+	*v = downloadValidators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+
+	for _, value := range res.Header["Cache-Control"] {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "immutable") {
+				v.Immutable = true
+			}
+		}
+	}
+}
+
+// empty returns whether no validators were given.
+func (v *downloadValidators) empty() bool {
+	return len(v.ETag) == 0 && len(v.LastModified) == 0 && !v.Immutable
+}
+
+// ifRange returns the value of the If-Range header to send when resuming a
+// download, or the empty string if none should be sent. Only strong ETags may
+// be used, and immutable content needs none.
+func (v *downloadValidators) ifRange() string {
+	if v.Immutable {
+		return ""
+	}
+	if len(v.ETag) > 0 && !strings.HasPrefix(v.ETag, "W/") {
+		return v.ETag
+	}
+	return v.LastModified
+}
+
+// readDownloadValidators reads the validators saved in the given file, and
+// removes it. Empty validators are returned if there are none.
+func readDownloadValidators(path string) *downloadValidators {
+	v := &downloadValidators{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return v
+	}
+	os.Remove(path)
+
+	if err := json.Unmarshal(data, v); err != nil {
+		tracerx.Printf("xfer: ignoring invalid download validators in %q: %v", path, err)
+		return &downloadValidators{}
+	}
+	return v
+}
+
+// writeDownloadValidators saves the given validators in the given file, unless
+// they are empty.
+func writeDownloadValidators(path string, v *downloadValidators) {
+	if v.empty() {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		tracerx.Printf("xfer: unable to save download validators in %q: %v", path, err)
+	}
+}
@@ -0,0 +1,53 @@
+package tq
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadValidatorsFromResponse(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("ETag", `"abc"`)
+	res.Header.Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+
+	v := &downloadValidators{}
+	v.setFromResponse(res)
+	assert.Equal(t, `"abc"`, v.ifRange())
+	assert.False(t, v.Immutable)
+
+	res.Header.Set("ETag", `W/"abc"`)
+	v.setFromResponse(res)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", v.ifRange())
+
+	res.Header.Set("Cache-Control", "public, max-age=31536000, Immutable")
+	v.setFromResponse(res)
+	assert.True(t, v.Immutable)
+	assert.Empty(t, v.ifRange())
+
+	v.setFromResponse(&http.Response{Header: http.Header{}})
+	assert.True(t, v.empty())
+}
+
+func TestDownloadValidatorsRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "download-validators")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "oid.validators")
+	writeDownloadValidators(path, &downloadValidators{})
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	writeDownloadValidators(path, &downloadValidators{ETag: `"abc"`})
+	assert.Equal(t, &downloadValidators{ETag: `"abc"`}, readDownloadValidators(path))
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, &downloadValidators{}, readDownloadValidators(path))
+}
@@ -1,6 +1,14 @@
 package tq
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+)
+
+// errTransferCanceled is returned by the progress callback to stop a transfer
+// in progress when the queue is canceled.
+var errTransferCanceled = errors.New("transfer canceled")
 
 type MalformedObjectError struct {
 	Name string
@@ -27,3 +35,68 @@ func (e MalformedObjectError) Error() string {
 	}
 	return fmt.Sprintf("missing object: %s (%s)", e.Name, e.Oid)
 }
+
+// ObjectChangedError is returned when the content of an object being uploaded
+// does not match its OID and size, such as because its file was changed while
+// it was being uploaded. The upload is stopped before it completes, so the
+// server never receives the wrong content.
+type ObjectChangedError struct {
+	Name string
+	Oid  string
+	Size int64
+	Err  error
+}
+
+func newObjectChangedError(name, oid string, size int64, err error) error {
+	return &ObjectChangedError{Name: name, Oid: oid, Size: size, Err: err}
+}
+
+func (e *ObjectChangedError) Error() string {
+	return fmt.Sprintf("object %s (%s) changed while it was being uploaded: %v", e.Name, e.Oid, e.Err)
+}
+
+// IsObjectChangedError returns whether the given error, or its cause, is an
+// *ObjectChangedError.
+func IsObjectChangedError(err error) bool {
+	_, ok := errors.Cause(err).(*ObjectChangedError)
+	return ok
+}
+
+// QuarantinedObjectError is returned for an uploaded object which the server
+// still holds in quarantine pending a scan, such as for viruses, once Git LFS
+// has stopped verifying it. The server has the object, but may not make it
+// available until the scan completes, and may yet reject it.
+type QuarantinedObjectError struct {
+	Name    string
+	Oid     string
+	Message string
+}
+
+func newQuarantinedObjectError(name, oid, message string) error {
+	return &QuarantinedObjectError{Name: name, Oid: oid, Message: message}
+}
+
+func (e *QuarantinedObjectError) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("object %s (%s) is quarantined pending a scan: %s", e.Name, e.Oid, e.Message)
+	}
+	return fmt.Sprintf("object %s (%s) is quarantined pending a scan", e.Name, e.Oid)
+}
+
+// ObjectTransferError is returned for an object which the server refused to
+// transfer in its batch response, such as because it does not have it, or
+// the user is not allowed to access it.
+type ObjectTransferError struct {
+	Name string
+	Oid  string
+	Err  *ObjectError
+}
+
+func (e *ObjectTransferError) Error() string {
+	return fmt.Sprintf("[%v] %v: %v", e.Oid, e.Err.Message, e.Err)
+}
+
+// Cause returns the error the server gave for the object.
+func (e *ObjectTransferError) Cause() error {
+	return e.Err
+}
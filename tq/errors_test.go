@@ -21,3 +21,10 @@ func TestCorruptObjectErrorsAreRecognizable(t *testing.T) {
 	assert.Equal(t, "some-oid", err.Oid)
 	assert.True(t, err.Corrupt())
 }
+
+func TestObjectErrorsReportMissingObjects(t *testing.T) {
+	assert.True(t, (&ObjectError{Code: 404}).Missing())
+	assert.True(t, (&ObjectError{Code: 410}).Missing())
+	assert.False(t, (&ObjectError{Code: 403}).Missing())
+	assert.False(t, (&ObjectError{Code: 500}).Missing())
+}
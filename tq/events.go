@@ -0,0 +1,180 @@
+package tq
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/rubyist/tracerx"
+)
+
+// The kinds of event written to the events stream over the lifecycle of each
+// object in a transfer queue.
+const (
+	// EventQueued is written when an object is added to a queue.
+	EventQueued = "queued"
+	// EventStarted is written when the transfer of an object begins,
+	// once the server has said where to transfer it.
+	EventStarted = "started"
+	// EventProgress is written each time more of an object has been
+	// transferred.
+	EventProgress = "progress"
+	// EventVerified is written once an object has been transferred, and
+	// its content checked on download, or confirmed by the server on
+	// upload.
+	EventVerified = "verified"
+	// EventSkipped is written for an object which need not be
+	// transferred, because the server already has it.
+	EventSkipped = "skipped"
+	// EventFailed is written once the transfer of an object has failed,
+	// and will not be retried.
+	EventFailed = "failed"
+)
+
+// Event is a single line of JSON written to the events stream.
+type Event struct {
+	Event     string    `json:"event"`
+	Direction string    `json:"direction"`
+	Oid       string    `json:"oid,omitempty"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Bytes     int64     `json:"bytes,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// eventStream writes events, one JSON object per line, to the file descriptor
+// given by GIT_LFS_EVENTS_FD, or the Unix socket given by
+// GIT_LFS_EVENTS_SOCKET, so that wrappers and graphical clients can show the
+// progress of each object.
+type eventStream struct {
+	w  io.WriteCloser
+	mu sync.Mutex
+}
+
+var (
+	// eventStreams holds the streams opened so far by where they write,
+	// so that every manifest writes to the same one.
+	eventStreams   = make(map[string]*eventStream)
+	eventStreamsMu sync.Mutex
+)
+
+// newEventStream returns the events stream given by the environment, or nil,
+// which writes nothing, if there is none or it cannot be opened.
+func newEventStream(osEnv config.Environment) *eventStream {
+	if osEnv == nil {
+		return nil
+	}
+
+	key, open := "", func() (io.WriteCloser, error) { return nil, nil }
+	if v, _ := osEnv.Get("GIT_LFS_EVENTS_FD"); len(v) > 0 {
+		key = "fd:" + v
+		open = func() (io.WriteCloser, error) {
+			fd, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			return os.NewFile(uintptr(fd), "events"), nil
+		}
+	} else if v, _ := osEnv.Get("GIT_LFS_EVENTS_SOCKET"); len(v) > 0 {
+		key = "socket:" + v
+		open = func() (io.WriteCloser, error) {
+			return net.Dial("unix", v)
+		}
+	} else {
+		return nil
+	}
+
+	eventStreamsMu.Lock()
+	defer eventStreamsMu.Unlock()
+
+	if s, ok := eventStreams[key]; ok {
+		return s
+	}
+
+	w, err := open()
+	if err != nil || w == nil {
+		tracerx.Printf("tq: unable to open events stream %q: %v", key, err)
+		eventStreams[key] = nil
+		return nil
+	}
+
+	s := &eventStream{w: w}
+	eventStreams[key] = s
+	return s
+}
+
+// Write writes the given event as a line of JSON. If the stream cannot be
+// written to, such as because the reader has gone away, no further events are
+// written.
+func (s *eventStream) Write(e *Event) {
+	if s == nil {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		tracerx.Printf("tq: unable to encode event: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w == nil {
+		return
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		tracerx.Printf("tq: unable to write event, disabling events: %v", err)
+		s.w = nil
+	}
+}
+
+// emitEvent writes an event of the given kind for the object with the given
+// OID to the manifest's events stream, if any. The object's name and size are
+// those with which it was first added to the queue.
+func (q *TransferQueue) emitEvent(kind, oid string, bytes int64, err error) {
+	if q.manifest.events == nil || q.dryRun {
+		return
+	}
+
+	q.trMutex.Lock()
+	objs, ok := q.transfers[oid]
+	q.trMutex.Unlock()
+	if !ok || objs.First() == nil {
+		return
+	}
+
+	e := &Event{
+		Event:     kind,
+		Direction: q.direction.String(),
+		Oid:       oid,
+		Name:      objs.First().Name,
+		Size:      objs.First().Size,
+		Bytes:     bytes,
+		Time:      time.Now().UTC(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	q.manifest.events.Write(e)
+}
+
+// emitProgress writes a progress event for the object being transferred to
+// the given name, which is how transfer adapters report progress.
+func (q *TransferQueue) emitProgress(name string, read int64) {
+	if q.manifest.events == nil || q.dryRun {
+		return
+	}
+
+	q.trMutex.Lock()
+	oid := q.eventOids[name]
+	q.trMutex.Unlock()
+
+	q.emitEvent(EventProgress, oid, read, nil)
+}
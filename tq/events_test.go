@@ -0,0 +1,61 @@
+package tq
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStreamWritesToSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "events.sock")
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer l.Close()
+
+	s := newEventStream(config.EnvironmentOf(config.MapFetcher(map[string][]string{
+		"GIT_LFS_EVENTS_SOCKET": {name},
+	})))
+	require.NotNil(t, s)
+
+	conn, err := l.Accept()
+	require.Nil(t, err)
+	defer conn.Close()
+
+	s.Write(&Event{Event: EventStarted, Direction: "download", Oid: "abc", Name: "a.dat", Size: 3})
+	s.Write(&Event{Event: EventProgress, Direction: "download", Oid: "abc", Name: "a.dat", Size: 3, Bytes: 3})
+
+	scanner := bufio.NewScanner(conn)
+	var events []*Event
+	for len(events) < 2 && scanner.Scan() {
+		var e Event
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, &e)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, EventStarted, events[0].Event)
+	assert.Equal(t, "a.dat", events[0].Name)
+	assert.Equal(t, EventProgress, events[1].Event)
+	assert.Equal(t, int64(3), events[1].Bytes)
+}
+
+func TestEventStreamNotConfigured(t *testing.T) {
+	s := newEventStream(config.EnvironmentOf(config.MapFetcher(nil)))
+	assert.Nil(t, s)
+
+	// Writing to a nil stream does nothing.
+	s.Write(&Event{Event: EventQueued})
+}
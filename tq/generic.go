@@ -0,0 +1,261 @@
+package tq
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+const GenericAdapterName = "generic"
+
+// Adapter for objects kept in a generic artifact repository, such as a generic
+// repository in Artifactory or a raw repository in Nexus, without a Git LFS
+// server in front of it. It is used as a standalone transfer agent, by setting
+// lfs.standalonetransferagent to "generic", and finds each object at the URL
+// given by the lfs.generic.url template, which defaults to the object's path
+// below the LFS URL. Objects are downloaded with GET, and uploaded with PUT
+// along with their checksums, which the repository checks, unless a HEAD
+// request shows that they are already there. Credentials are found for these
+// URLs as for any other.
+type genericAdapter struct {
+	*adapterBase
+}
+
+func (a *genericAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *genericAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *genericAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	href := a.objectURL(t.Oid)
+	if a.direction == Upload {
+		return a.upload(t, href, cb, authOkFunc)
+	}
+	return a.download(t, href, cb, authOkFunc)
+}
+
+func (a *genericAdapter) download(t *Transfer, href string, cb ProgressCallback, authOkFunc func()) error {
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return err
+	}
+
+	req = a.apiClient.LogRequest(req, "lfs.data.download")
+	res, err := a.apiClient.DoWithAuth(a.remote, a.apiClient.Endpoints.AccessFor(href), req)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return errors.Errorf("generic: object %s is not in the repository", t.Oid)
+		}
+		return genericError(res, err)
+	}
+	defer res.Body.Close()
+
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	f, err := tools.TempFile(a.tempDir(), t.Oid, a.fs)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+	hasher := tools.NewHashingReader(io.LimitReader(res.Body, t.Size+1))
+	written, err := tools.CopyWithCallback(f, hasher, t.Size, ccb)
+	if err != nil {
+		return errors.NewRetriableError(errors.Wrapf(err, "cannot write data to tempfile %q", f.Name()))
+	}
+	if actual := hasher.Hash(); actual != t.Oid {
+		return fmt.Errorf("expected OID %s, got %s after %d bytes written", t.Oid, actual, written)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", f.Name(), err)
+	}
+
+	err = tools.RenameFileCopyPermissions(f.Name(), t.Path)
+	if _, err2 := os.Stat(t.Path); err2 == nil {
+		// Target file already exists, possibly was downloaded by other git-lfs process
+		return nil
+	}
+	return err
+}
+
+func (a *genericAdapter) upload(t *Transfer, href string, cb ProgressCallback, authOkFunc func()) error {
+	// Asking whether the object is there also finds any credentials the
+	// repository needs, before the object is sent.
+	exists, err := a.exists(t, href)
+	if err != nil {
+		return err
+	}
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+	if exists {
+		a.Trace("xfer: generic repository already has %q", t.Oid)
+		advanceCallbackProgress(cb, t, t.Size)
+		return nil
+	}
+
+	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "generic upload")
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest("PUT", href, nil)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = t.Size
+	req.Header.Set("Content-Length", strconv.FormatInt(t.Size, 10))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if err := setGenericChecksums(req, t, f); err != nil {
+		return err
+	}
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+	verifier := newVerifyingReader(tools.NewFileBody(f), t)
+	cbr := tools.NewBodyWithCallback(verifier, t.Size, ccb)
+	req.Body = cbr
+
+	req = a.apiClient.LogRequest(req, "lfs.data.upload")
+	res, err := a.apiClient.DoWithAuthNoRetry(a.remote, a.apiClient.Endpoints.AccessFor(href), req)
+	if err != nil {
+		if verifier.err != nil {
+			// The object's content changed, so retrying would
+			// fail again.
+			cbr.ResetProgress()
+			return verifier.err
+		}
+		if perr := cbr.ResetProgress(); perr != nil {
+			err = errors.Wrap(err, perr.Error())
+		}
+		return genericError(res, err)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	return nil
+}
+
+// exists returns whether the repository has the object at href already, as
+// the given size and, if the repository says, with the right SHA-256 hash.
+func (a *genericAdapter) exists(t *Transfer, href string) (bool, error) {
+	req, err := http.NewRequest("HEAD", href, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req = a.apiClient.LogRequest(req, "lfs.generic.exists")
+	res, err := a.apiClient.DoWithAuth(a.remote, a.apiClient.Endpoints.AccessFor(href), req)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, genericError(res, err)
+	}
+	res.Body.Close()
+
+	if res.ContentLength >= 0 && res.ContentLength != t.Size {
+		return false, nil
+	}
+	if sum := res.Header.Get("X-Checksum-Sha256"); len(sum) > 0 && !strings.EqualFold(sum, t.Oid) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// objectURL returns the URL of the object with the given OID, filling in the
+// lfs.generic.url template, in which "{oid}" is replaced by the OID and "{dir}"
+// by the two levels of directories which Git LFS stores it in.
+func (a *genericAdapter) objectURL(oid string) string {
+	template, _ := a.apiClient.GitEnv().Get("lfs.generic.url")
+	if len(template) == 0 {
+		e := a.apiClient.Endpoints.Endpoint(a.direction.String(), a.remote)
+		template = strings.TrimSuffix(e.Url, "/") + "/{dir}/{oid}"
+	}
+	return strings.NewReplacer(
+		"{oid}", oid,
+		"{dir}", oid[0:2]+"/"+oid[2:4],
+	).Replace(template)
+}
+
+func (a *genericAdapter) tempDir() string {
+	d := filepath.Join(a.fs.LFSStorageDir, "incomplete")
+	if err := tools.MkdirAll(d, a.fs); err != nil {
+		return os.TempDir()
+	}
+	return d
+}
+
+// setGenericChecksums sets the checksum headers which Artifactory checks an
+// upload against, and rewinds f.
+func setGenericChecksums(req *http.Request, t *Transfer, f *os.File) error {
+	sha1Hash := sha1.New()
+	md5Hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha1Hash, md5Hash), f); err != nil {
+		return errors.Wrap(err, "upload checksum")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "upload checksum rewind")
+	}
+
+	req.Header.Set("X-Checksum-Sha256", t.Oid)
+	req.Header.Set("X-Checksum-Sha1", hex.EncodeToString(sha1Hash.Sum(nil)))
+	req.Header.Set("X-Checksum-Md5", hex.EncodeToString(md5Hash.Sum(nil)))
+	return nil
+}
+
+// genericError wraps an error from a request to the repository, which can be
+// retried unless the repository rejected the request as it stands.
+func genericError(res *http.Response, err error) error {
+	if errors.IsAuthError(err) || res == nil {
+		return errors.NewRetriableError(err)
+	}
+	if res.StatusCode == 429 {
+		if retryAfter := res.Header.Get("Retry-After"); len(retryAfter) > 0 {
+			if retLaterErr := errors.NewRetriableLaterError(err, retryAfter); retLaterErr != nil {
+				return retLaterErr
+			}
+		}
+	}
+	if res.StatusCode >= 500 || res.StatusCode == 408 || res.StatusCode == 429 {
+		return errors.NewRetriableError(err)
+	}
+	return errors.Wrapf(err, "generic: %s %s", res.Request.Method, res.Request.URL)
+}
+
+func configureGenericAdapter(m *Manifest) {
+	newfunc := func(name string, dir Direction) Adapter {
+		ga := &genericAdapter{newAdapterBase(m.fs, name, dir, nil)}
+		// self implements impl
+		ga.transferImpl = ga
+		return ga
+	}
+	m.RegisterNewAdapterFunc(GenericAdapterName, Download, newfunc)
+	m.RegisterNewAdapterFunc(GenericAdapterName, Upload, newfunc)
+}
@@ -0,0 +1,131 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// genericRepository is a minimal generic artifact repository, which keeps
+// uploaded files in memory and checks their SHA-256 checksum.
+type genericRepository struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	puts  int
+}
+
+func (r *genericRepository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch req.Method {
+	case "HEAD", "GET":
+		data, ok := r.files[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sum := sha256.Sum256(data)
+		w.Header().Set("X-Checksum-Sha256", hex.EncodeToString(sum[:]))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if req.Method == "GET" {
+			w.Write(data)
+		}
+	case "PUT":
+		r.puts++
+		data, _ := ioutil.ReadAll(req.Body)
+		sum := sha256.Sum256(data)
+		if req.Header.Get("X-Checksum-Sha256") != hex.EncodeToString(sum[:]) ||
+			len(req.Header.Get("X-Checksum-Sha1")) != 40 ||
+			len(req.Header.Get("X-Checksum-Md5")) != 32 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		r.files[req.URL.Path] = data
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func newTestGenericAdapter(t *testing.T, dir string, direction Direction, gitConfig map[string]string) *genericAdapter {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, gitConfig))
+	require.Nil(t, err)
+
+	f := fs.New(cli.OSEnv(), filepath.Join(dir, ".git"), dir, "", 0644)
+	a := &genericAdapter{newAdapterBase(f, GenericAdapterName, direction, nil)}
+	a.transferImpl = a
+	a.apiClient = cli
+	return a
+}
+
+func TestGenericUploadAndDownload(t *testing.T) {
+	repo := &genericRepository{files: make(map[string][]byte)}
+	srv := httptest.NewServer(repo)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "tq-generic")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "generic content"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	path := filepath.Join(dir, "upload")
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	gitConfig := map[string]string{"lfs.url": srv.URL + "/lfs-local/"}
+	up := newTestGenericAdapter(t, dir, Upload, gitConfig)
+	tr := &Transfer{Oid: oid, Size: int64(len(content)), Path: path}
+	require.Nil(t, up.DoTransfer(nil, tr, nil, nil))
+	assert.Equal(t, content, string(repo.files["/lfs-local/"+oid[0:2]+"/"+oid[2:4]+"/"+oid]))
+	assert.Equal(t, 1, repo.puts)
+
+	// The object is not sent again once the repository has it.
+	var progress int64
+	cb := func(name string, total, read int64, current int) error {
+		progress = read
+		return nil
+	}
+	require.Nil(t, up.DoTransfer(nil, tr, cb, nil))
+	assert.Equal(t, 1, repo.puts)
+	assert.EqualValues(t, len(content), progress)
+
+	down := newTestGenericAdapter(t, dir, Download, gitConfig)
+	tr = &Transfer{Oid: oid, Size: int64(len(content)), Path: filepath.Join(dir, "download")}
+	require.Nil(t, down.DoTransfer(nil, tr, nil, nil))
+	data, err := ioutil.ReadFile(tr.Path)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(data))
+
+	missing := sha256.Sum256([]byte("missing"))
+	tr = &Transfer{Oid: hex.EncodeToString(missing[:]), Size: 7, Path: filepath.Join(dir, "missing")}
+	err = down.DoTransfer(nil, tr, nil, nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not in the repository")
+}
+
+func TestGenericObjectURL(t *testing.T) {
+	oid := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	a := newTestGenericAdapter(t, "", Download, map[string]string{
+		"lfs.url":         "https://example.com/lfs",
+		"lfs.generic.url": "https://repo.example.com/artifactory/lfs/{dir}/{oid}.bin",
+	})
+	assert.Equal(t, "https://repo.example.com/artifactory/lfs/01/23/"+oid+".bin", a.objectURL(oid))
+
+	a = newTestGenericAdapter(t, "", Download, map[string]string{
+		"lfs.url": "https://example.com/repository/lfs-raw",
+	})
+	assert.Equal(t, "https://example.com/repository/lfs-raw/01/23/"+oid, a.objectURL(oid))
+}
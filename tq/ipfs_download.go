@@ -0,0 +1,187 @@
+package tq
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+const IPFSAdapterName = "ipfs"
+
+// defaultIPFSGateway is the HTTP gateway of a local IPFS daemon, which is used
+// unless lfs.ipfs.gateway names another.
+const defaultIPFSGateway = "http://127.0.0.1:8080"
+
+// maxIPFSResolverResponse is the most the IPFS adapter reads from the mapping
+// service, which only ought to send a CID.
+const maxIPFSResolverResponse = 4096
+
+// Adapter for downloads from IPFS, which is used as a standalone transfer agent
+// (lfs.standalonetransferagent = ipfs), so the Git LFS API is not involved.
+// Each object's OID is resolved to an IPFS CID by the mapping service in
+// lfs.ipfs.resolver, if there is one, or else taken to be the CID of a single
+// raw block with the object's SHA-256 hash, which is what
+// "ipfs add --cid-version=1 --raw-leaves" gives for objects no larger than a
+// block. The content is then downloaded from the IPFS gateway in
+// lfs.ipfs.gateway and checked against its OID as usual, so neither the
+// mapping service nor the gateway needs to be trusted.
+type ipfsDownloadAdapter struct {
+	*adapterBase
+}
+
+func (a *ipfsDownloadAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *ipfsDownloadAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *ipfsDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	if a.direction == Upload {
+		return errors.Errorf("ipfs: cannot upload %s; add objects to IPFS with \"ipfs add\" and use another remote for pushes", t.Oid)
+	}
+
+	cid, err := a.resolve(t.Oid)
+	if err != nil {
+		return err
+	}
+	a.Trace("xfer: ipfs resolved %q to %s", t.Oid, cid)
+
+	gateway, _ := a.apiClient.GitEnv().Get("lfs.ipfs.gateway")
+	if len(gateway) == 0 {
+		gateway = defaultIPFSGateway
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(gateway, "/")+"/ipfs/"+cid, nil)
+	if err != nil {
+		return err
+	}
+
+	req = a.apiClient.LogRequest(req, "lfs.data.download")
+	res, err := a.apiClient.Do(req)
+	if err != nil {
+		return errors.NewRetriableError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err := fmt.Errorf("ipfs: gateway sent status %d for %s", res.StatusCode, cid)
+		if res.StatusCode == http.StatusNotFound {
+			return err
+		}
+		return errors.NewRetriableError(err)
+	}
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	f, err := tools.TempFile(a.tempDir(), t.Oid, a.fs)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+	hasher := tools.NewHashingReader(io.LimitReader(res.Body, t.Size+1))
+	written, err := tools.CopyWithCallback(f, hasher, t.Size, ccb)
+	if err != nil {
+		return errors.NewRetriableError(errors.Wrapf(err, "cannot write data to tempfile %q", f.Name()))
+	}
+	if actual := hasher.Hash(); actual != t.Oid {
+		return fmt.Errorf("expected OID %s, got %s after %d bytes written from IPFS", t.Oid, actual, written)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", f.Name(), err)
+	}
+
+	err = tools.RenameFileCopyPermissions(f.Name(), t.Path)
+	if _, err2 := os.Stat(t.Path); err2 == nil {
+		// Target file already exists, possibly was downloaded by other git-lfs process
+		return nil
+	}
+	return err
+}
+
+func (a *ipfsDownloadAdapter) tempDir() string {
+	d := filepath.Join(a.fs.LFSStorageDir, "incomplete")
+	if err := tools.MkdirAll(d, a.fs); err != nil {
+		return os.TempDir()
+	}
+	return d
+}
+
+// resolve returns the IPFS CID of the object with the given OID.
+func (a *ipfsDownloadAdapter) resolve(oid string) (string, error) {
+	resolver, _ := a.apiClient.GitEnv().Get("lfs.ipfs.resolver")
+	if len(resolver) == 0 {
+		return ipfsRawCID(oid)
+	}
+
+	req, err := http.NewRequest("GET", strings.Replace(resolver, "{oid}", oid, -1), nil)
+	if err != nil {
+		return "", err
+	}
+	req = a.apiClient.LogRequest(req, "lfs.ipfs.resolve")
+	res, err := a.apiClient.Do(req)
+	if err != nil {
+		return "", errors.NewRetriableError(err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("ipfs: no CID is known for %s", oid)
+	default:
+		return "", errors.NewRetriableError(fmt.Errorf("ipfs: resolver sent status %d for %s", res.StatusCode, oid))
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxIPFSResolverResponse))
+	if err != nil {
+		return "", errors.NewRetriableError(err)
+	}
+	cid := strings.TrimSpace(string(data))
+	if len(cid) == 0 || strings.ContainsAny(cid, "/?#\r\n\t ") {
+		return "", fmt.Errorf("ipfs: resolver sent an invalid CID for %s", oid)
+	}
+	return cid, nil
+}
+
+// ipfsRawCID returns the CIDv1 of a raw block whose SHA-256 hash is the given
+// OID, in the lower case base32 form which IPFS prints.
+func ipfsRawCID(oid string) (string, error) {
+	sum, err := hex.DecodeString(oid)
+	if err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("ipfs: invalid OID %q", oid)
+	}
+
+	// CID version 1, raw codec, then a multihash: SHA2-256, 32 bytes.
+	cid := append([]byte{0x01, 0x55, 0x12, 0x20}, sum...)
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	return "b" + strings.ToLower(enc.EncodeToString(cid)), nil
+}
+
+func configureIPFSAdapter(m *Manifest) {
+	newfunc := func(name string, dir Direction) Adapter {
+		ia := &ipfsDownloadAdapter{newAdapterBase(m.fs, name, dir, nil)}
+		// self implements impl
+		ia.transferImpl = ia
+		return ia
+	}
+	m.RegisterNewAdapterFunc(IPFSAdapterName, Download, newfunc)
+	m.RegisterNewAdapterFunc(IPFSAdapterName, Upload, newfunc)
+}
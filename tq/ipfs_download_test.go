@@ -0,0 +1,128 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFSRawCID(t *testing.T) {
+	// The CID IPFS gives an empty file added as a raw block.
+	cid, err := ipfsRawCID("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	require.Nil(t, err)
+	assert.Equal(t, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", cid)
+
+	_, err = ipfsRawCID("not-an-oid")
+	assert.NotNil(t, err)
+}
+
+func newTestIPFSAdapter(t *testing.T, dir string, gitConfig map[string]string) *ipfsDownloadAdapter {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, gitConfig))
+	require.Nil(t, err)
+
+	f := fs.New(cli.OSEnv(), filepath.Join(dir, ".git"), dir, "", 0644)
+	a := &ipfsDownloadAdapter{newAdapterBase(f, IPFSAdapterName, Download, nil)}
+	a.transferImpl = a
+	a.apiClient = cli
+	return a
+}
+
+func TestIPFSDownload(t *testing.T) {
+	content := "the quick brown fox jumps over the lazy dog"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/map/" + oid:
+			w.Write([]byte("bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi\n"))
+		case "/gw/ipfs/bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi":
+			w.Write([]byte(content))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "tq-ipfs")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a := newTestIPFSAdapter(t, dir, map[string]string{
+		"lfs.ipfs.gateway":  srv.URL + "/gw/",
+		"lfs.ipfs.resolver": srv.URL + "/map/{oid}",
+	})
+
+	tr := &Transfer{Oid: oid, Size: int64(len(content)), Path: filepath.Join(dir, "obj")}
+	var progress int64
+	cb := func(name string, total, read int64, current int) error {
+		progress = read
+		return nil
+	}
+	require.Nil(t, a.DoTransfer(nil, tr, cb, nil))
+
+	data, err := ioutil.ReadFile(tr.Path)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(data))
+	assert.EqualValues(t, len(content), progress)
+}
+
+func TestIPFSDownloadDerivesCID(t *testing.T) {
+	content := "raw block"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	cid, err := ipfsRawCID(oid)
+	require.Nil(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/ipfs/" + cid:
+			w.Write([]byte(content))
+		default:
+			// Corrupt content from the gateway must be rejected.
+			w.Write([]byte("something else"))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "tq-ipfs")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a := newTestIPFSAdapter(t, dir, map[string]string{"lfs.ipfs.gateway": srv.URL})
+
+	tr := &Transfer{Oid: oid, Size: int64(len(content)), Path: filepath.Join(dir, "obj")}
+	require.Nil(t, a.DoTransfer(nil, tr, nil, nil))
+	data, err := ioutil.ReadFile(tr.Path)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(data))
+
+	other := sha256.Sum256([]byte("other"))
+	tr = &Transfer{Oid: hex.EncodeToString(other[:]), Size: 5, Path: filepath.Join(dir, "other")}
+	assert.NotNil(t, a.DoTransfer(nil, tr, nil, nil))
+	_, err = os.Stat(tr.Path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIPFSAdapterIsStandaloneOnly(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, nil))
+	require.Nil(t, err)
+	assert.NotContains(t, NewManifest(nil, cli, "", "").GetDownloadAdapterNames(), IPFSAdapterName)
+
+	cli, err = lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.standalonetransferagent": "ipfs",
+	}))
+	require.Nil(t, err)
+	assert.Contains(t, NewManifest(nil, cli, "", "").GetDownloadAdapterNames(), IPFSAdapterName)
+}
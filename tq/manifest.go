@@ -1,6 +1,8 @@
 package tq
 
 import (
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -24,6 +26,7 @@ type Manifest struct {
 	maxRetries              int
 	maxRetryDelay           int
 	concurrentTransfers     int
+	batchSize               int
 	basicTransfersOnly      bool
 	standaloneTransferAgent string
 	tusTransfersAllowed     bool
@@ -33,7 +36,30 @@ type Manifest struct {
 	apiClient               *lfsapi.Client
 	sshTransfer             *ssh.SSHTransfer
 	batchClientAdapter      BatchClient
+	summaryFile             string
+	capabilities            *Capabilities
+	recorder                *recorder
+	events                  *eventStream
 	mu                      sync.Mutex
+
+	// notices holds the informational messages from the server which
+	// have already been shown to the user, so that each is shown only
+	// once, even across multiple transfer queues.
+	notices   map[string]bool
+	noticesMu sync.Mutex
+}
+
+// summaryFile returns the path of the file to which summaries of each transfer
+// are appended, given by lfs.summaryfile, which defaults to a file in the
+// Git LFS storage directory in CI mode.
+func summaryFile(gitEnv, osEnv config.Environment, f *fs.Filesystem) string {
+	if v, ok := gitEnv.Get("lfs.summaryfile"); ok && len(v) > 0 {
+		return v
+	}
+	if f != nil && len(f.LFSStorageDir) > 0 && config.IsCI(gitEnv, osEnv) {
+		return filepath.Join(f.LFSStorageDir, "summary.jsonl")
+	}
+	return ""
 }
 
 func (m *Manifest) APIClient() *lfsapi.Client {
@@ -52,10 +78,32 @@ func (m *Manifest) ConcurrentTransfers() int {
 	return m.concurrentTransfers
 }
 
+// BatchSize returns the maximum number of objects to send in each batch
+// request, given by lfs.transfer.batchsize, or zero if unset.
+func (m *Manifest) BatchSize() int {
+	return m.batchSize
+}
+
 func (m *Manifest) IsStandaloneTransfer() bool {
 	return m.standaloneTransferAgent != ""
 }
 
+// newNotice records that the given server notice is about to be shown to the
+// user, and returns whether it has not already been shown.
+func (m *Manifest) newNotice(notice string) bool {
+	m.noticesMu.Lock()
+	defer m.noticesMu.Unlock()
+
+	if m.notices == nil {
+		m.notices = make(map[string]bool)
+	}
+	if m.notices[notice] {
+		return false
+	}
+	m.notices[notice] = true
+	return true
+}
+
 func (m *Manifest) batchClient() BatchClient {
 	if r := m.MaxRetries(); r > 0 {
 		m.batchClientAdapter.SetMaxRetries(r)
@@ -84,7 +132,7 @@ func NewManifest(f *fs.Filesystem, apiClient *lfsapi.Client, operation, remote s
 		sshTransfer:          sshTransfer,
 	}
 
-	var tusAllowed bool
+	var tusAllowed, torrentAllowed bool
 	if git := apiClient.GitEnv(); git != nil {
 		if v := git.Int("lfs.transfer.maxretries", 0); v > 0 {
 			m.maxRetries = v
@@ -92,14 +140,22 @@ func NewManifest(f *fs.Filesystem, apiClient *lfsapi.Client, operation, remote s
 		if v := git.Int("lfs.transfer.maxretrydelay", -1); v > -1 {
 			m.maxRetryDelay = v
 		}
-		if v := git.Int("lfs.concurrenttransfers", 0); v > 0 {
-			m.concurrentTransfers = v
+		m.concurrentTransfers = concurrentTransfers(apiClient, operation, remote)
+		if v := git.Int("lfs.transfer.batchsize", 0); v > 0 {
+			m.batchSize = v
 		}
 		m.basicTransfersOnly = git.Bool("lfs.basictransfersonly", false)
 		m.standaloneTransferAgent = findStandaloneTransfer(
 			apiClient, operation, remote,
 		)
 		tusAllowed = git.Bool("lfs.tustransfers", false)
+		torrentAllowed = git.Bool("lfs.torrenttransfers", false)
+		m.summaryFile = summaryFile(git, apiClient.OSEnv(), f)
+		if os := apiClient.OSEnv(); os != nil {
+			dir, _ := os.Get("GIT_LFS_RECORD")
+			m.recorder = newRecorder(dir)
+			m.events = newEventStream(os)
+		}
 		configureCustomAdapters(git, m)
 	}
 
@@ -127,14 +183,43 @@ func NewManifest(f *fs.Filesystem, apiClient *lfsapi.Client, operation, remote s
 	if tusAllowed {
 		configureTusAdapter(m)
 	}
+	if torrentAllowed {
+		configureTorrentAdapter(m)
+	}
 	configureSSHAdapter(m)
+	switch m.standaloneTransferAgent {
+	case IPFSAdapterName:
+		configureIPFSAdapter(m)
+	case ScpAdapterName:
+		configureScpAdapter(m)
+	case GenericAdapterName:
+		configureGenericAdapter(m)
+	}
 	return m
 }
 
+// concurrentTransfers returns the number of concurrent transfers to use, which
+// may be given for the remote's LFS URL as lfs.<url>.concurrenttransfers.
+func concurrentTransfers(client *lfsapi.Client, operation, remote string) int {
+	if operation != "" && remote != "" {
+		ep := client.Endpoints.Endpoint(operation, remote)
+		uc := config.NewURLConfig(client.GitEnv())
+		if v, ok := uc.Get("lfs", ep.Url, "concurrenttransfers"); ok {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return config.ConcurrentTransfers(client.GitEnv(), client.OSEnv())
+}
+
 func findDefaultStandaloneTransfer(url string) string {
 	if strings.HasPrefix(url, "file://") {
 		return standaloneFileName
 	}
+	if strings.HasPrefix(url, "scp://") {
+		return ScpAdapterName
+	}
 	return ""
 }
 
@@ -187,7 +272,11 @@ func (m *Manifest) getAdapterNames(adapters map[string]NewAdapterFunc) []string
 
 	ret := make([]string, 0, len(adapters))
 	for n, _ := range adapters {
-		ret = append(ret, n)
+		// Don't offer adapters which the server has said it
+		// doesn't support.
+		if m.capabilities.SupportsTransfer(n) {
+			ret = append(ret, n)
+		}
 	}
 	return ret
 }
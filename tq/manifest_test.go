@@ -38,3 +38,52 @@ func TestManifestIgnoresNonInts(t *testing.T) {
 	m := NewManifest(nil, cli, "", "")
 	assert.Equal(t, 8, m.MaxRetries())
 }
+
+func TestManifestConcurrentTransfersForURL(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"remote.origin.url":                                   "https://github.com/org/repo",
+		"remote.mirror.url":                                   "https://mirror.example.com/org/repo",
+		"lfs.concurrenttransfers":                             "12",
+		"lfs.https://github.com/.concurrenttransfers":         "2",
+		"lfs.https://mirror.example.com/.concurrenttransfers": "not_an_int",
+	}))
+	require.Nil(t, err)
+
+	assert.Equal(t, 2, NewManifest(nil, cli, "download", "origin").ConcurrentTransfers())
+	assert.Equal(t, 12, NewManifest(nil, cli, "download", "mirror").ConcurrentTransfers())
+	assert.Equal(t, 12, NewManifest(nil, cli, "", "").ConcurrentTransfers())
+}
+
+func TestManifestBatchSizeIsConfigurable(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.transfer.batchsize": "25",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifest(nil, cli, "", "")
+	assert.Equal(t, 25, m.BatchSize())
+}
+
+func TestManifestIgnoresInvalidBatchSizes(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.transfer.batchsize": "0",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifest(nil, cli, "", "")
+	assert.Equal(t, 0, m.BatchSize())
+}
+
+func TestManifestOffersOnlyAdaptersTheServerSupports(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.tustransfers": "true",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifest(nil, cli, "", "")
+	assert.Contains(t, m.GetUploadAdapterNames(), "tus")
+
+	m.setCapabilities(&Capabilities{Transfers: []string{"other"}})
+	assert.NotContains(t, m.GetUploadAdapterNames(), "tus")
+	assert.Contains(t, m.GetUploadAdapterNames(), "basic")
+}
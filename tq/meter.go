@@ -5,6 +5,8 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +17,19 @@ import (
 	"github.com/git-lfs/git-lfs/v2/tools/humanize"
 )
 
+const (
+	// progressKey is the style of progress meter to show, which is
+	// progressTUI to show each transfer in progress below the meter, or
+	// otherwise only the meter.
+	progressKey = "lfs.progress"
+	progressTUI = "tui"
+
+	// maxDetails is the number of transfers in progress shown below the
+	// meter, and detailBarWidth is the width of the bar shown for each.
+	maxDetails     = 16
+	detailBarWidth = 20
+)
+
 // Meter provides a progress bar type output for the TransferQueue. It
 // is given an estimated file count and size up front and tracks the number of
 // files and bytes transferred as well as the number of files and bytes that
@@ -35,11 +50,25 @@ type Meter struct {
 	updates           chan *tasklog.Update
 	cfg               *config.Configuration
 
+	// detailed is whether lfs.progress is "tui", in which case the
+	// transfers in progress, by name, are shown below the meter on a
+	// terminal. They are guarded by fileIndexMutex.
+	detailed bool
+	active   map[string]*activeTransfer
+
 	DryRun    bool
 	Logger    *tools.SyncWriter
 	Direction Direction
 }
 
+// activeTransfer is a file being transferred, as shown by a detailed meter.
+type activeTransfer struct {
+	idx     int64
+	read    int64
+	total   int64
+	retries int
+}
+
 type env interface {
 	Get(key string) (val string, ok bool)
 }
@@ -83,6 +112,11 @@ func NewMeter(cfg *config.Configuration) *Meter {
 		fileIndexMutex: &sync.Mutex{},
 		updates:        make(chan *tasklog.Update),
 		cfg:            cfg,
+		active:         make(map[string]*activeTransfer),
+	}
+	if cfg != nil {
+		v, _ := cfg.Git.Get(progressKey)
+		m.detailed = v == progressTUI
 	}
 
 	return m
@@ -140,6 +174,14 @@ func (m *Meter) StartTransfer(name string) {
 	idx := atomic.AddInt64(&m.transferringFiles, 1)
 	m.fileIndexMutex.Lock()
 	m.fileIndex[name] = idx
+	if m.detailed {
+		if t, ok := m.active[name]; ok {
+			// The transfer is being retried in a new batch.
+			t.idx = idx
+		} else {
+			m.active[name] = &activeTransfer{idx: idx}
+		}
+	}
 	m.fileIndexMutex.Unlock()
 }
 
@@ -167,6 +209,14 @@ func (m *Meter) TransferBytes(direction, name string, read, total int64, current
 		atomic.AddUint64(&m.sampleCount, 1)
 	}
 
+	if m.detailed {
+		m.fileIndexMutex.Lock()
+		if t, ok := m.active[name]; ok {
+			t.read, t.total = read, total
+		}
+		m.fileIndexMutex.Unlock()
+	}
+
 	m.logBytes(direction, name, read, total)
 }
 
@@ -180,6 +230,36 @@ func (m *Meter) FinishTransfer(name string) {
 	atomic.AddInt64(&m.finishedFiles, 1)
 	m.fileIndexMutex.Lock()
 	delete(m.fileIndex, name)
+	delete(m.active, name)
+	m.fileIndexMutex.Unlock()
+}
+
+// RetryTransfer tells the progress meter that the transfer of a file failed
+// and will be retried.
+func (m *Meter) RetryTransfer(name string) {
+	if m == nil {
+		return
+	}
+
+	defer m.update(false)
+	m.fileIndexMutex.Lock()
+	if t, ok := m.active[name]; ok {
+		t.read = 0
+		t.retries++
+	}
+	m.fileIndexMutex.Unlock()
+}
+
+// FailTransfer tells the progress meter that the transfer of a file failed
+// and will not be retried.
+func (m *Meter) FailTransfer(name string) {
+	if m == nil {
+		return
+	}
+
+	defer m.update(false)
+	m.fileIndexMutex.Lock()
+	delete(m.active, name)
 	m.fileIndexMutex.Unlock()
 }
 
@@ -235,12 +315,78 @@ func (m *Meter) str() string {
 	// (Uploading|Downloading) LFS objects: 100% (10/10) 100 MiB | 10 MiB/s
 	percentage := 100 * float64(m.finishedFiles) / float64(m.estimatedFiles)
 
-	return fmt.Sprintf("%s LFS objects: %3.f%% (%d/%d), %s | %s",
+	s := fmt.Sprintf("%s LFS objects: %3.f%% (%d/%d), %s | %s",
 		m.Direction.Verb(),
 		percentage,
 		m.finishedFiles, m.estimatedFiles,
 		humanize.FormatBytes(clamp(m.currentBytes)),
 		humanize.FormatByteRate(clampf(m.avgBytes), time.Second))
+
+	if m.detailed && m.avgBytes > 0 && m.finishedFiles < int64(m.estimatedFiles) {
+		remaining := float64(m.estimatedBytes - m.currentBytes)
+		if remaining > 0 {
+			eta := time.Duration(remaining / m.avgBytes * float64(time.Second))
+			s += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+		}
+	}
+	return s
+}
+
+// Details returns a line for each file being transferred, with a bar showing
+// how much of it has been transferred, and how many times it has been retried,
+// in the order in which the transfers started. It returns nothing unless
+// lfs.progress is "tui". The logger shows these lines below the meter on a
+// terminal.
+func (m *Meter) Details() []string {
+	if m == nil || !m.detailed {
+		return nil
+	}
+
+	m.fileIndexMutex.Lock()
+	names := make([]string, 0, len(m.active))
+	for name := range m.active {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return m.active[names[i]].idx < m.active[names[j]].idx
+	})
+
+	lines := make([]string, 0, maxDetails+1)
+	for i, name := range names {
+		if i == maxDetails {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(names)-maxDetails))
+			break
+		}
+		lines = append(lines, m.active[name].str(name))
+	}
+	m.fileIndexMutex.Unlock()
+
+	return lines
+}
+
+// str returns the line shown for the transfer with the given name:
+//
+//	[=========>          ]  50%  10 MB/20 MB  name (retry 1)
+func (t *activeTransfer) str(name string) string {
+	var fraction float64
+	if t.total > 0 {
+		fraction = math.Min(float64(t.read)/float64(t.total), 1)
+	}
+	filled := int(fraction * detailBarWidth)
+
+	bar := strings.Repeat("=", filled)
+	if filled < detailBarWidth {
+		bar += ">" + strings.Repeat(" ", detailBarWidth-filled-1)
+	}
+
+	s := fmt.Sprintf("  [%s] %3.f%%  %s/%s  %s", bar, 100*fraction,
+		humanize.FormatBytes(clamp(t.read)),
+		humanize.FormatBytes(clamp(t.total)),
+		name)
+	if t.retries > 0 {
+		s += fmt.Sprintf(" (retry %d)", t.retries)
+	}
+	return s
 }
 
 // clamp clamps the given "x" within the acceptable domain of the uint64 integer
@@ -0,0 +1,41 @@
+package tq
+
+import (
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeterDetails(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string][]string{"lfs.progress": {"tui"}},
+	})
+	m := NewMeter(cfg)
+	m.DryRun = true
+
+	m.Add(100)
+	m.Add(200)
+	m.StartTransfer("a.dat")
+	m.StartTransfer("b.dat")
+	m.TransferBytes("download", "a.dat", 50, 100, 50)
+	m.RetryTransfer("b.dat")
+
+	assert.Equal(t, []string{
+		"  [==========>         ]  50%  50 B/100 B  a.dat",
+		"  [>                   ]   0%  0 B/0 B  b.dat (retry 1)",
+	}, m.Details())
+
+	m.FinishTransfer("a.dat")
+	m.FailTransfer("b.dat")
+	assert.Empty(t, m.Details())
+}
+
+func TestMeterDetailsDisabled(t *testing.T) {
+	m := NewMeter(config.NewFrom(config.Values{}))
+	m.DryRun = true
+
+	m.Add(100)
+	m.StartTransfer("a.dat")
+	assert.Nil(t, m.Details())
+}
@@ -0,0 +1,142 @@
+package tq
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rubyist/tracerx"
+)
+
+// PendingObject is an object which a transfer queue did not transfer, such as
+// because its transfer failed or was interrupted, as recorded in a pending
+// file, so that it may be retried without scanning for it again.
+type PendingObject struct {
+	Name   string    `json:"name"`
+	Oid    string    `json:"oid"`
+	Size   int64     `json:"size"`
+	Remote string    `json:"remote"`
+	Ref    string    `json:"ref,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ReadPendingFile returns the objects recorded in the given pending file, or
+// none if it does not exist.
+func ReadPendingFile(name string) ([]*PendingObject, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var objs []*PendingObject
+	if err := json.Unmarshal(data, &objs); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// WritePendingFile replaces the objects recorded in the given pending file with
+// the given objects, removing the file if there are none.
+func WritePendingFile(name string, objs []*PendingObject) error {
+	if len(objs) == 0 {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(objs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// writePending updates the queue's pending file, if any, once the queue has
+// finished: objects which it transferred, or found already transferred, are
+// no longer pending, and the others are, with the errors they failed with.
+// Objects recorded for other remotes are left alone. Failures are traced, but
+// otherwise ignored, since the transfers themselves have already finished.
+func (q *TransferQueue) writePending() {
+	if len(q.pendingFile) == 0 || q.dryRun {
+		return
+	}
+
+	existing, err := ReadPendingFile(q.pendingFile)
+	if err != nil {
+		tracerx.Printf("tq: unable to read pending objects from %q: %v", q.pendingFile, err)
+	}
+
+	now := time.Now().UTC()
+	q.trMutex.Lock()
+	pending := make([]*PendingObject, 0, len(existing))
+	for _, p := range existing {
+		if _, ok := q.transfers[p.Oid]; !ok || p.Remote != q.remote {
+			pending = append(pending, p)
+		}
+	}
+	for oid, objs := range q.transfers {
+		first := objs.First()
+		if objs.completed || objs.present || first == nil {
+			continue
+		}
+		pending = append(pending, &PendingObject{
+			Name:   first.Name,
+			Oid:    oid,
+			Size:   first.Size,
+			Remote: q.remote,
+			Ref:    q.ref.Refspec(),
+			Error:  q.failures[oid],
+			Time:   now,
+		})
+	}
+	q.trMutex.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Name != pending[j].Name {
+			return pending[i].Name < pending[j].Name
+		}
+		return pending[i].Oid < pending[j].Oid
+	})
+
+	if len(pending) == len(existing) && len(q.transfers) == 0 {
+		return
+	}
+	if err := WritePendingFile(q.pendingFile, pending); err != nil {
+		tracerx.Printf("tq: unable to write pending objects to %q: %v", q.pendingFile, err)
+	}
+}
+
+// recordFailure records the error with which the transfer of the object with
+// the given OID failed, to be written to the pending file, and writes it to the
+// events stream.
+func (q *TransferQueue) recordFailure(oid string, err error) {
+	q.trMutex.Lock()
+	q.failures[oid] = err.Error()
+	q.trMutex.Unlock()
+
+	q.emitEvent(EventFailed, oid, 0, err)
+}
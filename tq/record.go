@@ -0,0 +1,229 @@
+package tq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/rubyist/tracerx"
+)
+
+// redacted replaces the values of secrets, such as authentication headers and
+// signed URL query strings, in recordings.
+const redacted = "REDACTED"
+
+// BatchRecord is a batch request and its response, as recorded to the
+// directory given by GIT_LFS_RECORD, with any secrets redacted.
+type BatchRecord struct {
+	Time     time.Time `json:"time"`
+	Remote   string    `json:"remote"`
+	Endpoint string    `json:"endpoint"`
+	// Duration is how long the request took, in milliseconds.
+	Duration int64          `json:"duration_ms"`
+	Request  *batchRequest  `json:"request"`
+	Response *BatchResponse `json:"response,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// transferRecord is the outcome of a single transfer, appended as a line of
+// JSON to "transfers.jsonl" in the recording directory.
+type transferRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Oid       string    `json:"oid"`
+	Size      int64     `json:"size"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// recorder writes batch requests and responses, and the outcome of each
+// transfer, to a directory, so that problems with a server can be reproduced.
+type recorder struct {
+	dir string
+	seq uint64
+	mu  sync.Mutex
+}
+
+// newRecorder returns a recorder writing to the given directory, or nil, which
+// records nothing, if it is empty.
+func newRecorder(dir string) *recorder {
+	if len(dir) == 0 {
+		return nil
+	}
+	return &recorder{dir: dir}
+}
+
+// RecordBatch writes the given batch request, and its response or error, to a
+// new file in the recording directory.
+func (r *recorder) RecordBatch(remote, endpoint string, bReq *batchRequest, bRes *BatchResponse, err error, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+
+	rec := &BatchRecord{
+		Time:     time.Now().UTC(),
+		Remote:   remote,
+		Endpoint: sanitizeURL(endpoint),
+		Duration: elapsed.Nanoseconds() / int64(time.Millisecond),
+		Request: &batchRequest{
+			Operation:            bReq.Operation,
+			Objects:              sanitizeTransfers(bReq.Objects),
+			TransferAdapterNames: bReq.TransferAdapterNames,
+			Ref:                  bReq.Ref,
+		},
+	}
+	if bRes != nil {
+		rec.Response = &BatchResponse{
+			Objects:             sanitizeTransfers(bRes.Objects),
+			TransferAdapterName: bRes.TransferAdapterName,
+			Message:             bRes.Message,
+			Capabilities:        bRes.Capabilities,
+		}
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		tracerx.Printf("tq: unable to encode batch recording: %v", err)
+		return
+	}
+
+	seq := atomic.AddUint64(&r.seq, 1)
+	name := filepath.Join(r.dir, fmt.Sprintf("batch-%s-%d-%03d.json",
+		rec.Time.Format("20060102T150405"), os.Getpid(), seq))
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		tracerx.Printf("tq: unable to record batch to %q: %v", name, err)
+		return
+	}
+	if err := ioutil.WriteFile(name, append(data, '\n'), 0644); err != nil {
+		tracerx.Printf("tq: unable to record batch to %q: %v", name, err)
+		return
+	}
+	tracerx.Printf("tq: recorded batch to %q", name)
+}
+
+// RecordTransfer appends the outcome of the given transfer to the transfer
+// log in the recording directory.
+func (r *recorder) RecordTransfer(dir Direction, res TransferResult) {
+	if r == nil {
+		return
+	}
+
+	rec := &transferRecord{
+		Time:      time.Now().UTC(),
+		Operation: dir.String(),
+		Oid:       res.Transfer.Oid,
+		Size:      res.Transfer.Size,
+	}
+	if res.Error != nil {
+		rec.Error = res.Error.Error()
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		tracerx.Printf("tq: unable to encode transfer recording: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := filepath.Join(r.dir, "transfers.jsonl")
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		tracerx.Printf("tq: unable to record transfer to %q: %v", name, err)
+		return
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		tracerx.Printf("tq: unable to record transfer to %q: %v", name, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		tracerx.Printf("tq: unable to record transfer to %q: %v", name, err)
+	}
+}
+
+// sanitizeTransfers returns copies of the given transfers without their local
+// paths, and with the secrets in their actions redacted.
+func sanitizeTransfers(transfers []*Transfer) []*Transfer {
+	sanitized := make([]*Transfer, 0, len(transfers))
+	for _, t := range transfers {
+		c := *t
+		c.Path = ""
+		c.Actions = sanitizeActions(t.Actions)
+		c.Links = sanitizeActions(t.Links)
+		sanitized = append(sanitized, &c)
+	}
+	return sanitized
+}
+
+func sanitizeActions(actions ActionSet) ActionSet {
+	if actions == nil {
+		return nil
+	}
+
+	sanitized := make(ActionSet, len(actions))
+	for rel, a := range actions {
+		c := *a
+		c.Href = sanitizeURL(a.Href)
+		if len(a.Header) > 0 {
+			c.Header = make(map[string]string, len(a.Header))
+			for k := range a.Header {
+				c.Header[k] = redacted
+			}
+		}
+		sanitized[rel] = &c
+	}
+	return sanitized
+}
+
+// sanitizeURL redacts any credentials and query string, which often holds a
+// signature or token, from the given URL.
+func sanitizeURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return redacted
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if len(u.RawQuery) > 0 {
+		u.RawQuery = redacted
+	}
+	return u.String()
+}
+
+// ReadBatchRecord reads a batch request and response recorded to the given
+// file.
+func ReadBatchRecord(name string) (*BatchRecord, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &BatchRecord{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, errors.Wrapf(err, "invalid batch recording %q", name)
+	}
+	if rec.Request == nil || len(rec.Request.Operation) == 0 {
+		return nil, errors.Errorf("invalid batch recording %q: no request", name)
+	}
+	return rec, nil
+}
+
+// Replay sends the batch request in the given recording again, to the server
+// for the given remote, and returns its response.
+func Replay(m *Manifest, remote string, rec *BatchRecord) (*BatchResponse, error) {
+	req := *rec.Request
+	return m.batchClient().Batch(remote, &req)
+}
@@ -0,0 +1,247 @@
+package tq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/git-lfs/git-lfs/v2/ssh"
+	"github.com/git-lfs/git-lfs/v2/subprocess"
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/rubyist/tracerx"
+)
+
+const ScpAdapterName = "scp"
+
+// scpMissingStatus is the exit status of the remote download command when the
+// object is not in the remote directory.
+const scpMissingStatus = 3
+
+const (
+	// scpDownloadScript sends the object at $1.
+	scpDownloadScript = `test -f "$1" || exit 3; exec cat "$1"`
+	// scpUploadScript stores its input as the object at $1, unless it is
+	// already there, through a temporary file so that a partial upload is
+	// never taken for the object.
+	scpUploadScript = `test -f "$1" && exit 0; mkdir -p "${1%/*}" && cat >"$1.tmp$$" && mv -f "$1.tmp$$" "$1"`
+)
+
+// Adapter for remotes whose objects are kept in a plain directory on a host
+// reachable over SSH, laid out like the local object directory, for those who
+// have no Git LFS server. It is used as a standalone transfer agent, chosen
+// automatically for scp:// URLs such as "scp://user@host/srv/lfs", or by
+// setting lfs.standalonetransferagent to "scp" for an SSH remote, whose path is
+// then the directory. Each transfer runs a short POSIX shell script on the
+// host over SSH, so the host needs no software but a shell and SSH server.
+type scpAdapter struct {
+	*adapterBase
+}
+
+func (a *scpAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *scpAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *scpAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	meta, err := a.target()
+	if err != nil {
+		return err
+	}
+	if a.apiClient.Offline() {
+		return lfshttp.NewOfflineError(meta.UserAndHost)
+	}
+
+	// The object's path in the remote directory, which is always separated
+	// by slashes.
+	path := strings.TrimSuffix(meta.Path, "/") + "/" + t.Oid[0:2] + "/" + t.Oid[2:4] + "/" + t.Oid
+	if len(meta.Path) == 0 {
+		path = path[1:]
+	}
+
+	if a.direction == Upload {
+		return a.upload(t, meta, path, cb)
+	}
+	return a.download(t, meta, path, cb)
+}
+
+func (a *scpAdapter) download(t *Transfer, meta *ssh.SSHMetadata, path string, cb ProgressCallback) error {
+	f, err := tools.TempFile(a.tempDir(), t.Oid, a.fs)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cmd, stderr := a.command(meta, scpDownloadScript, path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+	hasher := tools.NewHashingReader(io.LimitReader(stdout, t.Size+1))
+	written, copyErr := tools.CopyWithCallback(f, hasher, t.Size, ccb)
+	if err := cmd.Wait(); err != nil {
+		return scpError(err, stderr, t.Oid)
+	}
+	if copyErr != nil {
+		return errors.NewRetriableError(errors.Wrapf(copyErr, "cannot write data to tempfile %q", f.Name()))
+	}
+	if actual := hasher.Hash(); actual != t.Oid {
+		return fmt.Errorf("expected OID %s, got %s after %d bytes written", t.Oid, actual, written)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", f.Name(), err)
+	}
+
+	err = tools.RenameFileCopyPermissions(f.Name(), t.Path)
+	if _, err2 := os.Stat(t.Path); err2 == nil {
+		// Target file already exists, possibly was downloaded by other git-lfs process
+		return nil
+	}
+	return err
+}
+
+func (a *scpAdapter) upload(t *Transfer, meta *ssh.SSHMetadata, path string, cb ProgressCallback) error {
+	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "scp upload")
+	}
+	defer f.Close()
+
+	cmd, stderr := a.command(meta, scpUploadScript, path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+	written, copyErr := tools.CopyWithCallback(stdin, f, t.Size, ccb)
+	stdin.Close()
+
+	// The script exits without reading its input if the object is
+	// already there, so a failed copy only matters if the script failed.
+	if err := cmd.Wait(); err != nil {
+		return scpError(err, stderr, t.Oid)
+	}
+	if copyErr != nil {
+		tracerx.Printf("xfer: scp stopped sending %q: %v", t.Oid, copyErr)
+		if cb != nil && written < t.Size {
+			return cb(t.Name, t.Size, t.Size, int(t.Size-written))
+		}
+	}
+	return nil
+}
+
+// command returns a command which runs the given script on the host with the
+// given argument, and the buffer which will hold its standard error.
+func (a *scpAdapter) command(meta *ssh.SSHMetadata, script, arg string) (*subprocess.Cmd, *bytes.Buffer) {
+	osEnv := a.apiClient.OSEnv()
+	gitEnv := a.apiClient.GitEnv()
+
+	exe, args, needShell := ssh.GetExeAndArgs(osEnv, gitEnv, meta, true)
+	args = append(args, fmt.Sprintf("sh -c %s git-lfs-scp %s",
+		subprocess.ShellQuoteSingle(script), subprocess.ShellQuoteSingle(arg)))
+	exe, args = ssh.FormatArgs(exe, args, needShell)
+
+	a.Trace("xfer: scp running %s %s", exe, strings.Join(args, " "))
+	cmd := subprocess.ExecCommand(exe, args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	return cmd, stderr
+}
+
+// target returns the host and directory which objects are transferred to and
+// from.
+func (a *scpAdapter) target() (*ssh.SSHMetadata, error) {
+	e := a.apiClient.Endpoints.Endpoint(a.direction.String(), a.remote)
+	if strings.HasPrefix(e.Url, "scp://") {
+		return parseScpURL(e.Url)
+	}
+	if len(e.SSHMetadata.UserAndHost) > 0 {
+		meta := e.SSHMetadata
+		return &meta, nil
+	}
+	return nil, errors.Errorf("scp: %q is not an SSH URL", e.Url)
+}
+
+func (a *scpAdapter) tempDir() string {
+	d := filepath.Join(a.fs.LFSStorageDir, "incomplete")
+	if err := tools.MkdirAll(d, a.fs); err != nil {
+		return os.TempDir()
+	}
+	return d
+}
+
+// parseScpURL parses a URL of the form "scp://[user@]host[:port]/path", where
+// the path is the remote object directory.
+func parseScpURL(rawurl string) (*ssh.SSHMetadata, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || len(u.Hostname()) == 0 {
+		return nil, errors.Errorf("scp: invalid URL %q", rawurl)
+	}
+
+	meta := &ssh.SSHMetadata{
+		UserAndHost: u.Hostname(),
+		Port:        u.Port(),
+		Path:        u.Path,
+	}
+	if u.User != nil && len(u.User.Username()) > 0 {
+		meta.UserAndHost = u.User.Username() + "@" + meta.UserAndHost
+	}
+	// As with scp, "/~/" starts a path relative to the home directory.
+	if strings.HasPrefix(meta.Path, "/~/") {
+		meta.Path = meta.Path[3:]
+	}
+	return meta, nil
+}
+
+func scpError(err error, stderr *bytes.Buffer, oid string) error {
+	msg := strings.TrimSpace(stderr.String())
+	if e, ok := err.(*exec.ExitError); ok {
+		if ws, ok := e.ProcessState.Sys().(syscall.WaitStatus); ok && ws.ExitStatus() == scpMissingStatus {
+			return errors.Errorf("scp: object %s is not on the remote", oid)
+		}
+	}
+	if len(msg) > 0 {
+		err = errors.Errorf("%v: %s", err, msg)
+	}
+	return errors.NewRetriableError(errors.Wrap(err, "scp"))
+}
+
+func configureScpAdapter(m *Manifest) {
+	newfunc := func(name string, dir Direction) Adapter {
+		sa := &scpAdapter{newAdapterBase(m.fs, name, dir, nil)}
+		// self implements impl
+		sa.transferImpl = sa
+		return sa
+	}
+	m.RegisterNewAdapterFunc(ScpAdapterName, Download, newfunc)
+	m.RegisterNewAdapterFunc(ScpAdapterName, Upload, newfunc)
+}
@@ -0,0 +1,39 @@
+package tq
+
+import (
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScpURL(t *testing.T) {
+	meta, err := parseScpURL("scp://git@example.com:2222/srv/lfs")
+	require.Nil(t, err)
+	assert.Equal(t, "git@example.com", meta.UserAndHost)
+	assert.Equal(t, "2222", meta.Port)
+	assert.Equal(t, "/srv/lfs", meta.Path)
+
+	meta, err = parseScpURL("scp://example.com/~/lfs")
+	require.Nil(t, err)
+	assert.Equal(t, "example.com", meta.UserAndHost)
+	assert.Equal(t, "", meta.Port)
+	assert.Equal(t, "lfs", meta.Path)
+
+	_, err = parseScpURL("scp:///srv/lfs")
+	assert.NotNil(t, err)
+}
+
+func TestScpAdapterIsChosenForScpURLs(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.url": "scp://git@example.com/srv/lfs",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifest(nil, cli, "download", "origin")
+	assert.True(t, m.IsStandaloneTransfer())
+	assert.Contains(t, m.GetDownloadAdapterNames(), ScpAdapterName)
+	assert.Contains(t, m.GetUploadAdapterNames(), ScpAdapterName)
+}
@@ -0,0 +1,82 @@
+package tq
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubyist/tracerx"
+)
+
+// summary is a machine-readable record of the outcome of a transfer queue,
+// appended as a single line of JSON to the file given by lfs.summaryfile.
+type summary struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Remote    string    `json:"remote"`
+	Ref       string    `json:"ref,omitempty"`
+	Objects   int       `json:"objects"`
+	Completed int       `json:"completed"`
+	Skipped   int64     `json:"skipped"`
+	Bytes     int64     `json:"bytes"`
+	Errors    int       `json:"errors"`
+}
+
+// writeSummary appends a summary of the transfers made by the queue to the
+// configured summary file, if any. Failures are traced, but otherwise ignored,
+// since the transfers themselves have already finished.
+func (q *TransferQueue) writeSummary() {
+	if len(q.manifest.summaryFile) == 0 || q.dryRun {
+		return
+	}
+
+	s := &summary{
+		Time:      time.Now().UTC(),
+		Operation: q.direction.String(),
+		Remote:    q.remote,
+		Ref:       q.ref.Refspec(),
+		Skipped:   atomic.LoadInt64(&q.skipped),
+		Errors:    len(q.errors),
+	}
+
+	q.trMutex.Lock()
+	for _, objs := range q.transfers {
+		s.Objects++
+		if objs.completed {
+			s.Completed++
+			if first := objs.First(); first != nil {
+				s.Bytes += first.Size
+			}
+		}
+	}
+	q.trMutex.Unlock()
+
+	if s.Objects == 0 && s.Errors == 0 {
+		return
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		tracerx.Printf("tq: unable to encode summary: %v", err)
+		return
+	}
+
+	name := q.manifest.summaryFile
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		tracerx.Printf("tq: unable to write summary to %q: %v", name, err)
+		return
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		tracerx.Printf("tq: unable to write summary to %q: %v", name, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		tracerx.Printf("tq: unable to write summary to %q: %v", name, err)
+	}
+}
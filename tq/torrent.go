@@ -0,0 +1,161 @@
+package tq
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// torrentMetainfo is the part of a BitTorrent metainfo (".torrent") file which
+// the torrent adapter uses: a single file, split into pieces with a SHA-1 hash
+// each, and the web seeds (BEP 19) it can be downloaded from.
+type torrentMetainfo struct {
+	Name        string
+	Length      int64
+	PieceLength int64
+	Pieces      [][]byte
+	WebSeeds    []string
+}
+
+// parseTorrentMetainfo parses a bencoded metainfo file for a single file.
+func parseTorrentMetainfo(data []byte) (*torrentMetainfo, error) {
+	v, rest, err := bdecode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("torrent: unexpected data after metainfo")
+	}
+
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrent: metainfo is not a dictionary")
+	}
+	info, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrent: metainfo has no info dictionary")
+	}
+	if _, ok := info["files"]; ok {
+		return nil, fmt.Errorf("torrent: metainfo has more than one file")
+	}
+
+	m := &torrentMetainfo{}
+	m.Name, _ = info["name"].(string)
+	m.Length, _ = info["length"].(int64)
+	m.PieceLength, _ = info["piece length"].(int64)
+	pieces, _ := info["pieces"].(string)
+	if m.Length < 0 || m.PieceLength <= 0 || len(pieces)%sha1.Size != 0 {
+		return nil, fmt.Errorf("torrent: invalid length, piece length or pieces")
+	}
+	for i := 0; i < len(pieces); i += sha1.Size {
+		m.Pieces = append(m.Pieces, []byte(pieces[i:i+sha1.Size]))
+	}
+	if int64(len(m.Pieces)) != (m.Length+m.PieceLength-1)/m.PieceLength {
+		return nil, fmt.Errorf("torrent: %d pieces do not cover %d bytes", len(m.Pieces), m.Length)
+	}
+
+	// "url-list" is either one URL or a list of them. A URL ending in a
+	// slash is a directory holding the file under its name.
+	var seeds []interface{}
+	switch list := root["url-list"].(type) {
+	case string:
+		seeds = []interface{}{list}
+	case []interface{}:
+		seeds = list
+	}
+	for _, s := range seeds {
+		seed, ok := s.(string)
+		if !ok || len(seed) == 0 {
+			continue
+		}
+		if strings.HasSuffix(seed, "/") {
+			seed += url.PathEscape(m.Name)
+		}
+		m.WebSeeds = append(m.WebSeeds, seed)
+	}
+	return m, nil
+}
+
+// Piece returns the offset and length of the piece with the given index.
+func (m *torrentMetainfo) Piece(i int) (int64, int64) {
+	offset := int64(i) * m.PieceLength
+	length := m.PieceLength
+	if offset+length > m.Length {
+		length = m.Length - offset
+	}
+	return offset, length
+}
+
+// bdecode decodes the bencoded value at the start of data into an int64, a
+// string, a []interface{} or a map[string]interface{}, and returns it along
+// with the rest of data.
+func bdecode(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("torrent: unexpected end of metainfo")
+	}
+
+	switch c := data[0]; {
+	case c == 'i':
+		end := bytes.IndexByte(data, 'e')
+		if end < 0 {
+			return nil, nil, fmt.Errorf("torrent: unterminated integer")
+		}
+		n, err := strconv.ParseInt(string(data[1:end]), 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("torrent: invalid integer %q", data[1:end])
+		}
+		return n, data[end+1:], nil
+	case c == 'l':
+		var list []interface{}
+		data = data[1:]
+		for len(data) > 0 && data[0] != 'e' {
+			v, rest, err := bdecode(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			list = append(list, v)
+			data = rest
+		}
+		if len(data) == 0 {
+			return nil, nil, fmt.Errorf("torrent: unterminated list")
+		}
+		return list, data[1:], nil
+	case c == 'd':
+		dict := make(map[string]interface{})
+		data = data[1:]
+		for len(data) > 0 && data[0] != 'e' {
+			k, rest, err := bdecode(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("torrent: dictionary key is not a string")
+			}
+			v, rest, err := bdecode(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			dict[key] = v
+			data = rest
+		}
+		if len(data) == 0 {
+			return nil, nil, fmt.Errorf("torrent: unterminated dictionary")
+		}
+		return dict, data[1:], nil
+	case c >= '0' && c <= '9':
+		colon := bytes.IndexByte(data, ':')
+		if colon < 0 {
+			return nil, nil, fmt.Errorf("torrent: invalid string length")
+		}
+		n, err := strconv.Atoi(string(data[:colon]))
+		if err != nil || n < 0 || colon+1+n > len(data) {
+			return nil, nil, fmt.Errorf("torrent: invalid string length %q", data[:colon])
+		}
+		return string(data[colon+1 : colon+1+n]), data[colon+1+n:], nil
+	}
+	return nil, nil, fmt.Errorf("torrent: unexpected %q in metainfo", data[0])
+}
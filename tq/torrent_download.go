@@ -0,0 +1,228 @@
+package tq
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+const TorrentAdapterName = "torrent"
+
+// maxTorrentPieceLength is the largest piece the torrent adapter accepts, since
+// each piece is held in memory until it is verified.
+const maxTorrentPieceLength = 64 * 1024 * 1024
+
+// Adapter for downloads described by BitTorrent metainfo, whose download action
+// links to a ".torrent" file for the object. The object is downloaded piece by
+// piece from the web seeds listed there, such as mirrors inside a build farm,
+// trying the next seed when one fails, and each piece is checked against its
+// SHA-1 hash, so pieces from an interrupted download are kept. The object as a
+// whole is then checked against its OID as usual.
+type torrentDownloadAdapter struct {
+	*adapterBase
+}
+
+func (a *torrentDownloadAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *torrentDownloadAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *torrentDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	rel, err := t.Rel("download")
+	if err != nil {
+		return err
+	}
+	if rel == nil {
+		return errors.Errorf("No download action for object: %s", t.Oid)
+	}
+
+	meta, err := a.metainfo(t, rel)
+	if err != nil {
+		return err
+	}
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+	if meta.Length != t.Size {
+		return fmt.Errorf("torrent: metainfo for %s is for %d bytes, expected %d", t.Oid, meta.Length, t.Size)
+	}
+	if meta.PieceLength > maxTorrentPieceLength {
+		return fmt.Errorf("torrent: pieces of %d bytes for %s are too large", meta.PieceLength, t.Oid)
+	}
+	if len(meta.WebSeeds) == 0 {
+		return fmt.Errorf("torrent: metainfo for %s has no web seeds", t.Oid)
+	}
+
+	dir := filepath.Join(a.fs.LFSStorageDir, "incomplete")
+	if err := tools.MkdirAll(dir, a.fs); err != nil {
+		return err
+	}
+	partName := filepath.Join(dir, t.Oid+".torrent.part")
+	f, err := os.OpenFile(partName, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(t.Size); err != nil {
+		return err
+	}
+
+	var done int64
+	buf := make([]byte, meta.PieceLength)
+	for i, sum := range meta.Pieces {
+		offset, length := meta.Piece(i)
+		piece := buf[:length]
+
+		// Keep the pieces of an earlier attempt which are intact.
+		if _, err := f.ReadAt(piece, offset); err == nil && pieceMatches(piece, sum) {
+			a.Trace("xfer: torrent piece %d of %q already downloaded", i, t.Oid)
+		} else {
+			if err := a.downloadPiece(meta, i, piece); err != nil {
+				return errors.NewRetriableError(err)
+			}
+			if _, err := f.WriteAt(piece, offset); err != nil {
+				return errors.Wrapf(err, "cannot write data to %q", partName)
+			}
+		}
+
+		done += length
+		if cb != nil {
+			if err := cb(t.Name, t.Size, done, int(length)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher := tools.NewLfsContentHash()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != t.Oid {
+		// Every piece matched the metainfo, so it describes something
+		// else, and the download cannot be resumed.
+		f.Close()
+		os.Remove(partName)
+		return fmt.Errorf("expected OID %s, got %s from torrent", t.Oid, actual)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", partName, err)
+	}
+
+	err = tools.RenameFileCopyPermissions(partName, t.Path)
+	if _, err2 := os.Stat(t.Path); err2 == nil {
+		// Target file already exists, possibly was downloaded by other git-lfs process
+		return nil
+	}
+	return err
+}
+
+// metainfo downloads and parses the metainfo file for the object.
+func (a *torrentDownloadAdapter) metainfo(t *Transfer, rel *Action) (*torrentMetainfo, error) {
+	req, err := a.newHTTPRequest("GET", rel)
+	if err != nil {
+		return nil, err
+	}
+
+	req = a.apiClient.LogRequest(req, "lfs.data.download")
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		if errors.IsAuthError(err) {
+			return nil, err
+		}
+		return nil, errors.NewRetriableError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.NewRetriableError(fmt.Errorf("torrent: unexpected status %d for metainfo of %s", res.StatusCode, t.Oid))
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.NewRetriableError(err)
+	}
+	return parseTorrentMetainfo(data)
+}
+
+// downloadPiece reads the piece with the given index into piece, from each web
+// seed in turn, starting from a different one for each piece to spread the
+// load, until one sends data which matches the piece's hash.
+func (a *torrentDownloadAdapter) downloadPiece(meta *torrentMetainfo, i int, piece []byte) error {
+	offset, length := meta.Piece(i)
+
+	var lastErr error
+	for n := 0; n < len(meta.WebSeeds); n++ {
+		seed := meta.WebSeeds[(i+n)%len(meta.WebSeeds)]
+		err := a.downloadPieceFrom(seed, offset, length, piece)
+		if err == nil && !pieceMatches(piece, meta.Pieces[i]) {
+			err = fmt.Errorf("torrent: piece %d from %s does not match its hash", i, seed)
+		}
+		if err == nil {
+			return nil
+		}
+
+		a.Trace("xfer: %v", err)
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (a *torrentDownloadAdapter) downloadPieceFrom(seed string, offset, length int64, piece []byte) error {
+	req, err := http.NewRequest("GET", seed, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	res, err := a.apiClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "torrent: web seed %s", seed)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+	case res.StatusCode == http.StatusOK && offset == 0:
+		// The seed ignored the range and sent the whole file, which
+		// starts with the piece.
+	default:
+		return fmt.Errorf("torrent: web seed %s sent status %d", seed, res.StatusCode)
+	}
+
+	if _, err := io.ReadFull(res.Body, piece); err != nil {
+		return errors.Wrapf(err, "torrent: web seed %s", seed)
+	}
+	return nil
+}
+
+func pieceMatches(piece, sum []byte) bool {
+	actual := sha1.Sum(piece)
+	return bytes.Equal(actual[:], sum)
+}
+
+func configureTorrentAdapter(m *Manifest) {
+	m.RegisterNewAdapterFunc(TorrentAdapterName, Download, func(name string, dir Direction) Adapter {
+		switch dir {
+		case Download:
+			td := &torrentDownloadAdapter{newAdapterBase(m.fs, name, dir, nil)}
+			// self implements impl
+			td.transferImpl = td
+			return td
+		case Upload:
+			panic("Should never ask the torrent adapter to upload")
+		}
+		return nil
+	})
+}
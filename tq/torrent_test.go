@@ -0,0 +1,161 @@
+package tq
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bstring bencodes a string.
+func bstring(s string) string {
+	return fmt.Sprintf("%d:%s", len(s), s)
+}
+
+// testMetainfo bencodes metainfo for content with the given piece length and
+// url-list, which is already bencoded.
+func testMetainfo(content string, pieceLength int, urlList string) string {
+	var pieces strings.Builder
+	for i := 0; i < len(content); i += pieceLength {
+		end := i + pieceLength
+		if end > len(content) {
+			end = len(content)
+		}
+		sum := sha1.Sum([]byte(content[i:end]))
+		pieces.Write(sum[:])
+	}
+	return fmt.Sprintf("d4:infod6:lengthi%de4:name3:obj12:piece lengthi%de6:pieces%d:%se8:url-list%se",
+		len(content), pieceLength, pieces.Len(), pieces.String(), urlList)
+}
+
+func TestParseTorrentMetainfo(t *testing.T) {
+	m, err := parseTorrentMetainfo([]byte(testMetainfo("hello world", 4, "l"+bstring("http://a/obj.data")+bstring("http://b/")+"e")))
+	require.Nil(t, err)
+
+	assert.Equal(t, "obj", m.Name)
+	assert.EqualValues(t, 11, m.Length)
+	assert.EqualValues(t, 4, m.PieceLength)
+	assert.Len(t, m.Pieces, 3)
+	assert.Equal(t, []string{"http://a/obj.data", "http://b/obj"}, m.WebSeeds)
+
+	offset, length := m.Piece(2)
+	assert.EqualValues(t, 8, offset)
+	assert.EqualValues(t, 3, length)
+}
+
+func TestParseTorrentMetainfoSingleWebSeed(t *testing.T) {
+	m, err := parseTorrentMetainfo([]byte(testMetainfo("hello", 8, bstring("http://a"))))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"http://a"}, m.WebSeeds)
+}
+
+func TestParseTorrentMetainfoInvalid(t *testing.T) {
+	for desc, data := range map[string]string{
+		"empty":            "",
+		"not a dictionary": "i1e",
+		"no info":          "de",
+		"trailing data":    "dei1e",
+		"multiple files":   "d4:infod5:filesleee",
+		"missing pieces":   "d4:infod6:lengthi10e12:piece lengthi4e6:pieces0:ee",
+		"bad string":       "d4:infod4:name99:xee",
+		"unterminated":     "d4:infod6:lengthi10e",
+	} {
+		_, err := parseTorrentMetainfo([]byte(data))
+		assert.NotNil(t, err, desc)
+	}
+}
+
+func TestTorrentDownload(t *testing.T) {
+	content := "the quick brown fox jumps over the lazy dog"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+
+	var seedRequests int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/obj.torrent":
+			assert.Equal(t, "token", req.Header.Get("Authorization"))
+			seeds := "l" + bstring(srv.URL+"/broken") + bstring(srv.URL+"/seed/") + "e"
+			w.Write([]byte(testMetainfo(content, 8, seeds)))
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/seed/obj":
+			atomic.AddInt32(&seedRequests, 1)
+			var start, end int
+			_, err := fmt.Sscanf(req.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			require.Nil(t, err)
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(content[start : end+1]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "tq-torrent")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, nil))
+	require.Nil(t, err)
+
+	f := fs.New(cli.OSEnv(), filepath.Join(dir, ".git"), dir, "", 0644)
+	a := &torrentDownloadAdapter{newAdapterBase(f, TorrentAdapterName, Download, nil)}
+	a.transferImpl = a
+	a.apiClient = cli
+
+	tr := &Transfer{
+		Oid:  oid,
+		Size: int64(len(content)),
+		Path: filepath.Join(dir, "obj"),
+		Actions: ActionSet{"download": &Action{
+			Href:   srv.URL + "/obj.torrent",
+			Header: map[string]string{"Authorization": "token"},
+		}},
+	}
+
+	var progress int64
+	cb := func(name string, total, read int64, current int) error {
+		progress = read
+		return nil
+	}
+	require.Nil(t, a.DoTransfer(nil, tr, cb, nil))
+
+	data, err := ioutil.ReadFile(tr.Path)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(data))
+	assert.EqualValues(t, len(content), progress)
+	assert.EqualValues(t, 6, seedRequests)
+}
+
+func TestTorrentAdapterIsOptIn(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, nil))
+	require.Nil(t, err)
+	assert.NotContains(t, NewManifest(nil, cli, "", "").GetDownloadAdapterNames(), TorrentAdapterName)
+
+	cli, err = lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.torrenttransfers": "true",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifest(nil, cli, "", "")
+	assert.Contains(t, m.GetDownloadAdapterNames(), TorrentAdapterName)
+	assert.NotContains(t, m.GetUploadAdapterNames(), TorrentAdapterName)
+}
@@ -83,6 +83,18 @@ func (e *ObjectError) Error() string {
 	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
 }
 
+// Missing returns whether the error reports that the object does not exist
+// on the server, or no longer does.
+func (e *ObjectError) Missing() bool {
+	return e.Code == 404 || e.Code == 410
+}
+
+// Denied returns whether the error reports that the user is not allowed to
+// access the object.
+func (e *ObjectError) Denied() bool {
+	return e.Code == 401 || e.Code == 403
+}
+
 // newTransfer returns a copy of the given Transfer, with the name and path
 // values set.
 func newTransfer(tr *Transfer, name string, path string) *Transfer {
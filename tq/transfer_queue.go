@@ -5,6 +5,7 @@ import (
 	"os"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/git-lfs/git-lfs/v2/errors"
@@ -178,6 +179,9 @@ func (q *abortableWaitGroup) Wait() {
 // including calling the API, passing the actual transfer request to transfer
 // adapters, and dealing with progress, errors and retries.
 type TransferQueue struct {
+	skipped           int64 // int64s must come first for struct alignment
+	transferred       int64
+	canceled          int64
 	direction         Direction
 	client            *tqClient
 	remote            string
@@ -191,6 +195,7 @@ type TransferQueue struct {
 	errors            []error
 	transfers         map[string]*objects
 	batchSize         int
+	batchSizer        *batchSizer
 	bufferDepth       int
 	incoming          chan *objectTuple // Channel for processing incoming items
 	errorc            chan error        // Channel for processing errors
@@ -209,12 +214,46 @@ type TransferQueue struct {
 	// an HTTP 422 response indicating that their upload destination does
 	// not support Content-Type detection.
 	unsupportedContentType bool
+
+	// notices holds the informational messages received from the server
+	// in batch responses, in the order they were first received, to be
+	// shown once the queue has finished.
+	notices   *tools.OrderedSet
+	noticesMu sync.Mutex
+
+	// present is called with the OID of each object which is known to be
+	// on the server once an upload is complete, or if the server reported
+	// already having it.
+	present func(oid string)
+
+	// missing is called with the OID of each object which the server
+	// reported not having when downloading.
+	missing func(oid string)
+
+	// pendingFile is the file in which the objects which were not
+	// transferred are recorded once the queue has finished, and failures
+	// holds the errors with which their transfers failed, by OID.
+	pendingFile string
+	failures    map[string]string
+
+	// eventOids maps the name of each object being transferred to its
+	// OID, since transfer adapters report progress by name.
+	eventOids map[string]string
+
+	// cancel is closed by Cancel(), after which no further transfers are
+	// started, and done is closed once Wait() has returned.
+	cancel     chan struct{}
+	cancelOnce sync.Once
+	done       chan struct{}
 }
 
 // objects holds a set of objects.
 type objects struct {
 	completed bool
-	objects   []*objectTuple
+	// present is whether the server reported already having the objects,
+	// when uploading, and so they were not transferred.
+	present bool
+	objects []*objectTuple
 }
 
 // All returns all *objectTuple's contained in the *objects set.
@@ -227,6 +266,7 @@ func (s *objects) All() []*objectTuple {
 func (s *objects) Append(os ...*objectTuple) *objects {
 	return &objects{
 		completed: s.completed,
+		present:   s.present,
 		objects:   append(s.objects, os...),
 	}
 }
@@ -270,6 +310,15 @@ func WithProgress(m *Meter) Option {
 	}
 }
 
+// WithPendingFile records the objects which the queue does not transfer in the
+// given file once it has finished, and drops those which it does transfer, so
+// that they may be retried later. See ReadPendingFile.
+func WithPendingFile(name string) Option {
+	return func(tq *TransferQueue) {
+		tq.pendingFile = name
+	}
+}
+
 func RemoteRef(ref *git.Ref) Option {
 	return func(tq *TransferQueue) {
 		tq.ref = ref
@@ -282,6 +331,22 @@ func WithProgressCallback(cb tools.CopyCallback) Option {
 	}
 }
 
+// WithPresentCallback sets a function to be called with the OID of each object
+// which is known to be on the server, once it has been uploaded, or if the
+// server reports already having it. It is only used for uploads, and may be
+// called concurrently.
+func WithPresentCallback(cb func(oid string)) Option {
+	return func(tq *TransferQueue) { tq.present = cb }
+}
+
+// WithMissingCallback sets a function to be called with the OID of each object
+// which the server reports it does not have, either because it was never
+// uploaded or because it has been removed. It is only used for downloads, and
+// may be called concurrently.
+func WithMissingCallback(cb func(oid string)) Option {
+	return func(tq *TransferQueue) { tq.missing = cb }
+}
+
 func WithBatchSize(size int) Option {
 	return func(tq *TransferQueue) { tq.batchSize = size }
 }
@@ -298,10 +363,15 @@ func NewTransferQueue(dir Direction, manifest *Manifest, remote string, options
 		remote:    remote,
 		errorc:    make(chan error),
 		transfers: make(map[string]*objects),
+		failures:  make(map[string]string),
+		eventOids: make(map[string]string),
 		trMutex:   &sync.Mutex{},
 		manifest:  manifest,
 		rc:        newRetryCounter(),
 		wait:      newAbortableWaitGroup(),
+		notices:   tools.NewOrderedSet(),
+		cancel:    make(chan struct{}),
+		done:      make(chan struct{}),
 	}
 
 	for _, opt := range options {
@@ -312,9 +382,13 @@ func NewTransferQueue(dir Direction, manifest *Manifest, remote string, options
 	q.rc.MaxRetryDelay = q.manifest.maxRetryDelay
 	q.client.SetMaxRetries(q.manifest.maxRetries)
 
+	if q.batchSize <= 0 {
+		q.batchSize = q.manifest.BatchSize()
+	}
 	if q.batchSize <= 0 {
 		q.batchSize = defaultBatchSize
 	}
+	q.batchSizer = newBatchSizer(q.batchSize)
 	if q.bufferDepth <= 0 {
 		q.bufferDepth = q.batchSize
 	}
@@ -369,6 +443,7 @@ func (q *TransferQueue) Add(name, path, oid string, size int64, missing bool, er
 		return
 	}
 
+	q.emitEvent(EventQueued, t.Oid, 0, nil)
 	q.incoming <- t
 }
 
@@ -398,7 +473,8 @@ func (q *TransferQueue) remember(t *objectTuple) objects {
 // previous before adding new items. The process works as follows:
 //
 //   1. Create a new batch, of size `q.batchSize`, and containing no items
-//   2. While the batch contains less items than `q.batchSize` AND the channel
+//   2. While the batch contains less items than the current batch size, which
+//      starts as `q.batchSize` but may shrink and grow again, AND the channel
 //      is open, read one item from the `q.incoming` channel.
 //      a. If the read was a channel close, go to step 4.
 //      b. If the read was a transferable item, go to step 3.
@@ -423,7 +499,7 @@ func (q *TransferQueue) collectBatches() {
 	pending := q.makeBatch()
 
 	for {
-		for !closing && (len(next) < q.batchSize) {
+		for !closing && (len(next) < q.batchSizer.Size()) {
 			t, ok := <-q.incoming
 			if !ok {
 				closing = true
@@ -473,7 +549,7 @@ func (q *TransferQueue) collectBatches() {
 		// - new additions that were enqueued behind retries, &
 		// - items collected while the batch was processing.
 		var minWaitTime time.Duration
-		next, pending, minWaitTime = retries.Concat(append(pending, collected...), q.batchSize)
+		next, pending, minWaitTime = retries.Concat(append(pending, collected...), q.batchSizer.Size())
 		if len(next) == 0 && len(pending) != 0 {
 			// There are some pending that cound not be queued.
 			// Wait the requested time before resuming loop.
@@ -519,6 +595,12 @@ func (q *TransferQueue) collectPendingUntil(done <-chan struct{}) (pending batch
 // processed.
 func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error) {
 	next := q.makeBatch()
+	if q.isCanceled() {
+		for _, t := range batch {
+			q.cancelObject(t.Oid)
+		}
+		return next, nil
+	}
 	tracerx.Printf("tq: sending batch of size %d", len(batch))
 
 	enqueueRetry := func(t *objectTuple, err error, readyTime *time.Time) {
@@ -555,8 +637,16 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 		// Query the Git LFS server for what transfer method to use and
 		// details such as URLs, authentication, etc.
 		var err error
+		start := time.Now()
 		bRes, err = Batch(q.manifest, q.direction, q.remote, q.ref, batch.ToTransfers())
 		if err != nil {
+			// If the batch was too large for the server, send its
+			// objects again in smaller batches, without counting
+			// that as a retry.
+			if q.batchSizer.Shrink(err) {
+				return append(next, batch...), nil
+			}
+
 			var hasNonScheduledErrors = false
 			// If there was an error making the batch API call, mark all of
 			// the objects for retry, and return them along with the error
@@ -582,6 +672,12 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 				return next, nil
 			}
 		}
+		q.batchSizer.Grow(len(batch), time.Since(start))
+	}
+
+	q.addNotices(bRes.Notices())
+	if bRes.Capabilities != nil {
+		q.useCapabilities(bRes.Capabilities)
 	}
 
 	if len(bRes.Objects) == 0 {
@@ -611,7 +707,18 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 
 	for _, o := range bRes.Objects {
 		if o.Error != nil {
-			q.errorc <- errors.Wrapf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+			if o.Error.Missing() {
+				q.markMissing(o.Oid)
+			}
+			var name string
+			q.trMutex.Lock()
+			if objects, ok := q.transfers[o.Oid]; ok && objects.First() != nil {
+				name = objects.First().Name
+			}
+			q.trMutex.Unlock()
+
+			q.recordFailure(o.Oid, o.Error)
+			q.errorc <- &ObjectTransferError{Name: name, Oid: o.Oid, Err: o.Error}
 			q.Skip(o.Size)
 			q.wait.Done()
 
@@ -639,21 +746,43 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 				if q.canRetryObject(tr.Oid, err) {
 					enqueueRetry(objects.First(), err, nil)
 				} else {
+					q.recordFailure(tr.Oid, err)
 					q.errorc <- errors.Errorf("[%v] %v", tr.Name, err)
 
 					q.Skip(o.Size)
 					q.wait.Done()
 				}
 			} else if a == nil && q.manifest.standaloneTransferAgent == "" {
+				q.trMutex.Lock()
+				objects.present = true
+				q.trMutex.Unlock()
+
+				q.markPresent(o.Oid)
+				q.emitEvent(EventSkipped, o.Oid, 0, nil)
 				q.Skip(o.Size)
 				q.wait.Done()
 			} else {
+				if a != nil && q.chunkedUploads() {
+					useChunkedUpload(a)
+				}
+				q.trMutex.Lock()
+				q.eventOids[objects.First().Name] = o.Oid
+				q.trMutex.Unlock()
+
 				q.meter.StartTransfer(objects.First().Name)
+				q.emitEvent(EventStarted, o.Oid, 0, nil)
 				toTransfer = append(toTransfer, tr)
 			}
 		}
 	}
 
+	if q.isCanceled() {
+		for _, t := range toTransfer {
+			q.cancelObject(t.Oid)
+		}
+		return next, nil
+	}
+
 	retries := q.addToAdapter(bRes.endpoint, toTransfer)
 	for t := range retries {
 		enqueueRetry(t, nil, nil)
@@ -662,6 +791,22 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 	return next, nil
 }
 
+// markPresent reports that the object with the given OID is on the server,
+// when uploading.
+func (q *TransferQueue) markPresent(oid string) {
+	if q.direction == Upload && q.present != nil {
+		q.present(oid)
+	}
+}
+
+// markMissing reports that the server does not have the object with the given
+// OID, when downloading.
+func (q *TransferQueue) markMissing(oid string) {
+	if q.direction == Download && q.missing != nil {
+		q.missing(oid)
+	}
+}
+
 // makeBatch returns a new, empty batch, with a capacity equal to the maximum
 // batch size designated by the `*TransferQueue`.
 func (q *TransferQueue) makeBatch() batch { return make(batch, 0, q.batchSize) }
@@ -768,8 +913,13 @@ func (q *TransferQueue) handleTransferResult(
 	res TransferResult, retries chan<- *objectTuple,
 ) {
 	oid := res.Transfer.Oid
+	q.manifest.recorder.RecordTransfer(q.direction, res)
 
-	if res.Error != nil {
+	if res.Error != nil && q.isCanceled() {
+		// The transfer was stopped by Cancel(), so it is neither
+		// retried nor reported as an error.
+		q.cancelObject(oid)
+	} else if res.Error != nil {
 		// If there was an error encountered when processing the
 		// transfer (res.Transfer), handle the error as is appropriate:
 		if readyTime, canRetry := q.canRetryObjectLater(oid, res.Error); canRetry {
@@ -777,6 +927,7 @@ func (q *TransferQueue) handleTransferResult(
 			// after a certain period of time, send it to
 			// the retry channel with a time when it's ready.
 			tracerx.Printf("tq: retrying object %s after %s seconds.", oid, time.Until(readyTime).Seconds())
+			q.meter.RetryTransfer(res.Transfer.Name)
 			q.trMutex.Lock()
 			objects, ok := q.transfers[oid]
 			q.trMutex.Unlock()
@@ -793,6 +944,7 @@ func (q *TransferQueue) handleTransferResult(
 			// channel, where it will be read at the call-site and
 			// its retry count will be incremented.
 			tracerx.Printf("tq: retrying object %s: %s", oid, res.Error)
+			q.meter.RetryTransfer(res.Transfer.Name)
 
 			q.trMutex.Lock()
 			objects, ok := q.transfers[oid]
@@ -809,6 +961,8 @@ func (q *TransferQueue) handleTransferResult(
 			// the retry channel, and the error will be reported
 			// immediately (unless the error is in response to a
 			// HTTP 422).
+			q.recordFailure(oid, res.Error)
+			q.meter.FailTransfer(res.Transfer.Name)
 			if errors.IsUnprocessableEntityError(res.Error) {
 				q.unsupportedContentType = true
 			} else {
@@ -817,6 +971,8 @@ func (q *TransferQueue) handleTransferResult(
 			q.wait.Done()
 		}
 	} else {
+		q.markPresent(oid)
+
 		q.trMutex.Lock()
 		objects := q.transfers[oid]
 		objects.completed = true
@@ -839,10 +995,59 @@ func (q *TransferQueue) handleTransferResult(
 		q.trMutex.Unlock()
 
 		q.meter.FinishTransfer(res.Transfer.Name)
+		q.emitEvent(EventVerified, oid, 0, nil)
+		atomic.AddInt64(&q.transferred, 1)
 		q.wait.Done()
 	}
 }
 
+// Cancel stops the queue from starting any further transfers, and stops those
+// in progress once their current write has completed, leaving any partially
+// downloaded objects to be resumed later. It returns whether the queue was
+// still running, in which case Wait() returns once the transfers have stopped.
+func (q *TransferQueue) Cancel() bool {
+	select {
+	case <-q.done:
+		return false
+	default:
+	}
+
+	q.cancelOnce.Do(func() {
+		tracerx.Printf("tq: canceling transfers")
+		close(q.cancel)
+	})
+	return true
+}
+
+// isCanceled returns whether Cancel() has been called.
+func (q *TransferQueue) isCanceled() bool {
+	select {
+	case <-q.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelObject marks the object with the given OID as not transferred because
+// the queue was canceled.
+func (q *TransferQueue) cancelObject(oid string) {
+	tracerx.Printf("tq: canceled transfer of %s", oid)
+	atomic.AddInt64(&q.canceled, 1)
+	q.wait.Done()
+}
+
+// Transferred returns the number of objects which have been transferred.
+func (q *TransferQueue) Transferred() int {
+	return int(atomic.LoadInt64(&q.transferred))
+}
+
+// Canceled returns the number of objects which were not transferred because
+// the queue was canceled.
+func (q *TransferQueue) Canceled() int {
+	return int(atomic.LoadInt64(&q.canceled))
+}
+
 func (q *TransferQueue) useAdapter(name string) {
 	q.adapterInitMutex.Lock()
 	defer q.adapterInitMutex.Unlock()
@@ -870,12 +1075,14 @@ func (q *TransferQueue) finishAdapter() {
 }
 
 // BatchSize returns the batch size of the receiving *TransferQueue, or, the
-// number of transfers to accept before beginning work on them.
+// number of transfers to accept before beginning work on them. It may shrink
+// while the queue runs, if the server rejects batches as too large.
 func (q *TransferQueue) BatchSize() int {
-	return q.batchSize
+	return q.batchSizer.Size()
 }
 
 func (q *TransferQueue) Skip(size int64) {
+	atomic.AddInt64(&q.skipped, 1)
 	q.meter.Skip(size)
 }
 
@@ -890,6 +1097,7 @@ func (q *TransferQueue) ensureAdapterBegun(e lfshttp.Endpoint) error {
 	// Progress callback - receives byte updates
 	cb := func(name string, total, read int64, current int) error {
 		q.meter.TransferBytes(q.direction.String(), name, read, total, current)
+		q.emitProgress(name, read)
 		if q.cb != nil {
 			// NOTE: this is the mechanism by which the logpath
 			// specified by GIT_LFS_PROGRESS is written to.
@@ -897,6 +1105,11 @@ func (q *TransferQueue) ensureAdapterBegun(e lfshttp.Endpoint) error {
 			// See: lfs.downloadFile() for more.
 			q.cb(total, read, current)
 		}
+		// Returning an error stops the transfer after the data
+		// read so far has been written.
+		if q.isCanceled() {
+			return errTransferCanceled
+		}
 		return nil
 	}
 
@@ -950,6 +1163,8 @@ func (q *TransferQueue) Wait() {
 
 	q.meter.Flush()
 	q.errorwait.Wait()
+	q.writeSummary()
+	q.writePending()
 
 	if q.manifest.sshTransfer != nil {
 		q.manifest.sshTransfer.Shutdown()
@@ -960,6 +1175,55 @@ func (q *TransferQueue) Wait() {
 			fmt.Fprintf(os.Stderr, "info: %s\n", line)
 		}
 	}
+	q.noticesMu.Lock()
+	for notice := range q.notices.Iter() {
+		if q.manifest.newNotice(notice) {
+			fmt.Fprintf(os.Stderr, "remote: %s\n", notice)
+		}
+	}
+	q.noticesMu.Unlock()
+
+	close(q.done)
+}
+
+// useCapabilities tunes the queue to the capabilities advertised by the
+// server.
+func (q *TransferQueue) useCapabilities(c *Capabilities) {
+	q.manifest.setCapabilities(c)
+	q.batchSizer.Limit(c.MaxBatchSize)
+}
+
+// chunkedUploads returns whether objects should be uploaded with chunked
+// transfer encoding, which is only done if the server says it accepts it.
+func (q *TransferQueue) chunkedUploads() bool {
+	if q.direction != Upload {
+		return false
+	}
+	c := q.manifest.Capabilities()
+	return c != nil && c.ChunkedUploads
+}
+
+// useChunkedUpload makes the basic adapter upload to the given action using
+// chunked transfer encoding, unless the server already said how to upload.
+func useChunkedUpload(a *Action) {
+	if _, ok := a.Header["Transfer-Encoding"]; ok {
+		return
+	}
+	if a.Header == nil {
+		a.Header = make(map[string]string)
+	}
+	a.Header["Transfer-Encoding"] = "chunked"
+}
+
+// addNotices records the given informational messages from the server to be
+// shown once the queue has finished.
+func (q *TransferQueue) addNotices(notices []string) {
+	q.noticesMu.Lock()
+	defer q.noticesMu.Unlock()
+
+	for _, notice := range notices {
+		q.notices.Add(notice)
+	}
 }
 
 // Watch returns a channel where the queue will write the value of each transfer
@@ -80,3 +80,23 @@ func TestBatchSizeReturnsBatchSize(t *testing.T) {
 
 	assert.Equal(t, 3, q.BatchSize())
 }
+
+func TestCancelStopsQueuedTransfers(t *testing.T) {
+	q := NewTransferQueue(Download, NewManifest(nil, nil, "", ""), "origin")
+
+	assert.True(t, q.Cancel())
+	q.Add("a.dat", "a.dat", "a-oid", 1, false, nil)
+	q.Add("b.dat", "b.dat", "b-oid", 1, false, nil)
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, 0, q.Transferred())
+	assert.Equal(t, 2, q.Canceled())
+}
+
+func TestCancelAfterWaitReturnsFalse(t *testing.T) {
+	q := NewTransferQueue(Download, NewManifest(nil, nil, "", ""), "origin")
+	q.Wait()
+
+	assert.False(t, q.Cancel())
+}
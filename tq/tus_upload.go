@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/git-lfs/git-lfs/v2/errors"
 	"github.com/git-lfs/git-lfs/v2/lfsapi"
 	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/git-lfs/git-lfs/v2/tools/humanize"
 )
 
 const (
@@ -18,6 +20,16 @@ const (
 	TusVersion     = "1.0.0"
 )
 
+const (
+	// defaultTusChunkSize is the most content sent in one PATCH request,
+	// unless lfs.tus.chunksize says otherwise.
+	defaultTusChunkSize = 64 * 1024 * 1024
+	// tusMaxResumes is how many times in a row an upload is resumed after
+	// a PATCH request fails without the server keeping any more of it,
+	// before the upload is left for the transfer queue to retry.
+	tusMaxResumes = 3
+)
+
 // Adapter for tus.io protocol resumaable uploads
 type tusUploadAdapter struct {
 	*adapterBase
@@ -42,29 +54,10 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 	// Also not supporting Concatenation to support parallel uploads of chunks; forward only
 
 	// 1. Send HEAD request to determine upload start point
-	//    Request must include Tus-Resumable header (version)
-	a.Trace("xfer: sending tus.io HEAD request for %q", t.Oid)
-	req, err := a.newHTTPRequest("HEAD", rel)
+	offset, err := a.uploadOffset(t, rel)
 	if err != nil {
 		return err
 	}
-
-	req.Header.Set("Tus-Resumable", TusVersion)
-
-	res, err := a.doHTTP(t, req)
-	if err != nil {
-		return errors.NewRetriableError(err)
-	}
-
-	//    Response will contain Upload-Offset if supported
-	offHdr := res.Header.Get("Upload-Offset")
-	if len(offHdr) == 0 {
-		return fmt.Errorf("missing Upload-Offset header from tus.io HEAD response at %q, contact server admin", rel.Href)
-	}
-	offset, err := strconv.ParseInt(offHdr, 10, 64)
-	if err != nil || offset < 0 {
-		return fmt.Errorf("invalid Upload-Offset value %q in response from tus.io HEAD at %q, contact server admin", offHdr, rel.Href)
-	}
 	// Upload-Offset=size means already completed (skip)
 	// Batch API will probably already detect this, but handle just in case
 	if offset >= t.Size {
@@ -80,46 +73,143 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 	}
 	defer f.Close()
 
+	// Progress is reported by position in the object, so that it can go
+	// back when the server has kept less of an interrupted PATCH than was
+	// sent.
+	var reported int64
+	report := func(pos int64) error {
+		defer func() { reported = pos }()
+		if cb != nil && pos != reported {
+			return cb(t.Name, t.Size, pos, int(pos-reported))
+		}
+		return nil
+	}
+
 	// Upload-Offset=0 means start from scratch, but still send PATCH
 	if offset == 0 {
 		a.Trace("xfer: tus.io uploading %q from start", t.Oid)
 	} else {
 		a.Trace("xfer: tus.io resuming upload %q from %d", t.Oid, offset)
-		advanceCallbackProgress(cb, t, offset)
+		report(offset)
+	}
+
+	chunkSize := int64(defaultTusChunkSize)
+	if v, ok := a.apiClient.GitEnv().Get("lfs.tus.chunksize"); ok {
+		if n, err := humanize.ParseBytes(v); err == nil {
+			chunkSize = int64(n)
+		}
+	}
+
+	// 2. Send PATCH requests of up to chunkSize bytes each from the
+	//    start point, so that an interrupted request loses at most one
+	//    chunk, and after a request fails, send HEAD again to find out
+	//    how much the server kept, and carry on from there.
+	verifier := newVerifyingReader(tools.NewFileBody(f), t)
+	var failures int
+	for offset < t.Size {
+		end := t.Size
+		if chunkSize > 0 && offset+chunkSize < end {
+			end = offset + chunkSize
+		}
+
+		next, err := a.patch(t, rel, verifier, offset, end, report, authOkFunc)
+		if err != nil {
+			// Take back the progress reported for this attempt,
+			// since it is all sent again if the upload is retried.
+			if verifier.err != nil {
+				report(0)
+				return verifier.err
+			}
+			if failures++; !errors.IsRetriableError(err) || failures > tusMaxResumes {
+				report(0)
+				return err
+			}
+
+			a.Trace("xfer: tus.io PATCH for %q failed, asking where to resume: %v", t.Oid, err)
+			if next, err = a.uploadOffset(t, rel); err != nil {
+				report(0)
+				return err
+			}
+			a.Trace("xfer: tus.io resuming upload %q from %d", t.Oid, next)
+		}
+		if next > offset {
+			failures = 0
+		}
+		if err := report(next); err != nil {
+			return err
+		}
+		offset = next
+	}
+
+	return verifyUpload(a.apiClient, a.remote, t)
+}
+
+// uploadOffset sends a HEAD request for the upload, and returns the offset in
+// the object up to which the server has its content.
+func (a *tusUploadAdapter) uploadOffset(t *Transfer, rel *Action) (int64, error) {
+	//    Request must include Tus-Resumable header (version)
+	a.Trace("xfer: sending tus.io HEAD request for %q", t.Oid)
+	req, err := a.newHTTPRequest("HEAD", rel)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Tus-Resumable", TusVersion)
+
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusPreconditionFailed {
+			return 0, tusVersionError(res, rel)
+		}
+		return 0, errors.NewRetriableError(err)
 	}
+	res.Body.Close()
 
-	// 2. Send PATCH request with byte start point (even if 0) in Upload-Offset
+	//    Response will contain Upload-Offset if supported
+	offHdr := res.Header.Get("Upload-Offset")
+	if len(offHdr) == 0 {
+		return 0, fmt.Errorf("missing Upload-Offset header from tus.io HEAD response at %q, contact server admin", rel.Href)
+	}
+	offset, err := strconv.ParseInt(offHdr, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid Upload-Offset value %q in response from tus.io HEAD at %q, contact server admin", offHdr, rel.Href)
+	}
+	return offset, nil
+}
+
+// patch sends the content of the object from offset to end in a PATCH request,
+// and returns the offset up to which the server then has its content.
+func (a *tusUploadAdapter) patch(t *Transfer, rel *Action, verifier *verifyingReader, offset, end int64, report func(int64) error, authOkFunc func()) (int64, error) {
 	//    Response status must be 204
 	//    Response Upload-Offset must be request Upload-Offset plus sent bytes
 	//    Response may include Upload-Expires header in which case check not passed
-
-	a.Trace("xfer: sending tus.io PATCH request for %q", t.Oid)
-	req, err = a.newHTTPRequest("PATCH", rel)
+	a.Trace("xfer: sending tus.io PATCH request for %q from %d to %d", t.Oid, offset, end)
+	req, err := a.newHTTPRequest("PATCH", rel)
 	if err != nil {
-		return err
+		return offset, err
 	}
 
 	req.Header.Set("Tus-Resumable", TusVersion)
 	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
 	req.Header.Set("Content-Type", "application/offset+octet-stream")
-	req.Header.Set("Content-Length", strconv.FormatInt(t.Size-offset, 10))
-	req.ContentLength = t.Size - offset
+	req.Header.Set("Content-Length", strconv.FormatInt(end-offset, 10))
+	req.ContentLength = end - offset
 
-	// Ensure progress callbacks made while uploading
-	// Wrap callback to give name context
-	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
-		if cb != nil {
-			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+	// The verifier hashes the content before offset first, unless it has
+	// just sent it in the previous chunk.
+	if verifier.read != offset {
+		if _, err := verifier.Seek(offset, io.SeekStart); err != nil {
+			return offset, err
 		}
-		return nil
 	}
 
-	var reader lfsapi.ReadSeekCloser = tools.NewBodyWithCallback(f, t.Size, ccb)
+	// Ensure progress callbacks made while uploading
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		return report(offset + readSoFar)
+	}
+	chunk := &tusChunkBody{verifier: verifier, start: offset, end: end, pos: offset}
+	var reader lfsapi.ReadSeekCloser = tools.NewBodyWithCallback(chunk, end-offset, ccb)
 	reader = newStartCallbackReader(reader, func() error {
-		// seek to the offset since lfsapi.Client rewinds the body
-		if _, err := f.Seek(offset, io.SeekCurrent); err != nil {
-			return err
-		}
 		// Signal auth was ok on first read; this frees up other workers to start
 		if authOkFunc != nil {
 			authOkFunc()
@@ -130,30 +220,120 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 	req.Body = reader
 
 	req = a.apiClient.LogRequest(req, "lfs.data.upload")
-	res, err = a.doHTTP(t, req)
+	res, err := a.doHTTP(t, req)
 	if err != nil {
-		return errors.NewRetriableError(err)
-	}
-
-	// A status code of 403 likely means that an authentication token for the
-	// upload has expired. This can be safely retried.
-	if res.StatusCode == 403 {
-		err = errors.New("http: received status 403")
-		return errors.NewRetriableError(err)
+		if verifier.err != nil {
+			return offset, verifier.err
+		}
+		if res != nil && res.StatusCode == http.StatusPreconditionFailed {
+			return offset, tusVersionError(res, rel)
+		}
+		// Any other error, including 404 or 410 for an upload which
+		// has expired, is worth retrying, since a retry gets a new
+		// upload action from the batch API.
+		return offset, errors.NewRetriableError(err)
 	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
 
-	if res.StatusCode > 299 {
-		return errors.Wrapf(nil, "Invalid status for %s %s: %d",
+	switch {
+	case res.StatusCode == 308:
+		// "308 Resume Incomplete": the server kept only part of the
+		// content, and says how much, so carry on from there.
+		next, ok := tusReceivedOffset(res)
+		if !ok || next < offset || next > end {
+			return offset, errors.NewRetriableError(fmt.Errorf("tus.io PATCH for %q was incomplete", t.Oid))
+		}
+		a.Trace("xfer: tus.io PATCH for %q incomplete at %d", t.Oid, next)
+		return next, nil
+	case res.StatusCode == 403:
+		// A status code of 403 likely means that an authentication token for the
+		// upload has expired. This can be safely retried.
+		return offset, errors.NewRetriableError(errors.New("http: received status 403"))
+	case res.StatusCode > 299:
+		return offset, errors.Wrapf(nil, "Invalid status for %s %s: %d",
 			req.Method,
 			strings.SplitN(req.URL.String(), "?", 2)[0],
 			res.StatusCode,
 		)
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
+	if next, ok := tusReceivedOffset(res); ok && next != end {
+		return offset, errors.NewRetriableError(fmt.Errorf("tus.io PATCH for %q ended at %d, expected %d", t.Oid, next, end))
+	}
+	return end, nil
+}
 
-	return verifyUpload(a.apiClient, a.remote, t)
+// tusReceivedOffset returns the offset up to which the server has the content
+// of an upload, from the Upload-Offset header of a tus.io response or the
+// Range header of a "308 Resume Incomplete" response.
+func tusReceivedOffset(res *http.Response) (int64, bool) {
+	if v := res.Header.Get("Upload-Offset"); len(v) > 0 {
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil && n >= 0
+	}
+	if res.StatusCode != 308 {
+		return 0, false
+	}
+
+	// "Range: bytes=0-<last byte received>", or no Range at all if the
+	// server has nothing yet.
+	v := res.Header.Get("Range")
+	if len(v) == 0 {
+		return 0, true
+	}
+	if !strings.HasPrefix(v, "bytes=0-") {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimPrefix(v, "bytes=0-"), 10, 64)
+	return n + 1, err == nil && n >= 0
+}
+
+func tusVersionError(res *http.Response, rel *Action) error {
+	return fmt.Errorf("tus.io server at %q does not support version %s (it supports %q), contact server admin",
+		rel.Href, TusVersion, res.Header.Get("Tus-Version"))
+}
+
+// tusChunkBody is the body of a PATCH request, which sends the content of the
+// object between start and end through the verifier.
+type tusChunkBody struct {
+	verifier   *verifyingReader
+	start, end int64
+	pos        int64
+}
+
+func (b *tusChunkBody) Read(p []byte) (int, error) {
+	if b.pos >= b.end {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.end-b.pos {
+		p = p[:b.end-b.pos]
+	}
+	n, err := b.verifier.Read(p)
+	b.pos += int64(n)
+	if err == io.EOF && b.pos < b.end {
+		err = io.ErrUnexpectedEOF
+	} else if err == nil && b.pos >= b.end {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek moves to the given offset from the start of the chunk, which is the only
+// kind of seek supported.
+func (b *tusChunkBody) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.Errorf("tq: unsupported seek in upload body (whence %d)", whence)
+	}
+	if _, err := b.verifier.Seek(b.start+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	b.pos = b.start + offset
+	return offset, nil
+}
+
+func (b *tusChunkBody) Close() error {
+	return nil
 }
 
 func configureTusAdapter(m *Manifest) {
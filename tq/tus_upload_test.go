@@ -0,0 +1,152 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/fs"
+	"github.com/git-lfs/git-lfs/v2/lfsapi"
+	"github.com/git-lfs/git-lfs/v2/lfshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tusServer is a tus.io upload endpoint for a single object, which keeps at
+// most keep bytes of each PATCH request, answering with "308 Resume
+// Incomplete" when it keeps less than it was sent, and fails the PATCH
+// requests whose numbers are in fail after keeping half of their content.
+type tusServer struct {
+	mu      sync.Mutex
+	content []byte
+	keep    int
+	fail    map[int]bool
+	patches []string
+}
+
+func (s *tusServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", "0.2.2")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	switch req.Method {
+	case "HEAD":
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.content)))
+	case "PATCH":
+		offset, _ := strconv.Atoi(req.Header.Get("Upload-Offset"))
+		data, _ := ioutil.ReadAll(req.Body)
+		s.patches = append(s.patches, req.Header.Get("Upload-Offset")+"+"+strconv.Itoa(len(data)))
+		if offset != len(s.content) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if s.fail[len(s.patches)] {
+			s.content = append(s.content, data[:len(data)/2]...)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if s.keep > 0 && len(data) > s.keep {
+			s.content = append(s.content, data[:s.keep]...)
+			w.Header().Set("Range", "bytes=0-"+strconv.Itoa(len(s.content)-1))
+			w.WriteHeader(308)
+			return
+		}
+		s.content = append(s.content, data...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.content)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func doTusUpload(t *testing.T, srv *tusServer, content string, gitConfig map[string]string) (int64, error) {
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "tq-tus")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "obj")
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	sum := sha256.Sum256([]byte(content))
+
+	cli, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, gitConfig))
+	require.Nil(t, err)
+
+	f := fs.New(cli.OSEnv(), filepath.Join(dir, ".git"), dir, "", 0644)
+	a := &tusUploadAdapter{newAdapterBase(f, TusAdapterName, Upload, nil)}
+	a.transferImpl = a
+	a.apiClient = cli
+
+	tr := &Transfer{
+		Oid:           hex.EncodeToString(sum[:]),
+		Size:          int64(len(content)),
+		Path:          path,
+		Authenticated: true,
+		Actions:       ActionSet{"upload": &Action{Href: ts.URL + "/upload"}},
+	}
+
+	var progress int64
+	cb := func(name string, total, read int64, current int) error {
+		progress += int64(current)
+		assert.Equal(t, progress, read)
+		return nil
+	}
+	return progress, a.DoTransfer(nil, tr, cb, nil)
+}
+
+func TestTusUploadInChunks(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	srv := &tusServer{}
+
+	progress, err := doTusUpload(t, srv, content, map[string]string{"lfs.tus.chunksize": "40"})
+	require.Nil(t, err)
+	assert.Equal(t, content, string(srv.content))
+	assert.Equal(t, []string{"0+40", "40+40", "80+20"}, srv.patches)
+	assert.EqualValues(t, len(content), progress)
+}
+
+func TestTusUploadResumesIncompletePatch(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	srv := &tusServer{keep: 30}
+
+	progress, err := doTusUpload(t, srv, content, nil)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(srv.content))
+	assert.Equal(t, []string{"0+100", "30+70", "60+40", "90+10"}, srv.patches)
+	assert.EqualValues(t, len(content), progress)
+}
+
+func TestTusUploadResumesFailedPatch(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	srv := &tusServer{fail: map[int]bool{1: true}}
+
+	progress, err := doTusUpload(t, srv, content, nil)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(srv.content))
+	assert.Equal(t, []string{"0+100", "50+50"}, srv.patches)
+	assert.EqualValues(t, len(content), progress)
+}
+
+func TestTusUploadGivesUpAfterRepeatedFailures(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	srv := &tusServer{fail: map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}}
+
+	progress, err := doTusUpload(t, srv, content, map[string]string{"lfs.tus.chunksize": "1"})
+	require.NotNil(t, err)
+	assert.Len(t, srv.patches, tusMaxResumes+1)
+	assert.EqualValues(t, 0, progress)
+}
@@ -0,0 +1,84 @@
+package tq
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// uploadClaimsKey is whether to ask the server about an object's
+	// upload URL before uploading it, and so not upload it while another
+	// client does.
+	uploadClaimsKey     = "lfs.transfer.uploadclaims"
+	defaultUploadClaims = false
+
+	// claimTimeoutKey is the number of seconds for which to wait while
+	// another client uploads an object, before uploading it anyway.
+	claimTimeoutKey     = "lfs.transfer.claimtimeout"
+	defaultClaimTimeout = 600
+
+	// minClaimDelay and maxClaimDelay bound how long to wait before
+	// asking about an object claimed by another client again, when the
+	// server does not say with a Retry-After header.
+	minClaimDelay = time.Second
+	maxClaimDelay = 30 * time.Second
+)
+
+// awaitUploadClaim sends a HEAD request to the upload URL of the given
+// transfer, and returns whether the server already has the object, so that it
+// need not be uploaded.
+//
+// A 200 response means that the server has the object, such as because another
+// client has uploaded it since the batch request. A 409 response means that
+// another client has claimed the object and is uploading it, so the request is
+// repeated, after the time given by any Retry-After header, until the server
+// has the object, the claim is released, or lfs.transfer.claimtimeout seconds
+// have passed. Any other response, including an error, means that the object
+// should be uploaded, and the server may treat the request as claiming it for
+// this client.
+func (a *basicUploadAdapter) awaitUploadClaim(t *Transfer, rel *Action) bool {
+	timeout := a.apiClient.GitEnv().Int(claimTimeoutKey, defaultClaimTimeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	delay := minClaimDelay
+
+	for {
+		req, err := a.newHTTPRequest("HEAD", rel)
+		if err != nil {
+			return false
+		}
+
+		req = a.apiClient.LogRequest(req, "lfs.data.claim")
+		res, err := a.doHTTP(t, req)
+		if res == nil {
+			tracerx.Printf("tq: claim %s err: %v", t.Oid[:7], err)
+			return false
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			tracerx.Printf("tq: claim %s: already uploaded", t.Oid[:7])
+			return true
+		case http.StatusConflict:
+		default:
+			return false
+		}
+
+		wait := retryAfter(res.Header.Get("Retry-After"), delay)
+		if time.Now().Add(wait).After(deadline) {
+			tracerx.Printf("tq: claim %s: timed out waiting for another upload", t.Oid[:7])
+			return false
+		}
+
+		tracerx.Printf("tq: claim %s: uploading elsewhere, checking again in %s", t.Oid[:7], wait)
+		time.Sleep(wait)
+		if delay *= 2; delay > maxClaimDelay {
+			delay = maxClaimDelay
+		}
+	}
+}
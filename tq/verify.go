@@ -1,7 +1,10 @@
 package tq
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/git-lfs/git-lfs/v2/lfsapi"
 	"github.com/git-lfs/git-lfs/v2/tools"
@@ -11,6 +14,18 @@ import (
 const (
 	maxVerifiesConfigKey     = "lfs.transfer.maxverifies"
 	defaultMaxVerifyAttempts = 3
+
+	// quarantineTimeoutConfigKey is the number of seconds for which an
+	// object which the server has quarantined pending a scan is verified
+	// again, before it is reported as quarantined.
+	quarantineTimeoutConfigKey = "lfs.transfer.quarantinetimeout"
+	defaultQuarantineTimeout   = 300
+
+	// minQuarantineDelay and maxQuarantineDelay bound how long to wait
+	// before verifying a quarantined object again, when the server does
+	// not say with a Retry-After header.
+	minQuarantineDelay = time.Second
+	maxQuarantineDelay = 30 * time.Second
 )
 
 func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
@@ -45,6 +60,44 @@ func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
 	mv = tools.MaxInt(defaultMaxVerifyAttempts, mv)
 	req = c.LogRequest(req, "lfs.verify")
 
+	timeout := c.GitEnv().Int(quarantineTimeoutConfigKey, defaultQuarantineTimeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	delay := minQuarantineDelay
+
+	for {
+		res, err := sendVerify(c, remote, t, action, req, mv)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusAccepted {
+			return res.Body.Close()
+		}
+
+		// The server has the object, but has quarantined it until it
+		// has been scanned, so verify it again later.
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+
+		wait := retryAfter(res.Header.Get("Retry-After"), delay)
+		if time.Now().Add(wait).After(deadline) {
+			return newQuarantinedObjectError(t.Name, t.Oid, body.Message)
+		}
+
+		tracerx.Printf("tq: verify %s quarantined, retrying in %s: %s", t.Oid[:7], wait, body.Message)
+		time.Sleep(wait)
+		if delay *= 2; delay > maxQuarantineDelay {
+			delay = maxQuarantineDelay
+		}
+	}
+}
+
+// sendVerify sends the given verify request, making up to "mv" attempts, and
+// returns the first response.
+func sendVerify(c *lfsapi.Client, remote string, t *Transfer, action *Action, req *http.Request, mv int) (*http.Response, error) {
+	var err error
 	for i := 1; i <= mv; i++ {
 		tracerx.Printf("tq: verify %s attempt #%d (max: %d)", t.Oid[:7], i, mv)
 
@@ -58,9 +111,23 @@ func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
 		if err != nil {
 			tracerx.Printf("tq: verify err: %+v", err.Error())
 		} else {
-			err = res.Body.Close()
-			break
+			return res, nil
+		}
+	}
+	return nil, err
+}
+
+// retryAfter returns how long to wait as given by the value of a Retry-After
+// header, either in seconds or as a date, or "fallback" if there is none.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := time.Parse(time.RFC1123, header); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
 		}
+		return 0
 	}
-	return err
+	return fallback
 }
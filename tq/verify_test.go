@@ -70,3 +70,57 @@ func TestVerifySuccess(t *testing.T) {
 	assert.Nil(t, verifyUpload(c, "origin", tr))
 	assert.EqualValues(t, 1, called)
 }
+
+func TestVerifyQuarantined(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tr Transfer
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&tr))
+		assert.Equal(t, "abcd1234", tr.Oid)
+
+		if atomic.AddUint32(&called, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"message":"scanning"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs." + srv.URL + "/verify.access": "None",
+	}))
+	require.Nil(t, err)
+	tr := &Transfer{
+		Oid:     "abcd1234",
+		Size:    123,
+		Actions: map[string]*Action{"verify": &Action{Href: srv.URL + "/verify"}},
+	}
+
+	assert.Nil(t, verifyUpload(c, "origin", tr))
+	assert.EqualValues(t, 3, called)
+}
+
+func TestVerifyQuarantinedTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"message":"scanning"}`))
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(lfshttp.NewContext(nil, nil, map[string]string{
+		"lfs.transfer.quarantinetimeout":    "10",
+		"lfs." + srv.URL + "/verify.access": "None",
+	}))
+	require.Nil(t, err)
+	tr := &Transfer{
+		Name:    "a.dat",
+		Oid:     "abcd1234",
+		Size:    123,
+		Actions: map[string]*Action{"verify": &Action{Href: srv.URL + "/verify"}},
+	}
+
+	err = verifyUpload(c, "origin", tr)
+	require.IsType(t, &QuarantinedObjectError{}, err)
+	assert.Equal(t, "object a.dat (abcd1234) is quarantined pending a scan: scanning", err.Error())
+}
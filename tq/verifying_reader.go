@@ -0,0 +1,89 @@
+package tq
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/git-lfs/git-lfs/v2/errors"
+	"github.com/git-lfs/git-lfs/v2/tools"
+)
+
+// verifyingReader hashes the content of an object as it is uploaded, and
+// fails the upload before its last bytes are sent if the content does not
+// match the object's OID and size, such as because its file was changed while
+// it was being uploaded. The server is then never sent a complete body with
+// the wrong content.
+type verifyingReader struct {
+	tools.ReadSeekCloser
+
+	t    *Transfer
+	hash hash.Hash
+	read int64
+
+	// err is the error the upload was failed with, if any, since the
+	// HTTP client may return it wrapped in one of its own.
+	err error
+}
+
+func newVerifyingReader(r tools.ReadSeekCloser, t *Transfer) *verifyingReader {
+	return &verifyingReader{
+		ReadSeekCloser: r,
+		t:              t,
+		hash:           tools.NewLfsContentHash(),
+	}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.ReadSeekCloser.Read(p)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+
+	if r.read+int64(n) > r.t.Size {
+		// Only the object's size is sent, so the rest is extra.
+		n = int(r.t.Size - r.read)
+	}
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+
+	if r.read < r.t.Size && err != io.EOF {
+		return n, nil
+	}
+
+	if r.read != r.t.Size {
+		r.err = newObjectChangedError(r.t.Name, r.t.Oid, r.t.Size,
+			errors.Errorf("read %d bytes, expected %d", r.read, r.t.Size))
+	} else if oid := hex.EncodeToString(r.hash.Sum(nil)); oid != r.t.Oid {
+		r.err = newObjectChangedError(r.t.Name, r.t.Oid, r.t.Size,
+			errors.Errorf("content has OID %s", oid))
+	}
+	if r.err != nil {
+		// Withhold the last bytes read, so that the body is cut short.
+		return 0, r.err
+	}
+	return n, io.EOF
+}
+
+// Seek moves to the given offset from the start of the object, which is the
+// only kind of seek supported, hashing the content before it again.
+func (r *verifyingReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.Errorf("tq: unsupported seek in upload body (whence %d)", whence)
+	}
+
+	if _, err := r.ReadSeekCloser.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r.hash.Reset()
+	r.read = 0
+	r.err = nil
+
+	n, err := io.CopyN(r.hash, r.ReadSeekCloser, offset)
+	r.read = n
+	return n, err
+}
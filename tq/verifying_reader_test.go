@@ -0,0 +1,80 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/v2/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verifyingReaderTransfer(content string) *Transfer {
+	sum := sha256.Sum256([]byte(content))
+	return &Transfer{
+		Name: "a.dat",
+		Oid:  hex.EncodeToString(sum[:]),
+		Size: int64(len(content)),
+	}
+}
+
+func TestVerifyingReaderMatchingContent(t *testing.T) {
+	tr := verifyingReaderTransfer("hello, world")
+	r := newVerifyingReader(tools.NewByteBody([]byte("hello, world")), tr)
+
+	data, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello, world", string(data))
+}
+
+func TestVerifyingReaderIgnoresExtraContent(t *testing.T) {
+	tr := verifyingReaderTransfer("hello")
+	r := newVerifyingReader(tools.NewByteBody([]byte("hello, world")), tr)
+
+	data, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestVerifyingReaderChangedContent(t *testing.T) {
+	tr := verifyingReaderTransfer("hello, world")
+	r := newVerifyingReader(tools.NewByteBody([]byte("HELLO, WORLD")), tr)
+
+	data, err := ioutil.ReadAll(r)
+	require.NotNil(t, err)
+	assert.True(t, IsObjectChangedError(err))
+	assert.Contains(t, err.Error(), "a.dat")
+	assert.True(t, int64(len(data)) < tr.Size, "expected body to be cut short")
+}
+
+func TestVerifyingReaderTruncatedContent(t *testing.T) {
+	tr := verifyingReaderTransfer("hello, world")
+	r := newVerifyingReader(tools.NewByteBody([]byte("hello")), tr)
+
+	_, err := ioutil.ReadAll(r)
+	require.NotNil(t, err)
+	assert.True(t, IsObjectChangedError(err))
+	assert.Contains(t, err.Error(), "read 5 bytes, expected 12")
+}
+
+func TestVerifyingReaderSeekRehashes(t *testing.T) {
+	tr := verifyingReaderTransfer("hello, world")
+	r := newVerifyingReader(tools.NewByteBody([]byte("hello, world")), tr)
+
+	_, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+
+	n, err := r.Seek(7, io.SeekStart)
+	require.Nil(t, err)
+	assert.EqualValues(t, 7, n)
+
+	data, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", string(data))
+
+	_, err = r.Seek(0, io.SeekCurrent)
+	assert.NotNil(t, err)
+}